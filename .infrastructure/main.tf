@@ -73,6 +73,25 @@ resource "google_secret_manager_secret_version" "firebase_json_key" {
   secret_data           = filebase64("../secrets/firebase-service-account.json")
 }
 
+# Keys signing invitation and email change tokens, the service disables the flows without them
+resource "google_secret_manager_secret" "signing_keys" {
+  for_each = toset(["invitation-signing-key", "email-change-signing-key"])
+
+  project   = var.project_id
+  secret_id = each.key
+
+  replication {
+    auto {}
+  }
+}
+
+resource "google_secret_manager_secret_version" "signing_keys" {
+  for_each = google_secret_manager_secret.signing_keys
+
+  secret      = each.value.id
+  secret_data = trimspace(file("../secrets/${each.key}"))
+}
+
 resource "google_cloud_run_v2_service" "this" {
   name                = local.service_name
   project             = var.project_id
@@ -155,6 +174,26 @@ resource "google_cloud_run_v2_service" "this" {
         name  = "GIN_MODE"
         value = "release"
       }
+
+      env {
+        name = "INVITATION_SIGNING_KEY"
+        value_source {
+          secret_key_ref {
+            secret  = google_secret_manager_secret.signing_keys["invitation-signing-key"].secret_id
+            version = "latest"
+          }
+        }
+      }
+
+      env {
+        name = "EMAIL_CHANGE_SIGNING_KEY"
+        value_source {
+          secret_key_ref {
+            secret  = google_secret_manager_secret.signing_keys["email-change-signing-key"].secret_id
+            version = "latest"
+          }
+        }
+      }
     }
 
     containers {
@@ -195,6 +234,15 @@ resource "google_secret_manager_secret_iam_binding" "run_firebase_json_key" {
   role      = "roles/secretmanager.secretAccessor"
 }
 
+resource "google_secret_manager_secret_iam_binding" "run_signing_keys" {
+  for_each = google_secret_manager_secret.signing_keys
+
+  project   = var.project_id
+  secret_id = each.value.secret_id
+  members   = ["serviceAccount:${google_service_account.run.email}"]
+  role      = "roles/secretmanager.secretAccessor"
+}
+
 resource "google_project_iam_member" "run_firebase_admin" {
   project = var.project_id
   member  = "serviceAccount:${google_service_account.run.email}"
@@ -0,0 +1,203 @@
+// Command backup backs up Firestore collections to GCS and restores them, see internal/backup.
+// Scheduled backups are taken by the service's backup route, this command takes one on demand
+// and restores them.
+//
+// The restore subcommand verifies every object of the backup against the checksums of its
+// manifest, then writes the collections to the database given, which may be in another project,
+// e.g. to rebuild a collection in a scratch database before copying documents back. The verify
+// subcommand only runs the checks.
+//
+// Usage:
+//
+//	go run ./cmd/backup export -project my-project [-database staging] -bucket backups [-collections users,documents] [-mode jsonl|managed]
+//	go run ./cmd/backup restore -location gs://backups/backups/20260102T150405Z -project restore-project [-database scratch] [-collections users]
+//	go run ./cmd/backup verify -location gs://backups/backups/20260102T150405Z
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	admin "cloud.google.com/go/firestore/apiv1/admin"
+	"cloud.google.com/go/storage"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/audit"
+	"github.com/thoughtgears/shared-services/internal/backup"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	switch os.Args[1] {
+	case "export":
+		export(os.Args[2:])
+	case "restore":
+		restore(os.Args[2:], false)
+	case "verify":
+		restore(os.Args[2:], true)
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: backup export|restore|verify [flags]")
+	os.Exit(2)
+}
+
+func export(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	projectID := flags.String("project", os.Getenv("GCP_PROJECT_ID"), "GCP project ID")
+	databaseID := flags.String("database", cmp.Or(os.Getenv("FIRESTORE_DATABASE_ID"), firestore.DefaultDatabaseID), "Firestore database ID")
+	bucket := flags.String("bucket", os.Getenv("BACKUP_BUCKET"), "Bucket the backup is written to")
+	prefix := flags.String("prefix", cmp.Or(os.Getenv("BACKUP_PREFIX"), "backups"), "Object prefix the backup is written under")
+	collections := flags.String("collections", cmp.Or(os.Getenv("BACKUP_COLLECTIONS"), "users,documents"), "Comma separated collections to back up")
+	mode := flags.String("mode", cmp.Or(os.Getenv("BACKUP_MODE"), backup.ModeJSONL), "Backup mode, jsonl or managed")
+	auditCollection := flags.String("audit-collection", "audit_logs", "Firestore collection holding audit events")
+	_ = flags.Parse(args)
+
+	if *projectID == "" || *bucket == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+
+	firestoreClient, err := firestore.NewClientWithDatabase(ctx, *projectID, *databaseID)
+	if err != nil {
+		log.Fatal().Msgf("Failed to create Firestore client: %v", err)
+	}
+	defer firestoreClient.Close()
+
+	storageClient, err := storage.NewClient(ctx)
+	if err != nil {
+		log.Fatal().Msgf("Failed to create GCS client: %v", err)
+	}
+	defer storageClient.Close()
+
+	var adminClient *admin.FirestoreAdminClient
+	if *mode == backup.ModeManaged {
+		adminClient = newAdminClient(ctx)
+		defer adminClient.Close()
+	}
+
+	backuper := backup.NewBackuper(
+		firestoreClient,
+		adminClient,
+		storageClient,
+		audit.NewRecorder(audit.NewFirestoreWriter(firestoreClient, *auditCollection)),
+		backup.Config{
+			Database:    databaseName(*projectID, *databaseID),
+			Collections: strings.Split(*collections, ","),
+			Mode:        *mode,
+			Bucket:      *bucket,
+			Prefix:      *prefix,
+		},
+	)
+
+	manifest, err := backuper.Backup(ctx)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to back up collections")
+	}
+	printJSON(manifest)
+
+	log.Info().
+		Str("location", manifest.Location).
+		Str("mode", manifest.Mode).
+		Int("files", len(manifest.Files)).
+		Msg("Backup complete")
+}
+
+func restore(args []string, verifyOnly bool) {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	location := flags.String("location", "", "Backup to restore, gs://<bucket>/<prefix>/<timestamp>")
+	projectID := flags.String("project", os.Getenv("GCP_PROJECT_ID"), "GCP project ID of the target database")
+	databaseID := flags.String("database", cmp.Or(os.Getenv("FIRESTORE_DATABASE_ID"), firestore.DefaultDatabaseID), "Firestore database ID of the target database")
+	collections := flags.String("collections", "", "Comma separated collections to restore, all of the backup when empty")
+	auditCollection := flags.String("audit-collection", "audit_logs", "Firestore collection of the target database holding audit events")
+	_ = flags.Parse(args)
+
+	if *location == "" || *projectID == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+
+	firestoreClient, err := firestore.NewClientWithDatabase(ctx, *projectID, *databaseID)
+	if err != nil {
+		log.Fatal().Msgf("Failed to create Firestore client: %v", err)
+	}
+	defer firestoreClient.Close()
+
+	storageClient, err := storage.NewClient(ctx)
+	if err != nil {
+		log.Fatal().Msgf("Failed to create GCS client: %v", err)
+	}
+	defer storageClient.Close()
+
+	adminClient := newAdminClient(ctx)
+	defer adminClient.Close()
+
+	opts := backup.RestoreOptions{VerifyOnly: verifyOnly}
+	if *collections != "" {
+		opts.Collections = strings.Split(*collections, ",")
+	}
+
+	restorer := backup.NewRestorer(
+		firestoreClient,
+		adminClient,
+		storageClient,
+		audit.NewRecorder(audit.NewFirestoreWriter(firestoreClient, *auditCollection)),
+		databaseName(*projectID, *databaseID),
+	)
+
+	result, err := restorer.Restore(ctx, *location, opts)
+	if result != nil {
+		printJSON(result)
+	}
+	if err != nil {
+		log.Fatal().Err(err).Str("location", *location).Msg("Failed to restore backup")
+	}
+
+	log.Info().
+		Str("location", result.Location).
+		Str("database", result.Database).
+		Int("verified", result.Verified).
+		Int("restored", result.Restored).
+		Bool("verify_only", verifyOnly).
+		Msg("Restore complete")
+}
+
+// newAdminClient creates a client of the Firestore admin API, for managed exports and imports.
+func newAdminClient(ctx context.Context) *admin.FirestoreAdminClient {
+	client, err := admin.NewFirestoreAdminClient(ctx)
+	if err != nil {
+		log.Fatal().Msgf("Failed to create Firestore admin client: %v", err)
+	}
+
+	return client
+}
+
+// databaseName returns the resource name of a Firestore database.
+func databaseName(projectID, databaseID string) string {
+	return fmt.Sprintf("projects/%s/databases/%s", projectID, databaseID)
+}
+
+// printJSON prints value as indented JSON to stdout.
+func printJSON(value any) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		log.Fatal().Msgf("Failed to print result: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+// Command gateway serves the public API on a single host and proxies each path prefix to the
+// service behind it, with shared authentication and one CORS policy.
+//
+// Usage:
+//
+//	GATEWAY_ROUTES=/v1/users=https://user-api.a.run.app,/v1/documents=https://document-api.a.run.app go run ./cmd/gateway
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+
+	"github.com/thoughtgears/shared-services/internal/config"
+	"github.com/thoughtgears/shared-services/internal/gateway"
+	"github.com/thoughtgears/shared-services/internal/idtoken"
+	"github.com/thoughtgears/shared-services/internal/router/middleware"
+	"github.com/thoughtgears/shared-services/pkg/app"
+)
+
+var cfg config.GatewayConfig
+
+func init() {
+	app.LoadConfig(&cfg)
+}
+
+func main() {
+	ctx := context.Background()
+
+	a, err := app.New(ctx, app.Config{
+		ServiceName:        cfg.ServiceName,
+		DomainName:         cfg.DomainName,
+		Port:               cfg.Port,
+		Local:              cfg.Local,
+		FirebaseSecretPath: cfg.FirebaseSecretPath,
+		MessageCatalogDir:  cfg.MessageCatalogDir,
+		AuthLockout: middleware.AuthLockoutConfig{
+			Threshold:     cfg.AuthLockoutThreshold,
+			Window:        cfg.AuthLockoutWindow,
+			BlockDuration: cfg.AuthLockoutDuration,
+		},
+		TrustedProxies:  cfg.TrustedProxies,
+		TrustedPlatform: cfg.TrustedPlatform,
+		ProjectID:       cfg.ProjectID,
+		OTELEndpoint:    cfg.OTELEndpoint,
+		OTELInsecure:    cfg.OTELInsecure,
+		TraceExporters:  cfg.TraceExporters,
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to start gateway")
+	}
+
+	routes, err := gateway.ParseRoutes(cfg.Routes, cfg.PublicRoutes)
+	if err != nil {
+		log.Fatal().Msgf("Failed to parse gateway routes: %v", err)
+	}
+
+	// The trace context is propagated so upstream spans join the gateway's trace
+	transport := otelhttp.NewTransport(http.DefaultTransport)
+
+	if cfg.UpstreamAuth {
+		for i, route := range routes {
+			audience := route.Upstream.Scheme + "://" + route.Upstream.Host
+			routes[i].Transport, err = idtoken.NewTransport(ctx, audience, idtoken.ServerlessAuthorizationHeader, transport)
+			if err != nil {
+				log.Fatal().Msgf("Failed to create upstream auth for %s: %v", route.Prefix, err)
+			}
+		}
+	}
+
+	gateway.Register(a.Router.Engine, routes, transport)
+
+	if err := a.Run(ctx); err != nil {
+		log.Fatal().Err(err).Msg("Failed to run server")
+	}
+}
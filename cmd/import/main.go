@@ -0,0 +1,210 @@
+// Command import onboards the users and documents of the legacy system from a manifest. The
+// manifest, CSV, JSON or JSON lines, lists one document per record with the Firebase UID of its
+// owner; users that do not exist yet are created from the record. The files it names are read
+// from a local directory or a GCS prefix, next to the manifest. Documents keep their timestamps
+// and are identified by owner and content hash, so files imported twice are skipped.
+//
+// The command uses the service configuration from the environment. Progress is checkpointed next
+// to the manifest, an interrupted import continues where it stopped when run again; -restart
+// ignores the checkpoint. -dry-run validates every record without writing anything.
+//
+// Usage:
+//
+//	go run ./cmd/import -manifest manifest.csv (-dir ./export | -bucket legacy-export [-prefix 2026-01]) [-tenant acme] [-dry-run] [-restart]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/audit"
+	"github.com/thoughtgears/shared-services/internal/clients"
+	"github.com/thoughtgears/shared-services/internal/config"
+	"github.com/thoughtgears/shared-services/internal/db"
+	"github.com/thoughtgears/shared-services/internal/fieldcrypt"
+	"github.com/thoughtgears/shared-services/internal/importer"
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/residency"
+	"github.com/thoughtgears/shared-services/internal/services"
+	"github.com/thoughtgears/shared-services/internal/tenant"
+	"github.com/thoughtgears/shared-services/pkg/app"
+	"github.com/thoughtgears/shared-services/pkg/providers"
+	"github.com/thoughtgears/shared-services/pkg/resilience"
+)
+
+var cfg config.Config
+
+func init() {
+	app.LoadConfig(&cfg)
+}
+
+func main() {
+	manifest := flag.String("manifest", "", "Manifest to import, relative to the directory or prefix")
+	dir := flag.String("dir", "", "Local directory holding the manifest and files")
+	bucket := flag.String("bucket", os.Getenv("IMPORT_BUCKET"), "Bucket holding the manifest and files")
+	prefix := flag.String("prefix", "", "Object prefix of the import in the bucket")
+	tenantID := flag.String("tenant", "", "Tenant to import into, required with tenancy enabled")
+	dryRun := flag.Bool("dry-run", false, "Validate every record without writing anything")
+	restart := flag.Bool("restart", false, "Ignore the checkpoint of an earlier run")
+	flag.Parse()
+
+	if *manifest == "" || (*dir == "") == (*bucket == "") {
+		fmt.Fprintln(os.Stderr, "-manifest and one of -dir or -bucket are required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// An interrupted import stops after the current record and saves its checkpoint
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.TenancyEnabled || *tenantID != "" {
+		if err := tenant.Validate(*tenantID); err != nil {
+			log.Fatal().Msgf("Invalid tenant: %v", err)
+		}
+		ctx = tenant.With(ctx, *tenantID)
+	}
+
+	clientBuilder := clients.NewBuilder(clients.Config{
+		UniverseDomain:         cfg.GoogleUniverseDomain,
+		FirestoreEndpoint:      cfg.FirestoreEndpoint,
+		StorageEndpoint:        cfg.StorageEndpoint,
+		IAMCredentialsEndpoint: cfg.IAMCredentialsEndpoint,
+		KMSEndpoint:            cfg.KMSEndpoint,
+		LoggingEndpoint:        cfg.LoggingEndpoint,
+		SpannerEndpoint:        cfg.SpannerEndpoint,
+	})
+
+	firestoreClient, err := clientBuilder.Firestore(ctx, cfg.ProjectID, cfg.FirestoreDatabaseID)
+	if err != nil {
+		log.Fatal().Msgf("Failed to create Firestore client: %v", err)
+	}
+	defer firestoreClient.Close()
+
+	storageClient, err := clientBuilder.Storage(ctx)
+	if err != nil {
+		log.Fatal().Msgf("Failed to create GCS client: %v", err)
+	}
+	defer storageClient.Close()
+
+	var fieldCipher *fieldcrypt.Cipher
+	if cfg.FieldEncryptionKMSKey != "" {
+		keyWrapper, err := fieldcrypt.NewKMSKeyWrapper(ctx, cfg.FieldEncryptionKMSKey, clientBuilder.KMSOptions()...)
+		if err != nil {
+			log.Fatal().Msgf("Failed to create KMS key wrapper: %v", err)
+		}
+		fieldCipher = fieldcrypt.NewCipher(keyWrapper, cfg.FieldEncryptionKeyRotation)
+	}
+
+	var closers []func(context.Context) error
+	defer func() {
+		for _, closer := range closers {
+			_ = closer(context.Background())
+		}
+	}()
+
+	env := &providers.Environment{
+		Config:    cfg,
+		Clients:   clientBuilder,
+		Firestore: firestoreClient,
+		Storage:   storageClient,
+		Cipher:    fieldCipher,
+		Policy: resilience.Policy{
+			Timeout:    cfg.BackendTimeout,
+			MaxRetries: cfg.BackendMaxRetries,
+			HedgeDelay: cfg.BackendHedgeDelay,
+		},
+		OnShutdown: func(fn func(ctx context.Context) error) { closers = append(closers, fn) },
+	}
+
+	repos, err := providers.NewRepositories(ctx, env)
+	if err != nil {
+		log.Fatal().Msgf("Failed to create repositories: %v", err)
+	}
+
+	regionStorages, err := providers.NewRegionStorages(ctx, env)
+	if err != nil {
+		log.Fatal().Msgf("Failed to create storage: %v", err)
+	}
+
+	residencyPolicy, err := residency.NewPolicy(cfg.Region, slices.Collect(maps.Keys(providers.RegionBuckets(cfg))), cfg.ResidencyCountryRegions)
+	if err != nil {
+		log.Fatal().Msgf("Failed to create residency policy: %v", err)
+	}
+
+	documentTypeService, err := newDocumentTypeService(repos.DocumentTypes)
+	if err != nil {
+		log.Fatal().Msgf("Failed to load document types: %v", err)
+	}
+
+	var source importer.Source
+	if *dir != "" {
+		source = importer.NewDirSource(*dir)
+	} else {
+		source = importer.NewGCSSource(storageClient, *bucket, *prefix)
+	}
+
+	auditRecorder := audit.NewRecorder(audit.NewFirestoreWriter(firestoreClient, cfg.AuditCollection))
+	userService := services.NewUserService(repos.Users, nil, cfg.DefaultPhoneRegion, auditRecorder)
+	importService := services.NewImportService(
+		source,
+		services.NewRegionalStorage(cfg.Region, regionStorages),
+		services.NewUserResidencyResolver(userService, residencyPolicy),
+		services.NewTenantSettingsService(repos.TenantSettings, 0, auditRecorder),
+		repos.Users,
+		repos.Documents,
+		documentTypeService,
+		auditRecorder,
+	)
+
+	report, err := importService.Import(ctx, services.ImportInput{Manifest: *manifest, DryRun: *dryRun, Restart: *restart})
+	if report != nil {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Error().Msgf("Failed to print report: %v", err)
+		}
+	}
+	if err != nil {
+		log.Fatal().Err(err).Str("manifest", *manifest).Msg("Failed to import")
+	}
+
+	log.Info().
+		Str("manifest", report.Manifest).
+		Bool("dry_run", report.DryRun).
+		Int("users_created", report.UsersCreated).
+		Int("documents_imported", report.DocumentsImported).
+		Int("duplicates", report.Duplicates).
+		Int("failed", report.Failed).
+		Msg("Import complete")
+}
+
+// newDocumentTypeService creates the document type service the service is configured with.
+func newDocumentTypeService(typeDatastore db.DB[models.DocumentTypeDefinition]) (services.DocumentTypeService, error) {
+	switch cfg.DocumentTypesSource {
+	case "firestore":
+		return services.NewFirestoreDocumentTypeService(typeDatastore, cfg.DocumentTypesCacheTTL), nil
+	case "config":
+		if cfg.DocumentTypesPath == "" {
+			return services.NewStaticDocumentTypeService(models.DefaultDocumentTypes()), nil
+		}
+
+		definitions, err := services.LoadDocumentTypesFile(cfg.DocumentTypesPath)
+		if err != nil {
+			return nil, fmt.Errorf("load document types: %w", err)
+		}
+
+		return services.NewStaticDocumentTypeService(definitions), nil
+	default:
+		return nil, fmt.Errorf("unknown document types source: %s", cfg.DocumentTypesSource)
+	}
+}
@@ -0,0 +1,95 @@
+// Command indexes prints the composite Firestore indexes and the TTL policies the services need,
+// as a firestore.indexes.json file for the Firebase CLI. Queries without an index fail with
+// db.MissingIndexError, so deploy the output before enabling new filters. Collections without
+// their TTL policy keep expired records forever.
+//
+// Usage:
+//
+//	go run ./cmd/indexes [-metadata-keys external_id,reference] > firestore.indexes.json
+//	firebase deploy --only firestore:indexes
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/db"
+	"github.com/thoughtgears/shared-services/internal/models"
+)
+
+// queryShape is a combination of filters a service queries a collection with.
+// Keep the shapes in sync with the queries in internal/services.
+type queryShape struct {
+	collection string
+	queries    []db.QueryConstraint
+}
+
+// shape returns the shape of query on the collection, the values of its filters do not matter.
+// Queries are built like the services build them, so a mistyped field fails here too.
+func shape[T any](collection string, query *db.QueryBuilder[T]) queryShape {
+	if err := query.Err(); err != nil {
+		log.Fatal().Err(err).Msg("Invalid query shape")
+	}
+
+	return queryShape{collection: collection, queries: query.Constraints()}
+}
+
+// knownShapes returns the query shapes of the services. Documents can be filtered on several
+// metadata keys at once, only filters on a single key are listed: each combination of keys needs
+// its own index, created from the link logged when it is first queried.
+func knownShapes(metadataKeys []string) []queryShape {
+	shapes := []queryShape{
+		shape("access_tokens", db.Query[models.AccessToken]().Where("user_id", db.Eq, "").Where("tenant_id", db.Eq, "")),
+	}
+	for _, owner := range []string{"user_id", "organization_id"} {
+		for _, key := range metadataKeys {
+			shapes = append(shapes, shape("documents", db.Query[models.Document]().Where(owner, db.Eq, "").Where("metadata."+key, db.Eq, "")))
+		}
+	}
+
+	return shapes
+}
+
+// ttlCollections are the collection groups whose documents carry db.TTLField.
+var ttlCollections = []string{"access_tokens", "invitations", "notification_deliveries", "session_revocations", "webhook_nonces"}
+
+func main() {
+	metadataKeys := flag.String("metadata-keys", os.Getenv("DOCUMENT_METADATA_FILTER_KEYS"), "Comma separated metadata keys documents can be filtered on")
+	flag.Parse()
+
+	if *metadataKeys == "" {
+		*metadataKeys = "external_id,reference"
+	}
+
+	var keys []string
+	for _, key := range strings.Split(*metadataKeys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	indexes := []db.Index{}
+	for _, shape := range knownShapes(keys) {
+		if index, ok := db.QueryIndex(shape.collection, shape.queries); ok {
+			indexes = append(indexes, index)
+		}
+	}
+
+	policies := make([]db.TTLPolicy, len(ttlCollections))
+	for i, collection := range ttlCollections {
+		policies[i] = db.NewTTLPolicy(collection)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(map[string]interface{}{
+		"indexes":        indexes,
+		"fieldOverrides": policies,
+	}); err != nil {
+		log.Fatal().Err(err).Msg("Failed to write indexes")
+	}
+}
@@ -0,0 +1,59 @@
+// Command migrate upgrades every document in a Firestore collection to the latest schema version.
+// Documents are also migrated lazily when read, so running this is only needed to backfill
+// a collection ahead of time, e.g. before dropping support for an old schema version.
+//
+// Usage:
+//
+//	go run ./cmd/migrate -project my-project [-database staging] -collection users [-dry-run]
+package main
+
+import (
+	"cmp"
+	"context"
+	"flag"
+	"os"
+
+	"cloud.google.com/go/firestore"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/db"
+	"github.com/thoughtgears/shared-services/internal/migrations"
+)
+
+func main() {
+	projectID := flag.String("project", os.Getenv("GCP_PROJECT_ID"), "GCP project ID")
+	databaseID := flag.String("database", cmp.Or(os.Getenv("FIRESTORE_DATABASE_ID"), firestore.DefaultDatabaseID), "Firestore database ID")
+	collection := flag.String("collection", "", "Firestore collection to migrate (users or documents)")
+	dryRun := flag.Bool("dry-run", false, "Only report how many documents would be migrated")
+	flag.Parse()
+
+	if *projectID == "" || *collection == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	registry := migrations.ForCollection(*collection)
+	if registry == nil {
+		log.Fatal().Msgf("Collection %s has no registered migrations", *collection)
+	}
+
+	ctx := context.Background()
+
+	client, err := firestore.NewClientWithDatabase(ctx, *projectID, *databaseID)
+	if err != nil {
+		log.Fatal().Msgf("Failed to create Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	count, err := db.Backfill(ctx, client, *collection, registry, *dryRun)
+	if err != nil {
+		log.Fatal().Err(err).Int("migrated", count).Msg("Failed to migrate collection")
+	}
+
+	log.Info().
+		Str("collection", *collection).
+		Int("schema_version", registry.LatestVersion()).
+		Int("migrated", count).
+		Bool("dry_run", *dryRun).
+		Msg("Migration complete")
+}
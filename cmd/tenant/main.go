@@ -0,0 +1,142 @@
+// Command tenant runs operator tasks on a single tenant.
+// The export subcommand writes all data of a tenant to a GCS export prefix with a manifest,
+// and with -purge deletes the tenant afterwards, for contract termination. Audit events
+// for the export are kept, they are the record of the offboarding.
+//
+// With -read-time the Firestore data is exported as it was at that time, a consistent snapshot
+// even while the tenant keeps writing. The time must be within the database's point-in-time
+// recovery window, an hour unless PITR is enabled, and cannot be combined with -purge.
+//
+// Usage:
+//
+//	go run ./cmd/tenant export -project my-project [-database staging] -tenant acme -buckets bucket-eu,bucket-us -export-bucket exports [-kms-key key] [-read-time 2026-01-02T15:04:05Z | -purge]
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/storage"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/audit"
+	"github.com/thoughtgears/shared-services/internal/fieldcrypt"
+	"github.com/thoughtgears/shared-services/internal/offboarding"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	switch os.Args[1] {
+	case "export":
+		export(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: tenant export [flags]")
+	os.Exit(2)
+}
+
+func export(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	projectID := flags.String("project", os.Getenv("GCP_PROJECT_ID"), "GCP project ID")
+	databaseID := flags.String("database", cmp.Or(os.Getenv("FIRESTORE_DATABASE_ID"), firestore.DefaultDatabaseID), "Firestore database ID")
+	tenantID := flags.String("tenant", "", "Tenant to export")
+	buckets := flags.String("buckets", os.Getenv("GCP_BUCKET_NAME"), "Comma separated buckets holding tenant objects")
+	exportBucket := flags.String("export-bucket", os.Getenv("TENANT_EXPORT_BUCKET"), "Bucket the export is written to")
+	exportPrefix := flags.String("export-prefix", "exports", "Object prefix the export is written under")
+	auditCollection := flags.String("audit-collection", "audit_logs", "Firestore collection holding audit events")
+	settingsCollection := flags.String("settings-collection", "tenant_settings", "Firestore collection holding tenant settings")
+	kmsKey := flags.String("kms-key", os.Getenv("FIELD_ENCRYPTION_KMS_KEY"), "KMS key to decrypt encrypted fields with")
+	purge := flags.Bool("purge", false, "Delete the tenant once the export is complete")
+	readTimeFlag := flags.String("read-time", "", "Export the Firestore data as it was at this RFC 3339 time")
+	_ = flags.Parse(args)
+
+	if *projectID == "" || *tenantID == "" || *buckets == "" || *exportBucket == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	// Purging at a read time would miss the documents written since
+	var readTime time.Time
+	if *readTimeFlag != "" {
+		parsed, err := time.Parse(time.RFC3339, *readTimeFlag)
+		if err != nil || *purge || parsed.After(time.Now()) {
+			fmt.Fprintln(os.Stderr, "-read-time must be a past RFC 3339 time and cannot be combined with -purge")
+			os.Exit(2)
+		}
+		readTime = parsed
+	}
+
+	ctx := context.Background()
+
+	firestoreClient, err := firestore.NewClientWithDatabase(ctx, *projectID, *databaseID)
+	if err != nil {
+		log.Fatal().Msgf("Failed to create Firestore client: %v", err)
+	}
+	defer firestoreClient.Close()
+	if !readTime.IsZero() {
+		// The client is only used for this export, so its reads can all be pinned
+		firestoreClient.WithReadOptions(firestore.ReadTime(readTime))
+	}
+
+	storageClient, err := storage.NewClient(ctx)
+	if err != nil {
+		log.Fatal().Msgf("Failed to create GCS client: %v", err)
+	}
+	defer storageClient.Close()
+
+	var cipher *fieldcrypt.Cipher
+	if *kmsKey != "" {
+		keyWrapper, err := fieldcrypt.NewKMSKeyWrapper(ctx, *kmsKey)
+		if err != nil {
+			log.Fatal().Msgf("Failed to create KMS key wrapper: %v", err)
+		}
+		cipher = fieldcrypt.NewCipher(keyWrapper, 0)
+	}
+
+	exporter := offboarding.NewExporter(
+		firestoreClient,
+		storageClient,
+		cipher,
+		audit.NewRecorder(audit.NewFirestoreWriter(firestoreClient, *auditCollection)),
+		offboarding.Config{
+			SourceBuckets:      strings.Split(*buckets, ","),
+			ExportBucket:       *exportBucket,
+			ExportPrefix:       *exportPrefix,
+			AuditCollection:    *auditCollection,
+			SettingsCollection: *settingsCollection,
+			ReadTime:           readTime,
+		},
+	)
+
+	manifest, err := exporter.Export(ctx, *tenantID, *purge)
+	if err != nil {
+		log.Fatal().Err(err).Str("tenant_id", *tenantID).Msg("Failed to export tenant")
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		log.Fatal().Msgf("Failed to print manifest: %v", err)
+	}
+
+	log.Info().
+		Str("tenant_id", manifest.TenantID).
+		Str("location", manifest.Location).
+		Int("files", len(manifest.Files)).
+		Bool("purged", manifest.Purged).
+		Msg("Tenant export complete")
+}
@@ -0,0 +1,45 @@
+package address
+
+import (
+	"context"
+
+	"github.com/thoughtgears/shared-services/internal/models"
+)
+
+// Validator checks and normalizes postal addresses.
+// Implementations return a *models.ValidationError with "address." prefixed
+// field names when the address is invalid, and any other error when the
+// validation itself could not be performed.
+type Validator interface {
+	Validate(ctx context.Context, address models.Address) (models.Address, error)
+}
+
+// NewValidator returns the Validator for the configured mode.
+// Supported modes are "off", "basic" and "google"; "off" returns nil so callers skip validation.
+// When storeCoordinates is false any coordinates found by the validator are dropped.
+func NewValidator(mode, apiKey string, storeCoordinates bool) (Validator, error) {
+	switch mode {
+	case "", "off":
+		return nil, nil
+	case "basic":
+		return NewBasicValidator(), nil
+	case "google":
+		validator, err := NewGoogleValidator(apiKey, storeCoordinates)
+		if err != nil {
+			return nil, err
+		}
+
+		return validator, nil
+	default:
+		return nil, &UnknownModeError{Mode: mode}
+	}
+}
+
+// UnknownModeError is returned by NewValidator for an unsupported mode.
+type UnknownModeError struct {
+	Mode string
+}
+
+func (e *UnknownModeError) Error() string {
+	return "unknown address validation mode: " + e.Mode
+}
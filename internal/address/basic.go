@@ -0,0 +1,153 @@
+package address
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+
+	"github.com/thoughtgears/shared-services/internal/models"
+)
+
+// postcodePatterns holds the postcode format for countries we commonly see.
+// Countries without a pattern only have their postcode trimmed and upper-cased.
+var postcodePatterns = map[string]*regexp.Regexp{
+	"GB": regexp.MustCompile(`^[A-Z]{1,2}[0-9][A-Z0-9]? ?[0-9][A-Z]{2}$`),
+	"IE": regexp.MustCompile(`^([AC-FHKNPRTV-Y][0-9]{2}|D6W) ?[0-9AC-FHKNPRTV-Y]{4}$`),
+	"US": regexp.MustCompile(`^[0-9]{5}(-[0-9]{4})?$`),
+	"CA": regexp.MustCompile(`^[A-Z][0-9][A-Z] ?[0-9][A-Z][0-9]$`),
+	"NL": regexp.MustCompile(`^[0-9]{4} ?[A-Z]{2}$`),
+	"DE": regexp.MustCompile(`^[0-9]{5}$`),
+	"FR": regexp.MustCompile(`^[0-9]{5}$`),
+	"ES": regexp.MustCompile(`^[0-9]{5}$`),
+	"IT": regexp.MustCompile(`^[0-9]{5}$`),
+	"SE": regexp.MustCompile(`^[0-9]{3} ?[0-9]{2}$`),
+	"NO": regexp.MustCompile(`^[0-9]{4}$`),
+	"DK": regexp.MustCompile(`^[0-9]{4}$`),
+	"AU": regexp.MustCompile(`^[0-9]{4}$`),
+}
+
+// BasicValidator validates addresses offline using ISO 3166 country codes
+// and per-country postcode patterns. It is the fallback when no address
+// validation API is configured.
+type BasicValidator struct {
+	countryNames map[string]string
+}
+
+// NewBasicValidator creates a BasicValidator.
+func NewBasicValidator() *BasicValidator {
+	// Build a lookup from English country names to ISO codes by walking every two letter code
+	names := make(map[string]string)
+	namer := display.English.Regions()
+	for a := 'A'; a <= 'Z'; a++ {
+		for b := 'A'; b <= 'Z'; b++ {
+			code := string([]rune{a, b})
+			region, err := language.ParseRegion(code)
+			if err != nil || !region.IsCountry() || region.String() != code {
+				continue
+			}
+
+			if name := namer.Name(region); name != "" {
+				names[strings.ToUpper(name)] = region.Canonicalize().String()
+			}
+		}
+	}
+
+	return &BasicValidator{countryNames: names}
+}
+
+// Validate normalizes the country to an ISO 3166-1 alpha-2 code and the postcode
+// to its canonical format, returning a validation error if either is invalid.
+// Empty addresses are accepted as-is since the address is optional.
+func (v *BasicValidator) Validate(_ context.Context, address models.Address) (models.Address, error) {
+	if address.IsZero() {
+		return address, nil
+	}
+
+	verr := &models.ValidationError{}
+
+	country, ok := v.normalizeCountry(address.Country)
+	if !ok {
+		verr.Add("address.country", "must be an ISO 3166 country code or name")
+	} else {
+		address.Country = country
+	}
+
+	postcode, ok := normalizePostcode(country, address.PostCode)
+	if !ok {
+		verr.Add("address.postcode", "is not a valid postcode for "+address.Country)
+	} else {
+		address.PostCode = postcode
+	}
+
+	if err := verr.Err(); err != nil {
+		return address, err
+	}
+
+	return address, nil
+}
+
+// normalizeCountry converts an alpha-2, alpha-3 or English country name to an alpha-2 code.
+func (v *BasicValidator) normalizeCountry(country string) (string, bool) {
+	country = strings.ToUpper(strings.TrimSpace(country))
+	if country == "" {
+		return "", false
+	}
+
+	if code, ok := v.countryNames[country]; ok {
+		return code, true
+	}
+
+	// Common names that differ from the CLDR display names
+	switch country {
+	case "UK", "GREAT BRITAIN", "ENGLAND", "SCOTLAND", "WALES", "NORTHERN IRELAND":
+		return "GB", true
+	case "USA", "UNITED STATES OF AMERICA":
+		return "US", true
+	}
+
+	region, err := language.ParseRegion(country)
+	if err != nil || !region.IsCountry() {
+		return "", false
+	}
+
+	return region.Canonicalize().String(), true
+}
+
+// normalizePostcode upper-cases the postcode, applies the canonical spacing for the
+// country and checks it against the country pattern if one is known.
+func normalizePostcode(country, postcode string) (string, bool) {
+	postcode = strings.ToUpper(strings.Join(strings.Fields(postcode), ""))
+	if postcode == "" {
+		return "", true
+	}
+
+	switch country {
+	case "GB", "CA", "IE":
+		// Inward code is always the last three characters for GB and CA, four for IE
+		split := 3
+		if country == "IE" {
+			split = 4
+		}
+		if len(postcode) > split {
+			postcode = postcode[:len(postcode)-split] + " " + postcode[len(postcode)-split:]
+		}
+	case "NL":
+		if len(postcode) == 6 {
+			postcode = postcode[:4] + " " + postcode[4:]
+		}
+	case "SE":
+		if len(postcode) == 5 {
+			postcode = postcode[:3] + " " + postcode[3:]
+		}
+	}
+
+	pattern, ok := postcodePatterns[country]
+	if !ok {
+		return postcode, true
+	}
+
+	return postcode, pattern.MatchString(postcode)
+}
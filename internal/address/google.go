@@ -0,0 +1,150 @@
+package address
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/thoughtgears/shared-services/internal/models"
+)
+
+const googleAddressValidationURL = "https://addressvalidation.googleapis.com/v1:validateAddress"
+
+// GoogleValidator validates addresses using the Google Address Validation API.
+// The country is normalized offline first, since the API requires a region code.
+type GoogleValidator struct {
+	apiKey           string
+	storeCoordinates bool
+	basic            *BasicValidator
+	httpClient       *http.Client
+}
+
+// NewGoogleValidator creates a GoogleValidator using the given API key.
+func NewGoogleValidator(apiKey string, storeCoordinates bool) (*GoogleValidator, error) {
+	if apiKey == "" {
+		return nil, errors.New("address validation API key is required")
+	}
+
+	return &GoogleValidator{
+		apiKey:           apiKey,
+		storeCoordinates: storeCoordinates,
+		basic:            NewBasicValidator(),
+		httpClient:       &http.Client{Timeout: 5 * time.Second},
+	}, nil
+}
+
+type googlePostalAddress struct {
+	RegionCode   string   `json:"regionCode"`
+	PostalCode   string   `json:"postalCode,omitempty"`
+	Locality     string   `json:"locality,omitempty"`
+	AddressLines []string `json:"addressLines,omitempty"`
+}
+
+type googleValidateRequest struct {
+	Address googlePostalAddress `json:"address"`
+}
+
+type googleValidateResponse struct {
+	Result struct {
+		Verdict struct {
+			ValidationGranularity string `json:"validationGranularity"`
+		} `json:"verdict"`
+		Address struct {
+			PostalAddress googlePostalAddress `json:"postalAddress"`
+		} `json:"address"`
+		Geocode struct {
+			Location struct {
+				Latitude  float64 `json:"latitude"`
+				Longitude float64 `json:"longitude"`
+			} `json:"location"`
+		} `json:"geocode"`
+	} `json:"result"`
+}
+
+// Validate sends the address to the Address Validation API and returns the
+// normalized postcode, locality and country, plus coordinates if enabled.
+// Addresses the API cannot confirm are reported as validation errors.
+func (v *GoogleValidator) Validate(ctx context.Context, address models.Address) (models.Address, error) {
+	if address.IsZero() {
+		return address, nil
+	}
+
+	country, ok := v.basic.normalizeCountry(address.Country)
+	if !ok {
+		verr := &models.ValidationError{}
+		verr.Add("address.country", "must be an ISO 3166 country code or name")
+
+		return address, verr
+	}
+
+	line := strings.TrimSpace(address.BuildingNumber + " " + address.Street)
+	body, err := json.Marshal(googleValidateRequest{
+		Address: googlePostalAddress{
+			RegionCode:   country,
+			PostalCode:   address.PostCode,
+			Locality:     address.City,
+			AddressLines: []string{line},
+		},
+	})
+	if err != nil {
+		return address, fmt.Errorf("failed to encode address validation request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, googleAddressValidationURL+"?key="+v.apiKey, bytes.NewReader(body))
+	if err != nil {
+		return address, fmt.Errorf("failed to create address validation request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return address, fmt.Errorf("failed to call address validation API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return address, fmt.Errorf("address validation API returned status %d", resp.StatusCode)
+	}
+
+	var result googleValidateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return address, fmt.Errorf("failed to decode address validation response: %w", err)
+	}
+
+	verdict := result.Result.Verdict
+	if verdict.ValidationGranularity == "" || verdict.ValidationGranularity == "OTHER" {
+		verr := &models.ValidationError{}
+		verr.Add("address", "could not be verified")
+
+		return address, verr
+	}
+
+	postal := result.Result.Address.PostalAddress
+	address.Country = country
+	if postal.RegionCode != "" {
+		address.Country = postal.RegionCode
+	}
+	if postal.PostalCode != "" {
+		address.PostCode = postal.PostalCode
+	}
+	if postal.Locality != "" {
+		address.City = postal.Locality
+	}
+
+	if v.storeCoordinates {
+		location := result.Result.Geocode.Location
+		if location.Latitude != 0 || location.Longitude != 0 {
+			address.Coordinates = &models.Coordinates{
+				Latitude:  location.Latitude,
+				Longitude: location.Longitude,
+			}
+		}
+	}
+
+	return address, nil
+}
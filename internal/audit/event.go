@@ -0,0 +1,120 @@
+package audit
+
+import (
+	"context"
+	"time"
+)
+
+// Action identifies the kind of operation an audit event records.
+type Action string
+
+const (
+	ActionUserCreate           Action = "user.create"
+	ActionUserUpdate           Action = "user.update"
+	ActionUserDelete           Action = "user.delete"
+	ActionUserRoleChange       Action = "user.role_change"
+	ActionUserMerge            Action = "user.merge"
+	ActionUserEmailRequest     Action = "user.email_change_request"
+	ActionUserEmailChange      Action = "user.email_change"
+	ActionUserAvatarUpdate     Action = "user.avatar_update"
+	ActionUserAvatarDelete     Action = "user.avatar_delete"
+	ActionUserClaimsUpdate     Action = "user.claims_update"
+	ActionUserSessionsRevoke   Action = "user.sessions_revoke"
+	ActionAccessTokenCreate    Action = "access_token.create"
+	ActionAccessTokenRevoke    Action = "access_token.revoke"
+	ActionDocumentCreate       Action = "document.create"
+	ActionDocumentUpdate       Action = "document.update"
+	ActionDocumentDelete       Action = "document.delete"
+	ActionDocumentShare        Action = "document.share"
+	ActionDocumentDownload     Action = "document.download"
+	ActionDocumentMoveRegion   Action = "document.move_region"
+	ActionDocumentRedact       Action = "document.redact"
+	ActionDocumentMerge        Action = "document.merge"
+	ActionDocumentClaim        Action = "document.claim"
+	ActionDocumentReview       Action = "document.review"
+	ActionDocumentTier         Action = "document.tier"
+	ActionDocumentRestore      Action = "document.restore"
+	ActionDocumentHold         Action = "document.legal_hold"
+	ActionDocumentHoldRelease  Action = "document.legal_hold_release"
+	ActionBundleCreate         Action = "bundle.create"
+	ActionBundleDelete         Action = "bundle.delete"
+	ActionBundleClaim          Action = "bundle.claim"
+	ActionBundleReview         Action = "bundle.review"
+	ActionCommentCreate        Action = "comment.create"
+	ActionCommentUpdate        Action = "comment.update"
+	ActionCommentDelete        Action = "comment.delete"
+	ActionOrganizationCreate   Action = "organization.create"
+	ActionOrganizationUpdate   Action = "organization.update"
+	ActionOrganizationDelete   Action = "organization.delete"
+	ActionMembershipDelete     Action = "membership.delete"
+	ActionInvitationCreate     Action = "invitation.create"
+	ActionInvitationRevoke     Action = "invitation.revoke"
+	ActionInvitationAccept     Action = "invitation.accept"
+	ActionTenantSettingsUpdate Action = "tenant_settings.update"
+	ActionTenantExport         Action = "tenant.export"
+	ActionTenantPurge          Action = "tenant.purge"
+	ActionTenantImport         Action = "tenant.import"
+	ActionBackupCreate         Action = "backup.create"
+	ActionBackupRestore        Action = "backup.restore"
+)
+
+// Outcome is the result of an audited operation.
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success"
+	OutcomeFailure Outcome = "failure"
+)
+
+// ActorType describes who performed an operation.
+type ActorType string
+
+const (
+	ActorTypeUser    ActorType = "user"
+	ActorTypeService ActorType = "service"
+	ActorTypeSystem  ActorType = "system"
+)
+
+// Actor is the identity that performed an audited operation.
+type Actor struct {
+	Type ActorType `json:"type" firestore:"type"`
+	ID   string    `json:"id" firestore:"id"`
+}
+
+// Target is the resource an audited operation acted on.
+type Target struct {
+	Type string `json:"type" firestore:"type"`
+	ID   string `json:"id" firestore:"id"`
+}
+
+// Event is a single entry in the audit log.
+type Event struct {
+	ID        string            `json:"id" firestore:"id"`
+	Time      time.Time         `json:"time" firestore:"time"`
+	Action    Action            `json:"action" firestore:"action"`
+	Outcome   Outcome           `json:"outcome" firestore:"outcome"`
+	Actor     Actor             `json:"actor" firestore:"actor"`
+	Target    Target            `json:"target" firestore:"target"`
+	TenantID  string            `json:"tenant_id,omitempty" firestore:"tenant_id,omitempty"`
+	RequestID string            `json:"request_id,omitempty" firestore:"request_id,omitempty"`
+	Error     string            `json:"error,omitempty" firestore:"error,omitempty"`
+	Details   map[string]string `json:"details,omitempty" firestore:"details,omitempty"`
+}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx carrying the actor of the current request.
+// It is set by the authentication middleware.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor stored in ctx. Operations without an authenticated
+// actor, such as background jobs, are attributed to the system.
+func ActorFrom(ctx context.Context) Actor {
+	if actor, ok := ctx.Value(actorKey{}).(Actor); ok {
+		return actor
+	}
+
+	return Actor{Type: ActorTypeSystem, ID: "system"}
+}
@@ -0,0 +1,32 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+)
+
+// FirestoreWriter stores audit events as documents in a Firestore collection.
+type FirestoreWriter struct {
+	client         *firestore.Client
+	collectionName string
+}
+
+// NewFirestoreWriter creates a FirestoreWriter for the given collection.
+func NewFirestoreWriter(client *firestore.Client, collectionName string) *FirestoreWriter {
+	return &FirestoreWriter{
+		client:         client,
+		collectionName: collectionName,
+	}
+}
+
+// Write stores the event using its ID as the document ID.
+// Create is used instead of Set so an existing audit entry can never be overwritten.
+func (w *FirestoreWriter) Write(ctx context.Context, event Event) error {
+	if _, err := w.client.Collection(w.collectionName).Doc(event.ID).Create(ctx, event); err != nil {
+		return fmt.Errorf("failed to write audit event to firestore: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	logging "google.golang.org/api/logging/v2"
+	"google.golang.org/api/option"
+)
+
+// CloudLoggingWriter writes audit events to a dedicated Cloud Logging log,
+// so they can be routed to a locked bucket with its own retention.
+type CloudLoggingWriter struct {
+	service *logging.Service
+	logName string
+}
+
+// NewCloudLoggingWriter creates a CloudLoggingWriter writing to projects/<projectID>/logs/<logID>.
+// opts are passed to the Cloud Logging client, e.g. to use a private endpoint.
+func NewCloudLoggingWriter(ctx context.Context, projectID, logID string, opts ...option.ClientOption) (*CloudLoggingWriter, error) {
+	service, err := logging.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cloud logging client: %w", err)
+	}
+
+	return &CloudLoggingWriter{
+		service: service,
+		logName: fmt.Sprintf("projects/%s/logs/%s", projectID, logID),
+	}, nil
+}
+
+// Write sends the event as a structured log entry. Failed operations are logged with WARNING severity.
+func (w *CloudLoggingWriter) Write(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit event: %w", err)
+	}
+
+	severity := "NOTICE"
+	if event.Outcome == OutcomeFailure {
+		severity = "WARNING"
+	}
+
+	_, err = w.service.Entries.Write(&logging.WriteLogEntriesRequest{
+		LogName:  w.logName,
+		Resource: &logging.MonitoredResource{Type: "global"},
+		Entries: []*logging.LogEntry{
+			{
+				InsertId:    event.ID,
+				Timestamp:   event.Time.Format(time.RFC3339Nano),
+				Severity:    severity,
+				JsonPayload: payload,
+				Labels: map[string]string{
+					"action":     string(event.Action),
+					"request_id": event.RequestID,
+				},
+			},
+		},
+	}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("failed to write audit event to cloud logging: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/requestid"
+	"github.com/thoughtgears/shared-services/internal/tenant"
+)
+
+// Writer persists audit events.
+type Writer interface {
+	Write(ctx context.Context, event Event) error
+}
+
+// Recorder builds audit events from the request context and sends them to every configured writer.
+// A nil *Recorder is valid and records nothing, so services can run without auditing in tests or locally.
+type Recorder struct {
+	writers []Writer
+}
+
+// NewRecorder creates a Recorder that writes every event to all the given writers.
+func NewRecorder(writers ...Writer) *Recorder {
+	return &Recorder{writers: writers}
+}
+
+// Record writes an audit event for an operation on target.
+// The actor, tenant and request ID are taken from ctx. If opErr is non-nil the event is recorded as a failure.
+// Writer errors are logged rather than returned, so an audit backend outage does not fail the
+// operation itself; the log line keeps the event recoverable.
+func (r *Recorder) Record(ctx context.Context, action Action, target Target, opErr error, details map[string]string) {
+	if r == nil || len(r.writers) == 0 {
+		return
+	}
+
+	event := Event{
+		ID:        uuid.NewString(),
+		Time:      time.Now().UTC(),
+		Action:    action,
+		Outcome:   OutcomeSuccess,
+		Actor:     ActorFrom(ctx),
+		Target:    target,
+		TenantID:  tenant.From(ctx),
+		RequestID: requestid.From(ctx),
+		Details:   details,
+	}
+
+	if opErr != nil {
+		event.Outcome = OutcomeFailure
+		event.Error = opErr.Error()
+	}
+
+	// The audit write must complete even if the request is cancelled after the operation succeeded
+	writeCtx := context.WithoutCancel(ctx)
+
+	var errs []error
+	for _, w := range r.writers {
+		if err := w.Write(writeCtx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		log.Error().Err(err).Interface("audit_event", event).Msg("Failed to write audit event")
+	}
+}
@@ -0,0 +1,254 @@
+// Package backup exports Firestore collections to GCS and restores them, for disaster recovery.
+// A backup is either a JSON Lines file per collection written by iterating it, or a managed
+// export of the Firestore admin API. Both end with a manifest.json holding the CRC32C checksum
+// of every object, restores verify the objects against it before writing anything.
+//
+// Collections are exported as collection groups, so with multi-tenancy the collection of every
+// tenant is included and restored to the same path. Encrypted fields are kept encrypted, the
+// restored data is read with the same KMS key.
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	admin "cloud.google.com/go/firestore/apiv1/admin"
+	"cloud.google.com/go/firestore/apiv1/admin/adminpb"
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+
+	"github.com/thoughtgears/shared-services/internal/audit"
+)
+
+// ManifestFileName is the name of the manifest written last to every backup, so its presence
+// marks the backup as complete.
+const ManifestFileName = "manifest.json"
+
+// Modes of a backup.
+const (
+	// ModeJSONL writes one JSON Lines file per collection, readable without Firestore tooling.
+	ModeJSONL = "jsonl"
+	// ModeManaged runs a managed export, faster for large collections and billed as reads.
+	ModeManaged = "managed"
+)
+
+// managedDir is the directory of a backup the managed export writes to.
+const managedDir = "firestore"
+
+// Config describes what is backed up and where backups are written.
+type Config struct {
+	// Database is the resource name of the database, projects/<project>/databases/<database>.
+	Database string
+	// Collections are the IDs of the collection groups to back up, e.g. users and documents.
+	Collections []string
+	// Mode is ModeJSONL or ModeManaged.
+	Mode string
+	// Bucket receives the backups under Prefix/<timestamp>/.
+	Bucket string
+	Prefix string
+}
+
+// File describes a single object of a backup, Path is relative to the backup location.
+type File struct {
+	Path       string `json:"path"`
+	Collection string `json:"collection,omitempty"`
+	Records    int    `json:"records,omitempty"`
+	Size       int64  `json:"size"`
+	CRC32C     uint32 `json:"crc32c"`
+}
+
+// Manifest describes a completed backup.
+type Manifest struct {
+	Database    string    `json:"database"`
+	Mode        string    `json:"mode"`
+	Collections []string  `json:"collections"`
+	CreatedAt   time.Time `json:"created_at"`
+	Location    string    `json:"location"`
+	Files       []File    `json:"files"`
+}
+
+// Backuper writes backups of the configured collections.
+type Backuper struct {
+	firestore *firestore.Client
+	admin     *admin.FirestoreAdminClient
+	storage   *storage.Client
+	audit     *audit.Recorder
+	cfg       Config
+}
+
+// NewBackuper creates a Backuper. adminClient runs managed exports, it may be nil for ModeJSONL.
+func NewBackuper(firestoreClient *firestore.Client, adminClient *admin.FirestoreAdminClient, storageClient *storage.Client, recorder *audit.Recorder, cfg Config) *Backuper {
+	return &Backuper{
+		firestore: firestoreClient,
+		admin:     adminClient,
+		storage:   storageClient,
+		audit:     recorder,
+		cfg:       cfg,
+	}
+}
+
+// Backup writes a backup of the configured collections to a new location and returns its manifest.
+func (b *Backuper) Backup(ctx context.Context) (*Manifest, error) {
+	now := time.Now().UTC()
+	location := path.Join(b.cfg.Prefix, now.Format("20060102T150405Z"))
+	manifest := &Manifest{
+		Database:    b.cfg.Database,
+		Mode:        b.cfg.Mode,
+		Collections: b.cfg.Collections,
+		CreatedAt:   now,
+		Location:    "gs://" + b.cfg.Bucket + "/" + location + "/",
+	}
+
+	err := b.backup(ctx, location, manifest)
+	b.audit.Record(ctx, audit.ActionBackupCreate, audit.Target{Type: "database", ID: b.cfg.Database}, err, map[string]string{
+		"location":    manifest.Location,
+		"mode":        manifest.Mode,
+		"collections": strings.Join(manifest.Collections, ","),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// backup writes the collections in the configured mode, then the manifest.
+func (b *Backuper) backup(ctx context.Context, location string, manifest *Manifest) error {
+	switch b.cfg.Mode {
+	case ModeJSONL:
+		for _, collection := range b.cfg.Collections {
+			file, err := b.exportCollection(ctx, location, collection)
+			if err != nil {
+				return err
+			}
+			manifest.Files = append(manifest.Files, file)
+		}
+	case ModeManaged:
+		files, err := b.exportManaged(ctx, location)
+		if err != nil {
+			return err
+		}
+		manifest.Files = files
+	default:
+		return fmt.Errorf("unknown backup mode %q", b.cfg.Mode)
+	}
+
+	return writeJSON(ctx, b.storage.Bucket(b.cfg.Bucket).Object(path.Join(location, ManifestFileName)), manifest)
+}
+
+// exportCollection writes every document of the collection group as one Record per line.
+func (b *Backuper) exportCollection(ctx context.Context, location string, collection string) (File, error) {
+	name := collection + ".jsonl"
+	writer := b.storage.Bucket(b.cfg.Bucket).Object(path.Join(location, name)).NewWriter(ctx)
+	writer.ContentType = "application/x-ndjson"
+	encoder := json.NewEncoder(writer)
+
+	records := 0
+	docs := b.firestore.CollectionGroup(collection).Documents(ctx)
+	defer docs.Stop()
+	for {
+		doc, err := docs.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			writer.CloseWithError(err)
+			return File{}, fmt.Errorf("failed to read %s: %w", collection, err)
+		}
+
+		data, err := encodeValue(doc.Data())
+		if err != nil {
+			writer.CloseWithError(err)
+			return File{}, fmt.Errorf("failed to encode %s: %w", doc.Ref.Path, err)
+		}
+		if err := encoder.Encode(Record{Path: relativePath(doc.Ref), Data: data.(map[string]any)}); err != nil {
+			writer.CloseWithError(err)
+			return File{}, fmt.Errorf("failed to write %s: %w", name, err)
+		}
+		records++
+	}
+
+	if err := writer.Close(); err != nil {
+		return File{}, fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	return File{
+		Path:       name,
+		Collection: collection,
+		Records:    records,
+		Size:       writer.Attrs().Size,
+		CRC32C:     writer.Attrs().CRC32C,
+	}, nil
+}
+
+// exportManaged runs a managed export of the collections into the backup and lists the objects
+// it wrote.
+func (b *Backuper) exportManaged(ctx context.Context, location string) ([]File, error) {
+	if b.admin == nil {
+		return nil, errors.New("managed backups need a Firestore admin client")
+	}
+
+	operation, err := b.admin.ExportDocuments(ctx, &adminpb.ExportDocumentsRequest{
+		Name:            b.cfg.Database,
+		CollectionIds:   b.cfg.Collections,
+		OutputUriPrefix: "gs://" + b.cfg.Bucket + "/" + path.Join(location, managedDir),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start managed export: %w", err)
+	}
+	if _, err := operation.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("failed to run managed export: %w", err)
+	}
+
+	var files []File
+	prefix := location + "/"
+	it := b.storage.Bucket(b.cfg.Bucket).Objects(ctx, &storage.Query{Prefix: path.Join(location, managedDir) + "/"})
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list managed export: %w", err)
+		}
+
+		files = append(files, File{
+			Path:   strings.TrimPrefix(attrs.Name, prefix),
+			Size:   attrs.Size,
+			CRC32C: attrs.CRC32C,
+		})
+	}
+
+	return files, nil
+}
+
+// relativePath returns the path of ref relative to the root of its database, e.g. users/123.
+func relativePath(ref *firestore.DocumentRef) string {
+	_, relative, _ := strings.Cut(ref.Path, "/documents/")
+
+	return relative
+}
+
+// writeJSON writes value as an indented JSON object to object.
+func writeJSON(ctx context.Context, object *storage.ObjectHandle, value any) error {
+	writer := object.NewWriter(ctx)
+	writer.ContentType = "application/json"
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		writer.CloseWithError(err)
+		return fmt.Errorf("failed to write %s: %w", object.ObjectName(), err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", object.ObjectName(), err)
+	}
+
+	return nil
+}
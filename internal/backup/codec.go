@@ -0,0 +1,177 @@
+package backup
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+// Firestore values without a JSON equivalent are written as an object with a single key naming
+// their type, so they are restored with the type they were read with. Doubles are tagged too, a
+// JSON number is restored as an integer.
+const (
+	timestampKey = "$timestamp"
+	bytesKey     = "$bytes"
+	doubleKey    = "$double"
+	geoPointKey  = "$geopoint"
+	referenceKey = "$reference"
+)
+
+// Record is one document of a backup file, its path is relative to the database root.
+type Record struct {
+	Path string         `json:"path"`
+	Data map[string]any `json:"data"`
+}
+
+// encodeValue converts a value read from Firestore into its JSON representation.
+func encodeValue(value any) (any, error) {
+	switch v := value.(type) {
+	case nil, bool, string, int64:
+		return v, nil
+	case float64:
+		return map[string]any{doubleKey: strconv.FormatFloat(v, 'g', -1, 64)}, nil
+	case time.Time:
+		return map[string]any{timestampKey: v.UTC().Format(time.RFC3339Nano)}, nil
+	case []byte:
+		return map[string]any{bytesKey: base64.StdEncoding.EncodeToString(v)}, nil
+	case *latlng.LatLng:
+		return map[string]any{geoPointKey: []float64{v.Latitude, v.Longitude}}, nil
+	case *firestore.DocumentRef:
+		return map[string]any{referenceKey: relativePath(v)}, nil
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			encoded, err := encodeValue(item)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			out[key] = encoded
+		}
+
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			encoded, err := encodeValue(item)
+			if err != nil {
+				return nil, fmt.Errorf("%d: %w", i, err)
+			}
+			out[i] = encoded
+		}
+
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unsupported value type %T", value)
+	}
+}
+
+// decodeValue converts a value of a backup file, decoded with json.Decoder.UseNumber, into the
+// value to write to Firestore. References are resolved against client, the restore target.
+func decodeValue(client *firestore.Client, value any) (any, error) {
+	switch v := value.(type) {
+	case json.Number:
+		return v.Int64()
+	case map[string]any:
+		if len(v) == 1 {
+			for key, item := range v {
+				if decoded, ok, err := decodeTagged(client, key, item); ok || err != nil {
+					return decoded, err
+				}
+			}
+		}
+
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			decoded, err := decodeValue(client, item)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			out[key] = decoded
+		}
+
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			decoded, err := decodeValue(client, item)
+			if err != nil {
+				return nil, fmt.Errorf("%d: %w", i, err)
+			}
+			out[i] = decoded
+		}
+
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+// decodeTagged decodes a value written as a single key object by encodeValue, ok is false for
+// maps that merely have a single key.
+func decodeTagged(client *firestore.Client, key string, value any) (any, bool, error) {
+	switch key {
+	case doubleKey:
+		text, _ := value.(string)
+		parsed, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, true, fmt.Errorf("invalid %s %q", doubleKey, text)
+		}
+
+		return parsed, true, nil
+	case timestampKey:
+		text, _ := value.(string)
+		parsed, err := time.Parse(time.RFC3339Nano, text)
+		if err != nil {
+			return nil, true, fmt.Errorf("invalid %s %q", timestampKey, text)
+		}
+
+		return parsed, true, nil
+	case bytesKey:
+		text, _ := value.(string)
+		decoded, err := base64.StdEncoding.DecodeString(text)
+		if err != nil {
+			return nil, true, fmt.Errorf("invalid %s: %w", bytesKey, err)
+		}
+
+		return decoded, true, nil
+	case geoPointKey:
+		point, _ := value.([]any)
+		if len(point) != 2 {
+			return nil, true, fmt.Errorf("invalid %s", geoPointKey)
+		}
+		latitude, err := toFloat(point[0])
+		if err != nil {
+			return nil, true, err
+		}
+		longitude, err := toFloat(point[1])
+		if err != nil {
+			return nil, true, err
+		}
+
+		return &latlng.LatLng{Latitude: latitude, Longitude: longitude}, true, nil
+	case referenceKey:
+		text, _ := value.(string)
+		if text == "" {
+			return nil, true, fmt.Errorf("invalid %s", referenceKey)
+		}
+
+		return client.Doc(text), true, nil
+	default:
+		return nil, false, nil
+	}
+}
+
+// toFloat converts a JSON number to a float64.
+func toFloat(value any) (float64, error) {
+	number, ok := value.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("invalid number %v", value)
+	}
+
+	return number.Float64()
+}
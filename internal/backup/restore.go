@@ -0,0 +1,318 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"path"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	admin "cloud.google.com/go/firestore/apiv1/admin"
+	"cloud.google.com/go/firestore/apiv1/admin/adminpb"
+	"cloud.google.com/go/storage"
+
+	"github.com/thoughtgears/shared-services/internal/audit"
+)
+
+// ErrIntegrity is returned when an object of a backup does not match its manifest.
+var ErrIntegrity = errors.New("backup integrity check failed")
+
+// crc32cTable is the Castagnoli table GCS computes object checksums with.
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// maxRecordSize limits a line of a backup file, Firestore documents are at most 1 MiB but the
+// JSON encoding of binary values is larger.
+const maxRecordSize = 4 << 20
+
+// RestoreOptions select what a restore writes.
+type RestoreOptions struct {
+	// Collections restores only these collections of the backup, all when empty.
+	Collections []string
+	// VerifyOnly checks the backup against its manifest without writing anything.
+	VerifyOnly bool
+}
+
+// RestoreResult reports what a restore did.
+type RestoreResult struct {
+	Location    string   `json:"location"`
+	Database    string   `json:"database"`
+	Collections []string `json:"collections"`
+	Verified    int      `json:"verified"`
+	Restored    int      `json:"restored"`
+}
+
+// Restorer restores backups into a target database, which may be in another project than the
+// database the backup was taken of.
+type Restorer struct {
+	firestore *firestore.Client
+	admin     *admin.FirestoreAdminClient
+	storage   *storage.Client
+	audit     *audit.Recorder
+	// database is the resource name of the target database, for managed imports
+	database string
+}
+
+// NewRestorer creates a Restorer writing to the database of firestoreClient, whose resource name
+// is database. adminClient runs managed imports, it may be nil to restore ModeJSONL backups only.
+func NewRestorer(firestoreClient *firestore.Client, adminClient *admin.FirestoreAdminClient, storageClient *storage.Client, recorder *audit.Recorder, database string) *Restorer {
+	return &Restorer{
+		firestore: firestoreClient,
+		admin:     adminClient,
+		storage:   storageClient,
+		audit:     recorder,
+		database:  database,
+	}
+}
+
+// Restore verifies the backup at location, gs://<bucket>/<prefix>/<timestamp>/, and writes the
+// selected collections to the target database. Every object is checked against the size and
+// CRC32C of the manifest before the first document is written, so a damaged backup restores
+// nothing. Restored documents replace those with the same path, other documents are kept.
+func (r *Restorer) Restore(ctx context.Context, location string, opts RestoreOptions) (*RestoreResult, error) {
+	bucket, prefix, err := parseLocation(location)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := r.readManifest(ctx, bucket, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	collections := manifest.Collections
+	if len(opts.Collections) > 0 {
+		for _, collection := range opts.Collections {
+			if !slices.Contains(manifest.Collections, collection) {
+				return nil, fmt.Errorf("collection %s is not in the backup", collection)
+			}
+		}
+		collections = opts.Collections
+	}
+
+	result := &RestoreResult{Location: location, Database: r.database, Collections: collections}
+	files := selectFiles(manifest, collections)
+	for _, file := range files {
+		if err := r.verify(ctx, bucket, prefix, file); err != nil {
+			return result, err
+		}
+		result.Verified++
+	}
+
+	if opts.VerifyOnly {
+		return result, nil
+	}
+
+	err = r.restore(ctx, bucket, prefix, manifest, collections, files, result)
+	r.audit.Record(ctx, audit.ActionBackupRestore, audit.Target{Type: "database", ID: r.database}, err, map[string]string{
+		"location":    location,
+		"mode":        manifest.Mode,
+		"collections": strings.Join(collections, ","),
+	})
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// restore writes the verified files of the backup to the target database.
+func (r *Restorer) restore(ctx context.Context, bucket, prefix string, manifest *Manifest, collections []string, files []File, result *RestoreResult) error {
+	switch manifest.Mode {
+	case ModeJSONL:
+		for _, file := range files {
+			restored, err := r.restoreFile(ctx, bucket, prefix, file)
+			result.Restored += restored
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case ModeManaged:
+		if r.admin == nil {
+			return errors.New("managed backups need a Firestore admin client to restore")
+		}
+
+		operation, err := r.admin.ImportDocuments(ctx, &adminpb.ImportDocumentsRequest{
+			Name:           r.database,
+			CollectionIds:  collections,
+			InputUriPrefix: "gs://" + bucket + "/" + path.Join(prefix, managedDir),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to start managed import: %w", err)
+		}
+		if err := operation.Wait(ctx); err != nil {
+			return fmt.Errorf("failed to run managed import: %w", err)
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("unknown backup mode %q", manifest.Mode)
+	}
+}
+
+// restoreFile writes every record of a JSON Lines file and returns how many were written.
+func (r *Restorer) restoreFile(ctx context.Context, bucket, prefix string, file File) (int, error) {
+	reader, err := r.storage.Bucket(bucket).Object(path.Join(prefix, file.Path)).NewReader(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", file.Path, err)
+	}
+	defer reader.Close()
+
+	bulk := r.firestore.BulkWriter(ctx)
+	var jobs []*firestore.BulkWriterJob
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxRecordSize)
+	for line := 1; scanner.Scan(); line++ {
+		record, err := r.decodeRecord(scanner.Bytes())
+		if err != nil {
+			bulk.End()
+			return 0, fmt.Errorf("invalid record %s:%d: %w", file.Path, line, err)
+		}
+
+		job, err := bulk.Set(r.firestore.Doc(record.Path), record.Data)
+		if err != nil {
+			bulk.End()
+			return 0, fmt.Errorf("failed to restore %s: %w", record.Path, err)
+		}
+		jobs = append(jobs, job)
+	}
+	bulk.End()
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", file.Path, err)
+	}
+
+	restored := 0
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return restored, fmt.Errorf("failed to restore %s: %w", file.Collection, err)
+		}
+		restored++
+	}
+
+	return restored, nil
+}
+
+// decodeRecord decodes a line of a JSON Lines file into the document to write.
+func (r *Restorer) decodeRecord(line []byte) (*Record, error) {
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+
+	var record Record
+	if err := decoder.Decode(&record); err != nil {
+		return nil, err
+	}
+	if record.Path == "" || strings.Count(record.Path, "/")%2 != 1 {
+		return nil, fmt.Errorf("invalid document path %q", record.Path)
+	}
+
+	data, err := decodeValue(r.firestore, record.Data)
+	if err != nil {
+		return nil, err
+	}
+	record.Data = data.(map[string]any)
+
+	return &record, nil
+}
+
+// verify checks an object of the backup against the manifest: the stored size and checksum, and
+// the checksum of the content read back. JSON Lines files must also hold the recorded number of
+// records.
+func (r *Restorer) verify(ctx context.Context, bucket, prefix string, file File) error {
+	object := r.storage.Bucket(bucket).Object(path.Join(prefix, file.Path))
+	attrs, err := object.Attrs(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return fmt.Errorf("%w: %s is missing", ErrIntegrity, file.Path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read attributes of %s: %w", file.Path, err)
+	}
+	if attrs.Size != file.Size || attrs.CRC32C != file.CRC32C {
+		return fmt.Errorf("%w: %s does not match the manifest", ErrIntegrity, file.Path)
+	}
+
+	// The content is read back rather than trusting the stored checksum alone
+	reader, err := object.ReadCompressed(true).NewReader(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", file.Path, err)
+	}
+	defer reader.Close()
+
+	hash := crc32.New(crc32cTable)
+	lines := &lineCounter{}
+	if _, err := io.Copy(io.MultiWriter(hash, lines), reader); err != nil {
+		return fmt.Errorf("failed to read %s: %w", file.Path, err)
+	}
+	if hash.Sum32() != file.CRC32C {
+		return fmt.Errorf("%w: checksum of %s does not match the manifest", ErrIntegrity, file.Path)
+	}
+	if file.Collection != "" && lines.count != file.Records {
+		return fmt.Errorf("%w: %s holds %d records, the manifest %d", ErrIntegrity, file.Path, lines.count, file.Records)
+	}
+
+	return nil
+}
+
+// readManifest reads the manifest of the backup under prefix in bucket.
+func (r *Restorer) readManifest(ctx context.Context, bucket, prefix string) (*Manifest, error) {
+	reader, err := r.storage.Bucket(bucket).Object(path.Join(prefix, ManifestFileName)).NewReader(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, fmt.Errorf("no backup at gs://%s/%s, the manifest is missing", bucket, prefix)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open manifest: %w", err)
+	}
+	defer reader.Close()
+
+	var manifest Manifest
+	if err := json.NewDecoder(reader).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	return &manifest, nil
+}
+
+// selectFiles returns the files of the manifest holding the collections. The objects of a
+// managed export are not split by collection, they are all verified.
+func selectFiles(manifest *Manifest, collections []string) []File {
+	files := make([]File, 0, len(manifest.Files))
+	for _, file := range manifest.Files {
+		if file.Collection == "" || slices.Contains(collections, file.Collection) {
+			files = append(files, file)
+		}
+	}
+
+	return files
+}
+
+// parseLocation splits gs://<bucket>/<prefix> into the bucket and the prefix.
+func parseLocation(location string) (string, string, error) {
+	rest, ok := strings.CutPrefix(location, "gs://")
+	bucket, prefix, _ := strings.Cut(rest, "/")
+	prefix = strings.Trim(prefix, "/")
+	if !ok || bucket == "" || prefix == "" {
+		return "", "", fmt.Errorf("invalid backup location %q, expected gs://<bucket>/<prefix>", location)
+	}
+
+	return bucket, prefix, nil
+}
+
+// lineCounter counts the lines written to it.
+type lineCounter struct {
+	count int
+}
+
+// Write implements io.Writer.
+func (l *lineCounter) Write(p []byte) (int, error) {
+	l.count += bytes.Count(p, []byte{'\n'})
+
+	return len(p), nil
+}
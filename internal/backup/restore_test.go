@@ -0,0 +1,127 @@
+package backup
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/storage"
+)
+
+// fakeGCS serves the objects of a bucket over the Cloud Storage JSON and XML APIs, its checksums
+// can differ from the content to simulate damaged objects.
+type fakeGCS struct {
+	bucket  string
+	objects map[string][]byte
+	crc32c  map[string]uint32
+}
+
+func (f *fakeGCS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Attributes are read with the JSON API, contents with the XML API
+	name, metadata := strings.CutPrefix(r.URL.Path, "/storage/v1/b/"+f.bucket+"/o/")
+	if !metadata {
+		name = strings.TrimPrefix(r.URL.Path, "/"+f.bucket+"/")
+	}
+	content, ok := f.objects[name]
+	if !ok {
+		http.Error(w, `{"error":{"code":404,"message":"Not Found"}}`, http.StatusNotFound)
+
+		return
+	}
+
+	if !metadata {
+		_, _ = w.Write(content)
+
+		return
+	}
+
+	checksum := crc32.Checksum(content, crc32cTable)
+	if stored, ok := f.crc32c[name]; ok {
+		checksum = stored
+	}
+	encoded := binary.BigEndian.AppendUint32(nil, checksum)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"bucket": f.bucket,
+		"name":   name,
+		"size":   strconv.Itoa(len(content)),
+		"crc32c": base64.StdEncoding.EncodeToString(encoded),
+	})
+}
+
+func newTestRestorer(t *testing.T, gcs *fakeGCS) *Restorer {
+	t.Helper()
+
+	server := httptest.NewServer(gcs)
+	t.Cleanup(server.Close)
+	t.Setenv("STORAGE_EMULATOR_HOST", server.Listener.Addr().String())
+
+	client, err := storage.NewClient(context.Background())
+	if err != nil {
+		t.Fatalf("storage.NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	return NewRestorer(nil, nil, client, nil, "projects/p/databases/(default)")
+}
+
+func TestVerify(t *testing.T) {
+	content := []byte(`{"path":"users/a","data":{}}` + "\n" + `{"path":"users/b","data":{}}` + "\n")
+	file := File{Path: "users.jsonl", Collection: "users", Records: 2, Size: int64(len(content)), CRC32C: crc32.Checksum(content, crc32cTable)}
+
+	tests := map[string]struct {
+		gcs  *fakeGCS
+		file File
+		want error
+	}{
+		"intact": {
+			gcs:  &fakeGCS{objects: map[string][]byte{"backup/users.jsonl": content}},
+			file: file,
+		},
+		"missing": {
+			gcs:  &fakeGCS{objects: map[string][]byte{}},
+			file: file,
+			want: ErrIntegrity,
+		},
+		"other size": {
+			gcs:  &fakeGCS{objects: map[string][]byte{"backup/users.jsonl": append(content, '\n')}},
+			file: file,
+			want: ErrIntegrity,
+		},
+		"corrupted content": {
+			// The stored checksum matches the manifest, the content read back does not
+			gcs: &fakeGCS{
+				objects: map[string][]byte{"backup/users.jsonl": []byte(strings.Replace(string(content), "a", "c", 1))},
+				crc32c:  map[string]uint32{"backup/users.jsonl": file.CRC32C},
+			},
+			file: file,
+			want: ErrIntegrity,
+		},
+		"other record count": {
+			gcs:  &fakeGCS{objects: map[string][]byte{"backup/users.jsonl": content}},
+			file: File{Path: file.Path, Collection: "users", Records: 3, Size: file.Size, CRC32C: file.CRC32C},
+			want: ErrIntegrity,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			tt.gcs.bucket = "backups"
+			r := newTestRestorer(t, tt.gcs)
+
+			err := r.verify(context.Background(), "backups", "backup", tt.file)
+			if tt.want == nil && err != nil {
+				t.Fatalf("verify() error = %v", err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+// Package bufpool reuses the buffers of the upload path, so concurrent uploads do not each
+// allocate and grow their own and leave the garbage collector behind under bursts.
+package bufpool
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// copyBufferSize is the size of the buffers Copy streams through.
+const copyBufferSize = 256 << 10
+
+var copyBuffers = sync.Pool{
+	New: func() any {
+		buf := make([]byte, copyBufferSize)
+
+		return &buf
+	},
+}
+
+// Copy copies src to dst like io.Copy, through a pooled buffer.
+func Copy(dst io.Writer, src io.Reader) (int64, error) {
+	buf := copyBuffers.Get().(*[]byte)
+	defer copyBuffers.Put(buf)
+
+	// Hide WriterTo and ReaderFrom, which would bypass the buffer with their own
+	return io.CopyBuffer(struct{ io.Writer }{dst}, struct{ io.Reader }{src}, *buf)
+}
+
+// ReadAll reads r to the end like io.ReadAll. size is the expected length, e.g. of an uploaded
+// file, so the content is read into one allocation instead of a slice grown by doubling, which
+// briefly holds up to three times the file in memory.
+func ReadAll(r io.Reader, size int64) ([]byte, error) {
+	if size <= 0 {
+		return io.ReadAll(r)
+	}
+
+	// ReadFrom grows the buffer unless MinRead bytes are free, the spare room lets it see the end
+	buf := bytes.NewBuffer(make([]byte, 0, size+bytes.MinRead))
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
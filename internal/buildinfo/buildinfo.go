@@ -0,0 +1,57 @@
+// Package buildinfo holds the version, commit and build time of the running binary, so logs,
+// health checks and telemetry show which revision is serving traffic.
+//
+// The values are injected at build time:
+//
+//	go build -ldflags "-X github.com/thoughtgears/shared-services/internal/buildinfo.Version=1.2.3 \
+//	  -X github.com/thoughtgears/shared-services/internal/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/thoughtgears/shared-services/internal/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Set through -ldflags -X at build time.
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// Info describes the running binary.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build info. Commit and build time fall back to the VCS stamp the Go toolchain
+// embeds, so binaries built without ldflags from a git checkout still report their revision.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = setting.Value
+			}
+		}
+	}
+
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+
+	return info
+}
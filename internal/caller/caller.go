@@ -0,0 +1,137 @@
+// Package caller carries the authenticated caller of a request in its context. The auth
+// middleware stores the verified user or service, handlers and services read it back through
+// the typed accessors instead of looking up untyped gin keys.
+package caller
+
+import (
+	"context"
+	"sync"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/idtoken"
+	"github.com/thoughtgears/shared-services/internal/models"
+)
+
+// AdminClaim is the Firebase custom claim that grants access to the admin routes.
+const AdminClaim = "admin"
+
+type (
+	tokenKey        struct{}
+	userRecordKey   struct{}
+	secondFactorKey struct{}
+	serviceKey      struct{}
+)
+
+// WithToken returns a copy of ctx carrying the verified token of the user making the request.
+func WithToken(ctx context.Context, token *auth.Token) context.Context {
+	return context.WithValue(ctx, tokenKey{}, token)
+}
+
+// TokenFrom returns the verified token of the user stored in ctx.
+func TokenFrom(ctx context.Context) (*auth.Token, bool) {
+	token, ok := ctx.Value(tokenKey{}).(*auth.Token)
+
+	return token, ok && token != nil
+}
+
+// UserLoader loads the user record of a Firebase UID, e.g. UserService.GetByID.
+type UserLoader func(ctx context.Context, uid string) (*models.User, error)
+
+// userRecord is the user record of a request, loaded at most once.
+type userRecord struct {
+	load UserLoader
+	once sync.Once
+	user *models.User
+}
+
+// WithUserLoader returns a copy of ctx that loads the record of the user stored in ctx with load,
+// the first time UserFrom asks for it.
+func WithUserLoader(ctx context.Context, load UserLoader) context.Context {
+	return context.WithValue(ctx, userRecordKey{}, &userRecord{load: load})
+}
+
+// UserFrom returns the user record of the user stored in ctx. The record is loaded on first use
+// and kept for the rest of the request; ok is false when there is no user, no loader in ctx or the
+// record cannot be loaded.
+func UserFrom(ctx context.Context) (*models.User, bool) {
+	record, ok := ctx.Value(userRecordKey{}).(*userRecord)
+	if !ok {
+		return nil, false
+	}
+	uid, ok := UIDFrom(ctx)
+	if !ok {
+		return nil, false
+	}
+
+	record.once.Do(func() {
+		user, err := record.load(ctx, uid)
+		if err != nil {
+			log.Warn().Err(err).Str("uid", uid).Msg("Failed to load user record of caller")
+
+			return
+		}
+		record.user = user
+	})
+
+	return record.user, record.user != nil
+}
+
+// UIDFrom returns the Firebase UID of the user stored in ctx.
+func UIDFrom(ctx context.Context) (string, bool) {
+	token, ok := TokenFrom(ctx)
+	if !ok || token.UID == "" {
+		return "", false
+	}
+
+	return token.UID, true
+}
+
+// IsAdmin reports whether the user stored in ctx carries the admin claim.
+func IsAdmin(ctx context.Context) bool {
+	token, ok := TokenFrom(ctx)
+	if !ok {
+		return false
+	}
+	isAdmin, _ := token.Claims[AdminClaim].(bool)
+
+	return isAdmin
+}
+
+// VerifiedEmailFrom returns the email of the user stored in ctx if Firebase has verified it.
+func VerifiedEmailFrom(ctx context.Context) (string, bool) {
+	token, ok := TokenFrom(ctx)
+	if !ok {
+		return "", false
+	}
+
+	email, _ := token.Claims["email"].(string)
+	verified, _ := token.Claims["email_verified"].(bool)
+
+	return email, verified && email != ""
+}
+
+// WithSecondFactor returns a copy of ctx carrying the second factor the user signed in with.
+func WithSecondFactor(ctx context.Context, factor string) context.Context {
+	return context.WithValue(ctx, secondFactorKey{}, factor)
+}
+
+// SignedInWithMFA reports whether the user stored in ctx signed in with a second factor.
+func SignedInWithMFA(ctx context.Context) bool {
+	factor, _ := ctx.Value(secondFactorKey{}).(string)
+
+	return factor != ""
+}
+
+// WithService returns a copy of ctx carrying the verified identity of the calling service.
+func WithService(ctx context.Context, identity *idtoken.Identity) context.Context {
+	return context.WithValue(ctx, serviceKey{}, identity)
+}
+
+// ServiceFrom returns the verified identity of the calling service stored in ctx.
+func ServiceFrom(ctx context.Context) (*idtoken.Identity, bool) {
+	identity, ok := ctx.Value(serviceKey{}).(*idtoken.Identity)
+
+	return identity, ok && identity != nil
+}
@@ -0,0 +1,147 @@
+// Package clients builds the Google Cloud clients shared by the services, applying the
+// endpoint overrides needed to reach the APIs over regional or private endpoints,
+// e.g. restricted.googleapis.com inside a VPC Service Controls perimeter.
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	admin "cloud.google.com/go/firestore/apiv1/admin"
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/option"
+	spannerapi "google.golang.org/api/spanner/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/thoughtgears/shared-services/internal/warmup"
+)
+
+// warmupName is the document and object read to warm up a client, it does not need to exist.
+const warmupName = "_warmup"
+
+// Config holds the endpoint overrides for each API. An empty endpoint uses the library default.
+type Config struct {
+	// UniverseDomain replaces googleapis.com as the default domain for every API. It is only
+	// needed outside the default universe; private endpoints use the overrides below.
+	UniverseDomain string
+
+	FirestoreEndpoint      string
+	StorageEndpoint        string
+	IAMCredentialsEndpoint string
+	KMSEndpoint            string
+	LoggingEndpoint        string
+	SpannerEndpoint        string
+}
+
+// Builder creates Google Cloud clients with the configured endpoints.
+type Builder struct {
+	cfg Config
+}
+
+// NewBuilder creates a Builder for the given endpoint configuration.
+func NewBuilder(cfg Config) *Builder {
+	return &Builder{cfg: cfg}
+}
+
+// options returns the client options for an API with the given endpoint override.
+func (b *Builder) options(endpoint string) []option.ClientOption {
+	var opts []option.ClientOption
+	if b.cfg.UniverseDomain != "" {
+		opts = append(opts, option.WithUniverseDomain(b.cfg.UniverseDomain))
+	}
+	if endpoint != "" {
+		opts = append(opts, option.WithEndpoint(endpoint))
+	}
+
+	return opts
+}
+
+// Firestore creates a Firestore client for a database of the project, so environments can keep
+// their data in separate databases of one project. An empty databaseID uses the default database.
+func (b *Builder) Firestore(ctx context.Context, projectID string, databaseID string) (*firestore.Client, error) {
+	if databaseID == "" {
+		databaseID = firestore.DefaultDatabaseID
+	}
+
+	client, err := firestore.NewClientWithDatabase(ctx, projectID, databaseID, b.options(b.cfg.FirestoreEndpoint)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create firestore client: %w", err)
+	}
+
+	return client, nil
+}
+
+// FirestoreAdmin creates a client of the Firestore admin API, used for managed exports and
+// imports. It shares the endpoint of the Firestore client.
+func (b *Builder) FirestoreAdmin(ctx context.Context) (*admin.FirestoreAdminClient, error) {
+	client, err := admin.NewFirestoreAdminClient(ctx, b.options(b.cfg.FirestoreEndpoint)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create firestore admin client: %w", err)
+	}
+
+	return client, nil
+}
+
+// Storage creates a Cloud Storage client.
+func (b *Builder) Storage(ctx context.Context) (*storage.Client, error) {
+	client, err := storage.NewClient(ctx, b.options(b.cfg.StorageEndpoint)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage client: %w", err)
+	}
+
+	return client, nil
+}
+
+// Spanner creates a client of the Cloud Spanner REST API.
+func (b *Builder) Spanner(ctx context.Context) (*spannerapi.Service, error) {
+	service, err := spannerapi.NewService(ctx, b.options(b.cfg.SpannerEndpoint)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spanner client: %w", err)
+	}
+
+	return service, nil
+}
+
+// IAMCredentialsOptions returns the client options for the IAM credentials API used to sign URLs.
+func (b *Builder) IAMCredentialsOptions() []option.ClientOption {
+	return b.options(b.cfg.IAMCredentialsEndpoint)
+}
+
+// KMSOptions returns the client options for the Cloud KMS API.
+func (b *Builder) KMSOptions() []option.ClientOption {
+	return b.options(b.cfg.KMSEndpoint)
+}
+
+// LoggingOptions returns the client options for the Cloud Logging API.
+func (b *Builder) LoggingOptions() []option.ClientOption {
+	return b.options(b.cfg.LoggingEndpoint)
+}
+
+// WarmFirestore returns a warmer that reads a document with client, opening its connection and
+// fetching its credentials. The document does not need to exist.
+func WarmFirestore(client *firestore.Client) warmup.Func {
+	return func(ctx context.Context) error {
+		_, err := client.Collection(warmupName).Doc(warmupName).Get(ctx)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return fmt.Errorf("failed to reach firestore: %w", err)
+		}
+
+		return nil
+	}
+}
+
+// WarmStorage returns a warmer that reads the attributes of an object in bucket with client,
+// opening its connection and fetching its credentials. The object does not need to exist.
+func WarmStorage(client *storage.Client, bucket string) warmup.Func {
+	return func(ctx context.Context) error {
+		_, err := client.Bucket(bucket).Object(warmupName).Attrs(ctx)
+		if err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+			return fmt.Errorf("failed to reach bucket %s: %w", bucket, err)
+		}
+
+		return nil
+	}
+}
@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 type Config struct {
 	ProjectID          string `envconfig:"GCP_PROJECT_ID" required:"true"`
 	Region             string `envconfig:"GCP_REGION" required:"true"`
@@ -9,5 +11,243 @@ type Config struct {
 	ServiceName        string `envconfig:"K_SERVICE" default:"portal-api"`
 	DomainName         string `envconfig:"DOMAIN_NAME" default:"thoughtgears.co.uk"`
 	OTELEndpoint       string `envconfig:"OTEL_ENDPOINT" default:"localhost:4317"`
+	OTELInsecure       bool   `envconfig:"OTEL_INSECURE" default:"true"`
 	FirebaseSecretPath string `envconfig:"FIREBASE_SECRET_PATH" default:"/secrets/firebase-service-account.json"`
+
+	// MessageCatalogDir holds translated error messages as <language>.json files, see i18n.LoadDir.
+	// Errors are answered in English only when it is empty.
+	MessageCatalogDir string `envconfig:"MESSAGE_CATALOG_DIR"`
+
+	// FirestoreDatabaseID selects the Firestore database of the project, so staging and production
+	// can keep their data in separate databases of one project.
+	FirestoreDatabaseID string `envconfig:"FIRESTORE_DATABASE_ID" default:"(default)"`
+
+	// DatabaseBackend selects where the repositories keep their data: "firestore", "mongodb",
+	// "spanner" or another provider registered with the providers package.
+	// With "mongodb" the collections are kept in MongoDatabase of the deployment at MongoURI, which
+	// must be a replica set for batched writes. With "spanner" they are kept in tables of
+	// SpannerDatabase on SpannerInstance of the project, created on startup.
+	// Audit events and tenant exports stay on Firestore.
+	DatabaseBackend string `envconfig:"DATABASE_BACKEND" default:"firestore"`
+	MongoURI        string `envconfig:"MONGODB_URI"`
+	MongoDatabase   string `envconfig:"MONGODB_DATABASE" default:"shared_services"`
+	SpannerInstance string `envconfig:"SPANNER_INSTANCE"`
+	SpannerDatabase string `envconfig:"SPANNER_DATABASE" default:"shared-services"`
+
+	// StorageBackend selects where stored objects are kept: "gcs" or another provider registered
+	// with the providers package.
+	StorageBackend string `envconfig:"STORAGE_BACKEND" default:"gcs"`
+
+	// Resilience policy applied to every repository and storage bucket: each attempt of an operation
+	// is bounded by BackendTimeout, transient failures are retried up to BackendMaxRetries times and
+	// reads still running after BackendHedgeDelay get a second attempt. Zero disables each of them.
+	BackendTimeout    time.Duration `envconfig:"BACKEND_TIMEOUT" default:"10s"`
+	BackendMaxRetries int           `envconfig:"BACKEND_MAX_RETRIES" default:"2"`
+	BackendHedgeDelay time.Duration `envconfig:"BACKEND_HEDGE_DELAY" default:"0"`
+
+	// DocumentTypesSource selects where document type definitions are loaded from: "config" or "firestore".
+	// With "config" the definitions are read from DocumentTypesPath, or the built-in defaults if it is empty.
+	DocumentTypesSource   string        `envconfig:"DOCUMENT_TYPES_SOURCE" default:"config"`
+	DocumentTypesPath     string        `envconfig:"DOCUMENT_TYPES_PATH"`
+	DocumentTypesCacheTTL time.Duration `envconfig:"DOCUMENT_TYPES_CACHE_TTL" default:"5m"`
+
+	// DocumentMetadataFilterKeys is the allow-list of metadata keys documents can be filtered on.
+	// Each key used in a filter needs a matching Firestore index.
+	DocumentMetadataFilterKeys []string `envconfig:"DOCUMENT_METADATA_FILTER_KEYS" default:"external_id,reference"`
+
+	// AddressValidationMode enables address validation on user writes: "off", "basic" (offline ISO/postcode checks)
+	// or "google" (Google Address Validation API, requires AddressValidationAPIKey).
+	AddressValidationMode   string `envconfig:"ADDRESS_VALIDATION_MODE" default:"off"`
+	AddressValidationAPIKey string `envconfig:"ADDRESS_VALIDATION_API_KEY"`
+	AddressStoreCoordinates bool   `envconfig:"ADDRESS_STORE_COORDINATES" default:"false"`
+
+	// DefaultPhoneRegion is used to parse national phone numbers for users without an address country.
+	DefaultPhoneRegion string `envconfig:"DEFAULT_PHONE_REGION" default:"GB"`
+
+	// FieldEncryptionKMSKey is the Cloud KMS key used to wrap the data keys for field level encryption
+	// of PII, e.g. projects/p/locations/l/keyRings/r/cryptoKeys/k. Encryption is disabled when empty.
+	FieldEncryptionKMSKey      string        `envconfig:"FIELD_ENCRYPTION_KMS_KEY"`
+	FieldEncryptionKeyRotation time.Duration `envconfig:"FIELD_ENCRYPTION_KEY_ROTATION" default:"24h"`
+
+	// SigningServiceAccount is the service account used to sign download URLs through the IAM
+	// credentials API. When empty the runtime service account is detected from the metadata server.
+	SigningServiceAccount string        `envconfig:"SIGNING_SERVICE_ACCOUNT"`
+	SignedURLTTL          time.Duration `envconfig:"SIGNED_URL_TTL" default:"15m"`
+
+	// AuditSinks lists where audit events for mutating operations are written: "firestore" and/or "logging".
+	// Auditing is disabled when empty.
+	AuditSinks      []string `envconfig:"AUDIT_SINKS" default:"firestore,logging"`
+	AuditCollection string   `envconfig:"AUDIT_COLLECTION" default:"audit_logs"`
+	AuditLogID      string   `envconfig:"AUDIT_LOG_ID" default:"audit"`
+
+	// CSRFEnabled turns on double-submit token CSRF protection for browser clients using session cookies.
+	// CSRFRouteGroups limits it to the listed route group prefixes, all routes are protected when empty.
+	CSRFEnabled     bool     `envconfig:"CSRF_ENABLED" default:"false"`
+	CSRFRouteGroups []string `envconfig:"CSRF_ROUTE_GROUPS" default:"/v1/users,/v1/documents"`
+
+	// AdminAllowedCIDRs restricts the admin route group, and the webhook receivers when
+	// WebhookIPAllowlist is set, to the listed CIDR ranges. No restriction is applied when empty.
+	// Outside local mode the service refuses to start with ranges but no TrustedProxies or TrustedPlatform.
+	AdminAllowedCIDRs  []string `envconfig:"ADMIN_ALLOWED_CIDRS"`
+	WebhookIPAllowlist bool     `envconfig:"WEBHOOK_IP_ALLOWLIST" default:"false"`
+
+	// WebhookReplayWindow is how far the signing time of a webhook delivery may be from its
+	// arrival, deliveries are remembered for as long to reject replays.
+	WebhookReplayWindow time.Duration `envconfig:"WEBHOOK_REPLAY_WINDOW" default:"5m"`
+
+	// Per-user rate limits keyed on the verified Firebase UID, in requests per second with a burst size.
+	// Upload limits apply to every request that changes data, read limits to GET and HEAD requests.
+	UserRateLimitEnabled     bool          `envconfig:"USER_RATE_LIMIT_ENABLED" default:"true"`
+	UserRateLimitReadRate    float64       `envconfig:"USER_RATE_LIMIT_READ_RATE" default:"10"`
+	UserRateLimitReadBurst   int           `envconfig:"USER_RATE_LIMIT_READ_BURST" default:"40"`
+	UserRateLimitUploadRate  float64       `envconfig:"USER_RATE_LIMIT_UPLOAD_RATE" default:"0.5"`
+	UserRateLimitUploadBurst int           `envconfig:"USER_RATE_LIMIT_UPLOAD_BURST" default:"10"`
+	UserRateLimitIdleTTL     time.Duration `envconfig:"USER_RATE_LIMIT_IDLE_TTL" default:"10m"`
+
+	// At most UploadMaxConcurrent uploads are handled at once per instance, others wait up to
+	// UploadQueueTimeout for a slot and are then rejected with 503, asking the client to retry after
+	// UploadRetryAfter. Uploads are not limited when UploadMaxConcurrent is 0.
+	UploadMaxConcurrent int           `envconfig:"UPLOAD_MAX_CONCURRENT" default:"8"`
+	UploadQueueTimeout  time.Duration `envconfig:"UPLOAD_QUEUE_TIMEOUT" default:"2s"`
+	UploadRetryAfter    time.Duration `envconfig:"UPLOAD_RETRY_AFTER" default:"5s"`
+
+	// Documents untouched for TieringAfter are moved to TieringStorageClass, NEARLINE, COLDLINE or
+	// ARCHIVE, at most TieringMaxDocuments per run of the admin tiering route. Tiering is disabled
+	// when TieringAfter is 0. Cold documents are moved back to STANDARD when they are requested
+	// unless TieringRestoreOnAccess is false.
+	TieringAfter           time.Duration `envconfig:"TIERING_AFTER" default:"0"`
+	TieringStorageClass    string        `envconfig:"TIERING_STORAGE_CLASS" default:"COLDLINE"`
+	TieringMaxDocuments    int           `envconfig:"TIERING_MAX_DOCUMENTS" default:"500"`
+	TieringRestoreOnAccess bool          `envconfig:"TIERING_RESTORE_ON_ACCESS" default:"true"`
+
+	// AuthLockoutThreshold failed token verifications from one IP within AuthLockoutWindow block it
+	// for AuthLockoutDuration. Lockout is disabled when the threshold is 0, the default; enabling it
+	// needs TrustedProxies or TrustedPlatform outside local mode.
+	AuthLockoutThreshold int           `envconfig:"AUTH_LOCKOUT_THRESHOLD" default:"0"`
+	AuthLockoutWindow    time.Duration `envconfig:"AUTH_LOCKOUT_WINDOW" default:"5m"`
+	AuthLockoutDuration  time.Duration `envconfig:"AUTH_LOCKOUT_DURATION" default:"15m"`
+
+	// TrustedProxies are the addresses or CIDR ranges of the proxies whose X-Forwarded-For is trusted
+	// to carry the client address, e.g. 169.254.0.0/16 for the Cloud Run front end, plus the ranges
+	// of a load balancer in front of it. TrustedPlatform is a header the platform sets to the client
+	// address instead, e.g. a custom header of the load balancer set from {client_ip_address}.
+	// Without either every request appears to come from the proxy. Services behind the gateway
+	// must list the gateway's addresses, it forwards the client address it resolved.
+	TrustedProxies  []string `envconfig:"TRUSTED_PROXIES"`
+	TrustedPlatform string   `envconfig:"TRUSTED_PLATFORM"`
+
+	// MFARequired makes the sensitive MFARequiredRoutes, given as "METHOD /route", require a token
+	// from a multi-factor sign-in. Other tokens get 403 with the mfa_required error code.
+	MFARequired       bool     `envconfig:"MFA_REQUIRED" default:"false"`
+	MFARequiredRoutes []string `envconfig:"MFA_REQUIRED_ROUTES" default:"GET /v1/documents/:id/download-url,GET /v1/documents/:id/content"`
+
+	// Endpoint overrides for the Google Cloud clients, used to reach the APIs through regional or
+	// private endpoints in VPC Service Controls constrained environments. Empty values use the defaults.
+	GoogleUniverseDomain   string `envconfig:"GOOGLE_UNIVERSE_DOMAIN"`
+	FirestoreEndpoint      string `envconfig:"FIRESTORE_ENDPOINT"`
+	StorageEndpoint        string `envconfig:"STORAGE_ENDPOINT"`
+	IAMCredentialsEndpoint string `envconfig:"IAM_CREDENTIALS_ENDPOINT"`
+	KMSEndpoint            string `envconfig:"KMS_ENDPOINT"`
+	LoggingEndpoint        string `envconfig:"LOGGING_ENDPOINT"`
+	SpannerEndpoint        string `envconfig:"SPANNER_ENDPOINT"`
+
+	// ResidencyBuckets adds storage buckets for regions other than the deployment region, e.g. "us-east1:docs-us".
+	// ResidencyCountryRegions maps ISO country codes to those regions, e.g. "US:us-east1,CA:us-east1".
+	// Users in unmapped countries keep their documents in GCP_REGION, in GCP_BUCKET_NAME.
+	ResidencyBuckets        map[string]string `envconfig:"RESIDENCY_BUCKETS"`
+	ResidencyCountryRegions map[string]string `envconfig:"RESIDENCY_COUNTRY_REGIONS"`
+
+	// TenancyEnabled scopes users, documents and stored objects to the tenant of each request.
+	// The tenant is the Identity Platform tenant of the token, or TenantHosts maps the request host
+	// to a tenant, e.g. "acme.thoughtgears.dev:acme".
+	TenancyEnabled bool              `envconfig:"TENANCY_ENABLED" default:"false"`
+	TenantHosts    map[string]string `envconfig:"TENANT_HOSTS"`
+
+	// InvitationSigningKey signs organization invitation tokens, it must be at least 32 bytes.
+	// Invitations are disabled when it is empty, locally a random key is generated instead, so
+	// tokens do not survive restarts.
+	// InvitationAcceptURL is the frontend page invitees are sent to with the token.
+	InvitationSigningKey string        `envconfig:"INVITATION_SIGNING_KEY"`
+	InvitationAcceptURL  string        `envconfig:"INVITATION_ACCEPT_URL" default:"https://thoughtgears.dev/invitations/accept"`
+	InvitationTTL        time.Duration `envconfig:"INVITATION_TTL" default:"168h"`
+
+	// MailMode selects how emails are sent: "log" writes them to the log, "smtp" sends them through SMTPAddr.
+	MailMode     string `envconfig:"MAIL_MODE" default:"log"`
+	MailFrom     string `envconfig:"MAIL_FROM" default:"no-reply@thoughtgears.dev"`
+	SMTPAddr     string `envconfig:"SMTP_ADDR"`
+	SMTPUsername string `envconfig:"SMTP_USERNAME"`
+	SMTPPassword string `envconfig:"SMTP_PASSWORD"`
+
+	// MailBrandName and MailBrandURL brand the account emails, e.g. password resets.
+	MailBrandName string `envconfig:"MAIL_BRAND_NAME" default:"Thoughtgears"`
+	MailBrandURL  string `envconfig:"MAIL_BRAND_URL" default:"https://thoughtgears.dev"`
+
+	// PasswordResetContinueURL is where users are sent once they have chosen a new password.
+	// EmailChangeConfirmURL is the frontend page email change links open with the token.
+	// EmailChangeSigningKey signs those tokens, at least 32 bytes and not the invitation key; email
+	// changes are disabled when it is empty, locally a random key is generated like for invitations.
+	PasswordResetContinueURL string        `envconfig:"PASSWORD_RESET_CONTINUE_URL" default:"https://thoughtgears.dev/login"`
+	EmailChangeSigningKey    string        `envconfig:"EMAIL_CHANGE_SIGNING_KEY"`
+	EmailChangeConfirmURL    string        `envconfig:"EMAIL_CHANGE_CONFIRM_URL" default:"https://thoughtgears.dev/account/email-change/confirm"`
+	EmailChangeTTL           time.Duration `envconfig:"EMAIL_CHANGE_TTL" default:"24h"`
+
+	// SessionRevocationCacheTTL is how long the session revocation of a user is cached, so a
+	// revocation on one instance reaches the others within it.
+	SessionRevocationCacheTTL time.Duration `envconfig:"SESSION_REVOCATION_CACHE_TTL" default:"30s"`
+
+	// PushMode selects how push notifications are sent: "log" writes them to the log, "fcm" sends them
+	// through Firebase Cloud Messaging to the topic of each user.
+	PushMode string `envconfig:"PUSH_MODE" default:"log"`
+
+	// TenantSettingsCacheTTL is how long tenant settings are cached before they are reloaded.
+	TenantSettingsCacheTTL time.Duration `envconfig:"TENANT_SETTINGS_CACHE_TTL" default:"1m"`
+
+	// TelemetryMaxTenants caps the distinct tenants labelled on metrics, later tenants are reported as "other".
+	TelemetryMaxTenants int `envconfig:"TELEMETRY_MAX_TENANTS" default:"200"`
+
+	// TenantExportBucket receives tenant offboarding exports under TenantExportPrefix,
+	// it defaults to GCP_BUCKET_NAME when empty.
+	TenantExportBucket string `envconfig:"TENANT_EXPORT_BUCKET"`
+	TenantExportPrefix string `envconfig:"TENANT_EXPORT_PREFIX" default:"exports"`
+
+	// BackupBucket receives backups of the BackupCollections under BackupPrefix, written when the
+	// backup route is called by a scheduled job. BackupMode is "jsonl" for a JSON Lines file per
+	// collection or "managed" for a managed Firestore export. Backups are disabled when the bucket
+	// is empty, and need the Firestore backend.
+	BackupBucket      string   `envconfig:"BACKUP_BUCKET"`
+	BackupPrefix      string   `envconfig:"BACKUP_PREFIX" default:"backups"`
+	BackupCollections []string `envconfig:"BACKUP_COLLECTIONS" default:"users,documents"`
+	BackupMode        string   `envconfig:"BACKUP_MODE" default:"jsonl"`
+
+	// ImportBucket holds the manifests and files of bulk imports, see cmd/import. The admin import
+	// route is only offered when it is set.
+	ImportBucket string `envconfig:"IMPORT_BUCKET"`
+
+	// ServiceAuthAudiences and ServiceAuthAllowedCallers enable Google ID token authentication
+	// for calls from other services, e.g. the service URL and the workers' service account emails.
+	ServiceAuthAudiences      []string `envconfig:"SERVICE_AUTH_AUDIENCES"`
+	ServiceAuthAllowedCallers []string `envconfig:"SERVICE_AUTH_ALLOWED_CALLERS"`
+
+	// ErrorReportingEnabled reports 5xx responses and recovered panics to Cloud Error Reporting.
+	// ServiceVersion is the version shown on reported errors.
+	ErrorReportingEnabled bool   `envconfig:"ERROR_REPORTING_ENABLED" default:"false"`
+	ServiceVersion        string `envconfig:"K_REVISION" default:"local"`
+
+	// TraceSampleRate is the fraction of requests traced. TraceRouteSampleRates overrides it for
+	// single routes as "METHOD /route=rate" pairs, so low-volume critical routes are always traced.
+	TraceSampleRate       float64  `envconfig:"TRACE_SAMPLE_RATE" default:"0.1"`
+	TraceRouteSampleRates []string `envconfig:"TRACE_ROUTE_SAMPLE_RATES" default:"POST /v1/users=1,POST /v1/documents=1"`
+
+	// SLOLatencyThreshold is the response time under which a request counts as good for the latency SLI.
+	// SLOGroupLatencyThresholds overrides it per route group as "/group=duration" pairs.
+	SLOLatencyThreshold       time.Duration `envconfig:"SLO_LATENCY_THRESHOLD" default:"500ms"`
+	SLOGroupLatencyThresholds []string      `envconfig:"SLO_GROUP_LATENCY_THRESHOLDS" default:"/v1/documents=2s"`
+
+	// DebugCaptureSize is the number of failing requests kept in memory while debug capture is
+	// enabled through the admin API. Zero disables the feature.
+	DebugCaptureSize int `envconfig:"DEBUG_CAPTURE_SIZE" default:"200"`
+
+	// TraceExporters is the pipeline spans are sent to, any of "otlp" (the collector at OTELEndpoint),
+	// "stdout" and "cloudtrace". It defaults to stdout in local mode and the collector otherwise.
+	TraceExporters []string `envconfig:"TRACE_EXPORTERS"`
 }
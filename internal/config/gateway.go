@@ -0,0 +1,49 @@
+package config
+
+import "time"
+
+// GatewayConfig configures the gateway binary, which serves the public API surface on one host
+// and proxies each path prefix to the independently deployed service behind it.
+type GatewayConfig struct {
+	Local              bool   `envconfig:"LOCAL" default:"false"`
+	Port               string `envconfig:"PORT" default:"8080"`
+	ServiceName        string `envconfig:"K_SERVICE" default:"gateway"`
+	DomainName         string `envconfig:"DOMAIN_NAME" default:"thoughtgears.co.uk"`
+	OTELEndpoint       string `envconfig:"OTEL_ENDPOINT" default:"localhost:4317"`
+	OTELInsecure       bool   `envconfig:"OTEL_INSECURE" default:"true"`
+	FirebaseSecretPath string `envconfig:"FIREBASE_SECRET_PATH" default:"/secrets/firebase-service-account.json"`
+
+	// MessageCatalogDir holds translated error messages as <language>.json files, see i18n.LoadDir.
+	// Errors are answered in English only when it is empty.
+	MessageCatalogDir string `envconfig:"MESSAGE_CATALOG_DIR"`
+
+	// Routes maps path prefixes to upstream services as prefix=url pairs,
+	// e.g. "/v1/users=https://user-api.a.run.app,/v1/documents=https://document-api.a.run.app".
+	// PublicRoutes are prefixes proxied without authentication.
+	Routes       []string `envconfig:"GATEWAY_ROUTES" required:"true"`
+	PublicRoutes []string `envconfig:"GATEWAY_PUBLIC_ROUTES" default:"/schemas"`
+
+	// AuthLockoutThreshold failed token verifications from one IP within AuthLockoutWindow block it
+	// for AuthLockoutDuration. Lockout is disabled when the threshold is 0, the default; enabling it
+	// needs TrustedProxies or TrustedPlatform outside local mode.
+	AuthLockoutThreshold int           `envconfig:"AUTH_LOCKOUT_THRESHOLD" default:"0"`
+	AuthLockoutWindow    time.Duration `envconfig:"AUTH_LOCKOUT_WINDOW" default:"5m"`
+	AuthLockoutDuration  time.Duration `envconfig:"AUTH_LOCKOUT_DURATION" default:"15m"`
+
+	// TrustedProxies are the addresses or CIDR ranges of the proxies whose X-Forwarded-For is trusted
+	// to carry the client address, e.g. 169.254.0.0/16 for the Cloud Run front end, plus the ranges
+	// of a load balancer in front of it. TrustedPlatform is a header the platform sets to the client
+	// address instead, e.g. a custom header of the load balancer set from {client_ip_address}.
+	// Without either every request appears to come from the proxy.
+	TrustedProxies  []string `envconfig:"TRUSTED_PROXIES"`
+	TrustedPlatform string   `envconfig:"TRUSTED_PLATFORM"`
+
+	// UpstreamAuth sends a Google ID token for each upstream in X-Serverless-Authorization,
+	// so the services can require IAM authentication while still receiving the user's token.
+	UpstreamAuth bool `envconfig:"GATEWAY_UPSTREAM_AUTH" default:"false"`
+
+	// ProjectID is only needed to export spans to Cloud Trace.
+	// TraceExporters is the span pipeline, see Config.TraceExporters.
+	ProjectID      string   `envconfig:"GCP_PROJECT_ID"`
+	TraceExporters []string `envconfig:"TRACE_EXPORTERS"`
+}
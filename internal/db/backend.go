@@ -0,0 +1,71 @@
+package db
+
+import (
+	"cloud.google.com/go/firestore"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Backend is the database repositories are created on, either Firestore, MongoDB or Cloud Spanner.
+type Backend struct {
+	firestore *firestore.Client
+	mongo     *mongo.Database
+	spanner   *SpannerClient
+}
+
+// NewFirestoreBackend creates a Backend keeping collections in the Firestore database of client.
+func NewFirestoreBackend(client *firestore.Client) *Backend {
+	return &Backend{firestore: client}
+}
+
+// NewMongoBackend creates a Backend keeping collections in a MongoDB database.
+func NewMongoBackend(database *mongo.Database) *Backend {
+	return &Backend{mongo: database}
+}
+
+// NewSpannerBackend creates a Backend keeping collections in the tables of a Cloud Spanner database.
+func NewSpannerBackend(client *SpannerClient) *Backend {
+	return &Backend{spanner: client}
+}
+
+// Snapshotter returns a Snapshotter for the backend. Only Firestore snapshots are supported, so
+// it is nil on MongoDB and Spanner and reads run without one.
+func (b *Backend) Snapshotter() *Snapshotter {
+	if b.firestore == nil {
+		return nil
+	}
+
+	return NewSnapshotter(b.firestore)
+}
+
+// NewRepository creates a DB for the collection on the backend.
+//
+// Parameters:
+//   - backend: Backend holding the collection
+//   - collectionName: Name of the collection
+//   - opts: Optional repository options
+//
+// Returns:
+//   - DB[T]: A repository for the specified type
+func NewRepository[T any](backend *Backend, collectionName string, opts ...RepositoryOption) DB[T] {
+	if backend.mongo != nil {
+		return NewMongoRepository[T](backend.mongo, collectionName, opts...)
+	}
+	if backend.spanner != nil {
+		return NewSpannerRepository[T](backend.spanner, collectionName, opts...)
+	}
+
+	return NewFirestoreRepository[T](backend.firestore, collectionName, opts...)
+}
+
+// NewTenantScopedBackendRepository creates a DB for the collection on the backend that scopes
+// it to the tenant in the context, see NewTenantScopedRepository.
+func NewTenantScopedBackendRepository[T any](backend *Backend, collectionName string, opts ...RepositoryOption) DB[T] {
+	if backend.mongo != nil {
+		return NewTenantScopedMongoRepository[T](backend.mongo, collectionName, opts...)
+	}
+	if backend.spanner != nil {
+		return NewTenantScopedSpannerRepository[T](backend.spanner, collectionName, opts...)
+	}
+
+	return NewTenantScopedRepository[T](backend.firestore, collectionName, opts...)
+}
@@ -0,0 +1,193 @@
+package db
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// UpdatedAtField is the field holding the time a document was last written. Change streams
+// started at a time only see documents with the field.
+const UpdatedAtField = "updated_at"
+
+// ChangeKind is what happened to a document.
+type ChangeKind string
+
+const (
+	// ChangeSet is a document that was created or updated.
+	ChangeSet ChangeKind = "set"
+	// ChangeDeleted is a document that was deleted.
+	ChangeDeleted ChangeKind = "deleted"
+)
+
+// Change is a change to a document of a collection. Data is the document after the change,
+// it is nil for deleted documents.
+type Change[T any] struct {
+	Kind ChangeKind
+	ID   string
+	Data *T
+	Time time.Time
+}
+
+// ChangeStream is a stream of changes to a collection, as returned by DB.Changes.
+// Next blocks until the next change and returns the error that ended the stream once it is
+// over, iterator.Done after Stop. Stop must be called when the stream is no longer used.
+type ChangeStream[T any] interface {
+	Next() (*Change[T], error)
+	Stop()
+}
+
+// Changes streams the changes to the collection, starting with every document written after
+// since, or every document for a zero since, as ChangeSet changes. Deletions are only seen while
+// the stream runs. The stream is backed by a Firestore snapshot listener, whose changes arrive
+// in batches; a listener re-reads every matching document when it restarts, so consumers must
+// handle the same change more than once.
+//
+// Parameters:
+//   - ctx: Context for the listener, the stream ends when it is done
+//   - since: Time after which written documents are streamed first
+//
+// Returns:
+//   - ChangeStream[T]: The stream of changes
+//   - error: Any error encountered while starting the stream
+func (r *firestoreRepository[T]) Changes(ctx context.Context, since time.Time) (ChangeStream[T], error) {
+	query := r.client.Collection(r.collectionName).Query
+	if !since.IsZero() {
+		query = query.Where(UpdatedAtField, ">", since)
+	}
+
+	return &firestoreChangeStream[T]{
+		ctx:       ctx,
+		repo:      r,
+		snapshots: query.Snapshots(ctx),
+	}, nil
+}
+
+// firestoreChangeStream is a ChangeStream over the snapshots of a Firestore query.
+type firestoreChangeStream[T any] struct {
+	ctx       context.Context
+	repo      *firestoreRepository[T]
+	snapshots *firestore.QuerySnapshotIterator
+	pending   []firestore.DocumentChange
+	readTime  time.Time
+}
+
+// Next returns the next change, waiting for the next snapshot when the last one is used up.
+func (s *firestoreChangeStream[T]) Next() (*Change[T], error) {
+	for len(s.pending) == 0 {
+		snapshot, err := s.snapshots.Next()
+		if err != nil {
+			return nil, err
+		}
+		s.pending = snapshot.Changes
+		s.readTime = snapshot.ReadTime
+	}
+
+	change := s.pending[0]
+	s.pending = s.pending[1:]
+
+	if change.Kind == firestore.DocumentRemoved {
+		return &Change[T]{Kind: ChangeDeleted, ID: change.Doc.Ref.ID, Time: s.readTime}, nil
+	}
+
+	data, err := s.repo.decode(s.ctx, change.Doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Change[T]{Kind: ChangeSet, ID: change.Doc.Ref.ID, Data: data, Time: change.Doc.UpdateTime}, nil
+}
+
+// Stop stops the listener.
+func (s *firestoreChangeStream[T]) Stop() {
+	s.snapshots.Stop()
+}
+
+// ChangeLog is an in-memory source of changes, for code that consumes change streams without a
+// Firestore listener, e.g. in tests. Every recorded change is kept, so it is not meant for
+// long-running processes.
+type ChangeLog[T any] struct {
+	mu      sync.Mutex
+	changes []*Change[T]
+	// recorded is closed and replaced when a change is recorded, to wake up waiting streams
+	recorded chan struct{}
+}
+
+// NewChangeLog creates an empty ChangeLog.
+func NewChangeLog[T any]() *ChangeLog[T] {
+	return &ChangeLog[T]{recorded: make(chan struct{})}
+}
+
+// Record adds a change to the log, a zero Time is set to now.
+func (l *ChangeLog[T]) Record(change Change[T]) {
+	if change.Time.IsZero() {
+		change.Time = time.Now().UTC()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.changes = append(l.changes, &change)
+	close(l.recorded)
+	l.recorded = make(chan struct{})
+}
+
+// Changes streams the recorded changes made after since, then every change recorded later.
+func (l *ChangeLog[T]) Changes(ctx context.Context, since time.Time) (ChangeStream[T], error) {
+	return &changeLogStream[T]{
+		ctx:     ctx,
+		log:     l,
+		since:   since,
+		stopped: make(chan struct{}),
+	}, nil
+}
+
+// changeLogStream is a ChangeStream reading a ChangeLog from its start.
+type changeLogStream[T any] struct {
+	ctx      context.Context
+	log      *ChangeLog[T]
+	since    time.Time
+	next     int
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// Next returns the next change after since, waiting for one to be recorded.
+func (s *changeLogStream[T]) Next() (*Change[T], error) {
+	for {
+		select {
+		case <-s.stopped:
+			return nil, iterator.Done
+		default:
+		}
+
+		s.log.mu.Lock()
+		for s.next < len(s.log.changes) {
+			change := *s.log.changes[s.next]
+			s.next++
+			if change.Time.After(s.since) {
+				s.log.mu.Unlock()
+
+				return &change, nil
+			}
+		}
+		recorded := s.log.recorded
+		s.log.mu.Unlock()
+
+		select {
+		case <-recorded:
+		case <-s.stopped:
+			return nil, iterator.Done
+		case <-s.ctx.Done():
+			return nil, s.ctx.Err()
+		}
+	}
+}
+
+// Stop ends the stream, a waiting Next returns iterator.Done.
+func (s *changeLogStream[T]) Stop() {
+	s.stopOnce.Do(func() { close(s.stopped) })
+}
@@ -2,15 +2,30 @@ package db
 
 import (
 	"context"
+	"time"
+
+	"github.com/thoughtgears/shared-services/pkg/apperr"
 )
 
+// ErrChanged is returned by UpdateIfUnchanged when the document was changed or deleted since it
+// was read.
+var ErrChanged = apperr.New(apperr.Conflict, "document changed since it was read")
+
 // DB defines a generic data access interface for any type T.
 // It provides standard CRUD operations and query capabilities with pagination support.
+// Reads made with the context of a Snapshotter.ReadOnly transaction on the repository's client
+// read from its snapshot, and writes fail with ErrReadOnly.
 type DB[T any] interface {
 	GetAll(ctx context.Context, pageToken string, pageSize int) ([]*T, string, error)
 	GetByID(ctx context.Context, id string) (*T, error)
 	GetByQuery(ctx context.Context, queries []QueryConstraint, pageToken string, pageSize int) ([]*T, string, error)
+	Find(ctx context.Context, query *QueryBuilder[T]) ([]*T, string, error)
+	FindPage(ctx context.Context, query *QueryBuilder[T]) (*Page[T], error)
 	Create(ctx context.Context, id string, data map[string]interface{}) (*T, error)
+	PrepareCreate(ctx context.Context, id string, data map[string]interface{}) (Write, error)
 	Update(ctx context.Context, id string, data map[string]interface{}) (*T, error)
+	UpdateIfUnchanged(ctx context.Context, id string, updatedAt time.Time, data map[string]interface{}) (*T, error)
 	Delete(ctx context.Context, id string) error
+	Count(ctx context.Context, queries []QueryConstraint) (int64, error)
+	Changes(ctx context.Context, since time.Time) (ChangeStream[T], error)
 }
@@ -4,11 +4,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
+	"slices"
+	"time"
 
 	"cloud.google.com/go/firestore"
+	firestorepb "cloud.google.com/go/firestore/apiv1/firestorepb"
 	"google.golang.org/api/iterator"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+
+	"github.com/thoughtgears/shared-services/internal/fieldcrypt"
+	"github.com/thoughtgears/shared-services/pkg/apperr"
 )
 
 type QueryOperator string
@@ -36,6 +43,26 @@ const (
 	QueryOperatorNotIn QueryOperator = "not-in"
 )
 
+// QueryOperators are the operators Firestore supports, constraints with any other operator are
+// rejected before a query is sent.
+var QueryOperators = []QueryOperator{
+	QueryOperatorEqual,
+	QueryOperatorNotEqual,
+	QueryOperatorLessThan,
+	QueryOperatorLessThanOrEqual,
+	QueryOperatorGreaterThan,
+	QueryOperatorGreaterThanOrEqual,
+	QueryOperatorIn,
+	QueryOperatorNotIn,
+	QueryOperatorArrayContains,
+	QueryOperatorArrayContainsAny,
+}
+
+// Valid reports whether the operator is one of QueryOperators.
+func (op QueryOperator) Valid() bool {
+	return slices.Contains(QueryOperators, op)
+}
+
 // QueryConstraint represents a Firestore query condition used to filter documents.
 // It maps directly to Firestore's Where() method parameters.
 type QueryConstraint struct {
@@ -49,6 +76,39 @@ type QueryConstraint struct {
 type firestoreRepository[T any] struct {
 	client         *firestore.Client
 	collectionName string
+	migrations     *MigrationRegistry
+	cipher         *fieldcrypt.Cipher
+	plaintextPaths []string
+	totals         totalCache
+}
+
+// RepositoryOption configures optional behaviour of a repository.
+type RepositoryOption func(*repositoryOptions)
+
+type repositoryOptions struct {
+	migrations     *MigrationRegistry
+	cipher         *fieldcrypt.Cipher
+	plaintextPaths []string
+}
+
+// WithMigrations enables schema versioning for the repository.
+// Documents are upgraded to the latest schema version when read and written back,
+// and new documents are stamped with the latest version.
+func WithMigrations(migrations *MigrationRegistry) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.migrations = migrations
+	}
+}
+
+// WithFieldEncryption encrypts the fields of T tagged with `encrypt:"true"` before they are
+// written and decrypts them after they are read. plaintextPaths lists dotted field paths
+// inside encrypted fields that must stay in plaintext because they are queried on.
+// A nil cipher leaves encryption disabled.
+func WithFieldEncryption(cipher *fieldcrypt.Cipher, plaintextPaths ...string) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.cipher = cipher
+		o.plaintextPaths = plaintextPaths
+	}
 }
 
 // NewFirestoreRepository creates a new instance of firestoreRepository for a specific type.
@@ -57,13 +117,22 @@ type firestoreRepository[T any] struct {
 // Parameters:
 //   - client: Initialized Firestore client
 //   - collectionName: Name of the Firestore collection where data will be stored
+//   - opts: Optional repository options, e.g. WithMigrations
 //
 // Returns:
 //   - Repository[T]: A repository instance for the specified type
-func NewFirestoreRepository[T any](client *firestore.Client, collectionName string) DB[T] {
+func NewFirestoreRepository[T any](client *firestore.Client, collectionName string, opts ...RepositoryOption) DB[T] {
+	var options repositoryOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	return &firestoreRepository[T]{
 		client:         client,
 		collectionName: collectionName,
+		migrations:     options.migrations,
+		cipher:         options.cipher,
+		plaintextPaths: options.plaintextPaths,
 	}
 }
 
@@ -87,7 +156,7 @@ func (r *firestoreRepository[T]) GetAll(ctx context.Context, pageToken string, p
 		query = query.Limit(pageSize)
 	}
 
-	iter := query.Documents(ctx)
+	iter := r.documents(ctx, query)
 	defer iter.Stop()
 
 	var results []*T
@@ -100,12 +169,12 @@ func (r *firestoreRepository[T]) GetAll(ctx context.Context, pageToken string, p
 		if err != nil {
 			return nil, "", fmt.Errorf("failed to iterate documents: %w", err)
 		}
-		var data T
-		if err := doc.DataTo(&data); err != nil {
-			return nil, "", fmt.Errorf("failed to convert document data: %w", err)
+		data, err := r.decode(ctx, doc)
+		if err != nil {
+			return nil, "", err
 		}
 
-		results = append(results, &data)
+		results = append(results, data)
 		lastDocID = doc.Ref.ID // Store the ID of the last successfully processed doc
 	}
 
@@ -130,28 +199,24 @@ func (r *firestoreRepository[T]) GetAll(ctx context.Context, pageToken string, p
 //   - *T: Document data
 //   - error: NotFound error or any other error encountered
 func (r *firestoreRepository[T]) GetByID(ctx context.Context, id string) (*T, error) {
-	doc, err := r.client.Collection(r.collectionName).Doc(id).Get(ctx)
+	doc, err := r.get(ctx, r.client.Collection(r.collectionName).Doc(id))
 	if err != nil {
 		if status.Code(err) == codes.NotFound {
-			return nil, fmt.Errorf("document with id %s not found: %w", id, err) // Consider a specific ErrNotFound
+			return nil, apperr.Errorf(apperr.NotFound, "document with id %s not found: %w", id, err)
 		}
 
 		return nil, fmt.Errorf("failed to get document %s: %w", id, err)
 	}
 	if !doc.Exists() { // Should be caught by the error check above, but good practice
-		return nil, fmt.Errorf("document with id %s not found (exists=false)", id)
+		return nil, apperr.Errorf(apperr.NotFound, "document with id %s not found (exists=false)", id)
 	}
 
-	var result T
-	if err := doc.DataTo(&result); err != nil {
-		return nil, fmt.Errorf("failed to convert document data: %w", err)
-	}
-
-	return &result, nil
+	return r.decode(ctx, doc)
 }
 
 // GetByQuery retrieves documents matching the specified query constraints with optional pagination.
-// Multiple constraints are combined with logical AND.
+// Multiple constraints are combined with logical AND. The constraints are validated like the
+// filters of a QueryBuilder before the query is sent to Firestore.
 //
 // Parameters:
 //   - ctx: Context for the database operation
@@ -164,32 +229,43 @@ func (r *firestoreRepository[T]) GetByID(ctx context.Context, id string) (*T, er
 //   - string: Token for retrieving the next page
 //   - error: Any error encountered during the operation
 func (r *firestoreRepository[T]) GetByQuery(ctx context.Context, queries []QueryConstraint, pageToken string, pageSize int) ([]*T, string, error) {
-	fsQuery := r.client.Collection(r.collectionName).Query
-	for _, q := range queries {
-		fsQuery = fsQuery.Where(q.Path, string(q.Op), q.Value)
+	return r.Find(ctx, queryOf[T](queries).After(pageToken).Limit(max(pageSize, 0)))
+}
+
+// Find retrieves the documents matching a query built with Query, see QueryBuilder.
+// The query is validated before it is sent to Firestore.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - query: The query, including its page token and limit
+//
+// Returns:
+//   - []*T: Slice of document data matching the query
+//   - string: Token for retrieving the next page, empty when the query has no limit or no more pages
+//   - error: Any error encountered during the operation
+func (r *firestoreRepository[T]) Find(ctx context.Context, query *QueryBuilder[T]) ([]*T, string, error) {
+	if err := query.Err(); err != nil {
+		return nil, "", err
 	}
 
-	// Add ordering for consistent pagination if not already specified in queries
-	// Note: Firestore requires the first OrderBy field to match the first range/inequality filter field if present.
-	// This simple implementation assumes DocumentID ordering is sufficient or that queries include ordering.
-	// A more robust implementation might need smarter OrderBy logic based on query constraints.
-	fsQuery = fsQuery.OrderBy(firestore.DocumentID, firestore.Asc)
+	// Documents that sort the same are ordered by document ID, for consistent pagination
+	fsQuery := query.compile(r.client.Collection(r.collectionName).Query)
 
-	if pageToken != "" {
+	if query.pageToken != "" {
 		// Fetch the document snapshot for the page token to use StartAfter
 		// This requires an extra read but is the standard way for non-cursor pagination
-		docSnapshot, err := r.client.Collection(r.collectionName).Doc(pageToken).Get(ctx)
+		docSnapshot, err := r.get(ctx, r.client.Collection(r.collectionName).Doc(query.pageToken))
 		if err != nil {
-			return nil, "", fmt.Errorf("failed to get page token document %s: %w", pageToken, err)
+			return nil, "", fmt.Errorf("failed to get page token document %s: %w", query.pageToken, err)
 		}
 		fsQuery = fsQuery.StartAfter(docSnapshot) // Use snapshot for StartAfter
 	}
 
-	if pageSize > 0 {
-		fsQuery = fsQuery.Limit(pageSize)
+	if query.limit > 0 {
+		fsQuery = fsQuery.Limit(query.limit)
 	}
 
-	iter := fsQuery.Documents(ctx)
+	iter := r.documents(ctx, fsQuery)
 	defer iter.Stop()
 
 	var results []*T
@@ -200,21 +276,21 @@ func (r *firestoreRepository[T]) GetByQuery(ctx context.Context, queries []Query
 			break
 		}
 		if err != nil {
-			return nil, "", fmt.Errorf("failed to iterate query documents: %w", err)
+			return nil, "", fmt.Errorf("failed to iterate query documents: %w", missingIndex(r.collectionName, err))
 		}
 
-		var data T
-		if err := doc.DataTo(&data); err != nil {
-			return nil, "", fmt.Errorf("failed to convert document data: %w", err)
+		data, err := r.decode(ctx, doc)
+		if err != nil {
+			return nil, "", err
 		}
 
-		results = append(results, &data)
+		results = append(results, data)
 		lastDocSnapshot = doc
 	}
 
 	// Use the last document's ID as the next page token
 	nextPageToken := ""
-	if pageSize > 0 && len(results) == pageSize && lastDocSnapshot != nil {
+	if query.limit > 0 && len(results) == query.limit && lastDocSnapshot != nil {
 		nextPageToken = lastDocSnapshot.Ref.ID
 	}
 
@@ -233,9 +309,17 @@ func (r *firestoreRepository[T]) GetByQuery(ctx context.Context, queries []Query
 //   - *T: The created document data
 //   - error: Any error encountered during creation
 func (r *firestoreRepository[T]) Create(ctx context.Context, id string, data map[string]interface{}) (*T, error) {
+	if err := r.writable(ctx); err != nil {
+		return nil, err
+	}
+	if err := r.prepare(ctx, id, data); err != nil {
+		return nil, err
+	}
+
 	if _, err := r.client.Collection(r.collectionName).Doc(id).Set(ctx, data); err != nil {
 		return nil, fmt.Errorf("failed to create document: %w", err)
 	}
+	r.totals.clear()
 
 	doc, err := r.client.Collection(r.collectionName).Doc(id).Get(ctx)
 	if err != nil {
@@ -246,12 +330,33 @@ func (r *firestoreRepository[T]) Create(ctx context.Context, id string, data map
 		return nil, fmt.Errorf("document with id %s not found after creation", doc.Ref.ID)
 	}
 
-	var result T
-	if err := doc.DataTo(&result); err != nil {
-		return nil, fmt.Errorf("failed to convert document data: %w", err)
+	return r.decode(ctx, doc)
+}
+
+// PrepareCreate prepares the creation of a document without writing it, so it can be committed
+// atomically with writes to other collections using Commit.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - id: ID for the new document
+//   - data: Data to store in the document
+//
+// Returns:
+//   - Write: The prepared write
+//   - error: Any error encountered while preparing the data
+func (r *firestoreRepository[T]) PrepareCreate(ctx context.Context, id string, data map[string]interface{}) (Write, error) {
+	if err := r.writable(ctx); err != nil {
+		return Write{}, err
+	}
+	if err := r.prepare(ctx, id, data); err != nil {
+		return Write{}, err
 	}
 
-	return &result, nil
+	return Write{
+		client: r.client,
+		ref:    r.client.Collection(r.collectionName).Doc(id),
+		data:   data,
+	}, nil
 }
 
 // Update modifies specific fields of an existing document.
@@ -266,10 +371,19 @@ func (r *firestoreRepository[T]) Create(ctx context.Context, id string, data map
 //   - *T: The updated document data
 //   - error: NotFound error or any other error encountered
 func (r *firestoreRepository[T]) Update(ctx context.Context, id string, data map[string]interface{}) (*T, error) {
+	if err := r.writable(ctx); err != nil {
+		return nil, err
+	}
+	firestoreData(data)
+	if err := r.encrypt(ctx, id, data); err != nil {
+		return nil, err
+	}
+
 	_, err := r.client.Collection(r.collectionName).Doc(id).Set(ctx, data, firestore.MergeAll)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update document %s: %w", id, err)
 	}
+	r.totals.clear()
 
 	doc, err := r.client.Collection(r.collectionName).Doc(id).Get(ctx)
 	if err != nil {
@@ -280,12 +394,59 @@ func (r *firestoreRepository[T]) Update(ctx context.Context, id string, data map
 		return nil, fmt.Errorf("document with id %s not found after update", id)
 	}
 
-	var result T
-	if err := doc.DataTo(&result); err != nil {
-		return nil, fmt.Errorf("failed to convert document data: %w", err)
+	return r.decode(ctx, doc)
+}
+
+// UpdateIfUnchanged modifies specific fields of a document like Update, provided the document
+// still exists and its updated_at field still holds updatedAt, the value read before. The check
+// and the write run in a transaction, so a document changed or deleted in between is left as is.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - id: ID of the document to update
+//   - updatedAt: The updated_at of the document the update was decided on
+//   - data: Map of fields to update with their new values
+//
+// Returns:
+//   - *T: The updated document data
+//   - error: ErrChanged if the document changed or was deleted, or any other error encountered
+func (r *firestoreRepository[T]) UpdateIfUnchanged(ctx context.Context, id string, updatedAt time.Time, data map[string]interface{}) (*T, error) {
+	if err := r.writable(ctx); err != nil {
+		return nil, err
+	}
+	firestoreData(data)
+	if err := r.encrypt(ctx, id, data); err != nil {
+		return nil, err
 	}
 
-	return &result, nil
+	ref := r.client.Collection(r.collectionName).Doc(id)
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return fmt.Errorf("document %s was deleted: %w", id, ErrChanged)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get document %s: %w", id, err)
+		}
+
+		current, _ := doc.Data()[UpdatedAtField].(time.Time)
+		if !current.Equal(updatedAt) {
+			return fmt.Errorf("document %s was updated at %s: %w", id, current.Format(time.RFC3339Nano), ErrChanged)
+		}
+
+		return tx.Set(ref, data, firestore.MergeAll)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update document %s: %w", id, err)
+	}
+	r.totals.clear()
+
+	doc, err := ref.Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get updated document %s: %w", id, err)
+	}
+
+	return r.decode(ctx, doc)
 }
 
 // Delete removes a document from the collection.
@@ -298,7 +459,12 @@ func (r *firestoreRepository[T]) Update(ctx context.Context, id string, data map
 // Returns:
 //   - error: NotFound error or any other error encountered
 func (r *firestoreRepository[T]) Delete(ctx context.Context, id string) error {
+	if err := r.writable(ctx); err != nil {
+		return err
+	}
+
 	_, err := r.client.Collection(r.collectionName).Doc(id).Delete(ctx)
+	r.totals.clear()
 	if status.Code(err) == codes.NotFound {
 		return fmt.Errorf("document with id %s not found: %w", id, err)
 	}
@@ -308,3 +474,194 @@ func (r *firestoreRepository[T]) Delete(ctx context.Context, id string) error {
 
 	return nil
 }
+
+// Count returns the number of documents matching the query constraints using a count aggregation.
+// An empty slice of constraints counts the whole collection. The constraints are validated like
+// the filters of a QueryBuilder.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - queries: Slice of QueryConstraint to filter the documents
+//
+// Returns:
+//   - int64: Number of matching documents
+//   - error: Any error encountered during the operation
+func (r *firestoreRepository[T]) Count(ctx context.Context, queries []QueryConstraint) (int64, error) {
+	if err := queryOf[T](queries).Err(); err != nil {
+		return 0, err
+	}
+
+	fsQuery := r.client.Collection(r.collectionName).Query
+	for _, q := range queries {
+		fsQuery = fsQuery.Where(q.Path, string(q.Op), q.Value)
+	}
+
+	aggregation := fsQuery.NewAggregationQuery().WithCount("count")
+	if tx, ok := readOnly(ctx, r.client); ok {
+		aggregation = aggregation.Transaction(tx)
+	}
+
+	result, err := aggregation.Get(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count documents: %w", missingIndex(r.collectionName, err))
+	}
+
+	count, ok := result["count"]
+	if !ok {
+		return 0, errors.New("count aggregation returned no result")
+	}
+
+	value, ok := count.(*firestorepb.Value)
+	if !ok {
+		return 0, fmt.Errorf("unexpected count aggregation result type %T", count)
+	}
+
+	return value.GetIntegerValue(), nil
+}
+
+// decode converts a document snapshot to T.
+// If migrations are configured and the document is on an older schema version, the
+// document is upgraded and written back in a transaction before it is converted. Inside a
+// read-only transaction the upgraded document is read outside of it, so it is the one read
+// that is not from the snapshot.
+func (r *firestoreRepository[T]) decode(ctx context.Context, doc *firestore.DocumentSnapshot) (*T, error) {
+	if r.migrations != nil && schemaVersion(doc.Data()) < r.migrations.LatestVersion() {
+		migrateCtx := ctx
+		if _, ok := readOnly(ctx, r.client); ok {
+			var cancel context.CancelFunc
+			migrateCtx, cancel = detached(ctx)
+			defer cancel()
+		}
+
+		migrated, err := r.migrate(migrateCtx, doc.Ref)
+		if err != nil {
+			return nil, err
+		}
+		doc = migrated
+	}
+
+	var result T
+	if err := doc.DataTo(&result); err != nil {
+		return nil, fmt.Errorf("failed to convert document data: %w", err)
+	}
+
+	if r.cipher != nil {
+		if err := r.cipher.DecryptStruct(ctx, r.collectionName, doc.Ref.ID, &result); err != nil {
+			return nil, fmt.Errorf("failed to decrypt document %s: %w", doc.Ref.ID, err)
+		}
+	}
+
+	return &result, nil
+}
+
+// prepare stamps the data of the new document id with the latest schema version and encrypts it.
+func (r *firestoreRepository[T]) prepare(ctx context.Context, id string, data map[string]interface{}) error {
+	firestoreData(data)
+	if r.migrations != nil {
+		if _, ok := data[SchemaVersionField]; !ok {
+			data[SchemaVersionField] = r.migrations.LatestVersion()
+		}
+	}
+
+	return r.encrypt(ctx, id, data)
+}
+
+// encrypt encrypts the tagged fields in data before it is written to the document id, if field
+// encryption is enabled. Documents rewritten by migrate keep their stored ciphertext instead.
+func (r *firestoreRepository[T]) encrypt(ctx context.Context, id string, data map[string]interface{}) error {
+	if r.cipher == nil {
+		return nil
+	}
+
+	if err := r.cipher.EncryptData(ctx, reflect.TypeFor[T](), r.collectionName, id, data, r.plaintextPaths); err != nil {
+		return fmt.Errorf("failed to encrypt document data: %w", err)
+	}
+
+	return nil
+}
+
+// migrate upgrades a single document to the latest schema version inside a transaction,
+// so concurrent readers migrating the same document cannot overwrite each other's writes.
+// It returns the snapshot of the migrated document.
+func (r *firestoreRepository[T]) migrate(ctx context.Context, ref *firestore.DocumentRef) (*firestore.DocumentSnapshot, error) {
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			return fmt.Errorf("failed to get document %s for migration: %w", ref.ID, err)
+		}
+
+		data := doc.Data()
+		changed, err := r.migrations.Migrate(data)
+		if err != nil {
+			return fmt.Errorf("failed to migrate document %s: %w", ref.ID, err)
+		}
+		if !changed {
+			return nil
+		}
+
+		if err := tx.Set(ref, data); err != nil {
+			return fmt.Errorf("failed to write migrated document %s: %w", ref.ID, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate document %s: %w", ref.ID, err)
+	}
+
+	doc, err := ref.Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get migrated document %s: %w", ref.ID, err)
+	}
+
+	return doc, nil
+}
+
+// Backfill migrates every document in the collection that is on an older schema version.
+// It is used by the migrate command to upgrade a collection ahead of time rather than lazily.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - client: Initialized Firestore client
+//   - collectionName: Name of the Firestore collection to migrate
+//   - migrations: Registry with the migrations for the collection
+//   - dryRun: If true, documents are only counted and not written
+//
+// Returns:
+//   - int: Number of documents that were (or in a dry run would be) migrated
+//   - error: Any error encountered during the operation
+func Backfill(ctx context.Context, client *firestore.Client, collectionName string, migrations *MigrationRegistry, dryRun bool) (int, error) {
+	repo := &firestoreRepository[map[string]interface{}]{
+		client:         client,
+		collectionName: collectionName,
+		migrations:     migrations,
+	}
+
+	latest := migrations.LatestVersion()
+	iter := client.Collection(collectionName).Documents(ctx)
+	defer iter.Stop()
+
+	migrated := 0
+	for {
+		doc, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return migrated, fmt.Errorf("failed to iterate documents: %w", err)
+		}
+
+		if schemaVersion(doc.Data()) >= latest {
+			continue
+		}
+
+		if !dryRun {
+			if _, err := repo.migrate(ctx, doc.Ref); err != nil {
+				return migrated, err
+			}
+		}
+		migrated++
+	}
+
+	return migrated, nil
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// indexURLPattern matches the link to create a missing index, which Firestore includes in the error.
+var indexURLPattern = regexp.MustCompile(`https://console\.firebase\.google\.com/\S+`)
+
+// MissingIndexError is returned by queries Firestore cannot run because a composite index
+// they need does not exist. The query shapes of the services are listed by cmd/indexes.
+type MissingIndexError struct {
+	Collection string
+	// URL opens the Firebase console with the missing index filled in, it is empty when
+	// Firestore did not send one.
+	URL string
+	Err error
+}
+
+// Error implements the error interface.
+func (e *MissingIndexError) Error() string {
+	return fmt.Sprintf("query on %s requires a missing index: %v", e.Collection, e.Err)
+}
+
+// Unwrap returns the Firestore error.
+func (e *MissingIndexError) Unwrap() error {
+	return e.Err
+}
+
+// IsMissingIndex reports whether err is caused by a missing composite index.
+func IsMissingIndex(err error) bool {
+	var indexErr *MissingIndexError
+
+	return errors.As(err, &indexErr)
+}
+
+// missingIndex returns a *MissingIndexError for a query error caused by a missing index, and
+// logs the link to create the index. Other errors are returned as they are.
+func missingIndex(collectionName string, err error) error {
+	if status.Code(err) != codes.FailedPrecondition || !strings.Contains(status.Convert(err).Message(), "index") {
+		return err
+	}
+
+	indexErr := &MissingIndexError{
+		Collection: collectionName,
+		URL:        indexURLPattern.FindString(status.Convert(err).Message()),
+		Err:        err,
+	}
+	log.Error().Str("collection", collectionName).Str("index_url", indexErr.URL).
+		Msg("Firestore query requires a missing composite index")
+
+	return indexErr
+}
+
+// IndexField is a field of a composite index, in the format of firestore.indexes.json.
+type IndexField struct {
+	FieldPath   string `json:"fieldPath"`
+	Order       string `json:"order,omitempty"`
+	ArrayConfig string `json:"arrayConfig,omitempty"`
+}
+
+// Index is a composite index, in the format of firestore.indexes.json.
+type Index struct {
+	CollectionGroup string       `json:"collectionGroup"`
+	QueryScope      string       `json:"queryScope"`
+	Fields          []IndexField `json:"fields"`
+}
+
+// QueryIndex returns the composite index GetByQuery and Count need for the constraints on the
+// collection, and false when the single-field indexes Firestore keeps by default are enough.
+// The index is collection scoped, so it also serves the tenant subcollections of the collection.
+func QueryIndex(collectionName string, queries []QueryConstraint) (Index, bool) {
+	if len(queries) == 0 {
+		return Index{}, false
+	}
+	// One equality filter is served by the field's single-field index
+	if len(queries) == 1 && queries[0].Op == QueryOperatorEqual {
+		return Index{}, false
+	}
+
+	// Equality filters come first, the filters on ranges and arrays after them
+	var equality, other []IndexField
+	for _, q := range queries {
+		switch q.Op {
+		case QueryOperatorEqual, QueryOperatorIn:
+			equality = append(equality, IndexField{FieldPath: q.Path, Order: "ASCENDING"})
+		case QueryOperatorArrayContains, QueryOperatorArrayContainsAny:
+			other = append(other, IndexField{FieldPath: q.Path, ArrayConfig: "CONTAINS"})
+		default:
+			other = append(other, IndexField{FieldPath: q.Path, Order: "ASCENDING"})
+		}
+	}
+
+	// Queries are ordered by document ID, see GetByQuery, which every index ends with implicitly
+	return Index{
+		CollectionGroup: collectionName[strings.LastIndex(collectionName, "/")+1:],
+		QueryScope:      "COLLECTION",
+		Fields:          append(equality, other...),
+	}, true
+}
@@ -0,0 +1,95 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SchemaVersionField is the document field holding the schema version of stored data.
+const SchemaVersionField = "schema_version"
+
+// Migration upgrades raw document data by one schema version.
+// It mutates the data in place and must be safe to run on data that
+// was written by any earlier version of the application.
+type Migration func(data map[string]interface{}) error
+
+// MigrationRegistry holds the ordered migrations for a collection.
+// Documents are upgraded lazily when read, so model changes can ship
+// without rewriting the whole collection up front.
+type MigrationRegistry struct {
+	migrations map[int]Migration
+}
+
+// NewMigrationRegistry creates an empty MigrationRegistry.
+func NewMigrationRegistry() *MigrationRegistry {
+	return &MigrationRegistry{
+		migrations: make(map[int]Migration),
+	}
+}
+
+// Register adds the migration that upgrades data from fromVersion to fromVersion+1.
+// It panics if a migration is already registered for the version, since that is a programming error.
+func (m *MigrationRegistry) Register(fromVersion int, migration Migration) *MigrationRegistry {
+	if _, ok := m.migrations[fromVersion]; ok {
+		panic(fmt.Sprintf("migration from schema version %d already registered", fromVersion))
+	}
+
+	m.migrations[fromVersion] = migration
+
+	return m
+}
+
+// LatestVersion returns the schema version data is at after all migrations have run.
+func (m *MigrationRegistry) LatestVersion() int {
+	versions := make([]int, 0, len(m.migrations))
+	for v := range m.migrations {
+		versions = append(versions, v)
+	}
+	if len(versions) == 0 {
+		return 0
+	}
+	sort.Ints(versions)
+
+	return versions[len(versions)-1] + 1
+}
+
+// Migrate runs every migration needed to bring data up to the latest version.
+// It returns true if the data was changed and needs to be written back.
+func (m *MigrationRegistry) Migrate(data map[string]interface{}) (bool, error) {
+	version := schemaVersion(data)
+	latest := m.LatestVersion()
+	if version >= latest {
+		return false, nil
+	}
+
+	for ; version < latest; version++ {
+		migration, ok := m.migrations[version]
+		if !ok {
+			return false, fmt.Errorf("no migration registered from schema version %d", version)
+		}
+
+		if err := migration(data); err != nil {
+			return false, fmt.Errorf("failed to migrate from schema version %d: %w", version, err)
+		}
+		data[SchemaVersionField] = int64(version + 1)
+	}
+
+	return true, nil
+}
+
+// schemaVersion reads the schema version from raw document data.
+// Documents written before versioning was introduced have no version and are treated as version 0.
+func schemaVersion(data map[string]interface{}) int {
+	switch v := data[SchemaVersionField].(type) {
+	case int64:
+		return int(v)
+	case int32:
+		return int(v)
+	case int:
+		return v
+	case float64:
+		return int(v)
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,542 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/api/iterator"
+
+	"github.com/thoughtgears/shared-services/internal/fieldcrypt"
+	"github.com/thoughtgears/shared-services/pkg/apperr"
+)
+
+// mongoRepository implements the DB interface for a MongoDB collection. Documents are stored
+// with the field names of their firestore tags and the document ID as _id, and queries follow
+// the Firestore semantics of the repository's callers, see mongoFilter.
+type mongoRepository[T any] struct {
+	collection     *mongo.Collection
+	collectionName string
+	migrations     *MigrationRegistry
+	cipher         *fieldcrypt.Cipher
+	plaintextPaths []string
+	totals         totalCache
+}
+
+// NewMongoRepository creates a new instance of mongoRepository for a specific type.
+// It implements the DB interface for the given type T on a MongoDB database, for deployments
+// without Firestore. Read-only transactions of a Snapshotter do not apply to it, and Commit
+// needs the database to run as a replica set.
+//
+// Parameters:
+//   - database: Connected MongoDB database
+//   - collectionName: Name of the MongoDB collection where data will be stored
+//   - opts: Optional repository options, e.g. WithMigrations
+//
+// Returns:
+//   - DB[T]: A repository instance for the specified type
+func NewMongoRepository[T any](database *mongo.Database, collectionName string, opts ...RepositoryOption) DB[T] {
+	var settings repositoryOptions
+	for _, opt := range opts {
+		opt(&settings)
+	}
+
+	return &mongoRepository[T]{
+		collection:     database.Collection(collectionName, options.Collection().SetRegistry(mongoRegistry)),
+		collectionName: collectionName,
+		migrations:     settings.migrations,
+		cipher:         settings.cipher,
+		plaintextPaths: settings.plaintextPaths,
+	}
+}
+
+// GetAll retrieves all documents from the collection with optional pagination, ordered by ID.
+func (r *mongoRepository[T]) GetAll(ctx context.Context, pageToken string, pageSize int) ([]*T, string, error) {
+	filter := bson.D{}
+	if pageToken != "" {
+		filter = bson.D{{Key: "_id", Value: bson.D{{Key: "$gt", Value: pageToken}}}}
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	if pageSize > 0 {
+		findOptions.SetLimit(int64(pageSize))
+	}
+
+	results, lastID, err := r.find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to iterate documents: %w", err)
+	}
+
+	nextPageToken := ""
+	if pageSize > 0 && len(results) == pageSize {
+		nextPageToken = lastID
+	}
+
+	return results, nextPageToken, nil
+}
+
+// GetByID retrieves a single document by its ID.
+func (r *mongoRepository[T]) GetByID(ctx context.Context, id string) (*T, error) {
+	raw, err := r.collection.FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Raw()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, apperr.Errorf(apperr.NotFound, "document with id %s not found: %w", id, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document %s: %w", id, err)
+	}
+
+	return r.decode(ctx, raw)
+}
+
+// GetByQuery retrieves documents matching the specified query constraints with optional pagination.
+func (r *mongoRepository[T]) GetByQuery(ctx context.Context, queries []QueryConstraint, pageToken string, pageSize int) ([]*T, string, error) {
+	return r.Find(ctx, queryOf[T](queries).After(pageToken).Limit(max(pageSize, 0)))
+}
+
+// Find retrieves the documents matching a query built with Query, see QueryBuilder. Pages
+// continue after the page token document in the order of the query, then of the document ID.
+func (r *mongoRepository[T]) Find(ctx context.Context, query *QueryBuilder[T]) ([]*T, string, error) {
+	if err := query.Err(); err != nil {
+		return nil, "", err
+	}
+
+	keys := mongoSortKeys(query.orders)
+	filter := mongoFilter(query.constraints, query.orders)
+	if query.pageToken != "" {
+		after, err := r.after(ctx, keys, query.pageToken)
+		if err != nil {
+			return nil, "", err
+		}
+		filter = bson.D{{Key: "$and", Value: bson.A{filter, after}}}
+	}
+
+	findOptions := options.Find().SetSort(mongoSort(keys))
+	if query.limit > 0 {
+		findOptions.SetLimit(int64(query.limit))
+	}
+
+	results, lastID, err := r.find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to iterate query documents: %w", err)
+	}
+
+	nextPageToken := ""
+	if query.limit > 0 && len(results) == query.limit {
+		nextPageToken = lastID
+	}
+
+	return results, nextPageToken, nil
+}
+
+// FindPage retrieves a page of the documents matching the query like Find, with the total count
+// for queries with IncludeTotal, see firestoreRepository.FindPage.
+func (r *mongoRepository[T]) FindPage(ctx context.Context, query *QueryBuilder[T]) (*Page[T], error) {
+	return findPage(ctx, r, &r.totals, r.collectionName, query, true)
+}
+
+// Create adds a new document to the collection with the specified ID.
+// If the document already exists, it will be overwritten.
+func (r *mongoRepository[T]) Create(ctx context.Context, id string, data map[string]interface{}) (*T, error) {
+	document, err := r.document(ctx, id, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := r.collection.ReplaceOne(ctx, bson.D{{Key: "_id", Value: id}}, document, options.Replace().SetUpsert(true)); err != nil {
+		return nil, fmt.Errorf("failed to create document: %w", err)
+	}
+	r.totals.clear()
+
+	raw, err := r.collection.FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Raw()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get created document: %w", err)
+	}
+
+	return r.decode(ctx, raw)
+}
+
+// PrepareCreate prepares the creation of a document without writing it, so it can be committed
+// atomically with writes to other collections of the same database using Commit.
+func (r *mongoRepository[T]) PrepareCreate(ctx context.Context, id string, data map[string]interface{}) (Write, error) {
+	document, err := r.document(ctx, id, data)
+	if err != nil {
+		return Write{}, err
+	}
+
+	return Write{collection: r.collection, data: document}, nil
+}
+
+// Update modifies specific fields of a document, nested maps are merged field by field.
+// A document that does not exist is created.
+func (r *mongoRepository[T]) Update(ctx context.Context, id string, data map[string]interface{}) (*T, error) {
+	if err := r.encrypt(ctx, id, data); err != nil {
+		return nil, err
+	}
+
+	update := mongoUpdate(data, time.Now().UTC())
+	if len(update) == 0 {
+		update = bson.D{{Key: "$setOnInsert", Value: bson.D{{Key: "_id", Value: id}}}}
+	}
+	if _, err := r.collection.UpdateOne(ctx, bson.D{{Key: "_id", Value: id}}, update, options.Update().SetUpsert(true)); err != nil {
+		return nil, fmt.Errorf("failed to update document %s: %w", id, err)
+	}
+	r.totals.clear()
+
+	raw, err := r.collection.FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Raw()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get updated document %s: %w", id, err)
+	}
+
+	return r.decode(ctx, raw)
+}
+
+// UpdateIfUnchanged modifies specific fields of a document like Update, provided the document
+// still exists and its updated_at field still holds updatedAt, the value read before.
+func (r *mongoRepository[T]) UpdateIfUnchanged(ctx context.Context, id string, updatedAt time.Time, data map[string]interface{}) (*T, error) {
+	if err := r.encrypt(ctx, id, data); err != nil {
+		return nil, err
+	}
+
+	filter := bson.D{{Key: "_id", Value: id}, {Key: UpdatedAtField, Value: updatedAt}}
+	result, err := r.collection.UpdateOne(ctx, filter, mongoUpdate(data, time.Now().UTC()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update document %s: %w", id, err)
+	}
+	if result.MatchedCount == 0 {
+		return nil, fmt.Errorf("failed to update document %s: %w", id, ErrChanged)
+	}
+	r.totals.clear()
+
+	raw, err := r.collection.FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Raw()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get updated document %s: %w", id, err)
+	}
+
+	return r.decode(ctx, raw)
+}
+
+// Delete removes a document from the collection.
+func (r *mongoRepository[T]) Delete(ctx context.Context, id string) error {
+	_, err := r.collection.DeleteOne(ctx, bson.D{{Key: "_id", Value: id}})
+	r.totals.clear()
+	if err != nil {
+		return fmt.Errorf("failed to delete document %s: %w", id, err)
+	}
+
+	return nil
+}
+
+// Count returns the number of documents matching the query constraints.
+// An empty slice of constraints counts the whole collection.
+func (r *mongoRepository[T]) Count(ctx context.Context, queries []QueryConstraint) (int64, error) {
+	if err := queryOf[T](queries).Err(); err != nil {
+		return 0, err
+	}
+
+	count, err := r.collection.CountDocuments(ctx, mongoFilter(queries, nil))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count documents: %w", err)
+	}
+
+	return count, nil
+}
+
+// Changes streams the changes to the collection like firestoreRepository.Changes, starting with
+// the documents written after since. It is backed by a MongoDB change stream, which needs the
+// database to run as a replica set.
+func (r *mongoRepository[T]) Changes(ctx context.Context, since time.Time) (ChangeStream[T], error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	// The change stream is opened before the documents are read, so no write falls in between
+	stream, err := r.collection.Watch(ctx, mongo.Pipeline{}, options.ChangeStream().SetFullDocument(options.UpdateLookup))
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to watch %s: %w", r.collectionName, err)
+	}
+
+	filter := bson.D{}
+	if !since.IsZero() {
+		filter = bson.D{{Key: UpdatedAtField, Value: bson.D{{Key: "$gt", Value: since}}}}
+	}
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		_ = stream.Close(context.Background())
+		cancel()
+		return nil, fmt.Errorf("failed to read %s: %w", r.collectionName, err)
+	}
+
+	return &mongoChangeStream[T]{
+		ctx:    ctx,
+		cancel: cancel,
+		repo:   r,
+		cursor: cursor,
+		stream: stream,
+	}, nil
+}
+
+// find runs a query and decodes its documents, it also returns the ID of the last document.
+func (r *mongoRepository[T]) find(ctx context.Context, filter bson.D, findOptions *options.FindOptions) ([]*T, string, error) {
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, "", err
+	}
+	defer cursor.Close(ctx)
+
+	var results []*T
+	var lastID string
+	for cursor.Next(ctx) {
+		data, err := r.decode(ctx, cursor.Current)
+		if err != nil {
+			return nil, "", err
+		}
+
+		results = append(results, data)
+		lastID, _ = cursor.Current.Lookup("_id").StringValueOK()
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, "", err
+	}
+
+	return results, lastID, nil
+}
+
+// after returns the filter for the documents that sort after the page token document: those
+// with a later value for a sort key and the same values for the keys before it.
+func (r *mongoRepository[T]) after(ctx context.Context, keys []QueryOrder, pageToken string) (bson.D, error) {
+	raw, err := r.collection.FindOne(ctx, bson.D{{Key: "_id", Value: pageToken}}).Raw()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page token document %s: %w", pageToken, err)
+	}
+
+	values := make([]bson.RawValue, len(keys))
+	for i, key := range keys {
+		value, err := raw.LookupErr(strings.Split(mongoPath(key.Path), ".")...)
+		if err != nil {
+			return nil, fmt.Errorf("page token document %s has no %s: %w", pageToken, key.Path, err)
+		}
+		values[i] = value
+	}
+
+	branches := make(bson.A, 0, len(keys))
+	for i, key := range keys {
+		branch := bson.D{}
+		for j, previous := range keys[:i] {
+			branch = append(branch, bson.E{Key: mongoPath(previous.Path), Value: bson.D{{Key: "$eq", Value: values[j]}}})
+		}
+
+		operator := "$gt"
+		if key.Direction == Desc {
+			operator = "$lt"
+		}
+		branch = append(branch, bson.E{Key: mongoPath(key.Path), Value: bson.D{{Key: operator, Value: values[i]}}})
+		branches = append(branches, branch)
+	}
+
+	return bson.D{{Key: "$or", Value: branches}}, nil
+}
+
+// document returns the MongoDB document of new document data, stamped with the latest schema
+// version and encrypted.
+func (r *mongoRepository[T]) document(ctx context.Context, id string, data map[string]interface{}) (map[string]interface{}, error) {
+	if r.migrations != nil {
+		if _, ok := data[SchemaVersionField]; !ok {
+			data[SchemaVersionField] = r.migrations.LatestVersion()
+		}
+	}
+	if err := r.encrypt(ctx, id, data); err != nil {
+		return nil, err
+	}
+
+	document := resolveData(data, time.Now().UTC())
+	document["_id"] = id
+
+	return document, nil
+}
+
+// encrypt encrypts the tagged fields in data before it is written to the document id, if field
+// encryption is enabled. Documents rewritten by migrate keep their stored ciphertext instead.
+func (r *mongoRepository[T]) encrypt(ctx context.Context, id string, data map[string]interface{}) error {
+	if r.cipher == nil {
+		return nil
+	}
+
+	if err := r.cipher.EncryptData(ctx, reflect.TypeFor[T](), r.collectionName, id, data, r.plaintextPaths); err != nil {
+		return fmt.Errorf("failed to encrypt document data: %w", err)
+	}
+
+	return nil
+}
+
+// decode converts a MongoDB document to T, migrating it first like firestoreRepository.decode.
+func (r *mongoRepository[T]) decode(ctx context.Context, raw bson.Raw) (*T, error) {
+	id, _ := raw.Lookup("_id").StringValueOK()
+
+	if r.migrations != nil {
+		version, _ := raw.Lookup(SchemaVersionField).AsInt64OK()
+		if int(version) < r.migrations.LatestVersion() {
+			migrated, err := r.migrate(ctx, id, raw)
+			if err != nil {
+				return nil, err
+			}
+			raw = migrated
+		}
+	}
+
+	var result T
+	if err := decodeMongo(raw, &result); err != nil {
+		return nil, fmt.Errorf("failed to convert document data: %w", err)
+	}
+
+	if r.cipher != nil {
+		if err := r.cipher.DecryptStruct(ctx, r.collectionName, id, &result); err != nil {
+			return nil, fmt.Errorf("failed to decrypt document %s: %w", id, err)
+		}
+	}
+
+	return &result, nil
+}
+
+// migrate upgrades a document to the latest schema version and writes it back, unless its schema
+// version or update time changed since it was read. It returns the migrated document.
+func (r *mongoRepository[T]) migrate(ctx context.Context, id string, raw bson.Raw) (bson.Raw, error) {
+	var data map[string]interface{}
+	if err := decodeMongo(raw, &data); err != nil {
+		return nil, fmt.Errorf("failed to read document %s for migration: %w", id, err)
+	}
+
+	changed, err := r.migrations.Migrate(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate document %s: %w", id, err)
+	}
+	if !changed {
+		return raw, nil
+	}
+
+	filter := bson.D{{Key: "_id", Value: id}}
+	for _, field := range []string{SchemaVersionField, UpdatedAtField} {
+		if value, err := raw.LookupErr(field); err == nil {
+			filter = append(filter, bson.E{Key: field, Value: value})
+		} else {
+			filter = append(filter, bson.E{Key: field, Value: bson.D{{Key: "$exists", Value: false}}})
+		}
+	}
+	if _, err := r.collection.ReplaceOne(ctx, filter, data); err != nil {
+		return nil, fmt.Errorf("failed to write migrated document %s: %w", id, err)
+	}
+
+	migrated, err := encodeMongo(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode migrated document %s: %w", id, err)
+	}
+
+	return migrated, nil
+}
+
+// mongoChangeStream is a ChangeStream over the documents written since its start time, then
+// over a MongoDB change stream.
+type mongoChangeStream[T any] struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	repo   *mongoRepository[T]
+
+	// mu is held by Next, so Stop closes the cursors once Next has returned
+	mu      sync.Mutex
+	cursor  *mongo.Cursor
+	stream  *mongo.ChangeStream
+	stopped atomic.Bool
+}
+
+// Next returns the next change, the documents written since the start time come first.
+func (s *mongoChangeStream[T]) Next() (*Change[T], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped.Load() {
+		return nil, iterator.Done
+	}
+
+	if s.cursor != nil {
+		if s.cursor.Next(s.ctx) {
+			return s.set(s.cursor.Current, mongoUpdatedAt(s.cursor.Current))
+		}
+		if err := s.cursor.Err(); err != nil {
+			return nil, s.err(err)
+		}
+		_ = s.cursor.Close(context.Background())
+		s.cursor = nil
+	}
+
+	for s.stream.Next(s.ctx) {
+		event := s.stream.Current
+		seconds, _ := event.Lookup("clusterTime").Timestamp()
+		changedAt := time.Unix(int64(seconds), 0).UTC()
+
+		switch operation := event.Lookup("operationType").StringValue(); operation {
+		case "insert", "update", "replace":
+			// A document deleted after the change has no full document, its deletion follows
+			if document, ok := event.Lookup("fullDocument").DocumentOK(); ok {
+				return s.set(bson.Raw(document), changedAt)
+			}
+		case "delete":
+			id, _ := event.Lookup("documentKey", "_id").StringValueOK()
+
+			return &Change[T]{Kind: ChangeDeleted, ID: id, Time: changedAt}, nil
+		case "drop", "rename", "dropDatabase", "invalidate":
+			return nil, fmt.Errorf("change stream of %s ended by %s", s.repo.collectionName, operation)
+		}
+	}
+
+	return nil, s.err(s.stream.Err())
+}
+
+// Stop ends the stream and closes its cursors.
+func (s *mongoChangeStream[T]) Stop() {
+	s.stopped.Store(true)
+	s.cancel()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cursor != nil {
+		_ = s.cursor.Close(context.Background())
+		s.cursor = nil
+	}
+	_ = s.stream.Close(context.Background())
+}
+
+// set returns the change that wrote the document.
+func (s *mongoChangeStream[T]) set(raw bson.Raw, changedAt time.Time) (*Change[T], error) {
+	data, err := s.repo.decode(s.ctx, raw)
+	if err != nil {
+		return nil, err
+	}
+	id, _ := raw.Lookup("_id").StringValueOK()
+
+	return &Change[T]{Kind: ChangeSet, ID: id, Data: data, Time: changedAt}, nil
+}
+
+// err returns the error that ended the stream, iterator.Done once it is stopped.
+func (s *mongoChangeStream[T]) err(err error) error {
+	if s.stopped.Load() || err == nil {
+		return iterator.Done
+	}
+
+	return err
+}
+
+// mongoUpdatedAt returns the time the document was last written, or now if it does not say.
+func mongoUpdatedAt(raw bson.Raw) time.Time {
+	if updatedAt, ok := raw.Lookup(UpdatedAtField).TimeOK(); ok {
+		return updatedAt.UTC()
+	}
+
+	return time.Now().UTC()
+}
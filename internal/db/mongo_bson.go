@@ -0,0 +1,215 @@
+package db
+
+import (
+	"bytes"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// mongoRegistry maps structs by their firestore tags, so the models are stored in MongoDB with
+// the same field names as in Firestore and need no bson tags. Nested documents and arrays in
+// untyped values decode to maps and slices, as they do from Firestore.
+var mongoRegistry = newMongoRegistry()
+
+func newMongoRegistry() *bsoncodec.Registry {
+	registry := bson.NewRegistry()
+
+	// Custom tag parsers are deprecated by the driver, but are the only way to reuse the firestore tags
+	codec, err := bsoncodec.NewStructCodec(bsoncodec.StructTagParserFunc(firestoreStructTags))
+	if err != nil {
+		panic(err)
+	}
+	registry.RegisterKindEncoder(reflect.Struct, codec)
+	registry.RegisterKindDecoder(reflect.Struct, codec)
+	registry.RegisterTypeMapEntry(bsontype.EmbeddedDocument, reflect.TypeFor[map[string]interface{}]())
+	registry.RegisterTypeMapEntry(bsontype.Array, reflect.TypeFor[[]interface{}]())
+
+	return registry
+}
+
+// firestoreStructTags reads the bson mapping of a struct field from its firestore tag, following
+// the naming rules of the Firestore client like firestoreField.
+func firestoreStructTags(field reflect.StructField) (bsoncodec.StructTags, error) {
+	name, options, _ := strings.Cut(field.Tag.Get("firestore"), ",")
+	if name == "-" {
+		return bsoncodec.StructTags{Skip: true}, nil
+	}
+
+	tags := bsoncodec.StructTags{
+		Name:      name,
+		OmitEmpty: slices.Contains(strings.Split(options, ","), "omitempty"),
+	}
+	if name == "" {
+		tags.Name = field.Name
+		tags.Inline = field.Anonymous && field.Type.Kind() == reflect.Struct
+	}
+
+	return tags, nil
+}
+
+// decodeMongo decodes a MongoDB document into v.
+func decodeMongo(raw bson.Raw, v interface{}) error {
+	decoder, err := bson.NewDecoder(bsonrw.NewBSONDocumentReader(raw))
+	if err != nil {
+		return err
+	}
+	if err := decoder.SetRegistry(mongoRegistry); err != nil {
+		return err
+	}
+
+	return decoder.Decode(v)
+}
+
+// encodeMongo encodes v as a MongoDB document.
+func encodeMongo(v interface{}) (bson.Raw, error) {
+	var buf bytes.Buffer
+	writer, err := bsonrw.NewBSONValueWriter(&buf)
+	if err != nil {
+		return nil, err
+	}
+	encoder, err := bson.NewEncoder(writer)
+	if err != nil {
+		return nil, err
+	}
+	if err := encoder.SetRegistry(mongoRegistry); err != nil {
+		return nil, err
+	}
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// mongoPath returns the MongoDB field path of a query path, the document ID is stored as _id.
+func mongoPath(path string) string {
+	if path == firestore.DocumentID {
+		return "_id"
+	}
+
+	return path
+}
+
+// mongoFilter translates the constraints of a query to a MongoDB filter. Like Firestore, the
+// filter leaves out documents without the fields the query is ordered on, and the not-equal
+// operators leave out documents without the field.
+func mongoFilter(constraints []QueryConstraint, orders []QueryOrder) bson.D {
+	clauses := make([]bson.D, 0, len(constraints)+len(orders))
+	for _, c := range constraints {
+		clauses = append(clauses, bson.D{{Key: mongoPath(c.Path), Value: mongoCondition(c)}})
+	}
+	for _, order := range orders {
+		if order.Path != firestore.DocumentID {
+			clauses = append(clauses, bson.D{{Key: order.Path, Value: bson.D{{Key: "$exists", Value: true}}}})
+		}
+	}
+	if len(clauses) == 0 {
+		return bson.D{}
+	}
+
+	return bson.D{{Key: "$and", Value: clauses}}
+}
+
+// mongoCondition translates the operator and value of a constraint to a MongoDB condition.
+// Equality on an array field matches arrays holding the value, which is array-contains.
+func mongoCondition(c QueryConstraint) bson.D {
+	switch c.Op {
+	case Ne:
+		return bson.D{{Key: "$ne", Value: c.Value}, {Key: "$exists", Value: true}}
+	case Lt:
+		return bson.D{{Key: "$lt", Value: c.Value}}
+	case Lte:
+		return bson.D{{Key: "$lte", Value: c.Value}}
+	case Gt:
+		return bson.D{{Key: "$gt", Value: c.Value}}
+	case Gte:
+		return bson.D{{Key: "$gte", Value: c.Value}}
+	case In, ArrayContainsAny:
+		return bson.D{{Key: "$in", Value: c.Value}}
+	case NotIn:
+		return bson.D{{Key: "$nin", Value: c.Value}, {Key: "$exists", Value: true}}
+	default:
+		return bson.D{{Key: "$eq", Value: c.Value}}
+	}
+}
+
+// mongoSortKeys returns the orders of a query followed by the document ID, the order pages are
+// read in.
+func mongoSortKeys(orders []QueryOrder) []QueryOrder {
+	keys := slices.Clone(orders)
+	if !slices.ContainsFunc(keys, func(order QueryOrder) bool { return order.Path == firestore.DocumentID }) {
+		keys = append(keys, QueryOrder{Path: firestore.DocumentID, Direction: Asc})
+	}
+
+	return keys
+}
+
+// mongoSort returns the MongoDB sort of the sort keys.
+func mongoSort(keys []QueryOrder) bson.D {
+	sort := make(bson.D, 0, len(keys))
+	for _, key := range keys {
+		direction := 1
+		if key.Direction == Desc {
+			direction = -1
+		}
+		sort = append(sort, bson.E{Key: mongoPath(key.Path), Value: direction})
+	}
+
+	return sort
+}
+
+// mongoUpdate translates the data of an Update to a MongoDB update. Nested maps are merged field
+// by field like a Firestore merge, firestore.Delete unsets a field, firestore.ServerTimestamp sets
+// it to now and ArrayUnion adds to it.
+func mongoUpdate(data map[string]interface{}, now time.Time) bson.D {
+	var set, unset, addToSet bson.D
+
+	var walk func(prefix string, data map[string]interface{})
+	walk = func(prefix string, data map[string]interface{}) {
+		for key, value := range data {
+			path := prefix + key
+			switch v := value.(type) {
+			case arrayUnion:
+				addToSet = append(addToSet, bson.E{Key: path, Value: bson.D{{Key: "$each", Value: []interface{}(v)}}})
+			case map[string]interface{}:
+				if len(v) == 0 {
+					set = append(set, bson.E{Key: path, Value: v})
+
+					continue
+				}
+				walk(path+".", v)
+			default:
+				switch value {
+				case firestore.Delete:
+					unset = append(unset, bson.E{Key: path, Value: ""})
+				case firestore.ServerTimestamp:
+					set = append(set, bson.E{Key: path, Value: now})
+				default:
+					set = append(set, bson.E{Key: path, Value: value})
+				}
+			}
+		}
+	}
+	walk("", data)
+
+	var update bson.D
+	if len(set) > 0 {
+		update = append(update, bson.E{Key: "$set", Value: set})
+	}
+	if len(unset) > 0 {
+		update = append(update, bson.E{Key: "$unset", Value: unset})
+	}
+	if len(addToSet) > 0 {
+		update = append(update, bson.E{Key: "$addToSet", Value: addToSet})
+	}
+
+	return update
+}
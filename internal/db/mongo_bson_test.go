@@ -0,0 +1,153 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMongoCondition(t *testing.T) {
+	tests := []struct {
+		op   QueryOperator
+		want bson.D
+	}{
+		{Eq, bson.D{{Key: "$eq", Value: "a"}}},
+		{ArrayContains, bson.D{{Key: "$eq", Value: "a"}}},
+		{Ne, bson.D{{Key: "$ne", Value: "a"}, {Key: "$exists", Value: true}}},
+		{Lt, bson.D{{Key: "$lt", Value: "a"}}},
+		{Lte, bson.D{{Key: "$lte", Value: "a"}}},
+		{Gt, bson.D{{Key: "$gt", Value: "a"}}},
+		{Gte, bson.D{{Key: "$gte", Value: "a"}}},
+		{In, bson.D{{Key: "$in", Value: "a"}}},
+		{ArrayContainsAny, bson.D{{Key: "$in", Value: "a"}}},
+		{NotIn, bson.D{{Key: "$nin", Value: "a"}, {Key: "$exists", Value: true}}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.op), func(t *testing.T) {
+			if got := mongoCondition(QueryConstraint{Path: "status", Op: tt.op, Value: "a"}); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mongoCondition() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMongoFilter(t *testing.T) {
+	got := mongoFilter(
+		[]QueryConstraint{{Path: "user_id", Op: Eq, Value: "user-1"}, {Path: firestore.DocumentID, Op: In, Value: []string{"a", "b"}}},
+		[]QueryOrder{{Path: "created_at", Direction: Desc}, {Path: firestore.DocumentID, Direction: Asc}},
+	)
+	want := bson.D{{Key: "$and", Value: []bson.D{
+		{{Key: "user_id", Value: bson.D{{Key: "$eq", Value: "user-1"}}}},
+		{{Key: "_id", Value: bson.D{{Key: "$in", Value: []string{"a", "b"}}}}},
+		// Like Firestore, documents without the order field are left out
+		{{Key: "created_at", Value: bson.D{{Key: "$exists", Value: true}}}},
+	}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mongoFilter() = %v, want %v", got, want)
+	}
+
+	if got := mongoFilter(nil, nil); len(got) != 0 {
+		t.Errorf("mongoFilter() without constraints = %v, want an empty filter", got)
+	}
+}
+
+func TestMongoSort(t *testing.T) {
+	keys := mongoSortKeys([]QueryOrder{{Path: "created_at", Direction: Desc}})
+	if want := []QueryOrder{{Path: "created_at", Direction: Desc}, {Path: firestore.DocumentID, Direction: Asc}}; !reflect.DeepEqual(keys, want) {
+		t.Fatalf("mongoSortKeys() = %v, want %v", keys, want)
+	}
+	if got, want := mongoSort(keys), (bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: 1}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("mongoSort() = %v, want %v", got, want)
+	}
+
+	// An explicit document ID order is not repeated
+	keys = mongoSortKeys([]QueryOrder{{Path: firestore.DocumentID, Direction: Desc}})
+	if len(keys) != 1 {
+		t.Errorf("mongoSortKeys() = %v, want the document ID order only", keys)
+	}
+}
+
+func TestMongoUpdate(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	update := mongoUpdate(map[string]interface{}{
+		"status":     "verified",
+		"metadata":   map[string]interface{}{"source": "upload"},
+		"review":     firestore.Delete,
+		"updated_at": firestore.ServerTimestamp,
+		"tags":       ArrayUnion("a", "b"),
+		"empty":      map[string]interface{}{},
+	}, now)
+
+	operators := make(map[string]map[string]interface{})
+	for _, op := range update {
+		fields := make(map[string]interface{})
+		for _, e := range op.Value.(bson.D) {
+			fields[e.Key] = e.Value
+		}
+		operators[op.Key] = fields
+	}
+
+	want := map[string]map[string]interface{}{
+		"$set": {
+			"status":          "verified",
+			"metadata.source": "upload",
+			"updated_at":      now,
+			"empty":           map[string]interface{}{},
+		},
+		"$unset":    {"review": ""},
+		"$addToSet": {"tags": bson.D{{Key: "$each", Value: []interface{}{"a", "b"}}}},
+	}
+	if !reflect.DeepEqual(operators, want) {
+		t.Errorf("mongoUpdate() = %v, want %v", operators, want)
+	}
+}
+
+func TestMongoCodecUsesFirestoreTags(t *testing.T) {
+	type Base struct {
+		CreatedAt time.Time `firestore:"created_at"`
+	}
+	type record struct {
+		Base
+		Name     string                 `firestore:"name"`
+		Note     string                 `firestore:"note,omitempty"`
+		Secret   string                 `firestore:"-"`
+		Metadata map[string]interface{} `firestore:"metadata"`
+	}
+
+	in := record{
+		Base:     Base{CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)},
+		Name:     "passport",
+		Secret:   "hidden",
+		Metadata: map[string]interface{}{"pages": []interface{}{"1", "2"}},
+	}
+	raw, err := encodeMongo(in)
+	if err != nil {
+		t.Fatalf("encodeMongo() error = %v", err)
+	}
+
+	var fields map[string]interface{}
+	if err := bson.Unmarshal(raw, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"created_at", "name", "metadata"} {
+		if _, ok := fields[key]; !ok {
+			t.Errorf("encoded document has no %s: %v", key, fields)
+		}
+	}
+	for _, key := range []string{"note", "Secret", "Base"} {
+		if _, ok := fields[key]; ok {
+			t.Errorf("encoded document has %s: %v", key, fields)
+		}
+	}
+
+	var out record
+	if err := decodeMongo(raw, &out); err != nil {
+		t.Fatalf("decodeMongo() error = %v", err)
+	}
+	if !out.CreatedAt.Equal(in.CreatedAt) || out.Name != in.Name || !reflect.DeepEqual(out.Metadata, in.Metadata) {
+		t.Errorf("decodeMongo() = %+v, want %+v", out, in)
+	}
+}
@@ -0,0 +1,267 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// Short names of the query operators, for use with the query builder.
+const (
+	Eq               = QueryOperatorEqual
+	Ne               = QueryOperatorNotEqual
+	Lt               = QueryOperatorLessThan
+	Lte              = QueryOperatorLessThanOrEqual
+	Gt               = QueryOperatorGreaterThan
+	Gte              = QueryOperatorGreaterThanOrEqual
+	In               = QueryOperatorIn
+	NotIn            = QueryOperatorNotIn
+	ArrayContains    = QueryOperatorArrayContains
+	ArrayContainsAny = QueryOperatorArrayContainsAny
+)
+
+// ErrInvalidQuery is returned for queries that misuse a field or an operator, before they are
+// sent to Firestore.
+var ErrInvalidQuery = errors.New("invalid query")
+
+// Direction is the sort direction of a query order.
+type Direction string
+
+const (
+	// Asc sorts from the lowest to the highest value.
+	Asc Direction = "asc"
+	// Desc sorts from the highest to the lowest value.
+	Desc Direction = "desc"
+)
+
+// QueryOrder is a field a query is sorted on.
+type QueryOrder struct {
+	Path      string
+	Direction Direction
+}
+
+// QueryBuilder builds a query on a collection of T. Field paths are checked against the firestore
+// tags of T and operators against their values as the query is built, the first mistake is
+// returned by Err and by the repository running the query.
+//
+//	query := db.Query[models.Document]().
+//		Where("user_id", db.Eq, userID).
+//		OrderBy("created_at", db.Desc).
+//		Limit(20)
+type QueryBuilder[T any] struct {
+	constraints  []QueryConstraint
+	orders       []QueryOrder
+	limit        int
+	pageToken    string
+	includeTotal bool
+	err          error
+}
+
+// Query starts a query on a collection of T, which matches every document until it is filtered.
+func Query[T any]() *QueryBuilder[T] {
+	return &QueryBuilder[T]{}
+}
+
+// Where filters the query on the field at path, multiple filters are combined with logical AND.
+func (q *QueryBuilder[T]) Where(path string, op QueryOperator, value interface{}) *QueryBuilder[T] {
+	if err := checkPath[T](path); err != nil {
+		return q.fail(err)
+	}
+	if err := checkOperator(op, value); err != nil {
+		return q.fail(fmt.Errorf("filter on %s: %w", path, err))
+	}
+
+	q.constraints = append(q.constraints, QueryConstraint{Path: path, Op: op, Value: value})
+
+	return q
+}
+
+// OrderBy sorts the query on the field at path. Orders apply in the order they are added, and
+// documents that sort the same are ordered by document ID so pages are stable.
+func (q *QueryBuilder[T]) OrderBy(path string, direction Direction) *QueryBuilder[T] {
+	if err := checkPath[T](path); err != nil {
+		return q.fail(err)
+	}
+	if direction != Asc && direction != Desc {
+		return q.fail(fmt.Errorf("order on %s: unknown direction %q", path, direction))
+	}
+
+	q.orders = append(q.orders, QueryOrder{Path: path, Direction: direction})
+
+	return q
+}
+
+// Limit caps the number of documents the query returns, 0 returns every matching document.
+func (q *QueryBuilder[T]) Limit(limit int) *QueryBuilder[T] {
+	if limit < 0 {
+		return q.fail(fmt.Errorf("limit must not be negative, got %d", limit))
+	}
+
+	q.limit = limit
+
+	return q
+}
+
+// After continues the query after the document the page token refers to, as returned with the
+// previous page. An empty token starts at the first document.
+func (q *QueryBuilder[T]) After(pageToken string) *QueryBuilder[T] {
+	q.pageToken = pageToken
+
+	return q
+}
+
+// IncludeTotal makes FindPage also count the documents matching the query across all pages.
+func (q *QueryBuilder[T]) IncludeTotal(include bool) *QueryBuilder[T] {
+	q.includeTotal = include
+
+	return q
+}
+
+// Constraints returns the filters of the query, e.g. to count the documents matching it.
+func (q *QueryBuilder[T]) Constraints() []QueryConstraint {
+	return q.constraints
+}
+
+// Err returns the first mistake made while building the query.
+func (q *QueryBuilder[T]) Err() error {
+	return q.err
+}
+
+// queryOf returns a query with the constraints as its filters, validating each of them.
+func queryOf[T any](queries []QueryConstraint) *QueryBuilder[T] {
+	query := Query[T]()
+	for _, q := range queries {
+		query.Where(q.Path, q.Op, q.Value)
+	}
+
+	return query
+}
+
+// fail records the first mistake made while building the query.
+func (q *QueryBuilder[T]) fail(err error) *QueryBuilder[T] {
+	if q.err == nil {
+		q.err = fmt.Errorf("%w on %s: %w", ErrInvalidQuery, reflect.TypeFor[T]().Name(), err)
+	}
+
+	return q
+}
+
+// compile applies the filters and orders of the query to fsQuery. The page token and limit are
+// applied by the repository, which needs the collection to resolve the token.
+func (q *QueryBuilder[T]) compile(fsQuery firestore.Query) firestore.Query {
+	for _, c := range q.constraints {
+		fsQuery = fsQuery.Where(c.Path, string(c.Op), c.Value)
+	}
+	for _, order := range q.orders {
+		direction := firestore.Asc
+		if order.Direction == Desc {
+			direction = firestore.Desc
+		}
+		fsQuery = fsQuery.OrderBy(order.Path, direction)
+	}
+
+	return fsQuery.OrderBy(firestore.DocumentID, firestore.Asc)
+}
+
+// checkOperator checks that op is a known operator and that value fits it: the list operators
+// take a non-empty slice of at most maxListValues values.
+func checkOperator(op QueryOperator, value interface{}) error {
+	if !op.Valid() {
+		return fmt.Errorf("unknown operator %q", op)
+	}
+
+	if op != In && op != NotIn && op != ArrayContainsAny {
+		return nil
+	}
+
+	list := reflect.ValueOf(value)
+	if list.Kind() != reflect.Slice && list.Kind() != reflect.Array {
+		return fmt.Errorf("operator %s needs a list of values, got %T", op, value)
+	}
+	if list.Len() == 0 || list.Len() > maxListValues {
+		return fmt.Errorf("operator %s needs between 1 and %d values, got %d", op, maxListValues, list.Len())
+	}
+
+	return nil
+}
+
+// maxListValues is the most values Firestore accepts for the in, not-in and array-contains-any operators.
+const maxListValues = 30
+
+// timeType is stored by Firestore as a timestamp rather than a map of its fields.
+var timeType = reflect.TypeFor[time.Time]()
+
+// checkPath checks that path, with dots between nested fields, names a field of T by its
+// firestore tag. Any key below a map field is accepted.
+func checkPath[T any](path string) error {
+	if path == firestore.DocumentID {
+		return nil
+	}
+	if path == "" {
+		return errors.New("empty field path")
+	}
+
+	typ := reflect.TypeFor[T]()
+	segments := strings.Split(path, ".")
+	for i, segment := range segments {
+		for typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+		if typ.Kind() == reflect.Map || typ.Kind() == reflect.Interface {
+			return nil
+		}
+		if typ.Kind() != reflect.Struct || typ == timeType {
+			return fmt.Errorf("field %s has no field %s", strings.Join(segments[:i], "."), segment)
+		}
+
+		field, ok := firestoreField(typ, segment)
+		if !ok {
+			return fmt.Errorf("unknown field %s", strings.Join(segments[:i+1], "."))
+		}
+		typ = field.Type
+	}
+
+	return nil
+}
+
+// firestoreField returns the field of the struct type stored under name, following the naming
+// rules of the Firestore client: the tag name, else the Go name, with untagged embedded structs
+// flattened into their parent.
+func firestoreField(typ reflect.Type, name string) (reflect.StructField, bool) {
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag, _, _ := strings.Cut(field.Tag.Get("firestore"), ",")
+		if tag == "-" {
+			continue
+		}
+		if tag == "" && field.Anonymous {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if nested, ok := firestoreField(embedded, name); ok {
+					return nested, true
+				}
+
+				continue
+			}
+		}
+		if tag == "" {
+			tag = field.Name
+		}
+		if tag == name {
+			return field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
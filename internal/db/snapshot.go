@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+)
+
+// ErrReadOnly is returned by repository writes made with the context of a read-only transaction.
+var ErrReadOnly = errors.New("write in read-only transaction")
+
+type readOnlyKey struct{}
+
+// readOnlyTx is the read-only transaction of a context and the client it was started on.
+type readOnlyTx struct {
+	client *firestore.Client
+	tx     *firestore.Transaction
+}
+
+// Snapshotter runs reads against a consistent snapshot of a Firestore database, so a view
+// assembled from several reads is not torn by concurrent writes. A nil Snapshotter runs the
+// reads without a snapshot.
+type Snapshotter struct {
+	client *firestore.Client
+}
+
+// NewSnapshotter creates a Snapshotter for the database of client.
+func NewSnapshotter(client *firestore.Client) *Snapshotter {
+	return &Snapshotter{client: client}
+}
+
+// ReadOnly runs fn in a read-only transaction. Every read fn makes with the context it is given,
+// through repositories using the same client, sees the database as of the same moment; writes
+// fail with ErrReadOnly. Reads may run concurrently, and fn may be called again if the
+// transaction is aborted, so it must only collect what it reads.
+//
+// A ReadOnly inside another runs fn in the outer transaction.
+func (s *Snapshotter) ReadOnly(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s == nil {
+		return fn(ctx)
+	}
+	if current, ok := ctx.Value(readOnlyKey{}).(readOnlyTx); ok && current.client == s.client {
+		return fn(ctx)
+	}
+
+	return s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		return fn(context.WithValue(ctx, readOnlyKey{}, readOnlyTx{client: s.client, tx: tx}))
+	}, firestore.ReadOnly)
+}
+
+// readOnly returns the read-only transaction of ctx when it was started on client.
+func readOnly(ctx context.Context, client *firestore.Client) (*firestore.Transaction, bool) {
+	current, ok := ctx.Value(readOnlyKey{}).(readOnlyTx)
+	if !ok || current.client != client {
+		return nil, false
+	}
+
+	return current.tx, true
+}
+
+// detached returns a context without the values of ctx, so a transaction can be run from inside
+// a read-only one, which cancels with ctx. The caller must call the returned function.
+func detached(ctx context.Context) (context.Context, context.CancelFunc) {
+	out, cancel := context.WithCancel(context.Background())
+	stop := context.AfterFunc(ctx, cancel)
+
+	return out, func() {
+		stop()
+		cancel()
+	}
+}
+
+// writable fails writes made with the context of a read-only transaction on the repository's client.
+func (r *firestoreRepository[T]) writable(ctx context.Context) error {
+	if _, ok := readOnly(ctx, r.client); ok {
+		return fmt.Errorf("failed to write to %s: %w", r.collectionName, ErrReadOnly)
+	}
+
+	return nil
+}
+
+// get reads a document, in the read-only transaction of ctx if there is one.
+func (r *firestoreRepository[T]) get(ctx context.Context, ref *firestore.DocumentRef) (*firestore.DocumentSnapshot, error) {
+	if tx, ok := readOnly(ctx, r.client); ok {
+		return tx.Get(ref)
+	}
+
+	return ref.Get(ctx)
+}
+
+// documents runs a query, in the read-only transaction of ctx if there is one.
+func (r *firestoreRepository[T]) documents(ctx context.Context, query firestore.Query) *firestore.DocumentIterator {
+	if tx, ok := readOnly(ctx, r.client); ok {
+		return tx.Documents(query)
+	}
+
+	return query.Documents(ctx)
+}
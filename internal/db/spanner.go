@@ -0,0 +1,547 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/api/iterator"
+	spannerapi "google.golang.org/api/spanner/v1"
+
+	"github.com/thoughtgears/shared-services/internal/fieldcrypt"
+	"github.com/thoughtgears/shared-services/pkg/apperr"
+)
+
+// spannerPollInterval is how often a change stream of a Spanner repository looks for writes.
+const spannerPollInterval = 5 * time.Second
+
+// spannerChangeBatch is the most changes a change stream of a Spanner repository reads at once.
+const spannerChangeBatch = 500
+
+// spannerRepository implements the DB interface for a collection kept in a Cloud Spanner table,
+// see SpannerTable. Rows are keyed by the collection path and the document ID, and queries
+// follow the Firestore semantics of the repository's callers, see SpannerTable.condition.
+type spannerRepository[T any] struct {
+	client         *SpannerClient
+	table          SpannerTable
+	collectionName string
+	migrations     *MigrationRegistry
+	cipher         *fieldcrypt.Cipher
+	plaintextPaths []string
+	totals         totalCache
+}
+
+// NewSpannerRepository creates a new instance of spannerRepository for a specific type.
+// It implements the DB interface for the given type T on a Cloud Spanner database, whose table
+// for the collection is created by SpannerClient.EnsureTables. Read-only transactions of a
+// Snapshotter do not apply to it.
+//
+// Parameters:
+//   - client: Client of the Spanner database
+//   - collectionName: Path of the collection, the table is named after its last segment
+//   - opts: Optional repository options, e.g. WithMigrations
+//
+// Returns:
+//   - DB[T]: A repository instance for the specified type
+func NewSpannerRepository[T any](client *SpannerClient, collectionName string, opts ...RepositoryOption) DB[T] {
+	var settings repositoryOptions
+	for _, opt := range opts {
+		opt(&settings)
+	}
+
+	return &spannerRepository[T]{
+		client:         client,
+		table:          NewSpannerTable[T](collectionName),
+		collectionName: collectionName,
+		migrations:     settings.migrations,
+		cipher:         settings.cipher,
+		plaintextPaths: settings.plaintextPaths,
+	}
+}
+
+// GetAll retrieves all documents from the collection with optional pagination, ordered by ID.
+func (r *spannerRepository[T]) GetAll(ctx context.Context, pageToken string, pageSize int) ([]*T, string, error) {
+	stmt, err := spannerQuery(r.table, r.collectionName, nil, nil, pageToken, max(pageSize, 0))
+	if err != nil {
+		return nil, "", err
+	}
+
+	results, lastID, err := r.find(ctx, stmt)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to iterate documents: %w", err)
+	}
+
+	nextPageToken := ""
+	if pageSize > 0 && len(results) == pageSize {
+		nextPageToken = lastID
+	}
+
+	return results, nextPageToken, nil
+}
+
+// GetByID retrieves a single document by its ID.
+func (r *spannerRepository[T]) GetByID(ctx context.Context, id string) (*T, error) {
+	var row map[string]interface{}
+	err := r.client.withSession(ctx, func(session string) error {
+		var err error
+		row, err = r.get(ctx, func(ctx context.Context, stmt *spannerStatement) (*spannerapi.ResultSet, error) {
+			return r.client.execute(ctx, session, stmt, nil)
+		}, id)
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document %s: %w", id, err)
+	}
+	if row == nil {
+		return nil, apperr.Errorf(apperr.NotFound, "document with id %s not found", id)
+	}
+
+	return r.decode(ctx, row)
+}
+
+// GetByQuery retrieves documents matching the specified query constraints with optional pagination.
+func (r *spannerRepository[T]) GetByQuery(ctx context.Context, queries []QueryConstraint, pageToken string, pageSize int) ([]*T, string, error) {
+	return r.Find(ctx, queryOf[T](queries).After(pageToken).Limit(max(pageSize, 0)))
+}
+
+// Find retrieves the documents matching a query built with Query, see QueryBuilder. Pages
+// continue after the page token document in the order of the query, then of the document ID.
+func (r *spannerRepository[T]) Find(ctx context.Context, query *QueryBuilder[T]) ([]*T, string, error) {
+	if err := query.Err(); err != nil {
+		return nil, "", err
+	}
+
+	stmt, err := spannerQuery(r.table, r.collectionName, query.constraints, query.orders, query.pageToken, query.limit)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %w", ErrInvalidQuery, err)
+	}
+
+	results, lastID, err := r.find(ctx, stmt)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to iterate query documents: %w", err)
+	}
+
+	nextPageToken := ""
+	if query.limit > 0 && len(results) == query.limit {
+		nextPageToken = lastID
+	}
+
+	return results, nextPageToken, nil
+}
+
+// FindPage retrieves a page of the documents matching the query like Find, with the total count
+// for queries with IncludeTotal, see firestoreRepository.FindPage.
+func (r *spannerRepository[T]) FindPage(ctx context.Context, query *QueryBuilder[T]) (*Page[T], error) {
+	return findPage(ctx, r, &r.totals, r.collectionName, query, true)
+}
+
+// Create adds a new document to the collection with the specified ID.
+// If the document already exists, it will be overwritten.
+func (r *spannerRepository[T]) Create(ctx context.Context, id string, data map[string]interface{}) (*T, error) {
+	write, err := r.document(ctx, id, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.client.apply(ctx, []*spannerapi.Mutation{{Replace: write}}); err != nil {
+		return nil, fmt.Errorf("failed to create document: %w", err)
+	}
+	r.totals.clear()
+
+	document, err := r.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get created document: %w", err)
+	}
+
+	return document, nil
+}
+
+// PrepareCreate prepares the creation of a document without writing it, so it can be committed
+// atomically with writes to other collections of the same database using Commit.
+func (r *spannerRepository[T]) PrepareCreate(ctx context.Context, id string, data map[string]interface{}) (Write, error) {
+	write, err := r.document(ctx, id, data)
+	if err != nil {
+		return Write{}, err
+	}
+
+	return Write{spanner: r.client, mutation: &spannerapi.Mutation{Insert: write}}, nil
+}
+
+// Update modifies specific fields of a document, nested maps are merged field by field.
+// A document that does not exist is created.
+func (r *spannerRepository[T]) Update(ctx context.Context, id string, data map[string]interface{}) (*T, error) {
+	return r.update(ctx, id, nil, data)
+}
+
+// UpdateIfUnchanged modifies specific fields of a document like Update, provided the document
+// still exists and its updated_at field still holds updatedAt, the value read before.
+func (r *spannerRepository[T]) UpdateIfUnchanged(ctx context.Context, id string, updatedAt time.Time, data map[string]interface{}) (*T, error) {
+	return r.update(ctx, id, &updatedAt, data)
+}
+
+// update merges data into the document id in a read-write transaction, provided its updated_at
+// field holds updatedAt when that is set.
+func (r *spannerRepository[T]) update(ctx context.Context, id string, updatedAt *time.Time, data map[string]interface{}) (*T, error) {
+	if err := r.table.check(data); err != nil {
+		return nil, err
+	}
+	if err := r.encrypt(ctx, id, data); err != nil {
+		return nil, err
+	}
+
+	err := r.client.readWrite(ctx, func(tx *spannerTx) ([]*spannerapi.Mutation, error) {
+		row, err := r.get(ctx, tx.query, id)
+		if err != nil {
+			return nil, err
+		}
+		if updatedAt != nil && (row == nil || !sameValue(row[UpdatedAtField], updatedAt.Truncate(time.Millisecond))) {
+			return nil, ErrChanged
+		}
+
+		document := make(map[string]interface{})
+		if row != nil {
+			document = documentData(row)
+		}
+		if err := mergeData(document, data, time.Now().UTC()); err != nil {
+			return nil, err
+		}
+
+		write, err := r.table.write(r.collectionName, id, document)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*spannerapi.Mutation{{InsertOrUpdate: write}}, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update document %s: %w", id, err)
+	}
+	r.totals.clear()
+
+	document, err := r.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get updated document %s: %w", id, err)
+	}
+
+	return document, nil
+}
+
+// Delete removes a document from the collection.
+func (r *spannerRepository[T]) Delete(ctx context.Context, id string) error {
+	mutation := &spannerapi.Mutation{Delete: &spannerapi.Delete{Table: r.table.name, KeySet: r.table.key(r.collectionName, id)}}
+	err := r.client.apply(ctx, []*spannerapi.Mutation{mutation})
+	r.totals.clear()
+	if err != nil {
+		return fmt.Errorf("failed to delete document %s: %w", id, err)
+	}
+
+	return nil
+}
+
+// Count returns the number of documents matching the query constraints.
+// An empty slice of constraints counts the whole collection.
+func (r *spannerRepository[T]) Count(ctx context.Context, queries []QueryConstraint) (int64, error) {
+	if err := queryOf[T](queries).Err(); err != nil {
+		return 0, err
+	}
+
+	stmt, err := spannerCount(r.table, r.collectionName, queries)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrInvalidQuery, err)
+	}
+
+	result, err := r.client.query(ctx, stmt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count documents: %w", err)
+	}
+	rows, err := spannerRows(result)
+	if err != nil || len(rows) != 1 {
+		return 0, fmt.Errorf("failed to read document count: %w", err)
+	}
+	count, _ := rows[0]["count"].(int64)
+
+	return count, nil
+}
+
+// Changes streams the changes to the collection like firestoreRepository.Changes, starting with
+// the documents written after since. Spanner tables have no listener, so the stream polls for
+// documents with a later updated_at; deletions are not seen, and neither are writes whose
+// updated_at is older than the latest change already returned.
+func (r *spannerRepository[T]) Changes(ctx context.Context, since time.Time) (ChangeStream[T], error) {
+	if _, ok := r.table.column(UpdatedAtField); !ok {
+		return nil, fmt.Errorf("failed to watch %s: spanner table %s has no %s column", r.collectionName, r.table.name, UpdatedAtField)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	return &spannerChangeStream[T]{ctx: ctx, cancel: cancel, repo: r, since: since}, nil
+}
+
+// find runs a query and decodes its documents, it also returns the ID of the last document.
+func (r *spannerRepository[T]) find(ctx context.Context, stmt *spannerStatement) ([]*T, string, error) {
+	result, err := r.client.query(ctx, stmt)
+	if err != nil {
+		return nil, "", err
+	}
+	rows, err := spannerRows(result)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var results []*T
+	var lastID string
+	for _, row := range rows {
+		data, err := r.decode(ctx, row)
+		if err != nil {
+			return nil, "", err
+		}
+
+		results = append(results, data)
+		lastID, _ = row[spannerIDColumn].(string)
+	}
+
+	return results, lastID, nil
+}
+
+// get reads the row of the document id with query, nil when there is none.
+func (r *spannerRepository[T]) get(ctx context.Context, query func(ctx context.Context, stmt *spannerStatement) (*spannerapi.ResultSet, error), id string) (map[string]interface{}, error) {
+	stmt, err := spannerQuery(r.table, r.collectionName, []QueryConstraint{{Path: "__name__", Op: Eq, Value: id}}, nil, "", 1)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := query(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := spannerRows(result)
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+
+	return rows[0], nil
+}
+
+// document returns the row of new document data, stamped with the latest schema version and
+// encrypted.
+func (r *spannerRepository[T]) document(ctx context.Context, id string, data map[string]interface{}) (*spannerapi.Write, error) {
+	if r.migrations != nil {
+		if _, ok := data[SchemaVersionField]; !ok {
+			data[SchemaVersionField] = r.migrations.LatestVersion()
+		}
+	}
+	if err := r.table.check(data); err != nil {
+		return nil, err
+	}
+	if err := r.encrypt(ctx, id, data); err != nil {
+		return nil, err
+	}
+
+	write, err := r.table.write(r.collectionName, id, resolveData(data, time.Now().UTC()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode document %s: %w", id, err)
+	}
+
+	return write, nil
+}
+
+// encrypt encrypts the tagged fields in data before it is written to the document id, if field
+// encryption is enabled. Documents rewritten by migrate keep their stored ciphertext instead.
+func (r *spannerRepository[T]) encrypt(ctx context.Context, id string, data map[string]interface{}) error {
+	if r.cipher == nil {
+		return nil
+	}
+
+	if err := r.cipher.EncryptData(ctx, reflect.TypeFor[T](), r.collectionName, id, data, r.plaintextPaths); err != nil {
+		return fmt.Errorf("failed to encrypt document data: %w", err)
+	}
+
+	return nil
+}
+
+// decode converts a row to T, migrating it first like firestoreRepository.decode.
+func (r *spannerRepository[T]) decode(ctx context.Context, row map[string]interface{}) (*T, error) {
+	id, _ := row[spannerIDColumn].(string)
+
+	data := documentData(row)
+	if r.migrations != nil && schemaVersion(data) < r.migrations.LatestVersion() {
+		migrated, err := r.migrate(ctx, id, data)
+		if err != nil {
+			return nil, err
+		}
+		data = migrated
+	}
+
+	raw, err := encodeMongo(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert document data: %w", err)
+	}
+	var result T
+	if err := decodeMongo(raw, &result); err != nil {
+		return nil, fmt.Errorf("failed to convert document data: %w", err)
+	}
+
+	if r.cipher != nil {
+		if err := r.cipher.DecryptStruct(ctx, r.collectionName, id, &result); err != nil {
+			return nil, fmt.Errorf("failed to decrypt document %s: %w", id, err)
+		}
+	}
+
+	return &result, nil
+}
+
+// migrate upgrades the fields of a document to the latest schema version and writes them back,
+// unless its schema version or update time changed since it was read. Fields the migrations
+// leave without a column are dropped. It returns the migrated fields.
+func (r *spannerRepository[T]) migrate(ctx context.Context, id string, data map[string]interface{}) (map[string]interface{}, error) {
+	version, updatedAt := data[SchemaVersionField], data[UpdatedAtField]
+
+	changed, err := r.migrations.Migrate(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate document %s: %w", id, err)
+	}
+	if !changed {
+		return data, nil
+	}
+
+	err = r.client.readWrite(ctx, func(tx *spannerTx) ([]*spannerapi.Mutation, error) {
+		row, err := r.get(ctx, tx.query, id)
+		if err != nil || row == nil || !sameValue(row[SchemaVersionField], version) || !sameValue(row[UpdatedAtField], updatedAt) {
+			return nil, err
+		}
+
+		write, err := r.table.write(r.collectionName, id, data)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*spannerapi.Mutation{{Update: write}}, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write migrated document %s: %w", id, err)
+	}
+
+	return data, nil
+}
+
+// sameValue reports whether two column values are the same, times compared as instants.
+func sameValue(a, b interface{}) bool {
+	if t, ok := a.(time.Time); ok {
+		u, ok := b.(time.Time)
+
+		return ok && t.Equal(u)
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// spannerChangeStream is a ChangeStream polling a Spanner table for documents written after the
+// latest change it returned, in the order of updated_at then document ID.
+type spannerChangeStream[T any] struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	repo    *spannerRepository[T]
+	stopped atomic.Bool
+
+	// mu is held by Next, the cursor is the updated_at and ID of the latest change returned
+	mu      sync.Mutex
+	since   time.Time
+	lastID  string
+	pending []*Change[T]
+}
+
+// Next returns the next change, polling until there is one.
+func (s *spannerChangeStream[T]) Next() (*Change[T], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(s.pending) == 0 {
+		if s.stopped.Load() {
+			return nil, iterator.Done
+		}
+
+		changes, err := s.poll()
+		if err != nil {
+			if s.stopped.Load() {
+				return nil, iterator.Done
+			}
+
+			return nil, err
+		}
+		if len(changes) > 0 {
+			s.pending = changes
+
+			break
+		}
+
+		select {
+		case <-s.ctx.Done():
+			if s.stopped.Load() {
+				return nil, iterator.Done
+			}
+
+			return nil, s.ctx.Err()
+		case <-time.After(spannerPollInterval):
+		}
+	}
+
+	change := s.pending[0]
+	s.pending = s.pending[1:]
+	s.since, s.lastID = change.Time, change.ID
+
+	return change, nil
+}
+
+// Stop ends the stream.
+func (s *spannerChangeStream[T]) Stop() {
+	s.stopped.Store(true)
+	s.cancel()
+}
+
+// poll reads the next documents after the cursor.
+func (s *spannerChangeStream[T]) poll() ([]*Change[T], error) {
+	var constraints []QueryConstraint
+	switch {
+	case s.lastID == "" && s.since.IsZero():
+	case s.lastID == "":
+		constraints = []QueryConstraint{{Path: UpdatedAtField, Op: Gt, Value: s.since}}
+	default:
+		// Documents written at the same time as the latest change follow it by ID
+		constraints = []QueryConstraint{{Path: UpdatedAtField, Op: Gte, Value: s.since}}
+	}
+
+	stmt, err := spannerQuery(s.repo.table, s.repo.collectionName, constraints,
+		[]QueryOrder{{Path: UpdatedAtField, Direction: Asc}}, "", spannerChangeBatch)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.repo.client.query(s.ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := spannerRows(result)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]*Change[T], 0, len(rows))
+	for _, row := range rows {
+		id, _ := row[spannerIDColumn].(string)
+		updatedAt, _ := row[UpdatedAtField].(time.Time)
+		if s.lastID != "" && updatedAt.Equal(s.since) && id <= s.lastID {
+			continue
+		}
+
+		data, err := s.repo.decode(s.ctx, row)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, &Change[T]{Kind: ChangeSet, ID: id, Data: data, Time: updatedAt.UTC()})
+	}
+
+	return changes, nil
+}
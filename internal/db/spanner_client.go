@@ -0,0 +1,308 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/googleapi"
+	spannerapi "google.golang.org/api/spanner/v1"
+)
+
+const (
+	// maxIdleSpannerSessions caps the sessions kept for reuse, the server deletes the others once
+	// they have been idle for an hour.
+	maxIdleSpannerSessions = 100
+	// maxSpannerAttempts is how often a transaction aborted by Spanner is attempted.
+	maxSpannerAttempts = 5
+	// spannerOperationPoll is how often a schema change is checked until it is done.
+	spannerOperationPoll = 2 * time.Second
+)
+
+// SpannerClient runs queries and transactions on a Cloud Spanner database through its REST API,
+// reusing its sessions.
+type SpannerClient struct {
+	service  *spannerapi.Service
+	database string
+
+	mu   sync.Mutex
+	idle []string
+}
+
+// NewSpannerClient creates a client for the database, named by its resource name
+// projects/<project>/instances/<instance>/databases/<database>.
+func NewSpannerClient(service *spannerapi.Service, database string) *SpannerClient {
+	return &SpannerClient{service: service, database: database}
+}
+
+// Close deletes the idle sessions of the client.
+func (c *SpannerClient) Close(ctx context.Context) error {
+	c.mu.Lock()
+	idle := c.idle
+	c.idle = nil
+	c.mu.Unlock()
+
+	var errs []error
+	for _, session := range idle {
+		if _, err := c.service.Projects.Instances.Databases.Sessions.Delete(session).Context(ctx).Do(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete spanner session %s: %w", session, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// EnsureTables creates the tables the collections are kept in, and adds the columns existing
+// tables lack. Columns are never changed or dropped, that is left to a manual schema change.
+func (c *SpannerClient) EnsureTables(ctx context.Context, tables ...SpannerTable) error {
+	result, err := c.query(ctx, &spannerStatement{
+		sql: "SELECT table_name, column_name FROM information_schema.columns WHERE table_schema = ''",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read spanner schema: %w", err)
+	}
+	rows, err := spannerRows(result)
+	if err != nil {
+		return fmt.Errorf("failed to read spanner schema: %w", err)
+	}
+
+	existing := make(map[string]map[string]bool)
+	for _, row := range rows {
+		table, _ := row["table_name"].(string)
+		column, _ := row["column_name"].(string)
+		if existing[table] == nil {
+			existing[table] = make(map[string]bool)
+		}
+		existing[table][column] = true
+	}
+
+	var statements []string
+	for _, table := range tables {
+		columns, ok := existing[table.name]
+		if !ok {
+			statements = append(statements, table.createStatement())
+			existing[table.name] = make(map[string]bool)
+			for _, column := range table.columnNames() {
+				existing[table.name][column] = true
+			}
+
+			continue
+		}
+		for _, column := range table.columns {
+			if !columns[column.name] {
+				statements = append(statements, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", quoteSpanner(table.name), column.definition()))
+				columns[column.name] = true
+			}
+		}
+	}
+	if len(statements) == 0 {
+		return nil
+	}
+
+	databases := c.service.Projects.Instances.Databases
+	op, err := databases.UpdateDdl(c.database, &spannerapi.UpdateDatabaseDdlRequest{Statements: statements}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("failed to update spanner schema: %w", err)
+	}
+	for !op.Done {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to wait for spanner schema update %s: %w", op.Name, ctx.Err())
+		case <-time.After(spannerOperationPoll):
+		}
+
+		if op, err = databases.Operations.Get(op.Name).Context(ctx).Do(); err != nil {
+			return fmt.Errorf("failed to get spanner schema update: %w", err)
+		}
+	}
+	if op.Error != nil {
+		return fmt.Errorf("failed to update spanner schema: %s", op.Error.Message)
+	}
+
+	return nil
+}
+
+// query runs a query in a single-use read-only transaction with strong reads.
+func (c *SpannerClient) query(ctx context.Context, stmt *spannerStatement) (*spannerapi.ResultSet, error) {
+	var result *spannerapi.ResultSet
+	err := c.withSession(ctx, func(session string) error {
+		var err error
+		result, err = c.execute(ctx, session, stmt, nil)
+
+		return err
+	})
+
+	return result, err
+}
+
+// execute runs a statement in the transaction, a single-use read-only one when it is nil. The
+// whole result is returned at once, so queries must stay within the 10 MiB the API returns.
+func (c *SpannerClient) execute(ctx context.Context, session string, stmt *spannerStatement, tx *spannerapi.TransactionSelector) (*spannerapi.ResultSet, error) {
+	params, err := json.Marshal(stmt.params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode query parameters: %w", err)
+	}
+
+	request := &spannerapi.ExecuteSqlRequest{
+		Sql:         stmt.sql,
+		Params:      params,
+		ParamTypes:  stmt.paramTypes,
+		Transaction: tx,
+	}
+
+	return c.service.Projects.Instances.Databases.Sessions.ExecuteSql(session, request).Context(ctx).Do()
+}
+
+// spannerTx is a read-write transaction of a session.
+type spannerTx struct {
+	client  *SpannerClient
+	session string
+	id      string
+}
+
+// query runs a query in the transaction, locking the rows it reads.
+func (tx *spannerTx) query(ctx context.Context, stmt *spannerStatement) (*spannerapi.ResultSet, error) {
+	return tx.client.execute(ctx, tx.session, stmt, &spannerapi.TransactionSelector{Id: tx.id})
+}
+
+// readWrite runs fn in a read-write transaction and commits the mutations it returns. Spanner
+// aborts transactions that conflict, they are run again.
+func (c *SpannerClient) readWrite(ctx context.Context, fn func(tx *spannerTx) ([]*spannerapi.Mutation, error)) error {
+	sessions := c.service.Projects.Instances.Databases.Sessions
+
+	return c.withSession(ctx, func(session string) error {
+		return retryAborted(ctx, func() error {
+			begin := &spannerapi.BeginTransactionRequest{Options: &spannerapi.TransactionOptions{ReadWrite: &spannerapi.ReadWrite{}}}
+			transaction, err := sessions.BeginTransaction(session, begin).Context(ctx).Do()
+			if err != nil {
+				return err
+			}
+
+			mutations, err := fn(&spannerTx{client: c, session: session, id: transaction.Id})
+			if err != nil {
+				// Rolling back releases the locks early, they expire with the transaction otherwise
+				_, _ = sessions.Rollback(session, &spannerapi.RollbackRequest{TransactionId: transaction.Id}).Context(ctx).Do()
+
+				return err
+			}
+
+			_, err = sessions.Commit(session, &spannerapi.CommitRequest{TransactionId: transaction.Id, Mutations: mutations}).Context(ctx).Do()
+
+			return err
+		})
+	})
+}
+
+// apply commits mutations in a single-use read-write transaction.
+func (c *SpannerClient) apply(ctx context.Context, mutations []*spannerapi.Mutation) error {
+	return c.withSession(ctx, func(session string) error {
+		return retryAborted(ctx, func() error {
+			commit := &spannerapi.CommitRequest{
+				SingleUseTransaction: &spannerapi.TransactionOptions{ReadWrite: &spannerapi.ReadWrite{}},
+				Mutations:            mutations,
+			}
+			_, err := c.service.Projects.Instances.Databases.Sessions.Commit(session, commit).Context(ctx).Do()
+
+			return err
+		})
+	})
+}
+
+// withSession runs fn with a session of the pool, and again with a new session when the server
+// no longer knows the one it got.
+func (c *SpannerClient) withSession(ctx context.Context, fn func(session string) error) error {
+	for attempt := 0; ; attempt++ {
+		session, err := c.acquire(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = fn(session)
+		if sessionNotFound(err) {
+			if attempt == 0 {
+				continue
+			}
+
+			return err
+		}
+		c.release(session)
+
+		return err
+	}
+}
+
+// acquire returns an idle session, or creates one.
+func (c *SpannerClient) acquire(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	if n := len(c.idle); n > 0 {
+		session := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+
+		return session, nil
+	}
+	c.mu.Unlock()
+
+	session, err := c.service.Projects.Instances.Databases.Sessions.Create(c.database, &spannerapi.CreateSessionRequest{}).Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("failed to create spanner session: %w", err)
+	}
+
+	return session.Name, nil
+}
+
+// release returns a session to the pool.
+func (c *SpannerClient) release(session string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.idle) < maxIdleSpannerSessions {
+		c.idle = append(c.idle, session)
+	}
+}
+
+// retryAborted runs fn until it is not aborted by Spanner, at most maxSpannerAttempts times.
+func retryAborted(ctx context.Context, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if spannerStatus(err) != "ABORTED" || attempt == maxSpannerAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * 10 * time.Millisecond):
+		}
+	}
+}
+
+// sessionNotFound reports whether err is the server no longer knowing a session.
+func sessionNotFound(err error) bool {
+	var apiErr *googleapi.Error
+
+	return spannerStatus(err) == "NOT_FOUND" && errors.As(err, &apiErr) && strings.Contains(apiErr.Message, "Session not found")
+}
+
+// spannerStatus returns the canonical status of a failed Spanner API call, e.g. ABORTED.
+func spannerStatus(err error) string {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return ""
+	}
+
+	var body struct {
+		Error struct {
+			Status string `json:"status"`
+		} `json:"error"`
+	}
+	if json.Unmarshal([]byte(apiErr.Body), &body) != nil {
+		return ""
+	}
+
+	return body.Error.Status
+}
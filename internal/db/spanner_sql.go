@@ -0,0 +1,790 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	spannerapi "google.golang.org/api/spanner/v1"
+)
+
+// The primary key of every Spanner table: the path of the collection holding the document and
+// the document ID.
+const (
+	spannerCollectionColumn = "collection_path"
+	spannerIDColumn         = "doc_id"
+)
+
+// Spanner column types of the document fields.
+const (
+	spannerString    = "STRING"
+	spannerInt64     = "INT64"
+	spannerFloat64   = "FLOAT64"
+	spannerBool      = "BOOL"
+	spannerTimestamp = "TIMESTAMP"
+	spannerBytes     = "BYTES"
+	spannerJSON      = "JSON"
+	spannerArray     = "ARRAY"
+)
+
+// spannerIdentifier matches the table and column names the mapping accepts.
+var spannerIdentifier = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// spannerColumn is a column of a Spanner table, holding a top-level field of the documents.
+type spannerColumn struct {
+	name string
+	code string
+}
+
+// SpannerTable is the Spanner table a collection of documents is kept in. It has a column for
+// every field of the model named after its firestore tag, like the field names in Firestore and
+// MongoDB: strings, numbers, booleans, times and bytes get a column of their type, structs, maps
+// and slices are kept as JSON. Timestamps are kept to the millisecond, as in MongoDB.
+type SpannerTable struct {
+	name    string
+	columns []spannerColumn
+}
+
+// NewSpannerTable returns the table the collections of T named collectionName are kept in. The
+// table is named after the last segment of the collection path, so subcollections and the
+// collections of every tenant share it. It panics when the name is not a valid table name, since
+// that is a programming error.
+func NewSpannerTable[T any](collectionName string) SpannerTable {
+	name := collectionName[strings.LastIndex(collectionName, "/")+1:]
+	if !spannerIdentifier.MatchString(name) {
+		panic(fmt.Sprintf("db: collection %s has no valid spanner table name", collectionName))
+	}
+
+	columns := spannerColumns(reflect.TypeFor[T]())
+	// Every document records its schema version, including those of models without the field
+	if !slices.ContainsFunc(columns, func(c spannerColumn) bool { return c.name == SchemaVersionField }) {
+		columns = append(columns, spannerColumn{name: SchemaVersionField, code: spannerInt64})
+	}
+
+	return SpannerTable{name: name, columns: columns}
+}
+
+// spannerColumns returns the columns of the fields of a struct type, following the naming rules
+// of firestoreField.
+func spannerColumns(typ reflect.Type) []spannerColumn {
+	var columns []spannerColumn
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag, _, _ := strings.Cut(field.Tag.Get("firestore"), ",")
+		if tag == "-" {
+			continue
+		}
+		if tag == "" && field.Anonymous {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				columns = append(columns, spannerColumns(embedded)...)
+
+				continue
+			}
+		}
+		if tag == "" {
+			tag = field.Name
+		}
+		if !spannerIdentifier.MatchString(tag) || tag == spannerCollectionColumn || tag == spannerIDColumn {
+			panic(fmt.Sprintf("db: field %s of %s has no valid spanner column name", tag, typ.Name()))
+		}
+
+		columns = append(columns, spannerColumn{name: tag, code: spannerCode(field.Type)})
+	}
+
+	return columns
+}
+
+// spannerCode returns the column type of a field type.
+func spannerCode(typ reflect.Type) string {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ == timeType {
+		return spannerTimestamp
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		return spannerString
+	case reflect.Bool:
+		return spannerBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return spannerInt64
+	case reflect.Float32, reflect.Float64:
+		return spannerFloat64
+	case reflect.Slice:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return spannerBytes
+		}
+	}
+
+	return spannerJSON
+}
+
+// column returns the column of the table with the name.
+func (t SpannerTable) column(name string) (spannerColumn, bool) {
+	for _, c := range t.columns {
+		if c.name == name {
+			return c, true
+		}
+	}
+
+	return spannerColumn{}, false
+}
+
+// check checks that every field of the data written to a document has a column.
+func (t SpannerTable) check(data map[string]interface{}) error {
+	for key := range data {
+		if _, ok := t.column(key); !ok {
+			return fmt.Errorf("spanner table %s has no column for field %s", t.name, key)
+		}
+	}
+
+	return nil
+}
+
+// columnNames returns the key columns followed by the columns of the fields.
+func (t SpannerTable) columnNames() []string {
+	names := []string{spannerCollectionColumn, spannerIDColumn}
+	for _, c := range t.columns {
+		names = append(names, c.name)
+	}
+
+	return names
+}
+
+// selectList returns the columns read for the documents of the table, aliased d.
+func (t SpannerTable) selectList() string {
+	names := t.columnNames()
+	for i, name := range names {
+		names[i] = "d." + quoteSpanner(name)
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// write returns the row of the document id of the collection, with a value for every column.
+func (t SpannerTable) write(collectionName string, id string, document map[string]interface{}) (*spannerapi.Write, error) {
+	values := []interface{}{collectionName, id}
+	for _, c := range t.columns {
+		value, err := spannerValue(c.code, document[c.name])
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", c.name, err)
+		}
+		values = append(values, value)
+	}
+
+	return &spannerapi.Write{Table: t.name, Columns: t.columnNames(), Values: [][]interface{}{values}}, nil
+}
+
+// key returns the key set of the document id of the collection.
+func (t SpannerTable) key(collectionName string, id string) *spannerapi.KeySet {
+	return &spannerapi.KeySet{Keys: [][]interface{}{{collectionName, id}}}
+}
+
+// createStatement returns the DDL statement creating the table.
+func (t SpannerTable) createStatement() string {
+	definitions := []string{
+		quoteSpanner(spannerCollectionColumn) + " STRING(MAX) NOT NULL",
+		quoteSpanner(spannerIDColumn) + " STRING(MAX) NOT NULL",
+	}
+	for _, c := range t.columns {
+		definitions = append(definitions, c.definition())
+	}
+
+	return fmt.Sprintf("CREATE TABLE %s (\n  %s\n) PRIMARY KEY (%s, %s)", quoteSpanner(t.name),
+		strings.Join(definitions, ",\n  "), quoteSpanner(spannerCollectionColumn), quoteSpanner(spannerIDColumn))
+}
+
+// definition returns the DDL definition of the column.
+func (c spannerColumn) definition() string {
+	switch c.code {
+	case spannerString, spannerBytes:
+		return quoteSpanner(c.name) + " " + c.code + "(MAX)"
+	default:
+		return quoteSpanner(c.name) + " " + c.code
+	}
+}
+
+// quoteSpanner quotes a table or column name.
+func quoteSpanner(name string) string {
+	return "`" + name + "`"
+}
+
+// spannerValue encodes a value for a column of the type as the Spanner API expects it, nil
+// for NULL.
+func spannerValue(code string, value interface{}) (interface{}, error) {
+	if code == spannerJSON {
+		return spannerJSONValue(value)
+	}
+
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil, nil
+	}
+
+	switch code {
+	case spannerString:
+		if v.Kind() == reflect.String {
+			return v.String(), nil
+		}
+	case spannerInt64:
+		switch {
+		case v.CanInt():
+			return strconv.FormatInt(v.Int(), 10), nil
+		case v.CanUint():
+			return strconv.FormatUint(v.Uint(), 10), nil
+		case v.CanFloat() && v.Float() == math.Trunc(v.Float()):
+			return strconv.FormatInt(int64(v.Float()), 10), nil
+		}
+	case spannerFloat64:
+		switch {
+		case v.CanFloat():
+			return spannerFloat(v.Float()), nil
+		case v.CanInt():
+			return float64(v.Int()), nil
+		case v.CanUint():
+			return float64(v.Uint()), nil
+		}
+	case spannerBool:
+		if v.Kind() == reflect.Bool {
+			return v.Bool(), nil
+		}
+	case spannerTimestamp:
+		switch t := v.Interface().(type) {
+		case time.Time:
+			return t.UTC().Truncate(time.Millisecond).Format(time.RFC3339Nano), nil
+		case primitive.DateTime:
+			return t.Time().UTC().Format(time.RFC3339Nano), nil
+		}
+	case spannerBytes:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return base64.StdEncoding.EncodeToString(v.Bytes()), nil
+		}
+	}
+
+	return nil, fmt.Errorf("cannot store %T in a %s column", value, code)
+}
+
+// spannerFloat encodes a FLOAT64, which the Spanner API takes as a string when it is not a number.
+func spannerFloat(f float64) interface{} {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+
+	return f
+}
+
+// spannerJSONValue encodes the value of a JSON column. Values are mapped like MongoDB documents,
+// with the firestore tags, and written as relaxed MongoDB Extended JSON so times and integers
+// keep their types: a time is stored as {"$date": "2006-01-02T15:04:05Z"}.
+func spannerJSONValue(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	document, err := bson.MarshalExtJSONWithRegistry(mongoRegistry, bson.D{{Key: "v", Value: value}}, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var wrapper struct {
+		V json.RawMessage `json:"v"`
+	}
+	if err := json.Unmarshal(document, &wrapper); err != nil {
+		return nil, err
+	}
+	if string(wrapper.V) == "null" {
+		return nil, nil
+	}
+
+	return string(wrapper.V), nil
+}
+
+// decodeSpannerJSON decodes the value of a JSON column, with maps and slices for the nested
+// documents and arrays like decodeMongo. Integers and times decode to int64 and time.Time, the
+// types Firestore returns, as relaxed Extended JSON does not tell int32 from int64.
+func decodeSpannerJSON(value string) (interface{}, error) {
+	var document map[string]interface{}
+	if err := bson.UnmarshalExtJSONWithRegistry(mongoRegistry, []byte(`{"v":`+value+`}`), false, &document); err != nil {
+		return nil, err
+	}
+
+	return widenSpannerJSON(document["v"]), nil
+}
+
+// widenSpannerJSON replaces the int32 and primitive.DateTime values within a decoded JSON value.
+func widenSpannerJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int32:
+		return int64(v)
+	case primitive.DateTime:
+		return v.Time().UTC()
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = widenSpannerJSON(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = widenSpannerJSON(item)
+		}
+	}
+
+	return value
+}
+
+// spannerRows decodes the rows of a result set to maps of their column values: strings, int64,
+// float64, bools, times, bytes, and the maps and slices of JSON values.
+func spannerRows(result *spannerapi.ResultSet) ([]map[string]interface{}, error) {
+	if result.Metadata == nil || result.Metadata.RowType == nil {
+		return nil, nil
+	}
+	fields := result.Metadata.RowType.Fields
+
+	rows := make([]map[string]interface{}, 0, len(result.Rows))
+	for _, values := range result.Rows {
+		row := make(map[string]interface{}, len(fields))
+		for i, field := range fields {
+			value, err := decodeSpannerValue(field.Type, values[i])
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %w", field.Name, err)
+			}
+			row[field.Name] = value
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// decodeSpannerValue decodes a value of the Spanner API of a column of the type.
+func decodeSpannerValue(typ *spannerapi.Type, value interface{}) (interface{}, error) {
+	if value == nil || typ == nil {
+		return value, nil
+	}
+
+	s, isString := value.(string)
+	switch typ.Code {
+	case spannerInt64:
+		return strconv.ParseInt(s, 10, 64)
+	case spannerFloat64:
+		if isString {
+			return strconv.ParseFloat(s, 64)
+		}
+	case spannerTimestamp:
+		return time.Parse(time.RFC3339Nano, s)
+	case spannerBytes:
+		return base64.StdEncoding.DecodeString(s)
+	case spannerJSON:
+		return decodeSpannerJSON(s)
+	}
+
+	return value, nil
+}
+
+// documentData returns the fields of a row, without its key and NULL columns.
+func documentData(row map[string]interface{}) map[string]interface{} {
+	data := make(map[string]interface{}, len(row))
+	for key, value := range row {
+		if value != nil && key != spannerCollectionColumn && key != spannerIDColumn {
+			data[key] = value
+		}
+	}
+
+	return data
+}
+
+// mergeData merges the data of an Update into the fields of a document like a Firestore merge:
+// nested maps are merged field by field, firestore.Delete removes a field,
+// firestore.ServerTimestamp sets it to now and ArrayUnion adds the values the array lacks.
+func mergeData(document map[string]interface{}, data map[string]interface{}, now time.Time) error {
+	for key, value := range data {
+		switch v := value.(type) {
+		case arrayUnion:
+			current, _ := document[key].([]interface{})
+			for _, item := range v {
+				// Compared as they are stored, so a struct matches the map it was stored as
+				stored, err := spannerJSONValue(item)
+				if err != nil {
+					return fmt.Errorf("field %s: %w", key, err)
+				}
+				plain := item
+				if s, ok := stored.(string); ok {
+					if plain, err = decodeSpannerJSON(s); err != nil {
+						return fmt.Errorf("field %s: %w", key, err)
+					}
+				}
+				if !slices.ContainsFunc(current, func(c interface{}) bool { return reflect.DeepEqual(c, plain) }) {
+					current = append(current, plain)
+				}
+			}
+			document[key] = current
+		case map[string]interface{}:
+			nested, ok := document[key].(map[string]interface{})
+			if !ok || len(v) == 0 {
+				nested = make(map[string]interface{}, len(v))
+			}
+			if err := mergeData(nested, v, now); err != nil {
+				return err
+			}
+			document[key] = nested
+		default:
+			switch value {
+			case firestore.Delete:
+				delete(document, key)
+			case firestore.ServerTimestamp:
+				document[key] = now
+			default:
+				document[key] = value
+			}
+		}
+	}
+
+	return nil
+}
+
+// spannerStatement is a SQL statement with its parameters.
+type spannerStatement struct {
+	sql        string
+	params     map[string]interface{}
+	paramTypes map[string]spannerapi.Type
+}
+
+// param adds a parameter with a value of the column type and returns its reference in SQL.
+func (s *spannerStatement) param(code string, value interface{}) (string, error) {
+	encoded, err := spannerValue(code, value)
+	if err != nil {
+		return "", err
+	}
+
+	return s.add(spannerapi.Type{Code: code}, encoded), nil
+}
+
+// arrayParam adds an array parameter with the values of a slice, of the column type.
+func (s *spannerStatement) arrayParam(code string, values interface{}) (string, error) {
+	list := reflect.ValueOf(values)
+	encoded := make([]interface{}, list.Len())
+	for i := range list.Len() {
+		value, err := spannerValue(code, list.Index(i).Interface())
+		if err != nil {
+			return "", err
+		}
+		encoded[i] = value
+	}
+
+	return s.add(spannerapi.Type{Code: spannerArray, ArrayElementType: &spannerapi.Type{Code: code}}, encoded), nil
+}
+
+// add adds a parameter and returns its reference in SQL.
+func (s *spannerStatement) add(typ spannerapi.Type, value interface{}) string {
+	if s.params == nil {
+		s.params = make(map[string]interface{})
+		s.paramTypes = make(map[string]spannerapi.Type)
+	}
+
+	name := fmt.Sprintf("p%d", len(s.params))
+	s.params[name] = value
+	s.paramTypes[name] = typ
+
+	return "@" + name
+}
+
+// expression returns the SQL expression of a query path on the table, aliased d, and the column
+// type of its values. Paths below a JSON column are read with JSON_VALUE, as strings, or cast to
+// the type of value when it is a number or a boolean.
+func (t SpannerTable) expression(path string, value interface{}) (string, string, error) {
+	if path == firestore.DocumentID {
+		return "d." + quoteSpanner(spannerIDColumn), spannerString, nil
+	}
+
+	name, nested, _ := strings.Cut(path, ".")
+	column, ok := t.column(name)
+	if !ok {
+		return "", "", fmt.Errorf("spanner table %s has no column for field %s", t.name, name)
+	}
+	expression := "d." + quoteSpanner(column.name)
+	if nested == "" {
+		return expression, column.code, nil
+	}
+	if column.code != spannerJSON {
+		return "", "", fmt.Errorf("field %s has no nested fields", name)
+	}
+
+	jsonPath, err := spannerJSONPath(nested)
+	if err != nil {
+		return "", "", err
+	}
+	expression = fmt.Sprintf("JSON_VALUE(%s, '%s')", expression, jsonPath)
+
+	if value == nil {
+		return expression, spannerString, nil
+	}
+	switch code := spannerCode(reflect.TypeOf(value)); code {
+	case spannerString:
+		return expression, spannerString, nil
+	case spannerInt64, spannerFloat64:
+		return fmt.Sprintf("SAFE_CAST(%s AS FLOAT64)", expression), spannerFloat64, nil
+	case spannerBool:
+		return fmt.Sprintf("SAFE_CAST(%s AS BOOL)", expression), spannerBool, nil
+	default:
+		return "", "", fmt.Errorf("cannot compare nested field %s with %T", path, value)
+	}
+}
+
+// spannerJSONPath returns the JSONPath of nested fields, with dots between them.
+func spannerJSONPath(nested string) (string, error) {
+	var path strings.Builder
+	path.WriteString("$")
+	for _, segment := range strings.Split(nested, ".") {
+		if segment == "" || strings.ContainsAny(segment, `'"\`) {
+			return "", fmt.Errorf("invalid nested field %q", segment)
+		}
+		if spannerIdentifier.MatchString(segment) {
+			path.WriteString("." + segment)
+		} else {
+			path.WriteString(`."` + segment + `"`)
+		}
+	}
+
+	return path.String(), nil
+}
+
+// condition translates a query constraint to a SQL condition on the table. Like Firestore, the
+// not-equal operators leave out documents without the field. The array operators match the
+// elements of JSON arrays by their string form.
+func (t SpannerTable) condition(s *spannerStatement, c QueryConstraint) (string, error) {
+	switch c.Op {
+	case ArrayContains, ArrayContainsAny:
+		return t.arrayCondition(s, c)
+	case In, NotIn:
+		list := reflect.ValueOf(c.Value)
+		expression, code, err := t.expression(c.Path, list.Index(0).Interface())
+		if err != nil {
+			return "", err
+		}
+		if code == spannerJSON {
+			return "", fmt.Errorf("cannot compare field %s with a list of values", c.Path)
+		}
+		param, err := s.arrayParam(code, c.Value)
+		if err != nil {
+			return "", fmt.Errorf("filter on %s: %w", c.Path, err)
+		}
+		if c.Op == NotIn {
+			return fmt.Sprintf("(%s IS NOT NULL AND %s NOT IN UNNEST(%s))", expression, expression, param), nil
+		}
+
+		return fmt.Sprintf("%s IN UNNEST(%s)", expression, param), nil
+	}
+
+	expression, code, err := t.expression(c.Path, c.Value)
+	if err != nil {
+		return "", err
+	}
+	if c.Value == nil {
+		switch c.Op {
+		case Eq:
+			return expression + " IS NULL", nil
+		case Ne:
+			return expression + " IS NOT NULL", nil
+		default:
+			return "", fmt.Errorf("operator %s needs a value on %s", c.Op, c.Path)
+		}
+	}
+	if code == spannerJSON {
+		return "", fmt.Errorf("field %s can only be compared with nil", c.Path)
+	}
+
+	param, err := s.param(code, c.Value)
+	if err != nil {
+		return "", fmt.Errorf("filter on %s: %w", c.Path, err)
+	}
+	operator := string(c.Op)
+	if c.Op == Eq {
+		operator = "="
+	}
+
+	return fmt.Sprintf("%s %s %s", expression, operator, param), nil
+}
+
+// arrayCondition translates the array-contains and array-contains-any operators on an array of a
+// JSON column, or nested in one.
+func (t SpannerTable) arrayCondition(s *spannerStatement, c QueryConstraint) (string, error) {
+	name, nested, _ := strings.Cut(c.Path, ".")
+	column, ok := t.column(name)
+	if !ok || column.code != spannerJSON {
+		return "", fmt.Errorf("operator %s needs an array field, got %s", c.Op, c.Path)
+	}
+	jsonPath := "$"
+	if nested != "" {
+		var err error
+		if jsonPath, err = spannerJSONPath(nested); err != nil {
+			return "", err
+		}
+	}
+	elements := fmt.Sprintf("UNNEST(JSON_VALUE_ARRAY(d.%s, '%s'))", quoteSpanner(column.name), jsonPath)
+
+	values := []interface{}{c.Value}
+	if c.Op == ArrayContainsAny {
+		list := reflect.ValueOf(c.Value)
+		values = make([]interface{}, list.Len())
+		for i := range list.Len() {
+			values[i] = list.Index(i).Interface()
+		}
+	}
+	strs := make([]string, len(values))
+	for i, value := range values {
+		str, err := spannerElement(value)
+		if err != nil {
+			return "", fmt.Errorf("filter on %s: %w", c.Path, err)
+		}
+		strs[i] = str
+	}
+
+	if c.Op == ArrayContains {
+		return fmt.Sprintf("%s IN %s", s.add(spannerapi.Type{Code: spannerString}, strs[0]), elements), nil
+	}
+	param, err := s.arrayParam(spannerString, strs)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("EXISTS(SELECT 1 FROM %s AS element WHERE element IN UNNEST(%s))", elements, param), nil
+}
+
+// spannerElement returns the string form JSON_VALUE_ARRAY gives an array element.
+func spannerElement(value interface{}) (string, error) {
+	v := reflect.ValueOf(value)
+	switch {
+	case v.Kind() == reflect.String:
+		return v.String(), nil
+	case v.Kind() == reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case v.CanInt():
+		return strconv.FormatInt(v.Int(), 10), nil
+	case v.CanUint():
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case v.CanFloat():
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64), nil
+	default:
+		return "", fmt.Errorf("cannot match array elements with %T", value)
+	}
+}
+
+// spannerQuery translates a query on the collection to SQL: its filters, its orders followed by
+// the document ID, the documents after the page token document in that order, and its limit.
+// Like Firestore, documents without the fields the query is ordered on are left out.
+func spannerQuery(t SpannerTable, collectionName string, constraints []QueryConstraint, orders []QueryOrder, pageToken string, limit int) (*spannerStatement, error) {
+	s := &spannerStatement{}
+	collection := s.add(spannerapi.Type{Code: spannerString}, collectionName)
+	conditions := []string{fmt.Sprintf("d.%s = %s", quoteSpanner(spannerCollectionColumn), collection)}
+	for _, c := range constraints {
+		condition, err := t.condition(s, c)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	sortKeys := mongoSortKeys(orders)
+	keys := make([]string, 0, len(sortKeys))
+	sorts := make([]string, 0, len(sortKeys))
+	for _, key := range sortKeys {
+		expression, _, err := t.expression(key.Path, nil)
+		if err != nil {
+			return nil, err
+		}
+		if key.Path != firestore.DocumentID {
+			conditions = append(conditions, expression+" IS NOT NULL")
+		}
+
+		direction := "ASC"
+		if key.Direction == Desc {
+			direction = "DESC"
+		}
+		keys = append(keys, expression)
+		sorts = append(sorts, expression+" "+direction)
+	}
+
+	from := fmt.Sprintf("%s AS d", quoteSpanner(t.name))
+	with := ""
+	if pageToken != "" {
+		// The sort keys of the page token document, read along with the page
+		token := s.add(spannerapi.Type{Code: spannerString}, pageToken)
+		selected := make([]string, len(keys))
+		for i, key := range keys {
+			selected[i] = fmt.Sprintf("%s AS k%d", key, i)
+		}
+		with = fmt.Sprintf("WITH page_token AS (SELECT %s FROM %s WHERE d.%s = %s AND d.%s = %s) ",
+			strings.Join(selected, ", "), from, quoteSpanner(spannerCollectionColumn), collection, quoteSpanner(spannerIDColumn), token)
+		from += ", page_token"
+
+		branches := make([]string, len(keys))
+		for i, key := range keys {
+			var branch []string
+			for j, previous := range keys[:i] {
+				branch = append(branch, fmt.Sprintf("%s = page_token.k%d", previous, j))
+			}
+			operator := ">"
+			if sortKeys[i].Direction == Desc {
+				operator = "<"
+			}
+			branch = append(branch, fmt.Sprintf("%s %s page_token.k%d", key, operator, i))
+			branches[i] = "(" + strings.Join(branch, " AND ") + ")"
+		}
+		conditions = append(conditions, "("+strings.Join(branches, " OR ")+")")
+	}
+
+	s.sql = fmt.Sprintf("%sSELECT %s FROM %s WHERE %s ORDER BY %s", with, t.selectList(), from,
+		strings.Join(conditions, " AND "), strings.Join(sorts, ", "))
+	if limit > 0 {
+		s.sql += " LIMIT " + strconv.Itoa(limit)
+	}
+
+	return s, nil
+}
+
+// spannerCount translates the filters of a query on the collection to a SQL count.
+func spannerCount(t SpannerTable, collectionName string, constraints []QueryConstraint) (*spannerStatement, error) {
+	s := &spannerStatement{}
+	conditions := []string{fmt.Sprintf("d.%s = %s", quoteSpanner(spannerCollectionColumn), s.add(spannerapi.Type{Code: spannerString}, collectionName))}
+	for _, c := range constraints {
+		condition, err := t.condition(s, c)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+	s.sql = fmt.Sprintf("SELECT COUNT(*) AS count FROM %s AS d WHERE %s", quoteSpanner(t.name), strings.Join(conditions, " AND "))
+
+	return s, nil
+}
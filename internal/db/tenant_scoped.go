@@ -0,0 +1,212 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/thoughtgears/shared-services/internal/tenant"
+)
+
+// tenantScopedRepository is a DB decorator that keeps every tenant's data in its own
+// subcollection, tenants/<tenant>/<collection>. The tenant is taken from the context of each
+// call, so one repository serves every tenant and queries can never cross tenants.
+type tenantScopedRepository[T any] struct {
+	collectionName string
+	newRepository  func(collectionName string) DB[T]
+
+	mu    sync.Mutex
+	repos map[string]DB[T]
+}
+
+// NewTenantScopedRepository creates a DB that scopes the collection to the tenant in the context.
+// Calls without a tenant fail with tenant.ErrMissing.
+//
+// Parameters:
+//   - client: Firestore client instance
+//   - collectionName: Name of the collection within each tenant
+//   - opts: Optional repository options, applied to every tenant's repository
+//
+// Returns:
+//   - DB[T]: A tenant scoped repository for the specified type
+func NewTenantScopedRepository[T any](client *firestore.Client, collectionName string, opts ...RepositoryOption) DB[T] {
+	return &tenantScopedRepository[T]{
+		collectionName: collectionName,
+		newRepository: func(collectionName string) DB[T] {
+			return NewFirestoreRepository[T](client, collectionName, opts...)
+		},
+		repos: make(map[string]DB[T]),
+	}
+}
+
+// NewTenantScopedMongoRepository creates a DB that scopes the collection to the tenant in the
+// context like NewTenantScopedRepository, with one MongoDB collection per tenant named
+// tenants/<tenant>/<collection>.
+func NewTenantScopedMongoRepository[T any](database *mongo.Database, collectionName string, opts ...RepositoryOption) DB[T] {
+	return &tenantScopedRepository[T]{
+		collectionName: collectionName,
+		newRepository: func(collectionName string) DB[T] {
+			return NewMongoRepository[T](database, collectionName, opts...)
+		},
+		repos: make(map[string]DB[T]),
+	}
+}
+
+// NewTenantScopedSpannerRepository creates a DB like NewTenantScopedRepository on a Cloud Spanner
+// database. The collections of every tenant share a table, their rows are keyed by the
+// collection path.
+func NewTenantScopedSpannerRepository[T any](client *SpannerClient, collectionName string, opts ...RepositoryOption) DB[T] {
+	return &tenantScopedRepository[T]{
+		collectionName: collectionName,
+		newRepository: func(collectionName string) DB[T] {
+			return NewSpannerRepository[T](client, collectionName, opts...)
+		},
+		repos: make(map[string]DB[T]),
+	}
+}
+
+// scoped returns the repository for the tenant in ctx.
+func (r *tenantScopedRepository[T]) scoped(ctx context.Context) (DB[T], error) {
+	id := tenant.From(ctx)
+	if id == "" {
+		return nil, fmt.Errorf("failed to access %s: %w", r.collectionName, tenant.ErrMissing)
+	}
+	if err := tenant.Validate(id); err != nil {
+		return nil, fmt.Errorf("failed to access %s: %w", r.collectionName, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	repo, ok := r.repos[id]
+	if !ok {
+		repo = r.newRepository(fmt.Sprintf("tenants/%s/%s", id, r.collectionName))
+		r.repos[id] = repo
+	}
+
+	return repo, nil
+}
+
+// GetAll retrieves all documents of the tenant's collection with optional pagination.
+func (r *tenantScopedRepository[T]) GetAll(ctx context.Context, pageToken string, pageSize int) ([]*T, string, error) {
+	repo, err := r.scoped(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return repo.GetAll(ctx, pageToken, pageSize)
+}
+
+// GetByID retrieves a document of the tenant's collection by its ID.
+func (r *tenantScopedRepository[T]) GetByID(ctx context.Context, id string) (*T, error) {
+	repo, err := r.scoped(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.GetByID(ctx, id)
+}
+
+// GetByQuery retrieves documents of the tenant's collection matching the query constraints.
+func (r *tenantScopedRepository[T]) GetByQuery(ctx context.Context, queries []QueryConstraint, pageToken string, pageSize int) ([]*T, string, error) {
+	repo, err := r.scoped(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return repo.GetByQuery(ctx, queries, pageToken, pageSize)
+}
+
+// Find retrieves documents of the tenant's collection matching the query.
+func (r *tenantScopedRepository[T]) Find(ctx context.Context, query *QueryBuilder[T]) ([]*T, string, error) {
+	repo, err := r.scoped(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return repo.Find(ctx, query)
+}
+
+// FindPage retrieves a page of documents of the tenant's collection matching the query.
+func (r *tenantScopedRepository[T]) FindPage(ctx context.Context, query *QueryBuilder[T]) (*Page[T], error) {
+	repo, err := r.scoped(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.FindPage(ctx, query)
+}
+
+// Create creates a document in the tenant's collection.
+func (r *tenantScopedRepository[T]) Create(ctx context.Context, id string, data map[string]interface{}) (*T, error) {
+	repo, err := r.scoped(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.Create(ctx, id, data)
+}
+
+// PrepareCreate prepares the creation of a document in the tenant's collection.
+func (r *tenantScopedRepository[T]) PrepareCreate(ctx context.Context, id string, data map[string]interface{}) (Write, error) {
+	repo, err := r.scoped(ctx)
+	if err != nil {
+		return Write{}, err
+	}
+
+	return repo.PrepareCreate(ctx, id, data)
+}
+
+// Update updates a document in the tenant's collection.
+func (r *tenantScopedRepository[T]) Update(ctx context.Context, id string, data map[string]interface{}) (*T, error) {
+	repo, err := r.scoped(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.Update(ctx, id, data)
+}
+
+// UpdateIfUnchanged updates a document in the tenant's collection if it is unchanged.
+func (r *tenantScopedRepository[T]) UpdateIfUnchanged(ctx context.Context, id string, updatedAt time.Time, data map[string]interface{}) (*T, error) {
+	repo, err := r.scoped(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.UpdateIfUnchanged(ctx, id, updatedAt, data)
+}
+
+// Delete deletes a document from the tenant's collection.
+func (r *tenantScopedRepository[T]) Delete(ctx context.Context, id string) error {
+	repo, err := r.scoped(ctx)
+	if err != nil {
+		return err
+	}
+
+	return repo.Delete(ctx, id)
+}
+
+// Count counts the documents of the tenant's collection matching the query constraints.
+func (r *tenantScopedRepository[T]) Count(ctx context.Context, queries []QueryConstraint) (int64, error) {
+	repo, err := r.scoped(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return repo.Count(ctx, queries)
+}
+
+// Changes streams the changes to the tenant's collection.
+func (r *tenantScopedRepository[T]) Changes(ctx context.Context, since time.Time) (ChangeStream[T], error) {
+	repo, err := r.scoped(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.Changes(ctx, since)
+}
@@ -0,0 +1,139 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// totalCountTTL is how long the total count of a query is reused, counting reads every
+	// matching index entry so it is not repeated for every page.
+	totalCountTTL = 30 * time.Second
+	// maxCachedTotals is the cache size above which expired totals are dropped.
+	maxCachedTotals = 1000
+)
+
+// Page is a page of query results. TotalCount is the number of documents matching the query
+// across all pages, it is only set for queries with IncludeTotal and when it could be counted.
+type Page[T any] struct {
+	Items         []*T
+	NextPageToken string
+	TotalCount    *int64
+}
+
+type cachedTotal struct {
+	count     int64
+	countedAt time.Time
+}
+
+// totalCache holds recent total counts of the queries of a repository. Counts are estimates
+// for display, so any write to the repository simply drops all of them.
+type totalCache struct {
+	mu     sync.Mutex
+	totals map[string]cachedTotal
+}
+
+// get returns the cached total of the query with the key.
+func (c *totalCache) get(key string) (int64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.totals[key]
+	if !ok || time.Since(cached.countedAt) >= totalCountTTL {
+		return 0, false
+	}
+
+	return cached.count, true
+}
+
+// put caches the total of the query with the key.
+func (c *totalCache) put(key string, count int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.totals == nil {
+		c.totals = make(map[string]cachedTotal)
+	}
+	if len(c.totals) >= maxCachedTotals {
+		for k, cached := range c.totals {
+			if time.Since(cached.countedAt) >= totalCountTTL {
+				delete(c.totals, k)
+			}
+		}
+	}
+	c.totals[key] = cachedTotal{count: count, countedAt: time.Now()}
+}
+
+// clear drops every cached total.
+func (c *totalCache) clear() {
+	c.mu.Lock()
+	c.totals = nil
+	c.mu.Unlock()
+}
+
+// totalKey returns the cache key of the filters of a query, its orders and page do not change
+// the total.
+func totalKey(queries []QueryConstraint) string {
+	var key strings.Builder
+	for _, q := range queries {
+		fmt.Fprintf(&key, "%s %s %#v\n", q.Path, q.Op, q.Value)
+	}
+
+	return key.String()
+}
+
+// FindPage retrieves a page of the documents matching the query like Find. With IncludeTotal
+// the page also holds the number of matching documents, counted with a count aggregation and
+// reused for a short while, except in a read-only transaction. The total is best effort: when it cannot be counted the page is
+// returned without it.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - query: The query, including its page token and limit
+//
+// Returns:
+//   - *Page[T]: The page of documents, with the total count if requested
+//   - error: Any error encountered while retrieving the documents
+func (r *firestoreRepository[T]) FindPage(ctx context.Context, query *QueryBuilder[T]) (*Page[T], error) {
+	// A total cached outside of a read-only transaction is not from its snapshot
+	_, snapshot := readOnly(ctx, r.client)
+
+	return findPage(ctx, r, &r.totals, r.collectionName, query, !snapshot)
+}
+
+// findPage implements FindPage with the Find and Count of repo, reusing the totals cached in
+// totals when cached is set.
+func findPage[T any](ctx context.Context, repo DB[T], totals *totalCache, collectionName string, query *QueryBuilder[T], cached bool) (*Page[T], error) {
+	items, nextPageToken, err := repo.Find(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page[T]{Items: items, NextPageToken: nextPageToken}
+	if !query.includeTotal {
+		return page, nil
+	}
+
+	key := totalKey(query.constraints)
+	if total, ok := totals.get(key); ok && cached {
+		page.TotalCount = &total
+
+		return page, nil
+	}
+
+	total, err := repo.Count(ctx, query.constraints)
+	if err != nil {
+		log.Warn().Err(err).Str("collection", collectionName).Msg("Failed to count query total, returning the page without it")
+
+		return page, nil
+	}
+	totals.put(key, total)
+	page.TotalCount = &total
+
+	return page, nil
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// arrayUnion is the value of an array field that ArrayUnion adds to.
+type arrayUnion []interface{}
+
+// ArrayUnion adds the values to an array field in Update data, skipping values the array already
+// holds, and sets the field to the values in Create data. Unlike firestore.ArrayUnion it works
+// with every backend. firestore.ServerTimestamp and firestore.Delete are understood by every
+// backend as they are.
+func ArrayUnion(values ...interface{}) interface{} {
+	return arrayUnion(values)
+}
+
+// firestoreData replaces the transforms of this package in data, and in maps nested in it, with
+// their Firestore equivalents.
+func firestoreData(data map[string]interface{}) {
+	for key, value := range data {
+		switch v := value.(type) {
+		case arrayUnion:
+			data[key] = firestore.ArrayUnion(v...)
+		case map[string]interface{}:
+			firestoreData(v)
+		}
+	}
+}
+
+// resolveData returns a copy of data with the transforms replaced by the values they write to a
+// new document: the server timestamp becomes now and deleted fields are left out.
+func resolveData(data map[string]interface{}, now time.Time) map[string]interface{} {
+	resolved := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		switch v := value.(type) {
+		case arrayUnion:
+			resolved[key] = []interface{}(v)
+		case map[string]interface{}:
+			resolved[key] = resolveData(v, now)
+		default:
+			switch value {
+			case firestore.Delete:
+				continue
+			case firestore.ServerTimestamp:
+				resolved[key] = now
+			default:
+				resolved[key] = value
+			}
+		}
+	}
+
+	return resolved
+}
@@ -0,0 +1,37 @@
+package db
+
+import "time"
+
+// TTLField is the field Firestore TTL policies delete documents by. A collection group only
+// expires documents once its policy is enabled, cmd/indexes lists the policies the services
+// rely on. Firestore deletes expired documents within about a day, so reads must still check
+// the expiry of records whose lifetime matters.
+const TTLField = "expire_at"
+
+// SetExpireAt marks the document data for deletion by the TTL policy of its collection once
+// expireAt has passed, and returns data.
+func SetExpireAt(data map[string]interface{}, expireAt time.Time) map[string]interface{} {
+	data[TTLField] = expireAt.UTC()
+
+	return data
+}
+
+// TTLPolicy is a TTL policy on TTLField, in the fieldOverrides format of firestore.indexes.json.
+// The field is left out of the single-field indexes, which TTL fields do not need and which
+// would slow down writes of increasing timestamps.
+type TTLPolicy struct {
+	CollectionGroup string        `json:"collectionGroup"`
+	FieldPath       string        `json:"fieldPath"`
+	TTL             bool          `json:"ttl"`
+	Indexes         []interface{} `json:"indexes"`
+}
+
+// NewTTLPolicy returns the TTL policy that expires documents of the collection group.
+func NewTTLPolicy(collectionGroup string) TTLPolicy {
+	return TTLPolicy{
+		CollectionGroup: collectionGroup,
+		FieldPath:       TTLField,
+		TTL:             true,
+		Indexes:         []interface{}{},
+	}
+}
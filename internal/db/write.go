@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	"go.mongodb.org/mongo-driver/mongo"
+	spannerapi "google.golang.org/api/spanner/v1"
+)
+
+// Write is a document creation prepared by a repository with PrepareCreate.
+// Writes of several repositories can be committed together with Commit.
+type Write struct {
+	client *firestore.Client
+	ref    *firestore.DocumentRef
+	// collection is set instead of client and ref for writes to MongoDB, data then holds the _id
+	collection *mongo.Collection
+	data       map[string]interface{}
+	// spanner is set instead of client and ref for writes to Cloud Spanner, with the row to insert
+	spanner  *SpannerClient
+	mutation *spannerapi.Mutation
+}
+
+// Commit creates the documents of the prepared writes in a single transaction, either every
+// document is created or none is. Unlike Create, a document that already exists is not
+// overwritten but fails the whole commit. Transactions on MongoDB need a replica set.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - writes: Writes prepared by repositories sharing the same Firestore, MongoDB or Spanner client
+//
+// Returns:
+//   - error: Any error encountered during the commit
+func Commit(ctx context.Context, writes ...Write) error {
+	if len(writes) == 0 {
+		return nil
+	}
+
+	if writes[0].collection != nil {
+		return commitMongo(ctx, writes)
+	}
+	if writes[0].spanner != nil {
+		return commitSpanner(ctx, writes)
+	}
+
+	client := writes[0].client
+	for _, w := range writes {
+		if w.client != client {
+			return errors.New("failed to commit writes: writes use different Firestore clients")
+		}
+	}
+
+	err := client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		for _, w := range writes {
+			if err := tx.Create(w.ref, w.data); err != nil {
+				return fmt.Errorf("failed to create document %s: %w", w.ref.Path, err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to commit writes: %w", err)
+	}
+
+	return nil
+}
+
+// commitMongo creates the documents of writes prepared by MongoDB repositories in a transaction.
+func commitMongo(ctx context.Context, writes []Write) error {
+	client := writes[0].collection.Database().Client()
+	for _, w := range writes {
+		if w.collection == nil || w.collection.Database().Client() != client {
+			return errors.New("failed to commit writes: writes use different MongoDB clients")
+		}
+	}
+
+	session, err := client.StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to commit writes: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(ctx mongo.SessionContext) (interface{}, error) {
+		for _, w := range writes {
+			if _, err := w.collection.InsertOne(ctx, w.data); err != nil {
+				return nil, fmt.Errorf("failed to create document %s/%v: %w", w.collection.Name(), w.data["_id"], err)
+			}
+		}
+
+		return nil, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to commit writes: %w", err)
+	}
+
+	return nil
+}
+
+// commitSpanner inserts the rows of writes prepared by Spanner repositories in a transaction.
+func commitSpanner(ctx context.Context, writes []Write) error {
+	client := writes[0].spanner
+	mutations := make([]*spannerapi.Mutation, 0, len(writes))
+	for _, w := range writes {
+		if w.spanner != client {
+			return errors.New("failed to commit writes: writes use different Spanner clients")
+		}
+		mutations = append(mutations, w.mutation)
+	}
+
+	if err := client.apply(ctx, mutations); err != nil {
+		return fmt.Errorf("failed to commit writes: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+// Package debugcapture keeps sanitized request and response bodies of a sample of failing requests
+// in memory, so malformed client payloads can be diagnosed without redeploying with extra logging.
+// Capturing is off until an admin enables it, and switches itself off again after a TTL.
+package debugcapture
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// MaxBodySize is the number of bytes of each body that is kept.
+const MaxBodySize = 16 << 10
+
+// Capture is a single failing request with its sanitized bodies.
+type Capture struct {
+	Time         time.Time `json:"time"`
+	RequestID    string    `json:"request_id"`
+	TenantID     string    `json:"tenant_id,omitempty"`
+	Method       string    `json:"method"`
+	Path         string    `json:"path"`
+	Status       int       `json:"status"`
+	ContentType  string    `json:"content_type,omitempty"`
+	RequestBody  string    `json:"request_body,omitempty"`
+	ResponseBody string    `json:"response_body,omitempty"`
+	Truncated    bool      `json:"truncated,omitempty"`
+}
+
+// Settings is the current capture mode.
+type Settings struct {
+	Enabled bool `json:"enabled"`
+	// SampleRate is the fraction of failing requests captured, between 0 and 1.
+	SampleRate float64   `json:"sample_rate"`
+	ExpiresAt  time.Time `json:"expires_at,omitempty"`
+}
+
+// Recorder holds the capture mode and a ring buffer of the most recent captures.
+type Recorder struct {
+	mu       sync.Mutex
+	settings Settings
+	captures []Capture
+	next     int
+	full     bool
+}
+
+// NewRecorder creates a disabled Recorder keeping at most size captures.
+func NewRecorder(size int) *Recorder {
+	return &Recorder{captures: make([]Capture, size)}
+}
+
+// Enable starts capturing sampleRate of failing requests until ttl has passed.
+func (r *Recorder) Enable(sampleRate float64, ttl time.Duration) Settings {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.settings = Settings{
+		Enabled:    true,
+		SampleRate: min(max(sampleRate, 0), 1),
+		ExpiresAt:  time.Now().Add(ttl),
+	}
+
+	return r.settings
+}
+
+// Disable stops capturing. Captures already taken are kept.
+func (r *Recorder) Disable() Settings {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.settings = Settings{}
+
+	return r.settings
+}
+
+// Settings returns the current capture mode.
+func (r *Recorder) Settings() Settings {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.expire()
+
+	return r.settings
+}
+
+// Sample reports whether the current request should be captured.
+func (r *Recorder) Sample() bool {
+	if r == nil {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.expire()
+
+	return r.settings.Enabled && rand.Float64() < r.settings.SampleRate
+}
+
+// Active reports whether capturing is enabled, so bodies only need to be buffered while it is.
+func (r *Recorder) Active() bool {
+	if r == nil {
+		return false
+	}
+
+	return r.Settings().Enabled
+}
+
+// Add stores a capture, overwriting the oldest one once the buffer is full.
+func (r *Recorder) Add(capture Capture) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.captures[r.next] = capture
+	r.next = (r.next + 1) % len(r.captures)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// List returns the captures of the tenant, newest first. An empty tenantID only matches
+// captures of requests without a tenant.
+func (r *Recorder) List(tenantID string) []Capture {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := r.next
+	if r.full {
+		count = len(r.captures)
+	}
+
+	captures := make([]Capture, 0, count)
+	for i := 1; i <= count; i++ {
+		capture := r.captures[(r.next-i+len(r.captures))%len(r.captures)]
+		if capture.TenantID == tenantID {
+			captures = append(captures, capture)
+		}
+	}
+
+	return captures
+}
+
+// expire disables capturing once the TTL has passed. The caller must hold r.mu.
+func (r *Recorder) expire() {
+	if r.settings.Enabled && time.Now().After(r.settings.ExpiresAt) {
+		r.settings = Settings{}
+	}
+}
@@ -0,0 +1,80 @@
+// Package errorreporting sends server errors to Google Cloud Error Reporting.
+// Events are written as structured log entries in the ReportedErrorEvent format, which Error Reporting
+// picks up from Cloud Logging, so no extra client or API call is needed on Cloud Run.
+package errorreporting
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/requestid"
+)
+
+// eventType marks a log entry as an error event for Error Reporting.
+const eventType = "type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent"
+
+// Event is a single server error.
+type Event struct {
+	// Code groups events in Error Reporting, e.g. "GET /v1/documents/:id 500".
+	Code    string
+	Message string
+	// Stack is the goroutine stack of a recovered panic. Without a stack the event is grouped by Code.
+	Stack []byte
+	// Handler is the name of the handler that failed, reported as the error location.
+	Handler    string
+	Method     string
+	URL        string
+	UserAgent  string
+	RemoteIP   string
+	StatusCode int
+	UserID     string
+}
+
+// Reporter writes error events for one service.
+// A nil *Reporter is valid and reports nothing, so reporting can be toggled by configuration.
+type Reporter struct {
+	service string
+	version string
+}
+
+// NewReporter creates a Reporter for the service and version shown in Error Reporting,
+// on Cloud Run the K_SERVICE and K_REVISION values.
+func NewReporter(service, version string) *Reporter {
+	return &Reporter{service: service, version: version}
+}
+
+// Report writes event to the log in the Error Reporting format.
+func (r *Reporter) Report(ctx context.Context, event Event) {
+	if r == nil {
+		return
+	}
+
+	// Error Reporting parses a Go panic from the message when the stack follows the "panic: " line,
+	// other events are grouped by their report location
+	message := event.Code + ": " + event.Message
+	if len(event.Stack) > 0 {
+		message = "panic: " + event.Message + "\n\n" + string(event.Stack)
+	}
+
+	log.Error().
+		Str("@type", eventType).
+		Str("request_id", requestid.From(ctx)).
+		Dict("serviceContext", zerolog.Dict().
+			Str("service", r.service).
+			Str("version", r.version)).
+		Dict("context", zerolog.Dict().
+			Dict("httpRequest", zerolog.Dict().
+				Str("method", event.Method).
+				Str("url", event.URL).
+				Str("userAgent", event.UserAgent).
+				Str("remoteIp", event.RemoteIP).
+				Int("responseStatusCode", event.StatusCode)).
+			Str("user", event.UserID).
+			Dict("reportLocation", zerolog.Dict().
+				Str("filePath", event.Handler).
+				Int("lineNumber", 0).
+				Str("functionName", event.Code))).
+		Msg(message)
+}
@@ -0,0 +1,239 @@
+package fieldcrypt
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thoughtgears/shared-services/internal/stats"
+)
+
+// prefix marks values encrypted by this package, so plaintext written before
+// encryption was enabled can still be read.
+const prefix = "enc:v2:"
+
+// MaxCachedKeys is the number of unwrapped data keys a Cipher keeps. Every rotation period adds a
+// key, and reads add the keys of older values, so the cache is bounded for long running processes.
+const MaxCachedKeys = 1000
+
+// ErrMalformedCiphertext is returned when an encrypted value cannot be parsed.
+var ErrMalformedCiphertext = errors.New("malformed encrypted value")
+
+// Binding identifies where an encrypted value is stored. It is authenticated together with the
+// value, so ciphertext copied into another collection, record or field fails to decrypt.
+type Binding struct {
+	// Collection is the collection of the record, e.g. users or tenants/acme/users.
+	Collection string
+	// RecordID is the ID of the record.
+	RecordID string
+	// Field is the dotted path of the value within the record, e.g. address.street.
+	Field string
+}
+
+// field returns the binding of the value at path within the same record.
+func (b Binding) field(path string) Binding {
+	b.Field = path
+
+	return b
+}
+
+// aad returns the additional authenticated data of the binding.
+func (b Binding) aad() []byte {
+	return []byte(b.Collection + "\x00" + b.RecordID + "\x00" + b.Field)
+}
+
+// dataKey is a data encryption key together with its wrapped form.
+type dataKey struct {
+	plaintext []byte
+	wrapped   string
+	createdAt time.Time
+}
+
+// Cipher encrypts individual field values with envelope encryption.
+// Values are encrypted with AES-256-GCM using a data key that is wrapped by the
+// KeyWrapper and stored alongside each value. The current data key is rotated
+// after rotateAfter, and unwrapped keys are cached so reads do not call KMS per value.
+type Cipher struct {
+	wrapper     KeyWrapper
+	rotateAfter time.Duration
+
+	mu      sync.Mutex
+	current *dataKey
+	cache   map[string][]byte
+}
+
+// NewCipher creates a Cipher using the given KeyWrapper.
+func NewCipher(wrapper KeyWrapper, rotateAfter time.Duration) *Cipher {
+	return &Cipher{
+		wrapper:     wrapper,
+		rotateAfter: rotateAfter,
+		cache:       make(map[string][]byte),
+	}
+}
+
+// EncryptString encrypts a single value bound to where it is stored. Empty strings are returned
+// unchanged so optional fields stay empty rather than holding ciphertext of nothing. Every other
+// value is encrypted, including values that look encrypted already, as it is caller input.
+func (c *Cipher) EncryptString(ctx context.Context, value string, binding Binding) (string, error) {
+	if value == "" {
+		return value, nil
+	}
+
+	key, err := c.currentKey(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := newAEAD(key.plaintext)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(value), binding.aad())
+
+	return prefix + key.wrapped + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptString decrypts a value produced by EncryptString with the same binding.
+// Values without the encryption prefix are returned unchanged.
+func (c *Cipher) DecryptString(ctx context.Context, value string, binding Binding) (string, error) {
+	value, ok := strings.CutPrefix(value, prefix)
+	if !ok {
+		return value, nil
+	}
+
+	wrapped, payload, ok := strings.Cut(value, ":")
+	if !ok {
+		return "", ErrMalformedCiphertext
+	}
+
+	key, err := c.unwrap(ctx, wrapped)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrMalformedCiphertext, err)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return "", ErrMalformedCiphertext
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], binding.aad())
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %w", err)
+	}
+
+	return string(plaintext), nil
+}
+
+// IsEncrypted reports whether a value was produced by EncryptString.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// currentKey returns the data key used for new values, generating and wrapping a new one
+// if there is none yet or the current key is older than the rotation period.
+func (c *Cipher) currentKey(ctx context.Context) (*dataKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.current != nil && time.Since(c.current.createdAt) < c.rotateAfter {
+		return c.current, nil
+	}
+
+	plaintext := make([]byte, 32)
+	if _, err := rand.Read(plaintext); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	wrapped, err := c.wrapper.Wrap(ctx, plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	key := &dataKey{
+		plaintext: plaintext,
+		wrapped:   base64.RawURLEncoding.EncodeToString(wrapped),
+		createdAt: time.Now(),
+	}
+	c.current = key
+	c.cacheKey(key.wrapped, plaintext)
+
+	return key, nil
+}
+
+// unwrap returns the plaintext of a wrapped data key, using the cache when possible.
+func (c *Cipher) unwrap(ctx context.Context, wrapped string) ([]byte, error) {
+	c.mu.Lock()
+	key, ok := c.cache[wrapped]
+	c.mu.Unlock()
+	if ok {
+		stats.CacheHit("data_keys")
+
+		return key, nil
+	}
+	stats.CacheMiss("data_keys")
+
+	raw, err := base64.RawURLEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedCiphertext, err)
+	}
+
+	key, err = c.wrapper.Unwrap(ctx, raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	c.mu.Lock()
+	c.cacheKey(wrapped, key)
+	c.mu.Unlock()
+
+	return key, nil
+}
+
+// cacheKey caches an unwrapped data key, evicting an arbitrary key when the cache is full.
+// The caller must hold c.mu.
+func (c *Cipher) cacheKey(wrapped string, key []byte) {
+	if _, ok := c.cache[wrapped]; !ok && len(c.cache) >= MaxCachedKeys {
+		for evict := range c.cache {
+			delete(c.cache, evict)
+
+			break
+		}
+	}
+	c.cache[wrapped] = key
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+
+	return aead, nil
+}
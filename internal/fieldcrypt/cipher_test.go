@@ -0,0 +1,194 @@
+package fieldcrypt
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// xorWrapper wraps data keys by XOR with a fixed key, counting the unwraps.
+type xorWrapper struct {
+	unwraps atomic.Int64
+}
+
+func (w *xorWrapper) Wrap(_ context.Context, dek []byte) ([]byte, error) {
+	return xor(dek), nil
+}
+
+func (w *xorWrapper) Unwrap(_ context.Context, wrapped []byte) ([]byte, error) {
+	w.unwraps.Add(1)
+	if len(wrapped) != 32 {
+		return nil, errors.New("bad wrapped key")
+	}
+
+	return xor(wrapped), nil
+}
+
+func xor(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i := range b {
+		out[i] = b[i] ^ 0x5a
+	}
+
+	return out
+}
+
+var testBinding = Binding{Collection: "users", RecordID: "user-1", Field: "phone"}
+
+func TestEncryptStringRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	c := NewCipher(&xorWrapper{}, time.Hour)
+
+	encrypted, err := c.EncryptString(ctx, "+4712345678", testBinding)
+	if err != nil {
+		t.Fatalf("EncryptString() error = %v", err)
+	}
+	if !strings.HasPrefix(encrypted, prefix) || strings.Contains(encrypted, "12345678") {
+		t.Fatalf("EncryptString() = %q, want ciphertext with prefix %q", encrypted, prefix)
+	}
+
+	decrypted, err := c.DecryptString(ctx, encrypted, testBinding)
+	if err != nil {
+		t.Fatalf("DecryptString() error = %v", err)
+	}
+	if decrypted != "+4712345678" {
+		t.Errorf("DecryptString() = %q, want %q", decrypted, "+4712345678")
+	}
+
+	if empty, err := c.EncryptString(ctx, "", testBinding); err != nil || empty != "" {
+		t.Errorf("EncryptString(\"\") = %q, %v, want an empty string", empty, err)
+	}
+}
+
+func TestDecryptStringRejectsOtherBinding(t *testing.T) {
+	ctx := context.Background()
+	c := NewCipher(&xorWrapper{}, time.Hour)
+
+	encrypted, err := c.EncryptString(ctx, "secret", testBinding)
+	if err != nil {
+		t.Fatalf("EncryptString() error = %v", err)
+	}
+
+	for name, binding := range map[string]Binding{
+		"collection": {Collection: "tenants/acme/users", RecordID: "user-1", Field: "phone"},
+		"record":     {Collection: "users", RecordID: "user-2", Field: "phone"},
+		"field":      {Collection: "users", RecordID: "user-1", Field: "email"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := c.DecryptString(ctx, encrypted, binding); err == nil {
+				t.Error("DecryptString() with another binding succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestEncryptStringEncryptsEncryptedLookingInput(t *testing.T) {
+	ctx := context.Background()
+	c := NewCipher(&xorWrapper{}, time.Hour)
+
+	// A value copied from another record must not be stored as is, it would decrypt as that record's
+	for _, value := range []string{prefix + "abc:def", prefix + "abc"} {
+		encrypted, err := c.EncryptString(ctx, value, testBinding)
+		if err != nil {
+			t.Fatalf("EncryptString(%q) error = %v", value, err)
+		}
+		if encrypted == value {
+			t.Fatalf("EncryptString(%q) returned its input", value)
+		}
+
+		decrypted, err := c.DecryptString(ctx, encrypted, testBinding)
+		if err != nil || decrypted != value {
+			t.Errorf("DecryptString() = %q, %v, want %q", decrypted, err, value)
+		}
+	}
+}
+
+func TestDecryptStringPlaintext(t *testing.T) {
+	ctx := context.Background()
+	c := NewCipher(&xorWrapper{}, time.Hour)
+
+	// Only values with the current prefix are decrypted, enc:v1 values sealed without a binding never shipped
+	for _, value := range []string{"plaintext", "enc:v1:abc:def"} {
+		if plain, err := c.DecryptString(ctx, value, testBinding); err != nil || plain != value {
+			t.Errorf("DecryptString(%q) = %q, %v, want it unchanged", value, plain, err)
+		}
+	}
+	if _, err := c.DecryptString(ctx, prefix+"no-payload", testBinding); !errors.Is(err, ErrMalformedCiphertext) {
+		t.Errorf("DecryptString() of malformed value error = %v, want ErrMalformedCiphertext", err)
+	}
+}
+
+func TestCipherKeyCache(t *testing.T) {
+	ctx := context.Background()
+	wrapper := &xorWrapper{}
+
+	// Rotating on every value adds a key to the cache per value
+	writer := NewCipher(wrapper, 0)
+	values := make([]string, MaxCachedKeys+10)
+	for i := range values {
+		encrypted, err := writer.EncryptString(ctx, "value", testBinding)
+		if err != nil {
+			t.Fatalf("EncryptString() error = %v", err)
+		}
+		values[i] = encrypted
+	}
+	if got := len(writer.cache); got > MaxCachedKeys {
+		t.Errorf("cached keys = %d, want at most %d", got, MaxCachedKeys)
+	}
+
+	reader := NewCipher(wrapper, time.Hour)
+	for range 2 {
+		if _, err := reader.DecryptString(ctx, values[0], testBinding); err != nil {
+			t.Fatalf("DecryptString() error = %v", err)
+		}
+	}
+	if got := wrapper.unwraps.Load(); got != 1 {
+		t.Errorf("unwraps = %d, want 1 for two reads with the same key", got)
+	}
+}
+
+type profile struct {
+	Phone    string            `firestore:"phone" encrypt:"true"`
+	Metadata map[string]string `firestore:"metadata" encrypt:"true"`
+	Name     string            `firestore:"name"`
+}
+
+func TestEncryptDataDecryptStruct(t *testing.T) {
+	ctx := context.Background()
+	c := NewCipher(&xorWrapper{}, time.Hour)
+
+	data := map[string]interface{}{
+		"phone":    "+4712345678",
+		"metadata": map[string]string{"external_id": "ext-1", "note": "private"},
+		"name":     "Ada",
+	}
+	if err := c.EncryptData(ctx, reflect.TypeFor[profile](), "users", "user-1", data, []string{"metadata.external_id"}); err != nil {
+		t.Fatalf("EncryptData() error = %v", err)
+	}
+
+	metadata := data["metadata"].(map[string]string)
+	if !IsEncrypted(data["phone"].(string)) || !IsEncrypted(metadata["note"]) {
+		t.Errorf("EncryptData() left tagged values in plaintext: %v", data)
+	}
+	if metadata["external_id"] != "ext-1" || data["name"] != "Ada" {
+		t.Errorf("EncryptData() encrypted exempt or untagged values: %v", data)
+	}
+
+	p := profile{Phone: data["phone"].(string), Metadata: metadata, Name: "Ada"}
+	if err := c.DecryptStruct(ctx, "users", "user-1", &p); err != nil {
+		t.Fatalf("DecryptStruct() error = %v", err)
+	}
+	if p.Phone != "+4712345678" || p.Metadata["note"] != "private" || p.Metadata["external_id"] != "ext-1" {
+		t.Errorf("DecryptStruct() = %+v, want the original values", p)
+	}
+
+	// The ciphertext of one record does not decrypt as another's
+	copied := profile{Phone: data["phone"].(string)}
+	if err := c.DecryptStruct(ctx, "users", "user-2", &copied); err == nil {
+		t.Error("DecryptStruct() of another record's ciphertext succeeded, want an error")
+	}
+}
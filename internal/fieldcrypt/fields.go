@@ -0,0 +1,176 @@
+package fieldcrypt
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// TagName is the struct tag marking fields to encrypt, e.g. `encrypt:"true"`.
+// Tagged string fields are encrypted directly; for tagged structs and
+// map[string]string fields every string value inside is encrypted, so the
+// stored document keeps its shape and can still be decoded into the model.
+const TagName = "encrypt"
+
+// EncryptData encrypts, in place, the values in data that belong to fields of t tagged for encryption.
+// data is keyed by the firestore field names of t, as passed to the repository, and is written to
+// the record id of collection. Every value is bound to the record and its dotted field path.
+// exempt lists dotted field paths (e.g. metadata.external_id) that must stay in plaintext
+// because they are used in queries.
+func (c *Cipher) EncryptData(ctx context.Context, t reflect.Type, collection, id string, data map[string]interface{}, exempt []string) error {
+	record := Binding{Collection: collection, RecordID: id}
+	for _, name := range encryptedFields(t) {
+		value, ok := data[name]
+		if !ok {
+			continue
+		}
+
+		encrypted, err := c.encryptValue(ctx, record.field(name), value, exempt)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt field %s: %w", name, err)
+		}
+		data[name] = encrypted
+	}
+
+	return nil
+}
+
+// DecryptStruct decrypts, in place, the fields of v tagged for encryption. v must be a pointer to a
+// struct read from the record id of collection.
+func (c *Cipher) DecryptStruct(ctx context.Context, collection, id string, v any) error {
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("decrypt target must be a pointer to a struct, got %T", v)
+	}
+
+	record := Binding{Collection: collection, RecordID: id}
+	val = val.Elem()
+	typ := val.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		if typ.Field(i).Tag.Get(TagName) != "true" {
+			continue
+		}
+
+		if err := c.decryptValue(ctx, record.field(fieldName(typ.Field(i))), val.Field(i)); err != nil {
+			return fmt.Errorf("failed to decrypt field %s: %w", typ.Field(i).Name, err)
+		}
+	}
+
+	return nil
+}
+
+// encryptedFields returns the firestore names of the fields of t tagged for encryption.
+func encryptedFields(t reflect.Type) []string {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Tag.Get(TagName) != "true" {
+			continue
+		}
+		names = append(names, fieldName(field))
+	}
+
+	return names
+}
+
+// fieldName returns the firestore name of a struct field.
+func fieldName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("firestore"), ",")[0]
+	if name == "" {
+		name = field.Name
+	}
+
+	return name
+}
+
+// encryptValue encrypts the string values within a field value. Values of other
+// types, such as numbers or Firestore sentinels, are returned unchanged.
+func (c *Cipher) encryptValue(ctx context.Context, binding Binding, value interface{}, exempt []string) (interface{}, error) {
+	path := binding.Field
+	if slices.Contains(exempt, path) {
+		return value, nil
+	}
+
+	switch v := value.(type) {
+	case string:
+		return c.EncryptString(ctx, v, binding)
+	case map[string]string:
+		out := make(map[string]string, len(v))
+		for key, s := range v {
+			if slices.Contains(exempt, path+"."+key) {
+				out[key] = s
+
+				continue
+			}
+
+			encrypted, err := c.EncryptString(ctx, s, binding.field(path+"."+key))
+			if err != nil {
+				return nil, err
+			}
+			out[key] = encrypted
+		}
+
+		return out, nil
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, nested := range v {
+			encrypted, err := c.encryptValue(ctx, binding.field(path+"."+key), nested, exempt)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = encrypted
+		}
+
+		return out, nil
+	default:
+		return value, nil
+	}
+}
+
+// decryptValue decrypts string values within a reflected field stored at binding.
+func (c *Cipher) decryptValue(ctx context.Context, binding Binding, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.String:
+		plaintext, err := c.DecryptString(ctx, v.String(), binding)
+		if err != nil {
+			return err
+		}
+		v.SetString(plaintext)
+	case reflect.Ptr:
+		if !v.IsNil() {
+			return c.decryptValue(ctx, binding, v.Elem())
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !v.Field(i).CanSet() {
+				continue
+			}
+			field := binding.field(binding.Field + "." + fieldName(v.Type().Field(i)))
+			if err := c.decryptValue(ctx, field, v.Field(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String || v.Type().Elem().Kind() != reflect.String {
+			return nil
+		}
+		for _, key := range v.MapKeys() {
+			plaintext, err := c.DecryptString(ctx, v.MapIndex(key).String(), binding.field(binding.Field+"."+key.String()))
+			if err != nil {
+				return err
+			}
+			v.SetMapIndex(key, reflect.ValueOf(plaintext).Convert(v.Type().Elem()))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package fieldcrypt
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"google.golang.org/api/cloudkms/v1"
+	"google.golang.org/api/option"
+)
+
+// KeyWrapper wraps and unwraps data encryption keys with a key encryption key.
+type KeyWrapper interface {
+	Wrap(ctx context.Context, dek []byte) ([]byte, error)
+	Unwrap(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// KMSKeyWrapper is a KeyWrapper backed by a Cloud KMS symmetric key.
+// The data encryption keys never leave the process unwrapped; only the
+// wrapped form is sent to KMS and stored alongside the ciphertext.
+type KMSKeyWrapper struct {
+	service *cloudkms.Service
+	keyName string
+}
+
+// NewKMSKeyWrapper creates a KMSKeyWrapper for the given crypto key resource name,
+// e.g. projects/p/locations/europe-west2/keyRings/r/cryptoKeys/k.
+// opts are passed to the KMS client, e.g. to use a private endpoint.
+func NewKMSKeyWrapper(ctx context.Context, keyName string, opts ...option.ClientOption) (*KMSKeyWrapper, error) {
+	service, err := cloudkms.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create KMS client: %w", err)
+	}
+
+	return &KMSKeyWrapper{
+		service: service,
+		keyName: keyName,
+	}, nil
+}
+
+// Wrap encrypts a data encryption key with the KMS key.
+func (k *KMSKeyWrapper) Wrap(ctx context.Context, dek []byte) ([]byte, error) {
+	resp, err := k.service.Projects.Locations.KeyRings.CryptoKeys.Encrypt(k.keyName, &cloudkms.EncryptRequest{
+		Plaintext: base64.StdEncoding.EncodeToString(dek),
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(resp.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode wrapped data key: %w", err)
+	}
+
+	return wrapped, nil
+}
+
+// Unwrap decrypts a data encryption key previously wrapped with the KMS key.
+func (k *KMSKeyWrapper) Unwrap(ctx context.Context, wrapped []byte) ([]byte, error) {
+	resp, err := k.service.Projects.Locations.KeyRings.CryptoKeys.Decrypt(k.keyName, &cloudkms.DecryptRequest{
+		Ciphertext: base64.StdEncoding.EncodeToString(wrapped),
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	dek, err := base64.StdEncoding.DecodeString(resp.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode data key: %w", err)
+	}
+
+	return dek, nil
+}
@@ -0,0 +1,129 @@
+// Package gateway proxies the public API surface to the services behind it by path prefix,
+// so the services stay independently deployable while clients see a single host.
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/requestid"
+	"github.com/thoughtgears/shared-services/internal/router/middleware"
+)
+
+// Route sends requests under Prefix to Upstream.
+type Route struct {
+	Prefix   string
+	Upstream *url.URL
+	// Public routes are proxied without authentication at the gateway.
+	Public bool
+	// Transport overrides the transport passed to Register, e.g. to authenticate to this upstream.
+	Transport http.RoundTripper
+}
+
+// ParseRoutes parses prefix=url pairs into routes. Prefixes listed in public are marked public.
+func ParseRoutes(pairs []string, public []string) ([]Route, error) {
+	routes := make([]Route, 0, len(pairs))
+	for _, pair := range pairs {
+		prefix, rawURL, ok := strings.Cut(pair, "=")
+		if !ok || !strings.HasPrefix(prefix, "/") {
+			return nil, fmt.Errorf("invalid gateway route %q, expected /prefix=url", pair)
+		}
+
+		upstream, err := url.Parse(rawURL)
+		if err != nil || upstream.Scheme == "" || upstream.Host == "" {
+			return nil, fmt.Errorf("invalid upstream URL for %s: %q", prefix, rawURL)
+		}
+
+		prefix = strings.TrimSuffix(prefix, "/")
+		routes = append(routes, Route{
+			Prefix:   prefix,
+			Upstream: upstream,
+			Public:   slices.Contains(public, prefix),
+		})
+	}
+
+	return routes, nil
+}
+
+// corsHeaders are set by the gateway, so the copies added by the upstream services are dropped.
+var corsHeaders = []string{
+	"Access-Control-Allow-Origin",
+	"Access-Control-Allow-Credentials",
+	"Access-Control-Allow-Headers",
+	"Access-Control-Allow-Methods",
+	"Access-Control-Expose-Headers",
+	"Access-Control-Max-Age",
+}
+
+// Register mounts every route on engine. Non-public routes verify the Firebase token at the gateway,
+// so invalid tokens are rejected and counted towards the auth lockout once, at the edge.
+// The token is still forwarded and verified again by the upstream service. Personal access
+// tokens are only verified by the upstream service, see middleware.EdgeAuth.
+//
+// The X-Forwarded-For header sent upstream holds the client address the gateway resolved, so
+// upstream services must list the gateway in their TRUSTED_PROXIES. Otherwise every request
+// appears to come from the gateway, and the auth lockout of one client blocks all of them.
+func Register(engine *gin.Engine, routes []Route, transport http.RoundTripper) {
+	for _, route := range routes {
+		routeTransport := transport
+		if route.Transport != nil {
+			routeTransport = route.Transport
+		}
+		proxy := newProxy(route, routeTransport)
+		handler := func(c *gin.Context) {
+			ctx := context.WithValue(c.Request.Context(), clientIPKey{}, c.ClientIP())
+			proxy.ServeHTTP(c.Writer, c.Request.WithContext(ctx))
+		}
+
+		group := engine.Group(route.Prefix)
+		if !route.Public {
+			group.Use(middleware.EdgeAuth())
+		}
+		group.Any("", handler)
+		group.Any("/*path", handler)
+	}
+}
+
+// clientIPKey is the context key of the client address the gateway resolved for a request.
+type clientIPKey struct{}
+
+// newProxy creates the reverse proxy for a route.
+func newProxy(route Route, transport http.RoundTripper) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Transport: transport,
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(route.Upstream)
+			r.SetXForwarded()
+			// SetXForwarded only adds the direct peer, which is a load balancer or the platform's
+			// front end rather than the client behind it
+			if ip, _ := r.In.Context().Value(clientIPKey{}).(string); ip != "" {
+				r.Out.Header.Set("X-Forwarded-For", ip)
+			}
+			// The gateway's request ID is reused, so one ID covers the whole request path
+			if id := requestid.From(r.In.Context()); id != "" {
+				r.Out.Header.Set(requestid.Header, id)
+			}
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			for _, header := range corsHeaders {
+				resp.Header.Del(header)
+			}
+
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			log.Error().Err(err).Str("upstream", route.Upstream.Host).Str("path", r.URL.Path).Msg("Failed to proxy request")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = fmt.Fprintf(w, `{"error":"upstream unavailable","message":"Failed to reach %s","status":%d}`, route.Prefix, http.StatusBadGateway)
+		},
+	}
+}
@@ -0,0 +1,61 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recorder is a ResponseRecorder the reverse proxy can serve, gin asks it for CloseNotify.
+type recorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (recorder) CloseNotify() <-chan bool {
+	return make(chan bool)
+}
+
+func TestProxyForwardsResolvedClientIP(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("X-Forwarded-For")))
+	}))
+	defer upstream.Close()
+
+	upstreamURL, err := url.Parse(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	if err := engine.SetTrustedProxies([]string{"192.0.2.1"}); err != nil {
+		t.Fatal(err)
+	}
+	Register(engine, []Route{{Prefix: "/v1/users", Upstream: upstreamURL, Public: true}}, http.DefaultTransport)
+
+	tests := map[string]struct {
+		remoteAddr string
+		want       string
+	}{
+		// The load balancer in front of the gateway is trusted, the client it reports is forwarded
+		"trusted proxy": {"192.0.2.1:1234", "198.51.100.7"},
+		// A client cannot choose the address the upstream sees
+		"untrusted peer": {"203.0.113.9:1234", "203.0.113.9"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/users/me", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-Forwarded-For", "198.51.100.7")
+			w := recorder{httptest.NewRecorder()}
+			engine.ServeHTTP(w, req)
+
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("upstream X-Forwarded-For = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
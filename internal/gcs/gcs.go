@@ -5,10 +5,16 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"mime"
+	"net/http"
+	"net/url"
 	"time"
 
 	"cloud.google.com/go/storage"
 	"google.golang.org/api/iterator"
+
+	"github.com/thoughtgears/shared-services/internal/bufpool"
+	"github.com/thoughtgears/shared-services/internal/stats"
 )
 
 // FileInfo contains metadata about a stored file
@@ -27,14 +33,18 @@ type FileInfo struct {
 type CloudStorage struct {
 	client     *storage.Client
 	bucketName string
+	signer     *IAMSigner
 }
 
 // NewGCSStorage creates a new CloudStorage instance
 // It initializes the GCS client and sets the bucket name and project ID.
-func NewGCSStorage(client *storage.Client, bucketName string) (*CloudStorage, error) {
+// The signer is used to create signed URLs; if nil, the storage client falls back
+// to the credentials it detects, which requires a service account key file.
+func NewGCSStorage(client *storage.Client, bucketName string, signer *IAMSigner) (*CloudStorage, error) {
 	return &CloudStorage{
 		client:     client,
 		bucketName: bucketName,
+		signer:     signer,
 	}, nil
 }
 
@@ -44,13 +54,20 @@ func NewGCSStorage(client *storage.Client, bucketName string) (*CloudStorage, er
 // If the upload is successful, it returns nil.
 // If there is an error, it returns the error.
 // The content type is set to the specified value.
-func (g *CloudStorage) Upload(ctx context.Context, path string, content io.Reader, contentType string) (*FileInfo, error) {
+func (g *CloudStorage) Upload(ctx context.Context, path string, content io.Reader, contentType string) (_ *FileInfo, err error) {
+	defer stats.Start("storage.uploads")()
+
+	start := time.Now()
+	var size int64
+	defer func() { recordOperation(ctx, g.bucketName, operationUpload, start, size, err) }()
+
 	bucket := g.client.Bucket(g.bucketName)
 	obj := bucket.Object(path)
 	wc := obj.NewWriter(ctx)
 	wc.ContentType = contentType
 
-	if _, err := io.Copy(wc, content); err != nil {
+	size, err = bufpool.Copy(wc, content)
+	if err != nil {
 		if err := wc.Close(); err != nil {
 			return nil, fmt.Errorf("failed to close writer after error: %w", err)
 		}
@@ -84,15 +101,23 @@ func (g *CloudStorage) Upload(ctx context.Context, path string, content io.Reade
 // If the download is successful, it returns the reader.
 // If there is an error, it returns the error.
 func (g *CloudStorage) Download(ctx context.Context, path string) (io.ReadCloser, error) {
+	start := time.Now()
 	bucket := g.client.Bucket(g.bucketName)
 	obj := bucket.Object(path)
 
 	r, err := obj.NewReader(ctx)
 	if err != nil {
+		recordOperation(ctx, g.bucketName, operationDownload, start, 0, err)
+
 		return nil, fmt.Errorf("failed to create reader: %w", err)
 	}
 
-	return r, nil
+	return &meteredReader{ReadCloser: r, ctx: ctx, bucket: g.bucketName, start: start}, nil
+}
+
+// IsNotExist reports whether err was returned for an object that does not exist.
+func IsNotExist(err error) bool {
+	return errors.Is(err, storage.ErrObjectNotExist)
 }
 
 // Delete a file from GCS
@@ -101,10 +126,13 @@ func (g *CloudStorage) Download(ctx context.Context, path string) (io.ReadCloser
 // If the deletion is successful, it returns nil.
 // If there is an error, it returns the error.
 func (g *CloudStorage) Delete(ctx context.Context, path string) error {
+	start := time.Now()
 	bucket := g.client.Bucket(g.bucketName)
 	obj := bucket.Object(path)
 
-	if err := obj.Delete(ctx); err != nil {
+	err := obj.Delete(ctx)
+	recordOperation(ctx, g.bucketName, operationDelete, start, 0, err)
+	if err != nil {
 		return fmt.Errorf("failed to delete object: %w", err)
 	}
 
@@ -117,7 +145,10 @@ func (g *CloudStorage) Delete(ctx context.Context, path string) error {
 // It iterates through the objects and appends their metadata to a slice of FileInfo.
 // If the listing is successful, it returns the slice of FileInfo.
 // If there is an error, it returns the error.
-func (g *CloudStorage) List(ctx context.Context, prefix string) ([]FileInfo, error) {
+func (g *CloudStorage) List(ctx context.Context, prefix string) (_ []FileInfo, err error) {
+	start := time.Now()
+	defer func() { recordOperation(ctx, g.bucketName, operationList, start, 0, err) }()
+
 	bucket := g.client.Bucket(g.bucketName)
 
 	var files []FileInfo
@@ -142,3 +173,80 @@ func (g *CloudStorage) List(ctx context.Context, prefix string) ([]FileInfo, err
 
 	return files, nil
 }
+
+// SetStorageClass moves a file to a storage class by rewriting the object in place.
+// The object keeps its name, content and metadata.
+func (g *CloudStorage) SetStorageClass(ctx context.Context, path string, class string) (err error) {
+	start := time.Now()
+	defer func() { recordOperation(ctx, g.bucketName, operationRewrite, start, 0, err) }()
+
+	obj := g.client.Bucket(g.bucketName).Object(path)
+	copier := obj.CopierFrom(obj)
+	copier.StorageClass = class
+
+	if _, err := copier.Run(ctx); err != nil {
+		return fmt.Errorf("failed to rewrite object to %s: %w", class, err)
+	}
+
+	return nil
+}
+
+// SetHold places or releases a temporary or event-based hold on an object, see HoldTemporary.
+// Only the hold changes, the object keeps its content and other metadata.
+func (g *CloudStorage) SetHold(ctx context.Context, path string, hold string, held bool) (err error) {
+	start := time.Now()
+	defer func() { recordOperation(ctx, g.bucketName, operationUpdate, start, 0, err) }()
+
+	var attrs storage.ObjectAttrsToUpdate
+	switch hold {
+	case HoldTemporary:
+		attrs.TemporaryHold = held
+	case HoldEventBased:
+		attrs.EventBasedHold = held
+	default:
+		return fmt.Errorf("unknown object hold %q", hold)
+	}
+
+	if _, err := g.client.Bucket(g.bucketName).Object(path).Update(ctx, attrs); err != nil {
+		return fmt.Errorf("failed to update %s hold: %w", hold, err)
+	}
+
+	return nil
+}
+
+// SignedURL creates a V4 signed URL to download a file from GCS
+// It takes a context, file path and the duration the URL is valid for as parameters.
+// When an IAMSigner is configured the URL is signed through the IAM credentials API,
+// so no private key needs to be available to the service.
+// When downloadName is set the file is served as an attachment with that name.
+// If there is an error, it returns the error.
+func (g *CloudStorage) SignedURL(ctx context.Context, path string, expires time.Duration, downloadName string) (string, error) {
+	opts := &storage.SignedURLOptions{
+		Scheme:  storage.SigningSchemeV4,
+		Method:  http.MethodGet,
+		Expires: time.Now().Add(expires),
+	}
+
+	if downloadName != "" {
+		// FormatMediaType encodes names that are not plain ASCII as RFC 2231 filename*
+		opts.QueryParameters = url.Values{
+			"response-content-disposition": {mime.FormatMediaType("attachment", map[string]string{"filename": downloadName})},
+		}
+	}
+
+	if g.signer != nil {
+		email, err := g.signer.Email(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to create signed URL: %w", err)
+		}
+		opts.GoogleAccessID = email
+		opts.SignBytes = g.signer.SignBytes(ctx)
+	}
+
+	signedURL, err := g.client.Bucket(g.bucketName).SignedURL(path, opts)
+	if err != nil {
+		return "", fmt.Errorf("failed to create signed URL: %w", err)
+	}
+
+	return signedURL, nil
+}
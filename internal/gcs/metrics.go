@@ -0,0 +1,120 @@
+package gcs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"google.golang.org/api/googleapi"
+)
+
+// Storage operations recorded on the metrics.
+const (
+	operationUpload   = "upload"
+	operationDownload = "download"
+	operationDelete   = "delete"
+	operationList     = "list"
+	operationRewrite  = "rewrite"
+	operationUpdate   = "update"
+)
+
+// storageMetrics are the GCS instruments, created from the global meter provider.
+var storageMetrics = sync.OnceValue(func() *storageInstruments {
+	meter := otel.Meter("github.com/thoughtgears/shared-services/internal/gcs")
+	duration, _ := meter.Float64Histogram("storage.operation.duration",
+		metric.WithDescription("Duration of Cloud Storage operations, downloads are measured until the reader is closed"),
+		metric.WithUnit("s"),
+	)
+	transferred, _ := meter.Int64Histogram("storage.operation.bytes",
+		metric.WithDescription("Bytes transferred by Cloud Storage uploads and downloads"),
+		metric.WithUnit("By"),
+	)
+	errs, _ := meter.Int64Counter("storage.operation.errors", metric.WithDescription("Failed Cloud Storage operations"))
+
+	return &storageInstruments{duration: duration, bytes: transferred, errors: errs}
+})
+
+type storageInstruments struct {
+	duration metric.Float64Histogram
+	bytes    metric.Int64Histogram
+	errors   metric.Int64Counter
+}
+
+// recordOperation records the duration and outcome of an operation on bucket. Bytes are only recorded
+// for uploads and downloads.
+func recordOperation(ctx context.Context, bucket, operation string, start time.Time, bytes int64, err error) {
+	attrs := []attribute.KeyValue{
+		attribute.String("bucket", bucket),
+		attribute.String("operation", operation),
+		attribute.Bool("error", err != nil),
+	}
+
+	storageMetrics().duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
+	if operation == operationUpload || operation == operationDownload {
+		storageMetrics().bytes.Record(ctx, bytes, metric.WithAttributes(attrs...))
+	}
+	if err != nil {
+		storageMetrics().errors.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("bucket", bucket),
+			attribute.String("operation", operation),
+			attribute.String("error_code", errorCode(err)),
+		))
+	}
+}
+
+// errorCode maps an error to a low cardinality code, the HTTP status for API errors.
+func errorCode(err error) string {
+	var apiErr *googleapi.Error
+	switch {
+	case errors.Is(err, storage.ErrObjectNotExist), errors.Is(err, storage.ErrBucketNotExist):
+		return "404"
+	case errors.As(err, &apiErr):
+		return strconv.Itoa(apiErr.Code)
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "deadline_exceeded"
+	default:
+		return "unknown"
+	}
+}
+
+// meteredReader counts the bytes read from a download and records the operation when it is closed,
+// so the duration covers the transfer and not just opening the object.
+type meteredReader struct {
+	io.ReadCloser
+	ctx    context.Context
+	bucket string
+	start  time.Time
+	bytes  int64
+	err    error
+	once   sync.Once
+}
+
+// Read implements io.Reader and remembers the first read error other than io.EOF.
+func (r *meteredReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.bytes += int64(n)
+	if err != nil && !errors.Is(err, io.EOF) && r.err == nil {
+		r.err = err
+	}
+
+	return n, err
+}
+
+// Close implements io.Closer.
+func (r *meteredReader) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(func() {
+		recordOperation(r.ctx, r.bucket, operationDownload, r.start, r.bytes, r.err)
+	})
+
+	return err
+}
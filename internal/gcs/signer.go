@@ -0,0 +1,96 @@
+package gcs
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"cloud.google.com/go/compute/metadata"
+	"google.golang.org/api/iamcredentials/v1"
+	"google.golang.org/api/option"
+
+	"github.com/thoughtgears/shared-services/internal/lazy"
+)
+
+// IAMSigner signs bytes with a service account through the IAM credentials SignBlob API.
+// It lets the service generate V4 signed URLs on Cloud Run using workload identity,
+// without an exported private key. The runtime identity needs
+// roles/iam.serviceAccountTokenCreator on the signing service account.
+type IAMSigner struct {
+	service *lazy.Value[*iamcredentials.Service]
+	email   *lazy.Value[string]
+}
+
+// NewIAMSigner creates an IAMSigner for the given service account email.
+// If email is empty the runtime service account is looked up on the metadata server.
+// opts are passed to the IAM credentials client, e.g. to use a private endpoint.
+// The client and the service account are only resolved when the first URL is signed, or by Warmup.
+func NewIAMSigner(email string, opts ...option.ClientOption) *IAMSigner {
+	return &IAMSigner{
+		service: lazy.New(func(ctx context.Context) (*iamcredentials.Service, error) {
+			service, err := iamcredentials.NewService(ctx, opts...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create IAM credentials client: %w", err)
+			}
+
+			return service, nil
+		}),
+		email: lazy.New(func(ctx context.Context) (string, error) {
+			if email != "" {
+				return email, nil
+			}
+
+			detected, err := metadata.EmailWithContext(ctx, "default")
+			if err != nil {
+				return "", fmt.Errorf("failed to detect runtime service account: %w", err)
+			}
+
+			return detected, nil
+		}),
+	}
+}
+
+// Email returns the email of the service account used for signing.
+func (s *IAMSigner) Email(ctx context.Context) (string, error) {
+	return s.email.Get(ctx)
+}
+
+// Warmup resolves the client and the service account, so the first signed URL does not wait for them.
+func (s *IAMSigner) Warmup(ctx context.Context) error {
+	if _, err := s.service.Get(ctx); err != nil {
+		return err
+	}
+	_, err := s.email.Get(ctx)
+
+	return err
+}
+
+// SignBytes returns a function that signs bytes with the service account key held by Google,
+// suitable for storage.SignedURLOptions.SignBytes.
+func (s *IAMSigner) SignBytes(ctx context.Context) func([]byte) ([]byte, error) {
+	return func(payload []byte) ([]byte, error) {
+		service, err := s.service.Get(ctx)
+		if err != nil {
+			return nil, err
+		}
+		email, err := s.email.Get(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		name := "projects/-/serviceAccounts/" + email
+		resp, err := service.Projects.ServiceAccounts.SignBlob(name, &iamcredentials.SignBlobRequest{
+			Payload: base64.StdEncoding.EncodeToString(payload),
+		}).Context(ctx).Do()
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign blob: %w", err)
+		}
+
+		signature, err := base64.StdEncoding.DecodeString(resp.SignedBlob)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode signed blob: %w", err)
+		}
+
+		return signature, nil
+	}
+}
@@ -3,6 +3,7 @@ package gcs
 import (
 	"context"
 	"io"
+	"time"
 )
 
 // Storage is an interface for a gcs service
@@ -15,4 +16,25 @@ type Storage interface {
 	Download(ctx context.Context, path string) (io.ReadCloser, error)
 	Delete(ctx context.Context, path string) error
 	List(ctx context.Context, prefix string) ([]FileInfo, error)
+	SignedURL(ctx context.Context, path string, expires time.Duration, downloadName string) (string, error)
+	// SetStorageClass moves a file to a storage class, e.g. StorageClassColdline.
+	SetStorageClass(ctx context.Context, path string, class string) error
+	// SetHold places or releases an object hold on a file, e.g. HoldTemporary.
+	SetHold(ctx context.Context, path string, hold string, held bool) error
 }
+
+// Storage classes of Cloud Storage. Colder classes cost less to keep and more to read, every class
+// can be read right away.
+const (
+	StorageClassStandard = "STANDARD"
+	StorageClassNearline = "NEARLINE"
+	StorageClassColdline = "COLDLINE"
+	StorageClassArchive  = "ARCHIVE"
+)
+
+// Object holds of Cloud Storage. A held object cannot be deleted or replaced until the hold is
+// released. Releasing an event-based hold starts the bucket's retention period over.
+const (
+	HoldTemporary  = "temporary"
+	HoldEventBased = "event_based"
+)
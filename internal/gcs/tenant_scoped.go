@@ -0,0 +1,100 @@
+package gcs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/thoughtgears/shared-services/internal/tenant"
+)
+
+// TenantScopedStorage is a Storage decorator that only allows access to objects under the
+// prefix of the tenant in the context, tenants/<tenant>/. Callers build paths with
+// tenant.PathPrefix; the decorator enforces that no path escapes the tenant.
+type TenantScopedStorage struct {
+	inner Storage
+}
+
+// NewTenantScopedStorage wraps a Storage so every call is checked against the tenant in the context.
+func NewTenantScopedStorage(inner Storage) *TenantScopedStorage {
+	return &TenantScopedStorage{inner: inner}
+}
+
+// checkPath returns an error unless path is inside the tenant's prefix.
+func checkPath(ctx context.Context, path string) error {
+	id := tenant.From(ctx)
+	if id == "" {
+		return fmt.Errorf("failed to access %s: %w", path, tenant.ErrMissing)
+	}
+
+	if !strings.HasPrefix(path, tenant.PathPrefix(ctx)) || strings.Contains(path, "..") {
+		return fmt.Errorf("path %s is outside tenant %s", path, id)
+	}
+
+	return nil
+}
+
+// Upload uploads a file within the tenant's prefix.
+func (s *TenantScopedStorage) Upload(ctx context.Context, path string, content io.Reader, contentType string) (*FileInfo, error) {
+	if err := checkPath(ctx, path); err != nil {
+		return nil, err
+	}
+
+	return s.inner.Upload(ctx, path, content, contentType)
+}
+
+// Download downloads a file within the tenant's prefix.
+func (s *TenantScopedStorage) Download(ctx context.Context, path string) (io.ReadCloser, error) {
+	if err := checkPath(ctx, path); err != nil {
+		return nil, err
+	}
+
+	return s.inner.Download(ctx, path)
+}
+
+// Delete deletes a file within the tenant's prefix.
+func (s *TenantScopedStorage) Delete(ctx context.Context, path string) error {
+	if err := checkPath(ctx, path); err != nil {
+		return err
+	}
+
+	return s.inner.Delete(ctx, path)
+}
+
+// List lists files within the tenant's prefix.
+func (s *TenantScopedStorage) List(ctx context.Context, prefix string) ([]FileInfo, error) {
+	if err := checkPath(ctx, prefix); err != nil {
+		return nil, err
+	}
+
+	return s.inner.List(ctx, prefix)
+}
+
+// SignedURL creates a signed URL for a file within the tenant's prefix.
+func (s *TenantScopedStorage) SignedURL(ctx context.Context, path string, expires time.Duration, downloadName string) (string, error) {
+	if err := checkPath(ctx, path); err != nil {
+		return "", err
+	}
+
+	return s.inner.SignedURL(ctx, path, expires, downloadName)
+}
+
+// SetStorageClass moves a file within the tenant's prefix to a storage class.
+func (s *TenantScopedStorage) SetStorageClass(ctx context.Context, path string, class string) error {
+	if err := checkPath(ctx, path); err != nil {
+		return err
+	}
+
+	return s.inner.SetStorageClass(ctx, path, class)
+}
+
+// SetHold places or releases a hold on a file within the tenant's prefix.
+func (s *TenantScopedStorage) SetHold(ctx context.Context, path string, hold string, held bool) error {
+	if err := checkPath(ctx, path); err != nil {
+		return err
+	}
+
+	return s.inner.SetHold(ctx, path, hold, held)
+}
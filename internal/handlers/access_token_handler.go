@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/redact"
+	"github.com/thoughtgears/shared-services/internal/router/middleware"
+	"github.com/thoughtgears/shared-services/internal/services"
+	"github.com/thoughtgears/shared-services/pkg/apperr"
+)
+
+// AccessTokenHandler serves the personal access tokens of users.
+type AccessTokenHandler struct {
+	service services.AccessTokenService
+}
+
+// NewAccessTokenHandler creates a new instance of AccessTokenHandler.
+func NewAccessTokenHandler(service services.AccessTokenService) *AccessTokenHandler {
+	return &AccessTokenHandler{
+		service: service,
+	}
+}
+
+// RegisterRoutes registers the routes for creating, listing and revoking access tokens.
+// They need a Firebase sign-in, so access tokens cannot be used to create more tokens.
+func (a *AccessTokenHandler) RegisterRoutes(router *gin.Engine) {
+	users := router.Group("/v1/users")
+	users.Use(middleware.FirebaseAuth(), middleware.TenantScope(), middleware.UserRateLimit())
+	{
+		users.POST("/:id/tokens", a.Create)
+		users.GET("/:id/tokens", a.List)
+		users.DELETE("/:id/tokens/:token_id", a.Revoke)
+	}
+}
+
+// createAccessTokenRequest is the payload for creating a personal access token.
+// ExpiresInDays defaults to 90 days when it is left out.
+type createAccessTokenRequest struct {
+	Name          string                    `json:"name" binding:"required"`
+	Scopes        []models.AccessTokenScope `json:"scopes" binding:"required"`
+	ExpiresInDays int                       `json:"expires_in_days"`
+}
+
+// Create handles the POST request to create a personal access token. The response holds the
+// token itself, which cannot be retrieved again.
+func (a *AccessTokenHandler) Create(c *gin.Context) {
+	var request createAccessTokenRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid request payload",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	token, err := a.service.Create(c, c.Param("id"), services.CreateAccessTokenInput{
+		Name:      request.Name,
+		Scopes:    request.Scopes,
+		ExpiresIn: time.Duration(request.ExpiresInDays) * 24 * time.Hour,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create access token")
+		if respondWithValidationError(c, err, "Invalid access token") {
+			return
+		}
+
+		apperr.Respond(c, err, "Failed to create access token")
+
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data":    token,
+		"message": "Access token created successfully, store it now as it is not shown again",
+		"status":  http.StatusCreated,
+	})
+}
+
+// List handles the GET request for a user's access tokens, without their secrets.
+func (a *AccessTokenHandler) List(c *gin.Context) {
+	tokens, err := a.service.List(c, c.Param("id"))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list access tokens")
+		if respondWithMissingIndex(c, err) {
+			return
+		}
+
+		apperr.Respond(c, err, "Failed to list access tokens")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    tokens,
+		"message": "Access tokens retrieved successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// Revoke handles the DELETE request to revoke an access token.
+func (a *AccessTokenHandler) Revoke(c *gin.Context) {
+	if err := a.service.Revoke(c, c.Param("id"), c.Param("token_id")); err != nil {
+		log.Error().Err(err).Msg("Failed to revoke access token")
+		apperr.Respond(c, err, "Failed to revoke access token")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Access token revoked successfully",
+		"status":  http.StatusOK,
+	})
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/text/language"
+
+	"github.com/thoughtgears/shared-services/internal/redact"
+	"github.com/thoughtgears/shared-services/internal/router/middleware"
+	"github.com/thoughtgears/shared-services/internal/services"
+	"github.com/thoughtgears/shared-services/pkg/apperr"
+)
+
+// AccountHandler serves the password reset and email change flows of user accounts.
+type AccountHandler struct {
+	service services.AccountService
+}
+
+// NewAccountHandler creates a new instance of AccountHandler.
+func NewAccountHandler(service services.AccountService) *AccountHandler {
+	return &AccountHandler{
+		service: service,
+	}
+}
+
+// RegisterRoutes registers the routes for password resets and email changes.
+// Requesting a password reset and confirming an email change are done by users who cannot sign in
+// with their current credentials, so those routes are not authenticated.
+func (a *AccountHandler) RegisterRoutes(router *gin.Engine) {
+	public := router.Group("/v1/account")
+	public.Use(middleware.TenantScope())
+	{
+		public.POST("/password-reset", a.SendPasswordReset)
+		public.POST("/email-change/confirm", a.ConfirmEmailChange)
+	}
+
+	account := router.Group("/v1/account")
+	account.Use(middleware.FirebaseAuth(), middleware.TenantScope(), middleware.UserRateLimit())
+	{
+		account.POST("/email-change", a.RequestEmailChange)
+	}
+}
+
+// passwordResetRequest is the payload for requesting a password reset email.
+type passwordResetRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+// SendPasswordReset handles the POST request to email a password reset link.
+// It responds the same whether or not an account uses the address. The email is in the first
+// language of the Accept-Language header.
+func (a *AccountHandler) SendPasswordReset(c *gin.Context) {
+	var request passwordResetRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid request payload",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	if err := a.service.SendPasswordReset(c, request.Email, acceptLanguage(c)); err != nil {
+		log.Error().Err(err).Msg("Failed to send password reset")
+		if respondWithValidationError(c, err, "Invalid password reset") {
+			return
+		}
+
+		apperr.Respond(c, err, "Failed to send password reset")
+
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "If an account uses this email address, a password reset link has been sent",
+		"status":  http.StatusAccepted,
+	})
+}
+
+// emailChangeRequest is the payload for changing the authenticated user's email address.
+type emailChangeRequest struct {
+	NewEmail string `json:"new_email" binding:"required"`
+}
+
+// RequestEmailChange handles the POST request to send a confirmation link to a new email address.
+func (a *AccountHandler) RequestEmailChange(c *gin.Context) {
+	var request emailChangeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid request payload",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	if err := a.service.RequestEmailChange(c, request.NewEmail); err != nil {
+		log.Error().Err(err).Msg("Failed to request email change")
+		if respondWithValidationError(c, err, "Invalid email change") {
+			return
+		}
+
+		apperr.Respond(c, err, "Failed to request email change")
+
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "A confirmation link has been sent to the new email address",
+		"status":  http.StatusAccepted,
+	})
+}
+
+// confirmEmailChangeRequest is the payload for confirming an email change.
+type confirmEmailChangeRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ConfirmEmailChange handles the POST request to apply an email change with the token of its link.
+func (a *AccountHandler) ConfirmEmailChange(c *gin.Context) {
+	var request confirmEmailChangeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid request payload",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	user, err := a.service.ConfirmEmailChange(c, request.Token)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to confirm email change")
+		if errors.Is(err, services.ErrEmailChangeInvalid) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid email change",
+				"message": "The email change link is invalid, expired or was already used",
+				"status":  http.StatusBadRequest,
+			})
+
+			return
+		}
+		if respondWithValidationError(c, err, "Invalid email change") {
+			return
+		}
+
+		apperr.Respond(c, err, "Failed to confirm email change")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    user,
+		"message": "Email address changed successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// acceptLanguage returns the language the caller prefers most in the Accept-Language header,
+// undetermined when it has none.
+func acceptLanguage(c *gin.Context) language.Tag {
+	tags, _, err := language.ParseAcceptLanguage(c.GetHeader("Accept-Language"))
+	if err != nil || len(tags) == 0 {
+		return language.Und
+	}
+
+	return tags[0]
+}
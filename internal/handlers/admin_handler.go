@@ -0,0 +1,734 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/debugcapture"
+	"github.com/thoughtgears/shared-services/internal/loglevel"
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/redact"
+	"github.com/thoughtgears/shared-services/internal/router/middleware"
+	"github.com/thoughtgears/shared-services/internal/services"
+	"github.com/thoughtgears/shared-services/internal/stats"
+	"github.com/thoughtgears/shared-services/internal/tenant"
+	"github.com/thoughtgears/shared-services/pkg/apperr"
+)
+
+// AdminHandler serves operations reserved for administrators, such as moving data between regions.
+type AdminHandler struct {
+	documents   services.DocumentService
+	users       services.UserMergeService
+	claims      services.UserClaimsService
+	settings    services.TenantSettingsService
+	offboarding services.OffboardingService
+	tiering     services.TieringService
+	imports     services.ImportService
+	captures    *debugcapture.Recorder
+	logLevel    *loglevel.Controller
+}
+
+// NewAdminHandler creates a new instance of AdminHandler.
+// The tenant export routes are only registered when offboarding is set, the tiering route when
+// tiering is set, the import route when imports is set, the debug capture routes when captures is
+// set, and the log level routes when logLevel is set.
+func NewAdminHandler(
+	documents services.DocumentService,
+	users services.UserMergeService,
+	claims services.UserClaimsService,
+	settings services.TenantSettingsService,
+	offboarding services.OffboardingService,
+	tiering services.TieringService,
+	imports services.ImportService,
+	captures *debugcapture.Recorder,
+	logLevel *loglevel.Controller,
+) *AdminHandler {
+	return &AdminHandler{
+		documents:   documents,
+		users:       users,
+		claims:      claims,
+		settings:    settings,
+		offboarding: offboarding,
+		tiering:     tiering,
+		imports:     imports,
+		captures:    captures,
+		logLevel:    logLevel,
+	}
+}
+
+// RegisterRoutes registers the admin routes under /admin/v1. They require a verified token with
+// the admin claim.
+func (a *AdminHandler) RegisterRoutes(router *gin.Engine) {
+	admin := router.Group("/admin/v1")
+	admin.Use(middleware.FirebaseAuth(), middleware.TenantScope(), middleware.RequireAdmin())
+	{
+		admin.POST("/documents/:id/move-region", a.MoveDocumentRegion)
+		admin.PUT("/documents/:id/legal-hold", a.PlaceLegalHold)
+		admin.DELETE("/documents/:id/legal-hold", a.ReleaseLegalHold)
+		admin.GET("/review-queue", a.GetReviewQueue)
+		admin.POST("/documents/:id/claim", a.ClaimDocument)
+		admin.POST("/documents/:id/review", a.ReviewDocument)
+		admin.GET("/review-queue/bundles", a.GetBundleReviewQueue)
+		admin.POST("/bundles/:id/claim", a.ClaimBundle)
+		admin.POST("/bundles/:id/review", a.ReviewBundle)
+		admin.POST("/users/:id/merge", a.MergeUsers)
+		admin.PUT("/users/:id/claims", a.SetUserClaims)
+		admin.GET("/settings", a.GetSettings)
+		admin.PUT("/settings", a.UpdateSettings)
+		if a.offboarding != nil {
+			admin.POST("/export", a.ExportTenant)
+		}
+		if a.tiering != nil {
+			admin.POST("/tiering/run", a.RunTiering)
+		}
+		if a.imports != nil {
+			admin.POST("/import", a.Import)
+		}
+		if a.captures != nil {
+			admin.GET("/debug/capture", a.GetDebugCapture)
+			admin.PUT("/debug/capture", a.SetDebugCapture)
+			admin.GET("/debug/captures", a.ListDebugCaptures)
+		}
+		admin.GET("/stats", a.GetStats)
+		if a.logLevel != nil {
+			admin.GET("/log-level", a.GetLogLevel)
+			admin.PUT("/log-level", a.SetLogLevel)
+		}
+	}
+}
+
+// moveRegionRequest is the payload for moving a document to another region.
+type moveRegionRequest struct {
+	Region string `json:"region" binding:"required"`
+}
+
+// MoveDocumentRegion handles the POST request to move a document's content to another residency region.
+func (a *AdminHandler) MoveDocumentRegion(c *gin.Context) {
+	id := c.Param("id")
+
+	var request moveRegionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid request payload",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	document, err := a.documents.MoveRegion(c, id, request.Region)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to move document region")
+		if respondWithValidationError(c, err, "Invalid region") {
+			return
+		}
+
+		apperr.Respond(c, err, "Failed to move document")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    document,
+		"message": "Document moved successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// PlaceLegalHold handles the PUT request to place a document under legal hold. While held the
+// document cannot be changed or deleted, its objects carry a temporary or event-based hold.
+func (a *AdminHandler) PlaceLegalHold(c *gin.Context) {
+	id := c.Param("id")
+
+	var request services.PlaceLegalHoldInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid request payload",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	document, err := a.documents.PlaceLegalHold(c, id, request)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to place legal hold")
+		if respondWithValidationError(c, err, "Invalid legal hold") {
+			return
+		}
+
+		apperr.Respond(c, err, "Failed to place legal hold")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    document,
+		"message": "Legal hold placed successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// ReleaseLegalHold handles the DELETE request to release the legal hold of a document.
+func (a *AdminHandler) ReleaseLegalHold(c *gin.Context) {
+	document, err := a.documents.ReleaseLegalHold(c, c.Param("id"))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to release legal hold")
+		apperr.Respond(c, err, "Failed to release legal hold")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    document,
+		"message": "Legal hold released successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// mergeUsersRequest is the payload for merging a duplicate user into another user.
+type mergeUsersRequest struct {
+	SourceID string `json:"source_id" binding:"required"`
+}
+
+// MergeUsers handles the POST request to merge the duplicate user source_id into the user id,
+// both identified by Firebase ID. The merged user is returned.
+func (a *AdminHandler) MergeUsers(c *gin.Context) {
+	id := c.Param("id")
+
+	var request mergeUsersRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid request payload",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	user, err := a.users.Merge(c, id, request.SourceID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to merge users")
+		if respondWithValidationError(c, err, "Invalid merge") {
+			return
+		}
+
+		apperr.Respond(c, err, "Failed to merge users")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    user,
+		"message": "Users merged successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// SetUserClaims handles the PUT request to replace the custom claims of a user, given by Firebase ID.
+// Roles or a tenant left out of the payload are removed from the user's claims.
+func (a *AdminHandler) SetUserClaims(c *gin.Context) {
+	var claims models.UserClaims
+	if err := c.ShouldBindJSON(&claims); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid request payload",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	user, err := a.claims.SetClaims(c, c.Param("id"), claims)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to set user claims")
+		if respondWithValidationError(c, err, "Invalid claims") {
+			return
+		}
+
+		apperr.Respond(c, err, "Failed to set user claims")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    user,
+		"message": "User claims updated successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// GetReviewQueue handles the GET request for the documents awaiting review, oldest first.
+// The status query parameter selects unclaimed (pending, the default) or claimed (in_review) documents.
+func (a *AdminHandler) GetReviewQueue(c *gin.Context) {
+	opts, err := parseListOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid pagination parameters",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	page, err := a.documents.ReviewQueue(c, models.DocumentStatus(c.Query("status")), opts)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get review queue")
+		if respondWithValidationError(c, err, "Invalid review queue") {
+			return
+		}
+
+		apperr.Respond(c, err, "Failed to retrieve review queue")
+
+		return
+	}
+
+	respondWithPage(c, page, "Review queue retrieved successfully")
+}
+
+// ClaimDocument handles the POST request to assign a pending document to the calling reviewer.
+func (a *AdminHandler) ClaimDocument(c *gin.Context) {
+	document, err := a.documents.Claim(c, c.Param("id"))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to claim document")
+		apperr.Respond(c, err, "Failed to claim document")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    document,
+		"message": "Document claimed successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// ReviewDocument handles the POST request to verify or reject a document claimed by the caller.
+func (a *AdminHandler) ReviewDocument(c *gin.Context) {
+	var request services.ReviewDocumentInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid request payload",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	document, err := a.documents.Review(c, c.Param("id"), request)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to review document")
+		if respondWithValidationError(c, err, "Invalid review") {
+			return
+		}
+
+		apperr.Respond(c, err, "Failed to review document")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    document,
+		"message": "Document reviewed successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// GetBundleReviewQueue handles the GET request for the bundles awaiting review, oldest first.
+// The status query parameter works like that of GetReviewQueue.
+func (a *AdminHandler) GetBundleReviewQueue(c *gin.Context) {
+	opts, err := parseListOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid pagination parameters",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	page, err := a.documents.BundleReviewQueue(c, models.DocumentStatus(c.Query("status")), opts)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get bundle review queue")
+		if respondWithValidationError(c, err, "Invalid review queue") {
+			return
+		}
+
+		apperr.Respond(c, err, "Failed to retrieve bundle review queue")
+
+		return
+	}
+
+	respondWithPage(c, page, "Bundle review queue retrieved successfully")
+}
+
+// ClaimBundle handles the POST request to assign a pending bundle to the calling reviewer.
+func (a *AdminHandler) ClaimBundle(c *gin.Context) {
+	bundle, err := a.documents.ClaimBundle(c, c.Param("id"))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to claim bundle")
+		apperr.Respond(c, err, "Failed to claim bundle")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    bundle,
+		"message": "Bundle claimed successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// ReviewBundle handles the POST request to verify or reject a bundle claimed by the caller.
+func (a *AdminHandler) ReviewBundle(c *gin.Context) {
+	var request services.ReviewDocumentInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid request payload",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	bundle, err := a.documents.ReviewBundle(c, c.Param("id"), request)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to review bundle")
+		if respondWithValidationError(c, err, "Invalid review") {
+			return
+		}
+
+		apperr.Respond(c, err, "Failed to review bundle")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    bundle,
+		"message": "Bundle reviewed successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// GetSettings handles the GET request for the runtime settings of the caller's tenant.
+func (a *AdminHandler) GetSettings(c *gin.Context) {
+	settings, err := a.settings.Get(c)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get tenant settings")
+		apperr.Respond(c, err, "Failed to retrieve settings")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    settings,
+		"message": "Settings retrieved successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// UpdateSettings handles the PUT request to replace the runtime settings of the caller's tenant.
+func (a *AdminHandler) UpdateSettings(c *gin.Context) {
+	var request models.TenantSettings
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid request payload",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	settings, err := a.settings.Update(c, &request)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to update tenant settings")
+		if respondWithValidationError(c, err, "Invalid settings") {
+			return
+		}
+
+		apperr.Respond(c, err, "Failed to update settings")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    settings,
+		"message": "Settings updated successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// ExportTenant handles the POST request to export, and optionally purge, all data of the caller's tenant.
+// The request blocks until the export is complete and returns its manifest.
+func (a *AdminHandler) ExportTenant(c *gin.Context) {
+	var request services.ExportTenantInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid request payload",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	manifest, err := a.offboarding.Export(c, request)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to export tenant")
+		if respondWithValidationError(c, err, "Invalid export request") {
+			return
+		}
+
+		// The manifest lists what was exported before the failure
+		code := apperr.Status(apperr.KindOf(err))
+		c.JSON(code, gin.H{
+			"data":    manifest,
+			"error":   redact.Error(err),
+			"message": "Failed to export tenant",
+			"status":  code,
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    manifest,
+		"message": "Tenant exported successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// RunTiering handles the POST request to move the content of the tenant's documents untouched for
+// the configured age to cold storage. A run moves a limited number of documents, when more remain
+// the result says so and the job, e.g. Cloud Scheduler, calls again.
+func (a *AdminHandler) RunTiering(c *gin.Context) {
+	result, err := a.tiering.Run(c)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to tier documents")
+		// The result counts what was tiered before the failure
+		code := apperr.Status(apperr.KindOf(err))
+		c.JSON(code, gin.H{
+			"data":    result,
+			"error":   redact.Error(err),
+			"message": "Failed to tier documents",
+			"status":  code,
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    result,
+		"message": "Documents tiered successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// Import handles the POST request to import the users and documents of a manifest in the import
+// bucket into the caller's tenant. The request blocks until the import is done and returns its
+// report; it keeps running if the caller disconnects, so the checkpoint stays accurate.
+func (a *AdminHandler) Import(c *gin.Context) {
+	var request services.ImportInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid request payload",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	report, err := a.imports.Import(context.WithoutCancel(c), request)
+	if err != nil {
+		log.Error().Err(err).Str("manifest", request.Manifest).Msg("Failed to import")
+		// The report counts what was imported before the failure
+		code := apperr.Status(apperr.KindOf(err))
+		c.JSON(code, gin.H{
+			"data":    report,
+			"error":   redact.Error(err),
+			"message": "Failed to import",
+			"status":  code,
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    report,
+		"message": "Import completed successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// maxDebugCaptureTTL limits how long debug capture can be enabled at once.
+const maxDebugCaptureTTL = time.Hour
+
+// debugCaptureRequest is the payload for changing the debug capture mode.
+type debugCaptureRequest struct {
+	Enabled    bool    `json:"enabled"`
+	SampleRate float64 `json:"sample_rate" binding:"min=0,max=1"`
+	// TTL is a duration such as "15m", capped at one hour.
+	TTL string `json:"ttl"`
+}
+
+// GetDebugCapture handles the GET request for the current debug capture mode.
+func (a *AdminHandler) GetDebugCapture(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data":    a.captures.Settings(),
+		"message": "Debug capture settings retrieved successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// SetDebugCapture handles the PUT request to enable or disable capturing of failing requests.
+// Capturing switches itself off once the TTL has passed. The mode and the captures are kept
+// in memory, so they only apply to the instance that served the request.
+func (a *AdminHandler) SetDebugCapture(c *gin.Context) {
+	var request debugCaptureRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid request payload",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	if !request.Enabled {
+		c.JSON(http.StatusOK, gin.H{
+			"data":    a.captures.Disable(),
+			"message": "Debug capture disabled",
+			"status":  http.StatusOK,
+		})
+
+		return
+	}
+
+	ttl := 15 * time.Minute
+	if request.TTL != "" {
+		parsed, err := time.ParseDuration(request.TTL)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid ttl",
+				"message": "ttl must be a positive duration such as 15m",
+				"status":  http.StatusBadRequest,
+			})
+
+			return
+		}
+		ttl = min(parsed, maxDebugCaptureTTL)
+	}
+
+	settings := a.captures.Enable(request.SampleRate, ttl)
+	log.Warn().Float64("sample_rate", settings.SampleRate).Time("expires_at", settings.ExpiresAt).Msg("Debug capture enabled")
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    settings,
+		"message": "Debug capture enabled",
+		"status":  http.StatusOK,
+	})
+}
+
+// ListDebugCaptures handles the GET request for the captured failing requests of the caller's tenant, newest first.
+func (a *AdminHandler) ListDebugCaptures(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data":    a.captures.List(tenant.From(c)),
+		"message": "Debug captures retrieved successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// maxLogLevelTTL limits how long a changed log level stays in effect.
+const maxLogLevelTTL = 4 * time.Hour
+
+// logLevelRequest is the payload for changing the log level.
+type logLevelRequest struct {
+	// Level is a zerolog level name such as "debug" or "info".
+	Level string `json:"level" binding:"required"`
+	// TTL is a duration such as "30m" after which the default level is restored, capped at four hours.
+	TTL string `json:"ttl"`
+}
+
+// GetLogLevel handles the GET request for the current log level.
+func (a *AdminHandler) GetLogLevel(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data":    a.logLevel.Get(),
+		"message": "Log level retrieved successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// SetLogLevel handles the PUT request to change the log level. The default level is restored
+// after the TTL. Like debug capture, the change only applies to the instance that served the request.
+func (a *AdminHandler) SetLogLevel(c *gin.Context) {
+	var request logLevelRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid request payload",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	level, err := zerolog.ParseLevel(request.Level)
+	if err != nil || level == zerolog.NoLevel {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid level",
+			"message": "level must be one of trace, debug, info, warn, error, fatal, panic or disabled",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	ttl := 30 * time.Minute
+	if request.TTL != "" {
+		parsed, err := time.ParseDuration(request.TTL)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid ttl",
+				"message": "ttl must be a positive duration such as 30m",
+				"status":  http.StatusBadRequest,
+			})
+
+			return
+		}
+		ttl = min(parsed, maxLogLevelTTL)
+	}
+
+	state := a.logLevel.Set(level, ttl)
+	log.Warn().Str("level", state.Level).Interface("reverts_at", state.RevertsAt).Msg("Log level changed")
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    state,
+		"message": "Log level changed",
+		"status":  http.StatusOK,
+	})
+}
+
+// GetStats handles the GET request for a summary of the live state of the instance that served it,
+// such as cache hit rates, in-flight work and recent errors.
+func (a *AdminHandler) GetStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data":    stats.Get(),
+		"message": "Stats retrieved successfully",
+		"status":  http.StatusOK,
+	})
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/bufpool"
+	"github.com/thoughtgears/shared-services/internal/redact"
+	"github.com/thoughtgears/shared-services/internal/router/middleware"
+	"github.com/thoughtgears/shared-services/internal/services"
+	"github.com/thoughtgears/shared-services/pkg/apperr"
+)
+
+// AvatarHandler serves the profile pictures of users.
+type AvatarHandler struct {
+	service services.AvatarService
+}
+
+// NewAvatarHandler creates a new instance of AvatarHandler.
+func NewAvatarHandler(service services.AvatarService) *AvatarHandler {
+	return &AvatarHandler{
+		service: service,
+	}
+}
+
+// RegisterRoutes registers the routes for uploading, removing and reading profile pictures.
+func (a *AvatarHandler) RegisterRoutes(router *gin.Engine) {
+	users := router.Group("/v1/users")
+	users.Use(middleware.FirebaseAuth(), middleware.TenantScope(), middleware.UserRateLimit())
+	{
+		users.PUT("/:id/avatar", middleware.UploadLimit(), a.Upload)
+		users.DELETE("/:id/avatar", a.Delete)
+		users.GET("/:id/avatar/:size", a.Get)
+	}
+}
+
+// Upload handles the PUT request to replace a user's profile picture with the image in the file form field.
+func (a *AvatarHandler) Upload(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get file from form")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "No file was uploaded or invalid file",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	openedFile, err := file.Open()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to open uploaded file")
+		apperr.Respond(c, err, "Failed to read uploaded file")
+
+		return
+	}
+	defer openedFile.Close()
+
+	content, err := bufpool.ReadAll(openedFile, file.Size)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to read file content")
+		apperr.Respond(c, err, "Failed to read file content")
+
+		return
+	}
+
+	user, err := a.service.Upload(c, c.Param("id"), content)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to upload avatar")
+		if respondWithValidationError(c, err, "Invalid avatar") {
+			return
+		}
+
+		apperr.Respond(c, err, "Failed to upload avatar")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    user,
+		"message": "Avatar uploaded successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// Delete handles the DELETE request to remove a user's profile picture.
+func (a *AvatarHandler) Delete(c *gin.Context) {
+	user, err := a.service.Delete(c, c.Param("id"))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to delete avatar")
+		apperr.Respond(c, err, "Failed to delete avatar")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    user,
+		"message": "Avatar deleted successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// Get handles the GET request for a size variant of a user's profile picture.
+// The URLs on the user change with every upload, so the variants can be cached for long.
+func (a *AvatarHandler) Get(c *gin.Context) {
+	size, err := strconv.Atoi(c.Param("size"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid size, expected a number of pixels",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	content, err := a.service.Get(c, c.Param("id"), size)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get avatar")
+		if respondWithValidationError(c, err, "Invalid avatar request") {
+			return
+		}
+		if errors.Is(err, services.ErrAvatarNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   redact.Error(err),
+				"message": "The user has no avatar",
+				"status":  http.StatusNotFound,
+			})
+
+			return
+		}
+
+		apperr.Respond(c, err, "Failed to retrieve avatar")
+
+		return
+	}
+	defer content.Close()
+
+	c.DataFromReader(http.StatusOK, content.Size, content.ContentType, content, map[string]string{
+		"Cache-Control": "private, max-age=86400",
+	})
+}
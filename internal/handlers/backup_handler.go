@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/router/middleware"
+	"github.com/thoughtgears/shared-services/internal/services"
+	"github.com/thoughtgears/shared-services/pkg/apperr"
+)
+
+// BackupHandler serves the backup route called by a scheduled job, e.g. Cloud Scheduler.
+type BackupHandler struct {
+	backups services.BackupService
+}
+
+// NewBackupHandler creates a new instance of BackupHandler. No route is registered when backups is nil.
+func NewBackupHandler(backups services.BackupService) *BackupHandler {
+	return &BackupHandler{
+		backups: backups,
+	}
+}
+
+// RegisterRoutes registers the backup route. Backups cover every tenant, so the route is for
+// other services presenting an ID token of an allowed service account, not for users.
+func (b *BackupHandler) RegisterRoutes(router *gin.Engine) {
+	if b.backups == nil {
+		return
+	}
+
+	backups := router.Group("/v1/backups")
+	backups.Use(middleware.ServiceAuth())
+	{
+		backups.POST("", b.CreateBackup)
+	}
+}
+
+// CreateBackup handles the POST request to back up the configured collections.
+// The request blocks until the backup is complete and returns its manifest.
+func (b *BackupHandler) CreateBackup(c *gin.Context) {
+	manifest, err := b.backups.Backup(c)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to back up collections")
+		apperr.Respond(c, err, "Failed to back up collections")
+
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data":    manifest,
+		"message": "Backup created successfully",
+		"status":  http.StatusCreated,
+	})
+}
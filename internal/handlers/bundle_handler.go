@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/bufpool"
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/redact"
+	"github.com/thoughtgears/shared-services/internal/router/middleware"
+	"github.com/thoughtgears/shared-services/internal/services"
+	"github.com/thoughtgears/shared-services/pkg/apperr"
+)
+
+// BundleHandler serves the bundles grouping the documents of a multi-part document,
+// such as the front and back of an ID card.
+type BundleHandler struct {
+	service services.DocumentService
+}
+
+// NewBundleHandler creates a new instance of BundleHandler.
+func NewBundleHandler(service services.DocumentService) *BundleHandler {
+	return &BundleHandler{
+		service: service,
+	}
+}
+
+// RegisterRoutes registers the routes for bundle operations.
+func (b *BundleHandler) RegisterRoutes(router *gin.Engine) {
+	bundles := router.Group("/v1/bundles")
+	bundles.Use(middleware.APIAuth(models.ScopeDocumentsRead, models.ScopeDocumentsWrite), middleware.TenantScope(), middleware.UserRateLimit())
+	{
+		bundles.POST("", middleware.UploadLimit(), b.Create)
+		bundles.GET("/:id", b.GetByID)
+		bundles.DELETE("/:id", b.Delete)
+	}
+}
+
+// Create handles the multipart POST request to create a bundle. Every "file" field becomes a
+// document of the bundle in the order it was sent, the other fields are those of a single
+// document upload and apply to all of them.
+func (b *BundleHandler) Create(c *gin.Context) {
+	userID := c.PostForm("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "user_id is required",
+			"message": "Missing required field: user_id",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	var expiryDate *time.Time
+	if expiryDateStr := c.PostForm("expiry_date"); expiryDateStr != "" {
+		parsed, err := time.Parse(time.DateOnly, expiryDateStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   redact.Error(err),
+				"message": "Invalid expiry date, expected YYYY-MM-DD",
+				"status":  http.StatusBadRequest,
+			})
+
+			return
+		}
+		expiryDate = &parsed
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil || len(form.File["file"]) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "No files were uploaded or invalid files",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	files := make([]services.BundleFile, 0, len(form.File["file"]))
+	for _, file := range form.File["file"] {
+		openedFile, err := file.Open()
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to open uploaded file")
+			apperr.Respond(c, err, "Failed to read uploaded file")
+
+			return
+		}
+
+		content, err := bufpool.ReadAll(openedFile, file.Size)
+		openedFile.Close()
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to read file content")
+			apperr.Respond(c, err, "Failed to read file content")
+
+			return
+		}
+
+		files = append(files, services.BundleFile{
+			Content:          content,
+			OriginalFilename: file.Filename,
+		})
+	}
+
+	bundle, err := b.service.CreateBundle(c, services.CreateBundleInput{
+		UserID:         userID,
+		OrganizationID: c.PostForm("organization_id"),
+		Type:           models.DocumentType(c.PostForm("document_type")),
+		ExpiryDate:     expiryDate,
+		Metadata:       c.PostFormMap("metadata"),
+		Files:          files,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create bundle")
+		if respondWithValidationError(c, err, "Invalid bundle") {
+			return
+		}
+
+		apperr.Respond(c, err, "Failed to create bundle")
+
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"data":    bundle,
+		"message": "Bundle created successfully",
+		"status":  http.StatusAccepted,
+	})
+}
+
+// GetByID handles the GET request to retrieve a bundle with its documents.
+func (b *BundleHandler) GetByID(c *gin.Context) {
+	bundle, err := b.service.GetBundle(c, c.Param("id"))
+	if err != nil {
+		log.Info().Err(err).Msg("Failed to get bundle by ID")
+		apperr.Respond(c, err, "Failed to retrieve bundle")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    bundle,
+		"message": "Bundle retrieved successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// Delete handles the DELETE request to delete a bundle together with its documents.
+func (b *BundleHandler) Delete(c *gin.Context) {
+	if err := b.service.DeleteBundle(c, c.Param("id")); err != nil {
+		log.Error().Err(err).Msg("Failed to delete bundle")
+		apperr.Respond(c, err, "Failed to delete bundle")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Bundle deleted successfully",
+	})
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/redact"
+	"github.com/thoughtgears/shared-services/internal/router/middleware"
+	"github.com/thoughtgears/shared-services/internal/services"
+	"github.com/thoughtgears/shared-services/pkg/apperr"
+)
+
+// CommentHandler serves the comments on documents, for document owners and for reviewers.
+type CommentHandler struct {
+	service services.CommentService
+}
+
+// NewCommentHandler creates a new instance of CommentHandler.
+func NewCommentHandler(service services.CommentService) *CommentHandler {
+	return &CommentHandler{
+		service: service,
+	}
+}
+
+// RegisterRoutes registers the comment routes. Owners use the document routes, reviewers the
+// admin routes, which give access to the comments on every document and mark their comments
+// as written by a reviewer.
+func (h *CommentHandler) RegisterRoutes(router *gin.Engine) {
+	comments := router.Group("/v1/documents/:id/comments")
+	comments.Use(middleware.FirebaseAuth(), middleware.TenantScope(), middleware.UserRateLimit())
+	h.registerCommentRoutes(comments)
+
+	reviewerComments := router.Group("/admin/v1/documents/:id/comments")
+	reviewerComments.Use(middleware.FirebaseAuth(), middleware.TenantScope(), middleware.RequireAdmin(), asReviewer)
+	h.registerCommentRoutes(reviewerComments)
+}
+
+func (h *CommentHandler) registerCommentRoutes(group *gin.RouterGroup) {
+	group.GET("", h.List)
+	group.POST("", h.Create)
+	group.PUT("/:comment_id", h.Update)
+	group.DELETE("/:comment_id", h.Delete)
+}
+
+// asReviewer marks the request as coming from a reviewer. It must run after RequireAdmin.
+func asReviewer(c *gin.Context) {
+	c.Request = c.Request.WithContext(services.WithReviewer(c.Request.Context()))
+	c.Next()
+}
+
+// List handles the GET request for the comments on a document, oldest first.
+func (h *CommentHandler) List(c *gin.Context) {
+	comments, err := h.service.List(c, c.Param("id"))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list comments")
+		apperr.Respond(c, err, "Failed to retrieve comments")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    comments,
+		"message": "Comments retrieved successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// Create handles the POST request to comment on a document.
+func (h *CommentHandler) Create(c *gin.Context) {
+	var request services.CommentInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid request payload",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	comment, err := h.service.Create(c, c.Param("id"), request)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create comment")
+		if respondWithValidationError(c, err, "Invalid comment") {
+			return
+		}
+
+		apperr.Respond(c, err, "Failed to create comment")
+
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data":    comment,
+		"message": "Comment created successfully",
+		"status":  http.StatusCreated,
+	})
+}
+
+// Update handles the PUT request to replace a comment written by the caller.
+func (h *CommentHandler) Update(c *gin.Context) {
+	var request services.CommentInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid request payload",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	comment, err := h.service.Update(c, c.Param("id"), c.Param("comment_id"), request)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to update comment")
+		if respondWithValidationError(c, err, "Invalid comment") {
+			return
+		}
+
+		apperr.Respond(c, err, "Failed to update comment")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    comment,
+		"message": "Comment updated successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// Delete handles the DELETE request to remove a comment written by the caller.
+func (h *CommentHandler) Delete(c *gin.Context) {
+	if err := h.service.Delete(c, c.Param("id"), c.Param("comment_id")); err != nil {
+		log.Error().Err(err).Msg("Failed to delete comment")
+		apperr.Respond(c, err, "Failed to delete comment")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Comment deleted successfully",
+		"status":  http.StatusOK,
+	})
+}
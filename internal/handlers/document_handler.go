@@ -2,15 +2,20 @@ package handlers
 
 import (
 	"errors"
-	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog/log"
 
+	"github.com/thoughtgears/shared-services/internal/bufpool"
 	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/redact"
 	"github.com/thoughtgears/shared-services/internal/router/middleware"
 	"github.com/thoughtgears/shared-services/internal/services"
+	"github.com/thoughtgears/shared-services/pkg/apperr"
 )
 
 // DocumentHandler is a struct that contains services for handling document-related operations.
@@ -34,14 +39,26 @@ func NewDocumentHandler(service services.DocumentService) *DocumentHandler {
 func (d *DocumentHandler) RegisterRoutes(router *gin.Engine) {
 	// Talent routes
 	documents := router.Group("/v1/documents")
-	documents.Use(middleware.FirebaseAuth())
+	documents.Use(middleware.APIAuth(models.ScopeDocumentsRead, models.ScopeDocumentsWrite), middleware.TenantScope(), middleware.UserRateLimit())
 	{
 		documents.GET("", d.GetAllByUserID) // Get all documents by user ID
 		documents.GET("/:id", d.GetByID)    // Get document by ID
-		documents.POST("", d.Create)
-		documents.PUT("/:id", d.Update)
+		documents.GET("/:id/download-url", d.GetDownloadURL)
+		documents.GET("/:id/content", d.GetContent)
+		documents.POST("", middleware.UploadLimit(), d.Create)
+		documents.POST("/merge", d.Merge)
+		documents.PUT("/:id", middleware.UploadLimit(), d.Update)
+		documents.PATCH("/:id", d.Patch)
+		documents.PUT("/:id/display-name", d.Rename)
+		documents.POST("/:id/redact", d.Redact)
 		documents.DELETE("/:id", d.Delete)
 	}
+
+	documentTypes := router.Group("/v1/document-types")
+	documentTypes.Use(middleware.APIAuth(models.ScopeDocumentsRead, models.ScopeDocumentsWrite), middleware.TenantScope(), middleware.UserRateLimit())
+	{
+		documentTypes.GET("", d.ListTypes)
+	}
 }
 
 // GetByID handles the GET request to retrieve a document by its unique ID.
@@ -53,11 +70,7 @@ func (d *DocumentHandler) GetByID(c *gin.Context) {
 	document, err := d.service.GetByID(c, id)
 	if err != nil {
 		log.Info().Err(err).Msg("Failed to get document by ID")
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   err.Error(),
-			"message": "Failed to retrieve document",
-			"status":  http.StatusInternalServerError,
-		})
+		apperr.Respond(c, err, "Failed to retrieve document")
 
 		return
 	}
@@ -68,28 +81,113 @@ func (d *DocumentHandler) GetByID(c *gin.Context) {
 	})
 }
 
+// GetDownloadURL handles the GET request for a signed URL to download a document.
+// The URL points directly at storage and expires after a short time.
+func (d *DocumentHandler) GetDownloadURL(c *gin.Context) {
+	id := c.Param("id")
+
+	url, expiresAt, err := d.service.GetDownloadURL(c, id)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create download URL")
+		apperr.Respond(c, err, "Failed to create download URL")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"url":        url,
+			"expires_at": expiresAt,
+		},
+		"message": "Download URL created successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// GetContent handles the GET request for a document image converted to a web friendly format.
+// The format query parameter selects jpg (the default) or png, and max_width scales the image down.
+func (d *DocumentHandler) GetContent(c *gin.Context) {
+	id := c.Param("id")
+
+	opts := services.ContentOptions{Format: services.ContentFormat(c.Query("format"))}
+	if maxWidth := c.Query("max_width"); maxWidth != "" {
+		width, err := strconv.Atoi(maxWidth)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   redact.Error(err),
+				"message": "Invalid max_width, expected a number of pixels",
+				"status":  http.StatusBadRequest,
+			})
+
+			return
+		}
+		opts.MaxWidth = width
+	}
+
+	content, err := d.service.GetContent(c, id, opts)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get document content")
+		if respondWithValidationError(c, err, "Invalid content request") {
+			return
+		}
+		apperr.Respond(c, err, "Failed to retrieve document content")
+
+		return
+	}
+	defer content.Close()
+
+	c.DataFromReader(http.StatusOK, content.Size, content.ContentType, content, map[string]string{
+		"Cache-Control": "private, max-age=300",
+	})
+}
+
 // GetAllByUserID handles the GET request to retrieve all documents associated with a specific user ID.
+// When the organization_id query parameter is set the organization's documents are returned instead.
 // It returns a slice of document objects and an error if any occurs.
 // This method is used to fetch all documents for a user.
 func (d *DocumentHandler) GetAllByUserID(c *gin.Context) {
 	userID := c.Query("user_id")
+	organizationID := c.Query("organization_id")
+
+	// Metadata filters are passed as metadata.<key>=<value> query parameters
+	metadata := make(map[string]string)
+	for key, values := range c.Request.URL.Query() {
+		if name, ok := strings.CutPrefix(key, "metadata."); ok && len(values) > 0 {
+			metadata[name] = values[0]
+		}
+	}
 
-	documents, err := d.service.GetAllByUserID(c, userID)
+	opts, err := parseListOptions(c)
 	if err != nil {
-		log.Info().Err(err).Msg("Failed to get documents by user ID")
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   err.Error(),
-			"message": "Failed to retrieve documents",
-			"status":  http.StatusInternalServerError,
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid pagination parameters",
+			"status":  http.StatusBadRequest,
 		})
 
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{
-		"data":    documents,
-		"message": "Documents retrieved successfully",
-		"status":  http.StatusOK,
-	})
+
+	var documents *models.Page[*models.Document]
+	if organizationID != "" {
+		documents, err = d.service.GetAllByOrganizationID(c, organizationID, metadata, opts)
+	} else {
+		documents, err = d.service.GetAllByUserID(c, userID, metadata, opts)
+	}
+	if err != nil {
+		log.Info().Err(err).Msg("Failed to get documents by user ID")
+		if respondWithValidationError(c, err, "Invalid document filter") {
+			return
+		}
+		if respondWithMissingIndex(c, err) {
+			return
+		}
+
+		apperr.Respond(c, err, "Failed to retrieve documents")
+
+		return
+	}
+	respondWithPage(c, documents, "Documents retrieved successfully")
 }
 
 // Create handles the POST request to create a new document.
@@ -108,24 +206,29 @@ func (d *DocumentHandler) Create(c *gin.Context) {
 		return
 	}
 
-	documentTypeStr := c.PostForm("document_type")
-	documentType, err := models.ParseDocumentType(documentTypeStr)
-	if err != nil {
-		log.Error().Err(err).Msg("Invalid document type")
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   err.Error(),
-			"message": "Invalid document type",
-			"status":  http.StatusBadRequest,
-		})
-
-		return
+	documentType := models.DocumentType(c.PostForm("document_type"))
+
+	var expiryDate *time.Time
+	if expiryDateStr := c.PostForm("expiry_date"); expiryDateStr != "" {
+		parsed, err := time.Parse(time.DateOnly, expiryDateStr)
+		if err != nil {
+			log.Error().Err(err).Msg("Invalid expiry date")
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   redact.Error(err),
+				"message": "Invalid expiry date, expected YYYY-MM-DD",
+				"status":  http.StatusBadRequest,
+			})
+
+			return
+		}
+		expiryDate = &parsed
 	}
 
 	file, err := c.FormFile("file")
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get file from form")
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   err.Error(),
+			"error":   redact.Error(err),
 			"message": "No file was uploaded or invalid file",
 			"status":  http.StatusBadRequest,
 		})
@@ -136,36 +239,36 @@ func (d *DocumentHandler) Create(c *gin.Context) {
 	openedFile, err := file.Open()
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to open uploaded file")
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   err.Error(),
-			"message": "Failed to read uploaded file",
-			"status":  http.StatusInternalServerError,
-		})
+		apperr.Respond(c, err, "Failed to read uploaded file")
 
 		return
 	}
 	defer openedFile.Close()
 
-	content, err := io.ReadAll(openedFile)
+	content, err := bufpool.ReadAll(openedFile, file.Size)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to read file content")
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   err.Error(),
-			"message": "Failed to read file content",
-			"status":  http.StatusInternalServerError,
-		})
+		apperr.Respond(c, err, "Failed to read file content")
 
 		return
 	}
 
-	newDocument, err := d.service.Create(c, userID, documentType, content)
+	newDocument, err := d.service.Create(c, services.CreateDocumentInput{
+		UserID:           userID,
+		OrganizationID:   c.PostForm("organization_id"),
+		Type:             documentType,
+		ExpiryDate:       expiryDate,
+		Metadata:         c.PostFormMap("metadata"),
+		Content:          content,
+		OriginalFilename: file.Filename,
+	})
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to create document")
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   err.Error(),
-			"message": "Failed to create document",
-			"status":  http.StatusInternalServerError,
-		})
+		if respondWithValidationError(c, err, "Invalid document") {
+			return
+		}
+
+		apperr.Respond(c, err, "Failed to create document")
 
 		return
 	}
@@ -187,7 +290,7 @@ func (d *DocumentHandler) Update(c *gin.Context) {
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get file from form")
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   err.Error(),
+			"error":   redact.Error(err),
 			"message": "No file was uploaded or invalid file",
 			"status":  http.StatusBadRequest,
 		})
@@ -198,43 +301,182 @@ func (d *DocumentHandler) Update(c *gin.Context) {
 	openedFile, err := file.Open()
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to open uploaded file")
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   err.Error(),
-			"message": "Failed to read uploaded file",
-			"status":  http.StatusInternalServerError,
-		})
+		apperr.Respond(c, err, "Failed to read uploaded file")
 
 		return
 	}
 	defer openedFile.Close()
 
-	content, err := io.ReadAll(openedFile)
+	content, err := bufpool.ReadAll(openedFile, file.Size)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to read file content")
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   err.Error(),
-			"message": "Failed to read file content",
-			"status":  http.StatusInternalServerError,
-		})
+		apperr.Respond(c, err, "Failed to read file content")
 
 		return
 	}
 
-	document, err := d.service.Update(c, id, content)
+	document, err := d.service.Update(c, id, content, file.Filename)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to update document")
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   err.Error(),
-			"message": "Failed to update document",
-			"status":  http.StatusInternalServerError,
+		if respondWithValidationError(c, err, "Invalid document") {
+			return
+		}
+
+		apperr.Respond(c, err, "Failed to update document")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    document,
+		"message": "Document updated successfully",
+	})
+}
+
+// patchDocumentRequest is the JSON body accepted by Patch.
+type patchDocumentRequest struct {
+	Metadata map[string]string `json:"metadata"`
+}
+
+// Patch handles the PATCH request to update the metadata of an existing document.
+// Metadata entries are merged into the existing metadata, an empty value removes the entry.
+// It returns the updated document object and an error if any occurs.
+func (d *DocumentHandler) Patch(c *gin.Context) {
+	id := c.Param("id")
+
+	var request patchDocumentRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid request payload",
+			"status":  http.StatusBadRequest,
 		})
 
 		return
 	}
 
+	document, err := d.service.UpdateMetadata(c, id, request.Metadata)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to update document metadata")
+		if respondWithValidationError(c, err, "Invalid document") {
+			return
+		}
+
+		apperr.Respond(c, err, "Failed to update document")
+
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"data":    document,
 		"message": "Document updated successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// renameDocumentRequest is the payload for setting a document's display name.
+type renameDocumentRequest struct {
+	DisplayName string `json:"display_name"`
+}
+
+// Rename handles the PUT request to set the display name of a document, an empty name removes it.
+func (d *DocumentHandler) Rename(c *gin.Context) {
+	id := c.Param("id")
+
+	var request renameDocumentRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid request payload",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	document, err := d.service.Rename(c, id, request.DisplayName)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to rename document")
+		if respondWithValidationError(c, err, "Invalid display name") {
+			return
+		}
+
+		apperr.Respond(c, err, "Failed to rename document")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    document,
+		"message": "Document renamed successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// Redact handles the POST request to black out regions of a document image.
+// The redacted image replaces the content, the original is preserved as a version of the document.
+func (d *DocumentHandler) Redact(c *gin.Context) {
+	id := c.Param("id")
+
+	var request services.RedactDocumentInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid request payload",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	document, err := d.service.Redact(c, id, request)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to redact document")
+		if respondWithValidationError(c, err, "Invalid redaction") {
+			return
+		}
+
+		apperr.Respond(c, err, "Failed to redact document")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    document,
+		"message": "Document redacted successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// Merge handles the POST request to combine the images and PDFs of several documents into a new PDF document.
+func (d *DocumentHandler) Merge(c *gin.Context) {
+	var request services.MergeDocumentsInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid request payload",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	document, err := d.service.Merge(c, request)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to merge documents")
+		if respondWithValidationError(c, err, "Invalid merge") {
+			return
+		}
+
+		apperr.Respond(c, err, "Failed to merge documents")
+
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"data":    document,
+		"message": "Documents merged successfully",
+		"status":  http.StatusAccepted,
 	})
 }
 
@@ -247,11 +489,10 @@ func (d *DocumentHandler) Delete(c *gin.Context) {
 	err := d.service.Delete(c, id)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to delete document")
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   err.Error(),
-			"message": "Failed to delete document",
-			"status":  http.StatusInternalServerError,
-		})
+		if respondWithValidationError(c, err, "Invalid document") {
+			return
+		}
+		apperr.Respond(c, err, "Failed to delete document")
 
 		return
 	}
@@ -260,3 +501,17 @@ func (d *DocumentHandler) Delete(c *gin.Context) {
 		"message": "Document deleted successfully",
 	})
 }
+
+// ListTypes handles the GET request to list the document types that can be uploaded.
+// It returns the type definitions including their allowed MIME types and retention settings.
+func (d *DocumentHandler) ListTypes(c *gin.Context) {
+	types, err := d.service.ListTypes(c)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list document types")
+		apperr.Respond(c, err, "Failed to retrieve document types")
+
+		return
+	}
+
+	respondWithPage(c, types, "Document types retrieved successfully")
+}
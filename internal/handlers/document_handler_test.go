@@ -0,0 +1,51 @@
+package handlers_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/thoughtgears/shared-services/internal/handlers"
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/services"
+	"github.com/thoughtgears/shared-services/pkg/httptestutil"
+)
+
+// stubDocuments is a DocumentService renaming the documents it holds, held ones are refused.
+type stubDocuments struct {
+	services.DocumentService
+	documents map[string]*models.Document
+}
+
+func (s *stubDocuments) Rename(_ context.Context, id string, displayName string) (*models.Document, error) {
+	document := s.documents[id]
+	if document.LegalHold != nil {
+		return nil, fmt.Errorf("%w: document %s", services.ErrLegalHold, id)
+	}
+	document.DisplayName = displayName
+
+	return document, nil
+}
+
+func TestRenameDocument(t *testing.T) {
+	verifier := httptestutil.NewVerifier()
+	documents := &stubDocuments{documents: map[string]*models.Document{
+		"doc-1": {ID: "doc-1", UserID: "user-1"},
+		"doc-2": {ID: "doc-2", UserID: "user-1", LegalHold: &models.LegalHold{Reason: "case 42"}},
+	}}
+	engine := httptestutil.NewRouter(verifier, handlers.NewDocumentHandler(documents))
+	token := verifier.Issue(httptestutil.User("user-1"))
+
+	rec := httptestutil.Do(engine, http.MethodPut, "/v1/documents/doc-1/display-name", token, map[string]string{"display_name": "Passport"})
+	httptestutil.AssertStatus(t, rec, http.StatusOK)
+	if document := httptestutil.DecodeData[models.Document](t, rec); document.DisplayName != "Passport" {
+		t.Errorf("display_name = %q, want %q", document.DisplayName, "Passport")
+	}
+
+	rec = httptestutil.Do(engine, http.MethodPut, "/v1/documents/doc-2/display-name", token, map[string]string{"display_name": "Passport"})
+	httptestutil.AssertError(t, rec, http.StatusConflict, "document is under legal hold: document doc-2")
+
+	rec = httptestutil.Do(engine, http.MethodPut, "/v1/documents/doc-1/display-name", "", map[string]string{"display_name": "Passport"})
+	httptestutil.AssertStatus(t, rec, http.StatusUnauthorized)
+}
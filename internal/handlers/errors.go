@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/thoughtgears/shared-services/internal/db"
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/redact"
+)
+
+// respondWithValidationError writes a 400 response listing the structured field errors
+// if err contains a *models.ValidationError. It returns true when a response was written,
+// so handlers can fall through to their generic error handling otherwise.
+func respondWithValidationError(c *gin.Context, err error, message string) bool {
+	var verr *models.ValidationError
+	if !errors.As(err, &verr) {
+		return false
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":   redact.Error(verr),
+		"fields":  verr.Fields,
+		"message": message,
+		"status":  http.StatusBadRequest,
+	})
+
+	return true
+}
+
+// respondWithMissingIndex writes a 501 response if err is caused by a missing Firestore index:
+// the filter combination is valid but the deployment cannot serve it until the index is created.
+// It returns true when a response was written.
+func respondWithMissingIndex(c *gin.Context, err error) bool {
+	if !db.IsMissingIndex(err) {
+		return false
+	}
+
+	c.JSON(http.StatusNotImplemented, gin.H{
+		"error":   "missing_index",
+		"message": "This combination of filters is not supported yet, the database index it needs has not been created",
+		"status":  http.StatusNotImplemented,
+	})
+
+	return true
+}
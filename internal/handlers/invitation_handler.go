@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/caller"
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/redact"
+	"github.com/thoughtgears/shared-services/internal/router/middleware"
+	"github.com/thoughtgears/shared-services/internal/services"
+	"github.com/thoughtgears/shared-services/pkg/apperr"
+)
+
+// InvitationHandler serves the organization invitation flow.
+type InvitationHandler struct {
+	service services.InvitationService
+}
+
+// NewInvitationHandler creates a new instance of InvitationHandler.
+// No routes are registered when service is nil, i.e. invitations are disabled.
+func NewInvitationHandler(service services.InvitationService) *InvitationHandler {
+	return &InvitationHandler{
+		service: service,
+	}
+}
+
+// RegisterRoutes registers the routes for creating, revoking and accepting invitations.
+func (i *InvitationHandler) RegisterRoutes(router *gin.Engine) {
+	if i.service == nil {
+		return
+	}
+
+	organizations := router.Group("/v1/organizations")
+	organizations.Use(middleware.FirebaseAuth(), middleware.TenantScope(), middleware.UserRateLimit())
+	{
+		organizations.POST("/:id/invitations", i.Create)
+		organizations.DELETE("/:id/invitations/:invitation_id", i.Revoke)
+	}
+
+	invitations := router.Group("/v1/invitations")
+	invitations.Use(middleware.FirebaseAuth(), middleware.TenantScope(), middleware.UserRateLimit())
+	{
+		invitations.POST("/accept", i.Accept)
+	}
+}
+
+// createInvitationRequest is the payload for inviting someone to an organization.
+type createInvitationRequest struct {
+	Email string      `json:"email" binding:"required"`
+	Role  models.Role `json:"role" binding:"required"`
+}
+
+// Create handles the POST request to invite someone to an organization by email.
+func (i *InvitationHandler) Create(c *gin.Context) {
+	var request createInvitationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid request payload",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	invitation, err := i.service.Create(c, c.Param("id"), request.Email, request.Role)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create invitation")
+		if respondWithValidationError(c, err, "Invalid invitation") {
+			return
+		}
+
+		apperr.Respond(c, err, "Failed to create invitation")
+
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data":    invitation,
+		"message": "Invitation sent successfully",
+		"status":  http.StatusCreated,
+	})
+}
+
+// Revoke handles the DELETE request to cancel a pending invitation.
+func (i *InvitationHandler) Revoke(c *gin.Context) {
+	if err := i.service.Revoke(c, c.Param("id"), c.Param("invitation_id")); err != nil {
+		log.Error().Err(err).Msg("Failed to revoke invitation")
+		apperr.Respond(c, err, "Failed to revoke invitation")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Invitation revoked successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// acceptInvitationRequest is the payload for redeeming an invitation.
+type acceptInvitationRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// Accept handles the POST request to redeem an invitation token for the authenticated user.
+// The user's email must be verified and match the address the invitation was sent to.
+func (i *InvitationHandler) Accept(c *gin.Context) {
+	var request acceptInvitationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid request payload",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	email, ok := caller.VerifiedEmailFrom(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "forbidden",
+			"message": "A verified email address is required to accept invitations",
+			"status":  http.StatusForbidden,
+		})
+
+		return
+	}
+
+	membership, err := i.service.Accept(c, request.Token, email)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to accept invitation")
+		if errors.Is(err, services.ErrInvitationInvalid) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid invitation",
+				"message": "The invitation is invalid, expired or was sent to another email address",
+				"status":  http.StatusBadRequest,
+			})
+
+			return
+		}
+		apperr.Respond(c, err, "Failed to accept invitation")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    membership,
+		"message": "Invitation accepted successfully",
+		"status":  http.StatusOK,
+	})
+}
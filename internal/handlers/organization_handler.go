@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/redact"
+	"github.com/thoughtgears/shared-services/internal/router/middleware"
+	"github.com/thoughtgears/shared-services/internal/services"
+	"github.com/thoughtgears/shared-services/pkg/apperr"
+)
+
+// OrganizationHandler is a struct that contains services for handling organization and membership operations.
+type OrganizationHandler struct {
+	service services.OrganizationService
+}
+
+// NewOrganizationHandler creates a new instance of OrganizationHandler.
+// It initializes the handler with the provided organization service.
+func NewOrganizationHandler(service services.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{
+		service: service,
+	}
+}
+
+// RegisterRoutes registers the routes for organizations and their members.
+func (o *OrganizationHandler) RegisterRoutes(router *gin.Engine) {
+	organizations := router.Group("/v1/organizations")
+	organizations.Use(middleware.FirebaseAuth(), middleware.TenantScope(), middleware.UserRateLimit())
+	{
+		organizations.GET("", o.List)
+		organizations.POST("", o.Create)
+		organizations.GET("/:id", o.GetByID)
+		organizations.PUT("/:id", o.Update)
+		organizations.DELETE("/:id", o.Delete)
+		organizations.GET("/:id/members", o.ListMembers)
+		organizations.PUT("/:id/members/:user_id", o.SetMember)
+		organizations.DELETE("/:id/members/:user_id", o.RemoveMember)
+	}
+}
+
+// respondWithError writes the response for an error returned by the organization service.
+func (o *OrganizationHandler) respondWithError(c *gin.Context, err error, message string) {
+	log.Error().Err(err).Msg(message)
+	if respondWithValidationError(c, err, message) {
+		return
+	}
+	if respondWithMissingIndex(c, err) {
+		return
+	}
+
+	apperr.Respond(c, err, message)
+}
+
+// List handles the GET request for the caller's memberships, one per organization they belong to.
+func (o *OrganizationHandler) List(c *gin.Context) {
+	opts, err := parseListOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid pagination parameters",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	memberships, err := o.service.ListForCaller(c, opts)
+	if err != nil {
+		o.respondWithError(c, err, "Failed to retrieve organizations")
+
+		return
+	}
+
+	respondWithPage(c, memberships, "Organizations retrieved successfully")
+}
+
+// GetByID handles the GET request to retrieve an organization the caller is a member of.
+func (o *OrganizationHandler) GetByID(c *gin.Context) {
+	organization, err := o.service.GetByID(c, c.Param("id"))
+	if err != nil {
+		o.respondWithError(c, err, "Failed to retrieve organization")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    organization,
+		"message": "Organization retrieved successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// Create handles the POST request to create an organization owned by the caller.
+func (o *OrganizationHandler) Create(c *gin.Context) {
+	var organization models.Organization
+	if err := c.ShouldBindJSON(&organization); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid request payload",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	created, err := o.service.Create(c, &organization)
+	if err != nil {
+		o.respondWithError(c, err, "Failed to create organization")
+
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data":    created,
+		"message": "Organization created successfully",
+		"status":  http.StatusCreated,
+	})
+}
+
+// Update handles the PUT request to rename an organization.
+func (o *OrganizationHandler) Update(c *gin.Context) {
+	var organization models.Organization
+	if err := c.ShouldBindJSON(&organization); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid request payload",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	updated, err := o.service.Update(c, c.Param("id"), &organization)
+	if err != nil {
+		o.respondWithError(c, err, "Failed to update organization")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    updated,
+		"message": "Organization updated successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// Delete handles the DELETE request to remove an organization and its memberships.
+func (o *OrganizationHandler) Delete(c *gin.Context) {
+	if err := o.service.Delete(c, c.Param("id")); err != nil {
+		o.respondWithError(c, err, "Failed to delete organization")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Organization deleted successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// ListMembers handles the GET request for a page of an organization's members.
+func (o *OrganizationHandler) ListMembers(c *gin.Context) {
+	opts, err := parseListOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid pagination parameters",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	members, err := o.service.ListMembers(c, c.Param("id"), opts)
+	if err != nil {
+		o.respondWithError(c, err, "Failed to retrieve members")
+
+		return
+	}
+
+	respondWithPage(c, members, "Members retrieved successfully")
+}
+
+// setMemberRequest is the payload for adding a member or changing their role.
+type setMemberRequest struct {
+	Role models.Role `json:"role" binding:"required"`
+}
+
+// SetMember handles the PUT request to add a member to an organization or change their role.
+func (o *OrganizationHandler) SetMember(c *gin.Context) {
+	var request setMemberRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid request payload",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	membership, err := o.service.SetMember(c, c.Param("id"), c.Param("user_id"), request.Role)
+	if err != nil {
+		o.respondWithError(c, err, "Failed to set member")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    membership,
+		"message": "Member saved successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// RemoveMember handles the DELETE request to remove a member from an organization.
+func (o *OrganizationHandler) RemoveMember(c *gin.Context) {
+	if err := o.service.RemoveMember(c, c.Param("id"), c.Param("user_id")); err != nil {
+		o.respondWithError(c, err, "Failed to remove member")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Member removed successfully",
+		"status":  http.StatusOK,
+	})
+}
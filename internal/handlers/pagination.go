@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/services"
+)
+
+// Pagination response headers, so generic HTTP clients and gateways can page through list
+// endpoints without parsing the envelope.
+const (
+	nextPageTokenHeader = "X-Next-Page-Token"
+	pageSizeHeader      = "X-Page-Size"
+	totalCountHeader    = "X-Total-Count"
+)
+
+// parseListOptions reads the standard pagination query parameters shared by all list endpoints:
+// page_token, page_size and include_total.
+func parseListOptions(c *gin.Context) (services.ListOptions, error) {
+	opts := services.ListOptions{
+		PageToken: c.Query("page_token"),
+	}
+
+	if pageSize := c.Query("page_size"); pageSize != "" {
+		size, err := strconv.Atoi(pageSize)
+		if err != nil || size < 1 || size > services.MaxPageSize {
+			return opts, fmt.Errorf("page_size must be a number between 1 and %d", services.MaxPageSize)
+		}
+		opts.PageSize = size
+	}
+
+	if includeTotal := c.Query("include_total"); includeTotal != "" {
+		include, err := strconv.ParseBool(includeTotal)
+		if err != nil {
+			return opts, fmt.Errorf("include_total must be true or false: %w", err)
+		}
+		opts.IncludeTotal = include
+	}
+
+	return opts, nil
+}
+
+// respondWithPage writes a page of a list endpoint in the standard envelope. The pagination fields
+// are also sent as headers, with an RFC 5988 Link header pointing at the first page and, unless this
+// is the last page, the next page.
+func respondWithPage[T any](c *gin.Context, page *models.Page[T], message string) {
+	c.Header(pageSizeHeader, strconv.Itoa(page.PageSize))
+	if page.TotalCount != nil {
+		c.Header(totalCountHeader, strconv.FormatInt(*page.TotalCount, 10))
+	}
+
+	links := []string{pageLink(c, "", "first")}
+	if page.NextPageToken != "" {
+		c.Header(nextPageTokenHeader, page.NextPageToken)
+		links = append(links, pageLink(c, page.NextPageToken, "next"))
+	}
+	for _, link := range links {
+		c.Writer.Header().Add("Link", link)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    page,
+		"message": message,
+		"status":  http.StatusOK,
+	})
+}
+
+// pageLink returns a Link header value for the page of the current request starting at pageToken,
+// keeping its other query parameters. The target is relative to the request URL, so it stays
+// correct behind the gateway.
+func pageLink(c *gin.Context, pageToken string, rel string) string {
+	query := c.Request.URL.Query()
+	query.Del("page_token")
+	if pageToken != "" {
+		query.Set("page_token", pageToken)
+	}
+
+	target := c.Request.URL.Path
+	if encoded := query.Encode(); encoded != "" {
+		target += "?" + encoded
+	}
+
+	return fmt.Sprintf(`<%s>; rel="%s"`, target, rel)
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/caller"
+	"github.com/thoughtgears/shared-services/internal/router/middleware"
+	"github.com/thoughtgears/shared-services/internal/services"
+	"github.com/thoughtgears/shared-services/pkg/apperr"
+)
+
+// ProfileHandler serves the aggregated profile of the authenticated user.
+type ProfileHandler struct {
+	service services.ProfileService
+}
+
+// NewProfileHandler creates a new instance of ProfileHandler.
+func NewProfileHandler(service services.ProfileService) *ProfileHandler {
+	return &ProfileHandler{
+		service: service,
+	}
+}
+
+// RegisterRoutes registers the profile route.
+func (p *ProfileHandler) RegisterRoutes(router *gin.Engine) {
+	profile := router.Group("/v1/profile")
+	profile.Use(middleware.FirebaseAuth(), middleware.TenantScope(), middleware.UserRateLimit())
+	{
+		profile.GET("", p.Get)
+	}
+}
+
+// Get handles the GET request for the caller's user, document summaries, quota usage and MFA status.
+func (p *ProfileHandler) Get(c *gin.Context) {
+	profile, err := p.service.Get(c)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get profile")
+		apperr.Respond(c, err, "Failed to retrieve profile")
+
+		return
+	}
+
+	profile.MFA.Verified = caller.SignedInWithMFA(c)
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    profile,
+		"message": "Profile retrieved successfully",
+		"status":  http.StatusOK,
+	})
+}
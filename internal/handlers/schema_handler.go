@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/schema"
+)
+
+// SchemaHandler serves JSON Schema definitions of the API models.
+// The schemas are derived from the Go structs at startup, so they always match what the API returns.
+type SchemaHandler struct {
+	schemas map[string]*schema.Schema
+}
+
+// NewSchemaHandler creates a new instance of SchemaHandler and generates the published schemas.
+func NewSchemaHandler() *SchemaHandler {
+	definitions := map[string]struct {
+		value any
+		title string
+	}{
+		"user":              {models.User{}, "User"},
+		"document":          {models.Document{}, "Document"},
+		"document-type":     {models.DocumentTypeDefinition{}, "Document type"},
+		"envelope":          {models.Envelope[any]{}, "Response envelope"},
+		"error":             {models.ErrorEnvelope{}, "Error response envelope"},
+		"user-response":     {models.Envelope[models.User]{}, "User response"},
+		"document-response": {models.Envelope[models.Document]{}, "Document response"},
+		"document-page":     {models.Envelope[models.Page[models.Document]]{}, "Document list response"},
+		"page":              {models.Page[any]{}, "Page of list results"},
+		"organization":      {models.Organization{}, "Organization"},
+		"membership":        {models.Membership{}, "Organization membership"},
+		"invitation":        {models.Invitation{}, "Organization invitation"},
+		"tenant-settings":   {models.TenantSettings{}, "Tenant settings"},
+	}
+
+	schemas := make(map[string]*schema.Schema, len(definitions))
+	for name, def := range definitions {
+		schemas[name] = schema.Generate(def.value, "/schemas/"+name, def.title)
+	}
+
+	return &SchemaHandler{schemas: schemas}
+}
+
+// RegisterRoutes registers the public schema routes.
+// Schemas are not authenticated so client code generators can fetch them directly.
+func (s *SchemaHandler) RegisterRoutes(router *gin.Engine) {
+	schemas := router.Group("/schemas")
+	{
+		schemas.GET("", s.List)
+		schemas.GET("/:name", s.Get)
+	}
+}
+
+// List handles the GET request listing the names of all published schemas.
+func (s *SchemaHandler) List(c *gin.Context) {
+	names := make([]string, 0, len(s.schemas))
+	for name := range s.schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    names,
+		"message": "Schemas retrieved successfully",
+		"status":  http.StatusOK,
+	})
+}
+
+// Get handles the GET request for a single schema.
+// The schema document is returned as-is, without the response envelope, so tools can consume it directly.
+func (s *SchemaHandler) Get(c *gin.Context) {
+	name := c.Param("name")
+
+	sch, ok := s.schemas[name]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "schema not found",
+			"message": "Unknown schema: " + name,
+			"status":  http.StatusNotFound,
+		})
+
+		return
+	}
+
+	c.Header("Content-Type", "application/schema+json")
+	c.JSON(http.StatusOK, sch)
+}
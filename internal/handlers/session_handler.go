@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/router/middleware"
+	"github.com/thoughtgears/shared-services/internal/services"
+	"github.com/thoughtgears/shared-services/pkg/apperr"
+)
+
+// SessionHandler serves the sign-in sessions of users.
+type SessionHandler struct {
+	service services.SessionService
+}
+
+// NewSessionHandler creates a new instance of SessionHandler.
+func NewSessionHandler(service services.SessionService) *SessionHandler {
+	return &SessionHandler{
+		service: service,
+	}
+}
+
+// RegisterRoutes registers the route for revoking the sessions of a user.
+func (s *SessionHandler) RegisterRoutes(router *gin.Engine) {
+	users := router.Group("/v1/users")
+	users.Use(middleware.FirebaseAuth(), middleware.TenantScope(), middleware.UserRateLimit())
+	{
+		users.POST("/:id/sessions/revoke", s.Revoke)
+	}
+}
+
+// Revoke handles the POST request to sign a user out on every device. The token of the request
+// is revoked as well, so the client has to sign in again.
+func (s *SessionHandler) Revoke(c *gin.Context) {
+	revocation, err := s.service.Revoke(c, c.Param("id"))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to revoke sessions")
+		apperr.Respond(c, err, "Failed to revoke sessions")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    revocation,
+		"message": "Sessions revoked successfully",
+		"status":  http.StatusOK,
+	})
+}
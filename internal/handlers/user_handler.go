@@ -6,23 +6,30 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/redact"
 	"github.com/thoughtgears/shared-services/internal/router/middleware"
 	"github.com/thoughtgears/shared-services/internal/services"
+	"github.com/thoughtgears/shared-services/pkg/apperr"
 )
 
 // UserHandler is a struct that contains services for handling user-related operations.
 // It provides a unified interface for handling user operations in the system.
 type UserHandler struct {
-	service services.UserService
+	service       services.UserService
+	notifications services.NotificationService
+	activity      services.ActivityService
 }
 
 // NewUserHandler creates a new instance of UserHandler.
-// It initializes the handler with the provided services.
+// It initializes the handler with the provided services, notifications serves the users' delivery logs
+// and activity their last-seen time and login history.
 // This function is used to set up the handler with the necessary services for user management.
 // It is typically called during the initialization phase of the application.
-func NewUserHandler(service services.UserService) *UserHandler {
+func NewUserHandler(service services.UserService, notifications services.NotificationService, activity services.ActivityService) *UserHandler {
 	return &UserHandler{
-		service: service,
+		service:       service,
+		notifications: notifications,
+		activity:      activity,
 	}
 }
 
@@ -31,11 +38,13 @@ func NewUserHandler(service services.UserService) *UserHandler {
 func (u *UserHandler) RegisterRoutes(router *gin.Engine) {
 	// Talent routes
 	users := router.Group("/v1/users")
-	users.Use(middleware.FirebaseAuth())
+	users.Use(middleware.FirebaseAuth(), middleware.TenantScope(), middleware.UserRateLimit())
 	{
 		users.GET("/:id", u.GetByID)
 		users.POST("", u.Create)
 		users.PUT("/:id", u.Update)
+		users.GET("/:id/notifications", u.ListNotifications)
+		users.GET("/:id/activity", u.GetActivity)
 	}
 }
 
@@ -47,11 +56,7 @@ func (u *UserHandler) GetByID(c *gin.Context) {
 
 	user, err := u.service.GetByID(c, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   err.Error(),
-			"message": "Failed to retrieve user",
-			"status":  http.StatusInternalServerError,
-		})
+		apperr.Respond(c, err, "Failed to retrieve user")
 
 		return
 	}
@@ -70,7 +75,7 @@ func (u *UserHandler) Create(c *gin.Context) {
 
 	if err := c.ShouldBindJSON(&user); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   err.Error(),
+			"error":   redact.Error(err),
 			"message": "Invalid request payload",
 			"status":  http.StatusBadRequest,
 		})
@@ -80,11 +85,11 @@ func (u *UserHandler) Create(c *gin.Context) {
 
 	newUser, err := u.service.Create(c, &user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   err.Error(),
-			"message": "Failed to create user",
-			"status":  http.StatusInternalServerError,
-		})
+		if respondWithValidationError(c, err, "Invalid user") {
+			return
+		}
+
+		apperr.Respond(c, err, "Failed to create user")
 
 		return
 	}
@@ -106,7 +111,7 @@ func (u *UserHandler) Update(c *gin.Context) {
 
 	if err := c.ShouldBindJSON(&user); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   err.Error(),
+			"error":   redact.Error(err),
 			"message": "Invalid request payload",
 			"status":  http.StatusBadRequest,
 		})
@@ -116,11 +121,11 @@ func (u *UserHandler) Update(c *gin.Context) {
 
 	updatedUser, err := u.service.Update(c, id, &user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   err.Error(),
-			"message": "Failed to update user",
-			"status":  http.StatusInternalServerError,
-		})
+		if respondWithValidationError(c, err, "Invalid user") {
+			return
+		}
+
+		apperr.Respond(c, err, "Failed to update user")
 
 		return
 	}
@@ -131,3 +136,43 @@ func (u *UserHandler) Update(c *gin.Context) {
 		"status":  http.StatusOK,
 	})
 }
+
+// ListNotifications handles the GET request to list the notifications delivered to a user, newest first.
+// Every channel of every notification is listed, including the ones skipped by the user's preferences.
+func (u *UserHandler) ListNotifications(c *gin.Context) {
+	opts, err := parseListOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   redact.Error(err),
+			"message": "Invalid pagination parameters",
+			"status":  http.StatusBadRequest,
+		})
+
+		return
+	}
+
+	deliveries, err := u.notifications.ListDeliveries(c, c.Param("id"), opts)
+	if err != nil {
+		apperr.Respond(c, err, "Failed to retrieve notifications")
+
+		return
+	}
+
+	respondWithPage(c, deliveries, "Notifications retrieved successfully")
+}
+
+// GetActivity handles the GET request to retrieve when a user was last seen and their recent sign-ins.
+func (u *UserHandler) GetActivity(c *gin.Context) {
+	activity, err := u.activity.Get(c, c.Param("id"))
+	if err != nil {
+		apperr.Respond(c, err, "Failed to retrieve user activity")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    activity,
+		"message": "User activity retrieved successfully",
+		"status":  http.StatusOK,
+	})
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/redact"
+	"github.com/thoughtgears/shared-services/internal/webhook"
+	"github.com/thoughtgears/shared-services/pkg/apperr"
+)
+
+// maxWebhookBodySize limits the body of webhook deliveries, which are read into memory to be verified.
+const maxWebhookBodySize = 1 << 20
+
+// WebhookHandler receives the webhooks of third parties for the sources registered on the receiver.
+type WebhookHandler struct {
+	receiver *webhook.Receiver
+}
+
+// NewWebhookHandler creates a new instance of WebhookHandler.
+func NewWebhookHandler(receiver *webhook.Receiver) *WebhookHandler {
+	return &WebhookHandler{
+		receiver: receiver,
+	}
+}
+
+// RegisterRoutes registers the webhook route. Deliveries are authenticated by the signature of
+// their provider instead of a user token.
+func (w *WebhookHandler) RegisterRoutes(router *gin.Engine) {
+	webhooks := router.Group("/v1/webhooks")
+	{
+		webhooks.POST("/:source", w.Receive)
+	}
+}
+
+// Receive handles the POST request of a webhook delivery. A delivery received before is
+// acknowledged without handling it again, so the provider stops retrying it. Failures of the
+// handler answer with an error status, so the provider retries.
+func (w *WebhookHandler) Receive(c *gin.Context) {
+	source := c.Param("source")
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookBodySize))
+	if err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":   redact.Error(err),
+			"message": "Webhook body too large",
+			"status":  http.StatusRequestEntityTooLarge,
+		})
+
+		return
+	}
+
+	err = w.receiver.Receive(c, source, c.Request.Header, body)
+	switch {
+	case err == nil:
+	case errors.Is(err, webhook.ErrReplayed):
+		log.Info().Str("source", source).Msg("Ignoring replayed webhook")
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Webhook already received",
+			"status":  http.StatusOK,
+		})
+
+		return
+	case errors.Is(err, webhook.ErrUnknownSource):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   redact.Error(err),
+			"message": "Unknown webhook source",
+			"status":  http.StatusNotFound,
+		})
+
+		return
+	case errors.Is(err, webhook.ErrInvalidSignature), errors.Is(err, webhook.ErrExpired):
+		log.Warn().Err(err).Str("source", source).Str("ip", c.ClientIP()).Msg("Rejected webhook")
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "unauthorized",
+			"message": "Invalid webhook signature",
+			"status":  http.StatusUnauthorized,
+		})
+
+		return
+	default:
+		log.Error().Err(err).Str("source", source).Msg("Failed to receive webhook")
+		apperr.Respond(c, err, "Failed to receive webhook")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Webhook received successfully",
+		"status":  http.StatusOK,
+	})
+}
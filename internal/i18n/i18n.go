@@ -0,0 +1,145 @@
+// Package i18n translates the user-facing message of error responses. Messages are looked up by
+// error code, the error field of the envelope, in the catalog of the language the client asked for
+// with Accept-Language. English is built in, other languages are added with Register or LoadDir.
+//
+// English responses keep the message of the handler, which is more specific than the catalog; the
+// English catalog lists the codes translators need to cover.
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/language"
+)
+
+// Catalog maps error codes to user-facing messages in one language.
+type Catalog map[string]string
+
+// English is the built-in catalog.
+var English = Catalog{
+	"internal":              "An unexpected error occurred",
+	"internal server error": "An unexpected error occurred",
+	"not_found":             "The resource was not found",
+	"invalid":               "The request is invalid",
+	"conflict":              "The resource was changed by another request, retry",
+	"unavailable":           "The service is temporarily unavailable, retry later",
+	"service unavailable":   "The service is temporarily unavailable, retry later",
+	"forbidden":             "You do not have access to this resource",
+	"unauthorized":          "Authentication required",
+	"insufficient_scope":    "The access token does not have the required scope",
+	"mfa_required":          "Multi-factor authentication is required for this action",
+	"too many requests":     "Too many requests, retry later",
+}
+
+var (
+	mu       sync.RWMutex
+	tags     = []language.Tag{language.English}
+	catalogs = map[language.Tag]Catalog{language.English: English}
+	matcher  = language.NewMatcher(tags)
+)
+
+// Register adds the messages of catalog to the language, replacing those it already has.
+func Register(tag language.Tag, catalog Catalog) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	existing, ok := catalogs[tag]
+	if !ok {
+		existing = make(Catalog, len(catalog))
+		catalogs[tag] = existing
+		tags = append(tags, tag)
+		matcher = language.NewMatcher(tags)
+	}
+	for code, message := range catalog {
+		existing[code] = message
+	}
+}
+
+// LoadDir registers every <language>.json file in dir, e.g. de.json or pt-BR.json, each a JSON
+// object of messages by error code.
+func LoadDir(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("failed to list message catalogs: %w", err)
+	}
+
+	for _, path := range paths {
+		tag, err := language.Parse(strings.TrimSuffix(filepath.Base(path), ".json"))
+		if err != nil {
+			return fmt.Errorf("failed to parse language of message catalog %s: %w", path, err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read message catalog %s: %w", path, err)
+		}
+
+		var catalog Catalog
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			return fmt.Errorf("failed to decode message catalog %s: %w", path, err)
+		}
+
+		Register(tag, catalog)
+	}
+
+	return nil
+}
+
+// Negotiate returns the language with a catalog that best matches an Accept-Language header,
+// English when none does.
+func Negotiate(acceptLanguage string) language.Tag {
+	if acceptLanguage == "" {
+		return language.English
+	}
+
+	requested, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(requested) == 0 {
+		return language.English
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	_, index, confidence := matcher.Match(requested...)
+	if confidence == language.No {
+		return language.English
+	}
+
+	return tags[index]
+}
+
+// Message returns the message of an error code in a language.
+func Message(tag language.Tag, code string) (string, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	message, ok := catalogs[tag][code]
+
+	return message, ok
+}
+
+// Localize returns the message of an error code in the language of the request, or fallback when
+// the request is in English or the code has no translation. Translated responses get a
+// Content-Language header.
+func Localize(c *gin.Context, code string, fallback string) string {
+	c.Writer.Header().Add("Vary", "Accept-Language")
+
+	tag := Negotiate(c.GetHeader("Accept-Language"))
+	if tag == language.English {
+		return fallback
+	}
+
+	message, ok := Message(tag, code)
+	if !ok {
+		return fallback
+	}
+	c.Header("Content-Language", tag.String())
+
+	return message
+}
@@ -0,0 +1,201 @@
+// Package identity performs account actions in Firebase Authentication on behalf of users and
+// reads the account details only Firebase has, such as MFA enrollment, so password resets and
+// email changes go through this service's API instead of the client SDKs.
+package identity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+
+	"github.com/thoughtgears/shared-services/internal/lazy"
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/tenant"
+)
+
+var (
+	// ErrEmailNotFound is returned when no account uses the email address.
+	ErrEmailNotFound = errors.New("no account with this email address")
+	// ErrEmailExists is returned when another account already uses the email address.
+	ErrEmailExists = errors.New("email address is already in use")
+)
+
+// Provider performs account actions in the identity provider.
+type Provider interface {
+	// PasswordResetLink returns a link that lets the owner of email choose a new password.
+	PasswordResetLink(ctx context.Context, email string) (string, error)
+	// UpdateEmail sets the email address of the account uid and marks it as verified.
+	UpdateEmail(ctx context.Context, uid string, email string) error
+	// MFAStatus returns the second factors enrolled on the account uid.
+	MFAStatus(ctx context.Context, uid string) (*models.MFAStatus, error)
+	// CustomClaims returns the custom claims of the account uid.
+	CustomClaims(ctx context.Context, uid string) (map[string]interface{}, error)
+	// SetCustomClaims replaces the custom claims of the account uid.
+	SetCustomClaims(ctx context.Context, uid string, claims map[string]interface{}) error
+	// RevokeSessions revokes the refresh tokens of the account uid, so its sessions end when their
+	// ID token expires and new ID tokens carry the current claims.
+	RevokeSessions(ctx context.Context, uid string) error
+}
+
+// authClient is implemented by both the project and the tenant auth clients.
+type authClient interface {
+	PasswordResetLinkWithSettings(ctx context.Context, email string, settings *auth.ActionCodeSettings) (string, error)
+	UpdateUser(ctx context.Context, uid string, user *auth.UserToUpdate) (*auth.UserRecord, error)
+	GetUser(ctx context.Context, uid string) (*auth.UserRecord, error)
+	SetCustomUserClaims(ctx context.Context, uid string, customClaims map[string]interface{}) error
+	RevokeRefreshTokens(ctx context.Context, uid string) error
+}
+
+// Firebase is the Provider for Firebase Authentication. With multi-tenancy the actions apply to
+// the Identity Platform tenant in the context.
+type Firebase struct {
+	auth        *lazy.Value[*auth.Client]
+	continueURL string
+}
+
+// NewFirebase creates a Firebase provider. continueURL is where users are sent once they have
+// chosen a new password. The auth client of app is created on the first action.
+func NewFirebase(app *firebase.App, continueURL string) *Firebase {
+	return &Firebase{
+		auth: lazy.New(func(ctx context.Context) (*auth.Client, error) {
+			return app.Auth(ctx)
+		}),
+		continueURL: continueURL,
+	}
+}
+
+// PasswordResetLink generates a password reset link with the Admin SDK.
+func (f *Firebase) PasswordResetLink(ctx context.Context, email string) (string, error) {
+	client, err := f.client(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	link, err := client.PasswordResetLinkWithSettings(ctx, email, &auth.ActionCodeSettings{URL: f.continueURL})
+	if auth.IsEmailNotFound(err) || auth.IsUserNotFound(err) {
+		return "", ErrEmailNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to generate password reset link: %w", err)
+	}
+
+	return link, nil
+}
+
+// UpdateEmail changes the email address of the account.
+func (f *Firebase) UpdateEmail(ctx context.Context, uid string, email string) error {
+	client, err := f.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.UpdateUser(ctx, uid, (&auth.UserToUpdate{}).Email(email).EmailVerified(true))
+	if auth.IsEmailAlreadyExists(err) {
+		return ErrEmailExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update account email: %w", err)
+	}
+
+	return nil
+}
+
+// MFAStatus reads the enrolled second factors of the account.
+func (f *Firebase) MFAStatus(ctx context.Context, uid string) (*models.MFAStatus, error) {
+	client, err := f.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := client.GetUser(ctx, uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+
+	status := &models.MFAStatus{Factors: []models.MFAFactor{}}
+	if record.MultiFactor == nil {
+		return status, nil
+	}
+	for _, factor := range record.MultiFactor.EnrolledFactors {
+		status.Factors = append(status.Factors, models.MFAFactor{
+			ID:          factor.UID,
+			Type:        factor.FactorID,
+			DisplayName: factor.DisplayName,
+			EnrolledAt:  time.UnixMilli(factor.EnrollmentTimestamp).UTC(),
+		})
+	}
+	status.Enrolled = len(status.Factors) > 0
+
+	return status, nil
+}
+
+// CustomClaims reads the custom claims of the account.
+func (f *Firebase) CustomClaims(ctx context.Context, uid string) (map[string]interface{}, error) {
+	client, err := f.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := client.GetUser(ctx, uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+
+	claims := make(map[string]interface{}, len(record.CustomClaims))
+	maps.Copy(claims, record.CustomClaims)
+
+	return claims, nil
+}
+
+// SetCustomClaims sets the custom claims of the account.
+func (f *Firebase) SetCustomClaims(ctx context.Context, uid string, claims map[string]interface{}) error {
+	client, err := f.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := client.SetCustomUserClaims(ctx, uid, claims); err != nil {
+		return fmt.Errorf("failed to set custom claims: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeSessions revokes the refresh tokens of the account.
+func (f *Firebase) RevokeSessions(ctx context.Context, uid string) error {
+	client, err := f.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := client.RevokeRefreshTokens(ctx, uid); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	return nil
+}
+
+// client returns the auth client of the tenant in ctx, or of the project without a tenant.
+func (f *Firebase) client(ctx context.Context) (authClient, error) {
+	client, err := f.auth.Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Firebase auth client: %w", err)
+	}
+
+	id := tenant.From(ctx)
+	if id == "" {
+		return client, nil
+	}
+
+	tenantClient, err := client.TenantManager.AuthForTenant(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Firebase auth client for tenant %s: %w", id, err)
+	}
+
+	return tenantClient, nil
+}
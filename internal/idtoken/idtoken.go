@@ -0,0 +1,144 @@
+// Package idtoken authenticates calls between our services with Google-signed ID tokens,
+// so workers and the gateway can call the APIs without a Firebase user token.
+package idtoken
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/idtoken"
+	"google.golang.org/api/option"
+)
+
+// ServerlessAuthorizationHeader is checked by Cloud Run IAM in place of Authorization when present.
+// Sending the service's token in it leaves Authorization free for the end user's Firebase token.
+const ServerlessAuthorizationHeader = "X-Serverless-Authorization"
+
+var (
+	// ErrInvalidToken is returned for tokens that are malformed, expired or for another audience.
+	ErrInvalidToken = errors.New("invalid ID token")
+	// ErrCallerNotAllowed is returned for valid tokens of service accounts that are not allowed to call.
+	ErrCallerNotAllowed = errors.New("caller not allowed")
+)
+
+// transport adds an ID token to every request before passing it to base.
+type transport struct {
+	tokens oauth2.TokenSource
+	header string
+	base   http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.tokens.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to mint ID token: %w", err)
+	}
+
+	// A RoundTripper must not modify the caller's request
+	req = req.Clone(req.Context())
+	req.Header.Set(t.header, "Bearer "+token.AccessToken)
+
+	return t.base.RoundTrip(req)
+}
+
+// NewTransport returns a RoundTripper that sends an ID token for audience, usually the URL of the
+// Cloud Run service being called, in header. Tokens are minted from the default credentials
+// (the metadata server on Cloud Run) and cached until shortly before they expire.
+func NewTransport(ctx context.Context, audience, header string, base http.RoundTripper, opts ...option.ClientOption) (http.RoundTripper, error) {
+	tokens, err := idtoken.NewTokenSource(ctx, audience, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ID token source for %s: %w", audience, err)
+	}
+
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &transport{tokens: tokens, header: header, base: base}, nil
+}
+
+// NewClient returns an HTTP client that authenticates every request to audience
+// with an ID token in the Authorization header.
+func NewClient(ctx context.Context, audience string, opts ...option.ClientOption) (*http.Client, error) {
+	rt, err := NewTransport(ctx, audience, "Authorization", http.DefaultTransport, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{Transport: rt}, nil
+}
+
+// Identity is the verified caller of a service-to-service request.
+type Identity struct {
+	// Email is the service account the token was minted for.
+	Email    string
+	Subject  string
+	Audience string
+	// IssuedAt is when the token was minted.
+	IssuedAt time.Time
+}
+
+// Verifier checks ID tokens sent by other services.
+type Verifier struct {
+	validator      *idtoken.Validator
+	audiences      []string
+	allowedCallers []string
+}
+
+// NewVerifier creates a Verifier accepting tokens minted for any of audiences by any of
+// the allowedCallers service account emails.
+func NewVerifier(ctx context.Context, audiences, allowedCallers []string) (*Verifier, error) {
+	if len(audiences) == 0 {
+		return nil, errors.New("at least one audience is required")
+	}
+	if len(allowedCallers) == 0 {
+		return nil, errors.New("at least one allowed caller is required")
+	}
+
+	validator, err := idtoken.NewValidator(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ID token validator: %w", err)
+	}
+
+	return &Verifier{
+		validator:      validator,
+		audiences:      audiences,
+		allowedCallers: allowedCallers,
+	}, nil
+}
+
+// Verify validates token and returns the identity of the calling service.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Identity, error) {
+	var (
+		payload *idtoken.Payload
+		err     error
+	)
+	for _, audience := range v.audiences {
+		payload, err = v.validator.Validate(ctx, token, audience)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	email, _ := payload.Claims["email"].(string)
+	verified, _ := payload.Claims["email_verified"].(bool)
+	if email == "" || !verified || !slices.Contains(v.allowedCallers, email) {
+		return nil, fmt.Errorf("%w: %q", ErrCallerNotAllowed, email)
+	}
+
+	return &Identity{
+		Email:    email,
+		Subject:  payload.Subject,
+		Audience: payload.Audience,
+		IssuedAt: time.Unix(payload.IssuedAt, 0),
+	}, nil
+}
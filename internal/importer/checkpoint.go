@@ -0,0 +1,83 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// checkpointSuffix is appended to the manifest name to name its checkpoint.
+const checkpointSuffix = ".checkpoint.json"
+
+// Report is the outcome of an import, and its checkpoint while it runs.
+type Report struct {
+	Manifest string `json:"manifest"`
+	DryRun   bool   `json:"dry_run"`
+	Records  int    `json:"records"`
+	// Next is the index of the first record not processed yet, an import resumes there.
+	Next int `json:"next"`
+	// ResumedFrom is the record the import resumed at, 0 for a fresh import.
+	ResumedFrom       int           `json:"resumed_from"`
+	UsersCreated      int           `json:"users_created"`
+	DocumentsImported int           `json:"documents_imported"`
+	Duplicates        int           `json:"duplicates"`
+	Failed            int           `json:"failed"`
+	Errors            []RecordError `json:"errors,omitempty"`
+	UpdatedAt         time.Time     `json:"updated_at"`
+}
+
+// RecordError is a record that could not be imported, Record is its 1-based number in the manifest.
+type RecordError struct {
+	Record int    `json:"record"`
+	File   string `json:"file,omitempty"`
+	Error  string `json:"error"`
+}
+
+// MaxReportedErrors caps the record errors kept in a report, the count continues in Failed.
+const MaxReportedErrors = 100
+
+// Fail counts a failed record and keeps its error, up to MaxReportedErrors.
+func (r *Report) Fail(index int, file string, err error) {
+	r.Failed++
+	if len(r.Errors) < MaxReportedErrors {
+		r.Errors = append(r.Errors, RecordError{Record: index + 1, File: file, Error: err.Error()})
+	}
+}
+
+// LoadCheckpoint returns the checkpoint of the manifest in source, or nil when there is none.
+func LoadCheckpoint(ctx context.Context, source Source, manifest string) (*Report, error) {
+	reader, err := source.Open(ctx, manifest+checkpointSuffix)
+	if errors.Is(err, ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint: %w", err)
+	}
+
+	return &report, nil
+}
+
+// SaveCheckpoint writes report as the checkpoint of its manifest in source.
+func SaveCheckpoint(ctx context.Context, source Source, report *Report) error {
+	report.UpdatedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+
+	return source.Write(ctx, report.Manifest+checkpointSuffix, data)
+}
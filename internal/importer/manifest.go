@@ -0,0 +1,183 @@
+// Package importer reads the manifests and files of bulk imports, used to onboard the users and
+// documents of the legacy system. A manifest lists one document per record with its owner; the
+// files it names are read from the same source, a local directory or a GCS prefix. Progress is
+// kept in a checkpoint next to the manifest, so an interrupted import resumes where it stopped.
+package importer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// metadataColumnPrefix marks the CSV columns holding document metadata, e.g. metadata.reference.
+const metadataColumnPrefix = "metadata."
+
+// Record is one document of a manifest and the user owning it. The user is identified by the
+// UID of their Firebase account, which is migrated separately; the user record is created from
+// the other user fields when it does not exist yet.
+type Record struct {
+	FirebaseID string `json:"firebase_id"`
+	Email      string `json:"email"`
+	FirstName  string `json:"first_name"`
+	LastName   string `json:"last_name"`
+	// UserCreatedAt is preserved from the legacy system for new users, now when empty.
+	UserCreatedAt *time.Time `json:"user_created_at,omitempty"`
+
+	// File is the path of the content relative to the source.
+	File             string            `json:"file"`
+	Type             string            `json:"type"`
+	OriginalFilename string            `json:"original_filename,omitempty"`
+	Status           string            `json:"status,omitempty"`
+	ExpiryDate       *time.Time        `json:"expiry_date,omitempty"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+	// CreatedAt and UpdatedAt are preserved from the legacy system, now when empty.
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// Validate checks the fields every record needs.
+func (r *Record) Validate() error {
+	var missing []string
+	if r.FirebaseID == "" {
+		missing = append(missing, "firebase_id")
+	}
+	if r.File == "" {
+		missing = append(missing, "file")
+	}
+	if r.Type == "" {
+		missing = append(missing, "type")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// ParseManifest reads the records of a manifest. The format follows the extension of name: .csv
+// with a header row naming the Record fields and metadata.<key> columns, .json with an array of
+// records, or .jsonl with one record per line. Times are RFC 3339.
+func ParseManifest(name string, r io.Reader) ([]Record, error) {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".csv":
+		return parseCSV(r)
+	case ".json":
+		var records []Record
+		if err := json.NewDecoder(r).Decode(&records); err != nil {
+			return nil, fmt.Errorf("failed to parse manifest: %w", err)
+		}
+
+		return records, nil
+	case ".jsonl":
+		return parseJSONL(r)
+	default:
+		return nil, fmt.Errorf("unsupported manifest format %q, use .csv, .json or .jsonl", path.Ext(name))
+	}
+}
+
+// parseJSONL reads one record per line, blank lines are skipped.
+func parseJSONL(r io.Reader) ([]Record, error) {
+	var records []Record
+	decoder := json.NewDecoder(r)
+	for {
+		var record Record
+		err := decoder.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse manifest record %d: %w", len(records)+1, err)
+		}
+		records = append(records, record)
+	}
+}
+
+// parseCSV reads the records of a CSV manifest by the column names of its header.
+func parseCSV(r io.Reader) ([]Record, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest header: %w", err)
+	}
+
+	var records []Record
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifest line %d: %w", line, err)
+		}
+
+		var record Record
+		for i, column := range header {
+			if err := record.set(strings.TrimSpace(column), strings.TrimSpace(row[i])); err != nil {
+				return nil, fmt.Errorf("invalid manifest line %d: %w", line, err)
+			}
+		}
+		records = append(records, record)
+	}
+}
+
+// set assigns the value of a CSV column. Empty values leave the field unset.
+func (r *Record) set(column string, value string) error {
+	if value == "" {
+		return nil
+	}
+
+	if key, ok := strings.CutPrefix(column, metadataColumnPrefix); ok {
+		if r.Metadata == nil {
+			r.Metadata = make(map[string]string)
+		}
+		r.Metadata[key] = value
+
+		return nil
+	}
+
+	switch column {
+	case "firebase_id":
+		r.FirebaseID = value
+	case "email":
+		r.Email = value
+	case "first_name":
+		r.FirstName = value
+	case "last_name":
+		r.LastName = value
+	case "file":
+		r.File = value
+	case "type":
+		r.Type = value
+	case "original_filename":
+		r.OriginalFilename = value
+	case "status":
+		r.Status = value
+	case "expiry_date", "created_at", "updated_at", "user_created_at":
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return fmt.Errorf("%s must be an RFC 3339 time", column)
+		}
+		switch column {
+		case "expiry_date":
+			r.ExpiryDate = &parsed
+		case "created_at":
+			r.CreatedAt = &parsed
+		case "user_created_at":
+			r.UserCreatedAt = &parsed
+		default:
+			r.UpdatedAt = &parsed
+		}
+	default:
+		return fmt.Errorf("unknown column %q", column)
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"cloud.google.com/go/storage"
+)
+
+// ErrNotExist is returned when a file is not in the source.
+var ErrNotExist = errors.New("file does not exist")
+
+// Source holds the manifest and the files of an import.
+type Source interface {
+	// Open opens the file at name, relative to the source.
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+	// Write writes the file at name, used for the checkpoint.
+	Write(ctx context.Context, name string, data []byte) error
+	// Sub returns the source of a subdirectory or prefix.
+	Sub(prefix string) Source
+}
+
+// DirSource reads an import from a local directory.
+type DirSource struct {
+	dir string
+}
+
+// NewDirSource creates a Source reading the files under dir.
+func NewDirSource(dir string) *DirSource {
+	return &DirSource{dir: dir}
+}
+
+// Open implements Source.
+func (s *DirSource) Open(_ context.Context, name string) (io.ReadCloser, error) {
+	full, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(full)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotExist, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", name, err)
+	}
+
+	return file, nil
+}
+
+// Write implements Source.
+func (s *DirSource) Write(_ context.Context, name string, data []byte) error {
+	full, err := s.path(name)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(full, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// Sub implements Source.
+func (s *DirSource) Sub(prefix string) Source {
+	return &DirSource{dir: filepath.Join(s.dir, filepath.FromSlash(prefix))}
+}
+
+// path returns the path of name in the directory, names may not leave it.
+func (s *DirSource) path(name string) (string, error) {
+	if !filepath.IsLocal(filepath.FromSlash(name)) {
+		return "", fmt.Errorf("file %q is outside the import directory", name)
+	}
+
+	return filepath.Join(s.dir, filepath.FromSlash(name)), nil
+}
+
+// GCSSource reads an import from the objects under a prefix of a bucket, e.g. an export of the
+// legacy system copied to a staging bucket.
+type GCSSource struct {
+	client *storage.Client
+	bucket string
+	prefix string
+}
+
+// NewGCSSource creates a Source reading the objects under prefix in bucket.
+func NewGCSSource(client *storage.Client, bucket string, prefix string) *GCSSource {
+	return &GCSSource{client: client, bucket: bucket, prefix: strings.Trim(prefix, "/")}
+}
+
+// Open implements Source.
+func (s *GCSSource) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	if !fs.ValidPath(name) {
+		return nil, fmt.Errorf("file %q is outside the import prefix", name)
+	}
+
+	reader, err := s.client.Bucket(s.bucket).Object(s.object(name)).NewReader(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotExist, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open gs://%s/%s: %w", s.bucket, s.object(name), err)
+	}
+
+	return reader, nil
+}
+
+// Write implements Source.
+func (s *GCSSource) Write(ctx context.Context, name string, data []byte) error {
+	if !fs.ValidPath(name) {
+		return fmt.Errorf("file %q is outside the import prefix", name)
+	}
+
+	writer := s.client.Bucket(s.bucket).Object(s.object(name)).NewWriter(ctx)
+	if _, err := io.Copy(writer, bytes.NewReader(data)); err != nil {
+		_ = writer.Close()
+
+		return fmt.Errorf("failed to write gs://%s/%s: %w", s.bucket, s.object(name), err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to write gs://%s/%s: %w", s.bucket, s.object(name), err)
+	}
+
+	return nil
+}
+
+// Sub implements Source.
+func (s *GCSSource) Sub(prefix string) Source {
+	return &GCSSource{client: s.client, bucket: s.bucket, prefix: s.object(strings.Trim(prefix, "/"))}
+}
+
+// object returns the object name of name under the prefix.
+func (s *GCSSource) object(name string) string {
+	if s.prefix == "" {
+		return name
+	}
+
+	return path.Join(s.prefix, name)
+}
@@ -0,0 +1,56 @@
+// Package lazy creates expensive values, such as API clients, on first use instead of on startup,
+// so a cold instance starts serving, and answering health probes, before it has talked to any
+// backend.
+package lazy
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Value is a value created by its init function the first time it is needed. It is safe for
+// concurrent use: callers arriving while it is being created wait for the same result. A failed
+// init is not kept, the next Get tries again, so a backend that was briefly unreachable on a cold
+// start does not break the instance for its lifetime like a sync.Once would.
+type Value[T any] struct {
+	init  func(ctx context.Context) (T, error)
+	mu    sync.Mutex
+	ready atomic.Bool
+	value T
+}
+
+// New creates a Value created by init on first use.
+func New[T any](init func(ctx context.Context) (T, error)) *Value[T] {
+	return &Value[T]{init: init}
+}
+
+// Get returns the value, creating it with ctx if it has not been created yet.
+func (v *Value[T]) Get(ctx context.Context) (T, error) {
+	if v.ready.Load() {
+		return v.value, nil
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.ready.Load() {
+		return v.value, nil
+	}
+
+	value, err := v.init(ctx)
+	if err != nil {
+		var zero T
+
+		return zero, err
+	}
+	v.value = value
+	v.ready.Store(true)
+
+	return value, nil
+}
+
+// Ready reports whether the value has been created.
+func (v *Value[T]) Ready() bool {
+	return v.ready.Load()
+}
@@ -0,0 +1,89 @@
+// Package loglevel changes the global log level at runtime, e.g. to turn on debug logging
+// during an incident, and reverts it automatically so a forgotten change does not flood the logs.
+package loglevel
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// State is the current log level and when it reverts to the default.
+type State struct {
+	Level        string     `json:"level"`
+	DefaultLevel string     `json:"default_level"`
+	RevertsAt    *time.Time `json:"reverts_at,omitempty"`
+}
+
+// Controller owns the zerolog global level.
+type Controller struct {
+	mu           sync.Mutex
+	defaultLevel zerolog.Level
+	revert       *time.Timer
+	revertsAt    *time.Time
+	// generation counts the calls of Set, so a revert that fires after a newer Set is ignored
+	generation uint64
+}
+
+// NewController creates a Controller reverting to the current global level.
+func NewController() *Controller {
+	return &Controller{defaultLevel: zerolog.GlobalLevel()}
+}
+
+// Set changes the global level until ttl has passed, replacing any earlier change.
+// Setting the default level cancels a pending revert.
+func (c *Controller) Set(level zerolog.Level, ttl time.Duration) State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.revert != nil {
+		c.revert.Stop()
+		c.revert = nil
+		c.revertsAt = nil
+	}
+
+	// Stop does not wait for a revert that already fired, it may be waiting for c.mu
+	c.generation++
+	generation := c.generation
+
+	zerolog.SetGlobalLevel(level)
+	if level != c.defaultLevel {
+		revertsAt := time.Now().Add(ttl)
+		c.revertsAt = &revertsAt
+		c.revert = time.AfterFunc(ttl, func() { c.reset(generation) })
+	}
+
+	return c.state()
+}
+
+// Get returns the current state.
+func (c *Controller) Get() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.state()
+}
+
+// reset restores the default level, unless Set was called again after the change of generation.
+func (c *Controller) reset(generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	zerolog.SetGlobalLevel(c.defaultLevel)
+	c.revert = nil
+	c.revertsAt = nil
+}
+
+// state returns the current state. The caller must hold c.mu.
+func (c *Controller) state() State {
+	return State{
+		Level:        zerolog.GlobalLevel().String(),
+		DefaultLevel: c.defaultLevel.String(),
+		RevertsAt:    c.revertsAt,
+	}
+}
@@ -0,0 +1,54 @@
+package loglevel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestSetRevertsToDefault(t *testing.T) {
+	t.Cleanup(func() { zerolog.SetGlobalLevel(zerolog.InfoLevel) })
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	c := NewController()
+
+	state := c.Set(zerolog.DebugLevel, 10*time.Millisecond)
+	if state.Level != "debug" || state.RevertsAt == nil {
+		t.Fatalf("Set() = %+v, want debug with a revert time", state)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for zerolog.GlobalLevel() != zerolog.InfoLevel {
+		if time.Now().After(deadline) {
+			t.Fatalf("level = %s after the ttl, want info", zerolog.GlobalLevel())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if state := c.Get(); state.RevertsAt != nil {
+		t.Errorf("Get() = %+v after the revert, want no revert time", state)
+	}
+}
+
+func TestStaleRevertKeepsNewerLevel(t *testing.T) {
+	t.Cleanup(func() { zerolog.SetGlobalLevel(zerolog.InfoLevel) })
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	c := NewController()
+
+	c.Set(zerolog.DebugLevel, time.Hour)
+	stale := c.generation
+	c.Set(zerolog.WarnLevel, time.Hour)
+
+	// The timer of the first Set fired before the second stopped it, and runs late
+	c.reset(stale)
+	if got := zerolog.GlobalLevel(); got != zerolog.WarnLevel {
+		t.Errorf("level = %s after a stale revert, want warn", got)
+	}
+	if state := c.Get(); state.RevertsAt == nil {
+		t.Error("Get() has no revert time after a stale revert, want the newer one")
+	}
+
+	c.reset(c.generation)
+	if got := zerolog.GlobalLevel(); got != zerolog.InfoLevel {
+		t.Errorf("level = %s after the revert, want info", got)
+	}
+}
@@ -0,0 +1,121 @@
+// Package mailer sends transactional emails such as organization invitations.
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Message is an email with a plain text body and, optionally, an HTML alternative.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+	HTML    string
+}
+
+// Mailer sends emails.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogMailer writes emails to the log instead of sending them. It is meant for local development.
+type LogMailer struct{}
+
+// Send logs the message.
+func (LogMailer) Send(_ context.Context, msg Message) error {
+	log.Info().Str("to", msg.To).Str("subject", msg.Subject).Str("body", msg.Body).Msg("Email not sent, mail mode is log")
+
+	return nil
+}
+
+// SMTPMailer sends emails through an SMTP relay with PLAIN authentication.
+type SMTPMailer struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPMailer creates an SMTPMailer for the relay at addr (host:port).
+// Authentication is skipped when username is empty.
+func NewSMTPMailer(addr, from, username, password string) (*SMTPMailer, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP address %q: %w", addr, err)
+	}
+
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &SMTPMailer{
+		addr: addr,
+		from: from,
+		auth: auth,
+	}, nil
+}
+
+// Send sends the message. net/smtp does not take a context, so ctx is not used for cancellation.
+func (m *SMTPMailer) Send(_ context.Context, msg Message) error {
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return fmt.Errorf("email headers must not contain line breaks")
+	}
+
+	headers := []string{
+		"From: " + m.from,
+		"To: " + msg.To,
+		"Subject: " + msg.Subject,
+		"MIME-Version: 1.0",
+	}
+
+	var body string
+	if msg.HTML == "" {
+		body = strings.Join(append(headers,
+			"Content-Type: text/plain; charset=UTF-8",
+			"",
+			msg.Body,
+		), "\r\n")
+	} else {
+		// Clients show the last alternative they support, so the HTML part comes last
+		boundary := "alt-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+		body = strings.Join(append(headers,
+			`Content-Type: multipart/alternative; boundary="`+boundary+`"`,
+			"",
+			"--"+boundary,
+			"Content-Type: text/plain; charset=UTF-8",
+			"",
+			msg.Body,
+			"--"+boundary,
+			"Content-Type: text/html; charset=UTF-8",
+			"",
+			msg.HTML,
+			"--"+boundary+"--",
+		), "\r\n")
+	}
+
+	if err := smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, []byte(body)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
+
+// New creates the Mailer for the given mode: "log" or "smtp".
+func New(mode, addr, from, username, password string) (Mailer, error) {
+	switch mode {
+	case "log":
+		return LogMailer{}, nil
+	case "smtp":
+		return NewSMTPMailer(addr, from, username, password)
+	default:
+		return nil, fmt.Errorf("unknown mail mode: %s", mode)
+	}
+}
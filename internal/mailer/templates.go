@@ -0,0 +1,175 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+
+	"golang.org/x/text/language"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// Brand is the product identity emails are rendered with.
+type Brand struct {
+	Name string
+	// URL is linked from the footer of every email, it is left out when empty.
+	URL string
+}
+
+// DefaultLanguage is the language of the templates without a language in their name.
+var DefaultLanguage = language.English
+
+// Templates renders branded emails. Every email has a <name>.txt template defining its subject
+// and plain text body and a <name>.html template defining its HTML body, both are rendered into
+// the shared layout of their format. Translations are named after the language they are in, e.g.
+// <name>.de.txt and <name>.de.html, the untranslated templates are in DefaultLanguage.
+type Templates struct {
+	brand  Brand
+	emails map[string]*translations
+}
+
+// translations are the templates of an email in every language it is available in, the default
+// language first.
+type translations struct {
+	tags    []language.Tag
+	matcher language.Matcher
+	text    []*texttemplate.Template
+	html    []*htmltemplate.Template
+}
+
+// templateData is what the templates are executed with: the brand, the language and the rendered
+// subject of the email and its data.
+type templateData struct {
+	Brand    Brand
+	Language string
+	Subject  string
+	Data     any
+}
+
+// NewTemplates parses the embedded email templates.
+func NewTemplates(brand Brand) (*Templates, error) {
+	entries, err := templateFS.ReadDir("templates")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read email templates: %w", err)
+	}
+
+	t := &Templates{
+		brand:  brand,
+		emails: make(map[string]*translations),
+	}
+	for _, entry := range entries {
+		file, ok := strings.CutSuffix(entry.Name(), ".txt")
+		if !ok || file == "layout" {
+			continue
+		}
+
+		name, lang, translated := strings.Cut(file, ".")
+		tag := DefaultLanguage
+		if translated {
+			if tag, err = language.Parse(lang); err != nil {
+				return nil, fmt.Errorf("invalid language of email template %s: %w", entry.Name(), err)
+			}
+		}
+
+		text, err := texttemplate.ParseFS(templateFS, "templates/layout.txt", "templates/"+file+".txt")
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse email template %s: %w", file, err)
+		}
+		html, err := htmltemplate.ParseFS(templateFS, "templates/layout.html", "templates/"+file+".html")
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse email template %s: %w", file, err)
+		}
+
+		email, ok := t.emails[name]
+		if !ok {
+			email = &translations{}
+			t.emails[name] = email
+		}
+		// The default language goes first, it is what the matcher falls back to
+		if tag == DefaultLanguage {
+			email.tags = append([]language.Tag{tag}, email.tags...)
+			email.text = append([]*texttemplate.Template{text}, email.text...)
+			email.html = append([]*htmltemplate.Template{html}, email.html...)
+		} else {
+			email.tags = append(email.tags, tag)
+			email.text = append(email.text, text)
+			email.html = append(email.html, html)
+		}
+	}
+
+	for name, email := range t.emails {
+		if email.tags[0] != DefaultLanguage {
+			return nil, fmt.Errorf("email template %s has no %s version", name, DefaultLanguage)
+		}
+		email.matcher = language.NewMatcher(email.tags)
+	}
+
+	return t, nil
+}
+
+// Render renders the email template name for the recipient to, in the language closest to lang
+// the template is available in.
+func (t *Templates) Render(name string, lang language.Tag, to string, data any) (Message, error) {
+	text, html, values, err := t.translation(name, lang, data)
+	if err != nil {
+		return Message{}, err
+	}
+
+	var body, htmlBody bytes.Buffer
+	if err := text.ExecuteTemplate(&body, "layout", values); err != nil {
+		return Message{}, fmt.Errorf("failed to render email %s: %w", name, err)
+	}
+	if err := html.ExecuteTemplate(&htmlBody, "layout", values); err != nil {
+		return Message{}, fmt.Errorf("failed to render email %s: %w", name, err)
+	}
+
+	return Message{
+		To:      to,
+		Subject: values.Subject,
+		Body:    body.String(),
+		HTML:    htmlBody.String(),
+	}, nil
+}
+
+// RenderText renders the subject and the plain text body of the email template name without the
+// layout, for channels other than email, in the language closest to lang.
+func (t *Templates) RenderText(name string, lang language.Tag, data any) (string, string, error) {
+	text, _, values, err := t.translation(name, lang, data)
+	if err != nil {
+		return "", "", err
+	}
+
+	var body bytes.Buffer
+	if err := text.ExecuteTemplate(&body, "body", values); err != nil {
+		return "", "", fmt.Errorf("failed to render email %s: %w", name, err)
+	}
+
+	return values.Subject, strings.TrimSpace(body.String()), nil
+}
+
+// translation returns the templates of the email name in the language closest to lang, and the
+// values to execute them with, the subject already rendered.
+func (t *Templates) translation(name string, lang language.Tag, data any) (*texttemplate.Template, *htmltemplate.Template, templateData, error) {
+	email, ok := t.emails[name]
+	if !ok {
+		return nil, nil, templateData{}, fmt.Errorf("unknown email template: %s", name)
+	}
+
+	_, index, _ := email.matcher.Match(lang)
+	text, html := email.text[index], email.html[index]
+
+	values := templateData{Brand: t.brand, Language: email.tags[index].String(), Data: data}
+
+	var subject bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", values); err != nil {
+		return nil, nil, templateData{}, fmt.Errorf("failed to render subject of email %s: %w", name, err)
+	}
+	values.Subject = strings.TrimSpace(subject.String())
+
+	return text, html, values, nil
+}
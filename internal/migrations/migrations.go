@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"github.com/thoughtgears/shared-services/internal/db"
+)
+
+// Users returns the schema migrations for the users collection.
+// Append new migrations at the end; never change a migration that has shipped.
+func Users() *db.MigrationRegistry {
+	return db.NewMigrationRegistry().
+		// v1 is the first versioned schema, existing documents only get stamped.
+		Register(0, func(map[string]interface{}) error { return nil })
+}
+
+// Documents returns the schema migrations for the documents collection.
+// Append new migrations at the end; never change a migration that has shipped.
+func Documents() *db.MigrationRegistry {
+	return db.NewMigrationRegistry().
+		// v1 is the first versioned schema, existing documents only get stamped.
+		Register(0, func(map[string]interface{}) error { return nil })
+}
+
+// ForCollection returns the migrations for a collection by name, or nil if the collection is not versioned.
+func ForCollection(name string) *db.MigrationRegistry {
+	switch name {
+	case "users":
+		return Users()
+	case "documents":
+		return Documents()
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// AccessTokenPrefix starts every personal access token, so they can be told apart from
+// Firebase ID tokens and found by secret scanners.
+const AccessTokenPrefix = "tgpat_"
+
+// AccessTokenScope is an API permission of a personal access token.
+type AccessTokenScope string
+
+const (
+	// ScopeDocumentsRead allows reading documents, bundles and document types.
+	ScopeDocumentsRead AccessTokenScope = "documents:read"
+	// ScopeDocumentsWrite allows creating, changing and deleting documents and bundles.
+	ScopeDocumentsWrite AccessTokenScope = "documents:write"
+)
+
+// AccessTokenScopes are the known access token scopes.
+var AccessTokenScopes = []AccessTokenScope{ScopeDocumentsRead, ScopeDocumentsWrite}
+
+// AccessToken is a personal access token that lets scripts and integrations call the document API
+// as the user, within its scopes. Only a hash of the secret is stored: the token itself is shown
+// once when it is created. UserID is the user's Firebase ID.
+// Tokens are looked up before the tenant of a request is known, so they are kept outside the
+// tenant-scoped collections and record their tenant instead.
+type AccessToken struct {
+	ID            string             `json:"id" firestore:"id"`
+	UserID        string             `json:"user_id" firestore:"user_id"`
+	TenantID      string             `json:"tenant_id,omitempty" firestore:"tenant_id"`
+	Name          string             `json:"name" firestore:"name"`
+	Scopes        []AccessTokenScope `json:"scopes" firestore:"scopes"`
+	SecretHash    string             `json:"-" firestore:"secret_hash"`
+	ExpiresAt     time.Time          `json:"expires_at" firestore:"expires_at"`
+	LastUsedAt    *time.Time         `json:"last_used_at,omitempty" firestore:"last_used_at,omitempty"`
+	RevokedAt     *time.Time         `json:"revoked_at,omitempty" firestore:"revoked_at,omitempty"`
+	ExpireAt      *time.Time         `json:"-" firestore:"expire_at,omitempty"`
+	CreatedAt     time.Time          `json:"created_at" firestore:"created_at,serverTimestamp"`
+	UpdatedAt     time.Time          `json:"updated_at" firestore:"updated_at,serverTimestamp"`
+	SchemaVersion int                `json:"schema_version" firestore:"schema_version"`
+}
+
+// HasScope reports whether the token grants scope.
+func (t *AccessToken) HasScope(scope AccessTokenScope) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+// Active reports whether the token can be used at now.
+func (t *AccessToken) Active(now time.Time) bool {
+	return t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
+
+// NewAccessToken is a newly created access token together with its secret token.
+type NewAccessToken struct {
+	*AccessToken
+	Token string `json:"token"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// MaxLoginHistory is the number of sign-ins kept in a user's login history.
+const MaxLoginHistory = 20
+
+// Login is a sign-in of a user, recorded the first time a token from that sign-in is used.
+// Provider is the Firebase sign-in provider, e.g. password or google.com.
+type Login struct {
+	IP        string    `json:"ip" firestore:"ip"`
+	UserAgent string    `json:"user_agent" firestore:"user_agent"`
+	Provider  string    `json:"provider" firestore:"provider"`
+	AuthTime  time.Time `json:"auth_time" firestore:"auth_time"`
+}
+
+// UserActivity tracks when a user was last seen and their most recent sign-ins, newest first.
+// It is keyed by the user's Firebase ID. LastSeenAt is only refreshed every few minutes,
+// so it is approximate.
+type UserActivity struct {
+	ID            string    `json:"id" firestore:"id"`
+	LastSeenAt    time.Time `json:"last_seen_at" firestore:"last_seen_at"`
+	Logins        []Login   `json:"logins" firestore:"logins"`
+	UpdatedAt     time.Time `json:"updated_at" firestore:"updated_at,serverTimestamp"`
+	SchemaVersion int       `json:"schema_version" firestore:"schema_version"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	// MinBundleParts is the minimum number of documents in a bundle, a single file is a plain document.
+	MinBundleParts = 2
+	// MaxBundleParts is the maximum number of documents in a bundle.
+	MaxBundleParts = 10
+)
+
+// Bundle groups the documents that make up one multi-part document, such as the front and back of
+// an ID card or the photo and signature pages of a passport. The documents of a bundle are created
+// together and verified as a unit: the bundle carries the review status, its documents have none.
+type Bundle struct {
+	ID             string       `json:"id" firestore:"id"`
+	UserID         string       `json:"user_id" firestore:"user_id"`
+	OrganizationID string       `json:"organization_id,omitempty" firestore:"organization_id,omitempty"`
+	Type           DocumentType `json:"type" firestore:"type"`
+	// DocumentIDs are the bundle's documents in the order they were uploaded
+	DocumentIDs []string       `json:"document_ids" firestore:"document_ids"`
+	Status      DocumentStatus `json:"status" firestore:"status"`
+	ReviewerID  string         `json:"reviewer_id,omitempty" firestore:"reviewer_id,omitempty"`
+	ClaimedAt   *time.Time     `json:"claimed_at,omitempty" firestore:"claimed_at,omitempty"`
+	ReviewedAt  *time.Time     `json:"reviewed_at,omitempty" firestore:"reviewed_at,omitempty"`
+	ReviewNote  string         `json:"review_note,omitempty" firestore:"review_note,omitempty"`
+	CreatedAt   time.Time      `json:"created_at" firestore:"created_at,serverTimestamp"`
+	UpdatedAt   time.Time      `json:"updated_at" firestore:"updated_at,serverTimestamp"`
+	// Documents are loaded along with the bundle, they are not stored on it
+	Documents []*Document `json:"documents,omitempty" firestore:"-"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "slices"
+
+// PlatformRole is a role a user holds across the platform, as opposed to the Role of an
+// organization membership. Platform roles are granted as Firebase custom claims.
+type PlatformRole string
+
+const (
+	// PlatformRoleAdmin grants the admin routes, it is also set as the admin claim.
+	PlatformRoleAdmin PlatformRole = "admin"
+	// PlatformRoleReviewer marks users reviewing documents for clients that tailor their interface.
+	PlatformRoleReviewer PlatformRole = "reviewer"
+	// PlatformRoleSupport marks support staff for clients that tailor their interface.
+	PlatformRoleSupport PlatformRole = "support"
+)
+
+// PlatformRoles are the known platform roles.
+var PlatformRoles = []PlatformRole{PlatformRoleAdmin, PlatformRoleReviewer, PlatformRoleSupport}
+
+// Valid reports whether r is a known platform role.
+func (r PlatformRole) Valid() bool {
+	return slices.Contains(PlatformRoles, r)
+}
+
+// UserClaims are the custom claims managed for a user. They are written to the Firebase account,
+// so they are in the user's ID tokens, and stored on the user so both can be compared.
+// Empty values remove the claim.
+type UserClaims struct {
+	Roles  []PlatformRole `json:"roles,omitempty" firestore:"roles,omitempty"`
+	Tenant string         `json:"tenant,omitempty" firestore:"tenant,omitempty"`
+}
+
+// Validate checks that the roles are known and not repeated.
+func (c *UserClaims) Validate() error {
+	verr := &ValidationError{}
+
+	for i, role := range c.Roles {
+		if !role.Valid() {
+			verr.Add("roles", "contains an unknown role "+string(role))
+		} else if slices.Contains(c.Roles[:i], role) {
+			verr.Add("roles", "contains "+string(role)+" more than once")
+		}
+	}
+
+	return verr.Err()
+}
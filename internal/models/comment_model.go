@@ -0,0 +1,72 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaxCommentLength is the maximum length of a comment body.
+const MaxCommentLength = 2000
+
+// CommentAuthorRole tells apart comments written by the document owner from those of reviewers.
+type CommentAuthorRole string
+
+const (
+	CommentAuthorOwner    CommentAuthorRole = "owner"
+	CommentAuthorReviewer CommentAuthorRole = "reviewer"
+)
+
+// CommentRegion is a rectangle on a document page, in fractions of the page width and height
+// from the top left corner, so it does not depend on the resolution the page is rendered at.
+type CommentRegion struct {
+	X      float64 `json:"x" firestore:"x"`
+	Y      float64 `json:"y" firestore:"y"`
+	Width  float64 `json:"width" firestore:"width"`
+	Height float64 `json:"height" firestore:"height"`
+}
+
+// Comment is a note on a document, such as a rejection reason or a request for clarification.
+// Comments are stored in a subcollection of the document and can refer to a page and a region on it.
+// The body is stored encrypted when field encryption is enabled.
+type Comment struct {
+	ID         string            `json:"id" firestore:"id"`
+	DocumentID string            `json:"document_id" firestore:"document_id"`
+	AuthorID   string            `json:"author_id" firestore:"author_id"`
+	AuthorRole CommentAuthorRole `json:"author_role" firestore:"author_role"`
+	Body       string            `json:"body" firestore:"body" encrypt:"true"`
+	Page       *int              `json:"page,omitempty" firestore:"page,omitempty"`
+	Region     *CommentRegion    `json:"region,omitempty" firestore:"region,omitempty"`
+	CreatedAt  time.Time         `json:"created_at" firestore:"created_at,serverTimestamp"`
+	UpdatedAt  time.Time         `json:"updated_at" firestore:"updated_at,serverTimestamp"`
+}
+
+// Validate checks the comment body and the page and region it refers to.
+func (c *Comment) Validate() error {
+	verr := &ValidationError{}
+
+	if c.Body == "" {
+		verr.Add("body", "is required")
+	} else if len(c.Body) > MaxCommentLength {
+		verr.Add("body", fmt.Sprintf("must be at most %d characters", MaxCommentLength))
+	}
+
+	if c.Page != nil && *c.Page < 1 {
+		verr.Add("page", "must be 1 or greater")
+	}
+
+	if r := c.Region; r != nil {
+		if c.Page == nil {
+			verr.Add("region", "requires a page")
+		}
+		if !r.WithinPage() {
+			verr.Add("region", "must lie within the page, as fractions of its width and height")
+		}
+	}
+
+	return verr.Err()
+}
+
+// WithinPage reports whether the region is not empty and lies within the page.
+func (r *CommentRegion) WithinPage() bool {
+	return r.X >= 0 && r.Y >= 0 && r.Width > 0 && r.Height > 0 && r.X+r.Width <= 1 && r.Y+r.Height <= 1
+}
@@ -2,8 +2,11 @@ package models
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
 )
 
 type DocumentType string
@@ -15,29 +18,213 @@ const (
 	DocumentTypeOther         DocumentType = "other"
 )
 
+// DocumentStatus is the verification state of a document. New documents and new versions are
+// pending until a reviewer claims them and records a decision.
+type DocumentStatus string
+
+const (
+	DocumentStatusPending  DocumentStatus = "pending"
+	DocumentStatusInReview DocumentStatus = "in_review"
+	DocumentStatusVerified DocumentStatus = "verified"
+	DocumentStatusRejected DocumentStatus = "rejected"
+)
+
+// Document is an uploaded file owned by a user, or by an organization when OrganizationID is set.
+// UserID is always the uploader; access to organization documents is granted through membership.
+// Documents that are part of a Bundle have a BundleID and no status of their own, they are reviewed with the bundle.
+// Versions holds earlier content that has to be preserved, such as the original of a redacted document.
+// StorageClass is the storage class tiering moved the content to, empty for STANDARD. Restoring is set
+// while cold content is moved back to STANDARD after it was requested, RestoredAt is when that last happened.
+// LegalHold is set while an administrator preserves the document, see LegalHold.
 type Document struct {
-	ID          string       `json:"id" firestore:"id"`
-	UserID      string       `json:"user_id" firestore:"user_id" `
-	Name        string       `json:"name" firestore:"name"`
-	Size        int64        `json:"size" firestore:"size"`
-	Type        DocumentType `json:"type" firestore:"type"`
-	ContentType string       `json:"content_type" firestore:"content_type"`
-	Path        string       `json:"path" firestore:"path"`
-	Bucket      string       `json:"bucket" firestore:"bucket"`
-	CreatedAt   time.Time    `json:"created_at" firestore:"created_at,serverTimestamp"`
-	UpdatedAt   time.Time    `json:"updated_at" firestore:"updated_at,serverTimestamp"`
-}
-
-func ParseDocumentType(docType string) (DocumentType, error) {
-	switch strings.ToUpper(docType) {
-	case "PASSPORT":
-		return DocumentTypePassport, nil
-	case "ID_CARD":
-		return DocumentTypeIDCard, nil
-	case "DRIVER_LICENSE":
-		return DocumentTypeDriverLicense, nil
-	// Add other types as needed
-	default:
-		return "", fmt.Errorf("unknown document type: %s", docType)
+	ID             string `json:"id" firestore:"id"`
+	UserID         string `json:"user_id" firestore:"user_id" `
+	OrganizationID string `json:"organization_id,omitempty" firestore:"organization_id,omitempty"`
+	Name           string `json:"name" firestore:"name"`
+	// OriginalFilename is the sanitized filename the client uploaded, Name is the generated object name
+	OriginalFilename string `json:"original_filename,omitempty" firestore:"original_filename,omitempty"`
+	// DisplayName is a label chosen by the user, it often contains a person's name so it is encrypted
+	DisplayName   string            `json:"display_name,omitempty" firestore:"display_name,omitempty" encrypt:"true"`
+	Size          int64             `json:"size" firestore:"size"`
+	Type          DocumentType      `json:"type" firestore:"type"`
+	ContentType   string            `json:"content_type" firestore:"content_type"`
+	Path          string            `json:"path" firestore:"path"`
+	Bucket        string            `json:"bucket" firestore:"bucket"`
+	Region        string            `json:"region,omitempty" firestore:"region,omitempty"`
+	ExpiryDate    *time.Time        `json:"expiry_date,omitempty" firestore:"expiry_date,omitempty"`
+	RetainUntil   *time.Time        `json:"retain_until,omitempty" firestore:"retain_until,omitempty"`
+	Metadata      map[string]string `json:"metadata,omitempty" firestore:"metadata,omitempty" encrypt:"true"`
+	Status        DocumentStatus    `json:"status,omitempty" firestore:"status,omitempty"`
+	ReviewerID    string            `json:"reviewer_id,omitempty" firestore:"reviewer_id,omitempty"`
+	ClaimedAt     *time.Time        `json:"claimed_at,omitempty" firestore:"claimed_at,omitempty"`
+	ReviewedAt    *time.Time        `json:"reviewed_at,omitempty" firestore:"reviewed_at,omitempty"`
+	ReviewNote    string            `json:"review_note,omitempty" firestore:"review_note,omitempty"`
+	BundleID      string            `json:"bundle_id,omitempty" firestore:"bundle_id,omitempty"`
+	Versions      []DocumentVersion `json:"versions,omitempty" firestore:"versions,omitempty"`
+	StorageClass  string            `json:"storage_class,omitempty" firestore:"storage_class,omitempty"`
+	Restoring     bool              `json:"restoring,omitempty" firestore:"restoring,omitempty"`
+	RestoredAt    *time.Time        `json:"restored_at,omitempty" firestore:"restored_at,omitempty"`
+	LegalHold     *LegalHold        `json:"legal_hold,omitempty" firestore:"legal_hold,omitempty"`
+	CreatedAt     time.Time         `json:"created_at" firestore:"created_at,serverTimestamp"`
+	UpdatedAt     time.Time         `json:"updated_at" firestore:"updated_at,serverTimestamp"`
+	SchemaVersion int               `json:"schema_version" firestore:"schema_version"`
+}
+
+// DocumentVersionReason is the reason earlier content of a document was preserved.
+type DocumentVersionReason string
+
+// DocumentVersionRedaction is the content of a document before it was redacted.
+const DocumentVersionRedaction DocumentVersionReason = "redaction"
+
+// DocumentVersion is earlier content of a document that was replaced but is kept in storage,
+// e.g. for compliance. It is not served to clients, downloads always return the current content.
+type DocumentVersion struct {
+	Name        string                `json:"name" firestore:"name"`
+	Path        string                `json:"path" firestore:"path"`
+	Size        int64                 `json:"size" firestore:"size"`
+	ContentType string                `json:"content_type" firestore:"content_type"`
+	Reason      DocumentVersionReason `json:"reason" firestore:"reason"`
+	ReplacedAt  time.Time             `json:"replaced_at" firestore:"replaced_at"`
+}
+
+// LegalHold preserves a document, e.g. for litigation or an investigation. While it is set the
+// document cannot be changed or deleted and its objects carry a Cloud Storage hold of Type.
+type LegalHold struct {
+	// Type is the object hold placed on the content, temporary or event_based
+	Type     string    `json:"type" firestore:"type"`
+	Reason   string    `json:"reason" firestore:"reason"`
+	PlacedBy string    `json:"placed_by" firestore:"placed_by"`
+	PlacedAt time.Time `json:"placed_at" firestore:"placed_at"`
+}
+
+// ContentPaths returns the storage paths of the document's content and of its preserved versions.
+func (d *Document) ContentPaths() []string {
+	paths := []string{d.Path}
+	for _, version := range d.Versions {
+		paths = append(paths, version.Path)
+	}
+
+	return paths
+}
+
+// DocumentRule is a validation rule that applies to a specific document type.
+// Rules add any problems they find to the provided ValidationError.
+// Rules expressible as data belong on DocumentTypeDefinition; registered rules
+// are for checks that need code.
+type DocumentRule func(d *Document, verr *ValidationError)
+
+const (
+	// MaxMetadataEntries is the maximum number of metadata entries on a document.
+	MaxMetadataEntries = 20
+	// MaxMetadataKeyLength is the maximum length of a metadata key.
+	MaxMetadataKeyLength = 64
+	// MaxMetadataValueLength is the maximum length of a metadata value.
+	MaxMetadataValueLength = 512
+	// MaxDisplayNameLength is the maximum length of a document display name.
+	MaxDisplayNameLength = 200
+)
+
+// metadataKeyPattern restricts metadata keys to characters that are safe to use in Firestore field paths.
+var metadataKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+var (
+	documentRulesMu sync.RWMutex
+	documentRules   = map[DocumentType][]DocumentRule{}
+)
+
+// RegisterDocumentRule adds a validation rule for the given document type.
+// Rules are evaluated in registration order after the common document checks.
+func RegisterDocumentRule(docType DocumentType, rule DocumentRule) {
+	documentRulesMu.Lock()
+	defer documentRulesMu.Unlock()
+
+	documentRules[docType] = append(documentRules[docType], rule)
+}
+
+// RequireExpiryDate is a DocumentRule that requires an expiry date which has not yet passed.
+func RequireExpiryDate(d *Document, verr *ValidationError) {
+	if d.ExpiryDate == nil {
+		verr.Add("expiry_date", fmt.Sprintf("is required for %s documents", d.Type))
+
+		return
+	}
+
+	if d.ExpiryDate.Before(time.Now()) {
+		verr.Add("expiry_date", "must not be in the past")
+	}
+}
+
+// Validate checks the document invariants and the rules registered for its type.
+// It returns a *ValidationError describing every invalid field, or nil if the document is valid.
+func (d *Document) Validate() error {
+	return d.ValidateAs(nil)
+}
+
+// ValidateAs validates the document like Validate, additionally applying the rules
+// from the given type definition. A nil definition only runs the common checks.
+func (d *Document) ValidateAs(def *DocumentTypeDefinition) error {
+	verr := &ValidationError{}
+
+	if d.UserID == "" {
+		verr.Add("user_id", "is required")
+	}
+
+	if d.Type == "" {
+		verr.Add("type", "is required")
+	}
+
+	if d.ContentType == "" {
+		verr.Add("content_type", "is required")
+	}
+
+	if d.Size <= 0 {
+		verr.Add("size", "must be greater than zero")
+	}
+
+	ValidateMetadata(d.Metadata, verr)
+
+	ValidateDisplayName(d.DisplayName, verr)
+
+	if def != nil {
+		def.Apply(d, verr)
+	}
+
+	documentRulesMu.RLock()
+	rules := documentRules[d.Type]
+	documentRulesMu.RUnlock()
+
+	for _, rule := range rules {
+		rule(d, verr)
+	}
+
+	return verr.Err()
+}
+
+// ValidateMetadata checks the size and format of a document metadata map,
+// adding any problems to the provided ValidationError.
+func ValidateMetadata(metadata map[string]string, verr *ValidationError) {
+	if len(metadata) > MaxMetadataEntries {
+		verr.Add("metadata", fmt.Sprintf("must not contain more than %d entries", MaxMetadataEntries))
+	}
+
+	for key, value := range metadata {
+		field := "metadata." + key
+		if len(key) > MaxMetadataKeyLength || !metadataKeyPattern.MatchString(key) {
+			verr.Add(field, fmt.Sprintf("key must be lowercase snake_case and at most %d characters", MaxMetadataKeyLength))
+		}
+
+		if len(value) > MaxMetadataValueLength {
+			verr.Add(field, fmt.Sprintf("value must be at most %d characters", MaxMetadataValueLength))
+		}
+	}
+}
+
+// ValidateDisplayName checks the length and characters of a document display name,
+// adding any problems to the provided ValidationError.
+func ValidateDisplayName(name string, verr *ValidationError) {
+	if len(name) > MaxDisplayNameLength {
+		verr.Add("display_name", fmt.Sprintf("must be at most %d characters", MaxDisplayNameLength))
+	} else if strings.ContainsFunc(name, unicode.IsControl) {
+		verr.Add("display_name", "must not contain control characters")
 	}
 }
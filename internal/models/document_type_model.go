@@ -0,0 +1,56 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// DocumentTypeDefinition describes a document type accepted by the service.
+// Definitions are loaded from configuration or the document_types collection,
+// so new types and their rules can be introduced without a code change.
+type DocumentTypeDefinition struct {
+	ID                DocumentType `json:"id" firestore:"id"`
+	Name              string       `json:"name" firestore:"name"`
+	Aliases           []string     `json:"aliases,omitempty" firestore:"aliases"`
+	AllowedMimeTypes  []string     `json:"allowed_mime_types,omitempty" firestore:"allowed_mime_types"`
+	RequireExpiryDate bool         `json:"require_expiry_date" firestore:"require_expiry_date"`
+	RetentionDays     int          `json:"retention_days,omitempty" firestore:"retention_days"`
+}
+
+// DefaultDocumentTypes returns the built-in document type definitions used when
+// no other source is configured. The aliases keep the historical upload values working.
+func DefaultDocumentTypes() []DocumentTypeDefinition {
+	images := []string{"application/pdf", "image/png", "image/jpeg", "image/tiff", "image/bmp"}
+
+	return []DocumentTypeDefinition{
+		{ID: DocumentTypePassport, Name: "Passport", AllowedMimeTypes: images, RequireExpiryDate: true},
+		{ID: DocumentTypeIDCard, Name: "ID card", AllowedMimeTypes: images},
+		{ID: DocumentTypeDriverLicense, Name: "Driver licence", Aliases: []string{"driver_license"}, AllowedMimeTypes: images, RequireExpiryDate: true},
+		{ID: DocumentTypeOther, Name: "Other"},
+	}
+}
+
+// Apply runs the type specific validation rules against a document,
+// adding any problems to the provided ValidationError.
+func (t *DocumentTypeDefinition) Apply(d *Document, verr *ValidationError) {
+	if len(t.AllowedMimeTypes) > 0 && !slices.Contains(t.AllowedMimeTypes, d.ContentType) {
+		verr.Add("content_type", fmt.Sprintf("%s is not allowed for %s documents", d.ContentType, t.ID))
+	}
+
+	if t.RequireExpiryDate {
+		RequireExpiryDate(d, verr)
+	}
+}
+
+// RetainUntil returns the time until which a document created at the given time must be kept,
+// or nil if the type has no retention period configured.
+func (t *DocumentTypeDefinition) RetainUntil(createdAt time.Time) *time.Time {
+	if t.RetentionDays <= 0 {
+		return nil
+	}
+
+	retainUntil := createdAt.AddDate(0, 0, t.RetentionDays)
+
+	return &retainUntil
+}
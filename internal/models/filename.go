@@ -0,0 +1,50 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxFilenameLength is the maximum length in bytes of a stored original filename.
+const MaxFilenameLength = 255
+
+// SanitizeFilename makes a client supplied filename safe to store and to send back in a
+// Content-Disposition header or archive entry. Directories are dropped, control characters and
+// characters that are not allowed in Windows filenames are replaced, and the name is truncated to
+// MaxFilenameLength bytes keeping the extension. It returns an empty string if nothing usable is left.
+func SanitizeFilename(name string) string {
+	// Browsers on Windows may send the full client path
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r == utf8.RuneError, unicode.IsControl(r), strings.ContainsRune(`<>:"|?*`, r):
+			return '_'
+		case unicode.IsSpace(r):
+			return ' '
+		default:
+			return r
+		}
+	}, name)
+	name = strings.Trim(name, " .")
+	if strings.Trim(name, "_") == "" {
+		return ""
+	}
+
+	if len(name) > MaxFilenameLength {
+		ext := ""
+		if i := strings.LastIndexByte(name, '.'); i > 0 && len(name)-i <= 16 {
+			ext = name[i:]
+		}
+		base := name[:MaxFilenameLength-len(ext)]
+		for !utf8.ValidString(base) {
+			base = base[:len(base)-1]
+		}
+		name = strings.TrimRight(base, " .") + ext
+	}
+
+	return name
+}
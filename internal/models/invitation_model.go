@@ -0,0 +1,50 @@
+package models
+
+import (
+	"net/mail"
+	"time"
+)
+
+// InvitationStatus is the state of an organization invitation.
+type InvitationStatus string
+
+const (
+	InvitationStatusPending  InvitationStatus = "pending"
+	InvitationStatusAccepted InvitationStatus = "accepted"
+	InvitationStatusRevoked  InvitationStatus = "revoked"
+)
+
+// Invitation invites a person, by email, to join an organization with a role.
+// The invitee receives a signed token by email; redeeming it while the invitation is pending
+// and unexpired adds their Firebase account to the organization.
+type Invitation struct {
+	ID             string           `json:"id" firestore:"id"`
+	OrganizationID string           `json:"organization_id" firestore:"organization_id"`
+	Email          string           `json:"email" firestore:"email"`
+	Role           Role             `json:"role" firestore:"role"`
+	Status         InvitationStatus `json:"status" firestore:"status"`
+	InvitedBy      string           `json:"invited_by" firestore:"invited_by"`
+	ExpiresAt      time.Time        `json:"expires_at" firestore:"expires_at"`
+	AcceptedBy     string           `json:"accepted_by,omitempty" firestore:"accepted_by,omitempty"`
+	AcceptedAt     *time.Time       `json:"accepted_at,omitempty" firestore:"accepted_at,omitempty"`
+	ExpireAt       *time.Time       `json:"-" firestore:"expire_at,omitempty"`
+	CreatedAt      time.Time        `json:"created_at" firestore:"created_at,serverTimestamp"`
+	UpdatedAt      time.Time        `json:"updated_at" firestore:"updated_at,serverTimestamp"`
+	SchemaVersion  int              `json:"schema_version" firestore:"schema_version"`
+}
+
+// Validate checks the invitee email and role.
+func (i *Invitation) Validate() error {
+	verr := &ValidationError{}
+
+	if i.Email == "" {
+		verr.Add("email", "is required")
+	} else if addr, err := mail.ParseAddress(i.Email); err != nil || addr.Address != i.Email {
+		verr.Add("email", "must be a valid email address")
+	}
+	if !i.Role.Valid() {
+		verr.Add("role", "must be one of member, admin, owner")
+	}
+
+	return verr.Err()
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// NotificationChannel is a way notifications reach a user.
+type NotificationChannel string
+
+const (
+	NotificationChannelEmail   NotificationChannel = "email"
+	NotificationChannelPush    NotificationChannel = "push"
+	NotificationChannelWebhook NotificationChannel = "webhook"
+)
+
+// NotificationChannels lists every channel, in the order notifications are delivered.
+var NotificationChannels = []NotificationChannel{
+	NotificationChannelEmail,
+	NotificationChannelPush,
+	NotificationChannelWebhook,
+}
+
+// IsValid reports whether c is a known notification channel.
+func (c NotificationChannel) IsValid() bool {
+	switch c {
+	case NotificationChannelEmail, NotificationChannelPush, NotificationChannelWebhook:
+		return true
+	}
+
+	return false
+}
+
+// NotificationPreferences turn notification channels on or off for a user.
+// Channels are opt-out: a channel without an entry is enabled.
+type NotificationPreferences map[NotificationChannel]bool
+
+// Enabled reports whether notifications are delivered on the channel.
+func (p NotificationPreferences) Enabled(channel NotificationChannel) bool {
+	enabled, ok := p[channel]
+
+	return !ok || enabled
+}
+
+// validate adds an error to verr for every unknown channel.
+func (p NotificationPreferences) validate(verr *ValidationError) {
+	for channel := range p {
+		if !channel.IsValid() {
+			verr.Add(fmt.Sprintf("notification_preferences.%s", channel), "must be email, push or webhook")
+		}
+	}
+}
+
+// NotificationDeliveryStatus is the outcome of delivering a notification on one channel.
+type NotificationDeliveryStatus string
+
+const (
+	NotificationDeliveryDelivered NotificationDeliveryStatus = "delivered"
+	NotificationDeliveryFailed    NotificationDeliveryStatus = "failed"
+	// NotificationDeliverySkipped is recorded when the user turned the channel off
+	// or the channel has nowhere to deliver to.
+	NotificationDeliverySkipped NotificationDeliveryStatus = "skipped"
+)
+
+// NotificationDelivery records one attempt to deliver a notification to a user on a channel,
+// so users and support can see which notifications were sent and why one did not arrive.
+// Deliveries are keyed so that listing them by ID returns the newest first.
+type NotificationDelivery struct {
+	ID            string                     `json:"id" firestore:"id"`
+	UserID        string                     `json:"user_id" firestore:"user_id"`
+	Event         string                     `json:"event" firestore:"event"`
+	Channel       NotificationChannel        `json:"channel" firestore:"channel"`
+	Status        NotificationDeliveryStatus `json:"status" firestore:"status"`
+	Subject       string                     `json:"subject" firestore:"subject"`
+	Error         string                     `json:"error,omitempty" firestore:"error,omitempty"`
+	ExpireAt      *time.Time                 `json:"-" firestore:"expire_at,omitempty"`
+	CreatedAt     time.Time                  `json:"created_at" firestore:"created_at,serverTimestamp"`
+	SchemaVersion int                        `json:"schema_version" firestore:"schema_version"`
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// MaxOrganizationNameLength is the maximum length of an organization name.
+const MaxOrganizationNameLength = 100
+
+// Organization is a team of users that can own documents together.
+// Access to an organization and its documents is granted through a Membership.
+type Organization struct {
+	ID            string    `json:"id" firestore:"id"`
+	Name          string    `json:"name" firestore:"name"`
+	CreatedBy     string    `json:"created_by" firestore:"created_by"`
+	CreatedAt     time.Time `json:"created_at" firestore:"created_at,serverTimestamp"`
+	UpdatedAt     time.Time `json:"updated_at" firestore:"updated_at,serverTimestamp"`
+	SchemaVersion int       `json:"schema_version" firestore:"schema_version"`
+}
+
+// Validate checks that the organization has a usable name.
+func (o *Organization) Validate() error {
+	verr := &ValidationError{}
+
+	name := strings.TrimSpace(o.Name)
+	switch {
+	case name == "":
+		verr.Add("name", "is required")
+	case len(name) > MaxOrganizationNameLength:
+		verr.Add("name", "must be at most 100 characters")
+	}
+
+	return verr.Err()
+}
+
+// Role is the level of access a member has in an organization.
+type Role string
+
+const (
+	// RoleMember can read and upload the organization's documents.
+	RoleMember Role = "member"
+	// RoleAdmin can also delete documents, manage members and rename the organization.
+	RoleAdmin Role = "admin"
+	// RoleOwner can also delete the organization and change other admins.
+	RoleOwner Role = "owner"
+)
+
+// roleRank orders the roles, a higher rank includes every permission of the lower ranks.
+var roleRank = map[Role]int{
+	RoleMember: 1,
+	RoleAdmin:  2,
+	RoleOwner:  3,
+}
+
+// Valid reports whether r is a known role.
+func (r Role) Valid() bool {
+	_, ok := roleRank[r]
+
+	return ok
+}
+
+// AtLeast reports whether r grants at least the permissions of min.
+func (r Role) AtLeast(min Role) bool {
+	return r.Valid() && roleRank[r] >= roleRank[min]
+}
+
+// Membership links a user, by Firebase UID, to an organization with a role.
+// The membership ID is derived from both, see MembershipID, so a user is a member at most once.
+type Membership struct {
+	ID             string    `json:"id" firestore:"id"`
+	OrganizationID string    `json:"organization_id" firestore:"organization_id"`
+	UserID         string    `json:"user_id" firestore:"user_id"`
+	Role           Role      `json:"role" firestore:"role"`
+	CreatedAt      time.Time `json:"created_at" firestore:"created_at,serverTimestamp"`
+	UpdatedAt      time.Time `json:"updated_at" firestore:"updated_at,serverTimestamp"`
+	SchemaVersion  int       `json:"schema_version" firestore:"schema_version"`
+}
+
+// MembershipID returns the document ID of a user's membership in an organization.
+func MembershipID(organizationID, userID string) string {
+	return organizationID + "_" + userID
+}
+
+// Validate checks that the membership references a user and has a known role.
+func (m *Membership) Validate() error {
+	verr := &ValidationError{}
+
+	if m.OrganizationID == "" {
+		verr.Add("organization_id", "is required")
+	}
+	if m.UserID == "" {
+		verr.Add("user_id", "is required")
+	}
+	if !m.Role.Valid() {
+		verr.Add("role", "must be one of member, admin, owner")
+	}
+
+	return verr.Err()
+}
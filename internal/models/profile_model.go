@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// Profile is the aggregated view of the authenticated user returned by GET /v1/profile,
+// so the frontend can render the account page in a single round-trip.
+type Profile struct {
+	User      *User             `json:"user"`
+	Documents []DocumentSummary `json:"documents"`
+	Quota     DocumentQuota     `json:"quota"`
+	MFA       MFAStatus         `json:"mfa"`
+}
+
+// MFAStatus is the multi-factor authentication status of a user's Firebase account.
+// Verified reports whether the current session was signed in with a second factor, which
+// routes enforcing MFA require.
+type MFAStatus struct {
+	Enrolled bool        `json:"enrolled"`
+	Verified bool        `json:"verified"`
+	Factors  []MFAFactor `json:"factors"`
+}
+
+// MFAFactor is a second factor enrolled on a Firebase account.
+type MFAFactor struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	DisplayName string    `json:"display_name,omitempty"`
+	EnrolledAt  time.Time `json:"enrolled_at"`
+}
+
+// DocumentSummary is the subset of a Document shown in listings.
+type DocumentSummary struct {
+	ID               string         `json:"id"`
+	Name             string         `json:"name"`
+	OriginalFilename string         `json:"original_filename,omitempty"`
+	DisplayName      string         `json:"display_name,omitempty"`
+	Type             DocumentType   `json:"type"`
+	Size             int64          `json:"size"`
+	Status           DocumentStatus `json:"status,omitempty"`
+	BundleID         string         `json:"bundle_id,omitempty"`
+	ExpiryDate       *time.Time     `json:"expiry_date,omitempty"`
+	CreatedAt        time.Time      `json:"created_at"`
+}
+
+// DocumentQuota is a user's document usage against the tenant's quota.
+// Limit is 0 when the tenant sets no quota.
+type DocumentQuota struct {
+	Used  int64 `json:"used"`
+	Limit int   `json:"limit"`
+}
+
+// Summary returns the DocumentSummary of the document.
+func (d *Document) Summary() DocumentSummary {
+	return DocumentSummary{
+		ID:               d.ID,
+		Name:             d.Name,
+		OriginalFilename: d.OriginalFilename,
+		DisplayName:      d.DisplayName,
+		Type:             d.Type,
+		Size:             d.Size,
+		Status:           d.Status,
+		BundleID:         d.BundleID,
+		ExpiryDate:       d.ExpiryDate,
+		CreatedAt:        d.CreatedAt,
+	}
+}
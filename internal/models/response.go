@@ -0,0 +1,43 @@
+package models
+
+// Envelope is the standard response body returned by every successful endpoint.
+// Handlers build it with gin.H; this type documents the shape for published schemas.
+type Envelope[T any] struct {
+	Data    T      `json:"data,omitempty"`
+	Message string `json:"message"`
+	Status  int    `json:"status"`
+}
+
+// ErrorEnvelope is the standard response body returned when a request fails.
+// Fields is only present for validation errors.
+type ErrorEnvelope struct {
+	Error   string       `json:"error"`
+	Message string       `json:"message"`
+	Status  int          `json:"status"`
+	Fields  []FieldError `json:"fields,omitempty"`
+}
+
+// Page is the response data returned by every list endpoint.
+// NextPageToken is empty on the last page. TotalCount is only set when the
+// client asked for it, since counting requires an extra aggregation query. It is
+// best effort: it may lag recent changes by a few seconds and is left out when
+// the count fails, so clients can show "page 2 of 14" but not rely on it.
+type Page[T any] struct {
+	Items         []T    `json:"items"`
+	NextPageToken string `json:"next_page_token,omitempty"`
+	PageSize      int    `json:"page_size"`
+	TotalCount    *int64 `json:"total_count,omitempty"`
+}
+
+// NewPage creates a Page, making sure Items is never null in the JSON output.
+func NewPage[T any](items []T, nextPageToken string, pageSize int) *Page[T] {
+	if items == nil {
+		items = []T{}
+	}
+
+	return &Page[T]{
+		Items:         items,
+		NextPageToken: nextPageToken,
+		PageSize:      pageSize,
+	}
+}
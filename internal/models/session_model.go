@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// SessionRevocation records when the sessions of a user were last revoked. ID tokens issued
+// before TokensValidAfter are rejected, so a stolen token stops working immediately instead of
+// at its expiry. UserID is the user's Firebase ID.
+// Revocations are checked before the tenant of a request is known, so they are kept outside the
+// tenant-scoped collections and record their tenant instead. They are deleted at ExpireAt,
+// once every token they reject has expired.
+type SessionRevocation struct {
+	ID               string     `json:"id" firestore:"id"`
+	UserID           string     `json:"user_id" firestore:"user_id"`
+	TenantID         string     `json:"tenant_id,omitempty" firestore:"tenant_id"`
+	TokensValidAfter time.Time  `json:"tokens_valid_after" firestore:"tokens_valid_after"`
+	ExpireAt         *time.Time `json:"-" firestore:"expire_at,omitempty"`
+	UpdatedAt        time.Time  `json:"updated_at" firestore:"updated_at,serverTimestamp"`
+	SchemaVersion    int        `json:"schema_version" firestore:"schema_version"`
+}
+
+// SessionRevocationID returns the ID of the revocation document of a user, Firebase IDs are only
+// unique within a tenant.
+func SessionRevocationID(tenantID string, userID string) string {
+	if tenantID == "" {
+		return userID
+	}
+
+	return tenantID + "_" + userID
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// MaxWebhookURLs is the maximum number of webhook URLs a tenant can register.
+const MaxWebhookURLs = 10
+
+// DefaultTenantSettingsID is the settings document used when multi-tenancy is disabled.
+const DefaultTenantSettingsID = "default"
+
+// TenantSettings is the runtime configuration of a tenant, so per-customer behaviour can change
+// without a redeploy. Zero values mean "no restriction" or "use the service default".
+type TenantSettings struct {
+	ID string `json:"id" firestore:"id"`
+	// AllowedDocumentTypes restricts uploads to these types, every type is allowed when empty.
+	AllowedDocumentTypes []DocumentType `json:"allowed_document_types,omitempty" firestore:"allowed_document_types,omitempty"`
+	// MaxDocumentsPerUser caps how many documents a user can store.
+	MaxDocumentsPerUser int `json:"max_documents_per_user,omitempty" firestore:"max_documents_per_user,omitempty"`
+	// RetentionDays overrides the retention of every document type for the tenant.
+	RetentionDays int `json:"retention_days,omitempty" firestore:"retention_days,omitempty"`
+	// WebhookURLs receive event notifications for the tenant.
+	WebhookURLs   []string  `json:"webhook_urls,omitempty" firestore:"webhook_urls,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at" firestore:"updated_at,serverTimestamp"`
+	SchemaVersion int       `json:"schema_version" firestore:"schema_version"`
+}
+
+// Validate checks quotas are not negative and webhook URLs are absolute HTTPS URLs.
+func (s *TenantSettings) Validate() error {
+	verr := &ValidationError{}
+
+	if s.MaxDocumentsPerUser < 0 {
+		verr.Add("max_documents_per_user", "must not be negative")
+	}
+	if s.RetentionDays < 0 {
+		verr.Add("retention_days", "must not be negative")
+	}
+	if len(s.WebhookURLs) > MaxWebhookURLs {
+		verr.Add("webhook_urls", fmt.Sprintf("must have at most %d entries", MaxWebhookURLs))
+	}
+	for i, raw := range s.WebhookURLs {
+		u, err := url.Parse(raw)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			verr.Add(fmt.Sprintf("webhook_urls[%d]", i), "must be an absolute https URL")
+		}
+	}
+
+	return verr.Err()
+}
+
+// AllowsDocumentType reports whether the tenant accepts uploads of the given type.
+func (s *TenantSettings) AllowsDocumentType(t DocumentType) bool {
+	if len(s.AllowedDocumentTypes) == 0 {
+		return true
+	}
+
+	for _, allowed := range s.AllowedDocumentTypes {
+		if allowed == t {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,17 +1,61 @@
 package models
 
-import "time"
+import (
+	"net/mail"
+	"time"
 
+	"golang.org/x/text/language"
+)
+
+const (
+	// DefaultLocale is used when a user has not set a locale.
+	DefaultLocale = "en-GB"
+	// DefaultTimezone is used when a user has not set a timezone.
+	DefaultTimezone = "Europe/London"
+)
+
+// User is a registered user of the portal.
+// Phone is stored in E.164 format with PhoneRegion set to the detected ISO 3166-1 region.
+// Locale and PreferredLanguage are BCP 47 tags and Timezone is an IANA timezone name;
+// they drive date formatting and the language notifications are rendered in.
+// NotificationPreferences turn notification channels off, every channel is on by default.
+// When duplicate accounts are merged the duplicate is soft-deleted with DeletedAt and points to
+// the surviving user with MergedInto, the survivor lists the Firebase IDs merged into it.
+// Avatar is set by uploading a profile picture, see AvatarSizes. Claims mirror the custom claims
+// set on the Firebase account by administrators.
+// Fields tagged encrypt:"true" are stored encrypted when field encryption is enabled.
 type User struct {
-	ID         string    `json:"id" firestore:"id"`
-	FirstName  string    `json:"first_name" firestore:"first_name"`
-	LastName   string    `json:"last_name" firestore:"last_name"`
-	Email      string    `json:"email" firestore:"email"`
-	Phone      string    `json:"phone" firestore:"phone"`
-	Address    Address   `json:"address" firestore:"address"`
-	FirebaseID string    `json:"firebase_id" firestore:"firebase_id"`
-	CreatedAt  time.Time `json:"created_at" firestore:"created_at,serverTimestamp"`
-	UpdatedAt  time.Time `json:"updated_at" firestore:"updated_at,serverTimestamp"`
+	ID                string    `json:"id" firestore:"id"`
+	FirstName         string    `json:"first_name" firestore:"first_name"`
+	LastName          string    `json:"last_name" firestore:"last_name"`
+	Email             string    `json:"email" firestore:"email"`
+	Phone             string    `json:"phone" firestore:"phone" encrypt:"true"`
+	PhoneRegion       string    `json:"phone_region,omitempty" firestore:"phone_region,omitempty"`
+	Address           Address   `json:"address" firestore:"address" encrypt:"true"`
+	FirebaseID        string    `json:"firebase_id" firestore:"firebase_id"`
+	Locale            string    `json:"locale" firestore:"locale"`
+	Timezone          string    `json:"timezone" firestore:"timezone"`
+	PreferredLanguage string    `json:"preferred_language" firestore:"preferred_language"`
+	CreatedAt         time.Time `json:"created_at" firestore:"created_at,serverTimestamp"`
+	UpdatedAt         time.Time `json:"updated_at" firestore:"updated_at,serverTimestamp"`
+	SchemaVersion     int       `json:"schema_version" firestore:"schema_version"`
+
+	NotificationPreferences NotificationPreferences `json:"notification_preferences,omitempty" firestore:"notification_preferences,omitempty"`
+	MergedInto              string                  `json:"merged_into,omitempty" firestore:"merged_into,omitempty"`
+	MergedFirebaseIDs       []string                `json:"merged_firebase_ids,omitempty" firestore:"merged_firebase_ids,omitempty"`
+	DeletedAt               *time.Time              `json:"deleted_at,omitempty" firestore:"deleted_at,omitempty"`
+	Avatar                  *Avatar                 `json:"avatar,omitempty" firestore:"avatar,omitempty"`
+	Claims                  *UserClaims             `json:"claims,omitempty" firestore:"claims,omitempty"`
+}
+
+// AvatarSizes are the widths in pixels of the square WebP variants generated from a profile picture.
+var AvatarSizes = []int{32, 128, 512}
+
+// Avatar is the profile picture of a user. URLs maps each of the AvatarSizes to the API path
+// serving that variant, the paths change with every upload so clients can cache them.
+type Avatar struct {
+	URLs      map[string]string `json:"urls" firestore:"urls"`
+	UpdatedAt time.Time         `json:"updated_at" firestore:"updated_at"`
 }
 
 type Address struct {
@@ -20,4 +64,120 @@ type Address struct {
 	City           string `json:"city" firestore:"city"`
 	PostCode       string `json:"postcode" firestore:"postcode"`
 	Country        string `json:"country" firestore:"country"`
+	// Coordinates are set by address validation when geocoding is enabled.
+	Coordinates *Coordinates `json:"coordinates,omitempty" firestore:"coordinates,omitempty"`
+}
+
+// Coordinates is a geographic position in decimal degrees.
+type Coordinates struct {
+	Latitude  float64 `json:"latitude" firestore:"latitude"`
+	Longitude float64 `json:"longitude" firestore:"longitude"`
+}
+
+// IsZero reports whether no address fields have been set.
+func (a *Address) IsZero() bool {
+	return a.BuildingNumber == "" && a.Street == "" && a.City == "" && a.PostCode == "" && a.Country == ""
+}
+
+// Validate checks that the required user fields are set and well-formed.
+// It returns a *ValidationError describing every invalid field, or nil if the user is valid.
+func (u *User) Validate() error {
+	verr := &ValidationError{}
+
+	if u.FirstName == "" {
+		verr.Add("first_name", "is required")
+	}
+
+	if u.LastName == "" {
+		verr.Add("last_name", "is required")
+	}
+
+	if u.Email == "" {
+		verr.Add("email", "is required")
+	} else if _, err := mail.ParseAddress(u.Email); err != nil {
+		verr.Add("email", "must be a valid email address")
+	}
+
+	if u.FirebaseID == "" {
+		verr.Add("firebase_id", "is required")
+	}
+
+	if u.Locale != "" {
+		if _, err := language.Parse(u.Locale); err != nil {
+			verr.Add("locale", "must be a valid BCP 47 locale, e.g. en-GB")
+		}
+	}
+
+	if u.Timezone != "" {
+		if _, err := time.LoadLocation(u.Timezone); err != nil {
+			verr.Add("timezone", "must be a valid IANA timezone, e.g. Europe/London")
+		}
+	}
+
+	if u.PreferredLanguage != "" {
+		if _, err := language.Parse(u.PreferredLanguage); err != nil {
+			verr.Add("preferred_language", "must be a valid BCP 47 language tag, e.g. en")
+		}
+	}
+
+	u.NotificationPreferences.validate(verr)
+
+	return verr.Err()
+}
+
+// Location returns the user's timezone, falling back to DefaultTimezone
+// if none is set or it can no longer be loaded.
+func (u *User) Location() *time.Location {
+	if u.Timezone != "" {
+		if loc, err := time.LoadLocation(u.Timezone); err == nil {
+			return loc
+		}
+	}
+
+	loc, err := time.LoadLocation(DefaultTimezone)
+	if err != nil {
+		return time.UTC
+	}
+
+	return loc
+}
+
+// Language returns the language notifications should be rendered in.
+// It prefers PreferredLanguage, then the language of the locale, then the default locale.
+func (u *User) Language() language.Tag {
+	for _, candidate := range []string{u.PreferredLanguage, u.Locale} {
+		if candidate == "" {
+			continue
+		}
+
+		if tag, err := language.Parse(candidate); err == nil {
+			base, _ := tag.Base()
+
+			return language.Make(base.String())
+		}
+	}
+
+	return language.BritishEnglish
+}
+
+// FormatDate formats t as a date in the user's timezone using the
+// day/month ordering conventional for the user's locale.
+func (u *User) FormatDate(t time.Time) string {
+	locale := u.Locale
+	if locale == "" {
+		locale = DefaultLocale
+	}
+
+	layout := "02/01/2006"
+	if tag, err := language.Parse(locale); err == nil {
+		region, _ := tag.Region()
+		switch region.String() {
+		case "US", "PH", "CA":
+			layout = "01/02/2006"
+		case "CN", "JP", "KR", "TW", "HU", "LT", "SE":
+			layout = "2006-01-02"
+		}
+	}
+
+	return t.In(u.Location()).Format(layout)
 }
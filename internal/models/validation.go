@@ -0,0 +1,52 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Validator is implemented by models that can check their own invariants.
+// Services call Validate before a model is persisted so that invalid data
+// never reaches the database, regardless of which handler produced it.
+type Validator interface {
+	Validate() error
+}
+
+// FieldError describes a single validation failure on a model field.
+// Field uses the JSON name of the field so clients can map it back to their payload.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when a model fails validation.
+// It collects every field error found instead of stopping at the first one,
+// so clients can show all problems with a payload at once.
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+// Error implements the error interface and joins all field errors into a single message.
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		messages = append(messages, fmt.Sprintf("%s: %s", f.Field, f.Message))
+	}
+
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// Add records a validation failure for the given field.
+func (e *ValidationError) Add(field, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message})
+}
+
+// Err returns the ValidationError as an error if any field errors were recorded,
+// or nil otherwise. It allows validators to build up errors and return in one step.
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+
+	return e
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// WebhookNonce records a webhook delivery that has been received, so a replay of it is rejected.
+// The ID is derived from the source and the nonce of the delivery. Nonces are deleted at
+// ExpireAt, once the replay window of the delivery has passed.
+type WebhookNonce struct {
+	ID            string     `json:"id" firestore:"id"`
+	Source        string     `json:"source" firestore:"source"`
+	ExpireAt      *time.Time `json:"-" firestore:"expire_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at" firestore:"created_at,serverTimestamp"`
+	SchemaVersion int        `json:"schema_version" firestore:"schema_version"`
+}
@@ -0,0 +1,507 @@
+// Package offboarding exports all data of a tenant to a GCS prefix and optionally purges it,
+// for contract termination. The export holds one JSON Lines file per Firestore collection,
+// the tenant's audit log, a copy of every stored object and a manifest.json describing them.
+package offboarding
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+
+	"github.com/thoughtgears/shared-services/internal/audit"
+	"github.com/thoughtgears/shared-services/internal/fieldcrypt"
+	"github.com/thoughtgears/shared-services/internal/tenant"
+	"github.com/thoughtgears/shared-services/pkg/apperr"
+)
+
+// ErrLegalHold is returned when a purge is refused because documents of the tenant are under
+// legal hold. The export is complete, the purge can be retried once the holds are released.
+var ErrLegalHold = apperr.New(apperr.Conflict, "tenant has documents under legal hold")
+
+// legalHoldField is the field set on documents under legal hold, see models.Document.
+const legalHoldField = "legal_hold"
+
+// ManifestFileName is the name of the manifest written last to every export,
+// so its presence marks the export as complete.
+const ManifestFileName = "manifest.json"
+
+// Config describes where tenant data lives and where exports are written.
+type Config struct {
+	// SourceBuckets are all buckets holding tenant objects, e.g. one per residency region.
+	SourceBuckets []string
+	// ExportBucket receives the exports under ExportPrefix/<tenant>/<timestamp>/.
+	ExportBucket string
+	ExportPrefix string
+	// AuditCollection holds the audit events, filtered on tenant_id for the export.
+	AuditCollection string
+	// SettingsCollection holds the per-tenant settings documents, keyed by tenant ID.
+	SettingsCollection string
+	// ReadTime is the time the Firestore client reads the database at, when it was pinned with
+	// firestore.ReadTime for a point-in-time export. It is recorded in the manifest.
+	ReadTime time.Time
+}
+
+// ManifestFile describes a single file in an export.
+type ManifestFile struct {
+	Path    string `json:"path"`
+	Source  string `json:"source"`
+	Records int    `json:"records,omitempty"`
+	Size    int64  `json:"size"`
+	CRC32C  uint32 `json:"crc32c"`
+}
+
+// Manifest describes a completed export.
+type Manifest struct {
+	TenantID  string         `json:"tenant_id"`
+	CreatedAt time.Time      `json:"created_at"`
+	ReadTime  *time.Time     `json:"read_time,omitempty"`
+	Location  string         `json:"location"`
+	Files     []ManifestFile `json:"files"`
+	Purged    bool           `json:"purged"`
+
+	// prefix is the object path of the export in the export bucket
+	prefix string
+}
+
+// Exporter exports and purges tenant data.
+type Exporter struct {
+	firestore *firestore.Client
+	storage   *storage.Client
+	cipher    *fieldcrypt.Cipher
+	audit     *audit.Recorder
+	cfg       Config
+}
+
+// NewExporter creates an Exporter. When cipher is set, encrypted fields are decrypted
+// so the export is readable without access to the service's KMS key; it may be nil.
+func NewExporter(firestoreClient *firestore.Client, storageClient *storage.Client, cipher *fieldcrypt.Cipher, recorder *audit.Recorder, cfg Config) *Exporter {
+	return &Exporter{
+		firestore: firestoreClient,
+		storage:   storageClient,
+		cipher:    cipher,
+		audit:     recorder,
+		cfg:       cfg,
+	}
+}
+
+// Export writes all data of the tenant to a new export prefix and returns its manifest.
+// If purge is set the tenant's documents, settings and objects are deleted once the export is
+// complete, unless documents are under legal hold, see ErrLegalHold. Audit events are kept, they are the record of the offboarding itself.
+func (e *Exporter) Export(ctx context.Context, tenantID string, purge bool) (*Manifest, error) {
+	if err := tenant.Validate(tenantID); err != nil {
+		return nil, err
+	}
+
+	ctx = tenant.With(ctx, tenantID)
+	target := audit.Target{Type: "tenant", ID: tenantID}
+
+	manifest, err := e.export(ctx, tenantID)
+	details := map[string]string{}
+	if manifest != nil {
+		details["location"] = manifest.Location
+	}
+	e.audit.Record(ctx, audit.ActionTenantExport, target, err, details)
+	if err != nil {
+		return nil, err
+	}
+
+	if !purge {
+		return manifest, nil
+	}
+
+	err = e.purge(ctx, tenantID)
+	e.audit.Record(ctx, audit.ActionTenantPurge, target, err, details)
+	if err != nil {
+		return manifest, fmt.Errorf("export complete, purge failed: %w", err)
+	}
+
+	// The manifest is rewritten so it records that the source data is gone
+	manifest.Purged = true
+	if err := e.writeJSON(ctx, manifest.prefix, ManifestFileName, manifest); err != nil {
+		return manifest, fmt.Errorf("failed to update manifest after purge: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// export writes the tenant's collections, audit log and objects, then the manifest.
+func (e *Exporter) export(ctx context.Context, tenantID string) (*Manifest, error) {
+	now := time.Now().UTC()
+	location := path.Join(e.cfg.ExportPrefix, tenantID, now.Format("20060102T150405Z"))
+	manifest := &Manifest{
+		TenantID:  tenantID,
+		CreatedAt: now,
+		Location:  "gs://" + e.cfg.ExportBucket + "/" + location + "/",
+		prefix:    location,
+	}
+	if !e.cfg.ReadTime.IsZero() {
+		readTime := e.cfg.ReadTime.UTC()
+		manifest.ReadTime = &readTime
+	}
+
+	collections := e.firestore.Doc("tenants/" + tenantID).Collections(ctx)
+	for {
+		collection, err := collections.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tenant collections: %w", err)
+		}
+
+		files, err := e.exportCollection(ctx, location, "firestore", collection)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Files = append(manifest.Files, files...)
+	}
+
+	if e.cfg.SettingsCollection != "" {
+		query := e.firestore.Collection(e.cfg.SettingsCollection).Where(firestore.DocumentID, "==", e.firestore.Collection(e.cfg.SettingsCollection).Doc(tenantID))
+		file, err := e.exportQuery(ctx, location, "firestore/"+e.cfg.SettingsCollection+".jsonl", e.cfg.SettingsCollection, query)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Files = append(manifest.Files, file)
+	}
+
+	if e.cfg.AuditCollection != "" {
+		query := e.firestore.Collection(e.cfg.AuditCollection).Where("tenant_id", "==", tenantID)
+		file, err := e.exportQuery(ctx, location, "audit_logs.jsonl", e.cfg.AuditCollection, query)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Files = append(manifest.Files, file)
+	}
+
+	for _, bucket := range e.cfg.SourceBuckets {
+		files, err := e.copyObjects(ctx, location, bucket, "tenants/"+tenantID+"/")
+		if err != nil {
+			return nil, err
+		}
+		manifest.Files = append(manifest.Files, files...)
+	}
+
+	if err := e.writeJSON(ctx, location, ManifestFileName, manifest); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// exportCollection writes a collection and, recursively, the subcollections of its documents.
+func (e *Exporter) exportCollection(ctx context.Context, location, dir string, collection *firestore.CollectionRef) ([]ManifestFile, error) {
+	name := path.Join(dir, collection.ID)
+	file, err := e.exportQuery(ctx, location, name+".jsonl", collection.Path, collection.Query)
+	if err != nil {
+		return nil, err
+	}
+	files := []ManifestFile{file}
+
+	refs := collection.DocumentRefs(ctx)
+	for {
+		ref, err := refs.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list documents in %s: %w", collection.ID, err)
+		}
+
+		subcollections := ref.Collections(ctx)
+		for {
+			sub, err := subcollections.Next()
+			if errors.Is(err, iterator.Done) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to list subcollections of %s: %w", ref.ID, err)
+			}
+
+			subFiles, err := e.exportCollection(ctx, location, path.Join(name, ref.ID), sub)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, subFiles...)
+		}
+	}
+
+	return files, nil
+}
+
+// exportQuery writes every document matched by query as one JSON object per line.
+func (e *Exporter) exportQuery(ctx context.Context, location, name, source string, query firestore.Query) (ManifestFile, error) {
+	writer := e.storage.Bucket(e.cfg.ExportBucket).Object(path.Join(location, name)).NewWriter(ctx)
+	writer.ContentType = "application/x-ndjson"
+	encoder := json.NewEncoder(writer)
+
+	records := 0
+	docs := query.Documents(ctx)
+	defer docs.Stop()
+	for {
+		doc, err := docs.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			writer.CloseWithError(err)
+			return ManifestFile{}, fmt.Errorf("failed to read %s: %w", source, err)
+		}
+
+		record := fieldcrypt.Binding{Collection: collectionName(source), RecordID: doc.Ref.ID}
+		data, err := e.decrypt(ctx, record, doc.Data())
+		if err != nil {
+			writer.CloseWithError(err)
+			return ManifestFile{}, fmt.Errorf("failed to decrypt %s/%s: %w", source, doc.Ref.ID, err)
+		}
+		if err := encoder.Encode(map[string]any{"id": doc.Ref.ID, "data": data}); err != nil {
+			writer.CloseWithError(err)
+			return ManifestFile{}, fmt.Errorf("failed to write %s: %w", name, err)
+		}
+		records++
+	}
+
+	if err := writer.Close(); err != nil {
+		return ManifestFile{}, fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	return ManifestFile{
+		Path:    name,
+		Source:  source,
+		Records: records,
+		Size:    writer.Attrs().Size,
+		CRC32C:  writer.Attrs().CRC32C,
+	}, nil
+}
+
+// copyObjects copies every object under prefix in bucket into the export.
+func (e *Exporter) copyObjects(ctx context.Context, location, bucket, prefix string) ([]ManifestFile, error) {
+	var files []ManifestFile
+
+	it := e.storage.Bucket(bucket).Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects in %s: %w", bucket, err)
+		}
+
+		name := path.Join("objects", bucket, strings.TrimPrefix(attrs.Name, prefix))
+		dst := e.storage.Bucket(e.cfg.ExportBucket).Object(path.Join(location, name))
+		copied, err := dst.CopierFrom(e.storage.Bucket(bucket).Object(attrs.Name)).Run(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy gs://%s/%s: %w", bucket, attrs.Name, err)
+		}
+
+		files = append(files, ManifestFile{
+			Path:   name,
+			Source: "gs://" + bucket + "/" + attrs.Name,
+			Size:   copied.Size,
+			CRC32C: copied.CRC32C,
+		})
+	}
+
+	return files, nil
+}
+
+// writeJSON writes value as an indented JSON object to name under the export location.
+func (e *Exporter) writeJSON(ctx context.Context, location, name string, value any) error {
+	writer := e.storage.Bucket(e.cfg.ExportBucket).Object(path.Join(location, name)).NewWriter(ctx)
+	writer.ContentType = "application/json"
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		writer.CloseWithError(err)
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// decrypt returns value, stored at binding, with every encrypted string replaced by its plaintext.
+func (e *Exporter) decrypt(ctx context.Context, binding fieldcrypt.Binding, value any) (any, error) {
+	if e.cipher == nil {
+		return value, nil
+	}
+
+	switch v := value.(type) {
+	case string:
+		return e.cipher.DecryptString(ctx, v, binding)
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			field := binding
+			field.Field = strings.TrimPrefix(binding.Field+"."+key, ".")
+			decrypted, err := e.decrypt(ctx, field, item)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = decrypted
+		}
+
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			decrypted, err := e.decrypt(ctx, binding, item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = decrypted
+		}
+
+		return out, nil
+	default:
+		return value, nil
+	}
+}
+
+// purge deletes the tenant's Firestore data, settings and stored objects. Nothing is deleted
+// while any of the tenant's documents is under legal hold.
+func (e *Exporter) purge(ctx context.Context, tenantID string) error {
+	tenantDoc := e.firestore.Doc("tenants/" + tenantID)
+	held, err := e.heldDocuments(ctx, tenantDoc)
+	if err != nil {
+		return err
+	}
+	if len(held) > 0 {
+		return fmt.Errorf("%w: %d held, e.g. %s", ErrLegalHold, len(held), strings.Join(held[:min(len(held), 5)], ", "))
+	}
+
+	bulk := e.firestore.BulkWriter(ctx)
+
+	refs, err := e.documentRefs(ctx, tenantDoc)
+	if err != nil {
+		bulk.End()
+		return err
+	}
+	refs = append(refs, tenantDoc)
+	if e.cfg.SettingsCollection != "" {
+		refs = append(refs, e.firestore.Collection(e.cfg.SettingsCollection).Doc(tenantID))
+	}
+
+	jobs := make([]*firestore.BulkWriterJob, 0, len(refs))
+	for _, ref := range refs {
+		job, err := bulk.Delete(ref)
+		if err != nil {
+			bulk.End()
+			return fmt.Errorf("failed to delete %s: %w", ref.Path, err)
+		}
+		jobs = append(jobs, job)
+	}
+	bulk.End()
+
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return fmt.Errorf("failed to delete tenant documents: %w", err)
+		}
+	}
+
+	for _, bucket := range e.cfg.SourceBuckets {
+		it := e.storage.Bucket(bucket).Objects(ctx, &storage.Query{Prefix: "tenants/" + tenantID + "/"})
+		for {
+			attrs, err := it.Next()
+			if errors.Is(err, iterator.Done) {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("failed to list objects in %s: %w", bucket, err)
+			}
+
+			if err := e.storage.Bucket(bucket).Object(attrs.Name).Delete(ctx); err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+				return fmt.Errorf("failed to delete gs://%s/%s: %w", bucket, attrs.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// heldDocuments returns the paths of the documents under legal hold in the collections of parent.
+// Documents are only held in the tenant's top level collections, so nested ones are not queried.
+func (e *Exporter) heldDocuments(ctx context.Context, parent *firestore.DocumentRef) ([]string, error) {
+	var held []string
+
+	collections := parent.Collections(ctx)
+	for {
+		collection, err := collections.Next()
+		if errors.Is(err, iterator.Done) {
+			return held, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list collections of %s: %w", parent.Path, err)
+		}
+
+		docs := collection.Where(legalHoldField, "!=", nil).Select().Documents(ctx)
+		for {
+			doc, err := docs.Next()
+			if errors.Is(err, iterator.Done) {
+				break
+			}
+			if err != nil {
+				docs.Stop()
+				return nil, fmt.Errorf("failed to list held documents in %s: %w", collection.ID, err)
+			}
+			held = append(held, path.Join(collection.ID, doc.Ref.ID))
+		}
+		docs.Stop()
+	}
+}
+
+// documentRefs returns every document below parent, nested documents before their parents.
+func (e *Exporter) documentRefs(ctx context.Context, parent *firestore.DocumentRef) ([]*firestore.DocumentRef, error) {
+	var refs []*firestore.DocumentRef
+
+	collections := parent.Collections(ctx)
+	for {
+		collection, err := collections.Next()
+		if errors.Is(err, iterator.Done) {
+			return refs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list collections of %s: %w", parent.Path, err)
+		}
+
+		docs := collection.DocumentRefs(ctx)
+		for {
+			ref, err := docs.Next()
+			if errors.Is(err, iterator.Done) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to list documents in %s: %w", collection.Path, err)
+			}
+
+			nested, err := e.documentRefs(ctx, ref)
+			if err != nil {
+				return nil, err
+			}
+			refs = append(refs, nested...)
+			refs = append(refs, ref)
+		}
+	}
+}
+
+// collectionName returns the path of a collection relative to the database, as the repositories
+// name it when binding encrypted fields, e.g. tenants/acme/users.
+func collectionName(source string) string {
+	if _, name, ok := strings.Cut(source, "/documents/"); ok {
+		return name
+	}
+
+	return source
+}
@@ -0,0 +1,185 @@
+package pdf
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// filePage is a page of a File, copied into the written PDF.
+type filePage struct {
+	file  *File
+	index int
+}
+
+// imported tracks the objects of a file copied into the written PDF.
+type imported struct {
+	// ids maps object numbers in the file to those written
+	ids map[int]int
+	// queue holds the file's object numbers allocated but not written yet
+	queue []int
+}
+
+// reserve maps the page object to its written number, so references to it point to the copy.
+func (fp filePage) reserve(p *writer, id int) {
+	if page := fp.file.pages[fp.index]; page.id != 0 {
+		p.imported(fp.file).ids[page.id] = id
+	}
+}
+
+// write copies the page with its inherited attributes, and every object it refers to.
+func (fp filePage) write(p *writer, id int) error {
+	page := dict{"Type": name("Page"), "Parent": ref{id: 2}}
+	for key, value := range fp.file.pages[fp.index].dict {
+		if key == "Type" || key == "Parent" {
+			continue
+		}
+		page[key] = p.copyValue(fp.file, value)
+	}
+	p.object(id, string(appendValue(nil, page)))
+
+	return p.flush(fp.file)
+}
+
+// imported returns the copy state of a file.
+func (p *writer) imported(f *File) *imported {
+	if p.imports == nil {
+		p.imports = make(map[*File]*imported)
+	}
+	state, ok := p.imports[f]
+	if !ok {
+		state = &imported{ids: make(map[int]int)}
+		p.imports[f] = state
+	}
+
+	return state
+}
+
+// copyValue returns value with the references to objects of f replaced by references to their
+// copies, which are queued to be written by flush.
+func (p *writer) copyValue(f *File, value any) any {
+	switch v := value.(type) {
+	case ref:
+		return p.importRef(f, v.id)
+	case dict:
+		out := make(dict, len(v))
+		for key, item := range v {
+			out[key] = p.copyValue(f, item)
+		}
+
+		return out
+	case array:
+		out := make(array, len(v))
+		for i, item := range v {
+			out[i] = p.copyValue(f, item)
+		}
+
+		return out
+	default:
+		return value
+	}
+}
+
+// importRef returns the reference to the copy of object id of f. Pages that are not copied and
+// the page tree of f become null, so copying a page does not pull in the rest of its file.
+func (p *writer) importRef(f *File, id int) any {
+	state := p.imported(f)
+	if copied, ok := state.ids[id]; ok {
+		return ref{id: copied}
+	}
+	if f.pageIDs[id] || f.nodeIDs[id] {
+		return nil
+	}
+
+	copied := p.alloc()
+	state.ids[id] = copied
+	state.queue = append(state.queue, id)
+
+	return ref{id: copied}
+}
+
+// flush writes the queued objects of f, and the objects they refer to in turn.
+func (p *writer) flush(f *File) error {
+	state := p.imported(f)
+	for len(state.queue) > 0 {
+		id := state.queue[0]
+		state.queue = state.queue[1:]
+
+		obj, err := f.object(id)
+		if err != nil {
+			return err
+		}
+
+		copied := state.ids[id]
+		if s, ok := obj.(*stream); ok {
+			d := make(dict, len(s.dict))
+			for key, value := range s.dict {
+				if key != "Length" {
+					d[key] = p.copyValue(f, value)
+				}
+			}
+			d["Length"] = int64(len(s.data))
+			p.stream(copied, string(appendValue(nil, d)), s.data)
+
+			continue
+		}
+		p.object(copied, string(appendValue(nil, p.copyValue(f, obj))))
+	}
+
+	return nil
+}
+
+// appendValue appends the PDF syntax of an object to b.
+func appendValue(b []byte, value any) []byte {
+	switch v := value.(type) {
+	case nil:
+		return append(b, "null"...)
+	case bool:
+		return strconv.AppendBool(b, v)
+	case int64:
+		return strconv.AppendInt(b, v, 10)
+	case float64:
+		return strconv.AppendFloat(b, v, 'f', -1, 64)
+	case name:
+		b = append(b, '/')
+		for i := 0; i < len(v); i++ {
+			c := v[i]
+			if c < '!' || c > '~' || c == '#' || isDelimiter(c) {
+				b = fmt.Appendf(b, "#%02x", c)
+
+				continue
+			}
+			b = append(b, c)
+		}
+
+		return b
+	case str:
+		return fmt.Appendf(b, "<%x>", []byte(v))
+	case ref:
+		return fmt.Appendf(b, "%d 0 R", v.id)
+	case array:
+		b = append(b, '[')
+		for i, item := range v {
+			if i > 0 {
+				b = append(b, ' ')
+			}
+			b = appendValue(b, item)
+		}
+
+		return append(b, ']')
+	case dict:
+		// Keys are sorted so the same input always gives the same file
+		b = append(b, "<<"...)
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			b = append(b, ' ')
+			b = appendValue(b, key)
+			b = append(b, ' ')
+			b = appendValue(b, v[key])
+		}
+
+		return append(b, " >>"...)
+	default:
+		panic(fmt.Sprintf("pdf: cannot write %T", value))
+	}
+}
@@ -0,0 +1,165 @@
+// Package pdf writes minimal PDF files from images and the pages of other PDFs, e.g. to combine
+// photos and scans of ID documents into a single file that can be submitted to third parties.
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// A4 page size in points, the unit of PDF page coordinates.
+const (
+	a4Width  = 595.28
+	a4Height = 841.89
+	margin   = 24
+)
+
+// Image is a baseline JPEG with three color components (RGB or YCbCr), as written by image/jpeg.
+type Image struct {
+	JPEG   []byte
+	Width  int
+	Height int
+}
+
+// Page is a page of a PDF written by Write: an Image, or a page of a File read with Read.
+type Page interface {
+	// reserve is called with the object number of every page before any is written
+	reserve(p *writer, id int)
+	// write writes the page object id and the objects it uses
+	write(p *writer, id int) error
+}
+
+// Write writes a PDF with the pages in order. Images are placed on A4 pages, see Image, pages
+// read from other PDFs are copied with the objects they use.
+func Write(w io.Writer, pages []Page) error {
+	if len(pages) == 0 {
+		return errors.New("failed to write PDF: no pages")
+	}
+
+	// Objects 1 and 2 are the catalog and the page tree, the pages are numbered next so that
+	// pages referring to each other, e.g. with links, can be copied
+	p := &writer{next: 3}
+	p.printf("%%PDF-1.7\n%%\xe2\xe3\xcf\xd3\n")
+
+	pageIDs := make([]int, len(pages))
+	for i, page := range pages {
+		pageIDs[i] = p.alloc()
+		page.reserve(p, pageIDs[i])
+	}
+
+	p.object(1, "<< /Type /Catalog /Pages 2 0 R >>")
+
+	var kids bytes.Buffer
+	for _, id := range pageIDs {
+		fmt.Fprintf(&kids, "%d 0 R ", id)
+	}
+	p.object(2, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", bytes.TrimSpace(kids.Bytes()), len(pages)))
+
+	for i, page := range pages {
+		if err := page.write(p, pageIDs[i]); err != nil {
+			return fmt.Errorf("failed to write PDF: page %d: %w", i+1, err)
+		}
+	}
+
+	p.trailer()
+
+	if _, err := w.Write(p.buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write PDF: %w", err)
+	}
+
+	return nil
+}
+
+func (img Image) reserve(*writer, int) {}
+
+// write places the image on an A4 page, scaled to fit keeping its aspect ratio and centered.
+// Landscape images are placed on landscape pages.
+func (img Image) write(p *writer, pageID int) error {
+	if img.Width <= 0 || img.Height <= 0 || len(img.JPEG) == 0 {
+		return errors.New("image is empty")
+	}
+
+	imageID, contentID := p.alloc(), p.alloc()
+
+	p.stream(imageID, fmt.Sprintf(
+		"<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /DCTDecode /Length %d >>",
+		img.Width, img.Height, len(img.JPEG),
+	), img.JPEG)
+
+	pageWidth, pageHeight := a4Width, a4Height
+	if img.Width > img.Height {
+		pageWidth, pageHeight = a4Height, a4Width
+	}
+
+	scale := min((pageWidth-2*margin)/float64(img.Width), (pageHeight-2*margin)/float64(img.Height))
+	width, height := float64(img.Width)*scale, float64(img.Height)*scale
+	x, y := (pageWidth-width)/2, (pageHeight-height)/2
+
+	content := fmt.Appendf(nil, "q %.2f 0 0 %.2f %.2f %.2f cm /Im0 Do Q\n", width, height, x, y)
+	p.stream(contentID, fmt.Sprintf("<< /Length %d >>", len(content)), content)
+
+	p.object(pageID, fmt.Sprintf(
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /XObject << /Im0 %d 0 R >> >> /Contents %d 0 R >>",
+		pageWidth, pageHeight, imageID, contentID,
+	))
+
+	return nil
+}
+
+// writer builds a PDF file and records the offset of every object for the cross-reference table.
+type writer struct {
+	buf     bytes.Buffer
+	offsets map[int]int
+	// next is the next free object number
+	next int
+	// imports maps the object numbers of every file pages are copied from to the written ones
+	imports map[*File]*imported
+}
+
+// alloc returns a new object number.
+func (p *writer) alloc() int {
+	id := p.next
+	p.next++
+
+	return id
+}
+
+func (p *writer) printf(format string, args ...any) {
+	fmt.Fprintf(&p.buf, format, args...)
+}
+
+// object writes an indirect object with the given dictionary.
+func (p *writer) object(id int, dict string) {
+	p.begin(id)
+	p.printf("%s\nendobj\n", dict)
+}
+
+// stream writes an indirect stream object with the given dictionary and data.
+func (p *writer) stream(id int, dict string, data []byte) {
+	p.begin(id)
+	p.printf("%s\nstream\n", dict)
+	p.buf.Write(data)
+	p.printf("\nendstream\nendobj\n")
+}
+
+func (p *writer) begin(id int) {
+	if p.offsets == nil {
+		p.offsets = make(map[int]int)
+	}
+	p.offsets[id] = p.buf.Len()
+	p.printf("%d 0 obj\n", id)
+}
+
+// trailer writes the cross-reference table and the trailer pointing at the catalog.
+func (p *writer) trailer() {
+	size := p.next
+	start := p.buf.Len()
+
+	p.printf("xref\n0 %d\n0000000000 65535 f \n", size)
+	for id := 1; id < size; id++ {
+		p.printf("%010d 00000 n \n", p.offsets[id])
+	}
+	p.printf("trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", size, start)
+}
@@ -0,0 +1,215 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"testing"
+)
+
+func testImage(t *testing.T, width, height int) Image {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height)), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	return Image{JPEG: buf.Bytes(), Width: width, Height: height}
+}
+
+func TestWriteImportedPages(t *testing.T) {
+	var source bytes.Buffer
+	if err := Write(&source, []Page{testImage(t, 20, 10), testImage(t, 10, 20)}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	file, err := Read(source.Bytes())
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if got := len(file.Pages()); got != 2 {
+		t.Fatalf("Read() pages = %d, want 2", got)
+	}
+
+	var merged bytes.Buffer
+	if err := Write(&merged, append([]Page{testImage(t, 10, 10)}, file.Pages()...)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	file, err = Read(merged.Bytes())
+	if err != nil {
+		t.Fatalf("Read() of merged PDF error = %v", err)
+	}
+	if got := len(file.Pages()); got != 3 {
+		t.Fatalf("merged pages = %d, want 3", got)
+	}
+
+	// The landscape page of the source keeps its size and its image
+	page := file.pages[1].dict
+	if box, _ := page["MediaBox"].(array); len(box) != 4 || box[2] != a4Height {
+		t.Errorf("MediaBox = %v, want a landscape A4 page", page["MediaBox"])
+	}
+	resources, err := file.dict(page["Resources"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	xobjects, err := file.dict(resources["XObject"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image, err := file.object(xobjects["Im0"].(ref).id); err != nil || image.(*stream).dict["Width"] != int64(20) {
+		t.Errorf("Im0 = %v, %v, want the 20px wide image", image, err)
+	}
+}
+
+func TestReadRefusesInvalidPDFs(t *testing.T) {
+	if _, err := Read([]byte("not a pdf")); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Read() error = %v, want ErrMalformed", err)
+	}
+
+	var source bytes.Buffer
+	if err := Write(&source, []Page{testImage(t, 10, 10)}); err != nil {
+		t.Fatal(err)
+	}
+	encrypted := bytes.Replace(source.Bytes(), []byte("/Root 1 0 R"), []byte("/Root 1 0 R /Encrypt 1 0 R"), 1)
+	if _, err := Read(encrypted); !errors.Is(err, ErrEncrypted) {
+		t.Errorf("Read() error = %v, want ErrEncrypted", err)
+	}
+}
+
+// testObject is an object of a file built by buildPDF. Objects with a stream are stored at
+// index in that object stream.
+type testObject struct {
+	body          string
+	stream, index int
+}
+
+// buildPDF writes objects numbered from 1 and a cross-reference stream listing them.
+func buildPDF(objects ...testObject) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n")
+
+	xref := new(bytes.Buffer)
+	xref.Write([]byte{0, 0, 0, 0, 0, 0, 0})
+	for i, obj := range objects {
+		if obj.stream != 0 {
+			xref.WriteByte(2)
+			_ = binary.Write(xref, binary.BigEndian, uint32(obj.stream))
+			_ = binary.Write(xref, binary.BigEndian, uint16(obj.index))
+
+			continue
+		}
+
+		xref.WriteByte(1)
+		_ = binary.Write(xref, binary.BigEndian, uint32(buf.Len()))
+		_ = binary.Write(xref, binary.BigEndian, uint16(0))
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj.body)
+	}
+
+	id, offset := len(objects)+1, buf.Len()
+	xref.WriteByte(1)
+	_ = binary.Write(xref, binary.BigEndian, uint32(offset))
+	_ = binary.Write(xref, binary.BigEndian, uint16(0))
+	fmt.Fprintf(&buf, "%d 0 obj\n<< /Type /XRef /Size %d /W [1 4 2] /Root 1 0 R /Length %d >>\nstream\n", id, id+1, xref.Len())
+	buf.Write(xref.Bytes())
+	fmt.Fprintf(&buf, "\nendstream\nendobj\nstartxref\n%d\n%%%%EOF\n", offset)
+
+	return buf.Bytes()
+}
+
+// objStm returns the body of an object stream holding object, header gives its number and offset.
+func objStm(header, object string) string {
+	data := header + " " + object
+
+	return fmt.Sprintf("<< /Type /ObjStm /N 1 /First %d /Length %d >>\nstream\n%s\nendstream", len(header)+1, len(data), data)
+}
+
+func TestReadObjectStreams(t *testing.T) {
+	file, err := Read(buildPDF(
+		testObject{body: "<< /Type /Catalog /Pages 2 0 R >>"},
+		testObject{body: "<< /Type /Pages /Kids [4 0 R] /Count 1 >>"},
+		testObject{body: objStm("4 0", "<< /Type /Page >>")},
+		testObject{stream: 3},
+	))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if got := len(file.Pages()); got != 1 {
+		t.Errorf("Read() pages = %d, want 1", got)
+	}
+}
+
+func TestReadRefusesMalformedPDFs(t *testing.T) {
+	tests := map[string][]byte{
+		"stream length overflows": buildPDF(
+			testObject{body: "<< /Type /Catalog /Pages 2 0 R >>"},
+			testObject{body: "<< /Type /Pages /Kids [3 0 R] /Count 1 /Length 9223372036854775800 >>\nstream\nx\nendstream"},
+			testObject{body: "<< /Type /Page >>"},
+		),
+		"stream length beyond the file": buildPDF(
+			testObject{body: "<< /Type /Catalog /Pages 2 0 R >>"},
+			testObject{body: "<< /Type /Pages /Kids [3 0 R] /Count 1 /Length 100000 >>\nstream\nx\nendstream"},
+			testObject{body: "<< /Type /Page >>"},
+		),
+		"negative object stream offset": buildPDF(
+			testObject{body: "<< /Type /Catalog /Pages 2 0 R >>"},
+			testObject{body: "<< /Type /Pages /Kids [4 0 R] /Count 1 >>"},
+			testObject{body: objStm("4 -100", "<< /Type /Page >>")},
+			testObject{stream: 3},
+		),
+		"object stream offset overflows": buildPDF(
+			testObject{body: "<< /Type /Catalog /Pages 2 0 R >>"},
+			testObject{body: "<< /Type /Pages /Kids [4 0 R] /Count 1 >>"},
+			testObject{body: objStm("4 9223372036854775807", "<< /Type /Page >>")},
+			testObject{stream: 3},
+		),
+		"page tree cycle": buildPDF(
+			testObject{body: "<< /Type /Catalog /Pages 2 0 R >>"},
+			testObject{body: "<< /Type /Pages /Kids [2 0 R] /Count 1 >>"},
+		),
+		"truncated": buildPDF(
+			testObject{body: "<< /Type /Catalog /Pages 2 0 R >>"},
+		)[:40],
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Read(data); !errors.Is(err, ErrMalformed) {
+				t.Errorf("Read() error = %v, want ErrMalformed", err)
+			}
+		})
+	}
+}
+
+// FuzzRead checks that uploaded files cannot crash Read, or Write of the pages it read.
+func FuzzRead(f *testing.F) {
+	var source bytes.Buffer
+	if err := Write(&source, []Page{Image{JPEG: []byte{0xff, 0xd8}, Width: 1, Height: 1}}); err != nil {
+		f.Fatal(err)
+	}
+	f.Add(source.Bytes())
+	f.Add(buildPDF(
+		testObject{body: "<< /Type /Catalog /Pages 2 0 R >>"},
+		testObject{body: "<< /Type /Pages /Kids [4 0 R] /Count 1 >>"},
+		testObject{body: objStm("4 0", "<< /Type /Page /Contents 5 0 R >>")},
+		testObject{stream: 3},
+		testObject{body: "<< /Length 2 >>\nstream\nq Q\nendstream"},
+	))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		file, err := Read(data)
+		if err != nil {
+			if !errors.Is(err, ErrMalformed) && !errors.Is(err, ErrEncrypted) {
+				t.Fatalf("Read() error = %v, want ErrMalformed or ErrEncrypted", err)
+			}
+
+			return
+		}
+
+		_ = Write(io.Discard, file.Pages())
+	})
+}
@@ -0,0 +1,1008 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+var (
+	// ErrMalformed is returned when a PDF cannot be parsed.
+	ErrMalformed = errors.New("malformed PDF")
+	// ErrEncrypted is returned when a PDF is encrypted, its pages cannot be read without the key.
+	ErrEncrypted = errors.New("encrypted PDF")
+)
+
+// maxDepth bounds the nesting of arrays and dictionaries and of the page tree, so a crafted
+// file cannot exhaust the stack.
+const maxDepth = 64
+
+// The objects of a PDF file. Numbers are int64 or float64, booleans bool and null is nil.
+type (
+	name   string
+	str    []byte
+	array  []any
+	dict   map[name]any
+	ref    struct{ id int }
+	stream struct {
+		dict dict
+		// data is the encoded stream data, as stored in the file
+		data []byte
+	}
+	// keyword is a bare word or delimiter, such as obj, R or <<.
+	keyword string
+)
+
+// xrefEntry locates an object in the file, at an offset or inside an object stream.
+type xrefEntry struct {
+	offset int
+	// stream is the object stream holding the object, its index is the position within it
+	stream int
+	index  int
+}
+
+// objectStream is a decoded object stream.
+type objectStream struct {
+	data    []byte
+	offsets []int
+}
+
+// pageNode is a page of a file with the attributes it inherits from the page tree.
+type pageNode struct {
+	// id is the object number of the page, 0 for pages stored inline in their parent
+	id   int
+	dict dict
+}
+
+// File is a parsed PDF whose pages can be written into another PDF, see Write.
+type File struct {
+	data    []byte
+	xref    map[int]xrefEntry
+	trailer dict
+	objects map[int]any
+	loading map[int]bool
+	streams map[int]*objectStream
+	pages   []pageNode
+	// pageIDs and nodeIDs are the object numbers of the pages and of the inner page tree nodes
+	pageIDs map[int]bool
+	nodeIDs map[int]bool
+}
+
+// Read parses a PDF. Content streams are kept as they are stored, only the cross-reference
+// and object streams needed to find the pages are decoded.
+func Read(data []byte) (*File, error) {
+	// Offsets are relative to the header, some writers put bytes before it
+	start := bytes.Index(data, []byte("%PDF-"))
+	if start < 0 {
+		return nil, fmt.Errorf("%w: no PDF header", ErrMalformed)
+	}
+
+	f := &File{
+		data:    data[start:],
+		xref:    make(map[int]xrefEntry),
+		objects: make(map[int]any),
+		loading: make(map[int]bool),
+		streams: make(map[int]*objectStream),
+		pageIDs: make(map[int]bool),
+		nodeIDs: make(map[int]bool),
+	}
+	if err := f.readXref(); err != nil {
+		return nil, err
+	}
+	if _, ok := f.trailer["Encrypt"]; ok {
+		return nil, ErrEncrypted
+	}
+
+	root, err := f.dict(f.trailer["Root"])
+	if err != nil || root == nil {
+		return nil, fmt.Errorf("%w: no document catalog", ErrMalformed)
+	}
+	if err := f.walkPages(root["Pages"], dict{}, 0); err != nil {
+		return nil, err
+	}
+	if len(f.pages) == 0 {
+		return nil, fmt.Errorf("%w: no pages", ErrMalformed)
+	}
+
+	return f, nil
+}
+
+// Pages returns the pages of the file, in order.
+func (f *File) Pages() []Page {
+	pages := make([]Page, len(f.pages))
+	for i := range f.pages {
+		pages[i] = filePage{file: f, index: i}
+	}
+
+	return pages
+}
+
+// inheritable are the page attributes a page takes from its ancestors when it has none itself.
+var inheritable = []name{"Resources", "MediaBox", "CropBox", "Rotate"}
+
+// walkPages collects the pages below a node of the page tree.
+func (f *File) walkPages(node any, inherited dict, depth int) error {
+	if depth > maxDepth {
+		return fmt.Errorf("%w: page tree too deep", ErrMalformed)
+	}
+
+	id := 0
+	if r, ok := node.(ref); ok {
+		if f.pageIDs[r.id] || f.nodeIDs[r.id] {
+			return fmt.Errorf("%w: page tree has a cycle", ErrMalformed)
+		}
+		id = r.id
+	}
+
+	d, err := f.dict(node)
+	if err != nil {
+		return err
+	}
+	if d == nil {
+		return fmt.Errorf("%w: missing page tree node", ErrMalformed)
+	}
+
+	kids, err := f.resolve(d["Kids"])
+	if err != nil {
+		return err
+	}
+	if kidList, ok := kids.(array); ok && d["Type"] != name("Page") {
+		if id != 0 {
+			f.nodeIDs[id] = true
+		}
+
+		attributes := make(dict, len(inherited))
+		for key, value := range inherited {
+			attributes[key] = value
+		}
+		for _, key := range inheritable {
+			if value, ok := d[key]; ok {
+				attributes[key] = value
+			}
+		}
+
+		for _, kid := range kidList {
+			if err := f.walkPages(kid, attributes, depth+1); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	page := make(dict, len(d)+len(inherited))
+	for key, value := range inherited {
+		page[key] = value
+	}
+	for key, value := range d {
+		page[key] = value
+	}
+	if _, ok := page["MediaBox"]; !ok {
+		page["MediaBox"] = array{int64(0), int64(0), a4Width, a4Height}
+	}
+
+	if id != 0 {
+		f.pageIDs[id] = true
+	}
+	f.pages = append(f.pages, pageNode{id: id, dict: page})
+
+	return nil
+}
+
+// resolve returns the object a reference points to, other values are returned as they are.
+func (f *File) resolve(value any) (any, error) {
+	r, ok := value.(ref)
+	if !ok {
+		return value, nil
+	}
+
+	return f.object(r.id)
+}
+
+// dict resolves value and returns it as a dictionary, or the dictionary of a stream.
+// A missing value returns nil.
+func (f *File) dict(value any) (dict, error) {
+	resolved, err := f.resolve(value)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := resolved.(type) {
+	case nil:
+		return nil, nil
+	case dict:
+		return v, nil
+	case *stream:
+		return v.dict, nil
+	default:
+		return nil, fmt.Errorf("%w: expected a dictionary, got %T", ErrMalformed, resolved)
+	}
+}
+
+// object returns the object with the given number. Objects missing from the file are null.
+func (f *File) object(id int) (any, error) {
+	if obj, ok := f.objects[id]; ok {
+		return obj, nil
+	}
+	if f.loading[id] {
+		return nil, fmt.Errorf("%w: object %d refers to itself", ErrMalformed, id)
+	}
+
+	entry, ok := f.xref[id]
+	if !ok {
+		return nil, nil
+	}
+
+	f.loading[id] = true
+	defer delete(f.loading, id)
+
+	var obj any
+	var err error
+	if entry.stream != 0 {
+		obj, err = f.compressedObject(entry)
+	} else {
+		obj, err = f.indirectObject(entry.offset, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object %d: %w", id, err)
+	}
+	f.objects[id] = obj
+
+	return obj, nil
+}
+
+// indirectObject parses the object id at offset, id is 0 when it is not known yet.
+func (f *File) indirectObject(offset, id int) (any, error) {
+	if offset < 0 || offset >= len(f.data) {
+		return nil, fmt.Errorf("%w: offset %d out of range", ErrMalformed, offset)
+	}
+
+	l := &lexer{data: f.data, pos: offset}
+	number, _ := l.token()
+	_, _ = l.token()
+	if kw, _ := l.token(); kw != keyword("obj") {
+		return nil, fmt.Errorf("%w: no object at offset %d", ErrMalformed, offset)
+	}
+	if n, ok := number.(int64); !ok || (id != 0 && int(n) != id) {
+		return nil, fmt.Errorf("%w: offset %d holds another object", ErrMalformed, offset)
+	}
+
+	obj, err := l.object()
+	if err != nil {
+		return nil, err
+	}
+
+	d, ok := obj.(dict)
+	if !ok {
+		return obj, nil
+	}
+	pos := l.pos
+	if kw, _ := l.token(); kw != keyword("stream") {
+		l.pos = pos
+
+		return obj, nil
+	}
+
+	return f.readStream(l, d)
+}
+
+// readStream reads the data of a stream whose dictionary l has just parsed, up to the stream keyword.
+func (f *File) readStream(l *lexer, d dict) (*stream, error) {
+	// The data starts after the end of line following the keyword
+	start := l.pos
+	if start < len(f.data) && f.data[start] == '\r' {
+		start++
+	}
+	if start < len(f.data) && f.data[start] == '\n' {
+		start++
+	}
+
+	length := -1
+	if value, err := f.resolve(d["Length"]); err == nil {
+		if n, ok := value.(int64); ok {
+			length = int(n)
+		}
+	}
+
+	if length > len(l.data)-start {
+		return nil, fmt.Errorf("%w: stream length %d out of range", ErrMalformed, length)
+	}
+
+	// Writers get the length wrong, so it is only trusted when endstream follows it
+	end := start + length
+	if length < 0 || !bytes.HasPrefix(bytes.TrimLeft(l.data[end:min(end+16, len(l.data))], "\r\n \t"), []byte("endstream")) {
+		index := bytes.Index(l.data[start:], []byte("endstream"))
+		if index < 0 {
+			return nil, fmt.Errorf("%w: unterminated stream", ErrMalformed)
+		}
+		end = start + index
+		for end > start && (l.data[end-1] == '\n' || l.data[end-1] == '\r') {
+			end--
+		}
+	}
+
+	return &stream{dict: d, data: l.data[start:end]}, nil
+}
+
+// compressedObject parses an object stored in an object stream.
+func (f *File) compressedObject(entry xrefEntry) (any, error) {
+	objects, ok := f.streams[entry.stream]
+	if !ok {
+		container, err := f.object(entry.stream)
+		if err != nil {
+			return nil, err
+		}
+		s, ok := container.(*stream)
+		if !ok {
+			return nil, fmt.Errorf("%w: object stream %d is not a stream", ErrMalformed, entry.stream)
+		}
+
+		objects, err = f.readObjectStream(s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read object stream %d: %w", entry.stream, err)
+		}
+		f.streams[entry.stream] = objects
+	}
+
+	if entry.index < 0 || entry.index >= len(objects.offsets) {
+		return nil, fmt.Errorf("%w: index %d out of range of object stream %d", ErrMalformed, entry.index, entry.stream)
+	}
+
+	l := &lexer{data: objects.data, pos: objects.offsets[entry.index]}
+
+	return l.object()
+}
+
+// readObjectStream decodes an object stream and the offsets of the objects in it.
+func (f *File) readObjectStream(s *stream) (*objectStream, error) {
+	data, err := f.decode(s)
+	if err != nil {
+		return nil, err
+	}
+
+	count, _ := s.dict["N"].(int64)
+	first, _ := s.dict["First"].(int64)
+	if count < 0 || first < 0 || int(first) > len(data) {
+		return nil, fmt.Errorf("%w: invalid object stream header", ErrMalformed)
+	}
+
+	l := &lexer{data: data}
+	offsets := make([]int, 0, min(count, 10000))
+	for range count {
+		_, _ = l.token()
+		offset, err := l.token()
+		n, ok := offset.(int64)
+		if err != nil || !ok || n < 0 || n > int64(len(data))-first {
+			return nil, fmt.Errorf("%w: invalid object stream header", ErrMalformed)
+		}
+		offsets = append(offsets, int(first+n))
+	}
+
+	return &objectStream{data: data, offsets: offsets}, nil
+}
+
+// readXref reads the cross-reference sections from the last one back. Entries of later sections
+// take precedence, so earlier ones only fill in objects not seen yet.
+func (f *File) readXref() error {
+	index := bytes.LastIndex(f.data, []byte("startxref"))
+	if index < 0 {
+		return fmt.Errorf("%w: no startxref", ErrMalformed)
+	}
+
+	l := &lexer{data: f.data, pos: index + len("startxref")}
+	start, err := l.token()
+	offset, ok := start.(int64)
+	if err != nil || !ok {
+		return fmt.Errorf("%w: invalid startxref", ErrMalformed)
+	}
+
+	seen := make(map[int64]bool)
+	for {
+		if seen[offset] {
+			return fmt.Errorf("%w: cross-reference sections form a cycle", ErrMalformed)
+		}
+		seen[offset] = true
+
+		trailer, err := f.readXrefSection(int(offset))
+		if err != nil {
+			return err
+		}
+		if f.trailer == nil {
+			f.trailer = trailer
+		}
+
+		// Hybrid files list the objects of object streams in a separate cross-reference stream
+		if stm, ok := trailer["XRefStm"].(int64); ok && !seen[stm] {
+			seen[stm] = true
+			if _, err := f.readXrefSection(int(stm)); err != nil {
+				return err
+			}
+		}
+
+		prev, ok := trailer["Prev"].(int64)
+		if !ok {
+			return nil
+		}
+		offset = prev
+	}
+}
+
+// readXrefSection reads a cross-reference table or stream at offset and returns its trailer.
+func (f *File) readXrefSection(offset int) (dict, error) {
+	if offset < 0 || offset >= len(f.data) {
+		return nil, fmt.Errorf("%w: cross-reference offset %d out of range", ErrMalformed, offset)
+	}
+
+	l := &lexer{data: f.data, pos: offset}
+	if kw, _ := l.token(); kw != keyword("xref") {
+		return f.readXrefStream(offset)
+	}
+
+	for {
+		tok, err := l.token()
+		if err != nil {
+			return nil, fmt.Errorf("%w: unterminated cross-reference table", ErrMalformed)
+		}
+		if tok == keyword("trailer") {
+			break
+		}
+
+		first, ok := tok.(int64)
+		count, err := l.token()
+		n, countOK := count.(int64)
+		if !ok || err != nil || !countOK || first < 0 || n < 0 {
+			return nil, fmt.Errorf("%w: invalid cross-reference subsection", ErrMalformed)
+		}
+
+		for i := range n {
+			position, _ := l.token()
+			_, _ = l.token()
+			kind, err := l.token()
+			if err != nil {
+				return nil, fmt.Errorf("%w: truncated cross-reference table", ErrMalformed)
+			}
+
+			id := int(first + i)
+			if _, ok := f.xref[id]; ok || kind != keyword("n") {
+				continue
+			}
+			if position, ok := position.(int64); ok {
+				f.xref[id] = xrefEntry{offset: int(position)}
+			}
+		}
+	}
+
+	obj, err := l.object()
+	if err != nil {
+		return nil, err
+	}
+	trailer, ok := obj.(dict)
+	if !ok {
+		return nil, fmt.Errorf("%w: invalid trailer", ErrMalformed)
+	}
+
+	return trailer, nil
+}
+
+// readXrefStream reads a cross-reference stream at offset and returns its dictionary, which
+// serves as the trailer.
+func (f *File) readXrefStream(offset int) (dict, error) {
+	obj, err := f.indirectObject(offset, 0)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := obj.(*stream)
+	if !ok || s.dict["Type"] != name("XRef") {
+		return nil, fmt.Errorf("%w: no cross-reference at offset %d", ErrMalformed, offset)
+	}
+
+	data, err := f.decode(s)
+	if err != nil {
+		return nil, err
+	}
+
+	widths, ok := s.dict["W"].(array)
+	if !ok || len(widths) != 3 {
+		return nil, fmt.Errorf("%w: invalid cross-reference stream widths", ErrMalformed)
+	}
+	w := make([]int, 3)
+	for i, width := range widths {
+		n, ok := width.(int64)
+		if !ok || n < 0 || n > 8 {
+			return nil, fmt.Errorf("%w: invalid cross-reference stream widths", ErrMalformed)
+		}
+		w[i] = int(n)
+	}
+	entrySize := w[0] + w[1] + w[2]
+	if entrySize == 0 {
+		return nil, fmt.Errorf("%w: invalid cross-reference stream widths", ErrMalformed)
+	}
+
+	index, ok := s.dict["Index"].(array)
+	if !ok {
+		size, _ := s.dict["Size"].(int64)
+		index = array{int64(0), size}
+	}
+
+	for i := 0; i+1 < len(index); i += 2 {
+		first, ok1 := index[i].(int64)
+		count, ok2 := index[i+1].(int64)
+		if !ok1 || !ok2 || first < 0 || count < 0 {
+			return nil, fmt.Errorf("%w: invalid cross-reference stream index", ErrMalformed)
+		}
+
+		for j := range count {
+			if len(data) < entrySize {
+				return nil, fmt.Errorf("%w: truncated cross-reference stream", ErrMalformed)
+			}
+			entry := data[:entrySize]
+			data = data[entrySize:]
+
+			// The type defaults to 1 when its width is 0
+			kind := 1
+			if w[0] > 0 {
+				kind = int(field(entry[:w[0]]))
+			}
+			second := int(field(entry[w[0] : w[0]+w[1]]))
+			third := int(field(entry[w[0]+w[1]:]))
+
+			id := int(first + j)
+			if _, ok := f.xref[id]; ok {
+				continue
+			}
+			switch kind {
+			case 1:
+				f.xref[id] = xrefEntry{offset: second}
+			case 2:
+				f.xref[id] = xrefEntry{stream: second, index: third}
+			}
+		}
+	}
+
+	return s.dict, nil
+}
+
+// field decodes a big-endian field of a cross-reference stream entry.
+func field(b []byte) int64 {
+	var n int64
+	for _, c := range b {
+		n = n<<8 | int64(c)
+	}
+
+	return n
+}
+
+// decode returns the decoded data of a stream. Only the filters used for cross-reference and
+// object streams are supported, content streams are copied without decoding them.
+func (f *File) decode(s *stream) ([]byte, error) {
+	filters, err := f.resolve(s.dict["Filter"])
+	if err != nil {
+		return nil, err
+	}
+	params, err := f.resolve(s.dict["DecodeParms"])
+	if err != nil {
+		return nil, err
+	}
+
+	var filterList, paramList array
+	switch v := filters.(type) {
+	case nil:
+	case name:
+		filterList, paramList = array{v}, array{params}
+	case array:
+		filterList = v
+		paramList, _ = params.(array)
+	default:
+		return nil, fmt.Errorf("%w: invalid stream filter", ErrMalformed)
+	}
+
+	data := s.data
+	for i, filter := range filterList {
+		if filter != name("FlateDecode") {
+			return nil, fmt.Errorf("%w: unsupported stream filter %v", ErrMalformed, filter)
+		}
+
+		reader, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrMalformed, err)
+		}
+		// Truncated streams are common, what could be decompressed is used
+		decoded, err := io.ReadAll(reader)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("%w: %w", ErrMalformed, err)
+		}
+
+		var param dict
+		if i < len(paramList) {
+			param, err = f.dict(paramList[i])
+			if err != nil {
+				return nil, err
+			}
+		}
+		if data, err = unpredict(decoded, param); err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+// unpredict reverses the PNG predictors applied before compression, as configured by params.
+func unpredict(data []byte, params dict) ([]byte, error) {
+	predictor, _ := params["Predictor"].(int64)
+	if predictor <= 1 {
+		return data, nil
+	}
+	if predictor < 10 {
+		return nil, fmt.Errorf("%w: unsupported predictor %d", ErrMalformed, predictor)
+	}
+
+	colors, bits, columns := int64(1), int64(8), int64(1)
+	if v, ok := params["Colors"].(int64); ok {
+		colors = v
+	}
+	if v, ok := params["BitsPerComponent"].(int64); ok {
+		bits = v
+	}
+	if v, ok := params["Columns"].(int64); ok {
+		columns = v
+	}
+	if colors < 1 || bits < 1 || columns < 1 || colors*bits*columns > 1<<20 {
+		return nil, fmt.Errorf("%w: invalid predictor parameters", ErrMalformed)
+	}
+
+	bpp := int(max((colors*bits)/8, 1))
+	rowSize := int((colors*bits*columns + 7) / 8)
+
+	out := make([]byte, 0, len(data))
+	previous := make([]byte, rowSize)
+	for len(data) > 0 {
+		if len(data) < rowSize+1 {
+			break
+		}
+		filter, row := data[0], append([]byte(nil), data[1:rowSize+1]...)
+		data = data[rowSize+1:]
+
+		for i := range row {
+			var left, upLeft byte
+			if i >= bpp {
+				left, upLeft = row[i-bpp], previous[i-bpp]
+			}
+			up := previous[i]
+
+			switch filter {
+			case 0:
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += up
+			case 3:
+				row[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[i] += paeth(left, up, upLeft)
+			default:
+				return nil, fmt.Errorf("%w: invalid PNG predictor %d", ErrMalformed, filter)
+			}
+		}
+
+		out = append(out, row...)
+		previous = row
+	}
+
+	return out, nil
+}
+
+// paeth is the Paeth predictor of the PNG specification.
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	default:
+		return c
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
+
+// lexer reads the tokens and objects of PDF syntax from data.
+type lexer struct {
+	data  []byte
+	pos   int
+	depth int
+}
+
+func isSpace(c byte) bool {
+	return c == 0 || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+func isDelimiter(c byte) bool {
+	return bytes.IndexByte([]byte("()<>[]{}/%"), c) >= 0
+}
+
+// skipSpace skips white space and comments.
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.data) {
+		switch c := l.data[l.pos]; {
+		case isSpace(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// token returns the next token: a name, string or number, or a keyword for everything else.
+func (l *lexer) token() (any, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	c := l.data[l.pos]
+	switch {
+	case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+		l.pos += 2
+		return keyword("<<"), nil
+	case c == '>' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '>':
+		l.pos += 2
+		return keyword(">>"), nil
+	case c == '[' || c == ']' || c == '{' || c == '}':
+		l.pos++
+		return keyword(l.data[l.pos-1 : l.pos]), nil
+	case c == '/':
+		return l.name(), nil
+	case c == '(':
+		return l.literal()
+	case c == '<':
+		return l.hex()
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isSpace(l.data[l.pos]) && !isDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	if l.pos == start {
+		l.pos++
+		return nil, fmt.Errorf("%w: unexpected %q at offset %d", ErrMalformed, c, start)
+	}
+
+	word := string(l.data[start:l.pos])
+	if n, err := strconv.ParseInt(word, 10, 64); err == nil {
+		return n, nil
+	}
+	if c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9') {
+		if n, err := strconv.ParseFloat(word, 64); err == nil {
+			return n, nil
+		}
+	}
+
+	return keyword(word), nil
+}
+
+// name reads a name, decoding #xx escapes.
+func (l *lexer) name() name {
+	l.pos++
+	var b []byte
+	for l.pos < len(l.data) && !isSpace(l.data[l.pos]) && !isDelimiter(l.data[l.pos]) {
+		c := l.data[l.pos]
+		if c == '#' && l.pos+2 < len(l.data) {
+			if n, err := strconv.ParseUint(string(l.data[l.pos+1:l.pos+3]), 16, 8); err == nil {
+				b = append(b, byte(n))
+				l.pos += 3
+
+				continue
+			}
+		}
+		b = append(b, c)
+		l.pos++
+	}
+
+	return name(b)
+}
+
+// literal reads a string in parentheses, which may contain balanced parentheses and escapes.
+func (l *lexer) literal() (str, error) {
+	l.pos++
+	var b []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return str(b), nil
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				continue
+			}
+			c = l.data[l.pos]
+			l.pos++
+
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				// A backslash before the end of a line continues the string on the next
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					n := int(c - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						n = n*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(n)
+				}
+			}
+		}
+		b = append(b, c)
+	}
+
+	return nil, fmt.Errorf("%w: unterminated string", ErrMalformed)
+}
+
+// hex reads a string of hexadecimal digits in angle brackets.
+func (l *lexer) hex() (str, error) {
+	l.pos++
+	var digits []byte
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+
+		switch {
+		case c == '>':
+			if len(digits)%2 == 1 {
+				digits = append(digits, '0')
+			}
+			b := make([]byte, len(digits)/2)
+			for i := range b {
+				n, _ := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+				b[i] = byte(n)
+			}
+
+			return str(b), nil
+		case isSpace(c):
+		case (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F'):
+			digits = append(digits, c)
+		default:
+			return nil, fmt.Errorf("%w: invalid hexadecimal string", ErrMalformed)
+		}
+	}
+
+	return nil, fmt.Errorf("%w: unterminated string", ErrMalformed)
+}
+
+// object reads the next object. Integers followed by a generation and R are references.
+func (l *lexer) object() (any, error) {
+	tok, err := l.token()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+
+	switch t := tok.(type) {
+	case keyword:
+		switch t {
+		case "<<":
+			return l.dict()
+		case "[":
+			return l.array()
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("%w: unexpected %s", ErrMalformed, t)
+	case int64:
+		pos := l.pos
+		if generation, err := l.token(); err == nil {
+			if _, ok := generation.(int64); ok {
+				if r, err := l.token(); err == nil && r == keyword("R") {
+					return ref{id: int(t)}, nil
+				}
+			}
+		}
+		l.pos = pos
+
+		return t, nil
+	default:
+		return tok, nil
+	}
+}
+
+// dict reads the entries of a dictionary after its opening <<.
+func (l *lexer) dict() (dict, error) {
+	if l.depth++; l.depth > maxDepth {
+		return nil, fmt.Errorf("%w: objects nested too deep", ErrMalformed)
+	}
+	defer func() { l.depth-- }()
+
+	d := dict{}
+	for {
+		tok, err := l.token()
+		if err != nil {
+			return nil, fmt.Errorf("%w: unterminated dictionary", ErrMalformed)
+		}
+		if tok == keyword(">>") {
+			return d, nil
+		}
+
+		key, ok := tok.(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: dictionary key is not a name", ErrMalformed)
+		}
+		value, err := l.object()
+		if err != nil {
+			return nil, err
+		}
+		// A null value is the same as a missing entry
+		if value != nil {
+			d[key] = value
+		}
+	}
+}
+
+// array reads the elements of an array after its opening [.
+func (l *lexer) array() (array, error) {
+	if l.depth++; l.depth > maxDepth {
+		return nil, fmt.Errorf("%w: objects nested too deep", ErrMalformed)
+	}
+	defer func() { l.depth-- }()
+
+	a := array{}
+	for {
+		pos := l.pos
+		tok, err := l.token()
+		if err != nil {
+			return nil, fmt.Errorf("%w: unterminated array", ErrMalformed)
+		}
+		if tok == keyword("]") {
+			return a, nil
+		}
+		l.pos = pos
+
+		value, err := l.object()
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, value)
+	}
+}
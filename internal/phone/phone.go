@@ -0,0 +1,198 @@
+package phone
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+var (
+	// ErrInvalidNumber is returned when a phone number cannot be parsed or has an invalid length.
+	ErrInvalidNumber = errors.New("invalid phone number")
+	// ErrUnknownRegion is returned when a national number is given without a known default region.
+	ErrUnknownRegion = errors.New("unknown phone number region")
+)
+
+// Number is a parsed phone number.
+type Number struct {
+	// E164 is the number in E.164 format, e.g. +447911123456.
+	E164 string
+	// Region is the ISO 3166-1 alpha-2 code of the country the number belongs to.
+	Region string
+	// CallingCode is the country calling code without the leading plus, e.g. 44.
+	CallingCode string
+	// NationalNumber is the significant national number without trunk prefix.
+	NationalNumber string
+}
+
+// regionInfo describes the numbering plan of a region.
+type regionInfo struct {
+	callingCode string
+	trunkPrefix string
+	minLength   int
+	maxLength   int
+}
+
+// regions covers the numbering plans for the countries we serve most.
+// Regions sharing a calling code (e.g. NANP) are disambiguated by mainRegion.
+var regions = map[string]regionInfo{
+	"GB": {callingCode: "44", trunkPrefix: "0", minLength: 9, maxLength: 10},
+	"IE": {callingCode: "353", trunkPrefix: "0", minLength: 7, maxLength: 9},
+	"US": {callingCode: "1", trunkPrefix: "1", minLength: 10, maxLength: 10},
+	"CA": {callingCode: "1", trunkPrefix: "1", minLength: 10, maxLength: 10},
+	"FR": {callingCode: "33", trunkPrefix: "0", minLength: 9, maxLength: 9},
+	"DE": {callingCode: "49", trunkPrefix: "0", minLength: 6, maxLength: 13},
+	"NL": {callingCode: "31", trunkPrefix: "0", minLength: 9, maxLength: 9},
+	"BE": {callingCode: "32", trunkPrefix: "0", minLength: 8, maxLength: 9},
+	"ES": {callingCode: "34", minLength: 9, maxLength: 9},
+	"IT": {callingCode: "39", minLength: 6, maxLength: 11},
+	"PT": {callingCode: "351", minLength: 9, maxLength: 9},
+	"SE": {callingCode: "46", trunkPrefix: "0", minLength: 7, maxLength: 10},
+	"NO": {callingCode: "47", minLength: 8, maxLength: 8},
+	"DK": {callingCode: "45", minLength: 8, maxLength: 8},
+	"FI": {callingCode: "358", trunkPrefix: "0", minLength: 5, maxLength: 12},
+	"PL": {callingCode: "48", minLength: 9, maxLength: 9},
+	"CH": {callingCode: "41", trunkPrefix: "0", minLength: 9, maxLength: 9},
+	"AT": {callingCode: "43", trunkPrefix: "0", minLength: 4, maxLength: 13},
+	"AU": {callingCode: "61", trunkPrefix: "0", minLength: 9, maxLength: 9},
+	"NZ": {callingCode: "64", trunkPrefix: "0", minLength: 8, maxLength: 10},
+	"IN": {callingCode: "91", trunkPrefix: "0", minLength: 10, maxLength: 10},
+	"ZA": {callingCode: "27", trunkPrefix: "0", minLength: 9, maxLength: 9},
+}
+
+// mainRegion is the region reported for a calling code shared by several regions.
+var mainRegion = map[string]string{
+	"1": "US",
+}
+
+// callingCodes lists the known calling codes, longest first, for prefix matching.
+var callingCodes = func() []string {
+	seen := make(map[string]bool)
+	codes := make([]string, 0, len(regions))
+	for _, info := range regions {
+		if !seen[info.callingCode] {
+			seen[info.callingCode] = true
+			codes = append(codes, info.callingCode)
+		}
+	}
+	sort.Slice(codes, func(i, j int) bool { return len(codes[i]) > len(codes[j]) })
+
+	return codes
+}()
+
+// Parse parses a phone number written in international format (+44 7911 123456, 0044...)
+// or in the national format of defaultRegion (07911 123456) and returns it normalized.
+// Numbers for regions without a known numbering plan are accepted in international
+// format if their length is valid for E.164.
+func Parse(raw, defaultRegion string) (*Number, error) {
+	digits, international, err := extractDigits(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if !international {
+		region := strings.ToUpper(defaultRegion)
+		info, ok := regions[region]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownRegion, defaultRegion)
+		}
+
+		national := strings.TrimPrefix(digits, info.trunkPrefix)
+		if info.trunkPrefix == "" || len(digits) == info.maxLength {
+			national = digits
+		}
+
+		return build(region, info, national)
+	}
+
+	for _, code := range callingCodes {
+		if !strings.HasPrefix(digits, code) {
+			continue
+		}
+
+		region := regionForCallingCode(code, defaultRegion)
+		info := regions[region]
+
+		// Drop a trunk prefix written after the calling code, as in +44 (0)7911 123456
+		national := digits[len(code):]
+		if info.trunkPrefix == "0" {
+			national = strings.TrimPrefix(national, "0")
+		}
+
+		return build(region, info, national)
+	}
+
+	// Unknown calling code, fall back to the E.164 length limits
+	if len(digits) < 8 || len(digits) > 15 {
+		return nil, fmt.Errorf("%w: must have between 8 and 15 digits", ErrInvalidNumber)
+	}
+
+	return &Number{E164: "+" + digits}, nil
+}
+
+// extractDigits strips formatting characters from the number and reports whether it was
+// written in international format. Letters and other symbols make the number invalid.
+func extractDigits(raw string) (string, bool, error) {
+	raw = strings.TrimSpace(raw)
+	international := strings.HasPrefix(raw, "+")
+
+	var b strings.Builder
+	for _, r := range strings.TrimPrefix(raw, "+") {
+		switch {
+		case unicode.IsDigit(r):
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')' || r == '/':
+			continue
+		default:
+			return "", false, fmt.Errorf("%w: unexpected character %q", ErrInvalidNumber, r)
+		}
+	}
+
+	digits := b.String()
+	if !international && strings.HasPrefix(digits, "00") {
+		digits = digits[2:]
+		international = true
+	}
+
+	if digits == "" {
+		return "", false, fmt.Errorf("%w: no digits", ErrInvalidNumber)
+	}
+
+	return digits, international, nil
+}
+
+// regionForCallingCode picks the region for a calling code, preferring the default
+// region when it shares the calling code (e.g. CA for +1 numbers of Canadian users).
+func regionForCallingCode(code, defaultRegion string) string {
+	if info, ok := regions[strings.ToUpper(defaultRegion)]; ok && info.callingCode == code {
+		return strings.ToUpper(defaultRegion)
+	}
+
+	if region, ok := mainRegion[code]; ok {
+		return region
+	}
+
+	for region, info := range regions {
+		if info.callingCode == code {
+			return region
+		}
+	}
+
+	return ""
+}
+
+// build checks the national number length against the region plan and assembles the Number.
+func build(region string, info regionInfo, national string) (*Number, error) {
+	if len(national) < info.minLength || len(national) > info.maxLength {
+		return nil, fmt.Errorf("%w: wrong length for %s", ErrInvalidNumber, region)
+	}
+
+	return &Number{
+		E164:           "+" + info.callingCode + national,
+		Region:         region,
+		CallingCode:    info.callingCode,
+		NationalNumber: national,
+	}, nil
+}
@@ -0,0 +1,81 @@
+// Package push sends push notifications to the devices of users.
+package push
+
+import (
+	"context"
+	"fmt"
+
+	"firebase.google.com/go/v4/messaging"
+	"github.com/rs/zerolog/log"
+)
+
+// Message is a push notification for a single user. Data is delivered to the app with it.
+type Message struct {
+	UserID string
+	Title  string
+	Body   string
+	Data   map[string]string
+}
+
+// Sender sends push notifications.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogSender writes push notifications to the log instead of sending them. It is meant for local development.
+type LogSender struct{}
+
+// Send logs the message.
+func (LogSender) Send(_ context.Context, msg Message) error {
+	log.Info().Str("user_id", msg.UserID).Str("title", msg.Title).Str("body", msg.Body).Msg("Push notification not sent, push mode is log")
+
+	return nil
+}
+
+// FCMSender sends push notifications through Firebase Cloud Messaging. Every user has a topic
+// named after their ID, which the apps subscribe to on sign in, so no device tokens are stored.
+type FCMSender struct {
+	client *messaging.Client
+}
+
+// NewFCMSender creates an FCMSender using the messaging client of the Firebase app.
+func NewFCMSender(client *messaging.Client) *FCMSender {
+	return &FCMSender{client: client}
+}
+
+// Send sends the message to the user's topic.
+func (s *FCMSender) Send(ctx context.Context, msg Message) error {
+	if _, err := s.client.Send(ctx, &messaging.Message{
+		Topic: Topic(msg.UserID),
+		Notification: &messaging.Notification{
+			Title: msg.Title,
+			Body:  msg.Body,
+		},
+		Data: msg.Data,
+	}); err != nil {
+		return fmt.Errorf("failed to send push notification: %w", err)
+	}
+
+	return nil
+}
+
+// Topic returns the messaging topic of a user's devices.
+func Topic(userID string) string {
+	return "user-" + userID
+}
+
+// New creates the Sender for the given mode: "log" or "fcm". The messaging client is only used for "fcm".
+func New(mode string, client *messaging.Client) (Sender, error) {
+	switch mode {
+	case "log":
+		return LogSender{}, nil
+	case "fcm":
+		if client == nil {
+			return nil, fmt.Errorf("push mode fcm requires a Firebase messaging client")
+		}
+
+		return NewFCMSender(client), nil
+	default:
+		return nil, fmt.Errorf("unknown push mode: %s", mode)
+	}
+}
@@ -0,0 +1,90 @@
+// Package redact removes personal data and credentials from strings before they are logged
+// or returned to clients, so PII does not leak into Cloud Logging or error responses.
+package redact
+
+import (
+	"io"
+	"regexp"
+)
+
+// Placeholder replaces redacted values.
+const Placeholder = "[REDACTED]"
+
+type rule struct {
+	pattern     *regexp.Regexp
+	replacement []byte
+}
+
+// rules are applied in order. Key based rules run first so the value patterns do not
+// partially match values that are removed entirely.
+var rules = []rule{
+	// JSON fields that always hold sensitive values, e.g. "phone":"+447700900123"
+	{
+		pattern:     regexp.MustCompile(`(?i)("(?:phone|email|authorization|password|token|id_token|refresh_token)"\s*:\s*)"(?:[^"\\]|\\.)*"`),
+		replacement: []byte(`$1"` + Placeholder + `"`),
+	},
+	// Authorization header values
+	{
+		pattern:     regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9\-._~+/]+=*`),
+		replacement: []byte(`$1 ` + Placeholder),
+	},
+	// Signed URL credentials and signatures
+	{
+		pattern:     regexp.MustCompile(`(?i)\b(X-Goog-Signature|X-Goog-Credential|Signature|GoogleAccessId)=[^&"\s]+`),
+		replacement: []byte(`$1=` + Placeholder),
+	},
+	// Email addresses
+	{
+		pattern:     regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+		replacement: []byte(Placeholder),
+	},
+	// Phone numbers in E.164 format, which is how they are stored
+	{
+		pattern:     regexp.MustCompile(`\+[1-9]\d{7,14}\b`),
+		replacement: []byte(Placeholder),
+	},
+}
+
+// Bytes returns a copy of b with sensitive values replaced by Placeholder.
+func Bytes(b []byte) []byte {
+	for _, r := range rules {
+		b = r.pattern.ReplaceAll(b, r.replacement)
+	}
+
+	return b
+}
+
+// String returns s with sensitive values replaced by Placeholder.
+func String(s string) string {
+	return string(Bytes([]byte(s)))
+}
+
+// Error returns the redacted message of err, or an empty string if err is nil.
+func Error(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	return String(err.Error())
+}
+
+// Writer redacts everything written to it before passing it on to the wrapped writer.
+// zerolog writes each event in a single call, so values are never split across writes.
+type Writer struct {
+	out io.Writer
+}
+
+// NewWriter creates a Writer that redacts output written to out.
+func NewWriter(out io.Writer) *Writer {
+	return &Writer{out: out}
+}
+
+// Write implements io.Writer. It reports the length of p as written when the redacted
+// output was written in full, since callers expect n to refer to their own input.
+func (w *Writer) Write(p []byte) (int, error) {
+	if _, err := w.out.Write(Bytes(p)); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,20 @@
+package requestid
+
+import "context"
+
+// Header is the HTTP header carrying the request ID.
+const Header = "X-Request-ID"
+
+type contextKey struct{}
+
+// With returns a copy of ctx carrying the request ID.
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// From returns the request ID stored in ctx, or an empty string if there is none.
+func From(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+
+	return id
+}
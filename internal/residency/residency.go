@@ -0,0 +1,58 @@
+// Package residency decides which region a user's data is stored in, so documents
+// stay within the jurisdiction required for the user's country.
+package residency
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Policy maps countries to storage regions. Countries without an explicit mapping,
+// and users without a known country, are placed in the default region.
+type Policy struct {
+	defaultRegion string
+	countries     map[string]string
+	regions       []string
+}
+
+// NewPolicy creates a Policy. countryRegions maps ISO 3166-1 alpha-2 country codes to regions;
+// every region it refers to, and the default region, must be in regions.
+func NewPolicy(defaultRegion string, regions []string, countryRegions map[string]string) (*Policy, error) {
+	if !slices.Contains(regions, defaultRegion) {
+		return nil, fmt.Errorf("default region %q has no storage configured", defaultRegion)
+	}
+
+	countries := make(map[string]string, len(countryRegions))
+	for country, region := range countryRegions {
+		if !slices.Contains(regions, region) {
+			return nil, fmt.Errorf("region %q for country %s has no storage configured", region, country)
+		}
+		countries[strings.ToUpper(country)] = region
+	}
+
+	return &Policy{
+		defaultRegion: defaultRegion,
+		countries:     countries,
+		regions:       regions,
+	}, nil
+}
+
+// DefaultRegion returns the region used when no country specific region applies.
+func (p *Policy) DefaultRegion() string {
+	return p.defaultRegion
+}
+
+// RegionFor returns the region data for users in the given country must be stored in.
+func (p *Policy) RegionFor(country string) string {
+	if region, ok := p.countries[strings.ToUpper(country)]; ok {
+		return region
+	}
+
+	return p.defaultRegion
+}
+
+// Allowed reports whether region is one of the configured storage regions.
+func (p *Policy) Allowed(region string) bool {
+	return slices.Contains(p.regions, region)
+}
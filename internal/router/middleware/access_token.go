@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/audit"
+	"github.com/thoughtgears/shared-services/internal/i18n"
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/telemetry"
+)
+
+// AccessTokenProvider is the sign-in provider set on requests authenticated with a personal access token.
+const AccessTokenProvider = "access_token"
+
+// AccessTokenVerifier verifies personal access tokens, see services.AccessTokenService.
+type AccessTokenVerifier interface {
+	VerifyAccessToken(ctx context.Context, token string) (*models.AccessToken, error)
+}
+
+// Global access token verifier, nil when personal access tokens are disabled
+var accessTokens AccessTokenVerifier
+
+// InitAccessTokens enables personal access tokens on server startup.
+// APIAuth only accepts Firebase ID tokens until it has been called.
+func InitAccessTokens(verifier AccessTokenVerifier) {
+	accessTokens = verifier
+}
+
+// APIAuth is FirebaseAuth for the routes scripts and integrations may call: it also accepts
+// personal access tokens. Requests with an access token act as its user, within its scopes:
+// reads need the read scope and every other request the write scope.
+func APIAuth(read models.AccessTokenScope, write models.AccessTokenScope) gin.HandlerFunc {
+	firebaseAuth := FirebaseAuth()
+
+	return func(c *gin.Context) {
+		token, err := extractToken(c.GetHeader("Authorization"))
+		if err != nil || accessTokens == nil || !strings.HasPrefix(token, models.AccessTokenPrefix) {
+			firebaseAuth(c)
+
+			return
+		}
+
+		scope := write
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			scope = read
+		}
+		accessTokenAuth(c, token, scope)
+	}
+}
+
+// EdgeAuth is FirebaseAuth for a gateway in front of the services. Requests with a personal
+// access token are passed on unverified, as the gateway cannot look the token up: the upstream
+// service verifies it with APIAuth and counts its failures towards the lockout.
+func EdgeAuth() gin.HandlerFunc {
+	firebaseAuth := FirebaseAuth()
+
+	return func(c *gin.Context) {
+		token, err := extractToken(c.GetHeader("Authorization"))
+		if err == nil && strings.HasPrefix(token, models.AccessTokenPrefix) {
+			c.Next()
+
+			return
+		}
+
+		firebaseAuth(c)
+	}
+}
+
+// accessTokenAuth authenticates the request with a personal access token that has scope.
+// The request gets a token like FirebaseAuth sets, without claims, so the tenant and the user
+// are resolved as for signed-in users and the admin routes stay out of reach.
+func accessTokenAuth(c *gin.Context, token string, scope models.AccessTokenScope) {
+	ctx := c.Request.Context()
+	if authBlocked(ctx, c.ClientIP()) {
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+			"error":   "too many requests",
+			"message": i18n.Localize(c, "too many requests", "Too many failed authentication attempts, retry later"),
+		})
+
+		return
+	}
+
+	accessToken, err := accessTokens.VerifyAccessToken(ctx, token)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to verify access token")
+		recordAuthFailure(ctx, c.ClientIP(), "", "invalid_access_token")
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error":   "unauthorized",
+			"message": i18n.Localize(c, "unauthorized", "Invalid access token"),
+		})
+
+		return
+	}
+
+	if !accessToken.HasScope(scope) {
+		log.Warn().Str("uid", accessToken.UserID).Str("access_token_id", accessToken.ID).Str("scope", string(scope)).Msg("Access token without the required scope")
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error":   "insufficient_scope",
+			"message": i18n.Localize(c, "insufficient_scope", "The access token does not have the "+string(scope)+" scope"),
+		})
+
+		return
+	}
+
+	// Access tokens are never a multi-factor sign-in
+	if requireMFA(c, accessToken.UserID, "") {
+		return
+	}
+
+	ctx = withToken(ctx, &auth.Token{
+		UID:     accessToken.UserID,
+		Subject: accessToken.UserID,
+		Firebase: auth.FirebaseInfo{
+			SignInProvider: AccessTokenProvider,
+			Tenant:         accessToken.TenantID,
+		},
+		Claims: map[string]interface{}{},
+	})
+	ctx = audit.WithActor(ctx, audit.Actor{
+		Type: audit.ActorTypeUser,
+		ID:   accessToken.UserID,
+	})
+	c.Request = c.Request.WithContext(telemetry.WithCohort(ctx, telemetry.CohortUser))
+	c.Next()
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/gin-gonic/gin"
+
+	"github.com/thoughtgears/shared-services/internal/models"
+)
+
+// ActivityRecorder records the requests of authenticated users, see services.ActivityService.
+type ActivityRecorder interface {
+	RecordActivity(ctx context.Context, userID string, login models.Login)
+}
+
+// Global activity recorder, nil when activity tracking is disabled
+var activityRecorder ActivityRecorder
+
+// InitActivityTracking enables login activity and last-seen tracking on server startup.
+// FirebaseAuth records nothing until it has been called.
+func InitActivityTracking(recorder ActivityRecorder) {
+	activityRecorder = recorder
+}
+
+// recordActivity records the request of the user the verified token belongs to. It runs after the
+// rest of the chain, so the context carries the tenant resolved by TenantScope, and requests
+// aborted by later middleware are not recorded.
+func recordActivity(c *gin.Context, token *auth.Token) {
+	if activityRecorder == nil || c.IsAborted() {
+		return
+	}
+
+	activityRecorder.RecordActivity(c.Request.Context(), token.UID, models.Login{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Provider:  token.Firebase.SignInProvider,
+		AuthTime:  time.Unix(token.AuthTime, 0).UTC(),
+	})
+}
@@ -8,15 +8,28 @@ import (
 	"strings"
 
 	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog/log"
 	"google.golang.org/api/option"
+
+	"github.com/thoughtgears/shared-services/internal/audit"
+	"github.com/thoughtgears/shared-services/internal/caller"
+	"github.com/thoughtgears/shared-services/internal/i18n"
+	"github.com/thoughtgears/shared-services/internal/lazy"
+	"github.com/thoughtgears/shared-services/internal/telemetry"
+	"github.com/thoughtgears/shared-services/internal/warmup"
 )
 
 // Global Firebase app instance to avoid recreating it for each request
 var firebaseApp *firebase.App
 
-// InitFirebase initializes the Firebase app on server startup
+// Global auth client of the Firebase app, created on the first request that needs it. It caches
+// the public keys tokens are verified with, so it is shared by all requests.
+var authClient *lazy.Value[*auth.Client]
+
+// InitFirebase initializes the Firebase app on server startup. The auth client is created on
+// first use, or by the "firebase_auth" warmer.
 func InitFirebase(ctx context.Context, secretPath string) error {
 	var err error
 	opt := option.WithCredentialsFile(secretPath)
@@ -25,9 +38,59 @@ func InitFirebase(ctx context.Context, secretPath string) error {
 		return fmt.Errorf("failed to initialize Firebase app: %w", err)
 	}
 
+	app := firebaseApp
+	authClient = lazy.New(func(ctx context.Context) (*auth.Client, error) {
+		return app.Auth(ctx)
+	})
+	warmup.Register("firebase_auth", func(ctx context.Context) error {
+		_, err := authClient.Get(ctx)
+
+		return err
+	})
+
 	return nil
 }
 
+// TokenVerifier verifies Firebase ID tokens, *auth.Client implements it.
+type TokenVerifier interface {
+	VerifyIDToken(ctx context.Context, idToken string) (*auth.Token, error)
+}
+
+// Global verifier used instead of the auth client of the Firebase app, e.g. a fake in handler tests
+var tokenVerifier TokenVerifier
+
+// InitTokenVerifier makes FirebaseAuth verify ID tokens with verifier instead of the Firebase app,
+// so handlers can be exercised without a Firebase project.
+func InitTokenVerifier(verifier TokenVerifier) {
+	tokenVerifier = verifier
+}
+
+// Global loader of the user records of callers, nil when only their tokens are available
+var userLoader caller.UserLoader
+
+// InitUserLoader makes the user record of the caller available to handlers and services through
+// caller.UserFrom, loaded with load the first time it is asked for.
+func InitUserLoader(load caller.UserLoader) {
+	userLoader = load
+}
+
+// withToken returns a copy of ctx carrying the verified token of the caller and, when a loader
+// is set, its user record.
+func withToken(ctx context.Context, token *auth.Token) context.Context {
+	ctx = caller.WithToken(ctx, token)
+	if userLoader != nil {
+		ctx = caller.WithUserLoader(ctx, userLoader)
+	}
+
+	return ctx
+}
+
+// FirebaseApp returns the Firebase app created by InitFirebase, or nil before it is called,
+// so services can use the other Firebase clients such as messaging.
+func FirebaseApp() *firebase.App {
+	return firebaseApp
+}
+
 // FirebaseAuth is middleware that validates Firebase auth tokens
 // and adds the user information to the context.
 // It uses the Firebase Admin SDK to verify the token and extract user claims.
@@ -37,24 +100,34 @@ func InitFirebase(ctx context.Context, secretPath string) error {
 func FirebaseAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Ensure Firebase app is initialized
-		if firebaseApp == nil {
+		if firebaseApp == nil && tokenVerifier == nil {
 			log.Error().Msg("Firebase app not initialized")
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 				"error":   "internal server error",
-				"message": "Firebase client not initialized",
+				"message": i18n.Localize(c, "internal server error", "Firebase client not initialized"),
 			})
 
 			return
 		}
 
-		// Get the auth client
+		// Reject sources locked out after repeated failures before doing any verification work
 		ctx := c.Request.Context()
-		client, err := firebaseApp.Auth(ctx)
+		if authBlocked(ctx, c.ClientIP()) {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":   "too many requests",
+				"message": i18n.Localize(c, "too many requests", "Too many failed authentication attempts, retry later"),
+			})
+
+			return
+		}
+
+		// Get the auth client
+		client, err := verifier(ctx)
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to get Auth client")
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 				"error":   "internal server error",
-				"message": "Failed to get Auth client",
+				"message": i18n.Localize(c, "internal server error", "Failed to get Auth client"),
 			})
 
 			return
@@ -65,9 +138,10 @@ func FirebaseAuth() gin.HandlerFunc {
 		idToken, err := extractToken(authHeader)
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to extract token, invalid format")
+			recordAuthFailure(ctx, c.ClientIP(), "", "invalid_format")
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"error":   "unauthorized",
-				"message": "Invalid token format",
+				"message": i18n.Localize(c, "unauthorized", "Invalid token format"),
 			})
 
 			return
@@ -77,19 +151,53 @@ func FirebaseAuth() gin.HandlerFunc {
 		token, err := client.VerifyIDToken(ctx, idToken)
 		if err != nil {
 			log.Error().Err(err).Msgf("Failed to verify ID token: %v", token)
+			recordAuthFailure(ctx, c.ClientIP(), idToken, "invalid_token")
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"error":   "unauthorized",
-				"message": "Invalid token",
+				"message": i18n.Localize(c, "unauthorized", "Invalid token"),
 			})
 
 			return
 		}
 
+		if rejectRevokedSession(c, token) {
+			return
+		}
+
+		factor := secondFactor(idToken)
+		if requireMFA(c, token.UID, factor) {
+			return
+		}
+
 		// Add the token claims to the context
-		c.Set("user", token)
+		ctx = caller.WithSecondFactor(withToken(c.Request.Context(), token), factor)
+		cohort := telemetry.CohortUser
+		if caller.IsAdmin(ctx) {
+			cohort = telemetry.CohortAdmin
+		}
+		ctx = audit.WithActor(ctx, audit.Actor{
+			Type: audit.ActorTypeUser,
+			ID:   token.UID,
+		})
+		c.Request = c.Request.WithContext(telemetry.WithCohort(ctx, cohort))
 		c.Next()
+		recordActivity(c, token)
+
+	}
+}
+
+// verifier returns the verifier set by InitTokenVerifier, or the auth client of the Firebase app.
+func verifier(ctx context.Context) (TokenVerifier, error) {
+	if tokenVerifier != nil {
+		return tokenVerifier, nil
+	}
 
+	client, err := authClient.Get(ctx)
+	if err != nil {
+		return nil, err
 	}
+
+	return client, nil
 }
 
 // extractToken extracts the token from the Authorization header.
@@ -105,3 +213,34 @@ func extractToken(authHeader string) (string, error) {
 
 	return parts[1], nil
 }
+
+// AdminClaim is the Firebase custom claim that grants access to the admin routes.
+const AdminClaim = caller.AdminClaim
+
+// RequireAdmin is middleware that only lets through users whose verified token carries
+// the admin custom claim. It must run after FirebaseAuth; other users get 403 Forbidden.
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := caller.TokenFrom(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   "unauthorized",
+				"message": i18n.Localize(c, "unauthorized", "Authentication required"),
+			})
+
+			return
+		}
+
+		if !caller.IsAdmin(c) {
+			log.Warn().Str("uid", token.UID).Str("path", c.Request.URL.Path).Msg("Non-admin user denied access to admin route")
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "forbidden",
+				"message": i18n.Localize(c, "forbidden", "Admin access required"),
+			})
+
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,220 @@
+package middleware
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/thoughtgears/shared-services/internal/stats"
+)
+
+// AuthLockoutConfig configures the failed authentication lockout.
+type AuthLockoutConfig struct {
+	// Threshold is the number of failed token verifications from one source within Window
+	// after which the source is blocked for BlockDuration.
+	Threshold     int
+	Window        time.Duration
+	BlockDuration time.Duration
+	// MaxEntries caps the sources tracked at once, DefaultAuthLockoutMaxEntries when 0. Failures
+	// of new sources are only counted on the metrics while the cap is reached.
+	MaxEntries int
+}
+
+// DefaultAuthLockoutMaxEntries is the number of sources tracked at once unless configured.
+const DefaultAuthLockoutMaxEntries = 10000
+
+// Global failure trackers, shared by every route group using FirebaseAuth. Failures are tracked
+// per client IP, which is locked out, and per claimed UID, which only raises alerts. The UIDs come
+// from unverified tokens, so they are kept apart and forged ones cannot crowd out the IPs.
+var (
+	authFailures *authFailureTracker
+	uidFailures  *authFailureTracker
+)
+
+// InitAuthLockout enables failed authentication tracking on server startup. Expired entries are
+// dropped in the background every window. FirebaseAuth only records metrics until it has been called.
+func InitAuthLockout(cfg AuthLockoutConfig) {
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = DefaultAuthLockoutMaxEntries
+	}
+
+	authFailures = newAuthFailureTracker(cfg)
+	uidFailures = newAuthFailureTracker(cfg)
+
+	go func() {
+		ticker := time.NewTicker(max(cfg.Window, time.Second))
+		defer ticker.Stop()
+
+		for now := range ticker.C {
+			authFailures.sweep(now)
+			uidFailures.sweep(now)
+		}
+	}()
+}
+
+// authMetrics are the anomaly counters for token verification. They are created from the global
+// meter provider, so they are only exported once telemetry has been initialised.
+var authMetrics = sync.OnceValue(func() *authCounters {
+	meter := otel.Meter("github.com/thoughtgears/shared-services/internal/router/middleware")
+	failures, _ := meter.Int64Counter("auth.failures", metric.WithDescription("Failed token verifications"))
+	lockouts, _ := meter.Int64Counter("auth.lockouts", metric.WithDescription("Sources blocked after repeated failed token verifications"))
+	blocked, _ := meter.Int64Counter("auth.blocked_requests", metric.WithDescription("Requests rejected while the source was blocked"))
+
+	return &authCounters{failures: failures, lockouts: lockouts, blocked: blocked}
+})
+
+type authCounters struct {
+	failures metric.Int64Counter
+	lockouts metric.Int64Counter
+	blocked  metric.Int64Counter
+}
+
+type failureEntry struct {
+	count        int
+	windowStart  time.Time
+	blockedUntil time.Time
+}
+
+type authFailureTracker struct {
+	cfg     AuthLockoutConfig
+	mu      sync.Mutex
+	entries map[string]*failureEntry
+}
+
+func newAuthFailureTracker(cfg AuthLockoutConfig) *authFailureTracker {
+	return &authFailureTracker{
+		cfg:     cfg,
+		entries: make(map[string]*failureEntry),
+	}
+}
+
+// blocked reports whether the key is currently locked out.
+func (t *authFailureTracker) blocked(key string) bool {
+	if t == nil {
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry, ok := t.entries[key]
+
+	return ok && time.Now().Before(entry.blockedUntil)
+}
+
+// fail records a failure for key and reports whether it caused a new lockout. A key that is not
+// tracked yet is ignored while the tracker holds MaxEntries keys.
+func (t *authFailureTracker) fail(key string) bool {
+	if t == nil {
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	entry, ok := t.entries[key]
+	if !ok && len(t.entries) >= t.cfg.MaxEntries {
+		stats.Add("auth_lockout.untracked", 1)
+
+		return false
+	}
+	if !ok || now.Sub(entry.windowStart) > t.cfg.Window {
+		entry = &failureEntry{windowStart: now}
+		t.entries[key] = entry
+	}
+	entry.count++
+
+	if entry.count >= t.cfg.Threshold && now.After(entry.blockedUntil) {
+		entry.blockedUntil = now.Add(t.cfg.BlockDuration)
+		entry.count = 0
+		entry.windowStart = now
+
+		return true
+	}
+
+	return false
+}
+
+// sweep drops entries whose window and block have both expired.
+func (t *authFailureTracker) sweep(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key, entry := range t.entries {
+		if now.Sub(entry.windowStart) > t.cfg.Window && now.After(entry.blockedUntil) {
+			delete(t.entries, key)
+		}
+	}
+}
+
+// recordAuthFailure counts a failed token verification for the client IP and, when the token can be
+// decoded, the UID it claims to be for. Only the IP is locked out: the UID comes from an unverified
+// token, so blocking on it would let anyone lock a victim out of their account with forged tokens.
+// Repeated failures for one UID still raise an alert, as they point to credential stuffing.
+func recordAuthFailure(ctx context.Context, clientIP, idToken, reason string) {
+	uid := unverifiedSubject(idToken)
+
+	authMetrics().failures.Add(ctx, 1, metric.WithAttributes(attribute.String("reason", reason)))
+
+	if authFailures.fail(clientIP) {
+		authMetrics().lockouts.Add(ctx, 1, metric.WithAttributes(attribute.String("key", "ip")))
+		log.Error().
+			Str("alert", "auth_lockout").
+			Str("client_ip", clientIP).
+			Dur("block_duration", authFailures.cfg.BlockDuration).
+			Msg("Client blocked after repeated failed authentication")
+	}
+
+	if uid != "" && uidFailures.fail(uid) {
+		authMetrics().lockouts.Add(ctx, 1, metric.WithAttributes(attribute.String("key", "uid")))
+		log.Error().
+			Str("alert", "auth_failures_for_uid").
+			Str("uid", uid).
+			Str("client_ip", clientIP).
+			Msg("Repeated failed authentication for one user")
+	}
+}
+
+// authBlocked reports whether the client IP is locked out and counts the rejected request.
+func authBlocked(ctx context.Context, clientIP string) bool {
+	if !authFailures.blocked(clientIP) {
+		return false
+	}
+
+	authMetrics().blocked.Add(ctx, 1)
+	stats.Add("auth_lockout.blocked", 1)
+
+	return true
+}
+
+// unverifiedSubject returns the sub claim of a JWT without verifying it, or an empty string.
+// It must only be used for counting, never for authorisation.
+func unverifiedSubject(idToken string) string {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ""
+	}
+
+	var claims struct {
+		Subject string `json:"sub"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || len(claims.Subject) > 128 {
+		return ""
+	}
+
+	return claims.Subject
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuthFailureTrackerBlocksAtThreshold(t *testing.T) {
+	tracker := newAuthFailureTracker(AuthLockoutConfig{Threshold: 3, Window: time.Minute, BlockDuration: time.Minute, MaxEntries: 10})
+
+	for i := 1; i < 3; i++ {
+		if tracker.fail("192.0.2.1") {
+			t.Fatalf("fail() #%d locked out, want a lockout at the threshold of 3", i)
+		}
+	}
+	if !tracker.fail("192.0.2.1") {
+		t.Fatal("fail() #3 did not lock out")
+	}
+	if !tracker.blocked("192.0.2.1") {
+		t.Error("blocked() = false after a lockout")
+	}
+	if tracker.blocked("192.0.2.2") {
+		t.Error("blocked() = true for another source")
+	}
+}
+
+func TestAuthFailureTrackerBoundsEntries(t *testing.T) {
+	tracker := newAuthFailureTracker(AuthLockoutConfig{Threshold: 1, Window: time.Minute, BlockDuration: time.Minute, MaxEntries: 2})
+
+	tracker.fail("192.0.2.1")
+	tracker.fail("192.0.2.2")
+	if tracker.fail("192.0.2.3") {
+		t.Error("fail() locked out a source beyond MaxEntries")
+	}
+	if got := len(tracker.entries); got != 2 {
+		t.Errorf("entries = %d, want 2", got)
+	}
+
+	// Expired entries make room again
+	tracker.sweep(time.Now().Add(2 * time.Minute))
+	if got := len(tracker.entries); got != 0 {
+		t.Errorf("entries after sweep = %d, want 0", got)
+	}
+	if !tracker.fail("192.0.2.3") {
+		t.Error("fail() did not track a source after the sweep")
+	}
+}
+
+func TestUnverifiedSubject(t *testing.T) {
+	token := func(payload string) string {
+		return "header." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".signature"
+	}
+
+	tests := map[string]struct {
+		idToken string
+		want    string
+	}{
+		"subject":      {token(`{"sub":"user-1"}`), "user-1"},
+		"not a jwt":    {"opaque-token", ""},
+		"bad payload":  {"header.!!!.signature", ""},
+		"long subject": {token(`{"sub":"` + strings.Repeat("a", 129) + `"}`), ""},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := unverifiedSubject(tt.idToken); got != tt.want {
+				t.Errorf("unverifiedSubject() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/thoughtgears/shared-services/internal/caller"
+	"github.com/thoughtgears/shared-services/internal/router/middleware"
+	"github.com/thoughtgears/shared-services/pkg/httptestutil"
+)
+
+// protected serves GET /protected behind FirebaseAuth.
+type protected struct{}
+
+func (protected) RegisterRoutes(r *gin.Engine) {
+	r.GET("/protected", middleware.FirebaseAuth(), func(c *gin.Context) {
+		uid, _ := caller.UIDFrom(c.Request.Context())
+		c.JSON(http.StatusOK, gin.H{"status": http.StatusOK, "data": uid})
+	})
+}
+
+func TestFirebaseAuthLockout(t *testing.T) {
+	middleware.InitAuthLockout(middleware.AuthLockoutConfig{Threshold: 3, Window: time.Minute, BlockDuration: time.Minute})
+	verifier := httptestutil.NewVerifier()
+	engine := httptestutil.NewRouter(verifier, protected{})
+	token := verifier.Issue(httptestutil.User("user-1"))
+
+	from := func(ip, idToken string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+		req.RemoteAddr = ip + ":1234"
+
+		return httptestutil.Serve(engine, req, idToken)
+	}
+
+	rec := from("192.0.2.10", token)
+	httptestutil.AssertStatus(t, rec, http.StatusOK)
+	if uid := httptestutil.DecodeData[string](t, rec); uid != "user-1" {
+		t.Errorf("caller UID = %q, want %q", uid, "user-1")
+	}
+	for range 3 {
+		httptestutil.AssertStatus(t, from("192.0.2.10", "forged."+token), http.StatusUnauthorized)
+	}
+
+	// The source is locked out even with a valid token, other sources are not
+	httptestutil.AssertStatus(t, from("192.0.2.10", token), http.StatusTooManyRequests)
+	httptestutil.AssertStatus(t, from("192.0.2.11", token), http.StatusOK)
+}
@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/i18n"
+)
+
+const (
+	// DefaultCSRFCookieName is the cookie the CSRF token is issued in. It is readable by scripts
+	// so the frontend can copy it into the CSRF header.
+	DefaultCSRFCookieName = "csrf_token"
+	// DefaultCSRFHeaderName is the header browser clients echo the CSRF token in.
+	DefaultCSRFHeaderName = "X-CSRF-Token"
+	// DefaultSessionCookieName is the session cookie that marks a request as cookie authenticated.
+	// Firebase Hosting only forwards a cookie with this name to backends.
+	DefaultSessionCookieName = "__session"
+
+	csrfTokenBytes = 32
+)
+
+// CSRFConfig configures the CSRF middleware.
+type CSRFConfig struct {
+	// RouteGroups are the path prefixes of the route groups that are protected, e.g. "/v1/users".
+	// An empty list protects every route.
+	RouteGroups []string
+	// CookieName, HeaderName and SessionCookieName default to the Default* constants when empty.
+	CookieName        string
+	HeaderName        string
+	SessionCookieName string
+	// Secure marks the CSRF cookie as HTTPS only. It should only be false locally.
+	Secure bool
+}
+
+// CSRF is middleware implementing double-submit token CSRF protection for cookie based sessions.
+// Safe requests (GET, HEAD, OPTIONS) receive a random token in a cookie if they do not have one.
+// Unsafe requests must send the same token in the CSRF header, otherwise they are rejected with 403.
+//
+// Requests without the session cookie are exempt: API clients that authenticate with a bearer
+// token cannot be forged by a browser, since browsers do not attach Authorization headers on their own.
+func CSRF(cfg CSRFConfig) gin.HandlerFunc {
+	if cfg.CookieName == "" {
+		cfg.CookieName = DefaultCSRFCookieName
+	}
+	if cfg.HeaderName == "" {
+		cfg.HeaderName = DefaultCSRFHeaderName
+	}
+	if cfg.SessionCookieName == "" {
+		cfg.SessionCookieName = DefaultSessionCookieName
+	}
+
+	return func(c *gin.Context) {
+		if !matchesRouteGroup(c.Request.URL.Path, cfg.RouteGroups) {
+			c.Next()
+
+			return
+		}
+
+		cookieToken, _ := c.Cookie(cfg.CookieName)
+
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			if cookieToken == "" {
+				token, err := newCSRFToken()
+				if err != nil {
+					log.Error().Err(err).Msg("Failed to generate CSRF token")
+					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+						"error":   "internal server error",
+						"message": i18n.Localize(c, "internal server error", "Failed to generate CSRF token"),
+					})
+
+					return
+				}
+				c.SetSameSite(http.SameSiteStrictMode)
+				c.SetCookie(cfg.CookieName, token, 0, "/", "", cfg.Secure, false)
+			}
+			c.Next()
+
+			return
+		}
+
+		if _, err := c.Cookie(cfg.SessionCookieName); err != nil {
+			c.Next()
+
+			return
+		}
+
+		headerToken := c.GetHeader(cfg.HeaderName)
+		if cookieToken == "" || subtle.ConstantTimeCompare([]byte(cookieToken), []byte(headerToken)) != 1 {
+			log.Warn().Str("path", c.Request.URL.Path).Str("method", c.Request.Method).Msg("CSRF token missing or invalid")
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "forbidden",
+				"message": i18n.Localize(c, "forbidden", "CSRF token missing or invalid"),
+			})
+
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// matchesRouteGroup reports whether path is inside one of the route group prefixes.
+// An empty list of groups matches every path.
+func matchesRouteGroup(path string, groups []string) bool {
+	if len(groups) == 0 {
+		return true
+	}
+
+	for _, group := range groups {
+		group = strings.TrimSuffix(group, "/")
+		if path == group || strings.HasPrefix(path, group+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newCSRFToken returns a random URL safe token.
+func newCSRFToken() (string, error) {
+	b := make([]byte, csrfTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/thoughtgears/shared-services/internal/debugcapture"
+	"github.com/thoughtgears/shared-services/internal/redact"
+	"github.com/thoughtgears/shared-services/internal/requestid"
+	"github.com/thoughtgears/shared-services/internal/tenant"
+)
+
+// Global debug capture recorder, nil when debug capture is not available
+var debugRecorder *debugcapture.Recorder
+
+// InitDebugCapture makes debug capture available on server startup. Capturing itself
+// stays off until it is enabled on the recorder, usually through the admin API.
+func InitDebugCapture(recorder *debugcapture.Recorder) {
+	debugRecorder = recorder
+}
+
+// captureWriter keeps the start of the response body of failed requests.
+type captureWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *captureWriter) Write(data []byte) (int, error) {
+	if w.Status() >= http.StatusBadRequest && w.body.Len() < debugcapture.MaxBodySize {
+		w.body.Write(data[:min(len(data), debugcapture.MaxBodySize-w.body.Len())])
+	}
+
+	return w.ResponseWriter.Write(data)
+}
+
+// DebugCapture is middleware that records the sanitized request and response bodies of a sample
+// of 4xx and 5xx responses while debug capture is enabled. Multipart uploads are recorded without
+// their body. Bodies are redacted like log lines before they are stored.
+func DebugCapture() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !debugRecorder.Active() {
+			c.Next()
+			return
+		}
+
+		contentType := c.GetHeader("Content-Type")
+		var requestBody []byte
+		truncated := false
+		if c.Request.Body != nil && !strings.HasPrefix(contentType, "multipart/") {
+			// Only the start of the body is read, the handler still sees the whole body
+			requestBody, _ = io.ReadAll(io.LimitReader(c.Request.Body, debugcapture.MaxBodySize+1))
+			truncated = len(requestBody) > debugcapture.MaxBodySize
+			c.Request.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(requestBody), c.Request.Body), c.Request.Body}
+			requestBody = requestBody[:min(len(requestBody), debugcapture.MaxBodySize)]
+		}
+
+		writer := &captureWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		status := c.Writer.Status()
+		if status < http.StatusBadRequest || !debugRecorder.Sample() {
+			return
+		}
+
+		debugRecorder.Add(debugcapture.Capture{
+			Time:         time.Now().UTC(),
+			RequestID:    requestid.From(c.Request.Context()),
+			TenantID:     tenant.From(c.Request.Context()),
+			Method:       c.Request.Method,
+			Path:         c.Request.URL.Path,
+			Status:       status,
+			ContentType:  contentType,
+			RequestBody:  string(redact.Bytes(requestBody)),
+			ResponseBody: string(redact.Bytes(writer.body.Bytes())),
+			Truncated:    truncated || writer.body.Len() >= debugcapture.MaxBodySize,
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/thoughtgears/shared-services/internal/audit"
+	"github.com/thoughtgears/shared-services/internal/errorreporting"
+	"github.com/thoughtgears/shared-services/internal/i18n"
+)
+
+// maxReportedBody caps how much of a 5xx response body is kept to read its error message.
+const maxReportedBody = 4 << 10
+
+// Global error reporter, nil when error reporting is disabled
+var errorReporter *errorreporting.Reporter
+
+// InitErrorReporting enables reporting of 5xx responses and panics on server startup.
+func InitErrorReporting(reporter *errorreporting.Reporter) {
+	errorReporter = reporter
+}
+
+// errorBodyWriter keeps the start of the response body of server errors, so the reported
+// event carries the handler's error message without every handler having to report it.
+type errorBodyWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *errorBodyWriter) Write(data []byte) (int, error) {
+	if w.Status() >= http.StatusInternalServerError && w.body.Len() < maxReportedBody {
+		w.body.Write(data[:min(len(data), maxReportedBody-w.body.Len())])
+	}
+
+	return w.ResponseWriter.Write(data)
+}
+
+// ErrorReporting is middleware that reports 5xx responses and recovered panics to Error Reporting,
+// with the request, the authenticated user and, for panics, the stack. Panics are answered with
+// a 500 response. Events are grouped by method, route and status code.
+// It is a no-op until InitErrorReporting has been called.
+func ErrorReporting() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if errorReporter == nil {
+			c.Next()
+			return
+		}
+
+		writer := &errorBodyWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+
+				reportError(c, http.StatusInternalServerError, fmt.Sprint(recovered), debug.Stack())
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+					"error":   "internal server error",
+					"message": i18n.Localize(c, "internal server error", "An unexpected error occurred"),
+					"status":  http.StatusInternalServerError,
+				})
+			}
+		}()
+
+		c.Next()
+
+		if status := c.Writer.Status(); status >= http.StatusInternalServerError {
+			reportError(c, status, errorMessage(c, writer.body.Bytes()), nil)
+		}
+	}
+}
+
+// reportError sends the event for the current request.
+func reportError(c *gin.Context, status int, message string, stack []byte) {
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+
+	userID := ""
+	if actor := audit.ActorFrom(c.Request.Context()); actor.Type == audit.ActorTypeUser {
+		userID = actor.ID
+	}
+
+	errorReporter.Report(c.Request.Context(), errorreporting.Event{
+		Code:       c.Request.Method + " " + route + " " + strconv.Itoa(status),
+		Message:    message,
+		Stack:      stack,
+		Handler:    c.HandlerName(),
+		Method:     c.Request.Method,
+		URL:        c.Request.URL.Path,
+		UserAgent:  c.Request.UserAgent(),
+		RemoteIP:   c.ClientIP(),
+		StatusCode: status,
+		UserID:     userID,
+	})
+}
+
+// errorMessage returns the errors attached to the context, or else the error field of the response body.
+func errorMessage(c *gin.Context, body []byte) string {
+	if len(c.Errors) > 0 {
+		return c.Errors.String()
+	}
+
+	var response struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &response); err == nil && response.Error != "" {
+		return response.Message + ": " + response.Error
+	}
+
+	return http.StatusText(c.Writer.Status())
+}
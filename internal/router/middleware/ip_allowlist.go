@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/i18n"
+)
+
+// ParseCIDRs parses a list of CIDR ranges. Plain IP addresses are accepted as single host ranges.
+func ParseCIDRs(ranges []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(ranges))
+	for _, r := range ranges {
+		if ip := net.ParseIP(r); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR range %q: %w", r, err)
+		}
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
+// IPAllowlist is middleware that only lets requests from the given networks through to the route groups
+// with the given path prefixes. Other sources are logged and rejected with 403.
+// The client IP is taken from gin's ClientIP, so the router's trusted proxies decide whether
+// X-Forwarded-For is honoured. It must only be used when the router resolves the client address,
+// see router.Router.ResolvesClientIP.
+func IPAllowlist(routeGroups []string, allowed []*net.IPNet) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(routeGroups) == 0 || !matchesRouteGroup(c.Request.URL.Path, routeGroups) {
+			c.Next()
+
+			return
+		}
+
+		clientIP := c.ClientIP()
+		ip := net.ParseIP(clientIP)
+		for _, network := range allowed {
+			if ip != nil && network.Contains(ip) {
+				c.Next()
+
+				return
+			}
+		}
+
+		log.Warn().Str("client_ip", clientIP).Str("path", c.Request.URL.Path).Msg("Request rejected by IP allowlist")
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error":   "forbidden",
+			"message": i18n.Localize(c, "forbidden", "Source address not allowed"),
+		})
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/requestid"
 )
 
 // Logger returns a gin.HandlerFunc (middleware) that logs requests using
@@ -96,6 +98,7 @@ func StructuredLogger(logger *zerolog.Logger) gin.HandlerFunc {
 
 		// Log structured event with relevant fields
 		logEvent.Str("client_id", param.ClientIP).
+			Str("request_id", requestid.From(c.Request.Context())).
 			Str("method", param.Method).
 			Int("status_code", param.StatusCode).
 			Int("body_size", param.BodySize).
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/thoughtgears/shared-services/internal/stats"
+	"github.com/thoughtgears/shared-services/internal/telemetry"
+)
+
+// httpMetrics are the request instruments, created from the global meter provider.
+var httpMetrics = sync.OnceValue(func() *httpInstruments {
+	meter := otel.Meter("github.com/thoughtgears/shared-services/internal/router/middleware")
+	requests, _ := meter.Int64Counter("http.server.requests", metric.WithDescription("HTTP requests handled"))
+	duration, _ := meter.Float64Histogram("http.server.request.duration",
+		metric.WithDescription("Duration of HTTP requests"),
+		metric.WithUnit("s"),
+	)
+
+	return &httpInstruments{requests: requests, duration: duration}
+})
+
+type httpInstruments struct {
+	requests metric.Int64Counter
+	duration metric.Float64Histogram
+}
+
+// Metrics is middleware that counts requests and records their duration, labelled with the route,
+// status code, tenant and caller cohort, so error rates and usage can be broken down per customer.
+// The route template is used instead of the raw path to keep cardinality bounded, and requests
+// that match no route are grouped together.
+// Once InitSLO has been called matched requests are also counted against the availability and latency SLIs.
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Request = c.Request.WithContext(telemetry.WithCohort(c.Request.Context(), telemetry.CohortAnonymous))
+
+		done := stats.Start("requests")
+		c.Next()
+		done()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		// The tenant and cohort are stored on the request by the auth middleware further down the
+		// chain, so they are read from the request after the handlers ran.
+		attrs := append(telemetry.Dimensions(c.Request.Context()),
+			attribute.String("http.request.method", c.Request.Method),
+			attribute.String("http.route", route),
+			attribute.String("http.response.status_code", strconv.Itoa(c.Writer.Status())),
+		)
+
+		duration := time.Since(start)
+		instruments := httpMetrics()
+		instruments.requests.Add(c.Request.Context(), 1, metric.WithAttributes(attrs...))
+		instruments.duration.Record(c.Request.Context(), duration.Seconds(), metric.WithAttributes(attrs...))
+
+		if route != "unmatched" {
+			recordSLIs(c.Request.Context(), route, c.Writer.Status(), duration)
+		}
+		if c.Writer.Status() >= http.StatusBadRequest {
+			stats.RecordError(strconv.Itoa(c.Writer.Status()))
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/i18n"
+)
+
+// MFARequiredCode is the error code of the 403 response for routes that need a token from a
+// multi-factor sign-in, so clients can prompt for the second factor instead of showing a denial.
+const MFARequiredCode = "mfa_required"
+
+// Global set of "METHOD /route" keys that require multi-factor authentication, nil when disabled
+var mfaRoutes map[string]bool
+
+// InitMFAEnforcement requires tokens from a multi-factor sign-in for the routes, given as
+// "METHOD /route" with the route as registered, e.g. "GET /v1/documents/:id/content".
+// FirebaseAuth accepts single-factor tokens everywhere until it has been called.
+func InitMFAEnforcement(routes []string) {
+	mfaRoutes = make(map[string]bool, len(routes))
+	for _, route := range routes {
+		mfaRoutes[strings.TrimSpace(route)] = true
+	}
+}
+
+// requireMFA aborts the request with 403 Forbidden when its route requires multi-factor
+// authentication and the verified token was signed in without a second factor.
+func requireMFA(c *gin.Context, uid string, factor string) bool {
+	if !mfaRoutes[c.Request.Method+" "+c.FullPath()] || factor != "" {
+		return false
+	}
+
+	log.Warn().Str("uid", uid).Str("path", c.Request.URL.Path).Msg("Single-factor token denied access to MFA route")
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+		"error":   MFARequiredCode,
+		"message": i18n.Localize(c, MFARequiredCode, "Multi-factor authentication is required for this action"),
+	})
+
+	return true
+}
+
+// secondFactor returns the second factor, e.g. "phone" or "totp", the ID token was signed in
+// with, or an empty string for single-factor sign-ins. The Admin SDK drops the firebase claim it
+// is in, so it is read from the payload; the token must have been verified before.
+func secondFactor(idToken string) string {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ""
+	}
+
+	var claims struct {
+		Firebase struct {
+			SecondFactor string `json:"sign_in_second_factor"`
+		} `json:"firebase"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return ""
+	}
+
+	return claims.Firebase.SecondFactor
+}
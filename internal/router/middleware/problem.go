@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem details.
+const ProblemContentType = "application/problem+json"
+
+// Problem is an error response in the RFC 7807 format. Code is the error field of the standard
+// envelope and Fields its field errors, both carried as extension members.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code,omitempty"`
+	Fields   any    `json:"fields,omitempty"`
+}
+
+// problemWriter holds back JSON error responses, so they can be rewritten as problem details
+// once the handler is done.
+type problemWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	buffered bool
+}
+
+func (w *problemWriter) Write(data []byte) (int, error) {
+	if w.buffered || (w.Status() >= http.StatusBadRequest && isJSON(w.Header().Get("Content-Type"))) {
+		w.buffered = true
+
+		return w.body.Write(data)
+	}
+
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *problemWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// ProblemDetails is middleware that answers errors in the RFC 7807 application/problem+json format
+// to clients that ask for it in their Accept header. Everyone else keeps the standard envelope,
+// which handlers and middleware keep writing; it is converted on the way out.
+func ProblemDetails() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.NegotiateFormat(gin.MIMEJSON, ProblemContentType) != ProblemContentType {
+			c.Next()
+
+			return
+		}
+
+		writer := &problemWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if !writer.buffered {
+			return
+		}
+
+		body := writer.body.Bytes()
+		if problem, ok := toProblem(c, writer.Status(), body); ok {
+			if encoded, err := json.Marshal(problem); err == nil {
+				c.Header("Content-Type", ProblemContentType)
+				body = encoded
+			}
+		}
+		c.Header("Content-Length", strconv.Itoa(len(body)))
+		_, _ = c.Writer.Write(body)
+	}
+}
+
+// toProblem converts a standard error envelope to problem details.
+func toProblem(c *gin.Context, status int, body []byte) (Problem, bool) {
+	var envelope struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+		Fields  any    `json:"fields"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return Problem{}, false
+	}
+
+	return Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   envelope.Message,
+		Instance: c.Request.URL.Path,
+		Code:     envelope.Error,
+		Fields:   envelope.Fields,
+	}, true
+}
+
+// isJSON reports whether a Content-Type is JSON.
+func isJSON(contentType string) bool {
+	return strings.HasPrefix(contentType, gin.MIMEJSON)
+}
@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/time/rate"
+
+	"github.com/thoughtgears/shared-services/internal/caller"
+	"github.com/thoughtgears/shared-services/internal/i18n"
+	"github.com/thoughtgears/shared-services/internal/stats"
+)
+
+// RateLimitBudget is a token bucket budget: Rate requests per second on average with bursts up to Burst.
+type RateLimitBudget struct {
+	Rate  float64
+	Burst int
+}
+
+// UserRateLimitConfig configures the per-user rate limits.
+// Uploads cover every request that changes data, reads cover GET and HEAD requests,
+// so large uploads cannot starve a user's ability to browse and the other way around.
+type UserRateLimitConfig struct {
+	Read   RateLimitBudget
+	Upload RateLimitBudget
+	// IdleTTL is how long the limiter of an inactive user is kept before it is dropped.
+	IdleTTL time.Duration
+}
+
+// Global per-user limiters, shared by all route groups so a user has one budget across the API
+var userLimits *userRateLimiter
+
+// InitUserRateLimit enables per-user rate limiting on server startup.
+// UserRateLimit lets every request through until it has been called.
+func InitUserRateLimit(cfg UserRateLimitConfig) {
+	userLimits = &userRateLimiter{
+		cfg:      cfg,
+		limiters: make(map[string]*userLimiter),
+	}
+}
+
+// UserRateLimit is middleware that limits requests per authenticated Firebase UID, with
+// separate budgets for reads and uploads. It must run after FirebaseAuth. Requests over
+// budget are rejected with 429 and a Retry-After header.
+// IP based limits cannot tell apart users behind the same NAT, this keeps a single abusive
+// account from exhausting the service for everyone else.
+func UserRateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, _ := caller.UIDFrom(c)
+		if userLimits == nil || uid == "" {
+			c.Next()
+
+			return
+		}
+
+		budget := "upload"
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			budget = "read"
+		}
+
+		reservation := userLimits.reserve(uid, budget)
+		if delay := reservation.Delay(); !reservation.OK() || delay > 0 {
+			reservation.Cancel()
+			log.Warn().Str("uid", uid).Str("budget", budget).Msg("User rate limit exceeded")
+			stats.Add("rate_limit.rejected."+budget, 1)
+			if reservation.OK() {
+				c.Header("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			}
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":   "too many requests",
+				"message": i18n.Localize(c, "too many requests", "Rate limit exceeded, retry later"),
+			})
+
+			return
+		}
+
+		c.Next()
+	}
+}
+
+type userLimiter struct {
+	read     *rate.Limiter
+	upload   *rate.Limiter
+	lastSeen time.Time
+}
+
+type userRateLimiter struct {
+	cfg       UserRateLimitConfig
+	mu        sync.Mutex
+	limiters  map[string]*userLimiter
+	lastSweep time.Time
+}
+
+// reserve takes a token from the user's budget. Idle limiters are swept at most once per
+// IdleTTL, so memory stays bounded by the number of recently active users.
+func (l *userRateLimiter) reserve(uid, budget string) *rate.Reservation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if l.cfg.IdleTTL > 0 && now.Sub(l.lastSweep) > l.cfg.IdleTTL {
+		for id, limiter := range l.limiters {
+			if now.Sub(limiter.lastSeen) > l.cfg.IdleTTL {
+				delete(l.limiters, id)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	limiter, ok := l.limiters[uid]
+	if !ok {
+		limiter = &userLimiter{
+			read:   rate.NewLimiter(rate.Limit(l.cfg.Read.Rate), l.cfg.Read.Burst),
+			upload: rate.NewLimiter(rate.Limit(l.cfg.Upload.Rate), l.cfg.Upload.Burst),
+		}
+		l.limiters[uid] = limiter
+	}
+	limiter.lastSeen = now
+
+	if budget == "read" {
+		return limiter.read.ReserveN(now, 1)
+	}
+
+	return limiter.upload.ReserveN(now, 1)
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/thoughtgears/shared-services/internal/requestid"
+)
+
+// maxRequestIDLength limits the size of client supplied request IDs.
+const maxRequestIDLength = 128
+
+// RequestID is middleware that assigns every request an ID.
+// It reuses the X-Request-ID header if the client or load balancer sent one,
+// otherwise it generates a new UUID. The ID is stored in the request context,
+// so services and the audit log can read it, and echoed in the response header.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestid.Header)
+		if id == "" || len(id) > maxRequestIDLength {
+			id = uuid.NewString()
+		}
+
+		c.Request = c.Request.WithContext(requestid.With(c.Request.Context(), id))
+		c.Header(requestid.Header, id)
+		c.Next()
+	}
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/audit"
+	"github.com/thoughtgears/shared-services/internal/caller"
+	"github.com/thoughtgears/shared-services/internal/i18n"
+	"github.com/thoughtgears/shared-services/internal/idtoken"
+	"github.com/thoughtgears/shared-services/internal/telemetry"
+)
+
+// ServiceAuthConfig configures verification of service-to-service ID tokens.
+type ServiceAuthConfig struct {
+	// Audiences are the audiences callers mint tokens for, usually the service's own URLs.
+	Audiences []string
+	// AllowedCallers are the service account emails allowed to call.
+	AllowedCallers []string
+}
+
+// Global verifier for service-to-service tokens
+var serviceVerifier *idtoken.Verifier
+
+// InitServiceAuth initializes the service-to-service token verifier on server startup.
+func InitServiceAuth(ctx context.Context, cfg ServiceAuthConfig) error {
+	verifier, err := idtoken.NewVerifier(ctx, cfg.Audiences, cfg.AllowedCallers)
+	if err != nil {
+		return err
+	}
+	serviceVerifier = verifier
+
+	return nil
+}
+
+// ServiceAuth is middleware that only lets through other services presenting a Google-signed ID token
+// from an allowed service account. The token is read from X-Serverless-Authorization, falling back to
+// Authorization. The caller is recorded as a service actor for auditing.
+func ServiceAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Failing closed: a missing verifier must not open service routes to everyone
+		if serviceVerifier == nil {
+			log.Error().Msg("Service auth not initialized")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal server error",
+				"message": i18n.Localize(c, "internal server error", "Service authentication not initialized"),
+			})
+
+			return
+		}
+
+		ctx := c.Request.Context()
+		if authBlocked(ctx, c.ClientIP()) {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":   "too many requests",
+				"message": i18n.Localize(c, "too many requests", "Too many failed authentication attempts, retry later"),
+			})
+
+			return
+		}
+
+		authHeader := c.GetHeader(idtoken.ServerlessAuthorizationHeader)
+		if authHeader == "" {
+			authHeader = c.GetHeader("Authorization")
+		}
+
+		token, err := extractToken(authHeader)
+		if err != nil {
+			recordAuthFailure(ctx, c.ClientIP(), "", "invalid_format")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   "unauthorized",
+				"message": i18n.Localize(c, "unauthorized", "Invalid token format"),
+			})
+
+			return
+		}
+
+		identity, err := serviceVerifier.Verify(ctx, token)
+		if err != nil {
+			log.Warn().Err(err).Str("path", c.Request.URL.Path).Msg("Failed to verify service ID token")
+			recordAuthFailure(ctx, c.ClientIP(), "", "invalid_service_token")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   "unauthorized",
+				"message": i18n.Localize(c, "unauthorized", "Invalid token"),
+			})
+
+			return
+		}
+
+		ctx = audit.WithActor(caller.WithService(ctx, identity), audit.Actor{
+			Type: audit.ActorTypeService,
+			ID:   identity.Email,
+		})
+		c.Request = c.Request.WithContext(telemetry.WithCohort(ctx, telemetry.CohortService))
+		c.Next()
+	}
+}
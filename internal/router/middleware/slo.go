@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// SLOConfig configures the SLI counters recorded by Metrics.
+type SLOConfig struct {
+	// LatencyThreshold is the response time under which a request counts as good for the latency SLI.
+	LatencyThreshold time.Duration
+	// GroupThresholds overrides LatencyThreshold per route group, e.g. "/v1/documents".
+	GroupThresholds map[string]time.Duration
+}
+
+// Global SLO settings, SLIs are not recorded until InitSLO is called
+var sloConfig *SLOConfig
+
+// InitSLO enables the availability and latency SLI counters on server startup.
+func InitSLO(cfg SLOConfig) {
+	sloConfig = &cfg
+}
+
+// ParseSLOThresholds parses "/group=duration" pairs, e.g. "/v1/documents=1s".
+func ParseSLOThresholds(pairs []string) (map[string]time.Duration, error) {
+	thresholds := make(map[string]time.Duration, len(pairs))
+	for _, pair := range pairs {
+		group, rawThreshold, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid SLO threshold %q, expected /group=duration", pair)
+		}
+
+		threshold, err := time.ParseDuration(rawThreshold)
+		if err != nil || threshold <= 0 {
+			return nil, fmt.Errorf("invalid SLO threshold for %s: %q", group, rawThreshold)
+		}
+		thresholds[group] = threshold
+	}
+
+	return thresholds, nil
+}
+
+// sloMetrics are the SLI counters. Each request is counted once per SLI, labelled good or bad,
+// so burn rates are plain ratios of counters in Cloud Monitoring.
+var sloMetrics = sync.OnceValue(func() *sloInstruments {
+	meter := otel.Meter("github.com/thoughtgears/shared-services/internal/router/middleware")
+	availability, _ := meter.Int64Counter("slo.availability.requests",
+		metric.WithDescription("Requests counted for the availability SLI, bad when the response is a server error"))
+	latency, _ := meter.Int64Counter("slo.latency.requests",
+		metric.WithDescription("Requests counted for the latency SLI, bad when slower than the route group's threshold"))
+
+	return &sloInstruments{availability: availability, latency: latency}
+})
+
+type sloInstruments struct {
+	availability metric.Int64Counter
+	latency      metric.Int64Counter
+}
+
+// recordSLIs counts a finished request against the availability and latency SLIs of its route group.
+// Only successful requests are judged on latency, errors already count against availability.
+func recordSLIs(ctx context.Context, route string, status int, duration time.Duration) {
+	if sloConfig == nil {
+		return
+	}
+
+	group := routeGroup(route)
+	instruments := sloMetrics()
+
+	available := status < http.StatusInternalServerError
+	instruments.availability.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("slo.route_group", group),
+		attribute.Bool("slo.good", available),
+	))
+
+	if !available {
+		return
+	}
+
+	threshold := sloConfig.LatencyThreshold
+	if groupThreshold, ok := sloConfig.GroupThresholds[group]; ok {
+		threshold = groupThreshold
+	}
+	instruments.latency.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("slo.route_group", group),
+		attribute.Bool("slo.good", duration <= threshold),
+	))
+}
+
+// routeGroup returns the API version and resource of a route template, e.g. "/v1/documents"
+// for "/v1/documents/:id/download-url". Unversioned routes are grouped by their first segment.
+func routeGroup(route string) string {
+	segments := strings.SplitN(strings.TrimPrefix(route, "/"), "/", 3)
+	if len(segments) >= 2 && strings.HasPrefix(segments[0], "v") {
+		return "/" + segments[0] + "/" + segments[1]
+	}
+
+	return "/" + segments[0]
+}
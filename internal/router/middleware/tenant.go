@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/caller"
+	"github.com/thoughtgears/shared-services/internal/i18n"
+	"github.com/thoughtgears/shared-services/internal/tenant"
+)
+
+// TenancyConfig configures how the tenant of a request is resolved.
+type TenancyConfig struct {
+	// Hosts maps request hosts to tenant IDs, e.g. "acme.thoughtgears.dev" to "acme".
+	Hosts map[string]string
+}
+
+// Global tenancy configuration, multi-tenancy is disabled while it is nil
+var tenancy *TenancyConfig
+
+// InitTenancy enables multi-tenancy on server startup.
+// TenantScope lets every request through unchanged until it has been called.
+func InitTenancy(cfg TenancyConfig) {
+	hosts := make(map[string]string, len(cfg.Hosts))
+	for host, id := range cfg.Hosts {
+		hosts[strings.ToLower(host)] = id
+	}
+	tenancy = &TenancyConfig{Hosts: hosts}
+}
+
+// TenantScope is middleware that resolves the tenant of the request and stores it in the
+// request context for the tenant scoped repositories and storage. It must run after FirebaseAuth.
+//
+// The tenant comes from the Identity Platform tenant of the verified token, or from the host
+// header when the token has none. When both are present they must agree, so a user of one tenant
+// cannot reach another tenant's data through its hostname. Requests without a tenant get 403.
+func TenantScope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if tenancy == nil {
+			c.Next()
+
+			return
+		}
+
+		var tokenTenant string
+		if token, ok := caller.TokenFrom(c); ok {
+			tokenTenant = token.Firebase.Tenant
+		}
+		hostTenant := tenancy.Hosts[requestHost(c.Request)]
+
+		id := tokenTenant
+		if id == "" {
+			id = hostTenant
+		}
+
+		switch {
+		case id == "":
+			abortTenant(c, "No tenant for request")
+
+			return
+		case tokenTenant != "" && hostTenant != "" && tokenTenant != hostTenant:
+			log.Warn().Str("token_tenant", tokenTenant).Str("host_tenant", hostTenant).Msg("Token tenant does not match host")
+			abortTenant(c, "Tenant mismatch")
+
+			return
+		case tenant.Validate(id) != nil:
+			abortTenant(c, "Invalid tenant")
+
+			return
+		}
+
+		c.Request = c.Request.WithContext(tenant.With(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// requestHost returns the lower cased host of the request without the port.
+func requestHost(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return strings.ToLower(host)
+}
+
+func abortTenant(c *gin.Context, message string) {
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+		"error":   "forbidden",
+		"message": i18n.Localize(c, "forbidden", message),
+	})
+}
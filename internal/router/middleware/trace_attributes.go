@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/thoughtgears/shared-services/internal/audit"
+	"github.com/thoughtgears/shared-services/internal/tenant"
+)
+
+// TraceAttributes is middleware that adds the tenant, the authenticated user and the IDs of the
+// resources addressed by the route to the request span, so traces can be searched by them.
+// The ":id" parameter is named after the route's resource, e.g. "document.id" under /v1/documents,
+// and other "*_id" parameters after themselves, e.g. ":user_id" becomes "user.id".
+// It must run after the otelgin middleware.
+func TraceAttributes() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		// Auth and tenant middleware further down the chain store their values on the request,
+		// so they are read after the handlers ran.
+		span := trace.SpanFromContext(c.Request.Context())
+		if !span.IsRecording() {
+			return
+		}
+
+		ctx := c.Request.Context()
+		attrs := make([]attribute.KeyValue, 0, len(c.Params)+2)
+		if id := tenant.From(ctx); id != "" {
+			attrs = append(attrs, attribute.String("tenant.id", id))
+		}
+		if actor := audit.ActorFrom(ctx); actor.Type == audit.ActorTypeUser {
+			attrs = append(attrs, attribute.String("enduser.id", actor.ID))
+		}
+
+		for _, param := range c.Params {
+			if key := paramAttribute(c.FullPath(), param.Key); key != "" {
+				attrs = append(attrs, attribute.String(key, param.Value))
+			}
+		}
+
+		span.SetAttributes(attrs...)
+	}
+}
+
+// paramAttribute returns the span attribute name for a route parameter, or an empty string
+// for parameters that are not resource IDs.
+func paramAttribute(route, param string) string {
+	if param == "id" {
+		// "/v1/documents/:id" is the document, named after the resource segment before the parameter
+		segments := strings.Split(strings.TrimPrefix(route, "/"), "/")
+		for i, segment := range segments {
+			if segment == ":id" && i > 0 {
+				return strings.TrimSuffix(segments[i-1], "s") + ".id"
+			}
+		}
+
+		return ""
+	}
+
+	if name, ok := strings.CutSuffix(param, "_id"); ok {
+		return name + ".id"
+	}
+
+	return ""
+}
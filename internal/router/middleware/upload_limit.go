@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/i18n"
+	"github.com/thoughtgears/shared-services/internal/stats"
+)
+
+// UploadLimitConfig configures how many uploads an instance handles at once.
+type UploadLimitConfig struct {
+	// MaxConcurrent is the number of uploads in flight at once.
+	MaxConcurrent int
+	// QueueTimeout is how long an upload waits for a slot before it is rejected.
+	QueueTimeout time.Duration
+	// RetryAfter is the delay rejected clients are told to wait.
+	RetryAfter time.Duration
+}
+
+// Global upload slots, nil when uploads are not limited
+var uploadSlots chan struct{}
+
+// Global upload limit settings
+var uploadLimit UploadLimitConfig
+
+// InitUploadLimit enables the upload concurrency limit on server startup.
+// UploadLimit lets every upload through until it has been called.
+func InitUploadLimit(cfg UploadLimitConfig) {
+	uploadLimit = cfg
+	uploadSlots = make(chan struct{}, cfg.MaxConcurrent)
+}
+
+// UploadLimit is middleware for upload routes that caps the uploads an instance handles at once.
+// Uploads are read into memory, a burst of large files would otherwise get the container killed
+// for running out of memory. An upload waits up to the queue timeout for a slot, then it is
+// rejected with 503 and a Retry-After header so the client tries again, possibly on another instance.
+func UploadLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if uploadSlots == nil {
+			c.Next()
+
+			return
+		}
+
+		if !acquireUploadSlot(c) {
+			log.Warn().Str("path", c.Request.URL.Path).Int("max_concurrent", uploadLimit.MaxConcurrent).Msg("Upload concurrency limit reached")
+			stats.Add("upload_limit.rejected", 1)
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(uploadLimit.RetryAfter.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"error":   "service unavailable",
+				"message": i18n.Localize(c, "service unavailable", "Too many uploads in progress, retry later"),
+			})
+
+			return
+		}
+		defer func() { <-uploadSlots }()
+
+		c.Next()
+	}
+}
+
+// acquireUploadSlot takes an upload slot, waiting up to the queue timeout or until the client
+// goes away.
+func acquireUploadSlot(c *gin.Context) bool {
+	select {
+	case uploadSlots <- struct{}{}:
+		return true
+	default:
+	}
+
+	if uploadLimit.QueueTimeout <= 0 {
+		return false
+	}
+
+	timer := time.NewTimer(uploadLimit.QueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case uploadSlots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-c.Request.Context().Done():
+		return false
+	}
+}
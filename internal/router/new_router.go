@@ -2,19 +2,56 @@ package router
 
 import (
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 
+	"github.com/thoughtgears/shared-services/internal/buildinfo"
 	"github.com/thoughtgears/shared-services/internal/router/middleware"
+	"github.com/thoughtgears/shared-services/internal/warmup"
 )
 
 type Router struct {
 	Engine *gin.Engine
 	host   string
 	port   string
+	// resolvesClientIP is set when ClientIP returns the address of the client rather than that of
+	// the proxy in front of the service
+	resolvesClientIP bool
+}
+
+// options holds the optional settings of NewRouter.
+type options struct {
+	untracedRoutes  []string
+	trustedProxies  []string
+	trustedPlatform string
+}
+
+// Option configures optional behaviour of NewRouter.
+type Option func(*options)
+
+// WithUntracedRoutes disables tracing for the given route templates in addition to the health checks,
+// e.g. for high-volume probes that would only add noise and cost.
+func WithUntracedRoutes(routes ...string) Option {
+	return func(o *options) {
+		o.untracedRoutes = append(o.untracedRoutes, routes...)
+	}
+}
+
+// WithClientIP makes the router resolve the address of the client behind the proxies in front of
+// the service. X-Forwarded-For is honoured when the request comes from one of trustedProxies, IP
+// addresses or CIDR ranges, e.g. the range the Cloud Run front end connects from; trustedPlatform
+// is a header the platform sets to the client address, e.g. one a Google load balancer sets from
+// {client_ip_address}, or gin.PlatformCloudflare. Proxies must have been validated with
+// middleware.ParseCIDRs.
+func WithClientIP(trustedProxies []string, trustedPlatform string) Option {
+	return func(o *options) {
+		o.trustedProxies = trustedProxies
+		o.trustedPlatform = trustedPlatform
+	}
 }
 
 // NewRouter creates and configures a new Router instance with middleware and configuration.
@@ -24,21 +61,32 @@ type Router struct {
 // config.Debug is false.
 //
 // Middleware added includes:
+//   - A request ID for correlating logs and audit events (via middleware.RequestID()).
 //   - A custom structured logger (via middleware.Logger()).
 //   - Gin's default recovery middleware to handle panics gracefully.
+//   - RFC 7807 problem details for clients that accept them (via middleware.ProblemDetails()).
+//   - Tracing, except for health checks, warmup and routes passed to WithUntracedRoutes, with span attributes
+//     for the tenant, user and resource IDs (via middleware.TraceAttributes()).
+//   - Request metrics labelled with route, status, tenant and caller cohort (via middleware.Metrics()).
 //
-// It clears any default trusted proxies using SetTrustedProxies(nil), which is often
-// suitable when running behind a known reverse proxy or load balancer.
+// No proxy is trusted unless WithClientIP configures them, so by default ClientIP is the address
+// of the peer, which behind Cloud Run is the front end rather than the client.
 //
 // Parameters:
 //   - local: If the application is running locally its set to true.
 //   - port: A pointer to a string representing the port to run the server on.
+//   - opts: Optional settings, such as WithUntracedRoutes and WithClientIP.
 //
 // Returns:
 //   - A pointer to the configured *Router instance, ready to be run.
-func NewRouter(serviceName string, local bool, port *string) *Router {
+func NewRouter(serviceName string, local bool, port *string, opts ...Option) *Router {
 	var newRouter Router
 
+	settings := options{untracedRoutes: []string{"/health", "/healthz", "/warmup"}}
+	for _, opt := range opts {
+		opt(&settings)
+	}
+
 	if local {
 		gin.SetMode(gin.DebugMode)
 		newRouter.host = "127.0.0.1"
@@ -52,13 +100,22 @@ func NewRouter(serviceName string, local bool, port *string) *Router {
 	}
 
 	newRouter.Engine = gin.New()
+	// Let services read values stored on the request context (request ID, trace span, actor)
+	// through the *gin.Context they receive as their context.Context.
+	newRouter.Engine.ContextWithFallback = true
+	newRouter.Engine.Use(middleware.RequestID())
 	newRouter.Engine.Use(middleware.Logger())
 	newRouter.Engine.Use(gin.Recovery())
-	newRouter.Engine.Use(otelgin.Middleware(serviceName))
+	newRouter.Engine.Use(middleware.ProblemDetails())
+	newRouter.Engine.Use(otelgin.Middleware(serviceName, otelgin.WithGinFilter(func(c *gin.Context) bool {
+		return !slices.Contains(settings.untracedRoutes, c.FullPath())
+	})))
+	newRouter.Engine.Use(middleware.TraceAttributes())
+	newRouter.Engine.Use(middleware.Metrics())
 
 	newRouter.Engine.Use(cors.New(cors.Config{
 		AllowOrigins: []string{"https://www.thoughtgears.dev", "https://thoughtgears.dev", "http://localhost:5002"},
-		AllowMethods: []string{"PUT", "GET", "POST", "DELETE", "OPTIONS"},
+		AllowMethods: []string{"PUT", "PATCH", "GET", "POST", "DELETE", "OPTIONS"},
 		AllowHeaders: []string{
 			"Origin",
 			"Content-Type",
@@ -68,24 +125,25 @@ func NewRouter(serviceName string, local bool, port *string) *Router {
 			"Accept",
 			"Cache-Control",
 			"X-Requested-With",
+			"X-Request-ID",
+			middleware.DefaultCSRFHeaderName,
 		},
 		ExposeHeaders: []string{
 			"Content-Type",
 			"Content-Length",
+			"X-Request-ID",
+			"Link",
+			"X-Next-Page-Token",
+			"X-Page-Size",
+			"X-Total-Count",
 		},
 		MaxAge: 12 * time.Hour,
 	}))
 
-	// Explicitly clear trusted proxies (important for security depending on deployment)
-	// If behind a trusted proxy (like Cloudflare), you might configure this differently.
-	// --- Trusted Proxies ---
-	// Consider setting trusted proxies if behind LB/Reverse Proxy
-	// err := Engine.SetTrustedProxies([]string{"192.168.1.100", "10.0.0.0/8"})
-	// if err != nil {
-	//     log.Fatalf("Failed to set trusted proxies: %v", err)
-	// }
-	// For now, clearing them might be fine depending on your setup.
-	_ = newRouter.Engine.SetTrustedProxies(nil)
+	// Only the configured proxies are trusted to report the client address, none by default
+	_ = newRouter.Engine.SetTrustedProxies(settings.trustedProxies)
+	newRouter.Engine.TrustedPlatform = settings.trustedPlatform
+	newRouter.resolvesClientIP = local || len(settings.trustedProxies) > 0 || settings.trustedPlatform != ""
 
 	// Need health check for uptime monitoring
 	newRouter.Engine.GET("/health", func(c *gin.Context) {
@@ -95,5 +153,44 @@ func NewRouter(serviceName string, local bool, port *string) *Router {
 		})
 	})
 
+	// Detailed health check showing which build is serving traffic
+	newRouter.Engine.GET("/healthz", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"data":    buildinfo.Get(),
+			"status":  http.StatusOK,
+			"message": "Service is running",
+		})
+	})
+
+	// Prepares the clients of a cold instance, for the Cloud Run startup probe. Clients are
+	// created on first use, so /health answers before any backend has been reached.
+	newRouter.Engine.GET("/warmup", func(c *gin.Context) {
+		results, ok := warmup.Run(c)
+		if !ok {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"data":    results,
+				"error":   "service unavailable",
+				"message": "Failed to warm up clients",
+				"status":  http.StatusServiceUnavailable,
+			})
+
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":    results,
+			"status":  http.StatusOK,
+			"message": "Service is warm",
+		})
+	})
+
 	return &newRouter
 }
+
+// ResolvesClientIP reports whether gin's ClientIP is the address of the client, because the router
+// runs locally or knows the proxies in front of it. Features keyed on the client address, such as
+// the auth lockout and IP allowlists, must not be enabled otherwise: every client would share the
+// address of the proxy.
+func (r *Router) ResolvesClientIP() bool {
+	return r.resolvesClientIP
+}
@@ -1,7 +1,11 @@
 package router
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"time"
 )
 
 // Run starts the HTTP server and includes graceful shutdown handling.
@@ -14,3 +18,35 @@ func (r *Router) Run() error {
 
 	return nil
 }
+
+// Serve starts the HTTP server and shuts it down gracefully once ctx is done, waiting up to
+// shutdownTimeout for in-flight requests to finish.
+func (r *Router) Serve(ctx context.Context, shutdownTimeout time.Duration) error {
+	server := &http.Server{
+		Addr:    fmt.Sprintf("%s:%s", r.host, r.port),
+		Handler: r.Engine,
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-served:
+		return fmt.Errorf("run router: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutdown router: %w", err)
+	}
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("run router: %w", err)
+	}
+
+	return nil
+}
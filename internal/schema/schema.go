@@ -0,0 +1,108 @@
+package schema
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Draft is the JSON Schema dialect of the generated schemas.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema document, limited to the keywords the generator emits.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	ID                   string             `json:"$id,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Type                 any                `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Generate derives a JSON Schema from a Go value using its json struct tags.
+// Fields tagged with omitempty or declared as pointers are optional, all others are required.
+// time.Time is emitted as a date-time string.
+func Generate(v any, id, title string) *Schema {
+	s := generate(reflect.TypeOf(v))
+	s.Schema = Draft
+	s.ID = id
+	s.Title = title
+
+	return s
+}
+
+func generate(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+
+	if t.Kind() == reflect.Ptr {
+		inner := generate(t.Elem())
+		if typ, ok := inner.Type.(string); ok {
+			inner.Type = []string{typ, "null"}
+		}
+
+		return inner
+	}
+
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: generate(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: generate(t.Elem())}
+	case reflect.Struct:
+		return generateStruct(t)
+	default:
+		// interface{} and other dynamic types accept any value
+		return &Schema{}
+	}
+}
+
+func generateStruct(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		tagParts := strings.Split(tag, ",")
+		name := tagParts[0]
+		if name == "" {
+			name = field.Name
+		}
+
+		s.Properties[name] = generate(field.Type)
+
+		if !slices.Contains(tagParts[1:], "omitempty") && field.Type.Kind() != reflect.Ptr {
+			s.Required = append(s.Required, name)
+		}
+	}
+
+	return s
+}
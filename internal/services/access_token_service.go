@@ -0,0 +1,279 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/audit"
+	"github.com/thoughtgears/shared-services/internal/db"
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/tenant"
+)
+
+const (
+	// defaultAccessTokenTTL is how long access tokens are valid when no expiry is requested.
+	defaultAccessTokenTTL = 90 * 24 * time.Hour
+	// maxAccessTokenTTL is the longest validity of an access token.
+	maxAccessTokenTTL = 365 * 24 * time.Hour
+	// maxAccessTokens is the number of active access tokens a user can have.
+	maxAccessTokens = 20
+	// accessTokenUseInterval is how often the last use of a token is written, a token used by a
+	// busy script would otherwise write on every request.
+	accessTokenUseInterval = 5 * time.Minute
+)
+
+// ErrAccessTokenInvalid is returned when an access token is malformed, unknown, expired or revoked.
+var ErrAccessTokenInvalid = errors.New("access token is not valid")
+
+// CreateAccessTokenInput is the input for creating a personal access token.
+// ExpiresIn is the validity of the token, the default validity is used when it is 0.
+type CreateAccessTokenInput struct {
+	Name      string
+	Scopes    []models.AccessTokenScope
+	ExpiresIn time.Duration
+}
+
+// AccessTokenService manages personal access tokens and verifies them for the auth middleware.
+type AccessTokenService interface {
+	Create(ctx context.Context, userID string, input CreateAccessTokenInput) (*models.NewAccessToken, error)
+	List(ctx context.Context, userID string) ([]*models.AccessToken, error)
+	Revoke(ctx context.Context, userID string, tokenID string) error
+	VerifyAccessToken(ctx context.Context, token string) (*models.AccessToken, error)
+}
+
+// accessTokenService is the concrete implementation of AccessTokenService.
+type accessTokenService struct {
+	db    db.DB[models.AccessToken]
+	audit *audit.Recorder
+}
+
+// NewAccessTokenService creates a new instance of accessTokenService.
+func NewAccessTokenService(datastore db.DB[models.AccessToken], recorder *audit.Recorder) AccessTokenService {
+	return &accessTokenService{
+		db:    datastore,
+		audit: recorder,
+	}
+}
+
+// Create mints a personal access token for the user. The returned token is the only copy of its
+// secret. Users can only create tokens for themselves.
+func (a *accessTokenService) Create(ctx context.Context, userID string, input CreateAccessTokenInput) (*models.NewAccessToken, error) {
+	if err := authorizeSelf(ctx, userID, "access tokens"); err != nil {
+		return nil, err
+	}
+
+	verr := &models.ValidationError{}
+	if input.Name == "" || len(input.Name) > 100 {
+		verr.Add("name", "is required and must be at most 100 characters")
+	}
+	if len(input.Scopes) == 0 {
+		verr.Add("scopes", "is required")
+	}
+	for i, scope := range input.Scopes {
+		if !slices.Contains(models.AccessTokenScopes, scope) {
+			verr.Add("scopes", "contains an unknown scope "+string(scope))
+		} else if slices.Contains(input.Scopes[:i], scope) {
+			verr.Add("scopes", "contains "+string(scope)+" more than once")
+		}
+	}
+	if input.ExpiresIn == 0 {
+		input.ExpiresIn = defaultAccessTokenTTL
+	}
+	if input.ExpiresIn < 0 || input.ExpiresIn > maxAccessTokenTTL {
+		verr.Add("expires_in_days", fmt.Sprintf("must be between 1 and %d days", int(maxAccessTokenTTL.Hours()/24)))
+	}
+	if err := verr.Err(); err != nil {
+		return nil, fmt.Errorf("invalid access token: %w", err)
+	}
+
+	tokens, err := a.list(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	active := 0
+	for _, token := range tokens {
+		if token.Active(now) {
+			active++
+		}
+	}
+	if active >= maxAccessTokens {
+		verr.Add("name", fmt.Sprintf("users can have at most %d active access tokens", maxAccessTokens))
+
+		return nil, fmt.Errorf("invalid access token: %w", verr)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+	encodedSecret := base64.RawURLEncoding.EncodeToString(secret)
+
+	id := uuid.NewString()
+	expiresAt := now.Add(input.ExpiresIn)
+	created, err := a.db.Create(ctx, id, db.SetExpireAt(map[string]interface{}{
+		"id":          id,
+		"user_id":     userID,
+		"tenant_id":   tenant.From(ctx),
+		"name":        input.Name,
+		"scopes":      input.Scopes,
+		"secret_hash": hashSecret(encodedSecret),
+		"expires_at":  expiresAt,
+		"created_at":  firestore.ServerTimestamp,
+		"updated_at":  firestore.ServerTimestamp,
+	}, expiresAt.Add(expiredRecordRetention)))
+	a.audit.Record(ctx, audit.ActionAccessTokenCreate, accessTokenTarget(id), err, map[string]string{
+		"user_id": userID,
+		"scopes":  fmt.Sprint(input.Scopes),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create access token: %w", err)
+	}
+
+	return &models.NewAccessToken{
+		AccessToken: created,
+		Token:       models.AccessTokenPrefix + id + "." + encodedSecret,
+	}, nil
+}
+
+// List returns the access tokens of the user, including expired and revoked ones.
+// Users can only list their own tokens.
+func (a *accessTokenService) List(ctx context.Context, userID string) ([]*models.AccessToken, error) {
+	if err := authorizeSelf(ctx, userID, "access tokens"); err != nil {
+		return nil, err
+	}
+
+	return a.list(ctx, userID)
+}
+
+// list loads the access tokens of the user in the tenant of the context.
+func (a *accessTokenService) list(ctx context.Context, userID string) ([]*models.AccessToken, error) {
+	tokens, _, err := a.db.Find(ctx, db.Query[models.AccessToken]().
+		Where("user_id", db.Eq, userID).
+		Where("tenant_id", db.Eq, tenant.From(ctx)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list access tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// Revoke revokes an access token of the user, it stops working immediately.
+// Users can only revoke their own tokens.
+func (a *accessTokenService) Revoke(ctx context.Context, userID string, tokenID string) error {
+	if err := authorizeSelf(ctx, userID, "access tokens"); err != nil {
+		return err
+	}
+
+	token, err := a.get(ctx, tokenID)
+	if err != nil {
+		return err
+	}
+	if token == nil || token.UserID != userID || token.TenantID != tenant.From(ctx) {
+		return fmt.Errorf("access token %s: %w", tokenID, ErrForbidden)
+	}
+	if token.RevokedAt != nil {
+		return nil
+	}
+
+	_, err = a.db.Update(ctx, tokenID, map[string]interface{}{
+		"revoked_at": time.Now().UTC(),
+		"updated_at": firestore.ServerTimestamp,
+	})
+	a.audit.Record(ctx, audit.ActionAccessTokenRevoke, accessTokenTarget(tokenID), err, map[string]string{
+		"user_id": userID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+
+	return nil
+}
+
+// VerifyAccessToken returns the access token a token string belongs to if it is active.
+// The context has no tenant yet, the tenant of the request is the one recorded on the token.
+func (a *accessTokenService) VerifyAccessToken(ctx context.Context, token string) (*models.AccessToken, error) {
+	id, secret, ok := strings.Cut(strings.TrimPrefix(token, models.AccessTokenPrefix), ".")
+	if !ok || !strings.HasPrefix(token, models.AccessTokenPrefix) || uuid.Validate(id) != nil {
+		return nil, fmt.Errorf("%w: malformed token", ErrAccessTokenInvalid)
+	}
+
+	accessToken, err := a.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if accessToken == nil {
+		return nil, fmt.Errorf("%w: unknown token", ErrAccessTokenInvalid)
+	}
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(accessToken.SecretHash)) != 1 {
+		return nil, fmt.Errorf("%w: wrong secret", ErrAccessTokenInvalid)
+	}
+
+	now := time.Now().UTC()
+	if !accessToken.Active(now) {
+		return nil, fmt.Errorf("%w: token expired or revoked", ErrAccessTokenInvalid)
+	}
+
+	if accessToken.LastUsedAt == nil || now.Sub(*accessToken.LastUsedAt) >= accessTokenUseInterval {
+		// Tracking is best effort, a failed write must not reject the request
+		if _, err := a.db.Update(ctx, id, map[string]interface{}{"last_used_at": now}); err != nil {
+			log.Error().Err(err).Str("access_token_id", id).Msg("Failed to record access token use")
+		}
+		accessToken.LastUsedAt = &now
+	}
+
+	return accessToken, nil
+}
+
+// get loads an access token, it returns nil when there is no token with the ID.
+func (a *accessTokenService) get(ctx context.Context, id string) (*models.AccessToken, error) {
+	// Queried rather than fetched by ID, so an unknown token is not an error
+	tokens, _, err := a.db.Find(ctx, db.Query[models.AccessToken]().Where("id", db.Eq, id).Limit(1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access token: %w", err)
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	return tokens[0], nil
+}
+
+// authorizeSelf checks that the caller is the user, what describes the resource for the error.
+func authorizeSelf(ctx context.Context, userID string, what string) error {
+	uid, err := callerID(ctx)
+	if err != nil {
+		return err
+	}
+	if uid != userID {
+		return fmt.Errorf("%s of user %s: %w", what, userID, ErrForbidden)
+	}
+
+	return nil
+}
+
+// hashSecret returns the hex SHA-256 hash of an access token secret. The secrets are random,
+// so a fast hash is enough.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// accessTokenTarget returns the audit target for an access token.
+func accessTokenTarget(id string) audit.Target {
+	return audit.Target{Type: "access_token", ID: id}
+}
@@ -0,0 +1,277 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/text/language"
+
+	"github.com/thoughtgears/shared-services/internal/audit"
+	"github.com/thoughtgears/shared-services/internal/identity"
+	"github.com/thoughtgears/shared-services/internal/mailer"
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/signedtoken"
+	"github.com/thoughtgears/shared-services/internal/tenant"
+	"github.com/thoughtgears/shared-services/pkg/apperr"
+)
+
+// passwordResetInterval is the minimum time between two password reset emails to one address,
+// so the unauthenticated reset endpoint cannot be used to flood someone's inbox.
+const passwordResetInterval = 5 * time.Minute
+
+// emailChangeTokenPrefix marks the subject of email change tokens. They are signed with a key of
+// their own, the prefix also keeps a token from being used if the key were ever shared.
+const emailChangeTokenPrefix = "email_change:"
+
+var (
+	// ErrEmailChangeInvalid is returned when an email change link is invalid, expired, for another
+	// tenant or was already used.
+	ErrEmailChangeInvalid = apperr.New(apperr.Invalid, "email change is not valid")
+	// ErrEmailChangeDisabled is returned for email changes when no signing key is configured.
+	ErrEmailChangeDisabled = apperr.New(apperr.Unavailable, "email change is not enabled")
+)
+
+// AccountService runs the password reset and email change flows of Firebase accounts,
+// sending the links through the mailer with the branded templates.
+type AccountService interface {
+	SendPasswordReset(ctx context.Context, email string, lang language.Tag) error
+	RequestEmailChange(ctx context.Context, newEmail string) error
+	ConfirmEmailChange(ctx context.Context, token string) (*models.User, error)
+}
+
+// accountService is the concrete implementation of AccountService.
+type accountService struct {
+	identity   identity.Provider
+	users      UserService
+	mailer     mailer.Mailer
+	templates  *mailer.Templates
+	signer     *signedtoken.Signer
+	confirmURL string
+	ttl        time.Duration
+	audit      *audit.Recorder
+
+	mu         sync.Mutex
+	resetsSent map[string]time.Time
+}
+
+// NewAccountService creates a new instance of accountService.
+// confirmURL is the frontend page that confirms email changes, the token is added as the token
+// query parameter. Email change links expire after ttl. Email changes are disabled when signer is nil.
+func NewAccountService(
+	provider identity.Provider,
+	users UserService,
+	mailer mailer.Mailer,
+	templates *mailer.Templates,
+	signer *signedtoken.Signer,
+	confirmURL string,
+	ttl time.Duration,
+	recorder *audit.Recorder,
+) AccountService {
+	return &accountService{
+		identity:   provider,
+		users:      users,
+		mailer:     mailer,
+		templates:  templates,
+		signer:     signer,
+		confirmURL: confirmURL,
+		ttl:        ttl,
+		audit:      recorder,
+		resetsSent: make(map[string]time.Time),
+	}
+}
+
+// SendPasswordReset emails a password reset link to the account with the email address.
+// The caller is not authenticated, so the result does not reveal whether an account uses the
+// address: unknown addresses and throttled requests succeed without sending anything. The email
+// is in lang, the language the caller asked for, as the account is not looked up.
+func (a *accountService) SendPasswordReset(ctx context.Context, email string, lang language.Tag) error {
+	address, err := parseEmail("email", email)
+	if err != nil {
+		return err
+	}
+
+	if !a.allowReset(tenant.From(ctx) + "/" + strings.ToLower(address)) {
+		log.Info().Msg("Password reset throttled")
+
+		return nil
+	}
+
+	link, err := a.identity.PasswordResetLink(ctx, address)
+	if errors.Is(err, identity.ErrEmailNotFound) {
+		log.Info().Msg("Password reset requested for an unknown email address")
+
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return a.send(ctx, "password_reset", lang, address, map[string]string{"Link": link})
+}
+
+// allowReset reports whether a password reset email may be sent for key and records it.
+func (a *accountService) allowReset(key string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	for k, sent := range a.resetsSent {
+		if now.Sub(sent) >= passwordResetInterval {
+			delete(a.resetsSent, k)
+		}
+	}
+
+	if _, ok := a.resetsSent[key]; ok {
+		return false
+	}
+	a.resetsSent[key] = now
+
+	return true
+}
+
+// RequestEmailChange sends a link to newEmail that changes the caller's email address to it once
+// opened, proving the caller controls the new address. The link only works while the account
+// still has its current address, so it can be used once.
+func (a *accountService) RequestEmailChange(ctx context.Context, newEmail string) error {
+	if a.signer == nil {
+		return ErrEmailChangeDisabled
+	}
+
+	uid, err := callerID(ctx)
+	if err != nil {
+		return err
+	}
+
+	address, err := parseEmail("new_email", newEmail)
+	if err != nil {
+		return err
+	}
+
+	user, err := a.users.GetByID(ctx, uid)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if strings.EqualFold(address, user.Email) {
+		verr := &models.ValidationError{}
+		verr.Add("new_email", "must differ from the current email address")
+
+		return fmt.Errorf("invalid email change: %w", verr)
+	}
+
+	expiresAt := time.Now().Add(a.ttl)
+	token := a.signer.Sign(strings.Join([]string{
+		emailChangeTokenPrefix + tenant.From(ctx),
+		uid,
+		base64.RawURLEncoding.EncodeToString([]byte(user.Email)),
+		base64.RawURLEncoding.EncodeToString([]byte(address)),
+	}, ":"), expiresAt)
+
+	err = a.send(ctx, "email_change", user.Language(), address, map[string]string{
+		"Link":      a.confirmURL + "?token=" + url.QueryEscape(token),
+		"NewEmail":  address,
+		"ExpiresAt": user.FormatDate(expiresAt) + " " + expiresAt.In(user.Location()).Format("15:04 MST"),
+	})
+	a.audit.Record(ctx, audit.ActionUserEmailRequest, userTarget(user.ID), err, nil)
+
+	return err
+}
+
+// ConfirmEmailChange applies the email change of a link sent by RequestEmailChange to the Firebase
+// account and the user, and tells the previous address about the change. The token proves control
+// of the new address, so the caller does not have to be signed in.
+func (a *accountService) ConfirmEmailChange(ctx context.Context, token string) (*models.User, error) {
+	if a.signer == nil {
+		return nil, ErrEmailChangeDisabled
+	}
+
+	subject, err := a.signer.Verify(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrEmailChangeInvalid, err)
+	}
+
+	parts := strings.Split(strings.TrimPrefix(subject, emailChangeTokenPrefix), ":")
+	if !strings.HasPrefix(subject, emailChangeTokenPrefix) || len(parts) != 4 {
+		return nil, fmt.Errorf("%w: not an email change token", ErrEmailChangeInvalid)
+	}
+	tenantID, uid := parts[0], parts[1]
+	oldEmail, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrEmailChangeInvalid, err)
+	}
+	newEmail, err := base64.RawURLEncoding.DecodeString(parts[3])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrEmailChangeInvalid, err)
+	}
+
+	if tenantID != tenant.From(ctx) {
+		return nil, fmt.Errorf("%w: token is for another tenant", ErrEmailChangeInvalid)
+	}
+
+	user, err := a.users.GetByID(ctx, uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if !strings.EqualFold(user.Email, string(oldEmail)) {
+		return nil, fmt.Errorf("%w: email address changed since the link was sent", ErrEmailChangeInvalid)
+	}
+
+	err = a.identity.UpdateEmail(ctx, uid, string(newEmail))
+	a.audit.Record(ctx, audit.ActionUserEmailChange, userTarget(user.ID), err, nil)
+	if errors.Is(err, identity.ErrEmailExists) {
+		verr := &models.ValidationError{}
+		verr.Add("new_email", "is already used by another account")
+
+		return nil, fmt.Errorf("invalid email change: %w", verr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	updatedUser, err := a.users.Update(ctx, user.ID, &models.User{Email: string(newEmail)})
+	if err != nil {
+		return nil, err
+	}
+
+	// The account has changed already, a missing notice must not fail the confirmation
+	if err := a.send(ctx, "email_changed", user.Language(), string(oldEmail), nil); err != nil {
+		log.Error().Err(err).Str("user_id", user.ID).Msg("Failed to send email change notice")
+	}
+
+	return updatedUser, nil
+}
+
+// send renders the email template in lang and sends it to the address.
+func (a *accountService) send(ctx context.Context, template string, lang language.Tag, to string, data map[string]string) error {
+	msg, err := a.templates.Render(template, lang, to, data)
+	if err != nil {
+		return err
+	}
+
+	if err := a.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("failed to send %s email: %w", template, err)
+	}
+
+	return nil
+}
+
+// parseEmail validates an email address and returns it without any display name.
+func parseEmail(field string, email string) (string, error) {
+	parsed, err := mail.ParseAddress(email)
+	if err != nil {
+		verr := &models.ValidationError{}
+		verr.Add(field, "must be a valid email address")
+
+		return "", fmt.Errorf("invalid email address: %w", verr)
+	}
+
+	return parsed.Address, nil
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/thoughtgears/shared-services/internal/signedtoken"
+)
+
+func TestConfirmEmailChangeRejectsOtherTokens(t *testing.T) {
+	signer, err := signedtoken.NewSigner(bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	invitations, err := signedtoken.NewSigner(bytes.Repeat([]byte{2}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	accounts := NewAccountService(nil, nil, nil, nil, signer, "https://example.com/confirm", time.Hour, nil)
+	expires := time.Now().Add(time.Hour)
+
+	tests := map[string]string{
+		// An invitation token carries an invitation ID, and is signed with the invitation key
+		"invitation key": invitations.Sign(emailChangeTokenPrefix+":user-1:b2xk:bmV3", expires),
+		"other purpose":  signer.Sign("invitation-1", expires),
+		"expired":        signer.Sign(emailChangeTokenPrefix+":user-1:b2xk:bmV3", time.Now().Add(-time.Minute)),
+		"malformed":      "not-a-token",
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := accounts.ConfirmEmailChange(context.Background(), token); !errors.Is(err, ErrEmailChangeInvalid) {
+				t.Errorf("ConfirmEmailChange() error = %v, want ErrEmailChangeInvalid", err)
+			}
+		})
+	}
+}
+
+func TestEmailChangeDisabledWithoutKey(t *testing.T) {
+	accounts := NewAccountService(nil, nil, nil, nil, nil, "https://example.com/confirm", time.Hour, nil)
+
+	if err := accounts.RequestEmailChange(context.Background(), "new@example.com"); !errors.Is(err, ErrEmailChangeDisabled) {
+		t.Errorf("RequestEmailChange() error = %v, want ErrEmailChangeDisabled", err)
+	}
+	if _, err := accounts.ConfirmEmailChange(context.Background(), "token"); !errors.Is(err, ErrEmailChangeDisabled) {
+		t.Errorf("ConfirmEmailChange() error = %v, want ErrEmailChangeDisabled", err)
+	}
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/db"
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/tenant"
+)
+
+const (
+	// lastSeenInterval is how often the last-seen time of an active user is written.
+	lastSeenInterval = 5 * time.Minute
+	// seenRetention is how long the service remembers an idle user, a user coming back after it
+	// costs a read of their login history.
+	seenRetention = time.Hour
+)
+
+// ActivityService tracks when users were last seen and their recent sign-ins.
+type ActivityService interface {
+	RecordActivity(ctx context.Context, userID string, login models.Login)
+	Get(ctx context.Context, userID string) (*models.UserActivity, error)
+}
+
+// seenEntry is what the service remembers about a user, to skip writes for requests that neither
+// come from a new sign-in nor are due a last-seen update.
+type seenEntry struct {
+	authTime time.Time
+	written  time.Time
+}
+
+// activityService is the concrete implementation of ActivityService.
+type activityService struct {
+	db   db.DB[models.UserActivity]
+	mu   sync.Mutex
+	seen map[string]seenEntry
+}
+
+// NewActivityService creates a new instance of activityService with a db for user activity.
+func NewActivityService(datastore db.DB[models.UserActivity]) ActivityService {
+	return &activityService{
+		db:   datastore,
+		seen: make(map[string]seenEntry),
+	}
+}
+
+// RecordActivity records a request of an authenticated user. The first request of a sign-in adds
+// it to the user's login history, raising an alert when it comes from an IP address and a user
+// agent the user has not signed in from before. Other requests refresh the last-seen time at most
+// every lastSeenInterval. Activity is best effort: failures are logged, never returned.
+func (a *activityService) RecordActivity(ctx context.Context, userID string, login models.Login) {
+	key := tenant.From(ctx) + "/" + userID
+	now := time.Now().UTC()
+
+	a.mu.Lock()
+	entry, known := a.seen[key]
+	due := !known || !entry.authTime.Equal(login.AuthTime) || now.Sub(entry.written) >= lastSeenInterval
+	if due {
+		a.sweep(now)
+		a.seen[key] = seenEntry{authTime: login.AuthTime, written: now}
+	}
+	a.mu.Unlock()
+	if !due {
+		return
+	}
+
+	// A known sign-in only needs the last-seen time, other requests may be the first of a sign-in
+	if known && entry.authTime.Equal(login.AuthTime) {
+		if _, err := a.db.Update(ctx, userID, map[string]interface{}{
+			"id":           userID,
+			"last_seen_at": now,
+			"updated_at":   firestore.ServerTimestamp,
+		}); err != nil {
+			log.Error().Err(err).Str("user_id", userID).Msg("Failed to record last seen time")
+		}
+
+		return
+	}
+
+	if err := a.recordLogin(ctx, userID, login, now); err != nil {
+		log.Error().Err(err).Str("user_id", userID).Msg("Failed to record login")
+	}
+}
+
+// recordLogin adds the sign-in to the user's login history unless it is already there,
+// e.g. after a restart of the service, and refreshes the last-seen time.
+func (a *activityService) recordLogin(ctx context.Context, userID string, login models.Login, now time.Time) error {
+	activity, err := a.get(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	update := map[string]interface{}{
+		"id":           userID,
+		"last_seen_at": now,
+		"updated_at":   firestore.ServerTimestamp,
+	}
+
+	seen := slices.ContainsFunc(activity.Logins, func(l models.Login) bool {
+		return l.AuthTime.Equal(login.AuthTime)
+	})
+	if !seen {
+		newDevice := len(activity.Logins) > 0 && !slices.ContainsFunc(activity.Logins, func(l models.Login) bool {
+			return l.IP == login.IP || l.UserAgent == login.UserAgent
+		})
+		if newDevice {
+			log.Warn().
+				Str("alert", "login_new_device").
+				Str("user_id", userID).
+				Str("client_ip", login.IP).
+				Str("provider", login.Provider).
+				Msg("Sign-in from an IP address and user agent not seen for the user before")
+		}
+		recordLogin(ctx, login.Provider, newDevice)
+
+		logins := append([]models.Login{login}, activity.Logins...)
+		update["logins"] = logins[:min(len(logins), models.MaxLoginHistory)]
+	}
+
+	if _, err := a.db.Update(ctx, userID, update); err != nil {
+		return fmt.Errorf("failed to update user activity: %w", err)
+	}
+
+	return nil
+}
+
+// sweep forgets users idle for longer than seenRetention, so the map only holds active users.
+// The caller must hold the lock.
+func (a *activityService) sweep(now time.Time) {
+	for key, entry := range a.seen {
+		if now.Sub(entry.written) >= seenRetention {
+			delete(a.seen, key)
+		}
+	}
+}
+
+// Get returns the activity of a user. Users can only read their own activity.
+func (a *activityService) Get(ctx context.Context, userID string) (*models.UserActivity, error) {
+	uid, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if uid != userID {
+		return nil, fmt.Errorf("activity of user %s: %w", userID, ErrForbidden)
+	}
+
+	return a.get(ctx, userID)
+}
+
+// get loads the activity of a user. Users without recorded activity get an empty one.
+func (a *activityService) get(ctx context.Context, userID string) (*models.UserActivity, error) {
+	// Queried rather than fetched by ID, so a user without activity is not an error
+	results, _, err := a.db.Find(ctx, db.Query[models.UserActivity]().Where("id", db.Eq, userID).Limit(1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user activity: %w", err)
+	}
+
+	if len(results) == 0 {
+		return &models.UserActivity{ID: userID, Logins: []models.Login{}}, nil
+	}
+
+	return results[0], nil
+}
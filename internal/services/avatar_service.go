@@ -0,0 +1,212 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"slices"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/firestore"
+
+	"github.com/thoughtgears/shared-services/internal/audit"
+	"github.com/thoughtgears/shared-services/internal/db"
+	"github.com/thoughtgears/shared-services/internal/gcs"
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/webp"
+	"github.com/thoughtgears/shared-services/pkg/apperr"
+)
+
+// maxAvatarSize is the largest profile picture upload in bytes.
+const maxAvatarSize = 10 << 20
+
+// ErrAvatarNotFound is returned when a user has not uploaded a profile picture.
+var ErrAvatarNotFound = apperr.New(apperr.NotFound, "avatar not found")
+
+// AvatarService generates and serves the profile picture variants of users.
+type AvatarService interface {
+	Upload(ctx context.Context, userID string, content []byte) (*models.User, error)
+	Delete(ctx context.Context, userID string) (*models.User, error)
+	Get(ctx context.Context, userID string, size int) (*Content, error)
+}
+
+// avatarService is the concrete implementation of AvatarService.
+type avatarService struct {
+	users     UserService
+	datastore db.DB[models.User]
+	storage   gcs.Storage
+	audit     *audit.Recorder
+}
+
+// NewAvatarService creates a new instance of avatarService. The variants are stored in storage
+// under avatars/<firebase id>/, one <size>.webp object per size.
+func NewAvatarService(users UserService, datastore db.DB[models.User], storage gcs.Storage, recorder *audit.Recorder) AvatarService {
+	return &avatarService{
+		users:     users,
+		datastore: datastore,
+		storage:   storage,
+		audit:     recorder,
+	}
+}
+
+// Upload replaces the profile picture of the user with the image in content. The image is cropped
+// to a centered square and stored as one WebP variant per size in models.AvatarSizes, smaller
+// images are scaled up. Users can only change their own profile picture.
+func (a *avatarService) Upload(ctx context.Context, userID string, content []byte) (*models.User, error) {
+	user, err := a.authorize(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(content) > maxAvatarSize {
+		verr := &models.ValidationError{}
+		verr.Add("file", fmt.Sprintf("must not be larger than %d bytes", maxAvatarSize))
+
+		return nil, fmt.Errorf("invalid avatar: %w", verr)
+	}
+
+	img, err := decodeImage(content)
+	if err != nil {
+		return nil, err
+	}
+	square := cropSquare(img)
+
+	// The version changes the URLs on every upload, so clients never see a cached old picture
+	now := time.Now().UTC()
+	version := strconv.FormatInt(now.UnixMilli(), 36)
+	avatar := models.Avatar{URLs: make(map[string]string, len(models.AvatarSizes)), UpdatedAt: now}
+	for _, size := range models.AvatarSizes {
+		var buf bytes.Buffer
+		if err := webp.Encode(&buf, scaleSquare(square, size)); err != nil {
+			return nil, err
+		}
+
+		if _, err := a.storage.Upload(ctx, avatarPath(user.FirebaseID, size), &buf, "image/webp"); err != nil {
+			recordFailure(ctx, "avatar.upload")
+			return nil, fmt.Errorf("failed to upload avatar: %w", err)
+		}
+		avatar.URLs[strconv.Itoa(size)] = fmt.Sprintf("/v1/users/%s/avatar/%d?v=%s", user.FirebaseID, size, version)
+	}
+
+	updatedUser, err := a.datastore.Update(ctx, user.ID, map[string]interface{}{
+		"avatar":     avatar,
+		"updated_at": firestore.ServerTimestamp,
+	})
+	a.audit.Record(ctx, audit.ActionUserAvatarUpdate, userTarget(user.ID), err, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user avatar: %w", err)
+	}
+
+	return updatedUser, nil
+}
+
+// Delete removes the profile picture of the user. Users can only remove their own profile picture.
+func (a *avatarService) Delete(ctx context.Context, userID string) (*models.User, error) {
+	user, err := a.authorize(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, size := range models.AvatarSizes {
+		if err := a.storage.Delete(ctx, avatarPath(user.FirebaseID, size)); err != nil && !gcs.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to delete avatar: %w", err)
+		}
+	}
+
+	updatedUser, err := a.datastore.Update(ctx, user.ID, map[string]interface{}{
+		"avatar":     firestore.Delete,
+		"updated_at": firestore.ServerTimestamp,
+	})
+	a.audit.Record(ctx, audit.ActionUserAvatarDelete, userTarget(user.ID), err, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user avatar: %w", err)
+	}
+
+	return updatedUser, nil
+}
+
+// Get returns a variant of the profile picture of the user. Profile pictures are shown next to
+// users' names, so every authenticated user can read them.
+func (a *avatarService) Get(ctx context.Context, userID string, size int) (*Content, error) {
+	if !slices.Contains(models.AvatarSizes, size) {
+		verr := &models.ValidationError{}
+		verr.Add("size", fmt.Sprintf("must be one of %v", models.AvatarSizes))
+
+		return nil, fmt.Errorf("invalid avatar size: %w", verr)
+	}
+
+	user, err := a.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.Avatar == nil {
+		return nil, fmt.Errorf("user %s: %w", userID, ErrAvatarNotFound)
+	}
+
+	reader, err := a.storage.Download(ctx, avatarPath(user.FirebaseID, size))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download avatar: %w", err)
+	}
+
+	return &Content{ReadCloser: reader, ContentType: "image/webp", Size: -1}, nil
+}
+
+// authorize checks that the caller is the user and returns the user.
+func (a *avatarService) authorize(ctx context.Context, userID string) (*models.User, error) {
+	uid, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if uid != userID {
+		return nil, fmt.Errorf("avatar of user %s: %w", userID, ErrForbidden)
+	}
+
+	user, err := a.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+// avatarPath returns the storage path of an avatar variant.
+func avatarPath(firebaseID string, size int) string {
+	return fmt.Sprintf("avatars/%s/%d.webp", firebaseID, size)
+}
+
+// cropSquare returns the largest centered square of img.
+func cropSquare(img image.Image) image.Image {
+	bounds := img.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	x0 := bounds.Min.X + (bounds.Dx()-side)/2
+	y0 := bounds.Min.Y + (bounds.Dy()-side)/2
+
+	square := image.NewNRGBA(image.Rect(0, 0, side, side))
+	for y := range side {
+		for x := range side {
+			square.Set(x, y, img.At(x0+x, y0+y))
+		}
+	}
+
+	return square
+}
+
+// scaleSquare scales the square img to size. Images are scaled down by averaging and up by
+// repeating pixels.
+func scaleSquare(img image.Image, size int) image.Image {
+	side := img.Bounds().Dx()
+	if size <= side {
+		return resizeImage(img, size)
+	}
+
+	scaled := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for y := range size {
+		for x := range size {
+			scaled.Set(x, y, img.At(x*side/size, y*side/size))
+		}
+	}
+
+	return scaled
+}
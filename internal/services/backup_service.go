@@ -0,0 +1,28 @@
+package services
+
+import (
+	"context"
+
+	"github.com/thoughtgears/shared-services/internal/backup"
+)
+
+// BackupService backs up the configured Firestore collections, for disaster recovery.
+type BackupService interface {
+	Backup(ctx context.Context) (*backup.Manifest, error)
+}
+
+// backupService is the concrete implementation of BackupService.
+type backupService struct {
+	backuper *backup.Backuper
+}
+
+// NewBackupService creates a new instance of BackupService.
+func NewBackupService(backuper *backup.Backuper) BackupService {
+	return &backupService{backuper: backuper}
+}
+
+// Backup writes a backup of the collections and returns its manifest. The backup keeps running
+// if the caller disconnects, so no partial backup is left without its manifest.
+func (b *backupService) Backup(ctx context.Context) (*backup.Manifest, error) {
+	return b.backuper.Backup(context.WithoutCancel(ctx))
+}
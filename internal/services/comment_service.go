@@ -0,0 +1,265 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+
+	"cloud.google.com/go/firestore"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/audit"
+	"github.com/thoughtgears/shared-services/internal/db"
+	"github.com/thoughtgears/shared-services/internal/models"
+)
+
+// CommentStore returns the repository of the comments of a document, which live in a subcollection of it.
+type CommentStore func(documentID string) db.DB[models.Comment]
+
+// CommentInput holds the caller supplied values of a comment.
+type CommentInput struct {
+	Body   string                `json:"body"`
+	Page   *int                  `json:"page"`
+	Region *models.CommentRegion `json:"region"`
+}
+
+// CommentService manages the comments on a document. Comments are visible to the people with access
+// to the document and to reviewers; authors can edit and delete only their own comments.
+type CommentService interface {
+	List(ctx context.Context, documentID string) ([]*models.Comment, error)
+	Create(ctx context.Context, documentID string, input CommentInput) (*models.Comment, error)
+	Update(ctx context.Context, documentID string, id string, input CommentInput) (*models.Comment, error)
+	Delete(ctx context.Context, documentID string, id string) error
+}
+
+type reviewerKey struct{}
+
+// WithReviewer returns a copy of ctx marking the caller as a document reviewer, who can comment on
+// every document of the tenant. It must only be used on routes restricted to admins.
+func WithReviewer(ctx context.Context) context.Context {
+	return context.WithValue(ctx, reviewerKey{}, true)
+}
+
+// isReviewer reports whether the caller in ctx was marked as a reviewer.
+func isReviewer(ctx context.Context) bool {
+	reviewer, _ := ctx.Value(reviewerKey{}).(bool)
+
+	return reviewer
+}
+
+// commentService is the concrete implementation of CommentService.
+type commentService struct {
+	documents     db.DB[models.Document]
+	organizations OrganizationService
+	comments      CommentStore
+	audit         *audit.Recorder
+}
+
+// NewCommentService creates a new instance of commentService.
+// documents and organizations decide who can see a document's comments, comments stores them.
+func NewCommentService(documents db.DB[models.Document], organizations OrganizationService, comments CommentStore, recorder *audit.Recorder) CommentService {
+	return &commentService{
+		documents:     documents,
+		organizations: organizations,
+		comments:      comments,
+		audit:         recorder,
+	}
+}
+
+// authorize checks the caller can see the document and returns their ID and the role they comment in.
+// Organization documents are visible to members, other documents to their owner, and every
+// document to reviewers.
+func (s *commentService) authorize(ctx context.Context, documentID string) (string, models.CommentAuthorRole, error) {
+	uid, err := callerID(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	document, err := s.documents.GetByID(ctx, documentID)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get document by ID: %w", err)
+	}
+
+	if isReviewer(ctx) {
+		return uid, models.CommentAuthorReviewer, nil
+	}
+
+	if err := authorizeOwner(ctx, s.organizations, "document", document.UserID, document.OrganizationID, models.RoleMember); err != nil {
+		return "", "", err
+	}
+
+	// Admins may comment on documents of other users, they do so as reviewers
+	if document.OrganizationID == "" && !isOwner(ctx, document.UserID) {
+		return uid, models.CommentAuthorReviewer, nil
+	}
+
+	return uid, models.CommentAuthorOwner, nil
+}
+
+// List returns the comments on a document, oldest first.
+func (s *commentService) List(ctx context.Context, documentID string) ([]*models.Comment, error) {
+	if _, _, err := s.authorize(ctx, documentID); err != nil {
+		return nil, err
+	}
+
+	comments, _, err := s.comments(documentID).GetAll(ctx, "", 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comments: %w", err)
+	}
+
+	slices.SortFunc(comments, func(a, b *models.Comment) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+	if comments == nil {
+		comments = []*models.Comment{}
+	}
+
+	return comments, nil
+}
+
+// Create adds a comment by the caller to a document.
+func (s *commentService) Create(ctx context.Context, documentID string, input CommentInput) (*models.Comment, error) {
+	uid, role, err := s.authorize(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	candidate := &models.Comment{
+		ID:         uuid.NewString(),
+		DocumentID: documentID,
+		AuthorID:   uid,
+		AuthorRole: role,
+		Body:       input.Body,
+		Page:       input.Page,
+		Region:     input.Region,
+	}
+	if err := candidate.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid comment: %w", err)
+	}
+
+	comment := map[string]interface{}{
+		"id":          candidate.ID,
+		"document_id": documentID,
+		"author_id":   uid,
+		"author_role": role,
+		"body":        input.Body,
+		"created_at":  firestore.ServerTimestamp,
+		"updated_at":  firestore.ServerTimestamp,
+	}
+	if input.Page != nil {
+		comment["page"] = *input.Page
+	}
+	if input.Region != nil {
+		comment["region"] = *input.Region
+	}
+
+	created, err := s.comments(documentID).Create(ctx, candidate.ID, comment)
+	s.audit.Record(ctx, audit.ActionCommentCreate, documentTarget(documentID), err, map[string]string{
+		"comment_id":  candidate.ID,
+		"author_role": string(role),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create comment: %w", err)
+	}
+
+	return created, nil
+}
+
+// getOwn returns a comment on the document written by the caller.
+func (s *commentService) getOwn(ctx context.Context, documentID string, id string) (*models.Comment, error) {
+	uid, _, err := s.authorize(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := s.comments(documentID).GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment by ID: %w", err)
+	}
+
+	if comment.AuthorID != uid {
+		return nil, fmt.Errorf("comment was written by another user: %w", ErrForbidden)
+	}
+
+	return comment, nil
+}
+
+// Update replaces the body, page and region of a comment written by the caller.
+func (s *commentService) Update(ctx context.Context, documentID string, id string, input CommentInput) (*models.Comment, error) {
+	current, err := s.getOwn(ctx, documentID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	candidate := *current
+	candidate.Body = input.Body
+	candidate.Page = input.Page
+	candidate.Region = input.Region
+	if err := candidate.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid comment: %w", err)
+	}
+
+	comment := map[string]interface{}{
+		"body":       input.Body,
+		"page":       firestore.Delete,
+		"region":     firestore.Delete,
+		"updated_at": firestore.ServerTimestamp,
+	}
+	if input.Page != nil {
+		comment["page"] = *input.Page
+	}
+	if input.Region != nil {
+		comment["region"] = *input.Region
+	}
+
+	updated, err := s.comments(documentID).Update(ctx, id, comment)
+	s.audit.Record(ctx, audit.ActionCommentUpdate, documentTarget(documentID), err, map[string]string{
+		"comment_id": id,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update comment: %w", err)
+	}
+
+	return updated, nil
+}
+
+// Delete removes a comment written by the caller.
+func (s *commentService) Delete(ctx context.Context, documentID string, id string) error {
+	if _, err := s.getOwn(ctx, documentID, id); err != nil {
+		return err
+	}
+
+	err := s.comments(documentID).Delete(ctx, id)
+	s.audit.Record(ctx, audit.ActionCommentDelete, documentTarget(documentID), err, map[string]string{
+		"comment_id": id,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete comment: %w", err)
+	}
+
+	return nil
+}
+
+// deleteComments removes every comment on a document, Firestore does not delete subcollections
+// with their parent. Failures are logged, as the document itself is already gone.
+func deleteComments(ctx context.Context, store CommentStore, documentID string) {
+	if store == nil {
+		return
+	}
+
+	comments := store(documentID)
+	existing, _, err := comments.GetAll(ctx, "", 0)
+	if err != nil {
+		log.Error().Err(err).Str("document_id", documentID).Msg("Failed to list comments of deleted document")
+
+		return
+	}
+
+	for _, comment := range existing {
+		if err := comments.Delete(ctx, comment.ID); err != nil {
+			log.Error().Err(err).Str("document_id", documentID).Str("comment_id", comment.ID).Msg("Failed to delete comment of deleted document")
+		}
+	}
+}
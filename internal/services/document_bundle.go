@@ -0,0 +1,377 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/audit"
+	"github.com/thoughtgears/shared-services/internal/db"
+	"github.com/thoughtgears/shared-services/internal/gcs"
+	"github.com/thoughtgears/shared-services/internal/models"
+)
+
+// CreateBundleInput holds the caller supplied values used to create a bundle.
+// The owner, type, expiry date and metadata apply to every document of the bundle.
+type CreateBundleInput struct {
+	UserID string
+	// OrganizationID makes the organization the owner of the bundle, the caller must be a member.
+	OrganizationID string
+	Type           models.DocumentType
+	ExpiryDate     *time.Time
+	Metadata       map[string]string
+	// Files are the parts of the bundle in order, each becomes a document.
+	Files []BundleFile
+}
+
+// BundleFile is a single uploaded part of a bundle.
+type BundleFile struct {
+	Content []byte
+	// OriginalFilename is the filename the client uploaded, it is sanitized before it is stored.
+	OriginalFilename string
+}
+
+// CreateBundle creates a document for every file of the input and a bundle grouping them.
+// Every file is validated before anything is written, and the documents and the bundle are
+// created atomically: if any of them cannot be created none is, and the uploaded content is removed.
+func (d *documentService) CreateBundle(ctx context.Context, input CreateBundleInput) (*models.Bundle, error) {
+	if len(input.Files) < models.MinBundleParts || len(input.Files) > models.MaxBundleParts {
+		verr := &models.ValidationError{}
+		verr.Add("files", fmt.Sprintf("must contain between %d and %d files", models.MinBundleParts, models.MaxBundleParts))
+
+		return nil, fmt.Errorf("invalid bundle: %w", verr)
+	}
+
+	inputs := make([]CreateDocumentInput, len(input.Files))
+	fileTypes := make([]*FileTypeInfo, len(input.Files))
+	for i, file := range input.Files {
+		fileType, err := DetectFileType(file.Content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to detect file type of file %d: %w", i+1, err)
+		}
+
+		fileTypes[i] = fileType
+		inputs[i] = CreateDocumentInput{
+			UserID:           input.UserID,
+			OrganizationID:   input.OrganizationID,
+			Type:             input.Type,
+			ExpiryDate:       input.ExpiryDate,
+			Metadata:         input.Metadata,
+			Content:          file.Content,
+			OriginalFilename: file.OriginalFilename,
+		}
+	}
+
+	definition, settings, err := d.authorizeUpload(ctx, inputs[0], len(inputs))
+	if err != nil {
+		return nil, err
+	}
+
+	uploads := make([]*documentUpload, len(inputs))
+	for i := range inputs {
+		uploads[i], err = newDocumentUpload(inputs[i], fileTypes[i], definition)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file %d: %w", i+1, err)
+		}
+
+		if err := d.hooks.beforeCreate(ctx, uploads[i].candidate, inputs[i].Content); err != nil {
+			return nil, fmt.Errorf("invalid file %d: %w", i+1, err)
+		}
+	}
+
+	storage, region, err := d.storageForUser(ctx, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	bundleID := uuid.NewString()
+	documentIDs := make([]string, 0, len(uploads))
+	uploaded := make([]*gcs.FileInfo, 0, len(uploads))
+	writes := make([]db.Write, 0, len(uploads)+1)
+	for _, upload := range uploads {
+		document, fileInfo, err := d.store(ctx, storage, region, upload, definition, settings)
+		if err != nil {
+			removeUploads(ctx, storage, uploaded)
+			return nil, err
+		}
+		uploaded = append(uploaded, fileInfo)
+
+		// The bundle carries the review status of its documents
+		delete(document, "status")
+		document["bundle_id"] = bundleID
+
+		write, err := d.db.PrepareCreate(ctx, upload.id, document)
+		if err != nil {
+			removeUploads(ctx, storage, uploaded)
+			return nil, fmt.Errorf("failed to prepare document: %w", err)
+		}
+
+		writes = append(writes, write)
+		documentIDs = append(documentIDs, upload.id)
+	}
+
+	bundle := map[string]interface{}{
+		"id":           bundleID,
+		"user_id":      input.UserID,
+		"type":         definition.ID,
+		"document_ids": documentIDs,
+		"status":       models.DocumentStatusPending,
+		"created_at":   firestore.ServerTimestamp,
+		"updated_at":   firestore.ServerTimestamp,
+	}
+
+	if input.OrganizationID != "" {
+		bundle["organization_id"] = input.OrganizationID
+	}
+
+	write, err := d.bundles.PrepareCreate(ctx, bundleID, bundle)
+	if err != nil {
+		removeUploads(ctx, storage, uploaded)
+		return nil, fmt.Errorf("failed to prepare bundle: %w", err)
+	}
+
+	err = db.Commit(ctx, append(writes, write)...)
+	d.audit.Record(ctx, audit.ActionBundleCreate, bundleTarget(bundleID), err, map[string]string{
+		"user_id":      input.UserID,
+		"type":         string(definition.ID),
+		"document_ids": strings.Join(documentIDs, ","),
+	})
+	if err != nil {
+		recordFailure(ctx, "bundle.create")
+		removeUploads(ctx, storage, uploaded)
+		return nil, fmt.Errorf("failed to create bundle: %w", err)
+	}
+
+	for i, fileInfo := range uploaded {
+		d.audit.Record(ctx, audit.ActionDocumentCreate, documentTarget(documentIDs[i]), nil, map[string]string{
+			"user_id":   input.UserID,
+			"type":      string(definition.ID),
+			"bundle_id": bundleID,
+		})
+		recordUpload(ctx, string(definition.ID), fileInfo.Size)
+	}
+
+	created, err := d.GetBundle(ctx, bundleID)
+	if err != nil {
+		return nil, err
+	}
+	for _, document := range created.Documents {
+		d.hooks.afterCreate(ctx, document)
+	}
+
+	return created, nil
+}
+
+// GetBundle retrieves a bundle with its documents.
+// Bundles owned by an organization are only returned to its members.
+func (d *documentService) GetBundle(ctx context.Context, id string) (*models.Bundle, error) {
+	bundle, err := d.getBundleAuthorized(ctx, id, models.RoleMember)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.loadBundleDocuments(ctx, bundle); err != nil {
+		return nil, err
+	}
+
+	return bundle, nil
+}
+
+// getBundleAuthorized retrieves a bundle and checks the caller has at least minRole in the
+// organization that owns it. Bundles owned by an individual are only returned to their owner.
+func (d *documentService) getBundleAuthorized(ctx context.Context, id string, minRole models.Role) (*models.Bundle, error) {
+	bundle, err := d.bundles.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bundle by ID: %w", err)
+	}
+
+	if err := authorizeOwner(ctx, d.organizations, "bundle", bundle.UserID, bundle.OrganizationID, minRole); err != nil {
+		return nil, err
+	}
+
+	return bundle, nil
+}
+
+// loadBundleDocuments sets the documents of the bundle, in the order they were uploaded.
+func (d *documentService) loadBundleDocuments(ctx context.Context, bundle *models.Bundle) error {
+	documents, _, err := d.db.Find(ctx, db.Query[models.Document]().Where("bundle_id", db.Eq, bundle.ID))
+	if err != nil {
+		return fmt.Errorf("failed to get bundle documents: %w", err)
+	}
+
+	slices.SortFunc(documents, func(a, b *models.Document) int {
+		return cmp.Compare(slices.Index(bundle.DocumentIDs, a.ID), slices.Index(bundle.DocumentIDs, b.ID))
+	})
+	bundle.Documents = documents
+
+	return nil
+}
+
+// DeleteBundle deletes a bundle together with all of its documents.
+// The documents are deleted first, so a failure leaves the bundle in place to retry the delete.
+func (d *documentService) DeleteBundle(ctx context.Context, id string) error {
+	bundle, err := d.getBundleAuthorized(ctx, id, models.RoleAdmin)
+	if err != nil {
+		return err
+	}
+
+	if err := d.loadBundleDocuments(ctx, bundle); err != nil {
+		return err
+	}
+
+	// A held document keeps the whole bundle, none of its documents are deleted
+	for _, document := range bundle.Documents {
+		if err := checkLegalHold(document); err != nil {
+			return err
+		}
+	}
+
+	for _, document := range bundle.Documents {
+		if err := d.delete(ctx, document); err != nil {
+			d.audit.Record(ctx, audit.ActionBundleDelete, bundleTarget(id), err, nil)
+			return err
+		}
+	}
+
+	err = d.bundles.Delete(ctx, id)
+	d.audit.Record(ctx, audit.ActionBundleDelete, bundleTarget(id), err, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete bundle from database: %w", err)
+	}
+
+	return nil
+}
+
+// BundleReviewQueue lists the bundles with the given status, pending or in_review, oldest first.
+// Like ReviewQueue the page holds the first bundles of the queue and its total count.
+func (d *documentService) BundleReviewQueue(ctx context.Context, status models.DocumentStatus, opts ListOptions) (*models.Page[*models.Bundle], error) {
+	status, err := queueStatus(status)
+	if err != nil {
+		return nil, err
+	}
+
+	bundles, _, err := d.bundles.Find(ctx, db.Query[models.Bundle]().Where("status", db.Eq, status))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bundle review queue: %w", err)
+	}
+
+	slices.SortFunc(bundles, func(a, b *models.Bundle) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+
+	total := int64(len(bundles))
+	pageSize := opts.pageSize()
+	page := models.NewPage(bundles[:min(len(bundles), pageSize)], "", pageSize)
+	page.TotalCount = &total
+
+	return page, nil
+}
+
+// ClaimBundle assigns a pending bundle to the calling reviewer, following the rules of Claim.
+// The bundle is returned with its documents so the reviewer can work through them.
+func (d *documentService) ClaimBundle(ctx context.Context, id string) (*models.Bundle, error) {
+	reviewerID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle, err := d.bundles.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bundle by ID: %w", err)
+	}
+
+	claimed, err := checkClaim("bundle", bundle.Status, bundle.ReviewerID, reviewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !claimed {
+		previous := bundle.Status
+		bundle, err = d.bundles.Update(ctx, id, claimUpdate(reviewerID))
+		d.audit.Record(ctx, audit.ActionBundleClaim, bundleTarget(id), err, map[string]string{
+			"from_status": string(previous),
+			"to_status":   string(models.DocumentStatusInReview),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim bundle: %w", err)
+		}
+	}
+
+	if err := d.loadBundleDocuments(ctx, bundle); err != nil {
+		return nil, err
+	}
+
+	return bundle, nil
+}
+
+// ReviewBundle records the calling reviewer's decision on a bundle they claimed.
+// The decision applies to the bundle as a whole, its documents are verified or rejected together.
+func (d *documentService) ReviewBundle(ctx context.Context, id string, input ReviewDocumentInput) (*models.Bundle, error) {
+	reviewerID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := input.validate(); err != nil {
+		return nil, err
+	}
+
+	bundle, err := d.bundles.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bundle by ID: %w", err)
+	}
+
+	if bundle.Status != models.DocumentStatusInReview || bundle.ReviewerID != reviewerID {
+		return nil, fmt.Errorf("%w: bundle must be claimed by the reviewer first", ErrReviewConflict)
+	}
+
+	updatedBundle, err := d.bundles.Update(ctx, id, input.update())
+	d.audit.Record(ctx, audit.ActionBundleReview, bundleTarget(id), err, map[string]string{
+		"from_status": string(bundle.Status),
+		"to_status":   string(input.Status),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record bundle review: %w", err)
+	}
+	d.notifyReview(ctx, "bundle", bundle.UserID, id, input)
+
+	if err := d.loadBundleDocuments(ctx, updatedBundle); err != nil {
+		return nil, err
+	}
+
+	return updatedBundle, nil
+}
+
+// resetBundleReview puts a bundle back into the review queue, it is called when the content of
+// one of its documents changes.
+func (d *documentService) resetBundleReview(ctx context.Context, id string) error {
+	update := pendingReview()
+	update["updated_at"] = firestore.ServerTimestamp
+
+	if _, err := d.bundles.Update(ctx, id, update); err != nil {
+		return fmt.Errorf("failed to reset bundle review: %w", err)
+	}
+
+	return nil
+}
+
+// removeUploads deletes content uploaded for documents that were not created.
+func removeUploads(ctx context.Context, storage gcs.Storage, uploaded []*gcs.FileInfo) {
+	for _, fileInfo := range uploaded {
+		if err := storage.Delete(ctx, fileInfo.Path); err != nil {
+			log.Error().Err(err).Str("path", fileInfo.Path).Msg("Failed to delete content of document that was not created")
+		}
+	}
+}
+
+// bundleTarget returns the audit target for a bundle.
+func bundleTarget(id string) audit.Target {
+	return audit.Target{Type: "bundle", ID: id}
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/gcs"
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/tenant"
+)
+
+// ContentFormat is a web friendly image format documents can be converted to for display.
+type ContentFormat string
+
+const (
+	ContentFormatJPEG ContentFormat = "jpg"
+	ContentFormatPNG  ContentFormat = "png"
+)
+
+// MaxContentWidth is the largest width converted content can be scaled to.
+const MaxContentWidth = 4096
+
+// ContentOptions select the format and size of converted document content.
+type ContentOptions struct {
+	// Format is the format to convert to, JPEG when empty.
+	Format ContentFormat
+	// MaxWidth scales images wider than it down, keeping their aspect ratio. 0 keeps the original size.
+	MaxWidth int
+}
+
+// Content is document content to be streamed to the client. The caller must close it.
+type Content struct {
+	io.ReadCloser
+	ContentType string
+	// Size is the length of the content in bytes, or -1 when it is not known.
+	Size int64
+}
+
+// contentTypes maps the formats to the content type they are served with.
+var contentTypes = map[ContentFormat]string{
+	ContentFormatJPEG: "image/jpeg",
+	ContentFormatPNG:  "image/png",
+}
+
+// GetContent returns a document image converted to a web friendly format, so the frontend can
+// display documents uploaded in formats browsers cannot show. Conversions are cached in storage
+// under a derived prefix, keyed by the document's current content, so each is only done once.
+func (d *documentService) GetContent(ctx context.Context, id string, opts ContentOptions) (*Content, error) {
+	if opts.Format == "" {
+		opts.Format = ContentFormatJPEG
+	}
+
+	verr := &models.ValidationError{}
+	contentType, ok := contentTypes[opts.Format]
+	if !ok {
+		verr.Add("format", "must be jpg or png")
+	}
+	if opts.MaxWidth < 0 || opts.MaxWidth > MaxContentWidth {
+		verr.Add("max_width", fmt.Sprintf("must be between 0 and %d", MaxContentWidth))
+	}
+	if err := verr.Err(); err != nil {
+		return nil, fmt.Errorf("invalid content options: %w", err)
+	}
+
+	document, err := d.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document by ID: %w", err)
+	}
+
+	if !strings.HasPrefix(document.ContentType, "image/") {
+		verr.Add("content_type", fmt.Sprintf("%s documents cannot be converted", document.ContentType))
+
+		return nil, fmt.Errorf("invalid content options: %w", verr)
+	}
+
+	storage, _, err := d.storage.For(document.Region)
+	if err != nil {
+		return nil, err
+	}
+
+	d.restoreCold(ctx, document)
+
+	// Content that is already in the requested format and size is served as uploaded
+	if document.ContentType == contentType && opts.MaxWidth == 0 {
+		return download(ctx, storage, document.Path, contentType)
+	}
+
+	path := fmt.Sprintf("%s%s.%d.%s", derivedPrefix(ctx, id), document.Name, opts.MaxWidth, opts.Format)
+	cached, err := download(ctx, storage, path, contentType)
+	if err == nil {
+		return cached, nil
+	}
+	if !gcs.IsNotExist(err) {
+		return nil, err
+	}
+
+	original, err := download(ctx, storage, document.Path, document.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	content, err := io.ReadAll(original)
+	original.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document: %w", err)
+	}
+
+	img, err := decodeImage(content)
+	if err != nil {
+		return nil, err
+	}
+
+	converted, err := encodeImage(resizeImage(img, opts.MaxWidth), contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	// A failed cache write only costs a conversion on the next request
+	if _, err := storage.Upload(ctx, path, bytes.NewReader(converted), contentType); err != nil {
+		log.Error().Err(err).Str("document_id", id).Msg("Failed to cache converted document content")
+	}
+
+	return &Content{
+		ReadCloser:  io.NopCloser(bytes.NewReader(converted)),
+		ContentType: contentType,
+		Size:        int64(len(converted)),
+	}, nil
+}
+
+// download opens the object at path as Content of the given type.
+func download(ctx context.Context, storage gcs.Storage, path string, contentType string) (*Content, error) {
+	reader, err := storage.Download(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download document: %w", err)
+	}
+
+	return &Content{ReadCloser: reader, ContentType: contentType, Size: -1}, nil
+}
+
+// derivedPrefix returns the storage prefix of the content derived from a document, such as conversions.
+func derivedPrefix(ctx context.Context, id string) string {
+	return fmt.Sprintf("%sderived/%s/", tenant.PathPrefix(ctx), id)
+}
+
+// deleteDerived removes the content derived from a document, it is called when the content of the
+// document changes or is removed. Derived content is a cache, so failures are only logged.
+func deleteDerived(ctx context.Context, storage gcs.Storage, id string) {
+	files, err := storage.List(ctx, derivedPrefix(ctx, id))
+	if err != nil {
+		log.Error().Err(err).Str("document_id", id).Msg("Failed to list derived document content")
+
+		return
+	}
+
+	for _, file := range files {
+		if err := storage.Delete(ctx, file.Path); err != nil {
+			log.Error().Err(err).Str("document_id", id).Str("path", file.Path).Msg("Failed to delete derived document content")
+		}
+	}
+}
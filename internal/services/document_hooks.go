@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/models"
+)
+
+// DocumentPlugin is an optional feature hooked into the document lifecycle, such as a virus scan,
+// OCR, webhooks or quota accounting. A plugin implements any of the hook interfaces below and is
+// added with DocumentService.Use; it is called for the hooks it implements, in the order plugins
+// were added.
+type DocumentPlugin interface {
+	// Name identifies the plugin in errors and logs.
+	Name() string
+}
+
+// BeforeCreateHook is called with every new document after it passed validation and before
+// anything is stored. An error rejects the document.
+type BeforeCreateHook interface {
+	BeforeCreate(ctx context.Context, document *models.Document, content []byte) error
+}
+
+// AfterCreateHook is called with every document once it has been created.
+type AfterCreateHook interface {
+	AfterCreate(ctx context.Context, document *models.Document) error
+}
+
+// BeforeUpdateHook is called before the content of a document is replaced, with the document as it
+// is and the new content. An error rejects the update.
+type BeforeUpdateHook interface {
+	BeforeUpdate(ctx context.Context, document *models.Document, content []byte) error
+}
+
+// AfterUpdateHook is called with a document once its content has been replaced.
+type AfterUpdateHook interface {
+	AfterUpdate(ctx context.Context, document *models.Document) error
+}
+
+// BeforeDeleteHook is called before a document is deleted. An error stops the delete.
+type BeforeDeleteHook interface {
+	BeforeDelete(ctx context.Context, document *models.Document) error
+}
+
+// AfterDeleteHook is called with a document once it has been deleted.
+type AfterDeleteHook interface {
+	AfterDelete(ctx context.Context, document *models.Document) error
+}
+
+// documentHooks holds the plugins of a document service. Plugins are added on startup, before
+// the service handles requests.
+type documentHooks struct {
+	plugins []DocumentPlugin
+}
+
+// use adds plugins after those already added.
+func (h *documentHooks) use(plugins ...DocumentPlugin) {
+	h.plugins = append(h.plugins, plugins...)
+}
+
+// beforeCreate runs the BeforeCreate hooks, stopping at the first that rejects the document.
+func (h *documentHooks) beforeCreate(ctx context.Context, document *models.Document, content []byte) error {
+	for _, plugin := range h.plugins {
+		if hook, ok := plugin.(BeforeCreateHook); ok {
+			if err := hook.BeforeCreate(ctx, document, content); err != nil {
+				return fmt.Errorf("%s rejected the document: %w", plugin.Name(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// afterCreate runs the AfterCreate hooks.
+func (h *documentHooks) afterCreate(ctx context.Context, document *models.Document) {
+	for _, plugin := range h.plugins {
+		if hook, ok := plugin.(AfterCreateHook); ok {
+			logHookError(plugin, "AfterCreate", document, hook.AfterCreate(ctx, document))
+		}
+	}
+}
+
+// beforeUpdate runs the BeforeUpdate hooks, stopping at the first that rejects the update.
+func (h *documentHooks) beforeUpdate(ctx context.Context, document *models.Document, content []byte) error {
+	for _, plugin := range h.plugins {
+		if hook, ok := plugin.(BeforeUpdateHook); ok {
+			if err := hook.BeforeUpdate(ctx, document, content); err != nil {
+				return fmt.Errorf("%s rejected the update: %w", plugin.Name(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// afterUpdate runs the AfterUpdate hooks.
+func (h *documentHooks) afterUpdate(ctx context.Context, document *models.Document) {
+	for _, plugin := range h.plugins {
+		if hook, ok := plugin.(AfterUpdateHook); ok {
+			logHookError(plugin, "AfterUpdate", document, hook.AfterUpdate(ctx, document))
+		}
+	}
+}
+
+// beforeDelete runs the BeforeDelete hooks, stopping at the first that stops the delete.
+func (h *documentHooks) beforeDelete(ctx context.Context, document *models.Document) error {
+	for _, plugin := range h.plugins {
+		if hook, ok := plugin.(BeforeDeleteHook); ok {
+			if err := hook.BeforeDelete(ctx, document); err != nil {
+				return fmt.Errorf("%s stopped the delete: %w", plugin.Name(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// afterDelete runs the AfterDelete hooks.
+func (h *documentHooks) afterDelete(ctx context.Context, document *models.Document) {
+	for _, plugin := range h.plugins {
+		if hook, ok := plugin.(AfterDeleteHook); ok {
+			logHookError(plugin, "AfterDelete", document, hook.AfterDelete(ctx, document))
+		}
+	}
+}
+
+// logHookError logs the failure of an after hook. The operation has already succeeded, so the
+// failure is not returned to the caller; a plugin that must not miss events retries on its own.
+func logHookError(plugin DocumentPlugin, hook string, document *models.Document, err error) {
+	if err == nil {
+		return
+	}
+
+	log.Error().Err(err).Str("plugin", plugin.Name()).Str("hook", hook).Str("document_id", document.ID).Msg("Document hook failed")
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+
+	// Decoders register themselves with the image package, images in formats without a
+	// registered decoder cannot be redacted or converted
+	_ "image/gif"
+
+	"github.com/thoughtgears/shared-services/internal/models"
+)
+
+// maxImagePixels bounds the size of images that are decoded, so a small file that
+// declares huge dimensions cannot exhaust the memory of the service.
+const maxImagePixels = 50_000_000
+
+// decodeImage decodes an image in any registered format. Images in a format without a
+// decoder and images larger than maxImagePixels are reported as a validation error.
+func decodeImage(content []byte) (image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if errors.Is(err, image.ErrFormat) {
+		verr := &models.ValidationError{}
+		verr.Add("content_type", "documents in this format cannot be processed")
+
+		return nil, fmt.Errorf("invalid image: %w", verr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode document image: %w", err)
+	}
+
+	if config.Width*config.Height > maxImagePixels {
+		verr := &models.ValidationError{}
+		verr.Add("content", fmt.Sprintf("images larger than %d pixels cannot be processed", maxImagePixels))
+
+		return nil, fmt.Errorf("invalid image: %w", verr)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode document image: %w", err)
+	}
+
+	return img, nil
+}
+
+// encodeImage encodes img as a PNG or, for any other content type, as a JPEG.
+// JPEG has no transparency, so transparent areas are drawn on a white background.
+func encodeImage(img image.Image, contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch contentType {
+	case "image/png":
+		err = png.Encode(&buf, img)
+	default:
+		bounds := img.Bounds()
+		canvas := image.NewRGBA(bounds)
+		draw.Draw(canvas, bounds, image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.Draw(canvas, bounds, img, bounds.Min, draw.Over)
+		err = jpeg.Encode(&buf, canvas, &jpeg.Options{Quality: 90})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// resizeImage scales img down to width, keeping its aspect ratio. Every pixel is the average of
+// the source pixels it covers, which keeps text on scanned documents readable.
+func resizeImage(img image.Image, width int) image.Image {
+	bounds := img.Bounds()
+	if width <= 0 || width >= bounds.Dx() {
+		return img
+	}
+
+	height := max(1, bounds.Dy()*width/bounds.Dx())
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*bounds.Dy()/height)
+		for x := range width {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := max(x0+1, bounds.Min.X+(x+1)*bounds.Dx()/width)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					sr, sg, sb, sa := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(sr), g+uint64(sg), b+uint64(sb), a+uint64(sa)
+					n++
+				}
+			}
+
+			dst.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)})
+		}
+	}
+
+	return dst
+}
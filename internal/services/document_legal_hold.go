@@ -0,0 +1,173 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/audit"
+	"github.com/thoughtgears/shared-services/internal/gcs"
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/pkg/apperr"
+)
+
+// ErrLegalHold is returned when a document under legal hold would be changed or deleted.
+var ErrLegalHold = apperr.New(apperr.Conflict, "document is under legal hold")
+
+// PlaceLegalHoldInput is an administrator's request to place a document under legal hold.
+type PlaceLegalHoldInput struct {
+	// Reason records why the document is preserved, e.g. the case it belongs to.
+	Reason string `json:"reason" binding:"required"`
+	// Type is the object hold placed on the content, temporary when empty. Releasing an
+	// event_based hold starts the bucket's retention period over.
+	Type string `json:"type"`
+}
+
+// MaxLegalHoldReasonLength is the maximum length of the reason of a legal hold.
+const MaxLegalHoldReasonLength = 1000
+
+// PlaceLegalHold places a document under legal hold. Every object of the document gets a Cloud
+// Storage hold before the hold is recorded, so storage refuses deletes even if the record fails.
+// The hold is only recorded if the document is unchanged since it was read, a document updated
+// or deleted meanwhile fails with db.ErrChanged as its objects may not be the ones held.
+// Placing a hold on a document already held returns it unchanged.
+func (d *documentService) PlaceLegalHold(ctx context.Context, id string, input PlaceLegalHoldInput) (*models.Document, error) {
+	adminID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	input.Type = cmp.Or(input.Type, gcs.HoldTemporary)
+	if err := input.validate(); err != nil {
+		return nil, err
+	}
+
+	// Platform admins hold documents without being members of the owning organization
+	document, err := d.db.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document by ID: %w", err)
+	}
+
+	if document.LegalHold != nil {
+		return document, nil
+	}
+
+	storage, _, err := d.storage.For(document.Region)
+	if err != nil {
+		return nil, err
+	}
+
+	details := map[string]string{"type": input.Type, "reason": input.Reason}
+
+	paths := document.ContentPaths()
+	for i, path := range paths {
+		if err := storage.SetHold(ctx, path, input.Type, true); err != nil {
+			d.audit.Record(ctx, audit.ActionDocumentHold, documentTarget(id), err, details)
+			releaseHolds(ctx, storage, id, paths[:i], input.Type)
+
+			return nil, fmt.Errorf("failed to hold %s: %w", path, err)
+		}
+	}
+
+	updatedDocument, err := d.db.UpdateIfUnchanged(ctx, id, document.UpdatedAt, map[string]interface{}{
+		"legal_hold": models.LegalHold{
+			Type:     input.Type,
+			Reason:   input.Reason,
+			PlacedBy: adminID,
+			PlacedAt: time.Now().UTC(),
+		},
+		"updated_at": firestore.ServerTimestamp,
+	})
+	d.audit.Record(ctx, audit.ActionDocumentHold, documentTarget(id), err, details)
+	if err != nil {
+		releaseHolds(ctx, storage, id, paths, input.Type)
+
+		return nil, fmt.Errorf("failed to record legal hold: %w", err)
+	}
+
+	return updatedDocument, nil
+}
+
+// ReleaseLegalHold releases the legal hold of a document. The object holds are released before
+// the record, a failure leaves the document held so the release can be retried. Releasing a
+// document that is not held returns it unchanged.
+func (d *documentService) ReleaseLegalHold(ctx context.Context, id string) (*models.Document, error) {
+	// Platform admins release documents without being members of the owning organization
+	document, err := d.db.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document by ID: %w", err)
+	}
+
+	hold := document.LegalHold
+	if hold == nil {
+		return document, nil
+	}
+
+	storage, _, err := d.storage.For(document.Region)
+	if err != nil {
+		return nil, err
+	}
+
+	details := map[string]string{"type": hold.Type, "placed_by": hold.PlacedBy}
+
+	for _, path := range document.ContentPaths() {
+		if err := storage.SetHold(ctx, path, hold.Type, false); err != nil {
+			d.audit.Record(ctx, audit.ActionDocumentHoldRelease, documentTarget(id), err, details)
+
+			return nil, fmt.Errorf("failed to release hold of %s: %w", path, err)
+		}
+	}
+
+	updatedDocument, err := d.db.Update(ctx, id, map[string]interface{}{
+		"legal_hold": firestore.Delete,
+		"updated_at": firestore.ServerTimestamp,
+	})
+	d.audit.Record(ctx, audit.ActionDocumentHoldRelease, documentTarget(id), err, details)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record legal hold release: %w", err)
+	}
+
+	return updatedDocument, nil
+}
+
+// checkLegalHold returns an error wrapping ErrLegalHold if the document is under legal hold.
+func checkLegalHold(document *models.Document) error {
+	if document.LegalHold != nil {
+		return fmt.Errorf("%w: document %s", ErrLegalHold, document.ID)
+	}
+
+	return nil
+}
+
+// releaseHolds releases the holds placed on paths by a legal hold that could not be completed.
+// Failures are logged, the objects stay held until the hold is placed and released again.
+func releaseHolds(ctx context.Context, storage gcs.Storage, id string, paths []string, hold string) {
+	for _, path := range paths {
+		if err := storage.SetHold(ctx, path, hold, false); err != nil {
+			log.Error().Err(err).Str("document_id", id).Str("path", path).Msg("Failed to release hold of incomplete legal hold")
+		}
+	}
+}
+
+// validate checks the input of a legal hold, with the type already defaulted.
+func (input PlaceLegalHoldInput) validate() error {
+	verr := &models.ValidationError{}
+	if input.Reason == "" {
+		verr.Add("reason", "is required")
+	}
+	if len(input.Reason) > MaxLegalHoldReasonLength {
+		verr.Add("reason", fmt.Sprintf("must be at most %d characters", MaxLegalHoldReasonLength))
+	}
+	if input.Type != gcs.HoldTemporary && input.Type != gcs.HoldEventBased {
+		verr.Add("type", fmt.Sprintf("must be %s or %s", gcs.HoldTemporary, gcs.HoldEventBased))
+	}
+	if err := verr.Err(); err != nil {
+		return fmt.Errorf("invalid legal hold: %w", err)
+	}
+
+	return nil
+}
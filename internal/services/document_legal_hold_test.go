@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/thoughtgears/shared-services/internal/db"
+	"github.com/thoughtgears/shared-services/internal/gcs"
+	"github.com/thoughtgears/shared-services/internal/models"
+)
+
+func newHoldTestService(documents *fakeDB[models.Document], storage *fakeStorage) *documentService {
+	regional := NewRegionalStorage("europe-west1", map[string]gcs.Storage{"europe-west1": storage})
+
+	return NewDocumentService(regional, nil, nil, nil, documents, nil, nil, nil, time.Minute, nil, nil, nil, nil).(*documentService)
+}
+
+func heldDocument() *models.Document {
+	return &models.Document{
+		ID:        "doc-1",
+		UserID:    "user-1",
+		Path:      "user-1/doc-1",
+		UpdatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		LegalHold: &models.LegalHold{Type: gcs.HoldTemporary, Reason: "case 42", PlacedBy: "admin-1"},
+	}
+}
+
+func TestCheckLegalHold(t *testing.T) {
+	if err := checkLegalHold(&models.Document{ID: "doc-1"}); err != nil {
+		t.Errorf("checkLegalHold() of a document without hold error = %v", err)
+	}
+	if err := checkLegalHold(heldDocument()); !errors.Is(err, ErrLegalHold) {
+		t.Errorf("checkLegalHold() of a held document error = %v, want ErrLegalHold", err)
+	}
+}
+
+func TestLegalHoldBlocksChanges(t *testing.T) {
+	ctx := asUser("user-1", false)
+
+	tests := map[string]func(d *documentService) error{
+		"delete": func(d *documentService) error {
+			return d.Delete(ctx, "doc-1")
+		},
+		"rename": func(d *documentService) error {
+			_, err := d.Rename(ctx, "doc-1", "Passport")
+
+			return err
+		},
+		"update metadata": func(d *documentService) error {
+			_, err := d.UpdateMetadata(ctx, "doc-1", map[string]string{"country": "NO"})
+
+			return err
+		},
+		"move region": func(d *documentService) error {
+			_, err := d.MoveRegion(asUser("admin-1", true), "doc-1", "us-east1")
+
+			return err
+		},
+	}
+	for name, change := range tests {
+		t.Run(name, func(t *testing.T) {
+			documents := newFakeDB(map[string]*models.Document{"doc-1": heldDocument()})
+			storage := &fakeStorage{}
+
+			if err := change(newHoldTestService(documents, storage)); !errors.Is(err, ErrLegalHold) {
+				t.Fatalf("error = %v, want ErrLegalHold", err)
+			}
+			if len(documents.writes) > 0 || len(storage.ops) > 0 {
+				t.Errorf("held document was changed: writes %v, storage %v", documents.writes, storage.ops)
+			}
+		})
+	}
+}
+
+func TestPlaceLegalHold(t *testing.T) {
+	ctx := asUser("admin-1", true)
+	document := heldDocument()
+	document.LegalHold = nil
+	document.Versions = []models.DocumentVersion{{Path: "user-1/doc-1.v1"}}
+
+	documents := newFakeDB(map[string]*models.Document{"doc-1": document})
+	storage := &fakeStorage{}
+	if _, err := newHoldTestService(documents, storage).PlaceLegalHold(ctx, "doc-1", PlaceLegalHoldInput{Reason: "case 42"}); err != nil {
+		t.Fatalf("PlaceLegalHold() error = %v", err)
+	}
+
+	// Every object is held before the hold is recorded
+	want := []string{"hold user-1/doc-1 temporary true", "hold user-1/doc-1.v1 temporary true"}
+	if !reflect.DeepEqual(storage.ops, want) {
+		t.Errorf("storage = %v, want %v", storage.ops, want)
+	}
+	if !reflect.DeepEqual(documents.writes, []string{"update doc-1"}) {
+		t.Errorf("writes = %v, want the hold recorded", documents.writes)
+	}
+}
+
+func TestPlaceLegalHoldOnChangedDocument(t *testing.T) {
+	ctx := asUser("admin-1", true)
+	document := heldDocument()
+	document.LegalHold = nil
+
+	documents := newFakeDB(map[string]*models.Document{"doc-1": document})
+	documents.changed = true
+	storage := &fakeStorage{}
+	_, err := newHoldTestService(documents, storage).PlaceLegalHold(ctx, "doc-1", PlaceLegalHoldInput{Reason: "case 42"})
+	if !errors.Is(err, db.ErrChanged) {
+		t.Fatalf("PlaceLegalHold() error = %v, want db.ErrChanged", err)
+	}
+
+	// The objects held for the document that changed meanwhile are released again
+	want := []string{"hold user-1/doc-1 temporary true", "hold user-1/doc-1 temporary false"}
+	if !reflect.DeepEqual(storage.ops, want) {
+		t.Errorf("storage = %v, want %v", storage.ops, want)
+	}
+}
+
+func TestPlaceLegalHoldValidates(t *testing.T) {
+	documents := newFakeDB(map[string]*models.Document{"doc-1": heldDocument()})
+	d := newHoldTestService(documents, &fakeStorage{})
+
+	for name, input := range map[string]PlaceLegalHoldInput{
+		"no reason":    {},
+		"unknown type": {Reason: "case 42", Type: "forever"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := d.PlaceLegalHold(asUser("admin-1", true), "doc-1", input); !errors.As(err, new(*models.ValidationError)) {
+				t.Errorf("PlaceLegalHold() error = %v, want a validation error", err)
+			}
+		})
+	}
+
+	if _, err := d.PlaceLegalHold(context.Background(), "doc-1", PlaceLegalHoldInput{Reason: "case 42"}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("PlaceLegalHold() without a caller error = %v, want ErrForbidden", err)
+	}
+}
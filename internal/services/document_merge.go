@@ -0,0 +1,167 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/thoughtgears/shared-services/internal/audit"
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/pdf"
+)
+
+const (
+	// MaxMergeDocuments is the maximum number of documents merged into one PDF.
+	MaxMergeDocuments = 20
+	// MaxMergePages is the maximum number of pages of a merged PDF.
+	MaxMergePages = 500
+	// mergeImageWidth is the width images are scaled down to before they are merged,
+	// about the width of an A4 page at 300 dpi.
+	mergeImageWidth = 2480
+)
+
+// MergeDocumentsInput lists the documents to merge into a single PDF, in page order.
+type MergeDocumentsInput struct {
+	DocumentIDs []string `json:"document_ids" binding:"required"`
+	// Type is the document type of the merged PDF, other when empty.
+	Type models.DocumentType `json:"type"`
+	// DisplayName is the display name of the merged PDF.
+	DisplayName string `json:"display_name"`
+}
+
+// Merge combines a user's images and PDFs into a single PDF, e.g. an ID pack to submit to a third
+// party, and stores it as a new document of the same owner. Every image becomes a page, PDFs add
+// all their pages. The documents must all belong to the same owner, encrypted PDFs are refused.
+func (d *documentService) Merge(ctx context.Context, input MergeDocumentsInput) (*models.Document, error) {
+	verr := &models.ValidationError{}
+	if len(input.DocumentIDs) < 2 || len(input.DocumentIDs) > MaxMergeDocuments {
+		verr.Add("document_ids", fmt.Sprintf("must contain between 2 and %d documents", MaxMergeDocuments))
+	} else if len(slices.Compact(slices.Sorted(slices.Values(input.DocumentIDs)))) != len(input.DocumentIDs) {
+		verr.Add("document_ids", "must not contain duplicates")
+	}
+	// The name is checked up front so the merged document is not created when it is invalid
+	models.ValidateDisplayName(strings.TrimSpace(input.DisplayName), verr)
+	if err := verr.Err(); err != nil {
+		return nil, fmt.Errorf("invalid merge: %w", err)
+	}
+
+	documents := make([]*models.Document, len(input.DocumentIDs))
+	for i, id := range input.DocumentIDs {
+		document, err := d.GetByID(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get document by ID: %w", err)
+		}
+
+		field := fmt.Sprintf("document_ids[%d]", i)
+		if i > 0 && (document.UserID != documents[0].UserID || document.OrganizationID != documents[0].OrganizationID) {
+			verr.Add(field, "must belong to the same owner as the other documents")
+		}
+		if !strings.HasPrefix(document.ContentType, "image/") && document.ContentType != "application/pdf" {
+			verr.Add(field, fmt.Sprintf("%s documents cannot be merged, only images and PDFs", document.ContentType))
+		}
+		documents[i] = document
+	}
+	if err := verr.Err(); err != nil {
+		return nil, fmt.Errorf("invalid merge: %w", err)
+	}
+
+	var pages []pdf.Page
+	for i, document := range documents {
+		documentPages, err := d.pdfPages(ctx, document)
+		if errors.Is(err, pdf.ErrEncrypted) || errors.Is(err, pdf.ErrMalformed) {
+			verr.Add(fmt.Sprintf("document_ids[%d]", i), fmt.Sprintf("cannot be merged: %v", err))
+
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert document %s: %w", document.ID, err)
+		}
+		pages = append(pages, documentPages...)
+	}
+	if len(pages) > MaxMergePages {
+		verr.Add("document_ids", fmt.Sprintf("must have at most %d pages in total", MaxMergePages))
+	}
+	if err := verr.Err(); err != nil {
+		return nil, fmt.Errorf("invalid merge: %w", err)
+	}
+
+	var merged bytes.Buffer
+	if err := pdf.Write(&merged, pages); err != nil {
+		return nil, err
+	}
+
+	documentType := input.Type
+	if documentType == "" {
+		documentType = models.DocumentTypeOther
+	}
+
+	document, err := d.Create(ctx, CreateDocumentInput{
+		UserID:           documents[0].UserID,
+		OrganizationID:   documents[0].OrganizationID,
+		Type:             documentType,
+		Content:          merged.Bytes(),
+		OriginalFilename: "merged.pdf",
+	})
+	target := documentTarget("")
+	if document != nil {
+		target = documentTarget(document.ID)
+	}
+	d.audit.Record(ctx, audit.ActionDocumentMerge, target, err, map[string]string{
+		"document_ids": strings.Join(input.DocumentIDs, ","),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if input.DisplayName != "" {
+		return d.Rename(ctx, document.ID, input.DisplayName)
+	}
+
+	return document, nil
+}
+
+// pdfPages downloads a document and returns its pages for a PDF: the pages of a PDF, or a single
+// page for an image, re-encoded as a JPEG.
+func (d *documentService) pdfPages(ctx context.Context, document *models.Document) ([]pdf.Page, error) {
+	storage, _, err := d.storage.For(document.Region)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := download(ctx, storage, document.Path, document.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	content, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document: %w", err)
+	}
+
+	if document.ContentType == "application/pdf" {
+		file, err := pdf.Read(content)
+		if err != nil {
+			return nil, err
+		}
+
+		return file.Pages(), nil
+	}
+
+	img, err := decodeImage(content)
+	if err != nil {
+		return nil, err
+	}
+
+	// encodeImage writes JPEGs with three components, as the PDF page expects
+	img = resizeImage(img, mergeImageWidth)
+	encoded, err := encodeImage(img, "image/jpeg")
+	if err != nil {
+		return nil, err
+	}
+
+	return []pdf.Page{pdf.Image{JPEG: encoded, Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}}, nil
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"math"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/google/uuid"
+
+	"github.com/thoughtgears/shared-services/internal/audit"
+	"github.com/thoughtgears/shared-services/internal/db"
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/tenant"
+)
+
+// MaxRedactionRegions is the maximum number of regions redacted in one request.
+const MaxRedactionRegions = 50
+
+// RedactDocumentInput lists the regions of a document image to black out.
+// Regions use the coordinates of comment regions, fractions of the image width and height
+// from the top left corner, so they do not depend on the resolution the client rendered.
+type RedactDocumentInput struct {
+	Regions []models.CommentRegion `json:"regions" binding:"required"`
+}
+
+// Redact blacks out regions of a document image, e.g. an ID number the user must hide before
+// sharing the document. The redacted image becomes the content of the document, the original
+// stays in storage and is recorded in the document's versions for compliance. Only the
+// content changes, so the review status of the document is kept.
+func (d *documentService) Redact(ctx context.Context, id string, input RedactDocumentInput) (*models.Document, error) {
+	current, err := d.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document by ID: %w", err)
+	}
+
+	if err := checkLegalHold(current); err != nil {
+		return nil, err
+	}
+
+	verr := &models.ValidationError{}
+	if len(input.Regions) == 0 || len(input.Regions) > MaxRedactionRegions {
+		verr.Add("regions", fmt.Sprintf("must contain between 1 and %d regions", MaxRedactionRegions))
+	}
+	for i, region := range input.Regions {
+		if !region.WithinPage() {
+			verr.Add(fmt.Sprintf("regions[%d]", i), "must lie within the image, as fractions of its width and height")
+		}
+	}
+	if current.ContentType != "image/png" && current.ContentType != "image/jpeg" {
+		verr.Add("content_type", "only PNG and JPEG images can be redacted")
+	}
+	if err := verr.Err(); err != nil {
+		return nil, fmt.Errorf("invalid redaction: %w", err)
+	}
+
+	storage, _, err := d.storage.For(current.Region)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := storage.Download(ctx, current.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download document: %w", err)
+	}
+	content, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document: %w", err)
+	}
+
+	redacted, err := redactImage(content, current.ContentType, input.Regions)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.hooks.beforeUpdate(ctx, current, redacted); err != nil {
+		return nil, err
+	}
+
+	documentName := uuid.NewString()
+	ext := GetStandardizedExtension(current.Path)
+	path := fmt.Sprintf("%sdocuments/%s/%s.%s", tenant.PathPrefix(ctx), id, documentName, ext)
+
+	fileInfo, err := storage.Upload(ctx, path, bytes.NewReader(redacted), current.ContentType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload redacted document: %w", err)
+	}
+
+	updatedDocument, err := d.db.Update(ctx, id, map[string]interface{}{
+		"name": documentName,
+		"size": fileInfo.Size,
+		"path": path,
+		"versions": db.ArrayUnion(models.DocumentVersion{
+			Name:        current.Name,
+			Path:        current.Path,
+			Size:        current.Size,
+			ContentType: current.ContentType,
+			Reason:      models.DocumentVersionRedaction,
+			ReplacedAt:  time.Now().UTC(),
+		}),
+		"updated_at": firestore.ServerTimestamp,
+		// The redacted content is stored in the default class
+		"storage_class": firestore.Delete,
+	})
+	d.audit.Record(ctx, audit.ActionDocumentRedact, documentTarget(id), err, map[string]string{
+		"regions": fmt.Sprint(len(input.Regions)),
+	})
+	if err != nil {
+		recordFailure(ctx, "document.redact")
+		return nil, fmt.Errorf("failed to update document: %w", err)
+	}
+	recordUpload(ctx, string(current.Type), fileInfo.Size)
+	// Cached conversions show the unredacted content
+	deleteDerived(ctx, storage, id)
+	d.hooks.afterUpdate(ctx, updatedDocument)
+
+	return updatedDocument, nil
+}
+
+// redactImage decodes a PNG or JPEG image, fills the regions with black and encodes it again
+// in the same format. Re-encoding also drops any metadata embedded in the original file.
+func redactImage(content []byte, contentType string, regions []models.CommentRegion) ([]byte, error) {
+	src, err := decodeImage(content)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := src.Bounds()
+	img := image.NewRGBA(bounds)
+	draw.Draw(img, bounds, src, bounds.Min, draw.Src)
+
+	width, height := float64(bounds.Dx()), float64(bounds.Dy())
+	for _, region := range regions {
+		// Regions are widened to whole pixels so partially covered pixels are redacted too
+		rect := image.Rect(
+			bounds.Min.X+int(math.Floor(region.X*width)),
+			bounds.Min.Y+int(math.Floor(region.Y*height)),
+			bounds.Min.X+int(math.Ceil((region.X+region.Width)*width)),
+			bounds.Min.Y+int(math.Ceil((region.Y+region.Height)*height)),
+		)
+		draw.Draw(img, rect.Intersect(bounds), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	}
+
+	return encodeImage(img, contentType)
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"cloud.google.com/go/firestore"
+
+	"github.com/thoughtgears/shared-services/internal/audit"
+	"github.com/thoughtgears/shared-services/internal/db"
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/pkg/apperr"
+)
+
+// ErrReviewConflict is returned when a review transition does not apply to the document's current
+// state, e.g. the document was already claimed by another reviewer or has already been decided.
+var ErrReviewConflict = apperr.New(apperr.Conflict, "document review conflict")
+
+// ReviewDocumentInput is a reviewer's decision on a claimed document or bundle.
+type ReviewDocumentInput struct {
+	// Status is the decision, either verified or rejected.
+	Status models.DocumentStatus `json:"status" binding:"required"`
+	// Note explains the decision, it is required when a document is rejected.
+	Note string `json:"note"`
+}
+
+// MaxReviewNoteLength is the maximum length of a review note.
+const MaxReviewNoteLength = 1000
+
+// ReviewQueue lists the documents with the given status, pending or in_review, oldest first,
+// so reviewers work the backlog in the order it was uploaded. The page holds the first
+// documents of the queue and its total count, the queue is not paged further.
+func (d *documentService) ReviewQueue(ctx context.Context, status models.DocumentStatus, opts ListOptions) (*models.Page[*models.Document], error) {
+	status, err := queueStatus(status)
+	if err != nil {
+		return nil, err
+	}
+
+	// Queries are ordered by document ID, so the queue is sorted by upload time here
+	documents, _, err := d.db.Find(ctx, db.Query[models.Document]().Where("status", db.Eq, status))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review queue: %w", err)
+	}
+
+	slices.SortFunc(documents, func(a, b *models.Document) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+
+	total := int64(len(documents))
+	pageSize := opts.pageSize()
+	page := models.NewPage(documents[:min(len(documents), pageSize)], "", pageSize)
+	page.TotalCount = &total
+
+	return page, nil
+}
+
+// Claim assigns a pending document to the calling reviewer. Claiming a document the caller already
+// claimed succeeds, a document claimed by another reviewer or already decided is a conflict.
+func (d *documentService) Claim(ctx context.Context, id string) (*models.Document, error) {
+	reviewerID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reviewers work documents of every owner, so ownership is not checked
+	document, err := d.db.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document by ID: %w", err)
+	}
+
+	if document.BundleID != "" {
+		return nil, fmt.Errorf("%w: document is reviewed with bundle %s", ErrReviewConflict, document.BundleID)
+	}
+
+	claimed, err := checkClaim("document", document.Status, document.ReviewerID, reviewerID)
+	if err != nil {
+		return nil, err
+	}
+	if claimed {
+		return document, nil
+	}
+
+	updatedDocument, err := d.db.Update(ctx, id, claimUpdate(reviewerID))
+	d.audit.Record(ctx, audit.ActionDocumentClaim, documentTarget(id), err, map[string]string{
+		"from_status": string(document.Status),
+		"to_status":   string(models.DocumentStatusInReview),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim document: %w", err)
+	}
+
+	return updatedDocument, nil
+}
+
+// Review records the calling reviewer's decision on a document they claimed.
+func (d *documentService) Review(ctx context.Context, id string, input ReviewDocumentInput) (*models.Document, error) {
+	reviewerID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := input.validate(); err != nil {
+		return nil, err
+	}
+
+	document, err := d.db.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document by ID: %w", err)
+	}
+
+	if document.Status != models.DocumentStatusInReview || document.ReviewerID != reviewerID {
+		return nil, fmt.Errorf("%w: document must be claimed by the reviewer first", ErrReviewConflict)
+	}
+
+	updatedDocument, err := d.db.Update(ctx, id, input.update())
+	d.audit.Record(ctx, audit.ActionDocumentReview, documentTarget(id), err, map[string]string{
+		"from_status": string(document.Status),
+		"to_status":   string(input.Status),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record review: %w", err)
+	}
+	d.notifyReview(ctx, "document", document.UserID, id, input)
+
+	return updatedDocument, nil
+}
+
+// notifyReview tells the owner of a document or bundle of the given kind about the review decision,
+// in their language with the reviewed template.
+func (d *documentService) notifyReview(ctx context.Context, kind string, userID string, id string, input ReviewDocumentInput) {
+	if d.notifications == nil || userID == "" {
+		return
+	}
+
+	body := fmt.Sprintf("Your %s has been %s.", kind, input.Status)
+	if input.Note != "" {
+		body += "\n\nReviewer note: " + input.Note
+	}
+
+	d.notifications.Notify(ctx, userID, Notification{
+		Event:   kind + ".reviewed",
+		Subject: fmt.Sprintf("Your %s has been %s", kind, input.Status),
+		Body:    body,
+		Data: map[string]string{
+			kind + "_id": id,
+			"status":     string(input.Status),
+		},
+		Template: "reviewed",
+		TemplateData: map[string]string{
+			"kind":   kind,
+			"status": string(input.Status),
+			"note":   input.Note,
+		},
+	})
+}
+
+// queueStatus validates the status of a review queue, an empty status is the pending queue.
+func queueStatus(status models.DocumentStatus) (models.DocumentStatus, error) {
+	if status == "" {
+		return models.DocumentStatusPending, nil
+	}
+	if status != models.DocumentStatusPending && status != models.DocumentStatusInReview {
+		verr := &models.ValidationError{}
+		verr.Add("status", "must be pending or in_review")
+
+		return "", fmt.Errorf("invalid review queue: %w", verr)
+	}
+
+	return status, nil
+}
+
+// checkClaim checks that a document or bundle of the given kind in status, claimed by currentReviewer
+// if any, can be claimed by reviewerID. It reports whether reviewerID already holds the claim,
+// in which case nothing has to be written.
+func checkClaim(kind string, status models.DocumentStatus, currentReviewer string, reviewerID string) (bool, error) {
+	switch {
+	case status == models.DocumentStatusInReview && currentReviewer == reviewerID:
+		return true, nil
+	case status == models.DocumentStatusInReview:
+		return false, fmt.Errorf("%w: %s is claimed by another reviewer", ErrReviewConflict, kind)
+	case status != models.DocumentStatusPending:
+		return false, fmt.Errorf("%w: %s is %s", ErrReviewConflict, kind, status)
+	}
+
+	return false, nil
+}
+
+// claimUpdate returns the update that assigns a pending document or bundle to reviewerID.
+func claimUpdate(reviewerID string) map[string]interface{} {
+	return map[string]interface{}{
+		"status":      models.DocumentStatusInReview,
+		"reviewer_id": reviewerID,
+		"claimed_at":  time.Now().UTC(),
+		"updated_at":  firestore.ServerTimestamp,
+	}
+}
+
+// pendingReview returns the update that puts a document or bundle back into the review queue,
+// clearing any earlier claim and decision.
+func pendingReview() map[string]interface{} {
+	return map[string]interface{}{
+		"status":      models.DocumentStatusPending,
+		"reviewer_id": firestore.Delete,
+		"claimed_at":  firestore.Delete,
+		"reviewed_at": firestore.Delete,
+		"review_note": firestore.Delete,
+	}
+}
+
+// validate checks the decision is verified or rejected and that its note is valid.
+func (input ReviewDocumentInput) validate() error {
+	verr := &models.ValidationError{}
+	if input.Status != models.DocumentStatusVerified && input.Status != models.DocumentStatusRejected {
+		verr.Add("status", "must be verified or rejected")
+	}
+	if input.Status == models.DocumentStatusRejected && input.Note == "" {
+		verr.Add("note", "is required when a document is rejected")
+	}
+	if len(input.Note) > MaxReviewNoteLength {
+		verr.Add("note", fmt.Sprintf("must be at most %d characters", MaxReviewNoteLength))
+	}
+	if err := verr.Err(); err != nil {
+		return fmt.Errorf("invalid review: %w", err)
+	}
+
+	return nil
+}
+
+// update returns the update that records the decision.
+func (input ReviewDocumentInput) update() map[string]interface{} {
+	update := map[string]interface{}{
+		"status":      input.Status,
+		"reviewed_at": time.Now().UTC(),
+		"updated_at":  firestore.ServerTimestamp,
+	}
+	if input.Note != "" {
+		update["review_note"] = input.Note
+	}
+
+	return update
+}
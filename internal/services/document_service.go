@@ -3,14 +3,22 @@ package services
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
 
+	"github.com/thoughtgears/shared-services/internal/audit"
 	"github.com/thoughtgears/shared-services/internal/db"
 	"github.com/thoughtgears/shared-services/internal/gcs"
 	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/tenant"
 )
 
 // DocumentService handles operations specific to documents.
@@ -20,10 +28,45 @@ import (
 // The methods include creating, updating, deleting, and retrieving documents.
 type DocumentService interface {
 	GetByID(ctx context.Context, id string) (*models.Document, error)
-	GetAllByUserID(ctx context.Context, userID string) ([]*models.Document, error)
-	Create(ctx context.Context, userID string, documentType models.DocumentType, content []byte) (*models.Document, error)
-	Update(ctx context.Context, id string, content []byte) (*models.Document, error)
+	GetAllByUserID(ctx context.Context, userID string, metadata map[string]string, opts ListOptions) (*models.Page[*models.Document], error)
+	GetAllByOrganizationID(ctx context.Context, organizationID string, metadata map[string]string, opts ListOptions) (*models.Page[*models.Document], error)
+	Create(ctx context.Context, input CreateDocumentInput) (*models.Document, error)
+	Update(ctx context.Context, id string, content []byte, originalFilename string) (*models.Document, error)
+	UpdateMetadata(ctx context.Context, id string, metadata map[string]string) (*models.Document, error)
+	Rename(ctx context.Context, id string, displayName string) (*models.Document, error)
+	Redact(ctx context.Context, id string, input RedactDocumentInput) (*models.Document, error)
+	Merge(ctx context.Context, input MergeDocumentsInput) (*models.Document, error)
+	MoveRegion(ctx context.Context, id string, region string) (*models.Document, error)
+	PlaceLegalHold(ctx context.Context, id string, input PlaceLegalHoldInput) (*models.Document, error)
+	ReleaseLegalHold(ctx context.Context, id string) (*models.Document, error)
+	ReviewQueue(ctx context.Context, status models.DocumentStatus, opts ListOptions) (*models.Page[*models.Document], error)
+	Claim(ctx context.Context, id string) (*models.Document, error)
+	Review(ctx context.Context, id string, input ReviewDocumentInput) (*models.Document, error)
 	Delete(ctx context.Context, id string) error
+	CreateBundle(ctx context.Context, input CreateBundleInput) (*models.Bundle, error)
+	GetBundle(ctx context.Context, id string) (*models.Bundle, error)
+	DeleteBundle(ctx context.Context, id string) error
+	BundleReviewQueue(ctx context.Context, status models.DocumentStatus, opts ListOptions) (*models.Page[*models.Bundle], error)
+	ClaimBundle(ctx context.Context, id string) (*models.Bundle, error)
+	ReviewBundle(ctx context.Context, id string, input ReviewDocumentInput) (*models.Bundle, error)
+	GetDownloadURL(ctx context.Context, id string) (string, time.Time, error)
+	GetContent(ctx context.Context, id string, opts ContentOptions) (*Content, error)
+	ListTypes(ctx context.Context) (*models.Page[*models.DocumentTypeDefinition], error)
+	// Use adds plugins hooked into creating, updating and deleting documents, see DocumentPlugin.
+	Use(plugins ...DocumentPlugin)
+}
+
+// CreateDocumentInput holds the caller supplied values used to create a new document.
+type CreateDocumentInput struct {
+	UserID string
+	// OrganizationID makes the organization the owner of the document, the caller must be a member.
+	OrganizationID string
+	Type           models.DocumentType
+	ExpiryDate     *time.Time
+	Metadata       map[string]string
+	Content        []byte
+	// OriginalFilename is the filename the client uploaded, it is sanitized before it is stored.
+	OriginalFilename string
 }
 
 // documentService is the concrete implementation of DocumentService.
@@ -31,96 +74,344 @@ type DocumentService interface {
 // The storage service is expected to be a GCS or S3 storage service.
 // The db is expected to be a Firestore db.
 type documentService struct {
-	storage gcs.Storage
-	db      db.DB[models.Document]
+	storage            *RegionalStorage
+	residency          ResidencyResolver
+	organizations      OrganizationService
+	settings           TenantSettingsService
+	db                 db.DB[models.Document]
+	bundles            db.DB[models.Bundle]
+	types              DocumentTypeService
+	metadataFilterKeys []string
+	signedURLTTL       time.Duration
+	comments           CommentStore
+	notifications      NotificationService
+	tiering            TieringService
+	audit              *audit.Recorder
+	hooks              documentHooks
 }
 
 // NewDocumentService creates a new instance of documentService.
-// It initializes the service with a gcs service and a db for document data.
-func NewDocumentService(storage gcs.Storage, db db.DB[models.Document]) DocumentService {
+// It initializes the service with the regional storage, a db for document data, a db for the
+// bundles grouping multi-part documents and the document type service used to validate uploads.
+// residency decides the region new documents are stored in, nil stores everything in the default region.
+// organizations authorizes access to documents owned by an organization,
+// and settings applies the tenant's allowed types, quotas and retention to uploads.
+// metadataFilterKeys is the allow-list of metadata keys documents can be filtered on,
+// signedURLTTL is how long download URLs stay valid, comments are deleted along with their document,
+// notifications tells owners about review decisions, nil disables them,
+// tiering restores cold documents when they are requested, nil leaves them in their storage class,
+// and recorder audits every mutating operation.
+func NewDocumentService(
+	storage *RegionalStorage,
+	residency ResidencyResolver,
+	organizations OrganizationService,
+	settings TenantSettingsService,
+	db db.DB[models.Document],
+	bundles db.DB[models.Bundle],
+	types DocumentTypeService,
+	metadataFilterKeys []string,
+	signedURLTTL time.Duration,
+	comments CommentStore,
+	notifications NotificationService,
+	tiering TieringService,
+	recorder *audit.Recorder,
+) DocumentService {
 	return &documentService{
-		storage: storage,
-		db:      db,
+		storage:            storage,
+		residency:          residency,
+		organizations:      organizations,
+		settings:           settings,
+		db:                 db,
+		bundles:            bundles,
+		types:              types,
+		metadataFilterKeys: metadataFilterKeys,
+		signedURLTTL:       signedURLTTL,
+		comments:           comments,
+		notifications:      notifications,
+		tiering:            tiering,
+		audit:              recorder,
 	}
 }
 
+// Use adds plugins hooked into creating, updating and deleting documents.
+// It must be called on startup, before the service handles requests.
+func (d *documentService) Use(plugins ...DocumentPlugin) {
+	d.hooks.use(plugins...)
+}
+
 // GetByID retrieves a document by its unique ID.
 // It returns the document object if found, or an error if not.
-// This method is used to fetch document details.
+// Documents owned by an organization are only returned to its members.
 func (d *documentService) GetByID(ctx context.Context, id string) (*models.Document, error) {
+	return d.getAuthorized(ctx, id, models.RoleMember)
+}
+
+// getAuthorized retrieves a document and checks the caller has at least minRole in the
+// organization that owns it. Documents owned by an individual are only returned to their owner.
+func (d *documentService) getAuthorized(ctx context.Context, id string, minRole models.Role) (*models.Document, error) {
 	document, err := d.db.GetByID(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get document by ID: %w", err)
 	}
 
+	if err := authorizeOwner(ctx, d.organizations, "document", document.UserID, document.OrganizationID, minRole); err != nil {
+		return nil, err
+	}
+
 	return document, nil
 }
 
-// GetAllByUserID retrieves all documents associated with a specific user ID.
-// It returns a slice of document objects and an error if any occurs.
-func (d *documentService) GetAllByUserID(ctx context.Context, userID string) ([]*models.Document, error) {
-	query := []db.QueryConstraint{
-		{
-			Path:  "user_id",
-			Op:    db.QueryOperatorEqual,
-			Value: userID,
-		},
+// GetAllByUserID retrieves a page of documents associated with a specific user ID.
+// The documents can optionally be filtered on metadata values, limited to the allow-listed keys.
+// Only the caller's own documents can be listed, admins list those of any user.
+// It returns the page of document objects and an error if any occurs.
+func (d *documentService) GetAllByUserID(ctx context.Context, userID string, metadata map[string]string, opts ListOptions) (*models.Page[*models.Document], error) {
+	if err := authorizeUser(ctx, "documents", userID); err != nil {
+		return nil, err
 	}
 
-	documents, _, err := d.db.GetByQuery(ctx, query, "", 100)
+	return d.list(ctx, "user_id", userID, metadata, opts)
+}
+
+// GetAllByOrganizationID retrieves a page of documents owned by an organization the caller is a member of.
+// The documents can be filtered on metadata values like GetAllByUserID.
+func (d *documentService) GetAllByOrganizationID(ctx context.Context, organizationID string, metadata map[string]string, opts ListOptions) (*models.Page[*models.Document], error) {
+	if _, err := d.organizations.Authorize(ctx, organizationID, models.RoleMember); err != nil {
+		return nil, err
+	}
+
+	return d.list(ctx, "organization_id", organizationID, metadata, opts)
+}
+
+// list retrieves a page of documents whose owner field matches ownerID, filtered on metadata.
+func (d *documentService) list(ctx context.Context, ownerField string, ownerID string, metadata map[string]string, opts ListOptions) (*models.Page[*models.Document], error) {
+	query := db.Query[models.Document]().Where(ownerField, db.Eq, ownerID)
+
+	verr := &models.ValidationError{}
+	for key, value := range metadata {
+		if !slices.Contains(d.metadataFilterKeys, key) {
+			verr.Add("metadata."+key, "is not a filterable metadata key")
+
+			continue
+		}
+
+		query.Where("metadata."+key, db.Eq, value)
+	}
+	if err := verr.Err(); err != nil {
+		return nil, fmt.Errorf("invalid document filter: %w", err)
+	}
+
+	pageSize := opts.pageSize()
+	result, err := d.db.FindPage(ctx, query.After(opts.PageToken).Limit(pageSize).IncludeTotal(opts.IncludeTotal))
 	if err != nil {
-		return nil, fmt.Errorf("failed to get documents by user ID: %w", err)
+		return nil, fmt.Errorf("failed to get documents by %s: %w", ownerField, err)
 	}
 
-	return documents, nil
+	return newPage(result, pageSize), nil
 }
 
 // Create handles the creation of a new document.
 // It returns the created document object and an error if any occurs.
-// It uploads the document to the gcs service and saves the metadata in the database.
-func (d *documentService) Create(ctx context.Context, userID string, documentType models.DocumentType, content []byte) (*models.Document, error) {
-	data := bytes.NewReader(content)
-	documentID := uuid.NewString()
-	documentName := uuid.NewString()
-
-	fileExtension, err := DetectFileType(content)
+// The document is validated before anything is written, then it uploads the
+// document to the gcs service and saves the metadata in the database.
+func (d *documentService) Create(ctx context.Context, input CreateDocumentInput) (*models.Document, error) {
+	fileExtension, err := DetectFileType(input.Content)
 	if err != nil {
 		return nil, fmt.Errorf("failed to detect file type: %w", err)
 	}
 
-	ext := GetStandardizedExtension(fileExtension.Extension)
-	path := fmt.Sprintf("documents/%s/%s.%s", userID, documentName, ext)
+	definition, settings, err := d.authorizeUpload(ctx, input, 1)
+	if err != nil {
+		return nil, err
+	}
 
-	fileInfo, err := d.storage.Upload(ctx, path, data, fileExtension.MimeType)
+	upload, err := newDocumentUpload(input, fileExtension, definition)
 	if err != nil {
-		return nil, fmt.Errorf("failed to upload document: %w", err)
+		return nil, err
+	}
+
+	if err := d.hooks.beforeCreate(ctx, upload.candidate, input.Content); err != nil {
+		return nil, err
+	}
+
+	storage, region, err := d.storageForUser(ctx, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	document, fileInfo, err := d.store(ctx, storage, region, upload, definition, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	createdDocument, err := d.db.Create(ctx, upload.id, document)
+	d.audit.Record(ctx, audit.ActionDocumentCreate, documentTarget(upload.id), err, map[string]string{
+		"user_id": input.UserID,
+		"type":    string(definition.ID),
+	})
+	if err != nil {
+		recordFailure(ctx, "document.create")
+		return nil, fmt.Errorf("failed to create document: %w", err)
+	}
+	recordUpload(ctx, string(definition.ID), fileInfo.Size)
+	d.hooks.afterCreate(ctx, createdDocument)
+
+	return createdDocument, nil
+}
+
+// documentUpload is the content of a new document that passed validation and can be stored.
+type documentUpload struct {
+	id               string
+	name             string
+	input            CreateDocumentInput
+	fileType         *FileTypeInfo
+	originalFilename string
+	// candidate is the document as it was validated, before it is stored
+	candidate *models.Document
+}
+
+// newDocumentUpload validates the document that input creates as a document of definition.
+func newDocumentUpload(input CreateDocumentInput, fileType *FileTypeInfo, definition *models.DocumentTypeDefinition) (*documentUpload, error) {
+	upload := &documentUpload{
+		id:               uuid.NewString(),
+		name:             uuid.NewString(),
+		input:            input,
+		fileType:         fileType,
+		originalFilename: models.SanitizeFilename(input.OriginalFilename),
+	}
+
+	candidate := &models.Document{
+		ID:               upload.id,
+		UserID:           input.UserID,
+		OrganizationID:   input.OrganizationID,
+		Name:             upload.name,
+		OriginalFilename: upload.originalFilename,
+		Size:             int64(len(input.Content)),
+		Type:             definition.ID,
+		ContentType:      fileType.MimeType,
+		ExpiryDate:       input.ExpiryDate,
+		Metadata:         input.Metadata,
+	}
+	if err := candidate.ValidateAs(definition); err != nil {
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+	upload.candidate = candidate
+
+	return upload, nil
+}
+
+// authorizeUpload checks the caller can add count documents of the input's type and owner,
+// and returns the type definition and the tenant settings that apply to them.
+func (d *documentService) authorizeUpload(ctx context.Context, input CreateDocumentInput, count int) (*models.DocumentTypeDefinition, *models.TenantSettings, error) {
+	// The user is the uploader, also of organization documents, so it has to be the caller
+	if err := authorizeUser(ctx, "upload", input.UserID); err != nil {
+		return nil, nil, err
+	}
+
+	if input.OrganizationID != "" {
+		if _, err := d.organizations.Authorize(ctx, input.OrganizationID, models.RoleMember); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	definition, err := d.resolveType(ctx, string(input.Type))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	settings, err := d.settings.Get(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := d.checkTenantLimits(ctx, settings, definition, input.UserID, count); err != nil {
+		return nil, nil, err
+	}
+
+	return definition, settings, nil
+}
+
+// storageForUser returns the storage of the residency region of the user, and the region.
+func (d *documentService) storageForUser(ctx context.Context, userID string) (gcs.Storage, string, error) {
+	region := ""
+	if d.residency != nil {
+		var err error
+		region, err = d.residency.RegionForUser(ctx, userID)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to resolve storage region: %w", err)
+		}
+	}
+
+	return d.storage.For(region)
+}
+
+// store uploads the content of a new document and returns the document record to create for it.
+func (d *documentService) store(
+	ctx context.Context,
+	storage gcs.Storage,
+	region string,
+	upload *documentUpload,
+	definition *models.DocumentTypeDefinition,
+	settings *models.TenantSettings,
+) (map[string]interface{}, *gcs.FileInfo, error) {
+	input := upload.input
+	ext := GetStandardizedExtension(upload.fileType.Extension)
+	path := fmt.Sprintf("%sdocuments/%s/%s.%s", tenant.PathPrefix(ctx), input.UserID, upload.name, ext)
+
+	fileInfo, err := storage.Upload(ctx, path, bytes.NewReader(input.Content), upload.fileType.MimeType)
+	if err != nil {
+		recordFailure(ctx, "document.upload")
+		return nil, nil, fmt.Errorf("failed to upload document: %w", err)
 	}
 
 	document := map[string]interface{}{
-		"id":           documentID,
-		"user_id":      userID,
-		"name":         documentName,
+		"id":           upload.id,
+		"user_id":      input.UserID,
+		"name":         upload.name,
 		"size":         fileInfo.Size,
-		"type":         documentType,
-		"content_type": fileExtension.MimeType,
+		"type":         definition.ID,
+		"content_type": upload.fileType.MimeType,
 		"path":         path,
 		"bucket":       fileInfo.Bucket,
+		"region":       region,
+		"status":       models.DocumentStatusPending,
 		"created_at":   firestore.ServerTimestamp,
 		"updated_at":   firestore.ServerTimestamp,
 	}
 
-	createdDocument, err := d.db.Create(ctx, documentID, document)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create document: %w", err)
+	if input.OrganizationID != "" {
+		document["organization_id"] = input.OrganizationID
 	}
 
-	return createdDocument, nil
+	if upload.originalFilename != "" {
+		document["original_filename"] = upload.originalFilename
+	}
+
+	if input.ExpiryDate != nil {
+		document["expiry_date"] = *input.ExpiryDate
+	}
+
+	if len(input.Metadata) > 0 {
+		document["metadata"] = input.Metadata
+	}
+
+	retention := *definition
+	if settings.RetentionDays > 0 {
+		retention.RetentionDays = settings.RetentionDays
+	}
+
+	if retainUntil := retention.RetainUntil(time.Now()); retainUntil != nil {
+		document["retain_until"] = *retainUntil
+	}
+
+	return document, fileInfo, nil
 }
 
 // Update handles the update of an existing document.
 // It returns the updated document object and an error if any occurs.
 // It uploads the updated document to the gcs service and updates the metadata in the database.
-func (d *documentService) Update(ctx context.Context, id string, content []byte) (*models.Document, error) {
+// The original filename is replaced by the one of the new content, or removed if it has none.
+func (d *documentService) Update(ctx context.Context, id string, content []byte, originalFilename string) (*models.Document, error) {
 	data := bytes.NewReader(content)
 	documentName := uuid.NewString()
 
@@ -129,10 +420,45 @@ func (d *documentService) Update(ctx context.Context, id string, content []byte)
 		return nil, fmt.Errorf("failed to detect file type: %w", err)
 	}
 
+	current, err := d.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document by ID: %w", err)
+	}
+
+	if err := checkLegalHold(current); err != nil {
+		return nil, err
+	}
+
+	definition, err := d.resolveType(ctx, string(current.Type))
+	if err != nil {
+		return nil, err
+	}
+
+	originalFilename = models.SanitizeFilename(originalFilename)
+
+	candidate := *current
+	candidate.Name = documentName
+	candidate.OriginalFilename = originalFilename
+	candidate.Size = int64(len(content))
+	candidate.ContentType = fileExtension.MimeType
+	if err := candidate.ValidateAs(definition); err != nil {
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+
+	if err := d.hooks.beforeUpdate(ctx, current, content); err != nil {
+		return nil, err
+	}
+
+	// New content stays in the document's region, moving it is an explicit admin operation
+	storage, _, err := d.storage.For(current.Region)
+	if err != nil {
+		return nil, err
+	}
+
 	ext := GetStandardizedExtension(fileExtension.Extension)
-	path := fmt.Sprintf("documents/%s/%s.%s", id, documentName, ext)
+	path := fmt.Sprintf("%sdocuments/%s/%s.%s", tenant.PathPrefix(ctx), id, documentName, ext)
 
-	fileInfo, err := d.storage.Upload(ctx, path, data, fileExtension.MimeType)
+	fileInfo, err := storage.Upload(ctx, path, data, fileExtension.MimeType)
 	if err != nil {
 		return nil, fmt.Errorf("failed to upload document: %w", err)
 	}
@@ -143,12 +469,136 @@ func (d *documentService) Update(ctx context.Context, id string, content []byte)
 		"content_type": fileExtension.MimeType,
 		"path":         path,
 		"updated_at":   firestore.ServerTimestamp,
+		// The filename belongs to the content it was uploaded with
+		"original_filename": firestore.Delete,
+		// New content is stored in the default class
+		"storage_class": firestore.Delete,
+	}
+
+	if originalFilename != "" {
+		document["original_filename"] = originalFilename
+	}
+
+	// New content has to be reviewed again. A bundle is reviewed as a unit, so the whole bundle
+	// goes back into the queue, before the content changes so it is never part of a decided bundle.
+	if current.BundleID == "" {
+		maps.Copy(document, pendingReview())
+	} else if err := d.resetBundleReview(ctx, current.BundleID); err != nil {
+		return nil, err
 	}
 
 	updatedDocument, err := d.db.Update(ctx, id, document)
+	d.audit.Record(ctx, audit.ActionDocumentUpdate, documentTarget(id), err, map[string]string{
+		"fields": "content",
+	})
 	if err != nil {
+		recordFailure(ctx, "document.update")
 		return nil, fmt.Errorf("failed to update document: %w", err)
 	}
+	recordUpload(ctx, string(current.Type), fileInfo.Size)
+	deleteDerived(ctx, storage, id)
+	d.hooks.afterUpdate(ctx, updatedDocument)
+
+	return updatedDocument, nil
+}
+
+// UpdateMetadata merges the given entries into the document metadata.
+// Entries with an empty value are removed from the metadata, other entries are added or replaced.
+// It returns the updated document object and an error if any occurs.
+func (d *documentService) UpdateMetadata(ctx context.Context, id string, metadata map[string]string) (*models.Document, error) {
+	current, err := d.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document by ID: %w", err)
+	}
+
+	if err := checkLegalHold(current); err != nil {
+		return nil, err
+	}
+
+	definition, err := d.resolveType(ctx, string(current.Type))
+	if err != nil {
+		return nil, err
+	}
+
+	candidate := *current
+	candidate.Metadata = make(map[string]string, len(current.Metadata)+len(metadata))
+	maps.Copy(candidate.Metadata, current.Metadata)
+
+	changes := make(map[string]interface{}, len(metadata))
+	for key, value := range metadata {
+		if value == "" {
+			delete(candidate.Metadata, key)
+			changes[key] = firestore.Delete
+
+			continue
+		}
+
+		candidate.Metadata[key] = value
+		changes[key] = value
+	}
+
+	if err := candidate.ValidateAs(definition); err != nil {
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+
+	if len(changes) == 0 {
+		return current, nil
+	}
+
+	updatedDocument, err := d.db.Update(ctx, id, map[string]interface{}{
+		"metadata":   changes,
+		"updated_at": firestore.ServerTimestamp,
+	})
+	d.audit.Record(ctx, audit.ActionDocumentUpdate, documentTarget(id), err, map[string]string{
+		"fields": "metadata." + strings.Join(slices.Sorted(maps.Keys(changes)), ",metadata."),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update document metadata: %w", err)
+	}
+
+	return updatedDocument, nil
+}
+
+// Rename sets the display name of a document, an empty name removes it.
+// Only the document record changes, the stored object and its name are left untouched.
+func (d *documentService) Rename(ctx context.Context, id string, displayName string) (*models.Document, error) {
+	current, err := d.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document by ID: %w", err)
+	}
+
+	if err := checkLegalHold(current); err != nil {
+		return nil, err
+	}
+
+	// Only the name is validated, so documents that no longer pass their type rules,
+	// e.g. expired passports, can still be renamed
+	displayName = strings.TrimSpace(displayName)
+	verr := &models.ValidationError{}
+	models.ValidateDisplayName(displayName, verr)
+	if err := verr.Err(); err != nil {
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+
+	if displayName == current.DisplayName {
+		return current, nil
+	}
+
+	var value interface{} = displayName
+	if displayName == "" {
+		value = firestore.Delete
+	}
+
+	updatedDocument, err := d.db.Update(ctx, id, map[string]interface{}{
+		"display_name": value,
+		"updated_at":   firestore.ServerTimestamp,
+	})
+	d.audit.Record(ctx, audit.ActionDocumentUpdate, documentTarget(id), err, map[string]string{
+		"fields": "display_name",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to rename document: %w", err)
+	}
 
 	return updatedDocument, nil
 }
@@ -157,20 +607,235 @@ func (d *documentService) Update(ctx context.Context, id string, content []byte)
 // It removes the document from the gcs service and deletes the metadata from the database.
 // It returns an error if any occurs during the process.
 func (d *documentService) Delete(ctx context.Context, id string) error {
-	document, err := d.GetByID(ctx, id)
+	document, err := d.getAuthorized(ctx, id, models.RoleAdmin)
 	if err != nil {
 		return fmt.Errorf("failed to get document by ID: %w", err)
 	}
 
-	err = d.storage.Delete(ctx, document.Path)
+	if err := checkLegalHold(document); err != nil {
+		return err
+	}
+
+	if document.BundleID != "" {
+		verr := &models.ValidationError{}
+		verr.Add("bundle_id", fmt.Sprintf("document is part of bundle %s, delete the bundle instead", document.BundleID))
+
+		return fmt.Errorf("invalid document: %w", verr)
+	}
+
+	return d.delete(ctx, document)
+}
+
+// delete removes a document's content from storage and then its record and comments.
+func (d *documentService) delete(ctx context.Context, document *models.Document) error {
+	id := document.ID
+	storage, _, err := d.storage.For(document.Region)
 	if err != nil {
-		return fmt.Errorf("failed to delete document from gcs: %w", err)
+		return err
+	}
+
+	if err := d.hooks.beforeDelete(ctx, document); err != nil {
+		return err
+	}
+
+	for _, path := range document.ContentPaths() {
+		if err := storage.Delete(ctx, path); err != nil {
+			d.audit.Record(ctx, audit.ActionDocumentDelete, documentTarget(id), err, nil)
+			return fmt.Errorf("failed to delete document from gcs: %w", err)
+		}
 	}
 
 	err = d.db.Delete(ctx, id)
+	d.audit.Record(ctx, audit.ActionDocumentDelete, documentTarget(id), err, nil)
 	if err != nil {
 		return fmt.Errorf("failed to delete document from database: %w", err)
 	}
+	deleteComments(ctx, d.comments, id)
+	deleteDerived(ctx, storage, id)
+	d.hooks.afterDelete(ctx, document)
 
 	return nil
 }
+
+// MoveRegion moves a document's content to the storage of another residency region.
+// It is an explicit admin operation: documents are never moved between regions as a side effect
+// of other updates. The object is copied before the document is updated, and the source is only
+// removed once the document points at the new copy.
+func (d *documentService) MoveRegion(ctx context.Context, id string, region string) (*models.Document, error) {
+	// Platform admins move documents without being members of the owning organization
+	document, err := d.db.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document by ID: %w", err)
+	}
+
+	// Held objects cannot be deleted from the source region
+	if err := checkLegalHold(document); err != nil {
+		return nil, err
+	}
+
+	source, sourceRegion, err := d.storage.For(document.Region)
+	if err != nil {
+		return nil, err
+	}
+
+	target, _, err := d.storage.For(region)
+	if err != nil {
+		verr := &models.ValidationError{}
+		verr.Add("region", "is not a configured storage region")
+
+		return nil, fmt.Errorf("invalid region: %w", verr)
+	}
+
+	if sourceRegion == region {
+		return document, nil
+	}
+
+	fileInfo, err := copyObject(ctx, source, target, document.Path, document.ContentType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy document to region %s: %w", region, err)
+	}
+
+	// Preserved versions live next to the content and move with it
+	for _, version := range document.Versions {
+		if _, err := copyObject(ctx, source, target, version.Path, version.ContentType); err != nil {
+			return nil, fmt.Errorf("failed to copy document version to region %s: %w", region, err)
+		}
+	}
+
+	updatedDocument, err := d.db.Update(ctx, id, map[string]interface{}{
+		"region":     region,
+		"bucket":     fileInfo.Bucket,
+		"updated_at": firestore.ServerTimestamp,
+		// Copies are stored in the default class
+		"storage_class": firestore.Delete,
+	})
+	d.audit.Record(ctx, audit.ActionDocumentMoveRegion, documentTarget(id), err, map[string]string{
+		"from_region": sourceRegion,
+		"to_region":   region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update document region: %w", err)
+	}
+
+	for _, path := range document.ContentPaths() {
+		if err := source.Delete(ctx, path); err != nil {
+			log.Error().Err(err).Str("document_id", id).Str("region", sourceRegion).Msg("Failed to delete document from source region after move")
+		}
+	}
+	deleteDerived(ctx, source, id)
+
+	return updatedDocument, nil
+}
+
+// copyObject copies the object at path from the source storage to the same path in target.
+func copyObject(ctx context.Context, source gcs.Storage, target gcs.Storage, path string, contentType string) (*gcs.FileInfo, error) {
+	content, err := source.Download(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", path, err)
+	}
+	defer content.Close()
+
+	fileInfo, err := target.Upload(ctx, path, content, contentType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload %s: %w", path, err)
+	}
+
+	return fileInfo, nil
+}
+
+// GetDownloadURL creates a short-lived signed URL the client can use to download the document directly from storage.
+// It returns the URL, the time it expires and an error if any occurs.
+func (d *documentService) GetDownloadURL(ctx context.Context, id string) (string, time.Time, error) {
+	document, err := d.GetByID(ctx, id)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to get document by ID: %w", err)
+	}
+
+	storage, _, err := d.storage.For(document.Region)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	// Cold content is served as it is, it moves back to the default class for the next request
+	d.restoreCold(ctx, document)
+
+	expiresAt := time.Now().Add(d.signedURLTTL)
+	url, err := storage.SignedURL(ctx, document.Path, d.signedURLTTL, document.OriginalFilename)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to create download URL: %w", err)
+	}
+
+	return url, expiresAt, nil
+}
+
+// restoreCold starts restoring a document tiered to a cold storage class, if restoring is enabled.
+func (d *documentService) restoreCold(ctx context.Context, document *models.Document) {
+	if d.tiering == nil || document.StorageClass == "" {
+		return
+	}
+
+	d.tiering.Restore(ctx, document)
+}
+
+// ListTypes returns the document types that can be uploaded.
+// Types are few, so they are always returned as a single complete page.
+func (d *documentService) ListTypes(ctx context.Context) (*models.Page[*models.DocumentTypeDefinition], error) {
+	types, err := d.types.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list document types: %w", err)
+	}
+
+	total := int64(len(types))
+	page := models.NewPage(types, "", len(types))
+	page.TotalCount = &total
+
+	return page, nil
+}
+
+// resolveType looks up the definition for a document type. Unknown types are
+// reported as a validation error on the type field so callers can return a 400.
+func (d *documentService) resolveType(ctx context.Context, name string) (*models.DocumentTypeDefinition, error) {
+	definition, err := d.types.Get(ctx, name)
+	if errors.Is(err, ErrUnknownDocumentType) {
+		verr := &models.ValidationError{}
+		verr.Add("type", fmt.Sprintf("unknown document type: %s", name))
+
+		return nil, fmt.Errorf("invalid document: %w", verr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve document type: %w", err)
+	}
+
+	return definition, nil
+}
+
+// checkTenantLimits checks an upload of count documents against the tenant's allowed document types and document quota.
+func (d *documentService) checkTenantLimits(ctx context.Context, settings *models.TenantSettings, definition *models.DocumentTypeDefinition, userID string, count int) error {
+	verr := &models.ValidationError{}
+
+	if !settings.AllowsDocumentType(definition.ID) {
+		verr.Add("type", "is not allowed for this tenant")
+	}
+
+	if settings.MaxDocumentsPerUser > 0 {
+		used, err := d.db.Count(ctx, db.Query[models.Document]().Where("user_id", db.Eq, userID).Constraints())
+		if err != nil {
+			return fmt.Errorf("failed to count documents for quota: %w", err)
+		}
+
+		if used+int64(count) > int64(settings.MaxDocumentsPerUser) {
+			verr.Add("user_id", fmt.Sprintf("has reached the limit of %d documents", settings.MaxDocumentsPerUser))
+		}
+	}
+
+	if err := verr.Err(); err != nil {
+		return fmt.Errorf("invalid document: %w", err)
+	}
+
+	return nil
+}
+
+// documentTarget returns the audit target for a document.
+func documentTarget(id string) audit.Target {
+	return audit.Target{Type: "document", ID: id}
+}
@@ -2,9 +2,10 @@ package services
 
 import (
 	"bytes"
-	"errors"
 	"path/filepath"
 	"strings"
+
+	"github.com/thoughtgears/shared-services/pkg/apperr"
 )
 
 // FileTypeInfo contains information about detected file types
@@ -15,8 +16,8 @@ type FileTypeInfo struct {
 }
 
 var (
-	ErrInsufficientData = errors.New("insufficient data to determine file type")
-	ErrUnknownFileType  = errors.New("unknown or unsupported file type")
+	ErrInsufficientData = apperr.New(apperr.Invalid, "insufficient data to determine file type")
+	ErrUnknownFileType  = apperr.New(apperr.Invalid, "unknown or unsupported file type")
 )
 
 // DetectFileType determines the file type from a byte array using magic numbers
@@ -54,15 +55,28 @@ func DetectFileType(data []byte) (*FileTypeInfo, error) {
 	case bytes.HasPrefix(data, []byte{0x42, 0x4D}):
 		return &FileTypeInfo{MimeType: "image/bmp", Extension: ".bmp"}, nil
 
+	// HEIC: an ISO base media file, ftyp box (66 74 79 70) at offset 4 with a HEIF image brand
+	case bytes.Equal(data[4:8], []byte("ftyp")) && len(data) >= 12 && heicBrands[string(data[8:12])]:
+		return &FileTypeInfo{MimeType: "image/heic", Extension: ".heic"}, nil
+
 	default:
 		return nil, ErrUnknownFileType
 	}
 }
 
+// heicBrands are the major brands of HEIF files holding HEVC coded images, as written by phone cameras.
+var heicBrands = map[string]bool{
+	"heic": true,
+	"heix": true,
+	"heim": true,
+	"heis": true,
+	"mif1": true,
+}
+
 // GetStandardizedExtension takes a filename and returns a standardized file extension.
 // It converts the extension to lowercase and maps certain extensions to a standard format.
 // For example, it converts ".jpeg", ".jpe", ".jif", and ".jfif" to ".jpg",
-// and ".tif" to ".tiff". If the extension is not recognized, it defaults to ".bin".
+// ".tif" to ".tiff" and ".heif" to ".heic". If the extension is not recognized, it defaults to ".bin".
 // This function is useful for ensuring consistent file naming conventions across different file types.
 func GetStandardizedExtension(filename string) string {
 	ext := strings.ToLower(filepath.Ext(filename))
@@ -72,7 +86,9 @@ func GetStandardizedExtension(filename string) string {
 		return ".jpg"
 	case ".tif":
 		return ".tiff"
-	case ".pdf", ".png", ".jpg", ".tiff", ".bmp":
+	case ".heif":
+		return ".heic"
+	case ".pdf", ".png", ".jpg", ".tiff", ".bmp", ".heic":
 		return ext
 	default:
 		return ".bin" // Default binary extension for unknown types
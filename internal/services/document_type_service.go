@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thoughtgears/shared-services/internal/db"
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/stats"
+	"github.com/thoughtgears/shared-services/pkg/apperr"
+)
+
+// ErrUnknownDocumentType is returned when a document type is not defined.
+var ErrUnknownDocumentType = apperr.New(apperr.Invalid, "unknown document type")
+
+// DocumentTypeService resolves the document types accepted by the system.
+// Implementations load the definitions from configuration or from Firestore,
+// so types, their validation rules and retention can change without a deploy.
+type DocumentTypeService interface {
+	Get(ctx context.Context, name string) (*models.DocumentTypeDefinition, error)
+	List(ctx context.Context) ([]*models.DocumentTypeDefinition, error)
+}
+
+// staticDocumentTypeService serves a fixed set of document type definitions.
+type staticDocumentTypeService struct {
+	types []*models.DocumentTypeDefinition
+}
+
+// NewStaticDocumentTypeService creates a DocumentTypeService backed by the given definitions.
+// It is used for the built-in defaults and for definitions loaded from a config file.
+func NewStaticDocumentTypeService(definitions []models.DocumentTypeDefinition) DocumentTypeService {
+	types := make([]*models.DocumentTypeDefinition, 0, len(definitions))
+	for i := range definitions {
+		types = append(types, &definitions[i])
+	}
+
+	return &staticDocumentTypeService{types: types}
+}
+
+// LoadDocumentTypesFile reads document type definitions from a JSON file
+// containing an array of definitions.
+func LoadDocumentTypesFile(path string) ([]models.DocumentTypeDefinition, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document types file: %w", err)
+	}
+
+	var definitions []models.DocumentTypeDefinition
+	if err := json.Unmarshal(content, &definitions); err != nil {
+		return nil, fmt.Errorf("failed to parse document types file: %w", err)
+	}
+
+	return definitions, nil
+}
+
+// Get returns the definition matching the name, comparing IDs and aliases case-insensitively.
+func (s *staticDocumentTypeService) Get(_ context.Context, name string) (*models.DocumentTypeDefinition, error) {
+	return findDocumentType(s.types, name)
+}
+
+// List returns all known document type definitions.
+func (s *staticDocumentTypeService) List(_ context.Context) ([]*models.DocumentTypeDefinition, error) {
+	return s.types, nil
+}
+
+// firestoreDocumentTypeService loads document type definitions from a Firestore collection
+// and caches them in memory for the configured TTL.
+type firestoreDocumentTypeService struct {
+	db  db.DB[models.DocumentTypeDefinition]
+	ttl time.Duration
+
+	mu       sync.RWMutex
+	types    []*models.DocumentTypeDefinition
+	loadedAt time.Time
+}
+
+// NewFirestoreDocumentTypeService creates a DocumentTypeService backed by the document_types collection.
+// Definitions are reloaded at most once per ttl.
+func NewFirestoreDocumentTypeService(db db.DB[models.DocumentTypeDefinition], ttl time.Duration) DocumentTypeService {
+	return &firestoreDocumentTypeService{
+		db:  db,
+		ttl: ttl,
+	}
+}
+
+// Get returns the definition matching the name, comparing IDs and aliases case-insensitively.
+func (s *firestoreDocumentTypeService) Get(ctx context.Context, name string) (*models.DocumentTypeDefinition, error) {
+	types, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return findDocumentType(types, name)
+}
+
+// List returns all document type definitions, reloading them from Firestore when the cache has expired.
+func (s *firestoreDocumentTypeService) List(ctx context.Context) ([]*models.DocumentTypeDefinition, error) {
+	s.mu.RLock()
+	if s.types != nil && time.Since(s.loadedAt) < s.ttl {
+		types := s.types
+		s.mu.RUnlock()
+		stats.CacheHit("document_types")
+
+		return types, nil
+	}
+	s.mu.RUnlock()
+	stats.CacheMiss("document_types")
+
+	types, _, err := s.db.GetAll(ctx, "", 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load document types: %w", err)
+	}
+
+	s.mu.Lock()
+	s.types = types
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+
+	return types, nil
+}
+
+// findDocumentType looks up a definition by ID or alias, ignoring case.
+func findDocumentType(types []*models.DocumentTypeDefinition, name string) (*models.DocumentTypeDefinition, error) {
+	for _, t := range types {
+		if strings.EqualFold(string(t.ID), name) {
+			return t, nil
+		}
+
+		for _, alias := range t.Aliases {
+			if strings.EqualFold(alias, name) {
+				return t, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnknownDocumentType, name)
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"firebase.google.com/go/v4/auth"
+
+	"github.com/thoughtgears/shared-services/internal/caller"
+	"github.com/thoughtgears/shared-services/internal/db"
+	"github.com/thoughtgears/shared-services/internal/gcs"
+	"github.com/thoughtgears/shared-services/pkg/apperr"
+)
+
+var errNotFaked = errors.New("not supported by the fake")
+
+// fakeDB is a db.DB keeping records in memory. Writes are recorded rather than applied, the
+// records are returned as they were stored; changed makes UpdateIfUnchanged fail.
+type fakeDB[T any] struct {
+	mu      sync.Mutex
+	records map[string]*T
+	writes  []string
+	changed bool
+}
+
+func newFakeDB[T any](records map[string]*T) *fakeDB[T] {
+	return &fakeDB[T]{records: records}
+}
+
+func (f *fakeDB[T]) record(op, id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.writes = append(f.writes, op+" "+id)
+}
+
+func (f *fakeDB[T]) GetByID(_ context.Context, id string) (*T, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	record, ok := f.records[id]
+	if !ok {
+		return nil, apperr.Errorf(apperr.NotFound, "document with id %s not found", id)
+	}
+
+	return record, nil
+}
+
+func (f *fakeDB[T]) Create(ctx context.Context, id string, _ map[string]interface{}) (*T, error) {
+	f.record("create", id)
+
+	return f.GetByID(ctx, id)
+}
+
+func (f *fakeDB[T]) Update(ctx context.Context, id string, _ map[string]interface{}) (*T, error) {
+	f.record("update", id)
+
+	return f.GetByID(ctx, id)
+}
+
+func (f *fakeDB[T]) UpdateIfUnchanged(ctx context.Context, id string, _ time.Time, _ map[string]interface{}) (*T, error) {
+	if f.changed {
+		return nil, db.ErrChanged
+	}
+	f.record("update", id)
+
+	return f.GetByID(ctx, id)
+}
+
+func (f *fakeDB[T]) Delete(_ context.Context, id string) error {
+	f.record("delete", id)
+
+	return nil
+}
+
+func (f *fakeDB[T]) GetAll(context.Context, string, int) ([]*T, string, error) {
+	return nil, "", errNotFaked
+}
+
+func (f *fakeDB[T]) GetByQuery(context.Context, []db.QueryConstraint, string, int) ([]*T, string, error) {
+	return nil, "", errNotFaked
+}
+
+func (f *fakeDB[T]) Find(context.Context, *db.QueryBuilder[T]) ([]*T, string, error) {
+	return nil, "", errNotFaked
+}
+
+func (f *fakeDB[T]) FindPage(context.Context, *db.QueryBuilder[T]) (*db.Page[T], error) {
+	return nil, errNotFaked
+}
+
+func (f *fakeDB[T]) PrepareCreate(context.Context, string, map[string]interface{}) (db.Write, error) {
+	return db.Write{}, errNotFaked
+}
+
+func (f *fakeDB[T]) Count(context.Context, []db.QueryConstraint) (int64, error) {
+	return 0, errNotFaked
+}
+
+func (f *fakeDB[T]) Changes(context.Context, time.Time) (db.ChangeStream[T], error) {
+	return nil, errNotFaked
+}
+
+// fakeStorage is a gcs.Storage recording the operations on its objects.
+type fakeStorage struct {
+	mu      sync.Mutex
+	ops     []string
+	holdErr error
+}
+
+func (s *fakeStorage) record(format string, args ...any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ops = append(s.ops, fmt.Sprintf(format, args...))
+}
+
+func (s *fakeStorage) SetHold(_ context.Context, path string, hold string, held bool) error {
+	if s.holdErr != nil {
+		return s.holdErr
+	}
+	s.record("hold %s %s %t", path, hold, held)
+
+	return nil
+}
+
+func (s *fakeStorage) Delete(_ context.Context, path string) error {
+	s.record("delete %s", path)
+
+	return nil
+}
+
+func (s *fakeStorage) Upload(context.Context, string, io.Reader, string) (*gcs.FileInfo, error) {
+	return nil, errNotFaked
+}
+
+func (s *fakeStorage) Download(context.Context, string) (io.ReadCloser, error) {
+	return nil, errNotFaked
+}
+
+func (s *fakeStorage) List(context.Context, string) ([]gcs.FileInfo, error) {
+	return nil, errNotFaked
+}
+
+func (s *fakeStorage) SignedURL(context.Context, string, time.Duration, string) (string, error) {
+	return "", errNotFaked
+}
+
+func (s *fakeStorage) SetStorageClass(context.Context, string, string) error {
+	return errNotFaked
+}
+
+// asUser returns a context of a request by the user uid, an admin when admin is set.
+func asUser(uid string, admin bool) context.Context {
+	return caller.WithToken(context.Background(), &auth.Token{UID: uid, Claims: map[string]interface{}{caller.AdminClaim: admin}})
+}
@@ -0,0 +1,471 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/audit"
+	"github.com/thoughtgears/shared-services/internal/db"
+	"github.com/thoughtgears/shared-services/internal/importer"
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/tenant"
+	"github.com/thoughtgears/shared-services/pkg/apperr"
+)
+
+const (
+	// importCheckpointInterval is how many records are processed between checkpoints.
+	importCheckpointInterval = 50
+	// maxImportFileSize limits the files of an import, which are read into memory to be hashed.
+	maxImportFileSize = 50 << 20
+)
+
+// importNamespace derives the IDs of imported documents from their owner and content, so a file
+// imported twice maps to the same document.
+var importNamespace = uuid.MustParse("6f0f4d2e-9b7a-4c1e-8f3d-2a5b7c9e1d40")
+
+// ImportService imports the users and documents of the legacy system from a manifest.
+type ImportService interface {
+	Import(ctx context.Context, input ImportInput) (*importer.Report, error)
+}
+
+// ImportInput selects the manifest of an import. Prefix is the directory or object prefix of the
+// import in the source, the manifest and the files it names are relative to it. A dry run
+// validates every record without writing anything. Restart ignores the checkpoint of an earlier
+// run; documents it imported are recognised as duplicates.
+type ImportInput struct {
+	Prefix   string `json:"prefix"`
+	Manifest string `json:"manifest" binding:"required"`
+	DryRun   bool   `json:"dry_run"`
+	Restart  bool   `json:"restart"`
+}
+
+// importService is the concrete implementation of ImportService.
+type importService struct {
+	source    importer.Source
+	storage   *RegionalStorage
+	residency ResidencyResolver
+	settings  TenantSettingsService
+	users     db.DB[models.User]
+	documents db.DB[models.Document]
+	types     DocumentTypeService
+	audit     *audit.Recorder
+}
+
+// NewImportService creates a new instance of ImportService, reading imports from source and
+// storing the documents in the regional storage. residency may be nil to use the default region.
+func NewImportService(
+	source importer.Source,
+	storage *RegionalStorage,
+	residency ResidencyResolver,
+	settings TenantSettingsService,
+	users db.DB[models.User],
+	documents db.DB[models.Document],
+	types DocumentTypeService,
+	recorder *audit.Recorder,
+) ImportService {
+	return &importService{
+		source:    source,
+		storage:   storage,
+		residency: residency,
+		settings:  settings,
+		users:     users,
+		documents: documents,
+		types:     types,
+		audit:     recorder,
+	}
+}
+
+// importRun is the state of one import of a manifest.
+type importRun struct {
+	source   importer.Source
+	report   *importer.Report
+	settings *models.TenantSettings
+	types    map[string]*models.DocumentTypeDefinition
+	// users are the Firebase IDs of the users known to exist, or to be created in a dry run
+	users map[string]bool
+}
+
+// Import imports the records of the manifest into the tenant in the context. Records that fail
+// are reported and skipped, the import continues with the next one. Unless it is a dry run the
+// progress is checkpointed, a later run of the same manifest resumes after the last checkpoint.
+// When ctx is cancelled the import stops after the current record and saves its checkpoint.
+func (i *importService) Import(ctx context.Context, input ImportInput) (*importer.Report, error) {
+	source := i.source
+	if input.Prefix != "" {
+		source = source.Sub(input.Prefix)
+	}
+
+	records, err := i.readManifest(ctx, source, input.Manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := i.settings.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &importRun{
+		source:   source,
+		report:   &importer.Report{Manifest: input.Manifest, DryRun: input.DryRun, Records: len(records)},
+		settings: settings,
+		types:    make(map[string]*models.DocumentTypeDefinition),
+		users:    make(map[string]bool),
+	}
+
+	if !input.DryRun && !input.Restart {
+		checkpoint, err := importer.LoadCheckpoint(ctx, source, input.Manifest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load checkpoint: %w", err)
+		}
+		if checkpoint != nil && checkpoint.Records == len(records) {
+			run.report = checkpoint
+			run.report.ResumedFrom = checkpoint.Next
+		}
+	}
+
+	for index := run.report.Next; index < len(records) && ctx.Err() == nil; index++ {
+		if err := i.importRecord(ctx, run, &records[index]); err != nil {
+			log.Warn().Err(err).Int("record", index+1).Str("file", records[index].File).Msg("Failed to import record")
+			run.report.Fail(index, records[index].File, err)
+		}
+		run.report.Next = index + 1
+
+		if !input.DryRun && run.report.Next%importCheckpointInterval == 0 {
+			if err := importer.SaveCheckpoint(ctx, source, run.report); err != nil {
+				return run.report, fmt.Errorf("failed to save checkpoint: %w", err)
+			}
+		}
+	}
+
+	if !input.DryRun {
+		if err := importer.SaveCheckpoint(context.WithoutCancel(ctx), source, run.report); err != nil {
+			return run.report, fmt.Errorf("failed to save checkpoint: %w", err)
+		}
+	}
+
+	i.audit.Record(context.WithoutCancel(ctx), audit.ActionTenantImport, audit.Target{Type: "tenant", ID: tenant.From(ctx)}, ctx.Err(), map[string]string{
+		"manifest":           input.Manifest,
+		"dry_run":            strconv.FormatBool(input.DryRun),
+		"users_created":      strconv.Itoa(run.report.UsersCreated),
+		"documents_imported": strconv.Itoa(run.report.DocumentsImported),
+		"duplicates":         strconv.Itoa(run.report.Duplicates),
+		"failed":             strconv.Itoa(run.report.Failed),
+	})
+	if err := ctx.Err(); err != nil {
+		return run.report, fmt.Errorf("import interrupted at record %d: %w", run.report.Next+1, err)
+	}
+
+	return run.report, nil
+}
+
+// readManifest reads and parses the manifest at name in source.
+func (i *importService) readManifest(ctx context.Context, source importer.Source, name string) ([]importer.Record, error) {
+	reader, err := source.Open(ctx, name)
+	if errors.Is(err, importer.ErrNotExist) {
+		return nil, apperr.Wrap(apperr.NotFound, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	records, err := importer.ParseManifest(name, reader)
+	if err != nil {
+		return nil, apperr.Wrap(apperr.Invalid, err)
+	}
+
+	return records, nil
+}
+
+// importRecord imports the document of a record, creating its owner when needed. Documents are
+// identified by their owner and the hash of their content, a document that exists is counted as
+// a duplicate.
+func (i *importService) importRecord(ctx context.Context, run *importRun, record *importer.Record) error {
+	if err := record.Validate(); err != nil {
+		return err
+	}
+
+	definition, err := i.resolveType(ctx, run, record.Type)
+	if err != nil {
+		return err
+	}
+	if !run.settings.AllowsDocumentType(definition.ID) {
+		return fmt.Errorf("document type %s is not allowed for this tenant", definition.ID)
+	}
+
+	content, err := readImportFile(ctx, run.source, record.File)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(content)
+	id := uuid.NewSHA1(importNamespace, []byte(record.FirebaseID+":"+hex.EncodeToString(sum[:]))).String()
+
+	_, err = i.documents.GetByID(ctx, id)
+	if err == nil {
+		run.report.Duplicates++
+
+		return nil
+	}
+	if !apperr.Is(err, apperr.NotFound) {
+		return fmt.Errorf("failed to check for duplicate: %w", err)
+	}
+
+	fileType, err := DetectFileType(content)
+	if err != nil {
+		return fmt.Errorf("failed to detect file type: %w", err)
+	}
+
+	status, err := importStatus(record.Status)
+	if err != nil {
+		return err
+	}
+
+	candidate := &models.Document{
+		ID:               id,
+		UserID:           record.FirebaseID,
+		Name:             uuid.NewString(),
+		OriginalFilename: models.SanitizeFilename(record.OriginalFilename),
+		Size:             int64(len(content)),
+		Type:             definition.ID,
+		ContentType:      fileType.MimeType,
+		ExpiryDate:       record.ExpiryDate,
+		Metadata:         record.Metadata,
+		Status:           status,
+	}
+	if err := candidate.ValidateAs(definition); err != nil {
+		return fmt.Errorf("invalid document: %w", err)
+	}
+
+	if err := i.ensureUser(ctx, run, record); err != nil {
+		return err
+	}
+
+	if run.report.DryRun {
+		run.report.DocumentsImported++
+
+		return nil
+	}
+
+	if err := i.storeDocument(ctx, run, record, candidate, content, fileType, definition); err != nil {
+		return err
+	}
+	run.report.DocumentsImported++
+
+	return nil
+}
+
+// storeDocument uploads the content of candidate and creates it with the timestamps of record.
+// The content is deleted again when the document cannot be created.
+func (i *importService) storeDocument(
+	ctx context.Context,
+	run *importRun,
+	record *importer.Record,
+	candidate *models.Document,
+	content []byte,
+	fileType *FileTypeInfo,
+	definition *models.DocumentTypeDefinition,
+) error {
+	region := ""
+	if i.residency != nil {
+		var err error
+		region, err = i.residency.RegionForUser(ctx, candidate.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to resolve storage region: %w", err)
+		}
+	}
+
+	storage, region, err := i.storage.For(region)
+	if err != nil {
+		return err
+	}
+
+	ext := GetStandardizedExtension(fileType.Extension)
+	path := fmt.Sprintf("%sdocuments/%s/%s.%s", tenant.PathPrefix(ctx), candidate.UserID, candidate.Name, ext)
+
+	fileInfo, err := storage.Upload(ctx, path, bytes.NewReader(content), fileType.MimeType)
+	if err != nil {
+		return fmt.Errorf("failed to upload document: %w", err)
+	}
+
+	createdAt := time.Now().UTC()
+	if record.CreatedAt != nil {
+		createdAt = *record.CreatedAt
+	}
+	updatedAt := createdAt
+	if record.UpdatedAt != nil {
+		updatedAt = *record.UpdatedAt
+	}
+
+	document := map[string]interface{}{
+		"id":           candidate.ID,
+		"user_id":      candidate.UserID,
+		"name":         candidate.Name,
+		"size":         fileInfo.Size,
+		"type":         definition.ID,
+		"content_type": fileType.MimeType,
+		"path":         path,
+		"bucket":       fileInfo.Bucket,
+		"region":       region,
+		"status":       candidate.Status,
+		"created_at":   createdAt,
+		"updated_at":   updatedAt,
+	}
+
+	if candidate.OriginalFilename != "" {
+		document["original_filename"] = candidate.OriginalFilename
+	}
+
+	if candidate.ExpiryDate != nil {
+		document["expiry_date"] = *candidate.ExpiryDate
+	}
+
+	if len(candidate.Metadata) > 0 {
+		document["metadata"] = candidate.Metadata
+	}
+
+	// Retention runs from when the document was created in the legacy system
+	retention := *definition
+	if run.settings.RetentionDays > 0 {
+		retention.RetentionDays = run.settings.RetentionDays
+	}
+
+	if retainUntil := retention.RetainUntil(createdAt); retainUntil != nil {
+		document["retain_until"] = *retainUntil
+	}
+
+	_, err = i.documents.Create(ctx, candidate.ID, document)
+	i.audit.Record(ctx, audit.ActionDocumentCreate, documentTarget(candidate.ID), err, map[string]string{
+		"user_id": candidate.UserID,
+		"type":    string(definition.ID),
+		"import":  run.report.Manifest,
+	})
+	if err != nil {
+		if deleteErr := storage.Delete(ctx, path); deleteErr != nil {
+			log.Warn().Err(deleteErr).Str("path", path).Msg("Failed to delete content of failed import")
+		}
+
+		return fmt.Errorf("failed to create document: %w", err)
+	}
+	recordUpload(ctx, string(definition.ID), fileInfo.Size)
+
+	return nil
+}
+
+// ensureUser creates the user of record unless a user with its Firebase ID exists. The user keeps
+// the creation time of the legacy system.
+func (i *importService) ensureUser(ctx context.Context, run *importRun, record *importer.Record) error {
+	if run.users[record.FirebaseID] {
+		return nil
+	}
+
+	existing, _, err := i.users.Find(ctx, db.Query[models.User]().Where("firebase_id", db.Eq, record.FirebaseID).Limit(1))
+	if err != nil {
+		return fmt.Errorf("failed to look up user: %w", err)
+	}
+	if len(existing) > 0 {
+		run.users[record.FirebaseID] = true
+
+		return nil
+	}
+
+	user := &models.User{
+		ID:         uuid.NewString(),
+		FirstName:  record.FirstName,
+		LastName:   record.LastName,
+		Email:      record.Email,
+		FirebaseID: record.FirebaseID,
+	}
+	if err := user.Validate(); err != nil {
+		return fmt.Errorf("invalid user: %w", err)
+	}
+
+	if !run.report.DryRun {
+		createdAt := time.Now().UTC()
+		if record.UserCreatedAt != nil {
+			createdAt = *record.UserCreatedAt
+		}
+
+		_, err := i.users.Create(ctx, user.ID, map[string]interface{}{
+			"id":          user.ID,
+			"first_name":  user.FirstName,
+			"last_name":   user.LastName,
+			"email":       user.Email,
+			"firebase_id": user.FirebaseID,
+			"created_at":  createdAt,
+			"updated_at":  createdAt,
+		})
+		i.audit.Record(ctx, audit.ActionUserCreate, userTarget(user.ID), err, map[string]string{
+			"import": run.report.Manifest,
+		})
+		if err != nil {
+			return fmt.Errorf("error creating user: %w", err)
+		}
+	}
+
+	run.users[record.FirebaseID] = true
+	run.report.UsersCreated++
+
+	return nil
+}
+
+// resolveType returns the definition of the document type name, cached for the run.
+func (i *importService) resolveType(ctx context.Context, run *importRun, name string) (*models.DocumentTypeDefinition, error) {
+	if definition, ok := run.types[name]; ok {
+		return definition, nil
+	}
+
+	definition, err := i.types.Get(ctx, name)
+	if errors.Is(err, ErrUnknownDocumentType) {
+		return nil, fmt.Errorf("unknown document type: %s", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve document type: %w", err)
+	}
+	run.types[name] = definition
+
+	return definition, nil
+}
+
+// importStatus returns the review status of an imported document. Documents are pending unless
+// the legacy system recorded a decision, reviews in progress are not carried over.
+func importStatus(status string) (models.DocumentStatus, error) {
+	switch models.DocumentStatus(status) {
+	case "", models.DocumentStatusPending:
+		return models.DocumentStatusPending, nil
+	case models.DocumentStatusVerified, models.DocumentStatusRejected:
+		return models.DocumentStatus(status), nil
+	default:
+		return "", fmt.Errorf("status must be %s, %s or %s", models.DocumentStatusPending, models.DocumentStatusVerified, models.DocumentStatusRejected)
+	}
+}
+
+// readImportFile reads the file at name in source, up to maxImportFileSize.
+func readImportFile(ctx context.Context, source importer.Source, name string) ([]byte, error) {
+	reader, err := source.Open(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	content, err := io.ReadAll(io.LimitReader(reader, maxImportFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if len(content) > maxImportFileSize {
+		return nil, fmt.Errorf("file %s is larger than %d bytes", name, maxImportFileSize)
+	}
+
+	return content, nil
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/google/uuid"
+
+	"github.com/thoughtgears/shared-services/internal/audit"
+	"github.com/thoughtgears/shared-services/internal/db"
+	"github.com/thoughtgears/shared-services/internal/mailer"
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/signedtoken"
+	"github.com/thoughtgears/shared-services/pkg/apperr"
+)
+
+// expiredRecordRetention is how long expired invitations and access tokens are kept before
+// Firestore deletes them, so users can still see why one stopped working.
+const expiredRecordRetention = 30 * 24 * time.Hour
+
+// ErrInvitationInvalid is returned when an invitation cannot be redeemed: the token is invalid
+// or expired, the invitation is no longer pending, or it was sent to a different email address.
+var ErrInvitationInvalid = apperr.New(apperr.Invalid, "invitation is not valid")
+
+// InvitationService invites people to organizations by email and redeems the invitations.
+type InvitationService interface {
+	Create(ctx context.Context, organizationID string, email string, role models.Role) (*models.Invitation, error)
+	Revoke(ctx context.Context, organizationID string, id string) error
+	Accept(ctx context.Context, token string, email string) (*models.Membership, error)
+}
+
+// invitationService is the concrete implementation of InvitationService.
+type invitationService struct {
+	invitations   db.DB[models.Invitation]
+	memberships   db.DB[models.Membership]
+	organizations OrganizationService
+	signer        *signedtoken.Signer
+	mailer        mailer.Mailer
+	acceptURL     string
+	ttl           time.Duration
+	audit         *audit.Recorder
+}
+
+// NewInvitationService creates a new instance of invitationService.
+// acceptURL is the frontend page that redeems invitations, the token is added as the token query
+// parameter. Invitations expire after ttl.
+func NewInvitationService(
+	invitations db.DB[models.Invitation],
+	memberships db.DB[models.Membership],
+	organizations OrganizationService,
+	signer *signedtoken.Signer,
+	mailer mailer.Mailer,
+	acceptURL string,
+	ttl time.Duration,
+	recorder *audit.Recorder,
+) InvitationService {
+	return &invitationService{
+		invitations:   invitations,
+		memberships:   memberships,
+		organizations: organizations,
+		signer:        signer,
+		mailer:        mailer,
+		acceptURL:     acceptURL,
+		ttl:           ttl,
+		audit:         recorder,
+	}
+}
+
+// Create stores a pending invitation and emails the signed token to the invitee.
+// It requires the admin role in the organization, and only owners can invite owners.
+func (s *invitationService) Create(ctx context.Context, organizationID string, email string, role models.Role) (*models.Invitation, error) {
+	caller, err := s.organizations.Authorize(ctx, organizationID, models.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	candidate := &models.Invitation{Email: strings.ToLower(strings.TrimSpace(email)), Role: role}
+	if err := candidate.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid invitation: %w", err)
+	}
+
+	if role == models.RoleOwner && caller.Role != models.RoleOwner {
+		return nil, fmt.Errorf("only owners can invite owners: %w", ErrForbidden)
+	}
+
+	organization, err := s.organizations.GetByID(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	id := uuid.NewString()
+	expiresAt := time.Now().Add(s.ttl).UTC()
+	invitation, err := s.invitations.Create(ctx, id, db.SetExpireAt(map[string]interface{}{
+		"id":              id,
+		"organization_id": organizationID,
+		"email":           candidate.Email,
+		"role":            role,
+		"status":          models.InvitationStatusPending,
+		"invited_by":      caller.UserID,
+		"expires_at":      expiresAt,
+		"created_at":      firestore.ServerTimestamp,
+		"updated_at":      firestore.ServerTimestamp,
+	}, expiresAt.Add(expiredRecordRetention)))
+	s.audit.Record(ctx, audit.ActionInvitationCreate, organizationTarget(organizationID), err, map[string]string{
+		"invitation_id": id,
+		"role":          string(role),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	link := s.acceptURL + "?token=" + url.QueryEscape(s.signer.Sign(id, expiresAt))
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      candidate.Email,
+		Subject: fmt.Sprintf("You have been invited to join %s", organization.Name),
+		Body: fmt.Sprintf(
+			"You have been invited to join %s as %s.\n\nAccept the invitation before %s:\n%s\n",
+			organization.Name, role, expiresAt.Format(time.RFC1123), link,
+		),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to send invitation email: %w", err)
+	}
+
+	return invitation, nil
+}
+
+// Revoke cancels a pending invitation. It requires the admin role in the organization.
+func (s *invitationService) Revoke(ctx context.Context, organizationID string, id string) error {
+	if _, err := s.organizations.Authorize(ctx, organizationID, models.RoleAdmin); err != nil {
+		return err
+	}
+
+	invitation, err := s.invitations.GetByID(ctx, id)
+	if err != nil || invitation.OrganizationID != organizationID {
+		return fmt.Errorf("invitation not found: %w", err)
+	}
+
+	_, err = s.invitations.Update(ctx, id, map[string]interface{}{
+		"status":     models.InvitationStatusRevoked,
+		"updated_at": firestore.ServerTimestamp,
+	})
+	s.audit.Record(ctx, audit.ActionInvitationRevoke, organizationTarget(organizationID), err, map[string]string{
+		"invitation_id": id,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revoke invitation: %w", err)
+	}
+
+	return nil
+}
+
+// Accept redeems an invitation token for the caller, adding them to the organization with the
+// invited role. email is the verified email of the caller's Firebase account; it must match the
+// invitation so a forwarded or leaked link cannot be used by someone else.
+func (s *invitationService) Accept(ctx context.Context, token string, email string) (*models.Membership, error) {
+	uid, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := s.signer.Verify(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvitationInvalid, err)
+	}
+
+	invitation, err := s.invitations.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvitationInvalid, err)
+	}
+
+	switch {
+	case invitation.Status != models.InvitationStatusPending:
+		return nil, fmt.Errorf("%w: invitation is %s", ErrInvitationInvalid, invitation.Status)
+	case time.Now().After(invitation.ExpiresAt):
+		return nil, fmt.Errorf("%w: invitation expired", ErrInvitationInvalid)
+	case !strings.EqualFold(invitation.Email, email):
+		return nil, fmt.Errorf("%w: invitation was sent to a different email address", ErrInvitationInvalid)
+	}
+
+	membership, err := writeMembership(ctx, s.memberships, s.audit, invitation.OrganizationID, uid, invitation.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	_, err = s.invitations.Update(ctx, id, map[string]interface{}{
+		"status":      models.InvitationStatusAccepted,
+		"accepted_by": uid,
+		"accepted_at": now,
+		"updated_at":  firestore.ServerTimestamp,
+	})
+	s.audit.Record(ctx, audit.ActionInvitationAccept, organizationTarget(invitation.OrganizationID), err, map[string]string{
+		"invitation_id": id,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark invitation accepted: %w", err)
+	}
+
+	return membership, nil
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/thoughtgears/shared-services/internal/telemetry"
+)
+
+// serviceMetrics are the business level instruments, created from the global meter provider.
+var serviceMetrics = sync.OnceValue(func() *serviceInstruments {
+	meter := otel.Meter("github.com/thoughtgears/shared-services/internal/services")
+	uploads, _ := meter.Int64Counter("documents.uploads", metric.WithDescription("Documents uploaded, including new versions"))
+	uploadSize, _ := meter.Int64Histogram("documents.upload.size",
+		metric.WithDescription("Size of uploaded documents"),
+		metric.WithUnit("By"),
+	)
+	failures, _ := meter.Int64Counter("service.operation.failures", metric.WithDescription("Failed service operations"))
+	logins, _ := meter.Int64Counter("users.logins", metric.WithDescription("User sign-ins, labelled with whether the device was new"))
+
+	return &serviceInstruments{uploads: uploads, uploadSize: uploadSize, failures: failures, logins: logins}
+})
+
+type serviceInstruments struct {
+	uploads    metric.Int64Counter
+	uploadSize metric.Int64Histogram
+	failures   metric.Int64Counter
+	logins     metric.Int64Counter
+}
+
+// recordUpload records a document upload for the caller's tenant and cohort.
+func recordUpload(ctx context.Context, documentType string, size int64) {
+	attrs := append(telemetry.Dimensions(ctx), attribute.String("document_type", documentType))
+
+	serviceMetrics().uploads.Add(ctx, 1, metric.WithAttributes(attrs...))
+	serviceMetrics().uploadSize.Record(ctx, size, metric.WithAttributes(attrs...))
+}
+
+// recordFailure counts a failed operation for the caller's tenant and cohort.
+func recordFailure(ctx context.Context, operation string) {
+	attrs := append(telemetry.Dimensions(ctx), attribute.String("operation", operation))
+
+	serviceMetrics().failures.Add(ctx, 1, metric.WithAttributes(attrs...))
+}
+
+// recordLogin counts a sign-in for the caller's tenant and cohort.
+func recordLogin(ctx context.Context, provider string, newDevice bool) {
+	attrs := append(telemetry.Dimensions(ctx), attribute.String("provider", provider), attribute.Bool("new_device", newDevice))
+
+	serviceMetrics().logins.Add(ctx, 1, metric.WithAttributes(attrs...))
+}
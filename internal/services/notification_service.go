@@ -0,0 +1,306 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/db"
+	"github.com/thoughtgears/shared-services/internal/mailer"
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/push"
+)
+
+// webhookTimeout bounds how long a notification waits for each tenant webhook.
+const webhookTimeout = 10 * time.Second
+
+// deliveryRetention is how long notification deliveries are kept in the log.
+const deliveryRetention = 90 * 24 * time.Hour
+
+// Notification is an event a user is told about on every channel they have not turned off.
+type Notification struct {
+	// Event names what happened, e.g. document.reviewed, it is the type of the webhook payload.
+	Event   string
+	Subject string
+	Body    string
+	// Data is sent with push notifications and webhooks, e.g. the ID of the reviewed document.
+	Data map[string]string
+	// Template is the email template the subject and body are rendered from in the user's
+	// language, with TemplateData. Subject and Body are sent when it is empty or fails to render.
+	Template     string
+	TemplateData map[string]string
+
+	// email is the email rendered from Template
+	email *mailer.Message
+}
+
+// NotificationService delivers notifications to users and keeps a log of every delivery.
+type NotificationService interface {
+	Notify(ctx context.Context, userID string, notification Notification)
+	ListDeliveries(ctx context.Context, userID string, opts ListOptions) (*models.Page[*models.NotificationDelivery], error)
+}
+
+// notificationService is the concrete implementation of NotificationService.
+type notificationService struct {
+	deliveries db.DB[models.NotificationDelivery]
+	users      UserService
+	settings   TenantSettingsService
+	mailer     mailer.Mailer
+	templates  *mailer.Templates
+	push       push.Sender
+	httpClient *http.Client
+}
+
+// NewNotificationService creates a new instance of notificationService.
+// Emails are sent with mailer, push notifications with pusher and webhooks are posted to the
+// webhook URLs in the tenant settings.
+func NewNotificationService(
+	deliveries db.DB[models.NotificationDelivery],
+	users UserService,
+	settings TenantSettingsService,
+	mailer mailer.Mailer,
+	templates *mailer.Templates,
+	pusher push.Sender,
+) NotificationService {
+	return &notificationService{
+		deliveries: deliveries,
+		users:      users,
+		settings:   settings,
+		mailer:     mailer,
+		templates:  templates,
+		push:       pusher,
+		httpClient: &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// Notify delivers a notification to a user on every channel their preferences allow, and records
+// the outcome of each channel in the delivery log. Notifications are best effort: failures are
+// logged and recorded, they never fail the operation that triggered the notification.
+func (n *notificationService) Notify(ctx context.Context, userID string, notification Notification) {
+	user, err := n.users.GetByID(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", userID).Str("event", notification.Event).Msg("Failed to get user to notify")
+
+		return
+	}
+
+	notification = n.localize(user, notification)
+
+	for _, channel := range models.NotificationChannels {
+		status, err := n.deliver(ctx, channel, user, notification)
+		if status == models.NotificationDeliveryFailed {
+			log.Error().Err(err).Str("user_id", userID).Str("event", notification.Event).Str("channel", string(channel)).Msg("Failed to deliver notification")
+		}
+
+		n.record(ctx, userID, channel, status, notification, err)
+	}
+}
+
+var (
+	// errChannelDisabled is recorded for channels the user turned off.
+	errChannelDisabled = errors.New("turned off in the user's notification preferences")
+	// errNoRecipient is recorded for channels with nowhere to deliver to, e.g. a tenant without webhook URLs.
+	errNoRecipient = errors.New("no recipient configured for the channel")
+)
+
+// deliver delivers the notification on one channel if the user's preferences allow it.
+// It returns the status to record, with the error explaining why it was not delivered.
+func (n *notificationService) deliver(ctx context.Context, channel models.NotificationChannel, user *models.User, notification Notification) (models.NotificationDeliveryStatus, error) {
+	if !user.NotificationPreferences.Enabled(channel) {
+		return models.NotificationDeliverySkipped, errChannelDisabled
+	}
+
+	err := n.send(ctx, channel, user, notification)
+	switch {
+	case errors.Is(err, errNoRecipient):
+		return models.NotificationDeliverySkipped, err
+	case err != nil:
+		return models.NotificationDeliveryFailed, err
+	}
+
+	return models.NotificationDeliveryDelivered, nil
+}
+
+// localize renders the notification from its template in the user's language, the email with the
+// branded layout and the subject and body of the other channels without. A template that fails
+// to render is logged and the notification keeps its own text.
+func (n *notificationService) localize(user *models.User, notification Notification) Notification {
+	if notification.Template == "" || n.templates == nil {
+		return notification
+	}
+
+	lang := user.Language()
+	email, err := n.templates.Render(notification.Template, lang, user.Email, notification.TemplateData)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", user.ID).Str("event", notification.Event).Msg("Failed to render notification")
+
+		return notification
+	}
+	subject, body, err := n.templates.RenderText(notification.Template, lang, notification.TemplateData)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", user.ID).Str("event", notification.Event).Msg("Failed to render notification")
+
+		return notification
+	}
+
+	notification.Subject = subject
+	notification.Body = body
+	notification.email = &email
+
+	return notification
+}
+
+// send sends the notification on one channel.
+func (n *notificationService) send(ctx context.Context, channel models.NotificationChannel, user *models.User, notification Notification) error {
+	switch channel {
+	case models.NotificationChannelEmail:
+		if user.Email == "" {
+			return errNoRecipient
+		}
+
+		if notification.email != nil {
+			return n.mailer.Send(ctx, *notification.email)
+		}
+
+		return n.mailer.Send(ctx, mailer.Message{
+			To:      user.Email,
+			Subject: notification.Subject,
+			Body:    notification.Body,
+		})
+	case models.NotificationChannelPush:
+		return n.push.Send(ctx, push.Message{
+			UserID: user.FirebaseID,
+			Title:  notification.Subject,
+			Body:   notification.Body,
+			Data:   notification.Data,
+		})
+	case models.NotificationChannelWebhook:
+		return n.postWebhooks(ctx, user.FirebaseID, notification)
+	default:
+		return fmt.Errorf("unknown notification channel: %s", channel)
+	}
+}
+
+// webhookPayload is the JSON body posted to tenant webhooks.
+type webhookPayload struct {
+	Type      string            `json:"type"`
+	UserID    string            `json:"user_id"`
+	Subject   string            `json:"subject"`
+	Data      map[string]string `json:"data,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// postWebhooks posts the notification to every webhook URL of the tenant. It fails when any of
+// them does not answer with a 2xx status, after trying them all.
+func (n *notificationService) postWebhooks(ctx context.Context, userID string, notification Notification) error {
+	settings, err := n.settings.Get(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get tenant settings: %w", err)
+	}
+	if len(settings.WebhookURLs) == 0 {
+		return errNoRecipient
+	}
+
+	body, err := json.Marshal(webhookPayload{
+		Type:      notification.Event,
+		UserID:    userID,
+		Subject:   notification.Subject,
+		Data:      notification.Data,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	failed := 0
+	for _, url := range settings.WebhookURLs {
+		if err := n.postWebhook(ctx, url, body); err != nil {
+			log.Error().Err(err).Str("event", notification.Event).Msg("Failed to post notification webhook")
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d webhooks failed", failed, len(settings.WebhookURLs))
+	}
+
+	return nil
+}
+
+// postWebhook posts body to a single webhook URL.
+func (n *notificationService) postWebhook(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post webhook: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook answered with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// record writes a delivery to the log. A failed write is only logged, like a failed delivery.
+func (n *notificationService) record(ctx context.Context, userID string, channel models.NotificationChannel, status models.NotificationDeliveryStatus, notification Notification, deliveryErr error) {
+	now := time.Now()
+	id := deliveryID(now)
+	data := db.SetExpireAt(map[string]interface{}{
+		"id":         id,
+		"user_id":    userID,
+		"event":      notification.Event,
+		"channel":    channel,
+		"status":     status,
+		"subject":    notification.Subject,
+		"created_at": firestore.ServerTimestamp,
+	}, now.Add(deliveryRetention))
+	if deliveryErr != nil {
+		data["error"] = deliveryErr.Error()
+	}
+
+	if _, err := n.deliveries.Create(ctx, id, data); err != nil {
+		log.Error().Err(err).Str("user_id", userID).Str("event", notification.Event).Msg("Failed to record notification delivery")
+	}
+}
+
+// deliveryID returns a unique delivery ID that sorts before the IDs of earlier deliveries,
+// since queries are ordered by document ID and the log is read newest first.
+func deliveryID(at time.Time) string {
+	return fmt.Sprintf("%019d-%s", math.MaxInt64-at.UnixNano(), uuid.NewString())
+}
+
+// ListDeliveries returns a page of the notifications delivered to a user, newest first.
+// Users can only read their own delivery log.
+func (n *notificationService) ListDeliveries(ctx context.Context, userID string, opts ListOptions) (*models.Page[*models.NotificationDelivery], error) {
+	uid, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if uid != userID {
+		return nil, fmt.Errorf("notifications of user %s: %w", userID, ErrForbidden)
+	}
+
+	pageSize := opts.pageSize()
+	query := db.Query[models.NotificationDelivery]().Where("user_id", db.Eq, userID)
+	result, err := n.deliveries.FindPage(ctx, query.After(opts.PageToken).Limit(pageSize).IncludeTotal(opts.IncludeTotal))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification deliveries: %w", err)
+	}
+
+	return newPage(result, pageSize), nil
+}
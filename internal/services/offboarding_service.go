@@ -0,0 +1,49 @@
+package services
+
+import (
+	"context"
+
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/offboarding"
+	"github.com/thoughtgears/shared-services/internal/tenant"
+)
+
+// OffboardingService exports the data of the tenant in the context, for contract termination.
+type OffboardingService interface {
+	Export(ctx context.Context, input ExportTenantInput) (*offboarding.Manifest, error)
+}
+
+// ExportTenantInput holds the options of a tenant export.
+// Purging is irreversible, so it must be confirmed by repeating the tenant ID.
+type ExportTenantInput struct {
+	Purge           bool   `json:"purge"`
+	ConfirmTenantID string `json:"confirm_tenant_id"`
+}
+
+// offboardingService is the concrete implementation of OffboardingService.
+type offboardingService struct {
+	exporter *offboarding.Exporter
+}
+
+// NewOffboardingService creates a new instance of OffboardingService.
+func NewOffboardingService(exporter *offboarding.Exporter) OffboardingService {
+	return &offboardingService{exporter: exporter}
+}
+
+// Export writes all data of the tenant in ctx to the export bucket and optionally purges it.
+// The export keeps running if the caller disconnects, so a purge is never left half done.
+func (o *offboardingService) Export(ctx context.Context, input ExportTenantInput) (*offboarding.Manifest, error) {
+	tenantID := tenant.From(ctx)
+	if tenantID == "" {
+		return nil, tenant.ErrMissing
+	}
+
+	if input.Purge && input.ConfirmTenantID != tenantID {
+		validation := &models.ValidationError{}
+		validation.Add("confirm_tenant_id", "must match the tenant ID to purge")
+
+		return nil, validation
+	}
+
+	return o.exporter.Export(context.WithoutCancel(ctx), tenantID, input.Purge)
+}
@@ -0,0 +1,332 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	"github.com/google/uuid"
+
+	"github.com/thoughtgears/shared-services/internal/audit"
+	"github.com/thoughtgears/shared-services/internal/caller"
+	"github.com/thoughtgears/shared-services/internal/db"
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/pkg/apperr"
+)
+
+// ErrForbidden is returned when the caller is not allowed to perform an operation.
+var ErrForbidden = apperr.New(apperr.PermissionDenied, "forbidden")
+
+// OrganizationService handles organizations and their memberships.
+// Every method acts on behalf of the caller, taken from the audit actor in the context,
+// and checks the caller's role in the organization before doing anything.
+type OrganizationService interface {
+	GetByID(ctx context.Context, id string) (*models.Organization, error)
+	ListForCaller(ctx context.Context, opts ListOptions) (*models.Page[*models.Membership], error)
+	Create(ctx context.Context, organization *models.Organization) (*models.Organization, error)
+	Update(ctx context.Context, id string, organization *models.Organization) (*models.Organization, error)
+	Delete(ctx context.Context, id string) error
+	ListMembers(ctx context.Context, id string, opts ListOptions) (*models.Page[*models.Membership], error)
+	SetMember(ctx context.Context, id string, userID string, role models.Role) (*models.Membership, error)
+	RemoveMember(ctx context.Context, id string, userID string) error
+	Authorize(ctx context.Context, id string, minRole models.Role) (*models.Membership, error)
+}
+
+// organizationService is the concrete implementation of OrganizationService.
+type organizationService struct {
+	organizations db.DB[models.Organization]
+	memberships   db.DB[models.Membership]
+	audit         *audit.Recorder
+}
+
+// NewOrganizationService creates a new instance of organizationService.
+// It initializes the service with the db for organizations and the db for memberships.
+// recorder audits every mutating operation, nil disables auditing.
+func NewOrganizationService(
+	organizations db.DB[models.Organization],
+	memberships db.DB[models.Membership],
+	recorder *audit.Recorder,
+) OrganizationService {
+	return &organizationService{
+		organizations: organizations,
+		memberships:   memberships,
+		audit:         recorder,
+	}
+}
+
+// callerID returns the ID of the authenticated user making the request.
+// Services and system actors have no user ID and are denied, organizations are only managed by users.
+func callerID(ctx context.Context) (string, error) {
+	uid, ok := caller.UIDFrom(ctx)
+	if !ok {
+		return "", fmt.Errorf("no authenticated user: %w", ErrForbidden)
+	}
+
+	return uid, nil
+}
+
+// Authorize checks that the caller is a member of the organization with at least minRole.
+// It returns the caller's membership, or an error wrapping ErrForbidden.
+func (o *organizationService) Authorize(ctx context.Context, id string, minRole models.Role) (*models.Membership, error) {
+	uid, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	membership, err := o.memberships.GetByID(ctx, models.MembershipID(id, uid))
+	if err != nil || membership == nil {
+		return nil, fmt.Errorf("not a member of organization %s: %w", id, ErrForbidden)
+	}
+
+	if !membership.Role.AtLeast(minRole) {
+		return nil, fmt.Errorf("role %s in organization %s does not allow this: %w", membership.Role, id, ErrForbidden)
+	}
+
+	return membership, nil
+}
+
+// GetByID retrieves an organization the caller is a member of.
+func (o *organizationService) GetByID(ctx context.Context, id string) (*models.Organization, error) {
+	if _, err := o.Authorize(ctx, id, models.RoleMember); err != nil {
+		return nil, err
+	}
+
+	organization, err := o.organizations.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+
+	return organization, nil
+}
+
+// ListForCaller returns a page of the caller's memberships, one per organization they belong to.
+func (o *organizationService) ListForCaller(ctx context.Context, opts ListOptions) (*models.Page[*models.Membership], error) {
+	uid, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.listMemberships(ctx, db.Query[models.Membership]().Where("user_id", db.Eq, uid), opts)
+}
+
+// Create creates an organization with the caller as its owner.
+func (o *organizationService) Create(ctx context.Context, organization *models.Organization) (*models.Organization, error) {
+	if organization == nil {
+		return nil, fmt.Errorf("organization cannot be nil")
+	}
+
+	uid, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := organization.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid organization: %w", err)
+	}
+
+	id := uuid.NewString()
+	created, err := o.organizations.Create(ctx, id, map[string]interface{}{
+		"id":         id,
+		"name":       organization.Name,
+		"created_by": uid,
+		"created_at": firestore.ServerTimestamp,
+		"updated_at": firestore.ServerTimestamp,
+	})
+	o.audit.Record(ctx, audit.ActionOrganizationCreate, organizationTarget(id), err, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create organization: %w", err)
+	}
+
+	if _, err := writeMembership(ctx, o.memberships, o.audit, id, uid, models.RoleOwner); err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// Update renames an organization. It requires the admin role.
+func (o *organizationService) Update(ctx context.Context, id string, organization *models.Organization) (*models.Organization, error) {
+	if organization == nil {
+		return nil, fmt.Errorf("organization cannot be nil")
+	}
+
+	if _, err := o.Authorize(ctx, id, models.RoleAdmin); err != nil {
+		return nil, err
+	}
+
+	if err := organization.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid organization: %w", err)
+	}
+
+	updated, err := o.organizations.Update(ctx, id, map[string]interface{}{
+		"name":       organization.Name,
+		"updated_at": firestore.ServerTimestamp,
+	})
+	o.audit.Record(ctx, audit.ActionOrganizationUpdate, organizationTarget(id), err, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update organization: %w", err)
+	}
+
+	return updated, nil
+}
+
+// Delete deletes an organization and its memberships. It requires the owner role.
+// Documents owned by the organization are kept, they become reachable only by their uploaders.
+func (o *organizationService) Delete(ctx context.Context, id string) error {
+	if _, err := o.Authorize(ctx, id, models.RoleOwner); err != nil {
+		return err
+	}
+
+	query := db.Query[models.Membership]().Where("organization_id", db.Eq, id).Limit(MaxPageSize)
+	pageToken := ""
+	for {
+		memberships, next, err := o.memberships.Find(ctx, query.After(pageToken))
+		if err != nil {
+			return fmt.Errorf("failed to list organization members: %w", err)
+		}
+
+		for _, membership := range memberships {
+			if err := o.memberships.Delete(ctx, membership.ID); err != nil {
+				return fmt.Errorf("failed to delete membership: %w", err)
+			}
+		}
+
+		if next == "" {
+			break
+		}
+		pageToken = next
+	}
+
+	err := o.organizations.Delete(ctx, id)
+	o.audit.Record(ctx, audit.ActionOrganizationDelete, organizationTarget(id), err, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete organization: %w", err)
+	}
+
+	return nil
+}
+
+// ListMembers returns a page of the organization's memberships. Any member can list them.
+func (o *organizationService) ListMembers(ctx context.Context, id string, opts ListOptions) (*models.Page[*models.Membership], error) {
+	if _, err := o.Authorize(ctx, id, models.RoleMember); err != nil {
+		return nil, err
+	}
+
+	return o.listMemberships(ctx, db.Query[models.Membership]().Where("organization_id", db.Eq, id), opts)
+}
+
+// SetMember adds a user to the organization or changes their role. It requires the admin role,
+// and only owners can grant or take away the owner role.
+func (o *organizationService) SetMember(ctx context.Context, id string, userID string, role models.Role) (*models.Membership, error) {
+	caller, err := o.Authorize(ctx, id, models.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	candidate := &models.Membership{OrganizationID: id, UserID: userID, Role: role}
+	if err := candidate.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid membership: %w", err)
+	}
+
+	existing, _ := o.memberships.GetByID(ctx, models.MembershipID(id, userID))
+	changesOwner := role == models.RoleOwner || (existing != nil && existing.Role == models.RoleOwner)
+	if changesOwner && caller.Role != models.RoleOwner {
+		return nil, fmt.Errorf("only owners can change owners: %w", ErrForbidden)
+	}
+
+	return writeMembership(ctx, o.memberships, o.audit, id, userID, role)
+}
+
+// RemoveMember removes a user from the organization. It requires the admin role, only owners can
+// remove owners, and members can always remove themselves.
+func (o *organizationService) RemoveMember(ctx context.Context, id string, userID string) error {
+	uid, err := callerID(ctx)
+	if err != nil {
+		return err
+	}
+
+	membership, err := o.memberships.GetByID(ctx, models.MembershipID(id, userID))
+	if err != nil || membership == nil {
+		return fmt.Errorf("membership not found: %w", err)
+	}
+
+	if userID != uid {
+		caller, err := o.Authorize(ctx, id, models.RoleAdmin)
+		if err != nil {
+			return err
+		}
+		if membership.Role == models.RoleOwner && caller.Role != models.RoleOwner {
+			return fmt.Errorf("only owners can remove owners: %w", ErrForbidden)
+		}
+	}
+
+	err = o.memberships.Delete(ctx, membership.ID)
+	o.audit.Record(ctx, audit.ActionMembershipDelete, organizationTarget(id), err, map[string]string{
+		"user_id": userID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete membership: %w", err)
+	}
+
+	return nil
+}
+
+// writeMembership creates or replaces a user's membership in an organization.
+// It does not authorize the caller, callers must have checked access already.
+func writeMembership(
+	ctx context.Context,
+	memberships db.DB[models.Membership],
+	recorder *audit.Recorder,
+	id string,
+	userID string,
+	role models.Role,
+) (*models.Membership, error) {
+	membershipID := models.MembershipID(id, userID)
+
+	existing, _ := memberships.GetByID(ctx, membershipID)
+
+	var (
+		membership *models.Membership
+		err        error
+	)
+	if existing == nil {
+		membership, err = memberships.Create(ctx, membershipID, map[string]interface{}{
+			"id":              membershipID,
+			"organization_id": id,
+			"user_id":         userID,
+			"role":            role,
+			"created_at":      firestore.ServerTimestamp,
+			"updated_at":      firestore.ServerTimestamp,
+		})
+	} else {
+		membership, err = memberships.Update(ctx, membershipID, map[string]interface{}{
+			"role":       role,
+			"updated_at": firestore.ServerTimestamp,
+		})
+	}
+	recorder.Record(ctx, audit.ActionUserRoleChange, organizationTarget(id), err, map[string]string{
+		"user_id": userID,
+		"role":    string(role),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write membership: %w", err)
+	}
+
+	return membership, nil
+}
+
+// listMemberships returns a page of memberships matching the query.
+func (o *organizationService) listMemberships(ctx context.Context, query *db.QueryBuilder[models.Membership], opts ListOptions) (*models.Page[*models.Membership], error) {
+	pageSize := opts.pageSize()
+	result, err := o.memberships.FindPage(ctx, query.After(opts.PageToken).Limit(pageSize).IncludeTotal(opts.IncludeTotal))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list memberships: %w", err)
+	}
+
+	return newPage(result, pageSize), nil
+}
+
+// organizationTarget returns the audit target for an organization.
+func organizationTarget(id string) audit.Target {
+	return audit.Target{Type: "organization", ID: id}
+}
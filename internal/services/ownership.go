@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/thoughtgears/shared-services/internal/caller"
+	"github.com/thoughtgears/shared-services/internal/models"
+)
+
+// authorizeOwner checks the caller may access a resource of the given kind, e.g. document,
+// owned by organizationID, or by the user userID when it has no organization. Members of the
+// organization need at least minRole, personal resources are only accessible to their owner and
+// to admins. It returns an error wrapping ErrForbidden otherwise.
+func authorizeOwner(ctx context.Context, organizations OrganizationService, kind string, userID string, organizationID string, minRole models.Role) error {
+	if organizationID != "" {
+		_, err := organizations.Authorize(ctx, organizationID, minRole)
+
+		return err
+	}
+
+	return authorizeUser(ctx, kind, userID)
+}
+
+// authorizeUser checks the caller is the user userID or an admin, who act on behalf of any user.
+func authorizeUser(ctx context.Context, kind string, userID string) error {
+	if caller.IsAdmin(ctx) {
+		return nil
+	}
+
+	if _, err := callerID(ctx); err != nil {
+		return err
+	}
+	if !isOwner(ctx, userID) {
+		return fmt.Errorf("%s belongs to another user: %w", kind, ErrForbidden)
+	}
+
+	return nil
+}
+
+// isOwner reports whether the caller is the user userID, a Firebase UID. A caller signed in with
+// the account of a duplicate merged into userID owns its resources too, see UserMergeService.
+func isOwner(ctx context.Context, userID string) bool {
+	uid, ok := caller.UIDFrom(ctx)
+	if !ok {
+		return false
+	}
+	if uid == userID {
+		return true
+	}
+
+	user, ok := caller.UserFrom(ctx)
+
+	return ok && user.FirebaseID == userID
+}
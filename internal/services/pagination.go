@@ -0,0 +1,40 @@
+package services
+
+import (
+	"github.com/thoughtgears/shared-services/internal/db"
+	"github.com/thoughtgears/shared-services/internal/models"
+)
+
+const (
+	// DefaultPageSize is used when a list request does not specify a page size.
+	DefaultPageSize = 50
+	// MaxPageSize is the largest page size a list request may ask for.
+	MaxPageSize = 100
+)
+
+// ListOptions controls pagination of list operations.
+type ListOptions struct {
+	PageToken    string
+	PageSize     int
+	IncludeTotal bool
+}
+
+// pageSize returns the effective page size, applying the default and the maximum.
+func (o ListOptions) pageSize() int {
+	switch {
+	case o.PageSize <= 0:
+		return DefaultPageSize
+	case o.PageSize > MaxPageSize:
+		return MaxPageSize
+	default:
+		return o.PageSize
+	}
+}
+
+// newPage converts a page of query results to the page returned by the services.
+func newPage[T any](result *db.Page[T], pageSize int) *models.Page[*T] {
+	page := models.NewPage(result.Items, result.NextPageToken, pageSize)
+	page.TotalCount = result.TotalCount
+
+	return page
+}
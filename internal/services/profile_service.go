@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/thoughtgears/shared-services/internal/db"
+	"github.com/thoughtgears/shared-services/internal/identity"
+	"github.com/thoughtgears/shared-services/internal/models"
+)
+
+// ProfileDocumentLimit is the number of document summaries included in a profile,
+// the full list is paged through GET /v1/documents.
+const ProfileDocumentLimit = 20
+
+// ProfileService assembles the profile of the authenticated user from the user and document services.
+type ProfileService interface {
+	Get(ctx context.Context) (*models.Profile, error)
+}
+
+// profileService is the concrete implementation of ProfileService.
+type profileService struct {
+	users     UserService
+	documents DocumentService
+	settings  TenantSettingsService
+	identity  identity.Provider
+	snapshots *db.Snapshotter
+}
+
+// NewProfileService creates a new instance of ProfileService.
+func NewProfileService(
+	users UserService,
+	documents DocumentService,
+	settings TenantSettingsService,
+	provider identity.Provider,
+	snapshots *db.Snapshotter,
+) ProfileService {
+	return &profileService{
+		users:     users,
+		documents: documents,
+		settings:  settings,
+		identity:  provider,
+		snapshots: snapshots,
+	}
+}
+
+// Get returns the caller's user, their document summaries, quota usage and MFA enrollment.
+// The parts are loaded concurrently in a read-only transaction, so the user, the documents and
+// their count are from the same snapshot. Any failure fails the whole profile.
+func (p *profileService) Get(ctx context.Context) (*models.Profile, error) {
+	uid, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		profile   models.Profile
+		documents *models.Page[*models.Document]
+		settings  *models.TenantSettings
+		mfa       *models.MFAStatus
+	)
+
+	err = p.snapshots.ReadOnly(ctx, func(ctx context.Context) error {
+		group, groupCtx := errgroup.WithContext(ctx)
+		group.Go(func() error {
+			user, err := p.users.GetByID(groupCtx, uid)
+			if err != nil {
+				return fmt.Errorf("failed to get user: %w", err)
+			}
+			profile.User = user
+
+			return nil
+		})
+		group.Go(func() error {
+			page, err := p.documents.GetAllByUserID(groupCtx, uid, nil, ListOptions{PageSize: ProfileDocumentLimit, IncludeTotal: true})
+			if err != nil {
+				return fmt.Errorf("failed to list documents: %w", err)
+			}
+			documents = page
+
+			return nil
+		})
+		group.Go(func() error {
+			tenantSettings, err := p.settings.Get(groupCtx)
+			if err != nil {
+				return fmt.Errorf("failed to get tenant settings: %w", err)
+			}
+			settings = tenantSettings
+
+			return nil
+		})
+		group.Go(func() error {
+			status, err := p.identity.MFAStatus(groupCtx, uid)
+			if err != nil {
+				return fmt.Errorf("failed to get MFA status: %w", err)
+			}
+			mfa = status
+
+			return nil
+		})
+
+		return group.Wait()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	profile.Documents = make([]models.DocumentSummary, 0, len(documents.Items))
+	for _, document := range documents.Items {
+		profile.Documents = append(profile.Documents, document.Summary())
+	}
+
+	profile.Quota.Limit = settings.MaxDocumentsPerUser
+	if documents.TotalCount != nil {
+		profile.Quota.Used = *documents.TotalCount
+	}
+
+	profile.MFA = *mfa
+
+	return &profile, nil
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/thoughtgears/shared-services/internal/gcs"
+	"github.com/thoughtgears/shared-services/internal/residency"
+)
+
+// ResidencyResolver decides which region a user's documents are stored in.
+type ResidencyResolver interface {
+	RegionForUser(ctx context.Context, userID string) (string, error)
+}
+
+// userResidencyResolver places documents based on the country of the user's address.
+type userResidencyResolver struct {
+	users  UserService
+	policy *residency.Policy
+}
+
+// NewUserResidencyResolver creates a ResidencyResolver that looks up the user's address country
+// and applies the residency policy to it. Users that cannot be found use the default region.
+func NewUserResidencyResolver(users UserService, policy *residency.Policy) ResidencyResolver {
+	return &userResidencyResolver{
+		users:  users,
+		policy: policy,
+	}
+}
+
+// RegionForUser returns the storage region for the user's documents.
+func (r *userResidencyResolver) RegionForUser(ctx context.Context, userID string) (string, error) {
+	user, err := r.users.GetByID(ctx, userID)
+	if err != nil {
+		return r.policy.DefaultRegion(), nil
+	}
+
+	return r.policy.RegionFor(user.Address.Country), nil
+}
+
+// RegionalStorage holds one storage backend per residency region.
+type RegionalStorage struct {
+	defaultRegion string
+	storages      map[string]gcs.Storage
+}
+
+// NewRegionalStorage creates a RegionalStorage. Documents stored before regions were
+// recorded are read from the default region.
+func NewRegionalStorage(defaultRegion string, storages map[string]gcs.Storage) *RegionalStorage {
+	return &RegionalStorage{
+		defaultRegion: defaultRegion,
+		storages:      storages,
+	}
+}
+
+// For returns the storage for region and the region it resolved to.
+func (r *RegionalStorage) For(region string) (gcs.Storage, string, error) {
+	if region == "" {
+		region = r.defaultRegion
+	}
+
+	storage, ok := r.storages[region]
+	if !ok {
+		return nil, region, fmt.Errorf("no storage configured for region %q", region)
+	}
+
+	return storage, region, nil
+}
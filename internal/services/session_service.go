@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+
+	"github.com/thoughtgears/shared-services/internal/audit"
+	"github.com/thoughtgears/shared-services/internal/db"
+	"github.com/thoughtgears/shared-services/internal/identity"
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/stats"
+	"github.com/thoughtgears/shared-services/internal/tenant"
+)
+
+// idTokenLifetime is how long Firebase ID tokens are valid, a revocation is not needed once every
+// token issued before it has expired.
+const idTokenLifetime = time.Hour
+
+// maxCachedSessionRevocations is the cache size above which expired revocations are dropped.
+const maxCachedSessionRevocations = 10000
+
+// SessionService revokes the sessions of users and tells the auth middleware which ID tokens
+// are no longer valid.
+type SessionService interface {
+	Revoke(ctx context.Context, userID string) (*models.SessionRevocation, error)
+	TokensValidAfter(ctx context.Context, tenantID string, userID string) (time.Time, error)
+}
+
+type cachedSessionRevocation struct {
+	validAfter time.Time
+	loadedAt   time.Time
+}
+
+// sessionService is the concrete implementation of SessionService.
+type sessionService struct {
+	identity identity.Provider
+	db       db.DB[models.SessionRevocation]
+	ttl      time.Duration
+	audit    *audit.Recorder
+
+	mu    sync.RWMutex
+	cache map[string]cachedSessionRevocation
+}
+
+// NewSessionService creates a SessionService backed by the session_revocations collection. The
+// collection is shared by all tenants, so db must not be tenant scoped. Revocations are reloaded
+// at most once per ttl, so a revocation reaches other instances within ttl.
+func NewSessionService(provider identity.Provider, db db.DB[models.SessionRevocation], ttl time.Duration, recorder *audit.Recorder) SessionService {
+	return &sessionService{
+		identity: provider,
+		db:       db,
+		ttl:      ttl,
+		audit:    recorder,
+		cache:    make(map[string]cachedSessionRevocation),
+	}
+}
+
+// Revoke signs the user out everywhere: the refresh tokens of the user are revoked and ID tokens
+// issued until now are rejected from then on. Users can only revoke their own sessions.
+func (s *sessionService) Revoke(ctx context.Context, userID string) (*models.SessionRevocation, error) {
+	if err := authorizeSelf(ctx, userID, "sessions"); err != nil {
+		return nil, err
+	}
+
+	revocation, err := s.revoke(ctx, userID)
+	s.audit.Record(ctx, audit.ActionUserSessionsRevoke, userTarget(userID), err, nil)
+	if err != nil {
+		recordFailure(ctx, "user.sessions_revoke")
+		return nil, err
+	}
+
+	return revocation, nil
+}
+
+// revoke revokes the refresh tokens of the user and records the revocation.
+func (s *sessionService) revoke(ctx context.Context, userID string) (*models.SessionRevocation, error) {
+	if err := s.identity.RevokeSessions(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	// ID tokens carry their issue time in seconds, a token issued in the same second as the
+	// revocation is still accepted, as Firebase does for refresh tokens
+	tenantID := tenant.From(ctx)
+	id := models.SessionRevocationID(tenantID, userID)
+	validAfter := time.Now().UTC().Truncate(time.Second)
+	revocation, err := s.db.Update(ctx, id, db.SetExpireAt(map[string]interface{}{
+		"id":                 id,
+		"user_id":            userID,
+		"tenant_id":          tenantID,
+		"tokens_valid_after": validAfter,
+		"updated_at":         firestore.ServerTimestamp,
+	}, validAfter.Add(idTokenLifetime)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to record session revocation: %w", err)
+	}
+
+	s.mu.Lock()
+	s.cache[id] = cachedSessionRevocation{validAfter: validAfter, loadedAt: time.Now()}
+	s.mu.Unlock()
+
+	return revocation, nil
+}
+
+// TokensValidAfter returns the time ID tokens of the user in the tenant must be issued after,
+// the zero time when the sessions of the user were never revoked.
+func (s *sessionService) TokensValidAfter(ctx context.Context, tenantID string, userID string) (time.Time, error) {
+	id := models.SessionRevocationID(tenantID, userID)
+
+	s.mu.RLock()
+	cached, ok := s.cache[id]
+	s.mu.RUnlock()
+	if ok && time.Since(cached.loadedAt) < s.ttl {
+		stats.CacheHit("session_revocations")
+
+		return cached.validAfter, nil
+	}
+	stats.CacheMiss("session_revocations")
+
+	// Queried rather than fetched by ID, so a user without a revocation is not an error
+	results, _, err := s.db.Find(ctx, db.Query[models.SessionRevocation]().Where("id", db.Eq, id).Limit(1))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to load session revocation: %w", err)
+	}
+
+	var validAfter time.Time
+	if len(results) > 0 {
+		validAfter = results[0].TokensValidAfter
+	}
+
+	s.mu.Lock()
+	// The cache holds an entry per active user, expired ones are dropped once it grows large
+	if len(s.cache) >= maxCachedSessionRevocations {
+		for key, entry := range s.cache {
+			if time.Since(entry.loadedAt) >= s.ttl {
+				delete(s.cache, key)
+			}
+		}
+	}
+	s.cache[id] = cachedSessionRevocation{validAfter: validAfter, loadedAt: time.Now()}
+	s.mu.Unlock()
+
+	return validAfter, nil
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+
+	"github.com/thoughtgears/shared-services/internal/audit"
+	"github.com/thoughtgears/shared-services/internal/db"
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/stats"
+	"github.com/thoughtgears/shared-services/internal/tenant"
+)
+
+// TenantSettingsService loads the runtime configuration of the tenant in the context.
+// Settings are cached, so changes made through Update reach other instances within the cache TTL.
+type TenantSettingsService interface {
+	Get(ctx context.Context) (*models.TenantSettings, error)
+	Update(ctx context.Context, settings *models.TenantSettings) (*models.TenantSettings, error)
+}
+
+type cachedTenantSettings struct {
+	settings *models.TenantSettings
+	loadedAt time.Time
+}
+
+// tenantSettingsService is the concrete implementation of TenantSettingsService.
+type tenantSettingsService struct {
+	db    db.DB[models.TenantSettings]
+	ttl   time.Duration
+	audit *audit.Recorder
+
+	mu    sync.RWMutex
+	cache map[string]cachedTenantSettings
+}
+
+// NewTenantSettingsService creates a TenantSettingsService backed by the tenant_settings collection,
+// with one document per tenant ID. The collection is shared by all tenants, so db must not be tenant scoped.
+// Settings are reloaded at most once per ttl.
+func NewTenantSettingsService(db db.DB[models.TenantSettings], ttl time.Duration, recorder *audit.Recorder) TenantSettingsService {
+	return &tenantSettingsService{
+		db:    db,
+		ttl:   ttl,
+		audit: recorder,
+		cache: make(map[string]cachedTenantSettings),
+	}
+}
+
+// settingsID returns the settings document ID for the tenant in ctx.
+func settingsID(ctx context.Context) string {
+	if id := tenant.From(ctx); id != "" {
+		return id
+	}
+
+	return models.DefaultTenantSettingsID
+}
+
+// Get returns the settings of the tenant in ctx. Tenants without a settings document get
+// empty settings, which apply no restrictions.
+func (s *tenantSettingsService) Get(ctx context.Context) (*models.TenantSettings, error) {
+	id := settingsID(ctx)
+
+	s.mu.RLock()
+	cached, ok := s.cache[id]
+	s.mu.RUnlock()
+	if ok && time.Since(cached.loadedAt) < s.ttl {
+		stats.CacheHit("tenant_settings")
+
+		return cached.settings, nil
+	}
+	stats.CacheMiss("tenant_settings")
+
+	// Queried rather than fetched by ID, so a tenant without settings is not an error
+	results, _, err := s.db.Find(ctx, db.Query[models.TenantSettings]().Where("id", db.Eq, id).Limit(1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tenant settings: %w", err)
+	}
+
+	settings := &models.TenantSettings{ID: id}
+	if len(results) > 0 {
+		settings = results[0]
+	}
+
+	s.mu.Lock()
+	s.cache[id] = cachedTenantSettings{settings: settings, loadedAt: time.Now()}
+	s.mu.Unlock()
+
+	return settings, nil
+}
+
+// Update replaces the settings of the tenant in ctx.
+func (s *tenantSettingsService) Update(ctx context.Context, settings *models.TenantSettings) (*models.TenantSettings, error) {
+	if settings == nil {
+		return nil, fmt.Errorf("settings cannot be nil")
+	}
+
+	if err := settings.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid tenant settings: %w", err)
+	}
+
+	id := settingsID(ctx)
+	data := map[string]interface{}{
+		"id":                     id,
+		"allowed_document_types": settings.AllowedDocumentTypes,
+		"max_documents_per_user": settings.MaxDocumentsPerUser,
+		"retention_days":         settings.RetentionDays,
+		"webhook_urls":           settings.WebhookURLs,
+		"updated_at":             firestore.ServerTimestamp,
+	}
+
+	var (
+		updated *models.TenantSettings
+		err     error
+	)
+	if _, getErr := s.db.GetByID(ctx, id); getErr != nil {
+		updated, err = s.db.Create(ctx, id, data)
+	} else {
+		updated, err = s.db.Update(ctx, id, data)
+	}
+	s.audit.Record(ctx, audit.ActionTenantSettingsUpdate, audit.Target{Type: "tenant_settings", ID: id}, err, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update tenant settings: %w", err)
+	}
+
+	s.mu.Lock()
+	s.cache[id] = cachedTenantSettings{settings: updated, loadedAt: time.Now()}
+	s.mu.Unlock()
+
+	return updated, nil
+}
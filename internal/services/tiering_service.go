@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/audit"
+	"github.com/thoughtgears/shared-services/internal/db"
+	"github.com/thoughtgears/shared-services/internal/gcs"
+	"github.com/thoughtgears/shared-services/internal/models"
+)
+
+// tieringPageSize is how many candidate documents are read per query.
+const tieringPageSize = 100
+
+// TieringService moves the content of documents nobody touched for a while to a colder, cheaper
+// storage class, and back when it is requested again.
+type TieringService interface {
+	// Run moves the content of documents untouched for the configured age to the cold storage class.
+	Run(ctx context.Context) (*TieringResult, error)
+	// Restore moves the content of a cold document back to STANDARD in the background, the
+	// document is marked restoring until it is done. Cold content can be read meanwhile.
+	Restore(ctx context.Context, document *models.Document)
+}
+
+// TieringConfig configures the tiering of documents.
+type TieringConfig struct {
+	// After is how long a document has to be untouched, neither updated nor restored, to be tiered.
+	After time.Duration
+	// StorageClass is the class tiered content is moved to, e.g. gcs.StorageClassColdline.
+	StorageClass string
+	// MaxDocuments is how many documents a run moves at most, so a run fits in a request.
+	MaxDocuments int
+}
+
+// TieringResult reports what a tiering run did.
+type TieringResult struct {
+	Tiered int `json:"tiered"`
+	Failed int `json:"failed"`
+	// More is set when the run stopped at MaxDocuments, another run continues where it stopped.
+	More bool `json:"more"`
+}
+
+// tieringService is the concrete implementation of TieringService.
+type tieringService struct {
+	storage *RegionalStorage
+	db      db.DB[models.Document]
+	cfg     TieringConfig
+	audit   *audit.Recorder
+}
+
+// NewTieringService creates a new instance of TieringService, moving the content of documents in
+// the regional storage and recording the class on the documents in db.
+func NewTieringService(storage *RegionalStorage, db db.DB[models.Document], cfg TieringConfig, recorder *audit.Recorder) TieringService {
+	return &tieringService{
+		storage: storage,
+		db:      db,
+		cfg:     cfg,
+		audit:   recorder,
+	}
+}
+
+// Run tiers the documents of the tenant in the context, oldest first. Documents are read in pages
+// by last update; those already tiered or restored since the cutoff are skipped, as are documents
+// under legal hold, whose objects cannot be rewritten. A document that fails to move is logged and
+// counted, it is retried by the next run.
+func (t *tieringService) Run(ctx context.Context) (*TieringResult, error) {
+	cutoff := time.Now().Add(-t.cfg.After)
+	result := &TieringResult{}
+
+	pageToken := ""
+	for {
+		query := db.Query[models.Document]().
+			Where("updated_at", db.Lt, cutoff).
+			OrderBy("updated_at", db.Asc).
+			Limit(tieringPageSize).
+			After(pageToken)
+
+		documents, next, err := t.db.Find(ctx, query)
+		if err != nil {
+			return result, fmt.Errorf("failed to find documents to tier: %w", err)
+		}
+
+		for _, document := range documents {
+			if document.StorageClass == t.cfg.StorageClass || document.Restoring || document.LegalHold != nil {
+				continue
+			}
+			if document.RestoredAt != nil && document.RestoredAt.After(cutoff) {
+				continue
+			}
+
+			if result.Tiered+result.Failed >= t.cfg.MaxDocuments {
+				result.More = true
+
+				return result, nil
+			}
+
+			if err := t.tier(ctx, document); err != nil {
+				log.Error().Err(err).Str("document_id", document.ID).Msg("Failed to tier document")
+				result.Failed++
+
+				continue
+			}
+			result.Tiered++
+		}
+
+		if next == "" {
+			return result, nil
+		}
+		pageToken = next
+	}
+}
+
+// tier moves the content of a document and its preserved versions to the cold storage class.
+func (t *tieringService) tier(ctx context.Context, document *models.Document) error {
+	err := t.setStorageClass(ctx, document, t.cfg.StorageClass, map[string]interface{}{
+		"storage_class": t.cfg.StorageClass,
+	})
+	t.audit.Record(ctx, audit.ActionDocumentTier, documentTarget(document.ID), err, map[string]string{
+		"storage_class": t.cfg.StorageClass,
+	})
+
+	return err
+}
+
+// Restore moves the content of a cold document back to STANDARD. It keeps running after the
+// request that asked for the document is done. Documents under legal hold stay cold until the hold
+// is released, their content can be read meanwhile.
+func (t *tieringService) Restore(ctx context.Context, document *models.Document) {
+	if document.StorageClass == "" || document.Restoring || document.LegalHold != nil {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	if _, err := t.db.Update(ctx, document.ID, map[string]interface{}{"restoring": true}); err != nil {
+		log.Error().Err(err).Str("document_id", document.ID).Msg("Failed to mark document restoring")
+
+		return
+	}
+
+	go func() {
+		err := t.setStorageClass(ctx, document, gcs.StorageClassStandard, map[string]interface{}{
+			"storage_class": firestore.Delete,
+			"restoring":     firestore.Delete,
+			"restored_at":   time.Now().UTC(),
+		})
+		t.audit.Record(ctx, audit.ActionDocumentRestore, documentTarget(document.ID), err, map[string]string{
+			"storage_class": document.StorageClass,
+		})
+		if err == nil {
+			return
+		}
+
+		log.Error().Err(err).Str("document_id", document.ID).Msg("Failed to restore document")
+		// The next request retries the restore
+		if _, err := t.db.Update(ctx, document.ID, map[string]interface{}{"restoring": firestore.Delete}); err != nil {
+			log.Error().Err(err).Str("document_id", document.ID).Msg("Failed to clear document restoring")
+		}
+	}()
+}
+
+// setStorageClass moves every content path of a document to class, then applies update to it.
+func (t *tieringService) setStorageClass(ctx context.Context, document *models.Document, class string, update map[string]interface{}) error {
+	storage, _, err := t.storage.For(document.Region)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range document.ContentPaths() {
+		if err := storage.SetStorageClass(ctx, path, class); err != nil {
+			return fmt.Errorf("failed to move %s to %s: %w", path, class, err)
+		}
+	}
+
+	if _, err := t.db.Update(ctx, document.ID, update); err != nil {
+		return fmt.Errorf("failed to record storage class: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+
+	"github.com/thoughtgears/shared-services/internal/audit"
+	"github.com/thoughtgears/shared-services/internal/db"
+	"github.com/thoughtgears/shared-services/internal/identity"
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/tenant"
+)
+
+// Custom claim names managed by UserClaimsService. adminClaim must match caller.AdminClaim,
+// which grants the admin routes.
+const (
+	rolesClaim  = "roles"
+	adminClaim  = "admin"
+	tenantClaim = "tenant"
+)
+
+// UserClaimsService manages the Firebase custom claims of users.
+type UserClaimsService interface {
+	SetClaims(ctx context.Context, userID string, claims models.UserClaims) (*models.User, error)
+}
+
+// userClaimsService is the concrete implementation of UserClaimsService.
+type userClaimsService struct {
+	identity  identity.Provider
+	users     UserService
+	datastore db.DB[models.User]
+	audit     *audit.Recorder
+}
+
+// NewUserClaimsService creates a new instance of userClaimsService.
+func NewUserClaimsService(
+	provider identity.Provider,
+	users UserService,
+	datastore db.DB[models.User],
+	recorder *audit.Recorder,
+) UserClaimsService {
+	return &userClaimsService{
+		identity:  provider,
+		users:     users,
+		datastore: datastore,
+		audit:     recorder,
+	}
+}
+
+// SetClaims replaces the managed custom claims of the user with Firebase ID userID, keeping any
+// other claims of the account. The admin role also sets the admin claim. The user's refresh
+// tokens are revoked, so the user's sessions pick up the claims within the lifetime of their
+// current ID token, and the claims are stored on the user.
+//
+// The account is updated before the user record, so a failed call can be retried until both agree.
+// With multi-tenancy the tenant claim can only be the tenant of the request.
+func (s *userClaimsService) SetClaims(ctx context.Context, userID string, claims models.UserClaims) (*models.User, error) {
+	if err := claims.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid claims: %w", err)
+	}
+	if claims.Tenant != "" {
+		verr := &models.ValidationError{}
+		if err := tenant.Validate(claims.Tenant); err != nil {
+			verr.Add("tenant", "must be a valid tenant ID")
+		} else if scope := tenant.From(ctx); scope != "" && claims.Tenant != scope {
+			verr.Add("tenant", "must be the tenant of the request")
+		}
+		if err := verr.Err(); err != nil {
+			return nil, fmt.Errorf("invalid claims: %w", err)
+		}
+	}
+
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	details := map[string]string{
+		"roles":  strings.Join(roleNames(claims.Roles), ","),
+		"tenant": claims.Tenant,
+	}
+	updatedUser, err := s.setClaims(ctx, user, claims)
+	s.audit.Record(ctx, audit.ActionUserClaimsUpdate, userTarget(user.ID), err, details)
+	if err != nil {
+		recordFailure(ctx, "user.claims_update")
+		return nil, err
+	}
+
+	return updatedUser, nil
+}
+
+// setClaims writes the claims to the Firebase account and the user record.
+func (s *userClaimsService) setClaims(ctx context.Context, user *models.User, claims models.UserClaims) (*models.User, error) {
+	customClaims, err := s.identity.CustomClaims(ctx, user.FirebaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(customClaims, rolesClaim)
+	delete(customClaims, adminClaim)
+	delete(customClaims, tenantClaim)
+	if len(claims.Roles) > 0 {
+		customClaims[rolesClaim] = roleNames(claims.Roles)
+	}
+	if slices.Contains(claims.Roles, models.PlatformRoleAdmin) {
+		customClaims[adminClaim] = true
+	}
+	if claims.Tenant != "" {
+		customClaims[tenantClaim] = claims.Tenant
+	}
+
+	if err := s.identity.SetCustomClaims(ctx, user.FirebaseID, customClaims); err != nil {
+		return nil, err
+	}
+	if err := s.identity.RevokeSessions(ctx, user.FirebaseID); err != nil {
+		return nil, err
+	}
+
+	// Updates merge into the stored claims, so removed claims are deleted explicitly
+	var stored interface{} = firestore.Delete
+	if len(claims.Roles) > 0 || claims.Tenant != "" {
+		stored = map[string]interface{}{
+			"roles":  valueOrDelete(roleNames(claims.Roles), len(claims.Roles) > 0),
+			"tenant": valueOrDelete(claims.Tenant, claims.Tenant != ""),
+		}
+	}
+	updatedUser, err := s.datastore.Update(ctx, user.ID, map[string]interface{}{
+		"claims":     stored,
+		"updated_at": firestore.ServerTimestamp,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user claims: %w", err)
+	}
+
+	return updatedUser, nil
+}
+
+// valueOrDelete returns value when set is true and the Firestore delete sentinel otherwise.
+func valueOrDelete(value interface{}, set bool) interface{} {
+	if !set {
+		return firestore.Delete
+	}
+
+	return value
+}
+
+// roleNames returns the names of the roles.
+func roleNames(roles []models.PlatformRole) []string {
+	names := make([]string, len(roles))
+	for i, role := range roles {
+		names[i] = string(role)
+	}
+
+	return names
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+
+	"github.com/thoughtgears/shared-services/internal/audit"
+	"github.com/thoughtgears/shared-services/internal/db"
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/pkg/apperr"
+)
+
+// UserMergeService merges duplicate user accounts, e.g. a user who signed up once with Google
+// and once with email and password.
+type UserMergeService interface {
+	Merge(ctx context.Context, targetID string, sourceID string) (*models.User, error)
+}
+
+// userMergeService is the concrete implementation of UserMergeService.
+type userMergeService struct {
+	users     db.DB[models.User]
+	documents db.DB[models.Document]
+	bundles   db.DB[models.Bundle]
+	audit     *audit.Recorder
+}
+
+// NewUserMergeService creates a new instance of userMergeService.
+// It needs the dbs of every record owned by a user that is moved to the surviving account.
+func NewUserMergeService(
+	users db.DB[models.User],
+	documents db.DB[models.Document],
+	bundles db.DB[models.Bundle],
+	recorder *audit.Recorder,
+) UserMergeService {
+	return &userMergeService{
+		users:     users,
+		documents: documents,
+		bundles:   bundles,
+		audit:     recorder,
+	}
+}
+
+// Merge merges the user with Firebase ID sourceID into the user with Firebase ID targetID.
+// The documents and bundles of the source are reassigned to the target, the target records the
+// source's Firebase IDs and the source is soft-deleted, pointing to the target, so signing in
+// with either Firebase account resolves to the target user.
+//
+// Audit events cannot be rewritten, so the merge is recorded on both users instead: together with
+// the merged Firebase IDs it links the audit history of the source to the target. Organization
+// memberships are kept by Firebase ID and are not moved.
+//
+// Records are reassigned before the source is marked as merged, so a merge that failed halfway
+// can be retried.
+func (m *userMergeService) Merge(ctx context.Context, targetID string, sourceID string) (*models.User, error) {
+	verr := &models.ValidationError{}
+	if targetID == sourceID {
+		verr.Add("source_id", "must be a different user than the target")
+
+		return nil, fmt.Errorf("invalid merge: %w", verr)
+	}
+
+	target, err := m.getByFirebaseID(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	source, err := m.getByFirebaseID(ctx, sourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if target.MergedInto != "" {
+		verr.Add("id", "has been merged into another user")
+	}
+	if source.MergedInto != "" {
+		verr.Add("source_id", "has already been merged into another user")
+	}
+	if err := verr.Err(); err != nil {
+		return nil, fmt.Errorf("invalid merge: %w", err)
+	}
+
+	documents, err := reassign(ctx, m.documents, source.FirebaseID, target.FirebaseID, func(d *models.Document) string { return d.ID })
+	if err != nil {
+		return nil, fmt.Errorf("failed to reassign documents: %w", err)
+	}
+	bundles, err := reassign(ctx, m.bundles, source.FirebaseID, target.FirebaseID, func(b *models.Bundle) string { return b.ID })
+	if err != nil {
+		return nil, fmt.Errorf("failed to reassign bundles: %w", err)
+	}
+
+	// Users merged into the source earlier now resolve to the target directly
+	earlier, _, err := m.users.Find(ctx, db.Query[models.User]().Where("merged_into", db.Eq, source.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users merged into the source: %w", err)
+	}
+	for _, user := range earlier {
+		if _, err := m.users.Update(ctx, user.ID, map[string]interface{}{
+			"merged_into": target.ID,
+			"updated_at":  firestore.ServerTimestamp,
+		}); err != nil {
+			return nil, fmt.Errorf("failed to repoint merged user: %w", err)
+		}
+	}
+
+	mergedIDs := []interface{}{source.FirebaseID}
+	for _, id := range source.MergedFirebaseIDs {
+		mergedIDs = append(mergedIDs, id)
+	}
+	details := map[string]string{
+		"source_user_id":     source.ID,
+		"source_firebase_id": source.FirebaseID,
+		"target_user_id":     target.ID,
+		"target_firebase_id": target.FirebaseID,
+		"documents":          fmt.Sprint(documents),
+		"bundles":            fmt.Sprint(bundles),
+	}
+
+	updatedTarget, err := m.users.Update(ctx, target.ID, map[string]interface{}{
+		"merged_firebase_ids": db.ArrayUnion(mergedIDs...),
+		"updated_at":          firestore.ServerTimestamp,
+	})
+	if err == nil {
+		_, err = m.users.Update(ctx, source.ID, map[string]interface{}{
+			"merged_into": target.ID,
+			"deleted_at":  time.Now().UTC(),
+			"updated_at":  firestore.ServerTimestamp,
+		})
+	}
+	m.audit.Record(ctx, audit.ActionUserMerge, userTarget(target.ID), err, details)
+	m.audit.Record(ctx, audit.ActionUserMerge, userTarget(source.ID), err, details)
+	if err != nil {
+		recordFailure(ctx, "user.merge")
+		return nil, fmt.Errorf("failed to merge users: %w", err)
+	}
+
+	return updatedTarget, nil
+}
+
+// getByFirebaseID returns the user record with the Firebase ID, without resolving merged users.
+func (m *userMergeService) getByFirebaseID(ctx context.Context, firebaseID string) (*models.User, error) {
+	users, _, err := m.users.Find(ctx, db.Query[models.User]().Where("firebase_id", db.Eq, firebaseID).Limit(1))
+	if err != nil {
+		return nil, fmt.Errorf("error getting user by ID: %w", err)
+	}
+
+	if len(users) == 0 {
+		return nil, apperr.Errorf(apperr.NotFound, "user %s not found", firebaseID)
+	}
+
+	return users[0], nil
+}
+
+// reassign moves every record of a collection owned by the user fromID to the user toID
+// and returns how many were moved.
+func reassign[T any](ctx context.Context, datastore db.DB[T], fromID string, toID string, idOf func(*T) string) (int, error) {
+	records, _, err := datastore.Find(ctx, db.Query[T]().Where("user_id", db.Eq, fromID))
+	if err != nil {
+		return 0, err
+	}
+
+	for i, record := range records {
+		if _, err := datastore.Update(ctx, idOf(record), map[string]interface{}{
+			"user_id":    toID,
+			"updated_at": firestore.ServerTimestamp,
+		}); err != nil {
+			return i, err
+		}
+	}
+
+	return len(records), nil
+}
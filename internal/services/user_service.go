@@ -2,15 +2,21 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 
 	"cloud.google.com/go/firestore"
 	"github.com/google/uuid"
 
+	"github.com/thoughtgears/shared-services/internal/address"
+	"github.com/thoughtgears/shared-services/internal/audit"
 	"github.com/thoughtgears/shared-services/internal/db"
 	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/phone"
+	"github.com/thoughtgears/shared-services/pkg/apperr"
 )
 
 // UserService handles operations specific to users.
@@ -25,7 +31,10 @@ type UserService interface {
 // It uses a generic repository to perform CRUD operations on talent data.
 // The repository is expected to be initialized with a specific data type (models.User).
 type userService struct {
-	datastore db.DB[models.User]
+	datastore          db.DB[models.User]
+	addressValidator   address.Validator
+	defaultPhoneRegion string
+	audit              *audit.Recorder
 }
 
 // NewUserService creates a new instance of userService.
@@ -35,12 +44,18 @@ type userService struct {
 //
 // Parameters:
 //   - datastore: DB for user data
+//   - addressValidator: Validator used to normalize addresses, nil disables address validation
+//   - defaultPhoneRegion: Region used for national phone numbers when the user has no address country
+//   - recorder: Audit recorder for mutating operations, nil disables auditing
 //
 // Returns:
 //   - UserService: Instance of userService
-func NewUserService(datastore db.DB[models.User]) UserService {
+func NewUserService(datastore db.DB[models.User], addressValidator address.Validator, defaultPhoneRegion string, recorder *audit.Recorder) UserService {
 	return &userService{
-		datastore: datastore,
+		datastore:          datastore,
+		addressValidator:   addressValidator,
+		defaultPhoneRegion: defaultPhoneRegion,
+		audit:              recorder,
 	}
 }
 
@@ -49,20 +64,23 @@ func NewUserService(datastore db.DB[models.User]) UserService {
 // This method is used to fetch user details.
 // It is typically called when a user needs to be displayed.
 func (u *userService) GetByID(ctx context.Context, id string) (*models.User, error) {
-	query := []db.QueryConstraint{
-		{
-			Path:  "firebase_id",
-			Op:    db.QueryOperatorEqual,
-			Value: id,
-		},
-	}
-	user, _, err := u.datastore.GetByQuery(ctx, query, "", 1)
+	user, _, err := u.datastore.Find(ctx, db.Query[models.User]().Where("firebase_id", db.Eq, id).Limit(1))
 	if err != nil {
 		return nil, fmt.Errorf("error getting talent by ID: %w", err)
 	}
 
 	if len(user) == 0 {
-		return nil, fmt.Errorf("user not found")
+		return nil, apperr.New(apperr.NotFound, "user not found")
+	}
+
+	// The Firebase ID of a duplicate account resolves to the user it was merged into
+	if user[0].MergedInto != "" {
+		survivor, err := u.datastore.GetByID(ctx, user[0].MergedInto)
+		if err != nil {
+			return nil, fmt.Errorf("error getting merged user: %w", err)
+		}
+
+		return survivor, nil
 	}
 
 	return user[0], nil
@@ -77,26 +95,41 @@ func (u *userService) Create(ctx context.Context, user *models.User) (*models.Us
 		return nil, fmt.Errorf("user cannot be nil")
 	}
 
+	if err := user.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid user: %w", err)
+	}
+
+	normalizedAddress, err := u.validateAddress(ctx, user.Address)
+	if err != nil {
+		return nil, err
+	}
+	user.Address = normalizedAddress
+
+	if err := normalizePhone(user, u.defaultPhoneRegion); err != nil {
+		return nil, err
+	}
+
 	user.ID = uuid.NewString()
 	userData := map[string]interface{}{
-		"id":          user.ID,
-		"first_name":  user.FirstName,
-		"last_name":   user.LastName,
-		"email":       user.Email,
-		"phone":       user.Phone,
-		"firebase_id": user.FirebaseID,
-		"address": map[string]interface{}{
-			"building_number": user.Address.BuildingNumber,
-			"street":          user.Address.Street,
-			"city":            user.Address.City,
-			"postcode":        user.Address.PostCode,
-			"country":         user.Address.Country,
-		},
-		"created_at": firestore.ServerTimestamp,
-		"updated_at": firestore.ServerTimestamp,
+		"id":                 user.ID,
+		"first_name":         user.FirstName,
+		"last_name":          user.LastName,
+		"email":              user.Email,
+		"phone":              user.Phone,
+		"firebase_id":        user.FirebaseID,
+		"locale":             user.Locale,
+		"timezone":           user.Timezone,
+		"preferred_language": user.PreferredLanguage,
+		"address":            addressData(user.Address),
+		"created_at":         firestore.ServerTimestamp,
+		"updated_at":         firestore.ServerTimestamp,
+	}
+	if len(user.NotificationPreferences) > 0 {
+		userData["notification_preferences"] = user.NotificationPreferences
 	}
 
 	createdUser, err := u.datastore.Create(ctx, user.ID, userData)
+	u.audit.Record(ctx, audit.ActionUserCreate, userTarget(user.ID), err, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error creating user: %w", err)
 	}
@@ -114,14 +147,47 @@ func (u *userService) Update(ctx context.Context, id string, user *models.User)
 	}
 
 	updates := buildUpdateMapFromUser(user)
+	for _, field := range protectedFields {
+		delete(updates, field)
+	}
 
 	if len(updates) == 0 {
 		return currentUserData, nil
 	}
 
+	// Validate the user as it will look after the update, so partial updates
+	// are checked against the stored values for the fields they do not touch.
+	merged := *currentUserData
+	applyUserUpdates(&merged, user)
+	if err := merged.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid user: %w", err)
+	}
+
+	// The whole address is rewritten when any part changes, since normalization
+	// can touch fields the caller did not send.
+	if _, ok := updates["address"]; ok {
+		normalizedAddress, err := u.validateAddress(ctx, merged.Address)
+		if err != nil {
+			return nil, err
+		}
+		updates["address"] = addressData(normalizedAddress)
+		merged.Address = normalizedAddress
+	}
+
+	if _, ok := updates["phone"]; ok {
+		if err := normalizePhone(&merged, u.defaultPhoneRegion); err != nil {
+			return nil, err
+		}
+		updates["phone"] = merged.Phone
+		updates["phone_region"] = merged.PhoneRegion
+	}
+
 	updates["updated_at"] = firestore.ServerTimestamp
 
 	updatedUser, err := u.datastore.Update(ctx, id, updates)
+	u.audit.Record(ctx, audit.ActionUserUpdate, userTarget(id), err, map[string]string{
+		"fields": strings.Join(updatedFields(updates), ","),
+	})
 	if err != nil {
 		return nil, fmt.Errorf("error updating user: %w", err)
 	}
@@ -129,6 +195,30 @@ func (u *userService) Update(ctx context.Context, id string, user *models.User)
 	return updatedUser, nil
 }
 
+// protectedFields are only written by the operations that own them, such as merging accounts,
+// uploading an avatar, setting claims or migrating the schema, users cannot set them.
+var protectedFields = []string{"merged_into", "merged_firebase_ids", "deleted_at", "avatar", "claims", "schema_version"}
+
+// userTarget returns the audit target for a user.
+func userTarget(id string) audit.Target {
+	return audit.Target{Type: "user", ID: id}
+}
+
+// updatedFields returns the sorted top level field names of an update map,
+// so audit events show what changed without recording the values themselves.
+func updatedFields(updates map[string]interface{}) []string {
+	fields := make([]string, 0, len(updates))
+	for field := range updates {
+		if field == "updated_at" {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	return fields
+}
+
 // buildUpdateMapFromUser creates a map of fields to update from a User object
 func buildUpdateMapFromUser(user *models.User) map[string]interface{} {
 	if user == nil {
@@ -192,6 +282,109 @@ func buildUpdateMapFromUser(user *models.User) map[string]interface{} {
 	return updates
 }
 
+// validateAddress normalizes the address with the configured validator.
+// It returns the address unchanged when address validation is disabled.
+func (u *userService) validateAddress(ctx context.Context, addr models.Address) (models.Address, error) {
+	if u.addressValidator == nil {
+		return addr, nil
+	}
+
+	normalized, err := u.addressValidator.Validate(ctx, addr)
+	if err != nil {
+		var verr *models.ValidationError
+		if errors.As(err, &verr) {
+			return addr, fmt.Errorf("invalid user: %w", err)
+		}
+
+		return addr, fmt.Errorf("error validating address: %w", err)
+	}
+
+	return normalized, nil
+}
+
+// normalizePhone rewrites the user's phone number to E.164 and records the detected region.
+// National numbers are interpreted using the address country, or defaultRegion if the address has none.
+// An empty phone number is left untouched.
+func normalizePhone(user *models.User, defaultRegion string) error {
+	if user.Phone == "" {
+		return nil
+	}
+
+	region := user.Address.Country
+	if region == "" {
+		region = defaultRegion
+	}
+
+	number, err := phone.Parse(user.Phone, region)
+	if err != nil {
+		verr := &models.ValidationError{}
+		verr.Add("phone", err.Error())
+
+		return fmt.Errorf("invalid user: %w", verr)
+	}
+
+	user.Phone = number.E164
+	user.PhoneRegion = number.Region
+
+	return nil
+}
+
+// addressData converts an address to the map stored in Firestore.
+func addressData(addr models.Address) map[string]interface{} {
+	data := map[string]interface{}{
+		"building_number": addr.BuildingNumber,
+		"street":          addr.Street,
+		"city":            addr.City,
+		"postcode":        addr.PostCode,
+		"country":         addr.Country,
+	}
+
+	if addr.Coordinates != nil {
+		data["coordinates"] = map[string]interface{}{
+			"latitude":  addr.Coordinates.Latitude,
+			"longitude": addr.Coordinates.Longitude,
+		}
+	}
+
+	return data
+}
+
+// applyUserUpdates copies every non-zero field from src onto dst, mirroring the
+// fields buildUpdateMapFromUser would send to the database.
+func applyUserUpdates(dst, src *models.User) {
+	if src == nil {
+		return
+	}
+
+	applyNonZeroFields(reflect.ValueOf(dst).Elem(), reflect.ValueOf(src).Elem())
+}
+
+// applyNonZeroFields recursively copies non-zero fields from src to dst.
+func applyNonZeroFields(dst, src reflect.Value) {
+	for i := 0; i < src.NumField(); i++ {
+		field := src.Field(i)
+		if !field.CanInterface() {
+			continue
+		}
+
+		if field.Kind() == reflect.Struct {
+			if field.Type().String() == "time.Time" {
+				continue
+			}
+
+			applyNonZeroFields(dst.Field(i), field)
+
+			continue
+		}
+
+		if isZeroValue(field) {
+			continue
+		}
+
+		dst.Field(i).Set(field)
+	}
+}
+
 // buildNestedUpdateMap handles nested structures
 func buildNestedUpdateMap(obj interface{}) map[string]interface{} {
 	updates := make(map[string]interface{})
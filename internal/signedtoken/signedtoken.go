@@ -0,0 +1,84 @@
+// Package signedtoken creates short, URL safe tokens that carry a subject and an expiry time,
+// signed with HMAC-SHA256 so they can be handed to users (e.g. in invitation emails) and
+// verified later without storing the token itself.
+package signedtoken
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalid is returned for tokens that are malformed or have a bad signature.
+	ErrInvalid = errors.New("invalid token")
+	// ErrExpired is returned for correctly signed tokens past their expiry time.
+	ErrExpired = errors.New("token expired")
+)
+
+// Signer signs and verifies tokens with a secret key.
+type Signer struct {
+	key []byte
+}
+
+// NewSigner creates a Signer. The key should be at least 32 random bytes.
+func NewSigner(key []byte) (*Signer, error) {
+	if len(key) < 32 {
+		return nil, errors.New("signing key must be at least 32 bytes")
+	}
+
+	return &Signer{key: key}, nil
+}
+
+// Sign returns a token for subject that is valid until expires.
+// The subject is readable by anyone holding the token, it must not be secret.
+func (s *Signer) Sign(subject string, expires time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(subject + "|" + strconv.FormatInt(expires.Unix(), 10)))
+
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.mac(payload))
+}
+
+// Verify checks the token signature and expiry and returns its subject.
+func (s *Signer) Verify(token string) (string, error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", ErrInvalid
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, s.mac(payload)) {
+		return "", ErrInvalid
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", ErrInvalid
+	}
+
+	subject, expiry, ok := strings.Cut(string(decoded), "|")
+	if !ok {
+		return "", ErrInvalid
+	}
+
+	unix, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return "", ErrInvalid
+	}
+
+	if time.Now().After(time.Unix(unix, 0)) {
+		return "", ErrExpired
+	}
+
+	return subject, nil
+}
+
+func (s *Signer) mac(payload string) []byte {
+	h := hmac.New(sha256.New, s.key)
+	h.Write([]byte(payload))
+
+	return h.Sum(nil)
+}
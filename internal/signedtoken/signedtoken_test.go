@@ -0,0 +1,66 @@
+package signedtoken
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testSigner(t *testing.T, b byte) *Signer {
+	t.Helper()
+
+	s, err := NewSigner(bytes.Repeat([]byte{b}, 32))
+	if err != nil {
+		t.Fatalf("NewSigner() error = %v", err)
+	}
+
+	return s
+}
+
+func TestNewSignerRejectsShortKeys(t *testing.T) {
+	if _, err := NewSigner(make([]byte, 31)); err == nil {
+		t.Error("NewSigner() with a 31 byte key succeeded, want an error")
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	s := testSigner(t, 1)
+
+	token := s.Sign("invitation-1", time.Now().Add(time.Hour))
+	subject, err := s.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if subject != "invitation-1" {
+		t.Errorf("Verify() = %q, want %q", subject, "invitation-1")
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	s := testSigner(t, 1)
+	token := s.Sign("user-1", time.Now().Add(time.Hour))
+	payload, signature, _ := strings.Cut(token, ".")
+
+	tests := map[string]struct {
+		token string
+		want  error
+	}{
+		"expired":        {s.Sign("user-1", time.Now().Add(-time.Second)), ErrExpired},
+		"other key":      {testSigner(t, 2).Sign("user-1", time.Now().Add(time.Hour)), ErrInvalid},
+		"other payload":  {s.Sign("user-2", time.Now().Add(time.Hour))[:len(payload)] + "." + signature, ErrInvalid},
+		"no signature":   {payload, ErrInvalid},
+		"bad signature":  {payload + ".!!!", ErrInvalid},
+		"empty":          {"", ErrInvalid},
+		"unsigned junk":  {"a.b", ErrInvalid},
+		"trailing bytes": {token + "x", ErrInvalid},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := s.Verify(tt.token); !errors.Is(err, tt.want) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
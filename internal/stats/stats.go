@@ -0,0 +1,209 @@
+// Package stats keeps a summary of live process state, such as cache hit rates, in-flight work and
+// recent errors, for quick triage of a single instance without a full observability stack.
+// It complements the OpenTelemetry metrics, which are aggregated over all instances.
+package stats
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// ErrorWindow is how far back recent errors are counted.
+const ErrorWindow = 15 * time.Minute
+
+// CacheStats are the lookups served by a cache since the process started.
+type CacheStats struct {
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+}
+
+// RuntimeStats describe the Go runtime of the process.
+type RuntimeStats struct {
+	Goroutines int    `json:"goroutines"`
+	HeapAlloc  uint64 `json:"heap_alloc_bytes"`
+	HeapSys    uint64 `json:"heap_sys_bytes"`
+	NumGC      uint32 `json:"num_gc"`
+}
+
+// ErrorStats count the errors of the last ErrorWindow by code, e.g. the HTTP status.
+type ErrorStats struct {
+	Window string           `json:"window"`
+	Codes  map[string]int64 `json:"codes"`
+}
+
+// Snapshot is the state of the process at a point in time.
+type Snapshot struct {
+	StartedAt    time.Time             `json:"started_at"`
+	Uptime       string                `json:"uptime"`
+	Runtime      RuntimeStats          `json:"runtime"`
+	Caches       map[string]CacheStats `json:"caches"`
+	InFlight     map[string]int64      `json:"in_flight"`
+	Queues       map[string]int        `json:"queues"`
+	Counters     map[string]int64      `json:"counters"`
+	RecentErrors ErrorStats            `json:"recent_errors"`
+}
+
+// errorBucket counts the errors of one minute.
+type errorBucket struct {
+	minute int64
+	codes  map[string]int64
+}
+
+type registry struct {
+	startedAt time.Time
+
+	mu       sync.Mutex
+	caches   map[string]*CacheStats
+	inFlight map[string]int64
+	queues   map[string]func() int
+	counters map[string]int64
+	errors   [int(ErrorWindow / time.Minute)]errorBucket
+}
+
+var std = &registry{
+	startedAt: time.Now(),
+	caches:    make(map[string]*CacheStats),
+	inFlight:  make(map[string]int64),
+	queues:    make(map[string]func() int),
+	counters:  make(map[string]int64),
+}
+
+// CacheHit records a lookup served from the named cache.
+func CacheHit(cache string) {
+	std.mu.Lock()
+	defer std.mu.Unlock()
+
+	std.cache(cache).Hits++
+}
+
+// CacheMiss records a lookup the named cache could not serve.
+func CacheMiss(cache string) {
+	std.mu.Lock()
+	defer std.mu.Unlock()
+
+	std.cache(cache).Misses++
+}
+
+// cache returns the stats of the named cache. The caller must hold r.mu.
+func (r *registry) cache(name string) *CacheStats {
+	stats, ok := r.caches[name]
+	if !ok {
+		stats = &CacheStats{}
+		r.caches[name] = stats
+	}
+
+	return stats
+}
+
+// Start records the start of in-flight work of the given kind, e.g. an upload,
+// and returns the function to call when it is done.
+func Start(kind string) func() {
+	std.mu.Lock()
+	std.inFlight[kind]++
+	std.mu.Unlock()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			std.mu.Lock()
+			std.inFlight[kind]--
+			std.mu.Unlock()
+		})
+	}
+}
+
+// RegisterQueue reports the depth of a worker pool or queue, which is read on every snapshot.
+// Registering a name again replaces the earlier function.
+func RegisterQueue(name string, depth func() int) {
+	std.mu.Lock()
+	defer std.mu.Unlock()
+
+	std.queues[name] = depth
+}
+
+// Add adds delta to the named counter, e.g. requests rejected by a rate limiter.
+func Add(counter string, delta int64) {
+	std.mu.Lock()
+	defer std.mu.Unlock()
+
+	std.counters[counter] += delta
+}
+
+// RecordError counts an error with the given code towards the recent errors.
+func RecordError(code string) {
+	minute := time.Now().Unix() / 60
+
+	std.mu.Lock()
+	defer std.mu.Unlock()
+
+	bucket := &std.errors[minute%int64(len(std.errors))]
+	if bucket.minute != minute {
+		bucket.minute = minute
+		bucket.codes = make(map[string]int64)
+	}
+	bucket.codes[code]++
+}
+
+// Get returns a snapshot of the current process state.
+func Get() Snapshot {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+
+	snapshot := Snapshot{
+		StartedAt: std.startedAt,
+		Uptime:    time.Since(std.startedAt).Round(time.Second).String(),
+		Runtime: RuntimeStats{
+			Goroutines: runtime.NumGoroutine(),
+			HeapAlloc:  memStats.HeapAlloc,
+			HeapSys:    memStats.HeapSys,
+			NumGC:      memStats.NumGC,
+		},
+		Caches:   make(map[string]CacheStats),
+		InFlight: make(map[string]int64),
+		Queues:   make(map[string]int),
+		Counters: make(map[string]int64),
+		RecentErrors: ErrorStats{
+			Window: ErrorWindow.String(),
+			Codes:  make(map[string]int64),
+		},
+	}
+
+	std.mu.Lock()
+	for name, cache := range std.caches {
+		stats := *cache
+		if total := stats.Hits + stats.Misses; total > 0 {
+			stats.HitRate = float64(stats.Hits) / float64(total)
+		}
+		snapshot.Caches[name] = stats
+	}
+	for kind, n := range std.inFlight {
+		snapshot.InFlight[kind] = n
+	}
+	for name, n := range std.counters {
+		snapshot.Counters[name] = n
+	}
+	queues := make(map[string]func() int, len(std.queues))
+	for name, depth := range std.queues {
+		queues[name] = depth
+	}
+	oldest := time.Now().Unix()/60 - int64(len(std.errors)) + 1
+	for _, bucket := range std.errors {
+		if bucket.minute < oldest {
+			continue
+		}
+		for code, n := range bucket.codes {
+			snapshot.RecentErrors.Codes[code] += n
+		}
+	}
+	std.mu.Unlock()
+
+	// Queue depths are read without holding the lock, as they may take locks of their own
+	for name, depth := range queues {
+		snapshot.Queues[name] = depth()
+	}
+
+	return snapshot
+}
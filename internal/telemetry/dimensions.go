@@ -0,0 +1,92 @@
+package telemetry
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/thoughtgears/shared-services/internal/tenant"
+)
+
+// Cohorts are coarse groups of callers used as a metric dimension instead of user IDs,
+// which would make the number of time series unbounded.
+const (
+	CohortAnonymous = "anonymous"
+	CohortUser      = "user"
+	CohortAdmin     = "admin"
+	CohortSystem    = "system"
+	CohortService   = "service"
+)
+
+// OverflowValue replaces attribute values once a dimension has reached its cardinality limit.
+const OverflowValue = "other"
+
+// DefaultMaxTenants is the default number of distinct tenants labelled on metrics.
+const DefaultMaxTenants = 200
+
+type cohortKey struct{}
+
+// WithCohort returns a copy of ctx carrying the caller's cohort. It is set by the authentication middleware.
+func WithCohort(ctx context.Context, cohort string) context.Context {
+	return context.WithValue(ctx, cohortKey{}, cohort)
+}
+
+// CohortFrom returns the caller's cohort, or CohortSystem for work not started by a request.
+func CohortFrom(ctx context.Context) string {
+	if cohort, ok := ctx.Value(cohortKey{}).(string); ok {
+		return cohort
+	}
+
+	return CohortSystem
+}
+
+// cardinalityGuard passes through the first max distinct values it sees and maps every
+// later value to OverflowValue, so a growing number of tenants cannot blow up metric cardinality.
+type cardinalityGuard struct {
+	mu   sync.Mutex
+	max  int
+	seen map[string]struct{}
+}
+
+func (g *cardinalityGuard) value(v string) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.seen[v]; ok {
+		return v
+	}
+	if len(g.seen) >= g.max {
+		return OverflowValue
+	}
+	g.seen[v] = struct{}{}
+
+	return v
+}
+
+var tenantGuard = &cardinalityGuard{max: DefaultMaxTenants, seen: make(map[string]struct{})}
+
+// SetMaxTenants sets how many distinct tenants are labelled on metrics before the rest are
+// reported as OverflowValue. It must be called on startup, before metrics are recorded.
+func SetMaxTenants(n int) {
+	tenantGuard.mu.Lock()
+	defer tenantGuard.mu.Unlock()
+
+	tenantGuard.max = n
+}
+
+// Dimensions returns the tenant and cohort attributes for the caller in ctx.
+// Requests without a tenant are labelled "none".
+func Dimensions(ctx context.Context) []attribute.KeyValue {
+	id := tenant.From(ctx)
+	if id == "" {
+		id = "none"
+	} else {
+		id = tenantGuard.value(id)
+	}
+
+	return []attribute.KeyValue{
+		attribute.String("tenant", id),
+		attribute.String("cohort", CohortFrom(ctx)),
+	}
+}
@@ -0,0 +1,157 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/oauth"
+)
+
+// Exporter names a backend spans are sent to.
+type Exporter string
+
+const (
+	// ExporterOTLP sends spans to the OpenTelemetry collector at Otel.Endpoint.
+	ExporterOTLP Exporter = "otlp"
+	// ExporterStdout writes spans as JSON lines to stdout, for local debugging without a collector.
+	ExporterStdout Exporter = "stdout"
+	// ExporterCloudTrace sends spans directly to Cloud Trace through the Telemetry API, which needs Otel.ProjectID.
+	ExporterCloudTrace Exporter = "cloudtrace"
+)
+
+// cloudTraceEndpoint is the OTLP endpoint of the Google Cloud Telemetry API.
+const cloudTraceEndpoint = "telemetry.googleapis.com:443"
+
+// ParseExporters parses the exporter pipeline, e.g. "otlp,cloudtrace". An empty pipeline exports
+// to stdout in local mode and to the collector otherwise.
+func ParseExporters(names []string, local bool) ([]Exporter, error) {
+	if len(names) == 0 {
+		if local {
+			return []Exporter{ExporterStdout}, nil
+		}
+
+		return []Exporter{ExporterOTLP}, nil
+	}
+
+	exporters := make([]Exporter, 0, len(names))
+	for _, name := range names {
+		exporter := Exporter(strings.ToLower(strings.TrimSpace(name)))
+		switch exporter {
+		case ExporterOTLP, ExporterStdout, ExporterCloudTrace:
+			exporters = append(exporters, exporter)
+		default:
+			return nil, fmt.Errorf("unknown trace exporter %q, expected otlp, stdout or cloudtrace", name)
+		}
+	}
+
+	return exporters, nil
+}
+
+// newSpanExporter creates the span exporter for a pipeline entry.
+func (o *Otel) newSpanExporter(ctx context.Context, exporter Exporter) (sdktrace.SpanExporter, error) {
+	switch exporter {
+	case ExporterOTLP:
+		clientOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(o.Endpoint)}
+		if o.Insecure {
+			clientOpts = append(clientOpts, otlptracegrpc.WithInsecure())
+		} else {
+			clientOpts = append(clientOpts, otlptracegrpc.WithTLSCredentials(credentials.NewClientTLSFromCert(nil, "")))
+		}
+
+		return otlptrace.New(ctx, otlptracegrpc.NewClient(clientOpts...))
+	case ExporterStdout:
+		return newStdoutExporter(os.Stdout), nil
+	case ExporterCloudTrace:
+		if o.ProjectID == "" {
+			return nil, fmt.Errorf("the cloudtrace exporter needs a project ID")
+		}
+
+		creds, err := oauth.NewApplicationDefault(ctx, "https://www.googleapis.com/auth/trace.append")
+		if err != nil {
+			return nil, fmt.Errorf("failed to find credentials for Cloud Trace: %w", err)
+		}
+
+		return otlptrace.New(ctx, otlptracegrpc.NewClient(
+			otlptracegrpc.WithEndpoint(cloudTraceEndpoint),
+			otlptracegrpc.WithTLSCredentials(credentials.NewClientTLSFromCert(nil, "")),
+			otlptracegrpc.WithDialOption(grpc.WithPerRPCCredentials(creds)),
+			otlptracegrpc.WithHeaders(map[string]string{"x-goog-user-project": o.ProjectID}),
+		))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", exporter)
+	}
+}
+
+// stdoutSpan is the JSON line written for each span by the stdout exporter.
+type stdoutSpan struct {
+	Name         string         `json:"name"`
+	TraceID      string         `json:"trace_id"`
+	SpanID       string         `json:"span_id"`
+	ParentSpanID string         `json:"parent_span_id,omitempty"`
+	Kind         string         `json:"kind"`
+	Start        time.Time      `json:"start"`
+	Duration     string         `json:"duration"`
+	Status       string         `json:"status"`
+	Description  string         `json:"description,omitempty"`
+	Attributes   map[string]any `json:"attributes,omitempty"`
+}
+
+// stdoutExporter writes finished spans as JSON lines.
+type stdoutExporter struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+func newStdoutExporter(w io.Writer) *stdoutExporter {
+	return &stdoutExporter{encoder: json.NewEncoder(w)}
+}
+
+// ExportSpans implements sdktrace.SpanExporter.
+func (e *stdoutExporter) ExportSpans(_ context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, span := range spans {
+		line := stdoutSpan{
+			Name:        span.Name(),
+			TraceID:     span.SpanContext().TraceID().String(),
+			SpanID:      span.SpanContext().SpanID().String(),
+			Kind:        span.SpanKind().String(),
+			Start:       span.StartTime(),
+			Duration:    span.EndTime().Sub(span.StartTime()).String(),
+			Status:      span.Status().Code.String(),
+			Description: span.Status().Description,
+		}
+		if span.Parent().IsValid() {
+			line.ParentSpanID = span.Parent().SpanID().String()
+		}
+		if attrs := span.Attributes(); len(attrs) > 0 {
+			line.Attributes = make(map[string]any, len(attrs))
+			for _, attr := range attrs {
+				line.Attributes[string(attr.Key)] = attr.Value.AsInterface()
+			}
+		}
+
+		if err := e.encoder.Encode(line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Shutdown implements sdktrace.SpanExporter.
+func (e *stdoutExporter) Shutdown(context.Context) error {
+	return nil
+}
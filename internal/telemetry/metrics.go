@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"log"
 
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
 	"go.opentelemetry.io/otel/metric"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"google.golang.org/grpc/credentials"
 )
 
 var counter metric.Int64Counter // nolint:unused
@@ -19,17 +21,21 @@ func (o *Otel) InitCounter(ctx context.Context) func(context.Context) error {
 		resource.Default(),
 		resource.NewWithAttributes(
 			resource.Default().SchemaURL(),
-			semconv.ServiceName(o.ServiceName),
+			append(buildAttributes(), semconv.ServiceName(o.ServiceName))...,
 		),
 	)
 	if err != nil {
 		log.Fatalf("Error creating resource: %v", err)
 	}
 
-	exporter, err := otlpmetricgrpc.New(ctx,
-		otlpmetricgrpc.WithInsecure(),
-		otlpmetricgrpc.WithEndpoint(o.Endpoint),
-	)
+	exporterOpts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(o.Endpoint)}
+	if o.Insecure {
+		exporterOpts = append(exporterOpts, otlpmetricgrpc.WithInsecure())
+	} else {
+		exporterOpts = append(exporterOpts, otlpmetricgrpc.WithTLSCredentials(credentials.NewClientTLSFromCert(nil, "")))
+	}
+
+	exporter, err := otlpmetricgrpc.New(ctx, exporterOpts...)
 	if err != nil {
 		log.Fatalf("Error creating exporter: %s", err)
 	}
@@ -39,6 +45,9 @@ func (o *Otel) InitCounter(ctx context.Context) func(context.Context) error {
 		sdkmetric.WithResource(r),
 	)
 
+	// Register the provider globally so instruments created with otel.Meter elsewhere are exported
+	otel.SetMeterProvider(provider)
+
 	meter := provider.Meter(fmt.Sprintf("%s/%s", o.DomainName, o.ServiceName))
 	counter, err = meter.Int64Counter(fmt.Sprintf("%s/%s/requests", o.DomainName, o.ServiceName))
 	if err != nil {
@@ -1,15 +1,54 @@
 package telemetry
 
+import (
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+
+	"github.com/thoughtgears/shared-services/internal/buildinfo"
+)
+
 type Otel struct {
 	ServiceName string
 	DomainName  string
 	Endpoint    string
+	// Insecure disables TLS to the collector. It is only safe for a sidecar collector on localhost;
+	// exporting to a remote or private endpoint needs TLS.
+	Insecure bool
+	// Sampler decides which traces are recorded, all traces are sampled when it is nil.
+	Sampler sdktrace.Sampler
+	// Exporters are the backends spans are sent to, the collector at Endpoint when empty.
+	Exporters []Exporter
+	// ProjectID is the Google Cloud project spans are written to by ExporterCloudTrace.
+	ProjectID string
 }
 
-func NewTelemetry(serviceName, domainName, endpoint string) *Otel {
+func NewTelemetry(serviceName, domainName, endpoint string, insecure bool) *Otel {
 	return &Otel{
 		ServiceName: serviceName,
 		DomainName:  domainName,
 		Endpoint:    endpoint,
+		Insecure:    insecure,
+	}
+}
+
+// buildAttributes returns the resource attributes identifying the running build.
+func buildAttributes() []attribute.KeyValue {
+	info := buildinfo.Get()
+
+	return []attribute.KeyValue{
+		semconv.ServiceVersion(info.Version),
+		attribute.String("service.commit", info.Commit),
+		attribute.String("service.build_time", info.BuildTime),
 	}
 }
+
+// projectAttributes returns the resource attribute the Telemetry API uses to pick the project
+// spans are stored in, or nothing when the project is unknown.
+func projectAttributes(projectID string) []attribute.KeyValue {
+	if projectID == "" {
+		return nil
+	}
+
+	return []attribute.KeyValue{semconv.CloudAccountID(projectID), attribute.String("gcp.project_id", projectID)}
+}
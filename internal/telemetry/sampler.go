@@ -0,0 +1,77 @@
+package telemetry
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// ParseRouteSampleRates parses "METHOD /route=rate" pairs, e.g. "POST /v1/users=1".
+// The route is the gin route template, so path parameters are written as ":id".
+func ParseRouteSampleRates(pairs []string) (map[string]float64, error) {
+	rates := make(map[string]float64, len(pairs))
+	for _, pair := range pairs {
+		route, rawRate, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid route sample rate %q, expected METHOD /route=rate", pair)
+		}
+
+		rate, err := strconv.ParseFloat(rawRate, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("invalid sample rate for %s: %q", route, rawRate)
+		}
+		rates[strings.TrimSpace(route)] = rate
+	}
+
+	return rates, nil
+}
+
+// routeSampler samples server spans at the rate configured for their route, and everything else
+// at the default rate. Routes are matched on the method and route attributes set by otelgin.
+type routeSampler struct {
+	fallback sdktrace.Sampler
+	routes   map[string]sdktrace.Sampler
+}
+
+// NewRouteSampler creates a sampler that samples root spans of the routes in rates at their own rate,
+// e.g. to keep every trace of low-volume critical routes, and other root spans at defaultRate.
+// Child spans follow the decision of their parent, so traces are never partially sampled.
+func NewRouteSampler(defaultRate float64, rates map[string]float64) sdktrace.Sampler {
+	routes := make(map[string]sdktrace.Sampler, len(rates))
+	for route, rate := range rates {
+		routes[route] = sdktrace.TraceIDRatioBased(rate)
+	}
+
+	return sdktrace.ParentBased(&routeSampler{
+		fallback: sdktrace.TraceIDRatioBased(defaultRate),
+		routes:   routes,
+	})
+}
+
+// ShouldSample implements sdktrace.Sampler.
+func (s *routeSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	var method, route string
+	for _, attr := range p.Attributes {
+		switch attr.Key {
+		// otelgin uses either semantic convention version depending on OTEL_SEMCONV_STABILITY_OPT_IN
+		case attribute.Key("http.request.method"), attribute.Key("http.method"):
+			method = attr.Value.AsString()
+		case attribute.Key("http.route"):
+			route = attr.Value.AsString()
+		}
+	}
+
+	if sampler, ok := s.routes[method+" "+route]; ok {
+		return sampler.ShouldSample(p)
+	}
+
+	return s.fallback.ShouldSample(p)
+}
+
+// Description implements sdktrace.Sampler.
+func (s *routeSampler) Description() string {
+	return fmt.Sprintf("RouteSampler{default:%s,routes:%d}", s.fallback.Description(), len(s.routes))
+}
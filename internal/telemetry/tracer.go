@@ -5,8 +5,6 @@ import (
 	"log"
 
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
@@ -15,34 +13,50 @@ import (
 func (o *Otel) InitTracer(ctx context.Context) func(context.Context) error {
 	resources, err := resource.New(
 		ctx,
+		resource.WithAttributes(buildAttributes()...),
 		resource.WithAttributes(
 			semconv.ServiceName(o.ServiceName),
 			semconv.OTelScopeName(semconv.TelemetrySDKLanguageGo.Value.AsString()),
 			semconv.OTelScopeVersion("1.24.2"),
 		),
+		resource.WithAttributes(projectAttributes(o.ProjectID)...),
 	)
 	if err != nil {
 		log.Printf("Could not set resources: %v", err)
 	}
 
-	exporter, err := otlptrace.New(
-		ctx,
-		otlptracegrpc.NewClient(
-			otlptracegrpc.WithInsecure(),
-			otlptracegrpc.WithEndpoint(o.Endpoint),
-		),
-	)
-	if err != nil {
-		log.Printf("Failed to create trace exporter: %v", err)
+	exporters := o.Exporters
+	if len(exporters) == 0 {
+		exporters = []Exporter{ExporterOTLP}
 	}
 
-	otel.SetTracerProvider(
-		sdktrace.NewTracerProvider(
-			sdktrace.WithSampler(sdktrace.AlwaysSample()),
-			sdktrace.WithBatcher(exporter),
-			sdktrace.WithResource(resources),
-		),
-	)
+	sampler := o.Sampler
+	if sampler == nil {
+		sampler = sdktrace.AlwaysSample()
+	}
+
+	providerOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sampler),
+		sdktrace.WithResource(resources),
+	}
+	for _, name := range exporters {
+		exporter, err := o.newSpanExporter(ctx, name)
+		if err != nil {
+			log.Printf("Failed to create %s trace exporter: %v", name, err)
+			continue
+		}
+
+		// Local debugging output should show up as soon as a span ends, not when the next batch is sent
+		if name == ExporterStdout {
+			providerOpts = append(providerOpts, sdktrace.WithSyncer(exporter))
+		} else {
+			providerOpts = append(providerOpts, sdktrace.WithBatcher(exporter))
+		}
+	}
+
+	provider := sdktrace.NewTracerProvider(providerOpts...)
+	otel.SetTracerProvider(provider)
 
-	return exporter.Shutdown
+	// Shutting down the provider flushes and shuts down every exporter in the pipeline
+	return provider.Shutdown
 }
@@ -0,0 +1,52 @@
+// Package tenant carries the tenant of a request through the context, so the data layer
+// can keep each customer organisation's data apart in a shared deployment.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"regexp"
+)
+
+// ErrMissing is returned when tenant scoped data is accessed without a tenant in the context.
+var ErrMissing = errors.New("no tenant in context")
+
+// ErrInvalidID is returned for tenant IDs that are not safe to use in collection and object paths.
+var ErrInvalidID = errors.New("invalid tenant ID")
+
+// validID matches Identity Platform tenant IDs and other simple slugs.
+var validID = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9-]{0,62}$`)
+
+type contextKey struct{}
+
+// Validate checks that id can be used to scope collections and object paths.
+func Validate(id string) error {
+	if !validID.MatchString(id) {
+		return ErrInvalidID
+	}
+
+	return nil
+}
+
+// With returns a copy of ctx carrying the tenant ID.
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// From returns the tenant ID stored in ctx, or an empty string if there is none.
+func From(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+
+	return id
+}
+
+// PathPrefix returns the object path prefix for the tenant in ctx, e.g. "tenants/acme/",
+// or an empty string when there is no tenant.
+func PathPrefix(ctx context.Context) string {
+	id := From(ctx)
+	if id == "" {
+		return ""
+	}
+
+	return "tenants/" + id + "/"
+}
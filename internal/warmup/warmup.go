@@ -0,0 +1,92 @@
+// Package warmup prepares the clients of a cold instance before it gets real traffic. Clients
+// connect and fetch credentials on first use; a warmer makes that first use, so the GET /warmup
+// route, called by the Cloud Run startup probe, takes the latency instead of a user's request.
+package warmup
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/thoughtgears/shared-services/internal/lazy"
+	"github.com/thoughtgears/shared-services/internal/redact"
+)
+
+// Func prepares one client, e.g. by making a cheap call that opens its connection.
+type Func func(ctx context.Context) error
+
+// Result is the outcome of one warmer.
+type Result struct {
+	Name string `json:"name"`
+	// Duration is how long the warmer took, zero when it had already succeeded before.
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+type warmer struct {
+	name string
+	done *lazy.Value[struct{}]
+}
+
+var (
+	mu      sync.RWMutex
+	warmers []warmer
+)
+
+// Register adds a warmer run by Run. A warmer that succeeded is not run again, one that failed is
+// retried by the next Run.
+func Register(name string, fn Func) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	warmers = append(warmers, warmer{
+		name: name,
+		done: lazy.New(func(ctx context.Context) (struct{}, error) {
+			return struct{}{}, fn(ctx)
+		}),
+	})
+}
+
+// Run runs the registered warmers concurrently and waits for them. It returns their results in
+// the order they were registered and whether all of them succeeded.
+func Run(ctx context.Context) ([]Result, bool) {
+	mu.RLock()
+	registered := append([]warmer(nil), warmers...)
+	mu.RUnlock()
+
+	results := make([]Result, len(registered))
+	var wg sync.WaitGroup
+	for i, w := range registered {
+		results[i].Name = w.name
+		if w.done.Ready() {
+			results[i].Duration = "0s"
+
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			start := time.Now()
+			_, err := w.done.Get(ctx)
+			results[i].Duration = time.Since(start).Round(time.Millisecond).String()
+			if err != nil {
+				log.Error().Err(err).Str("client", w.name).Msg("Failed to warm up client")
+				results[i].Error = redact.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	ok := true
+	for _, result := range results {
+		if result.Error != "" {
+			ok = false
+		}
+	}
+
+	return results, ok
+}
@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/thoughtgears/shared-services/internal/db"
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/pkg/apperr"
+)
+
+// NonceStore remembers the nonces of received deliveries.
+type NonceStore interface {
+	// Claim records the nonce of a source until expireAt. It reports false when the nonce is
+	// already recorded and has not expired.
+	Claim(ctx context.Context, source string, nonce string, expireAt time.Time) (bool, error)
+	// Release forgets a claimed nonce, so the delivery is accepted again.
+	Release(ctx context.Context, source string, nonce string) error
+}
+
+// dbNonceStore keeps nonces in a repository, deleted by its TTL policy once expired.
+type dbNonceStore struct {
+	db db.DB[models.WebhookNonce]
+}
+
+// NewDBNonceStore creates a NonceStore keeping nonces in db.
+func NewDBNonceStore(db db.DB[models.WebhookNonce]) NonceStore {
+	return &dbNonceStore{db: db}
+}
+
+// Claim creates the nonce record, which fails when it already exists, so of concurrent claims
+// only one succeeds.
+func (s *dbNonceStore) Claim(ctx context.Context, source string, nonce string, expireAt time.Time) (bool, error) {
+	id := nonceID(source, nonce)
+	data := db.SetExpireAt(map[string]interface{}{
+		"id":         id,
+		"source":     source,
+		"created_at": firestore.ServerTimestamp,
+	}, expireAt)
+
+	write, err := s.db.PrepareCreate(ctx, id, data)
+	if err != nil {
+		return false, fmt.Errorf("failed to prepare nonce: %w", err)
+	}
+	err = db.Commit(ctx, write)
+	if err == nil {
+		return true, nil
+	}
+	if !apperr.Is(err, apperr.Conflict) && !mongo.IsDuplicateKeyError(err) {
+		return false, err
+	}
+
+	// The TTL policy deletes expired records with a delay, until then they are free to claim
+	existing, err := s.db.GetByID(ctx, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to get nonce: %w", err)
+	}
+	if existing.ExpireAt == nil || existing.ExpireAt.After(time.Now()) {
+		return false, nil
+	}
+	if _, err := s.db.Create(ctx, id, data); err != nil {
+		return false, fmt.Errorf("failed to record nonce: %w", err)
+	}
+
+	return true, nil
+}
+
+// Release deletes the nonce record.
+func (s *dbNonceStore) Release(ctx context.Context, source string, nonce string) error {
+	if err := s.db.Delete(ctx, nonceID(source, nonce)); err != nil {
+		return fmt.Errorf("failed to delete nonce: %w", err)
+	}
+
+	return nil
+}
+
+// nonceID returns the ID of the nonce record. Nonces are chosen by providers and may contain
+// characters not allowed in IDs, so they are hashed.
+func nonceID(source string, nonce string) string {
+	sum := sha256.Sum256([]byte(source + ":" + nonce))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,179 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thoughtgears/shared-services/internal/idtoken"
+)
+
+// Headers of deliveries signed by HMACVerifier.
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+	NonceHeader     = "X-Webhook-Nonce"
+)
+
+// HMACVerifier verifies deliveries of providers sharing a secret with us. The sender puts the unix
+// time in X-Webhook-Timestamp, a unique ID in X-Webhook-Nonce and the hex HMAC-SHA256 of
+// "<timestamp>.<nonce>.<body>" in X-Webhook-Signature, optionally prefixed with "sha256=".
+type HMACVerifier struct {
+	secret []byte
+}
+
+// NewHMACVerifier creates an HMACVerifier for deliveries signed with secret.
+func NewHMACVerifier(secret []byte) *HMACVerifier {
+	return &HMACVerifier{secret: secret}
+}
+
+// Verify implements Verifier.
+func (v *HMACVerifier) Verify(_ context.Context, header http.Header, body []byte) (*Delivery, error) {
+	timestamp := header.Get(TimestampHeader)
+	nonce := header.Get(NonceHeader)
+	signature := strings.TrimPrefix(header.Get(SignatureHeader), "sha256=")
+	if timestamp == "" || nonce == "" || signature == "" {
+		return nil, fmt.Errorf("%w: missing %s, %s or %s", ErrInvalidSignature, SignatureHeader, TimestampHeader, NonceHeader)
+	}
+
+	signedAt, err := parseUnix(timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	if !validMAC(v.secret, []byte(timestamp+"."+nonce+"."), body, signature) {
+		return nil, ErrInvalidSignature
+	}
+
+	return &Delivery{Nonce: nonce, SignedAt: signedAt}, nil
+}
+
+// StripeSignatureHeader carries the signatures of Stripe deliveries.
+const StripeSignatureHeader = "Stripe-Signature"
+
+// StripeVerifier verifies deliveries of Stripe webhook endpoints, following
+// https://docs.stripe.com/webhooks#verify-manually. The nonce is the ID of the event, so the
+// retries Stripe sends of an event that was handled are rejected as replays.
+type StripeVerifier struct {
+	secret []byte
+}
+
+// NewStripeVerifier creates a StripeVerifier for the signing secret of an endpoint, whsec_...
+func NewStripeVerifier(secret string) *StripeVerifier {
+	return &StripeVerifier{secret: []byte(secret)}
+}
+
+// Verify implements Verifier. Stripe sends several v1 signatures while a secret is rolled, one
+// of them has to match.
+func (v *StripeVerifier) Verify(_ context.Context, header http.Header, body []byte) (*Delivery, error) {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header.Get(StripeSignatureHeader), ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return nil, fmt.Errorf("%w: malformed %s", ErrInvalidSignature, StripeSignatureHeader)
+	}
+
+	signedAt, err := parseUnix(timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	valid := false
+	for _, signature := range signatures {
+		if validMAC(v.secret, []byte(timestamp+"."), body, signature) {
+			valid = true
+
+			break
+		}
+	}
+	if !valid {
+		return nil, ErrInvalidSignature
+	}
+
+	var event struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(body, &event); err != nil || event.ID == "" {
+		return nil, fmt.Errorf("%w: event without an ID", ErrInvalidSignature)
+	}
+
+	return &Delivery{Nonce: event.ID, SignedAt: signedAt}, nil
+}
+
+// PubSubVerifier verifies Pub/Sub push deliveries, such as Cloud Storage notifications. Pub/Sub
+// signs each push with a Google ID token of the subscription's service account. The nonce is the
+// message ID; Pub/Sub delivers at least once, so handlers stay idempotent for redeliveries after
+// the replay window.
+type PubSubVerifier struct {
+	tokens *idtoken.Verifier
+}
+
+// NewPubSubVerifier creates a PubSubVerifier accepting pushes with tokens tokens verifies, minted
+// for the push audience and the service account of the subscription.
+func NewPubSubVerifier(tokens *idtoken.Verifier) *PubSubVerifier {
+	return &PubSubVerifier{tokens: tokens}
+}
+
+// Verify implements Verifier. The delivery is signed when its ID token was issued, not when the
+// message was published. The token is valid for an hour, so checking its age against the replay
+// window keeps a captured push from being accepted again once its nonce has expired.
+func (v *PubSubVerifier) Verify(ctx context.Context, header http.Header, body []byte) (*Delivery, error) {
+	token, ok := strings.CutPrefix(header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return nil, fmt.Errorf("%w: missing bearer token", ErrInvalidSignature)
+	}
+	identity, err := v.tokens.Verify(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+	}
+
+	var push struct {
+		Message struct {
+			MessageID string `json:"messageId"`
+		} `json:"message"`
+	}
+	if err := json.Unmarshal(body, &push); err != nil || push.Message.MessageID == "" {
+		return nil, fmt.Errorf("%w: push without a message ID", ErrInvalidSignature)
+	}
+
+	return &Delivery{Nonce: push.Message.MessageID, SignedAt: identity.IssuedAt}, nil
+}
+
+// validMAC reports whether signature is the hex HMAC-SHA256 of prefix and body with secret.
+func validMAC(secret []byte, prefix []byte, body []byte, signature string) bool {
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(prefix)
+	mac.Write(body)
+
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// parseUnix parses a signing time in unix seconds.
+func parseUnix(timestamp string) (time.Time, error) {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid timestamp %q", ErrInvalidSignature, timestamp)
+	}
+
+	return time.Unix(seconds, 0), nil
+}
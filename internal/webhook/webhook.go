@@ -0,0 +1,144 @@
+// Package webhook receives webhooks from third parties. Each integration registers a source with
+// the Verifier of its provider and a Handler; the Receiver checks the signature, rejects
+// deliveries outside the replay window or seen before, and dispatches the rest to the handler,
+// so integrations only deal with verified, fresh events.
+//
+//	receiver.Register("stripe", webhook.NewStripeVerifier(secret), handleStripeEvent)
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// ErrUnknownSource is returned for deliveries to a source nothing is registered for.
+	ErrUnknownSource = errors.New("unknown webhook source")
+	// ErrInvalidSignature is returned for deliveries whose signature does not verify.
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	// ErrExpired is returned for deliveries signed outside the replay window.
+	ErrExpired = errors.New("webhook outside the replay window")
+	// ErrReplayed is returned for deliveries whose nonce has been seen before.
+	ErrReplayed = errors.New("webhook already received")
+)
+
+// Delivery is what a Verifier vouches for in a request.
+type Delivery struct {
+	// Nonce identifies the delivery, e.g. the event ID of the provider. A nonce is accepted once
+	// within the replay window.
+	Nonce string
+	// SignedAt is when the provider signed or sent the delivery.
+	SignedAt time.Time
+}
+
+// Verifier checks that a request comes from the provider of a source.
+type Verifier interface {
+	// Verify checks the signature of the request with the raw body and returns the delivery it
+	// vouches for. It fails with ErrInvalidSignature when the request was not signed by the provider.
+	Verify(ctx context.Context, header http.Header, body []byte) (*Delivery, error)
+}
+
+// Event is a verified delivery passed to the handler of its source.
+type Event struct {
+	Source   string
+	Nonce    string
+	SignedAt time.Time
+	Header   http.Header
+	// Body is the raw body, handlers decode the payload of their provider themselves.
+	Body []byte
+}
+
+// Handler processes the events of a source. An error fails the delivery, so the provider retries it.
+type Handler func(ctx context.Context, event *Event) error
+
+// source is a registered webhook source.
+type source struct {
+	verifier Verifier
+	handler  Handler
+}
+
+// Receiver verifies webhook deliveries and dispatches them to the handlers of their source.
+type Receiver struct {
+	nonces NonceStore
+	window time.Duration
+
+	mu      sync.RWMutex
+	sources map[string]source
+}
+
+// NewReceiver creates a Receiver accepting deliveries signed within window of their arrival,
+// either way to allow for clock skew. Nonces are remembered in nonces for the window.
+func NewReceiver(nonces NonceStore, window time.Duration) *Receiver {
+	return &Receiver{
+		nonces:  nonces,
+		window:  window,
+		sources: make(map[string]source),
+	}
+}
+
+// Register adds a source, deliveries to it are checked with verifier and passed to handler.
+// It panics when the source is already registered, like registering the same route twice.
+func (r *Receiver) Register(name string, verifier Verifier, handler Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.sources[name]; ok {
+		panic("webhook: source registered twice: " + name)
+	}
+	r.sources[name] = source{verifier: verifier, handler: handler}
+}
+
+// Receive verifies a delivery to a source and passes it to its handler. The nonce is claimed
+// before the handler runs so concurrent retries of the same delivery are handled once, and
+// released when the handler fails so the provider's next retry is handled again.
+func (r *Receiver) Receive(ctx context.Context, name string, header http.Header, body []byte) error {
+	r.mu.RLock()
+	src, ok := r.sources[name]
+	r.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownSource, name)
+	}
+
+	delivery, err := src.verifier.Verify(ctx, header, body)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if delivery.SignedAt.Before(now.Add(-r.window)) || delivery.SignedAt.After(now.Add(r.window)) {
+		return fmt.Errorf("%w: signed at %s", ErrExpired, delivery.SignedAt.UTC().Format(time.RFC3339))
+	}
+
+	// Nonces outlive the window on both sides of the signing time, so a replay is either
+	// expired or seen
+	claimed, err := r.nonces.Claim(ctx, name, delivery.Nonce, delivery.SignedAt.Add(r.window))
+	if err != nil {
+		return fmt.Errorf("failed to claim webhook nonce: %w", err)
+	}
+	if !claimed {
+		return fmt.Errorf("%w: %s", ErrReplayed, delivery.Nonce)
+	}
+
+	err = src.handler(ctx, &Event{
+		Source:   name,
+		Nonce:    delivery.Nonce,
+		SignedAt: delivery.SignedAt,
+		Header:   header,
+		Body:     body,
+	})
+	if err != nil {
+		if releaseErr := r.nonces.Release(ctx, name, delivery.Nonce); releaseErr != nil {
+			log.Error().Err(releaseErr).Str("source", name).Msg("Failed to release webhook nonce")
+		}
+
+		return fmt.Errorf("failed to handle %s webhook: %w", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryNonces is a NonceStore in memory.
+type memoryNonces struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+}
+
+func (m *memoryNonces) Claim(_ context.Context, source string, nonce string, expireAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.nonces == nil {
+		m.nonces = make(map[string]time.Time)
+	}
+	key := source + ":" + nonce
+	if expires, ok := m.nonces[key]; ok && expires.After(time.Now()) {
+		return false, nil
+	}
+	m.nonces[key] = expireAt
+
+	return true, nil
+}
+
+func (m *memoryNonces) Release(_ context.Context, source string, nonce string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.nonces, source+":"+nonce)
+
+	return nil
+}
+
+func sign(secret, message string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(message))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func hmacHeader(secret string, signedAt time.Time, nonce string, body string) http.Header {
+	timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+	header := http.Header{}
+	header.Set(TimestampHeader, timestamp)
+	header.Set(NonceHeader, nonce)
+	header.Set(SignatureHeader, "sha256="+sign(secret, timestamp+"."+nonce+"."+body))
+
+	return header
+}
+
+func TestHMACVerifier(t *testing.T) {
+	v := NewHMACVerifier([]byte("secret"))
+	body := `{"event":"created"}`
+	now := time.Now()
+
+	delivery, err := v.Verify(context.Background(), hmacHeader("secret", now, "n-1", body), []byte(body))
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if delivery.Nonce != "n-1" || delivery.SignedAt.Unix() != now.Unix() {
+		t.Errorf("Verify() = %+v, want nonce n-1 signed at %v", delivery, now)
+	}
+
+	tampered := hmacHeader("secret", now, "n-1", body)
+	tampered.Set(NonceHeader, "n-2")
+	tests := map[string]struct {
+		header http.Header
+		body   string
+	}{
+		"other secret":   {hmacHeader("other", now, "n-1", body), body},
+		"other body":     {hmacHeader("secret", now, "n-1", body), `{"event":"deleted"}`},
+		"other nonce":    {tampered, body},
+		"missing header": {http.Header{}, body},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := v.Verify(context.Background(), tt.header, []byte(tt.body)); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Verify() error = %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+}
+
+func TestStripeVerifier(t *testing.T) {
+	v := NewStripeVerifier("whsec_new")
+	body := `{"id":"evt_1","type":"invoice.paid"}`
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	// While a secret is rolled Stripe signs with both, one match is enough
+	header := http.Header{}
+	header.Set(StripeSignatureHeader, "t="+timestamp+",v1="+sign("whsec_old", timestamp+"."+body)+",v1="+sign("whsec_new", timestamp+"."+body))
+	delivery, err := v.Verify(context.Background(), header, []byte(body))
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if delivery.Nonce != "evt_1" {
+		t.Errorf("Verify() nonce = %q, want the event ID evt_1", delivery.Nonce)
+	}
+
+	header.Set(StripeSignatureHeader, "t="+timestamp+",v1="+sign("whsec_old", timestamp+"."+body))
+	if _, err := v.Verify(context.Background(), header, []byte(body)); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify() with another secret error = %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestReceiverRejectsReplays(t *testing.T) {
+	receiver := NewReceiver(&memoryNonces{}, 5*time.Minute)
+	var handled int
+	receiver.Register("partner", NewHMACVerifier([]byte("secret")), func(context.Context, *Event) error {
+		handled++
+
+		return nil
+	})
+
+	body := `{"event":"created"}`
+	header := hmacHeader("secret", time.Now(), "n-1", body)
+	if err := receiver.Receive(context.Background(), "partner", header, []byte(body)); err != nil {
+		t.Fatalf("Receive() error = %v", err)
+	}
+	if err := receiver.Receive(context.Background(), "partner", header, []byte(body)); !errors.Is(err, ErrReplayed) {
+		t.Errorf("Receive() of a replay error = %v, want ErrReplayed", err)
+	}
+
+	stale := hmacHeader("secret", time.Now().Add(-10*time.Minute), "n-2", body)
+	if err := receiver.Receive(context.Background(), "partner", stale, []byte(body)); !errors.Is(err, ErrExpired) {
+		t.Errorf("Receive() of a stale delivery error = %v, want ErrExpired", err)
+	}
+	future := hmacHeader("secret", time.Now().Add(10*time.Minute), "n-3", body)
+	if err := receiver.Receive(context.Background(), "partner", future, []byte(body)); !errors.Is(err, ErrExpired) {
+		t.Errorf("Receive() of a future delivery error = %v, want ErrExpired", err)
+	}
+
+	if err := receiver.Receive(context.Background(), "unknown", header, []byte(body)); !errors.Is(err, ErrUnknownSource) {
+		t.Errorf("Receive() to an unknown source error = %v, want ErrUnknownSource", err)
+	}
+	if handled != 1 {
+		t.Errorf("handled = %d, want 1", handled)
+	}
+}
+
+func TestReceiverReleasesFailedDeliveries(t *testing.T) {
+	receiver := NewReceiver(&memoryNonces{}, 5*time.Minute)
+	fail := true
+	receiver.Register("partner", NewHMACVerifier([]byte("secret")), func(context.Context, *Event) error {
+		if fail {
+			return errors.New("downstream unavailable")
+		}
+
+		return nil
+	})
+
+	body := `{"event":"created"}`
+	header := hmacHeader("secret", time.Now(), "n-1", body)
+	if err := receiver.Receive(context.Background(), "partner", header, []byte(body)); err == nil {
+		t.Fatal("Receive() with a failing handler succeeded")
+	}
+
+	// The provider's retry of the failed delivery is handled, not rejected as a replay
+	fail = false
+	if err := receiver.Receive(context.Background(), "partner", header, []byte(body)); err != nil {
+		t.Errorf("Receive() of the retry error = %v", err)
+	}
+}
@@ -0,0 +1,272 @@
+package webp
+
+import "math/bits"
+
+// Code length limits of the prefix codes and of the code used to write their code lengths.
+const (
+	maxCodeLength       = 15
+	maxCodeLengthLength = 7
+)
+
+// codeLengthOrder is the order the code lengths of the code length code are written in.
+var codeLengthOrder = [...]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// prefixCode is a canonical prefix code, with the codes bit-reversed for the LSB-first stream.
+// Symbols of a code with a single symbol are written with zero bits.
+type prefixCode struct {
+	codes   []uint32
+	lengths []int
+}
+
+// write writes the code of symbol.
+func (p prefixCode) write(b *bitWriter, symbol int) {
+	b.write(p.codes[symbol], p.lengths[symbol])
+}
+
+// writePrefixCode writes the prefix code for the symbol counts and returns it. Alphabets using at
+// most two symbols below 256 get a simple code, other alphabets a normal prefix code.
+func writePrefixCode(b *bitWriter, counts []int) prefixCode {
+	var used []int
+	for symbol, count := range counts {
+		if count > 0 {
+			used = append(used, symbol)
+		}
+	}
+
+	if len(used) <= 2 && (len(used) == 0 || used[len(used)-1] < 256) {
+		return writeSimpleCode(b, len(counts), used)
+	}
+
+	lengths := huffmanLengths(counts, maxCodeLength)
+	writeCodeLengths(b, lengths)
+
+	return canonicalCode(lengths)
+}
+
+// writeSimpleCode writes a simple code of one or two symbols. An alphabet without used symbols
+// is written as a code of symbol 0.
+func writeSimpleCode(b *bitWriter, alphabet int, used []int) prefixCode {
+	if len(used) == 0 {
+		used = []int{0}
+	}
+
+	b.writeBool(true)
+	b.write(uint32(len(used)-1), 1)
+	if used[0] < 2 {
+		b.writeBool(false)
+		b.write(uint32(used[0]), 1)
+	} else {
+		b.writeBool(true)
+		b.write(uint32(used[0]), 8)
+	}
+	if len(used) == 2 {
+		b.write(uint32(used[1]), 8)
+	}
+
+	lengths := make([]int, alphabet)
+	if len(used) == 2 {
+		lengths[used[0]], lengths[used[1]] = 1, 1
+	}
+
+	return canonicalCode(lengths)
+}
+
+// writeCodeLengths writes the code lengths of a normal prefix code, encoding runs of zeros with
+// the repeat symbols 17 and 18.
+func writeCodeLengths(b *bitWriter, lengths []int) {
+	type token struct {
+		symbol    int
+		extra     uint32
+		extraBits int
+	}
+
+	var tokens []token
+	for i := 0; i < len(lengths); {
+		run := 1
+		for i+run < len(lengths) && lengths[i+run] == lengths[i] {
+			run++
+		}
+
+		switch {
+		case lengths[i] == 0 && run >= 11:
+			run = min(run, 138)
+			tokens = append(tokens, token{symbol: 18, extra: uint32(run - 11), extraBits: 7})
+		case lengths[i] == 0 && run >= 3:
+			tokens = append(tokens, token{symbol: 17, extra: uint32(run - 3), extraBits: 3})
+		default:
+			run = 1
+			tokens = append(tokens, token{symbol: lengths[i]})
+		}
+		i += run
+	}
+
+	counts := make([]int, len(codeLengthOrder))
+	for _, t := range tokens {
+		counts[t.symbol]++
+	}
+	// A code of a single symbol is written with zero bits, which decoders only allow for the
+	// symbols of the image, so the code length code always gets a second symbol
+	if nonZero(counts) < 2 {
+		if counts[0] == 0 {
+			counts[0] = 1
+		} else {
+			counts[1] = 1
+		}
+	}
+	codeLengthLengths := huffmanLengths(counts, maxCodeLengthLength)
+
+	written := 4
+	for i, symbol := range codeLengthOrder {
+		if codeLengthLengths[symbol] > 0 {
+			written = max(written, i+1)
+		}
+	}
+
+	b.writeBool(false) // normal code
+	b.write(uint32(written-4), 4)
+	for _, symbol := range codeLengthOrder[:written] {
+		b.write(uint32(codeLengthLengths[symbol]), 3)
+	}
+	b.writeBool(false) // code lengths for the whole alphabet
+
+	code := canonicalCode(codeLengthLengths)
+	for _, t := range tokens {
+		code.write(b, t.symbol)
+		b.write(t.extra, t.extraBits)
+	}
+}
+
+// huffmanLengths returns the Huffman code lengths for the symbol counts, at most limit long.
+// Codes that are too long are flattened by raising the lowest counts until they fit.
+// At least two counts must be non-zero.
+func huffmanLengths(counts []int, limit int) []int {
+	type node struct {
+		weight  int
+		symbols []int
+	}
+
+	for floor := 1; ; floor *= 2 {
+		var nodes []node
+		for symbol, count := range counts {
+			if count > 0 {
+				nodes = append(nodes, node{weight: max(count, floor), symbols: []int{symbol}})
+			}
+		}
+
+		lengths := make([]int, len(counts))
+		for len(nodes) > 1 {
+			// Take the two lightest nodes and merge them, every symbol in them gets one bit longer
+			for i := 0; i < 2; i++ {
+				lightest := i
+				for j := i + 1; j < len(nodes); j++ {
+					if nodes[j].weight < nodes[lightest].weight {
+						lightest = j
+					}
+				}
+				nodes[i], nodes[lightest] = nodes[lightest], nodes[i]
+			}
+
+			merged := node{weight: nodes[0].weight + nodes[1].weight}
+			merged.symbols = append(append(merged.symbols, nodes[0].symbols...), nodes[1].symbols...)
+			for _, symbol := range merged.symbols {
+				lengths[symbol]++
+			}
+			nodes = append(nodes[2:], merged)
+		}
+
+		if maxLength(lengths) <= limit {
+			return lengths
+		}
+	}
+}
+
+// canonicalCode assigns the canonical codes to the code lengths.
+func canonicalCode(lengths []int) prefixCode {
+	var lengthCounts [maxCodeLength + 1]int
+	for _, length := range lengths {
+		lengthCounts[length]++
+	}
+	lengthCounts[0] = 0
+
+	var next [maxCodeLength + 1]uint32
+	code := uint32(0)
+	for length := 1; length <= maxCodeLength; length++ {
+		code = (code + uint32(lengthCounts[length-1])) << 1
+		next[length] = code
+	}
+
+	p := prefixCode{codes: make([]uint32, len(lengths)), lengths: make([]int, len(lengths))}
+	if nonZero(lengths) < 2 {
+		// A single symbol is written with zero bits
+		return p
+	}
+	for symbol, length := range lengths {
+		if length == 0 {
+			continue
+		}
+		p.codes[symbol] = bits.Reverse32(next[length]) >> (32 - length)
+		p.lengths[symbol] = length
+		next[length]++
+	}
+
+	return p
+}
+
+// nonZero returns the number of non-zero values.
+func nonZero(values []int) int {
+	n := 0
+	for _, v := range values {
+		if v != 0 {
+			n++
+		}
+	}
+
+	return n
+}
+
+// maxLength returns the largest code length.
+func maxLength(lengths []int) int {
+	longest := 0
+	for _, length := range lengths {
+		longest = max(longest, length)
+	}
+
+	return longest
+}
+
+// bitWriter packs values into bytes starting with the least significant bit.
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits int
+}
+
+// write writes the n low bits of v.
+func (b *bitWriter) write(v uint32, n int) {
+	b.acc |= uint64(v&(1<<n-1)) << b.nbits
+	b.nbits += n
+	for b.nbits >= 8 {
+		b.buf = append(b.buf, byte(b.acc))
+		b.acc >>= 8
+		b.nbits -= 8
+	}
+}
+
+// writeBool writes a single bit.
+func (b *bitWriter) writeBool(v bool) {
+	if v {
+		b.write(1, 1)
+	} else {
+		b.write(0, 1)
+	}
+}
+
+// bytes returns the written bytes, padding the last byte with zeros.
+func (b *bitWriter) bytes() []byte {
+	if b.nbits > 0 {
+		b.buf = append(b.buf, byte(b.acc))
+		b.acc, b.nbits = 0, 0
+	}
+
+	return b.buf
+}
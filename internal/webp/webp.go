@@ -0,0 +1,200 @@
+// Package webp writes lossless WebP images, e.g. the avatar variants of users. Only the features
+// of the format needed for a reasonable size are used: the subtract green and gradient predictor
+// transforms and a single set of prefix codes, without backward references or a color cache.
+package webp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+)
+
+// maxDimension is the largest width or height of a lossless WebP image.
+const maxDimension = 1 << 14
+
+// Alphabet sizes of the five prefix codes of an image stream. The green code also covers the
+// length prefixes of backward references, which are never written.
+const (
+	greenAlphabet    = 256 + 24
+	channelAlphabet  = 256
+	distanceAlphabet = 40
+)
+
+// predictorBits is the log2 size of the predictor blocks. Every block uses the same predictor,
+// so the largest blocks keep the predictor sub-image as small as possible.
+const predictorBits = 9
+
+// gradientPredictor is the predictor mode clamping L + T - TL, which works well for photos.
+const gradientPredictor = 12
+
+// Encode writes img to w as a lossless WebP image.
+func Encode(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width < 1 || height < 1 || width > maxDimension || height > maxDimension {
+		return fmt.Errorf("failed to encode WebP: invalid image size %dx%d", width, height)
+	}
+
+	pixels := make([]uint32, 0, width*height)
+	hasAlpha := false
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			pixel := argb(img, x, y)
+			hasAlpha = hasAlpha || pixel>>24 != 0xff
+			pixels = append(pixels, pixel)
+		}
+	}
+
+	b := &bitWriter{}
+	b.write(0x2f, 8)
+	b.write(uint32(width-1), 14)
+	b.write(uint32(height-1), 14)
+	b.writeBool(hasAlpha)
+	b.write(0, 3)
+
+	// Transforms are listed in the order they are applied and undone in reverse by decoders
+	b.writeBool(true)
+	b.write(2, 2) // subtract green
+	subtractGreen(pixels)
+
+	b.writeBool(true)
+	b.write(0, 2) // predictor
+	b.write(predictorBits-2, 3)
+	blocks := make([]uint32, blocksFor(width)*blocksFor(height))
+	for i := range blocks {
+		blocks[i] = gradientPredictor << 8
+	}
+	writeImageStream(b, blocks, false)
+	predict(pixels, width)
+
+	b.writeBool(false)
+	writeImageStream(b, pixels, true)
+
+	data := b.bytes()
+	chunkSize := len(data)
+	if len(data)%2 == 1 {
+		data = append(data, 0)
+	}
+
+	header := make([]byte, 20)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(12+len(data)))
+	copy(header[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(chunkSize))
+
+	if _, err := w.Write(header); err != nil {
+		return fmt.Errorf("failed to write WebP: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to write WebP: %w", err)
+	}
+
+	return nil
+}
+
+// argb returns the non-premultiplied color of a pixel packed as 0xAARRGGBB.
+func argb(img image.Image, x int, y int) uint32 {
+	r, g, b, a := img.At(x, y).RGBA()
+	if a == 0 {
+		return 0
+	}
+	if a != 0xffff {
+		r, g, b = r*0xffff/a, g*0xffff/a, b*0xffff/a
+	}
+
+	return (a>>8)<<24 | (r>>8)<<16 | (g>>8)<<8 | b>>8
+}
+
+// blocksFor returns the number of predictor blocks along a side of size pixels.
+func blocksFor(size int) int {
+	return (size + 1<<predictorBits - 1) >> predictorBits
+}
+
+// subtractGreen subtracts the green value from the red and blue values of every pixel.
+func subtractGreen(pixels []uint32) {
+	for i, p := range pixels {
+		green := (p >> 8) & 0xff
+		red := ((p >> 16) - green) & 0xff
+		blue := (p - green) & 0xff
+		pixels[i] = p&0xff00ff00 | red<<16 | blue
+	}
+}
+
+// predict replaces every pixel by its difference to the prediction from its neighbours, from the
+// last pixel backwards so the predictions use the original values. The first pixel is predicted
+// as opaque black, the rest of the first row from the left and the first column from the top.
+func predict(pixels []uint32, width int) {
+	for i := len(pixels) - 1; i >= 0; i-- {
+		x, y := i%width, i/width
+
+		var prediction uint32
+		switch {
+		case i == 0:
+			prediction = 0xff000000
+		case y == 0:
+			prediction = pixels[i-1]
+		case x == 0:
+			prediction = pixels[i-width]
+		default:
+			prediction = clampAddSubtract(pixels[i-1], pixels[i-width], pixels[i-width-1])
+		}
+
+		pixels[i] = subPixels(pixels[i], prediction)
+	}
+}
+
+// clampAddSubtract returns a + b - c for each channel, clamped to 0-255.
+func clampAddSubtract(a uint32, b uint32, c uint32) uint32 {
+	var out uint32
+	for shift := 0; shift < 32; shift += 8 {
+		v := int(a>>shift&0xff) + int(b>>shift&0xff) - int(c>>shift&0xff)
+		out |= uint32(min(max(v, 0), 255)) << shift
+	}
+
+	return out
+}
+
+// subPixels subtracts b from a for each channel, modulo 256.
+func subPixels(a uint32, b uint32) uint32 {
+	alphaGreen := 0x00ff00ff + (a & 0xff00ff00) - (b & 0xff00ff00)
+	redBlue := 0xff00ff00 + (a & 0x00ff00ff) - (b & 0x00ff00ff)
+
+	return alphaGreen&0xff00ff00 | redBlue&0x00ff00ff
+}
+
+// writeImageStream writes pixels as literals with one prefix code per channel. Only the main
+// image has the meta prefix code flag, transform sub-images do not.
+func writeImageStream(b *bitWriter, pixels []uint32, main bool) {
+	b.writeBool(false) // no color cache
+	if main {
+		b.writeBool(false) // no meta prefix codes
+	}
+
+	counts := [4][]int{
+		make([]int, greenAlphabet),
+		make([]int, channelAlphabet),
+		make([]int, channelAlphabet),
+		make([]int, channelAlphabet),
+	}
+	for _, p := range pixels {
+		counts[0][p>>8&0xff]++
+		counts[1][p>>16&0xff]++
+		counts[2][p&0xff]++
+		counts[3][p>>24]++
+	}
+
+	var codes [4]prefixCode
+	for i := range codes {
+		codes[i] = writePrefixCode(b, counts[i])
+	}
+	// Backward references are never used, so the distance code is a single unused symbol
+	writePrefixCode(b, make([]int, distanceAlphabet))
+
+	for _, p := range pixels {
+		codes[0].write(b, int(p>>8&0xff))
+		codes[1].write(b, int(p>>16&0xff))
+		codes[2].write(b, int(p&0xff))
+		codes[3].write(b, int(p>>24))
+	}
+}
@@ -1,90 +1,551 @@
 package main
 
 import (
+	"cmp"
 	"context"
+	"crypto/rand"
+	"fmt"
+	"maps"
+	"slices"
 
 	"cloud.google.com/go/firestore"
-	"cloud.google.com/go/storage"
-	"github.com/kelseyhightower/envconfig"
-	"github.com/rs/zerolog"
+	admin "cloud.google.com/go/firestore/apiv1/admin"
+	"firebase.google.com/go/v4/messaging"
 	"github.com/rs/zerolog/log"
 
+	"github.com/thoughtgears/shared-services/internal/address"
+	"github.com/thoughtgears/shared-services/internal/audit"
+	"github.com/thoughtgears/shared-services/internal/backup"
+	"github.com/thoughtgears/shared-services/internal/clients"
 	"github.com/thoughtgears/shared-services/internal/config"
 	"github.com/thoughtgears/shared-services/internal/db"
+	"github.com/thoughtgears/shared-services/internal/debugcapture"
+	"github.com/thoughtgears/shared-services/internal/errorreporting"
+	"github.com/thoughtgears/shared-services/internal/fieldcrypt"
 	"github.com/thoughtgears/shared-services/internal/gcs"
 	"github.com/thoughtgears/shared-services/internal/handlers"
+	"github.com/thoughtgears/shared-services/internal/identity"
+	"github.com/thoughtgears/shared-services/internal/importer"
+	"github.com/thoughtgears/shared-services/internal/loglevel"
+	"github.com/thoughtgears/shared-services/internal/mailer"
 	"github.com/thoughtgears/shared-services/internal/models"
-	"github.com/thoughtgears/shared-services/internal/router"
+	"github.com/thoughtgears/shared-services/internal/offboarding"
+	"github.com/thoughtgears/shared-services/internal/push"
+	"github.com/thoughtgears/shared-services/internal/residency"
 	"github.com/thoughtgears/shared-services/internal/router/middleware"
 	"github.com/thoughtgears/shared-services/internal/services"
+	"github.com/thoughtgears/shared-services/internal/signedtoken"
 	"github.com/thoughtgears/shared-services/internal/telemetry"
+	"github.com/thoughtgears/shared-services/internal/warmup"
+	"github.com/thoughtgears/shared-services/internal/webhook"
+	"github.com/thoughtgears/shared-services/pkg/app"
+	"github.com/thoughtgears/shared-services/pkg/providers"
+	"github.com/thoughtgears/shared-services/pkg/resilience"
 )
 
 var cfg config.Config
 
 const (
-	userCollection     = "users"
-	documentCollection = "documents"
+	adminRouteGroup   = "/admin/v1"
+	webhookRouteGroup = "/v1/webhooks"
 )
 
 func init() {
-	envconfig.MustProcess("", &cfg)
-	zerolog.SetGlobalLevel(zerolog.InfoLevel)
-	zerolog.LevelFieldName = "severity"
+	app.LoadConfig(&cfg)
 }
 
 func main() {
 	ctx := context.Background()
 
-	if err := middleware.InitFirebase(ctx, cfg.FirebaseSecretPath); err != nil {
-		log.Fatal().Err(err).Msg("Failed to initialize Firebase")
+	if cfg.UserRateLimitEnabled {
+		middleware.InitUserRateLimit(middleware.UserRateLimitConfig{
+			Read:    middleware.RateLimitBudget{Rate: cfg.UserRateLimitReadRate, Burst: cfg.UserRateLimitReadBurst},
+			Upload:  middleware.RateLimitBudget{Rate: cfg.UserRateLimitUploadRate, Burst: cfg.UserRateLimitUploadBurst},
+			IdleTTL: cfg.UserRateLimitIdleTTL,
+		})
 	}
 
-	// Only run OpenTelemetry if not in local mode
-	if !cfg.Local {
-		otel := telemetry.NewTelemetry(cfg.ServiceName, cfg.DomainName, cfg.OTELEndpoint)
-		cleanup := otel.InitTracer(ctx)
-		defer func() {
-			if err := cleanup(ctx); err != nil {
-				log.Fatal().Msgf("Failed to cleanup OpenTelemetry: %v", err)
-			}
-		}()
+	if cfg.UploadMaxConcurrent > 0 {
+		middleware.InitUploadLimit(middleware.UploadLimitConfig{
+			MaxConcurrent: cfg.UploadMaxConcurrent,
+			QueueTimeout:  cfg.UploadQueueTimeout,
+			RetryAfter:    cfg.UploadRetryAfter,
+		})
+	}
 
-		shutdown := otel.InitCounter(ctx)
-		defer func() {
-			if err := shutdown(ctx); err != nil {
-				log.Fatal().Msgf("Failed to shutdown OpenTelemetry: %v", err)
-			}
-		}()
+	if cfg.MFARequired {
+		middleware.InitMFAEnforcement(cfg.MFARequiredRoutes)
+	}
+
+	if len(cfg.ServiceAuthAudiences) > 0 {
+		err := middleware.InitServiceAuth(ctx, middleware.ServiceAuthConfig{
+			Audiences:      cfg.ServiceAuthAudiences,
+			AllowedCallers: cfg.ServiceAuthAllowedCallers,
+		})
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to initialize service auth")
+		}
+	}
+
+	telemetry.SetMaxTenants(cfg.TelemetryMaxTenants)
+
+	sloThresholds, err := middleware.ParseSLOThresholds(cfg.SLOGroupLatencyThresholds)
+	if err != nil {
+		log.Fatal().Msgf("Failed to parse SLO thresholds: %v", err)
+	}
+	middleware.InitSLO(middleware.SLOConfig{
+		LatencyThreshold: cfg.SLOLatencyThreshold,
+		GroupThresholds:  sloThresholds,
+	})
+
+	routeSampleRates, err := telemetry.ParseRouteSampleRates(cfg.TraceRouteSampleRates)
+	if err != nil {
+		log.Fatal().Msgf("Failed to parse route sample rates: %v", err)
 	}
 
-	firestoreClient, err := firestore.NewClient(ctx, cfg.ProjectID)
+	a, err := app.New(ctx, app.Config{
+		ServiceName:        cfg.ServiceName,
+		DomainName:         cfg.DomainName,
+		Port:               cfg.Port,
+		Local:              cfg.Local,
+		FirebaseSecretPath: cfg.FirebaseSecretPath,
+		MessageCatalogDir:  cfg.MessageCatalogDir,
+		AuthLockout: middleware.AuthLockoutConfig{
+			Threshold:     cfg.AuthLockoutThreshold,
+			Window:        cfg.AuthLockoutWindow,
+			BlockDuration: cfg.AuthLockoutDuration,
+		},
+		TrustedProxies:  cfg.TrustedProxies,
+		TrustedPlatform: cfg.TrustedPlatform,
+		ProjectID:       cfg.ProjectID,
+		OTELEndpoint:    cfg.OTELEndpoint,
+		OTELInsecure:    cfg.OTELInsecure,
+		TraceExporters:  cfg.TraceExporters,
+		TraceSampler:    telemetry.NewRouteSampler(cfg.TraceSampleRate, routeSampleRates),
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to start service")
+	}
+
+	clientBuilder := clients.NewBuilder(clients.Config{
+		UniverseDomain:         cfg.GoogleUniverseDomain,
+		FirestoreEndpoint:      cfg.FirestoreEndpoint,
+		StorageEndpoint:        cfg.StorageEndpoint,
+		IAMCredentialsEndpoint: cfg.IAMCredentialsEndpoint,
+		KMSEndpoint:            cfg.KMSEndpoint,
+		LoggingEndpoint:        cfg.LoggingEndpoint,
+		SpannerEndpoint:        cfg.SpannerEndpoint,
+	})
+
+	firestoreClient, err := clientBuilder.Firestore(ctx, cfg.ProjectID, cfg.FirestoreDatabaseID)
 	if err != nil {
 		log.Fatal().Msgf("Failed to create Firestore client: %v", err)
 	}
+	a.OnShutdown(func(context.Context) error { return firestoreClient.Close() })
+	// Clients connect on first use, the warmup route makes that use before real traffic arrives
+	warmup.Register("firestore", clients.WarmFirestore(firestoreClient))
 
-	storageClient, err := storage.NewClient(ctx)
+	storageClient, err := clientBuilder.Storage(ctx)
 	if err != nil {
 		log.Fatal().Msgf("Failed to create GCS client: %v", err)
 	}
+	a.OnShutdown(func(context.Context) error { return storageClient.Close() })
+	warmup.Register("storage", clients.WarmStorage(storageClient, cfg.BucketName))
+
+	var fieldCipher *fieldcrypt.Cipher
+	if cfg.FieldEncryptionKMSKey != "" {
+		keyWrapper, err := fieldcrypt.NewKMSKeyWrapper(ctx, cfg.FieldEncryptionKMSKey, clientBuilder.KMSOptions()...)
+		if err != nil {
+			log.Fatal().Msgf("Failed to create KMS key wrapper: %v", err)
+		}
+		fieldCipher = fieldcrypt.NewCipher(keyWrapper, cfg.FieldEncryptionKeyRotation)
+	}
+
+	// Locally there is no metadata server, so signing falls back to the detected credentials
+	var signer *gcs.IAMSigner
+	if !cfg.Local || cfg.SigningServiceAccount != "" {
+		signer = gcs.NewIAMSigner(cfg.SigningServiceAccount, clientBuilder.IAMCredentialsOptions()...)
+		warmup.Register("url_signer", signer.Warmup)
+	}
+
+	// With multi-tenancy every tenant gets its own collections and object prefix, resolved per request
+	if cfg.TenancyEnabled {
+		middleware.InitTenancy(middleware.TenancyConfig{Hosts: cfg.TenantHosts})
+	}
+
+	// Every repository and bucket is wrapped with the same timeouts, retries and hedging
+	env := &providers.Environment{
+		Config:    cfg,
+		Clients:   clientBuilder,
+		Firestore: firestoreClient,
+		Storage:   storageClient,
+		Signer:    signer,
+		Cipher:    fieldCipher,
+		Policy: resilience.Policy{
+			Timeout:    cfg.BackendTimeout,
+			MaxRetries: cfg.BackendMaxRetries,
+			HedgeDelay: cfg.BackendHedgeDelay,
+		},
+		OnShutdown: a.OnShutdown,
+	}
 
-	documentDataStore := db.NewFirestoreRepository[models.Document](firestoreClient, documentCollection)
-	userDatastore := db.NewFirestoreRepository[models.User](firestoreClient, userCollection)
-	storageStore, err := gcs.NewGCSStorage(storageClient, cfg.BucketName)
+	repos, err := providers.NewRepositories(ctx, env)
 	if err != nil {
-		log.Fatal().Msgf("Failed to create GCS storage client: %v", err)
+		log.Fatal().Msgf("Failed to create repositories: %v", err)
+	}
+
+	regionStorages, err := providers.NewRegionStorages(ctx, env)
+	if err != nil {
+		log.Fatal().Msgf("Failed to create storage: %v", err)
+	}
+	regionBuckets := providers.RegionBuckets(cfg)
+
+	residencyPolicy, err := residency.NewPolicy(cfg.Region, slices.Collect(maps.Keys(regionBuckets)), cfg.ResidencyCountryRegions)
+	if err != nil {
+		log.Fatal().Msgf("Failed to create residency policy: %v", err)
+	}
+
+	auditRecorder, err := newAuditRecorder(ctx, firestoreClient, clientBuilder)
+	if err != nil {
+		log.Fatal().Msgf("Failed to create audit recorder: %v", err)
+	}
+
+	documentTypeService, err := newDocumentTypeService(repos.DocumentTypes)
+	if err != nil {
+		log.Fatal().Msgf("Failed to load document types: %v", err)
+	}
+
+	addressValidator, err := address.NewValidator(cfg.AddressValidationMode, cfg.AddressValidationAPIKey, cfg.AddressStoreCoordinates)
+	if err != nil {
+		log.Fatal().Msgf("Failed to create address validator: %v", err)
+	}
+
+	userService := services.NewUserService(repos.Users, addressValidator, cfg.DefaultPhoneRegion, auditRecorder)
+
+	organizationService := services.NewOrganizationService(
+		repos.Organizations,
+		repos.Memberships,
+		auditRecorder,
+	)
+	organizationHandler := handlers.NewOrganizationHandler(organizationService)
+
+	if cfg.InvitationSigningKey != "" && cfg.InvitationSigningKey == cfg.EmailChangeSigningKey {
+		log.Fatal().Msg("INVITATION_SIGNING_KEY and EMAIL_CHANGE_SIGNING_KEY must differ")
+	}
+	invitationSigner := newTokenSigner("INVITATION_SIGNING_KEY", cfg.InvitationSigningKey)
+	emailChangeSigner := newTokenSigner("EMAIL_CHANGE_SIGNING_KEY", cfg.EmailChangeSigningKey)
+
+	mail, err := mailer.New(cfg.MailMode, cfg.SMTPAddr, cfg.MailFrom, cfg.SMTPUsername, cfg.SMTPPassword)
+	if err != nil {
+		log.Fatal().Msgf("Failed to create mailer: %v", err)
+	}
+
+	var invitationService services.InvitationService
+	if invitationSigner != nil {
+		invitationService = services.NewInvitationService(
+			repos.Invitations,
+			repos.Memberships,
+			organizationService,
+			invitationSigner,
+			mail,
+			cfg.InvitationAcceptURL,
+			cfg.InvitationTTL,
+			auditRecorder,
+		)
 	}
+	invitationHandler := handlers.NewInvitationHandler(invitationService)
 
-	documentService := services.NewDocumentService(storageStore, documentDataStore)
+	mailTemplates, err := mailer.NewTemplates(mailer.Brand{Name: cfg.MailBrandName, URL: cfg.MailBrandURL})
+	if err != nil {
+		log.Fatal().Msgf("Failed to parse email templates: %v", err)
+	}
+
+	tenantSettingsService := services.NewTenantSettingsService(
+		repos.TenantSettings,
+		cfg.TenantSettingsCacheTTL,
+		auditRecorder,
+	)
+
+	var messagingClient *messaging.Client
+	if cfg.PushMode == "fcm" {
+		messagingClient, err = middleware.FirebaseApp().Messaging(ctx)
+		if err != nil {
+			log.Fatal().Msgf("Failed to create Firebase messaging client: %v", err)
+		}
+	}
+	pushSender, err := push.New(cfg.PushMode, messagingClient)
+	if err != nil {
+		log.Fatal().Msgf("Failed to create push sender: %v", err)
+	}
+
+	notificationService := services.NewNotificationService(
+		repos.Deliveries,
+		userService,
+		tenantSettingsService,
+		mail,
+		mailTemplates,
+		pushSender,
+	)
+	activityService := services.NewActivityService(repos.Activity)
+	middleware.InitActivityTracking(activityService)
+	accessTokenService := services.NewAccessTokenService(
+		repos.AccessTokens,
+		auditRecorder,
+	)
+	middleware.InitAccessTokens(accessTokenService)
+	middleware.InitUserLoader(userService.GetByID)
+	userHandler := handlers.NewUserHandler(userService, notificationService, activityService)
+	// Avatars are shown to other users, so they are kept in the deployment region like user records
+	avatarHandler := handlers.NewAvatarHandler(
+		services.NewAvatarService(userService, repos.Users, regionStorages[cfg.Region], auditRecorder),
+	)
+	identityProvider := identity.NewFirebase(middleware.FirebaseApp(), cfg.PasswordResetContinueURL)
+	accountService := services.NewAccountService(
+		identityProvider,
+		userService,
+		mail,
+		mailTemplates,
+		emailChangeSigner,
+		cfg.EmailChangeConfirmURL,
+		cfg.EmailChangeTTL,
+		auditRecorder,
+	)
+
+	documentStorage := services.NewRegionalStorage(cfg.Region, regionStorages)
+	var tieringService services.TieringService
+	if cfg.TieringAfter > 0 {
+		tieringService = services.NewTieringService(documentStorage, repos.Documents, services.TieringConfig{
+			After:        cfg.TieringAfter,
+			StorageClass: cfg.TieringStorageClass,
+			MaxDocuments: cfg.TieringMaxDocuments,
+		}, auditRecorder)
+	}
+	// Restoring is optional, cold content is readable as it is
+	restoreService := tieringService
+	if !cfg.TieringRestoreOnAccess {
+		restoreService = nil
+	}
+
+	residencyResolver := services.NewUserResidencyResolver(userService, residencyPolicy)
+	documentService := services.NewDocumentService(
+		documentStorage,
+		residencyResolver,
+		organizationService,
+		tenantSettingsService,
+		repos.Documents,
+		repos.Bundles,
+		documentTypeService,
+		cfg.DocumentMetadataFilterKeys,
+		cfg.SignedURLTTL,
+		repos.Comments,
+		notificationService,
+		restoreService,
+		auditRecorder,
+	)
 	documentHandler := handlers.NewDocumentHandler(documentService)
+	commentHandler := handlers.NewCommentHandler(
+		services.NewCommentService(repos.Documents, organizationService, repos.Comments, auditRecorder),
+	)
 
-	userService := services.NewUserService(userDatastore)
-	userHandler := handlers.NewUserHandler(userService)
+	userMergeService := services.NewUserMergeService(repos.Users, repos.Documents, repos.Bundles, auditRecorder)
+	userClaimsService := services.NewUserClaimsService(identityProvider, userService, repos.Users, auditRecorder)
+	sessionService := services.NewSessionService(
+		identityProvider,
+		repos.SessionRevocations,
+		cfg.SessionRevocationCacheTTL,
+		auditRecorder,
+	)
+	middleware.InitSessionRevocation(sessionService)
 
-	r := router.NewRouter(cfg.ServiceName, cfg.Local, &cfg.Port)
+	// Tenant exports need the per-tenant layout in Firestore, so they are only offered with tenancy
+	// enabled on the Firestore backend
+	var offboardingService services.OffboardingService
+	if cfg.TenancyEnabled && cfg.DatabaseBackend == "firestore" {
+		exportBucket := cfg.TenantExportBucket
+		if exportBucket == "" {
+			exportBucket = cfg.BucketName
+		}
+		offboardingService = services.NewOffboardingService(offboarding.NewExporter(
+			firestoreClient,
+			storageClient,
+			fieldCipher,
+			auditRecorder,
+			offboarding.Config{
+				SourceBuckets:      slices.Collect(maps.Values(regionBuckets)),
+				ExportBucket:       exportBucket,
+				ExportPrefix:       cfg.TenantExportPrefix,
+				AuditCollection:    cfg.AuditCollection,
+				SettingsCollection: providers.TenantSettingsCollection,
+			},
+		))
+	}
+
+	var backupService services.BackupService
+	if cfg.BackupBucket != "" && cfg.DatabaseBackend == "firestore" {
+		var adminClient *admin.FirestoreAdminClient
+		if cfg.BackupMode == backup.ModeManaged {
+			adminClient, err = clientBuilder.FirestoreAdmin(ctx)
+			if err != nil {
+				log.Fatal().Msgf("Failed to create Firestore admin client: %v", err)
+			}
+			a.OnShutdown(func(context.Context) error { return adminClient.Close() })
+		}
+		backupService = services.NewBackupService(backup.NewBackuper(
+			firestoreClient,
+			adminClient,
+			storageClient,
+			auditRecorder,
+			backup.Config{
+				Database:    fmt.Sprintf("projects/%s/databases/%s", cfg.ProjectID, cmp.Or(cfg.FirestoreDatabaseID, firestore.DefaultDatabaseID)),
+				Collections: cfg.BackupCollections,
+				Mode:        cfg.BackupMode,
+				Bucket:      cfg.BackupBucket,
+				Prefix:      cfg.BackupPrefix,
+			},
+		))
+	}
+
+	var importService services.ImportService
+	if cfg.ImportBucket != "" {
+		importService = services.NewImportService(
+			importer.NewGCSSource(storageClient, cfg.ImportBucket, ""),
+			documentStorage,
+			residencyResolver,
+			tenantSettingsService,
+			repos.Users,
+			repos.Documents,
+			documentTypeService,
+			auditRecorder,
+		)
+	}
 
-	documentHandler.RegisterRoutes(r.Engine)
-	userHandler.RegisterRoutes(r.Engine)
+	r := a.Router
 
-	log.Fatal().Err(r.Run()).Msg("Failed to run server")
+	// Middleware must be added before the handlers register their routes to apply to them
+	if cfg.ErrorReportingEnabled {
+		middleware.InitErrorReporting(errorreporting.NewReporter(cfg.ServiceName, cfg.ServiceVersion))
+		r.Engine.Use(middleware.ErrorReporting())
+	}
+	if len(cfg.AdminAllowedCIDRs) > 0 {
+		// Behind a proxy every request has its address, the allowlist would let all or none through
+		if !r.ResolvesClientIP() {
+			log.Fatal().Msg("ADMIN_ALLOWED_CIDRS needs the client address, set TRUSTED_PROXIES or TRUSTED_PLATFORM")
+		}
+
+		allowedNetworks, err := middleware.ParseCIDRs(cfg.AdminAllowedCIDRs)
+		if err != nil {
+			log.Fatal().Msgf("Failed to parse admin allowed CIDRs: %v", err)
+		}
+
+		restrictedGroups := []string{adminRouteGroup}
+		if cfg.WebhookIPAllowlist {
+			restrictedGroups = append(restrictedGroups, webhookRouteGroup)
+		}
+		r.Engine.Use(middleware.IPAllowlist(restrictedGroups, allowedNetworks))
+	}
+	var debugCaptures *debugcapture.Recorder
+	if cfg.DebugCaptureSize > 0 {
+		debugCaptures = debugcapture.NewRecorder(cfg.DebugCaptureSize)
+		middleware.InitDebugCapture(debugCaptures)
+		r.Engine.Use(middleware.DebugCapture())
+	}
+	if cfg.CSRFEnabled {
+		r.Engine.Use(middleware.CSRF(middleware.CSRFConfig{
+			RouteGroups: cfg.CSRFRouteGroups,
+			Secure:      !cfg.Local,
+		}))
+	}
+
+	// Integrations register their webhook sources on the receiver
+	webhookReceiver := webhook.NewReceiver(webhook.NewDBNonceStore(repos.WebhookNonces), cfg.WebhookReplayWindow)
+
+	a.RegisterRoutes(
+		documentHandler,
+		commentHandler,
+		handlers.NewBundleHandler(documentService),
+		userHandler,
+		avatarHandler,
+		handlers.NewAccessTokenHandler(accessTokenService),
+		handlers.NewSessionHandler(sessionService),
+		organizationHandler,
+		invitationHandler,
+		handlers.NewAccountHandler(accountService),
+		handlers.NewProfileHandler(services.NewProfileService(userService, documentService, tenantSettingsService, identityProvider, repos.Snapshots)),
+		handlers.NewAdminHandler(documentService, userMergeService, userClaimsService, tenantSettingsService, offboardingService, tieringService, importService, debugCaptures, loglevel.NewController()),
+		handlers.NewWebhookHandler(webhookReceiver),
+		handlers.NewBackupHandler(backupService),
+		handlers.NewSchemaHandler(),
+	)
+
+	if err := a.Run(ctx); err != nil {
+		log.Fatal().Err(err).Msg("Failed to run server")
+	}
+}
+
+// newTokenSigner creates the signer of a kind of token from the key in the environment variable
+// name. It returns nil, disabling the tokens, when the key is empty; locally a random key is used.
+func newTokenSigner(name string, key string) *signedtoken.Signer {
+	secret := []byte(key)
+	if len(secret) == 0 {
+		if !cfg.Local {
+			log.Warn().Msgf("%s not set, its tokens are disabled", name)
+
+			return nil
+		}
+
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatal().Msgf("Failed to generate %s: %v", name, err)
+		}
+		log.Warn().Msgf("%s not set, using a random key", name)
+	}
+
+	signer, err := signedtoken.NewSigner(secret)
+	if err != nil {
+		log.Fatal().Msgf("Failed to create signer for %s: %v", name, err)
+	}
+
+	return signer
+}
+
+// newAuditRecorder creates the audit recorder writing to the configured sinks.
+func newAuditRecorder(ctx context.Context, firestoreClient *firestore.Client, clientBuilder *clients.Builder) (*audit.Recorder, error) {
+	writers := make([]audit.Writer, 0, len(cfg.AuditSinks))
+	for _, sink := range cfg.AuditSinks {
+		switch sink {
+		case "firestore":
+			writers = append(writers, audit.NewFirestoreWriter(firestoreClient, cfg.AuditCollection))
+		case "logging":
+			writer, err := audit.NewCloudLoggingWriter(ctx, cfg.ProjectID, cfg.AuditLogID, clientBuilder.LoggingOptions()...)
+			if err != nil {
+				return nil, err
+			}
+			writers = append(writers, writer)
+		default:
+			return nil, fmt.Errorf("unknown audit sink: %s", sink)
+		}
+	}
+
+	return audit.NewRecorder(writers...), nil
+}
+
+// newDocumentTypeService creates the document type service for the configured source.
+func newDocumentTypeService(typeDatastore db.DB[models.DocumentTypeDefinition]) (services.DocumentTypeService, error) {
+	switch cfg.DocumentTypesSource {
+	case "firestore":
+		return services.NewFirestoreDocumentTypeService(typeDatastore, cfg.DocumentTypesCacheTTL), nil
+	case "config":
+		if cfg.DocumentTypesPath == "" {
+			return services.NewStaticDocumentTypeService(models.DefaultDocumentTypes()), nil
+		}
+
+		definitions, err := services.LoadDocumentTypesFile(cfg.DocumentTypesPath)
+		if err != nil {
+			return nil, fmt.Errorf("load document types: %w", err)
+		}
+
+		return services.NewStaticDocumentTypeService(definitions), nil
+	default:
+		return nil, fmt.Errorf("unknown document types source: %s", cfg.DocumentTypesSource)
+	}
 }
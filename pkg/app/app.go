@@ -0,0 +1,204 @@
+// Package app bootstraps a service: it loads the configuration, sets up logging, Firebase
+// authentication and telemetry, creates the router and runs it until the process is told to stop,
+// then runs the shutdown hooks. A service only adds its own clients, services and routes.
+//
+//	var cfg config.Config
+//
+//	func init() {
+//		app.LoadConfig(&cfg)
+//	}
+//
+//	func main() {
+//		ctx := context.Background()
+//		a, err := app.New(ctx, app.Config{ServiceName: cfg.ServiceName, Port: cfg.Port})
+//		if err != nil {
+//			log.Fatal().Err(err).Msg("Failed to start service")
+//		}
+//		a.RegisterRoutes(handlers.NewSchemaHandler())
+//		if err := a.Run(ctx); err != nil {
+//			log.Fatal().Err(err).Msg("Failed to run server")
+//		}
+//	}
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kelseyhightower/envconfig"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/thoughtgears/shared-services/internal/buildinfo"
+	"github.com/thoughtgears/shared-services/internal/i18n"
+	"github.com/thoughtgears/shared-services/internal/redact"
+	"github.com/thoughtgears/shared-services/internal/router"
+	"github.com/thoughtgears/shared-services/internal/router/middleware"
+	"github.com/thoughtgears/shared-services/internal/telemetry"
+)
+
+// shutdownTimeout is how long in-flight requests get to finish, Cloud Run kills the instance
+// 10 seconds after SIGTERM.
+const shutdownTimeout = 8 * time.Second
+
+// Config is the configuration every service shares, taken from the service's own config.
+type Config struct {
+	ServiceName string
+	DomainName  string
+	Port        string
+	Local       bool
+
+	// FirebaseSecretPath is the service account file used to verify Firebase ID tokens.
+	FirebaseSecretPath string
+	// AuthLockout blocks IPs with too many failed token verifications, disabled when its
+	// threshold is 0. It needs the client address, see TrustedProxies.
+	AuthLockout middleware.AuthLockoutConfig
+
+	// TrustedProxies and TrustedPlatform tell the router how to find the client address behind
+	// the proxies in front of the service, see router.WithClientIP.
+	TrustedProxies  []string
+	TrustedPlatform string
+
+	// MessageCatalogDir holds the translations of error messages, see i18n.LoadDir. Errors are
+	// answered in English only when it is empty.
+	MessageCatalogDir string
+
+	// Traces are sent to TraceExporters, see telemetry.ParseExporters, and to the collector at
+	// OTELEndpoint when there are none. TraceSampler samples every trace when nil.
+	ProjectID      string
+	OTELEndpoint   string
+	OTELInsecure   bool
+	TraceExporters []string
+	TraceSampler   sdktrace.Sampler
+
+	// RouterOptions are passed to router.NewRouter.
+	RouterOptions []router.Option
+}
+
+// RouteRegistrar is a handler adding its routes to the engine.
+type RouteRegistrar interface {
+	RegisterRoutes(r *gin.Engine)
+}
+
+// App is a bootstrapped service.
+type App struct {
+	// Router serves the service, middleware must be added before routes are registered.
+	Router *router.Router
+
+	shutdown []func(ctx context.Context) error
+}
+
+// LoadConfig reads the environment into spec and sets up the structured logger. It is meant to
+// be called from init, so the configuration is loaded before anything logs.
+func LoadConfig(spec any) {
+	envconfig.MustProcess("", spec)
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	zerolog.LevelFieldName = "severity"
+	// Every log line passes through redaction so PII and credentials never reach Cloud Logging
+	log.Logger = log.Output(redact.NewWriter(os.Stderr))
+}
+
+// New bootstraps a service: it logs the build, initializes Firebase authentication, starts tracing
+// and, outside local mode, metrics, creates the router and initializes the auth lockout.
+//
+// Parameters:
+//   - ctx: Context for the initialization
+//   - cfg: Configuration of the service
+//
+// Returns:
+//   - *App: The bootstrapped service, ready for routes
+//   - error: Any error encountered while initializing
+func New(ctx context.Context, cfg Config) (*App, error) {
+	build := buildinfo.Get()
+	log.Info().
+		Str("version", build.Version).
+		Str("commit", build.Commit).
+		Str("build_time", build.BuildTime).
+		Str("go_version", build.GoVersion).
+		Msg("Starting " + cfg.ServiceName)
+
+	if err := middleware.InitFirebase(ctx, cfg.FirebaseSecretPath); err != nil {
+		return nil, fmt.Errorf("initialize firebase: %w", err)
+	}
+
+	if cfg.MessageCatalogDir != "" {
+		if err := i18n.LoadDir(cfg.MessageCatalogDir); err != nil {
+			return nil, fmt.Errorf("load message catalogs: %w", err)
+		}
+	}
+
+	traceExporters, err := telemetry.ParseExporters(cfg.TraceExporters, cfg.Local)
+	if err != nil {
+		return nil, fmt.Errorf("parse trace exporters: %w", err)
+	}
+
+	a := &App{}
+
+	// Traces are written to stdout in local mode unless a pipeline is configured
+	otel := telemetry.NewTelemetry(cfg.ServiceName, cfg.DomainName, cfg.OTELEndpoint, cfg.OTELInsecure)
+	otel.Sampler = cfg.TraceSampler
+	otel.Exporters = traceExporters
+	otel.ProjectID = cfg.ProjectID
+	a.OnShutdown(otel.InitTracer(ctx))
+
+	// Metrics need a collector, so they are only exported outside local mode
+	if !cfg.Local {
+		a.OnShutdown(otel.InitCounter(ctx))
+	}
+
+	if _, err := middleware.ParseCIDRs(cfg.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("parse trusted proxies: %w", err)
+	}
+	routerOptions := append([]router.Option{router.WithClientIP(cfg.TrustedProxies, cfg.TrustedPlatform)}, cfg.RouterOptions...)
+	a.Router = router.NewRouter(cfg.ServiceName, cfg.Local, &cfg.Port, routerOptions...)
+
+	// Behind a proxy every client would share its address, so one client could lock out all
+	if cfg.AuthLockout.Threshold > 0 {
+		if !a.Router.ResolvesClientIP() {
+			return nil, fmt.Errorf("auth lockout needs the client address, configure the trusted proxies or platform")
+		}
+		middleware.InitAuthLockout(cfg.AuthLockout)
+	}
+
+	return a, nil
+}
+
+// RegisterRoutes adds the routes of the handlers to the router.
+func (a *App) RegisterRoutes(handlers ...RouteRegistrar) {
+	for _, handler := range handlers {
+		handler.RegisterRoutes(a.Router.Engine)
+	}
+}
+
+// OnShutdown adds a hook run when the service stops, after the server has shut down. Hooks run
+// in the reverse order they were added, so later clients are closed before what they depend on.
+func (a *App) OnShutdown(fn func(ctx context.Context) error) {
+	a.shutdown = append(a.shutdown, fn)
+}
+
+// Run serves the routes until ctx is done or the process receives SIGINT or SIGTERM, shuts the
+// server down gracefully and runs the shutdown hooks. Failing hooks are logged, the error of the
+// server is returned.
+func (a *App) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := a.Router.Serve(ctx, shutdownTimeout)
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+	for _, fn := range slices.Backward(a.shutdown) {
+		if hookErr := fn(shutdownCtx); hookErr != nil {
+			log.Error().Err(hookErr).Msg("Failed to run shutdown hook")
+		}
+	}
+
+	return err
+}
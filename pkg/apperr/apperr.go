@@ -0,0 +1,128 @@
+// Package apperr defines the kinds of errors services return, so handlers can answer each with
+// the right status code instead of a blanket 500.
+//
+//	var ErrForbidden = apperr.New(apperr.PermissionDenied, "forbidden")
+//	return apperr.Errorf(apperr.NotFound, "user %s not found", id)
+//
+// Handlers write any error with Respond, which picks the status from its kind.
+package apperr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/thoughtgears/shared-services/internal/models"
+)
+
+// Kind is the kind of an error, it decides the status code of the response.
+type Kind string
+
+const (
+	// Internal is an unexpected failure, the kind of errors without one.
+	Internal Kind = "internal"
+	// NotFound is a resource that does not exist.
+	NotFound Kind = "not_found"
+	// Invalid is a request that fails validation.
+	Invalid Kind = "invalid"
+	// Conflict is a request that conflicts with the current state of a resource.
+	Conflict Kind = "conflict"
+	// PermissionDenied is a caller without access to a resource.
+	PermissionDenied Kind = "permission_denied"
+	// Unavailable is a dependency that is down or overloaded, the request may be retried.
+	Unavailable Kind = "unavailable"
+)
+
+// Error is an error of a kind.
+type Error struct {
+	Kind Kind
+	Err  error
+}
+
+// Error returns the message of the wrapped error.
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New creates an error of a kind with a message, e.g. for sentinel errors.
+func New(kind Kind, message string) error {
+	return &Error{Kind: kind, Err: errors.New(message)}
+}
+
+// Errorf creates an error of a kind, formatted like fmt.Errorf.
+func Errorf(kind Kind, format string, args ...any) error {
+	return &Error{Kind: kind, Err: fmt.Errorf(format, args...)}
+}
+
+// Wrap gives err a kind, nil stays nil.
+func Wrap(kind Kind, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &Error{Kind: kind, Err: err}
+}
+
+// KindOf returns the kind of err: the kind of the outermost *Error it wraps, Invalid for
+// validation errors, and the kind matching the status of gRPC errors from the Google Cloud
+// clients. Other errors are Internal.
+func KindOf(err error) Kind {
+	var kindErr *Error
+	if errors.As(err, &kindErr) {
+		return kindErr.Kind
+	}
+
+	var verr *models.ValidationError
+	if errors.As(err, &verr) {
+		return Invalid
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return Unavailable
+	}
+
+	if s, ok := status.FromError(err); ok {
+		switch s.Code() {
+		case codes.NotFound:
+			return NotFound
+		case codes.AlreadyExists, codes.Aborted:
+			return Conflict
+		case codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded:
+			return Unavailable
+		}
+	}
+
+	return Internal
+}
+
+// Is reports whether err is of kind.
+func Is(err error, kind Kind) bool {
+	return KindOf(err) == kind
+}
+
+// Status returns the HTTP status code of a kind.
+func Status(kind Kind) int {
+	switch kind {
+	case NotFound:
+		return http.StatusNotFound
+	case Invalid:
+		return http.StatusBadRequest
+	case Conflict:
+		return http.StatusConflict
+	case PermissionDenied:
+		return http.StatusForbidden
+	case Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,41 @@
+package apperr
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/thoughtgears/shared-services/internal/i18n"
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/internal/redact"
+)
+
+// Respond writes err as the standard error envelope, with the status code of its kind and message
+// describing what failed. Validation errors list their field errors, and permission errors do not
+// tell the caller why access was denied. The message is translated for the Accept-Language of the
+// request, keyed by the kind of err.
+func Respond(c *gin.Context, err error, message string) {
+	kind := KindOf(err)
+	code := Status(kind)
+
+	errorCode := string(kind)
+	body := gin.H{
+		"error":  redact.Error(err),
+		"status": code,
+	}
+	switch kind {
+	case PermissionDenied:
+		errorCode = "forbidden"
+		body["error"] = errorCode
+		message = "You do not have access to this resource"
+	case Invalid:
+		var verr *models.ValidationError
+		if errors.As(err, &verr) {
+			body["fields"] = verr.Fields
+		}
+	}
+
+	body["message"] = i18n.Localize(c, errorCode, message)
+
+	c.JSON(code, body)
+}
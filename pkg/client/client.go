@@ -0,0 +1,303 @@
+// Package client is a Go client for the shared-services REST APIs, for other internal services.
+// It injects the caller's ID token, retries transient failures, iterates paginated lists
+// and maps error responses to *APIError.
+//
+//	c, err := client.New("https://api.thoughtgears.dev", client.WithTokenSource(tokens))
+//	user, err := c.Users.Get(ctx, uid)
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thoughtgears/shared-services/internal/requestid"
+)
+
+const (
+	// DefaultMaxRetries is the number of times a failed idempotent request is retried.
+	DefaultMaxRetries = 3
+	// DefaultTimeout is the timeout of the default HTTP client.
+	DefaultTimeout = 30 * time.Second
+
+	baseBackoff = 200 * time.Millisecond
+	maxBackoff  = 5 * time.Second
+)
+
+// TokenSource returns the Firebase ID token sent as the bearer token of every request.
+// The document API also accepts personal access tokens, e.g. StaticToken("tgpat_...").
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// TokenSourceFunc adapts a function to a TokenSource.
+type TokenSourceFunc func(ctx context.Context) (string, error)
+
+// Token calls f.
+func (f TokenSourceFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// StaticToken returns a TokenSource that always returns token, such as a personal access token.
+func StaticToken(token string) TokenSource {
+	return TokenSourceFunc(func(context.Context) (string, error) {
+		return token, nil
+	})
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for requests.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTokenSource sets the source of the bearer token. Without it requests are unauthenticated.
+func WithTokenSource(tokens TokenSource) Option {
+	return func(c *Client) {
+		c.tokens = tokens
+	}
+}
+
+// WithMaxRetries sets how often failed idempotent requests are retried, 0 disables retries.
+func WithMaxRetries(maxRetries int) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+	}
+}
+
+// WithUserAgent sets the User-Agent header, so the API can tell calling services apart.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// Client is the entry point to the APIs, grouping a typed client per resource.
+type Client struct {
+	Users     *UsersClient
+	Documents *DocumentsClient
+
+	baseURL    *url.URL
+	httpClient *http.Client
+	tokens     TokenSource
+	maxRetries int
+	userAgent  string
+}
+
+// New creates a Client for the API at baseURL.
+func New(baseURL string, opts ...Option) (*Client, error) {
+	parsed, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if parsed.Scheme == "" || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid base URL: %s", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    parsed,
+		httpClient: &http.Client{Timeout: DefaultTimeout},
+		maxRetries: DefaultMaxRetries,
+		userAgent:  "shared-services-go-client",
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.Users = &UsersClient{client: c}
+	c.Documents = &DocumentsClient{client: c}
+
+	return c, nil
+}
+
+// request describes a single API call. The body is kept as bytes so it can be resent on retries.
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	body        []byte
+	contentType string
+}
+
+// jsonRequest creates a request with value encoded as the JSON body.
+func jsonRequest(method, path string, value any) (request, error) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		return request{}, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	return request{method: method, path: path, body: body, contentType: "application/json"}, nil
+}
+
+// envelope is the response wrapper used by every endpoint.
+type envelope struct {
+	Data json.RawMessage `json:"data"`
+}
+
+// do sends req, retrying transient failures, and decodes the data field of the response into out.
+// out may be nil when the response has no data.
+func (c *Client) do(ctx context.Context, req request, out any) error {
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, req)
+		if err == nil && resp.StatusCode < http.StatusBadRequest {
+			defer resp.Body.Close()
+
+			return decode(resp, out)
+		}
+
+		var retryAfter time.Duration
+		if err != nil {
+			lastErr = err
+		} else {
+			lastErr = newAPIError(resp)
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+			resp.Body.Close()
+		}
+
+		if attempt >= c.maxRetries || !retryable(req.method, resp, err) {
+			return lastErr
+		}
+
+		if err := sleep(ctx, backoff(attempt, retryAfter)); err != nil {
+			return errors.Join(lastErr, err)
+		}
+	}
+}
+
+// send performs a single attempt of req.
+func (c *Client) send(ctx context.Context, req request) (*http.Response, error) {
+	target := c.baseURL.JoinPath(req.path)
+	target.RawQuery = req.query.Encode()
+
+	var body io.Reader
+	if req.body != nil {
+		body = bytes.NewReader(req.body)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, target.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set("User-Agent", c.userAgent)
+	if req.contentType != "" {
+		httpReq.Header.Set("Content-Type", req.contentType)
+	}
+	// The caller's request ID is forwarded so both services log the same ID
+	if id := requestid.From(ctx); id != "" {
+		httpReq.Header.Set(requestid.Header, id)
+	}
+	if c.tokens != nil {
+		token, err := c.tokens.Token(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get token: %w", err)
+		}
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", req.method, req.path, err)
+	}
+
+	return resp, nil
+}
+
+// decode reads the data field of a successful response into out.
+func decode(resp *http.Response, out any) error {
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	var body envelope
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(body.Data) == 0 {
+		return fmt.Errorf("response has no data")
+	}
+	if err := json.Unmarshal(body.Data, out); err != nil {
+		return fmt.Errorf("failed to decode response data: %w", err)
+	}
+
+	return nil
+}
+
+// retryable reports whether a failed attempt may be retried. Only idempotent methods are retried,
+// so a create is never applied twice; rate limited requests were rejected and are always safe to retry.
+func retryable(method string, resp *http.Response, err error) bool {
+	if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// backoff returns the delay before the next attempt, honouring the server's Retry-After.
+func backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return min(retryAfter, maxBackoff)
+	}
+
+	delay := maxBackoff
+	if attempt < 8 {
+		delay = min(baseBackoff<<attempt, maxBackoff)
+	}
+
+	// Full jitter keeps callers that failed together from retrying together
+	return rand.N(delay) + time.Millisecond
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds.
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
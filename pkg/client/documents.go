@@ -0,0 +1,283 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"iter"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/thoughtgears/shared-services/internal/models"
+)
+
+type (
+	// Document is document metadata as returned by the documents API.
+	Document = models.Document
+	// DocumentType names a document type, see the document-types endpoint for the allowed values.
+	DocumentType = models.DocumentType
+	// DocumentPage is a single page of a document listing.
+	DocumentPage = models.Page[*models.Document]
+	// Region is a rectangle on a document page, in fractions of the page width and height.
+	Region = models.CommentRegion
+)
+
+// DocumentsClient calls the /v1/documents endpoints.
+type DocumentsClient struct {
+	client *Client
+}
+
+// ListDocumentsOptions filters and pages a document listing.
+// Either UserID or OrganizationID selects the owner of the documents.
+type ListDocumentsOptions struct {
+	UserID         string
+	OrganizationID string
+	// Metadata filters on metadata values, only keys allow-listed by the service can be used.
+	Metadata     map[string]string
+	PageToken    string
+	PageSize     int
+	IncludeTotal bool
+}
+
+// query returns the query parameters of the listing.
+func (o ListDocumentsOptions) query() url.Values {
+	query := url.Values{}
+	if o.UserID != "" {
+		query.Set("user_id", o.UserID)
+	}
+	if o.OrganizationID != "" {
+		query.Set("organization_id", o.OrganizationID)
+	}
+	for key, value := range o.Metadata {
+		query.Set("metadata."+key, value)
+	}
+	if o.PageToken != "" {
+		query.Set("page_token", o.PageToken)
+	}
+	if o.PageSize > 0 {
+		query.Set("page_size", strconv.Itoa(o.PageSize))
+	}
+	if o.IncludeTotal {
+		query.Set("include_total", "true")
+	}
+
+	return query
+}
+
+// Get returns the document with the given ID.
+func (d *DocumentsClient) Get(ctx context.Context, id string) (*Document, error) {
+	var document Document
+	if err := d.client.do(ctx, request{method: http.MethodGet, path: documentPath(id)}, &document); err != nil {
+		return nil, err
+	}
+
+	return &document, nil
+}
+
+// List returns a single page of documents.
+func (d *DocumentsClient) List(ctx context.Context, opts ListDocumentsOptions) (*DocumentPage, error) {
+	var page DocumentPage
+	if err := d.client.do(ctx, request{method: http.MethodGet, path: "/v1/documents", query: opts.query()}, &page); err != nil {
+		return nil, err
+	}
+
+	return &page, nil
+}
+
+// All iterates over every document matching opts, fetching further pages as needed.
+// Iteration stops at the first error, which is yielded with a nil document.
+func (d *DocumentsClient) All(ctx context.Context, opts ListDocumentsOptions) iter.Seq2[*Document, error] {
+	return func(yield func(*Document, error) bool) {
+		for {
+			page, err := d.List(ctx, opts)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			for _, document := range page.Items {
+				if !yield(document, nil) {
+					return
+				}
+			}
+
+			if page.NextPageToken == "" {
+				return
+			}
+			opts.PageToken = page.NextPageToken
+		}
+	}
+}
+
+// CreateDocumentInput holds the values of a document upload.
+type CreateDocumentInput struct {
+	UserID         string
+	OrganizationID string
+	Type           DocumentType
+	ExpiryDate     *time.Time
+	Metadata       map[string]string
+	FileName       string
+	Content        []byte
+}
+
+// Create uploads a new document.
+func (d *DocumentsClient) Create(ctx context.Context, input CreateDocumentInput) (*Document, error) {
+	fields := map[string]string{
+		"user_id":         input.UserID,
+		"organization_id": input.OrganizationID,
+		"document_type":   string(input.Type),
+	}
+	if input.ExpiryDate != nil {
+		fields["expiry_date"] = input.ExpiryDate.Format(time.DateOnly)
+	}
+	for key, value := range input.Metadata {
+		fields["metadata["+key+"]"] = value
+	}
+
+	req, err := multipartRequest(http.MethodPost, "/v1/documents", fields, input.FileName, input.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	var document Document
+	if err := d.client.do(ctx, req, &document); err != nil {
+		return nil, err
+	}
+
+	return &document, nil
+}
+
+// Update replaces the content of a document.
+func (d *DocumentsClient) Update(ctx context.Context, id string, fileName string, content []byte) (*Document, error) {
+	req, err := multipartRequest(http.MethodPut, documentPath(id), nil, fileName, content)
+	if err != nil {
+		return nil, err
+	}
+
+	var document Document
+	if err := d.client.do(ctx, req, &document); err != nil {
+		return nil, err
+	}
+
+	return &document, nil
+}
+
+// UpdateMetadata merges metadata into the document's metadata, an empty value removes the entry.
+func (d *DocumentsClient) UpdateMetadata(ctx context.Context, id string, metadata map[string]string) (*Document, error) {
+	req, err := jsonRequest(http.MethodPatch, documentPath(id), map[string]any{"metadata": metadata})
+	if err != nil {
+		return nil, err
+	}
+
+	var document Document
+	if err := d.client.do(ctx, req, &document); err != nil {
+		return nil, err
+	}
+
+	return &document, nil
+}
+
+// Rename sets the display name of a document, an empty name removes it.
+func (d *DocumentsClient) Rename(ctx context.Context, id string, displayName string) (*Document, error) {
+	req, err := jsonRequest(http.MethodPut, documentPath(id)+"/display-name", map[string]any{"display_name": displayName})
+	if err != nil {
+		return nil, err
+	}
+
+	var document Document
+	if err := d.client.do(ctx, req, &document); err != nil {
+		return nil, err
+	}
+
+	return &document, nil
+}
+
+// Redact blacks out regions of a document image, given as fractions of the image width and height.
+// The original content is preserved as a version of the document.
+func (d *DocumentsClient) Redact(ctx context.Context, id string, regions []Region) (*Document, error) {
+	req, err := jsonRequest(http.MethodPost, documentPath(id)+"/redact", map[string]any{"regions": regions})
+	if err != nil {
+		return nil, err
+	}
+
+	var document Document
+	if err := d.client.do(ctx, req, &document); err != nil {
+		return nil, err
+	}
+
+	return &document, nil
+}
+
+// Merge combines the images and PDFs of documents into a new PDF document, in order. Images become
+// one page each, PDFs add all their pages.
+// An empty documentType stores the PDF as a document of type other.
+func (d *DocumentsClient) Merge(ctx context.Context, documentIDs []string, documentType DocumentType) (*Document, error) {
+	req, err := jsonRequest(http.MethodPost, "/v1/documents/merge", map[string]any{"document_ids": documentIDs, "type": documentType})
+	if err != nil {
+		return nil, err
+	}
+
+	var document Document
+	if err := d.client.do(ctx, req, &document); err != nil {
+		return nil, err
+	}
+
+	return &document, nil
+}
+
+// Delete removes a document.
+func (d *DocumentsClient) Delete(ctx context.Context, id string) error {
+	return d.client.do(ctx, request{method: http.MethodDelete, path: documentPath(id)}, nil)
+}
+
+// DownloadURL is a short-lived signed URL to a document's content.
+type DownloadURL struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// GetDownloadURL returns a signed URL to download the document's content.
+func (d *DocumentsClient) GetDownloadURL(ctx context.Context, id string) (*DownloadURL, error) {
+	var download DownloadURL
+	if err := d.client.do(ctx, request{method: http.MethodGet, path: documentPath(id) + "/download-url"}, &download); err != nil {
+		return nil, err
+	}
+
+	return &download, nil
+}
+
+// documentPath returns the path of the document with the given ID.
+func documentPath(id string) string {
+	return "/v1/documents/" + url.PathEscape(id)
+}
+
+// multipartRequest creates a request with a multipart form body holding fields and the file.
+func multipartRequest(method, path string, fields map[string]string, fileName string, content []byte) (request, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	for name, value := range fields {
+		if value == "" {
+			continue
+		}
+		if err := writer.WriteField(name, value); err != nil {
+			return request{}, fmt.Errorf("failed to encode form field %s: %w", name, err)
+		}
+	}
+
+	part, err := writer.CreateFormFile("file", fileName)
+	if err != nil {
+		return request{}, fmt.Errorf("failed to encode file: %w", err)
+	}
+	if _, err := part.Write(content); err != nil {
+		return request{}, fmt.Errorf("failed to encode file: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return request{}, fmt.Errorf("failed to encode form: %w", err)
+	}
+
+	return request{method: method, path: path, body: body.Bytes(), contentType: writer.FormDataContentType()}, nil
+}
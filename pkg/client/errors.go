@@ -0,0 +1,87 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+var (
+	// ErrUnauthorized is matched by errors for requests without a valid token.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden is matched by errors for resources the caller has no access to.
+	ErrForbidden = errors.New("forbidden")
+	// ErrMFARequired is matched by errors for routes that need a multi-factor sign-in, the user
+	// must sign in again with their second factor. These errors match ErrForbidden as well.
+	ErrMFARequired = errors.New("mfa required")
+	// ErrNotFound is matched by errors for resources that do not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrInvalid is matched by errors for rejected request payloads, see APIError.Fields.
+	ErrInvalid = errors.New("invalid request")
+	// ErrRateLimited is matched by errors for requests rejected by rate limiting.
+	ErrRateLimited = errors.New("rate limited")
+)
+
+// FieldError describes a single validation failure on a request field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// APIError is returned for responses with an error status.
+// It matches the sentinel errors of this package with errors.Is.
+type APIError struct {
+	StatusCode int          `json:"status"`
+	Message    string       `json:"message"`
+	Detail     string       `json:"error"`
+	Fields     []FieldError `json:"fields,omitempty"`
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	if e.Detail != "" {
+		return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Message, e.Detail)
+	}
+
+	return fmt.Sprintf("%d %s", e.StatusCode, e.Message)
+}
+
+// Is maps the status code to the sentinel errors of this package.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrMFARequired:
+		return e.StatusCode == http.StatusForbidden && e.Detail == "mfa_required"
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrInvalid:
+		return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	default:
+		return false
+	}
+}
+
+// newAPIError builds an APIError from an error response. Bodies that are not the
+// standard error envelope, e.g. from a load balancer, keep the status text as message.
+func newAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err == nil {
+		_ = json.Unmarshal(body, apiErr)
+	}
+
+	apiErr.StatusCode = resp.StatusCode
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+
+	return apiErr
+}
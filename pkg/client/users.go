@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/thoughtgears/shared-services/internal/models"
+)
+
+type (
+	// User is a user profile as returned by the users API.
+	User = models.User
+	// NotificationDeliveryPage is a single page of a user's notification delivery log.
+	NotificationDeliveryPage = models.Page[*models.NotificationDelivery]
+	// UserActivity is a user's last-seen time and recent sign-ins.
+	UserActivity = models.UserActivity
+)
+
+// UsersClient calls the /v1/users endpoints.
+type UsersClient struct {
+	client *Client
+}
+
+// Get returns the user with the given Firebase ID.
+func (u *UsersClient) Get(ctx context.Context, id string) (*User, error) {
+	var user User
+	if err := u.client.do(ctx, request{method: http.MethodGet, path: "/v1/users/" + url.PathEscape(id)}, &user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// Create registers a new user.
+func (u *UsersClient) Create(ctx context.Context, user *User) (*User, error) {
+	req, err := jsonRequest(http.MethodPost, "/v1/users", user)
+	if err != nil {
+		return nil, err
+	}
+
+	var created User
+	if err := u.client.do(ctx, req, &created); err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+// Update replaces the profile of the user with the given ID.
+func (u *UsersClient) Update(ctx context.Context, id string, user *User) (*User, error) {
+	req, err := jsonRequest(http.MethodPut, "/v1/users/"+url.PathEscape(id), user)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated User
+	if err := u.client.do(ctx, req, &updated); err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// ListNotifications returns a single page of the notifications delivered to the user, newest first.
+// Only the authenticated user's own notifications can be listed.
+func (u *UsersClient) ListNotifications(ctx context.Context, id string, pageToken string, pageSize int) (*NotificationDeliveryPage, error) {
+	query := url.Values{}
+	if pageToken != "" {
+		query.Set("page_token", pageToken)
+	}
+	if pageSize > 0 {
+		query.Set("page_size", strconv.Itoa(pageSize))
+	}
+
+	var page NotificationDeliveryPage
+	req := request{method: http.MethodGet, path: "/v1/users/" + url.PathEscape(id) + "/notifications", query: query}
+	if err := u.client.do(ctx, req, &page); err != nil {
+		return nil, err
+	}
+
+	return &page, nil
+}
+
+// Activity returns when the user was last seen and their recent sign-ins.
+// Only the authenticated user's own activity can be read.
+func (u *UsersClient) Activity(ctx context.Context, id string) (*UserActivity, error) {
+	var activity UserActivity
+	if err := u.client.do(ctx, request{method: http.MethodGet, path: "/v1/users/" + url.PathEscape(id) + "/activity"}, &activity); err != nil {
+		return nil, err
+	}
+
+	return &activity, nil
+}
@@ -0,0 +1,60 @@
+package httptestutil
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/thoughtgears/shared-services/internal/models"
+)
+
+// AssertStatus fails t unless the response has the status code, and the status in its envelope
+// matches. Middleware rejections carry no status in their envelope, it is only checked when set.
+func AssertStatus(t testing.TB, rec *httptest.ResponseRecorder, code int) {
+	t.Helper()
+
+	if rec.Code != code {
+		t.Fatalf("status = %d, want %d\nbody: %s", rec.Code, code, rec.Body.String())
+	}
+
+	var env struct {
+		Status int `json:"status"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &env); err != nil {
+		t.Fatalf("response is not an envelope: %v\nbody: %s", err, rec.Body.String())
+	}
+	if env.Status != 0 && env.Status != code {
+		t.Errorf("envelope status = %d, want %d", env.Status, code)
+	}
+}
+
+// AssertError fails t unless the response has the status code and the error code in its error
+// envelope, which is returned for further checks such as the field errors.
+func AssertError(t testing.TB, rec *httptest.ResponseRecorder, code int, errorCode string) models.ErrorEnvelope {
+	t.Helper()
+
+	AssertStatus(t, rec, code)
+
+	var env models.ErrorEnvelope
+	if err := json.Unmarshal(rec.Body.Bytes(), &env); err != nil {
+		t.Fatalf("response is not an error envelope: %v\nbody: %s", err, rec.Body.String())
+	}
+	if env.Error != errorCode {
+		t.Errorf("error = %q, want %q", env.Error, errorCode)
+	}
+
+	return env
+}
+
+// DecodeData returns the data of the envelope of a successful response, failing t when it is not
+// a T.
+func DecodeData[T any](t testing.TB, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+
+	var env models.Envelope[T]
+	if err := json.Unmarshal(rec.Body.Bytes(), &env); err != nil {
+		t.Fatalf("response data is not a %T: %v\nbody: %s", env.Data, err, rec.Body.String())
+	}
+
+	return env.Data
+}
@@ -0,0 +1,67 @@
+package httptestutil
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+)
+
+// File is a file part of a multipart upload.
+type File struct {
+	// Field is the form field of the file, "file" when empty.
+	Field    string
+	Name     string
+	Content  []byte
+	MimeType string
+}
+
+// Upload serves a multipart/form-data POST to engine with the form fields and files, authenticated
+// with idToken when it is not empty, and returns the recorded response.
+func Upload(engine http.Handler, path, idToken string, fields map[string]string, files ...File) *httptest.ResponseRecorder {
+	body, contentType := Multipart(fields, files...)
+
+	req := httptest.NewRequest(http.MethodPost, path, body)
+	req.Header.Set("Content-Type", contentType)
+
+	return Serve(engine, req, idToken)
+}
+
+// Multipart encodes the form fields and files as a multipart/form-data body and returns it with
+// its content type.
+func Multipart(fields map[string]string, files ...File) (*bytes.Buffer, string) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+
+	for key, value := range fields {
+		if err := w.WriteField(key, value); err != nil {
+			panic(fmt.Sprintf("httptestutil: failed to write form field %s: %v", key, err))
+		}
+	}
+
+	for _, f := range files {
+		field := f.Field
+		if field == "" {
+			field = "file"
+		}
+		header := make(map[string][]string)
+		header["Content-Disposition"] = []string{fmt.Sprintf(`form-data; name=%q; filename=%q`, field, f.Name)}
+		if f.MimeType != "" {
+			header["Content-Type"] = []string{f.MimeType}
+		}
+		part, err := w.CreatePart(header)
+		if err != nil {
+			panic(fmt.Sprintf("httptestutil: failed to create file part %s: %v", f.Name, err))
+		}
+		if _, err := part.Write(f.Content); err != nil {
+			panic(fmt.Sprintf("httptestutil: failed to write file part %s: %v", f.Name, err))
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		panic(fmt.Sprintf("httptestutil: failed to close multipart body: %v", err))
+	}
+
+	return &body, w.FormDataContentType()
+}
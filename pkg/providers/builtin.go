@@ -0,0 +1,69 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/thoughtgears/shared-services/internal/db"
+	"github.com/thoughtgears/shared-services/internal/gcs"
+)
+
+func init() {
+	RegisterDatabase("firestore", newFirestoreRepositories)
+	RegisterDatabase("mongodb", newMongoRepositories)
+	RegisterDatabase("spanner", newSpannerRepositories)
+	RegisterStorage("gcs", newGCSStorage)
+}
+
+// newFirestoreRepositories keeps the collections in the Firestore database of the environment.
+func newFirestoreRepositories(_ context.Context, env *Environment) (*Repositories, error) {
+	return NewBackendRepositories(env, db.NewFirestoreBackend(env.Firestore)), nil
+}
+
+// newMongoRepositories keeps the collections in the MongoDB database at MONGODB_URI.
+func newMongoRepositories(ctx context.Context, env *Environment) (*Repositories, error) {
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(env.Config.MongoURI))
+	if err != nil {
+		return nil, fmt.Errorf("connect to mongodb: %w", err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		return nil, fmt.Errorf("ping mongodb: %w", err)
+	}
+	if env.OnShutdown != nil {
+		env.OnShutdown(client.Disconnect)
+	}
+
+	return NewBackendRepositories(env, db.NewMongoBackend(client.Database(env.Config.MongoDatabase))), nil
+}
+
+// newSpannerRepositories keeps the collections in tables of the Cloud Spanner database
+// SPANNER_DATABASE on SPANNER_INSTANCE, creating the tables and columns that are missing.
+func newSpannerRepositories(ctx context.Context, env *Environment) (*Repositories, error) {
+	if env.Config.SpannerInstance == "" {
+		return nil, errors.New("spanner backend: SPANNER_INSTANCE is not set")
+	}
+
+	service, err := env.Clients.Spanner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	database := fmt.Sprintf("projects/%s/instances/%s/databases/%s", env.Config.ProjectID, env.Config.SpannerInstance, env.Config.SpannerDatabase)
+	client := db.NewSpannerClient(service, database)
+	if err := client.EnsureTables(ctx, spannerTables()...); err != nil {
+		return nil, fmt.Errorf("prepare spanner database: %w", err)
+	}
+	if env.OnShutdown != nil {
+		env.OnShutdown(client.Close)
+	}
+
+	return NewBackendRepositories(env, db.NewSpannerBackend(client)), nil
+}
+
+// newGCSStorage stores objects in a Cloud Storage bucket.
+func newGCSStorage(_ context.Context, env *Environment, bucket string) (gcs.Storage, error) {
+	return gcs.NewGCSStorage(env.Storage, bucket, env.Signer)
+}
@@ -0,0 +1,142 @@
+// Package providers assembles the repositories and storage of a service from named providers, so
+// the backend can be picked per environment from the configuration instead of in main. A new
+// backend registers its provider from an init function:
+//
+//	func init() {
+//		providers.RegisterDatabase("postgres", newPostgresRepositories)
+//	}
+//
+// and is selected with DATABASE_BACKEND=postgres. Storage providers are selected with
+// STORAGE_BACKEND in the same way.
+package providers
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/storage"
+
+	"github.com/thoughtgears/shared-services/internal/clients"
+	"github.com/thoughtgears/shared-services/internal/config"
+	"github.com/thoughtgears/shared-services/internal/fieldcrypt"
+	"github.com/thoughtgears/shared-services/internal/gcs"
+	"github.com/thoughtgears/shared-services/pkg/resilience"
+)
+
+// Environment is what providers build on: the configuration and the clients shared by the
+// service, created once in main.
+type Environment struct {
+	Config  config.Config
+	Clients *clients.Builder
+
+	Firestore *firestore.Client
+	Storage   *storage.Client
+	// Signer signs download URLs, nil signs with the detected credentials.
+	Signer *gcs.IAMSigner
+	// Cipher encrypts tagged fields, nil disables field encryption.
+	Cipher *fieldcrypt.Cipher
+	// Policy wraps every repository and bucket with timeouts, retries and hedging.
+	Policy resilience.Policy
+
+	// OnShutdown adds a hook closing what a provider opened when the service stops.
+	OnShutdown func(fn func(ctx context.Context) error)
+}
+
+// DatabaseProvider creates the repositories of a database backend.
+type DatabaseProvider func(ctx context.Context, env *Environment) (*Repositories, error)
+
+// StorageProvider creates the storage of a bucket.
+type StorageProvider func(ctx context.Context, env *Environment, bucket string) (gcs.Storage, error)
+
+var (
+	mu        sync.RWMutex
+	databases = make(map[string]DatabaseProvider)
+	storages  = make(map[string]StorageProvider)
+)
+
+// RegisterDatabase makes a database provider available under name. It panics when the name is
+// already taken, like registering the same database/sql driver twice.
+func RegisterDatabase(name string, provider DatabaseProvider) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, ok := databases[name]; ok {
+		panic("providers: database provider registered twice: " + name)
+	}
+	databases[name] = provider
+}
+
+// RegisterStorage makes a storage provider available under name. It panics when the name is
+// already taken.
+func RegisterStorage(name string, provider StorageProvider) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, ok := storages[name]; ok {
+		panic("providers: storage provider registered twice: " + name)
+	}
+	storages[name] = provider
+}
+
+// NewRepositories creates the repositories with the provider named by DATABASE_BACKEND.
+func NewRepositories(ctx context.Context, env *Environment) (*Repositories, error) {
+	mu.RLock()
+	provider, ok := databases[env.Config.DatabaseBackend]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown database backend: %s (available: %s)", env.Config.DatabaseBackend, names(databases))
+	}
+
+	return provider(ctx, env)
+}
+
+// NewRegionStorages creates the storage of every region's bucket with the provider named by
+// STORAGE_BACKEND. The primary bucket serves the deployment region, residency buckets add the
+// other regions. With tenancy enabled every tenant gets its own object prefix.
+func NewRegionStorages(ctx context.Context, env *Environment) (map[string]gcs.Storage, error) {
+	mu.RLock()
+	provider, ok := storages[env.Config.StorageBackend]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown storage backend: %s (available: %s)", env.Config.StorageBackend, names(storages))
+	}
+
+	regionStorages := make(map[string]gcs.Storage)
+	for region, bucket := range RegionBuckets(env.Config) {
+		bucketStore, err := provider(ctx, env, bucket)
+		if err != nil {
+			return nil, fmt.Errorf("create storage for bucket %s: %w", bucket, err)
+		}
+		storageStore := resilience.NewStorage(bucketStore, env.Policy)
+		if env.Config.TenancyEnabled {
+			regionStorages[region] = gcs.NewTenantScopedStorage(storageStore)
+
+			continue
+		}
+		regionStorages[region] = storageStore
+	}
+
+	return regionStorages, nil
+}
+
+// RegionBuckets returns the bucket of every region, the primary bucket in the deployment region
+// and the residency buckets in theirs.
+func RegionBuckets(cfg config.Config) map[string]string {
+	regionBuckets := map[string]string{cfg.Region: cfg.BucketName}
+	maps.Copy(regionBuckets, cfg.ResidencyBuckets)
+
+	return regionBuckets
+}
+
+// names lists the registered provider names for errors.
+func names[P any](providers map[string]P) string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return strings.Join(slices.Sorted(maps.Keys(providers)), ", ")
+}
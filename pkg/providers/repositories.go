@@ -0,0 +1,126 @@
+package providers
+
+import (
+	"github.com/thoughtgears/shared-services/internal/db"
+	"github.com/thoughtgears/shared-services/internal/migrations"
+	"github.com/thoughtgears/shared-services/internal/models"
+	"github.com/thoughtgears/shared-services/pkg/resilience"
+)
+
+const (
+	userCollection         = "users"
+	documentCollection     = "documents"
+	documentTypeCollection = "document_types"
+	organizationCollection = "organizations"
+	membershipCollection   = "memberships"
+	invitationCollection   = "invitations"
+	bundleCollection       = "bundles"
+	deliveryCollection     = "notification_deliveries"
+	activityCollection     = "user_activity"
+	// commentCollection is a subcollection of each document
+	commentCollection = "comments"
+	// Access tokens are looked up before the tenant is known, they record their tenant instead
+	accessTokenCollection = "access_tokens"
+	// Session revocations are checked before the tenant is known, they record their tenant instead
+	sessionRevocationCollection = "session_revocations"
+	// Webhook nonces are recorded before the tenant is known
+	webhookNonceCollection = "webhook_nonces"
+
+	// TenantSettingsCollection keys tenant settings by tenant ID in one shared collection.
+	TenantSettingsCollection = "tenant_settings"
+)
+
+// Repositories are the repositories the services are built on.
+type Repositories struct {
+	Documents          db.DB[models.Document]
+	Users              db.DB[models.User]
+	Organizations      db.DB[models.Organization]
+	Memberships        db.DB[models.Membership]
+	Invitations        db.DB[models.Invitation]
+	Bundles            db.DB[models.Bundle]
+	Deliveries         db.DB[models.NotificationDelivery]
+	Activity           db.DB[models.UserActivity]
+	TenantSettings     db.DB[models.TenantSettings]
+	AccessTokens       db.DB[models.AccessToken]
+	SessionRevocations db.DB[models.SessionRevocation]
+	WebhookNonces      db.DB[models.WebhookNonce]
+	DocumentTypes      db.DB[models.DocumentTypeDefinition]
+	// Comments returns the repository of the comments on a document.
+	Comments func(documentID string) db.DB[models.Comment]
+	// Snapshots runs reads against a consistent snapshot, nil when the backend has none.
+	Snapshots *db.Snapshotter
+}
+
+// NewBackendRepositories creates the repositories on a db.Backend, with the schema migrations,
+// field encryption and resilience policy of the environment. With multi-tenancy every tenant
+// gets its own collections, resolved per request; tenant settings, access tokens, session
+// revocations, webhook nonces and document types are shared by all tenants.
+func NewBackendRepositories(env *Environment, backend *db.Backend) *Repositories {
+	// Metadata keys used in filters must stay queryable, so they are not encrypted
+	metadataPlaintextPaths := make([]string, 0, len(env.Config.DocumentMetadataFilterKeys))
+	for _, key := range env.Config.DocumentMetadataFilterKeys {
+		metadataPlaintextPaths = append(metadataPlaintextPaths, "metadata."+key)
+	}
+
+	tenancy := env.Config.TenancyEnabled
+	policy := env.Policy
+
+	return &Repositories{
+		Documents: newRepository[models.Document](backend, tenancy, policy, documentCollection,
+			db.WithMigrations(migrations.Documents()),
+			db.WithFieldEncryption(env.Cipher, metadataPlaintextPaths...),
+		),
+		Users: newRepository[models.User](backend, tenancy, policy, userCollection,
+			db.WithMigrations(migrations.Users()),
+			db.WithFieldEncryption(env.Cipher),
+		),
+		Organizations:      newRepository[models.Organization](backend, tenancy, policy, organizationCollection),
+		Memberships:        newRepository[models.Membership](backend, tenancy, policy, membershipCollection),
+		Invitations:        newRepository[models.Invitation](backend, tenancy, policy, invitationCollection),
+		Bundles:            newRepository[models.Bundle](backend, tenancy, policy, bundleCollection),
+		Deliveries:         newRepository[models.NotificationDelivery](backend, tenancy, policy, deliveryCollection),
+		Activity:           newRepository[models.UserActivity](backend, tenancy, policy, activityCollection),
+		TenantSettings:     newRepository[models.TenantSettings](backend, false, policy, TenantSettingsCollection),
+		AccessTokens:       newRepository[models.AccessToken](backend, false, policy, accessTokenCollection),
+		SessionRevocations: newRepository[models.SessionRevocation](backend, false, policy, sessionRevocationCollection),
+		WebhookNonces:      newRepository[models.WebhookNonce](backend, false, policy, webhookNonceCollection),
+		DocumentTypes:      newRepository[models.DocumentTypeDefinition](backend, false, policy, documentTypeCollection),
+		Comments: func(documentID string) db.DB[models.Comment] {
+			return newRepository[models.Comment](backend, tenancy, policy, documentCollection+"/"+documentID+"/"+commentCollection,
+				db.WithFieldEncryption(env.Cipher),
+			)
+		},
+		Snapshots: backend.Snapshotter(),
+	}
+}
+
+// spannerTables returns the Spanner tables of the collections in NewBackendRepositories. The
+// collections of every tenant, and the comments of every document, share a table.
+func spannerTables() []db.SpannerTable {
+	return []db.SpannerTable{
+		db.NewSpannerTable[models.Document](documentCollection),
+		db.NewSpannerTable[models.User](userCollection),
+		db.NewSpannerTable[models.Organization](organizationCollection),
+		db.NewSpannerTable[models.Membership](membershipCollection),
+		db.NewSpannerTable[models.Invitation](invitationCollection),
+		db.NewSpannerTable[models.Bundle](bundleCollection),
+		db.NewSpannerTable[models.NotificationDelivery](deliveryCollection),
+		db.NewSpannerTable[models.UserActivity](activityCollection),
+		db.NewSpannerTable[models.TenantSettings](TenantSettingsCollection),
+		db.NewSpannerTable[models.AccessToken](accessTokenCollection),
+		db.NewSpannerTable[models.SessionRevocation](sessionRevocationCollection),
+		db.NewSpannerTable[models.WebhookNonce](webhookNonceCollection),
+		db.NewSpannerTable[models.DocumentTypeDefinition](documentTypeCollection),
+		db.NewSpannerTable[models.Comment](commentCollection),
+	}
+}
+
+// newRepository creates a repository on the backend, scoped to the tenant in the context when
+// tenancy is set, and wraps it with the policy.
+func newRepository[T any](backend *db.Backend, tenancy bool, policy resilience.Policy, collectionName string, opts ...db.RepositoryOption) db.DB[T] {
+	if tenancy {
+		return resilience.NewDB(db.NewTenantScopedBackendRepository[T](backend, collectionName, opts...), policy)
+	}
+
+	return resilience.NewDB(db.NewRepository[T](backend, collectionName, opts...), policy)
+}
@@ -0,0 +1,132 @@
+package resilience
+
+import (
+	"context"
+	"time"
+
+	"github.com/thoughtgears/shared-services/internal/db"
+)
+
+// resilientDB is a DB decorator applying a Policy to every call of the wrapped repository.
+type resilientDB[T any] struct {
+	repo   db.DB[T]
+	policy Policy
+}
+
+// NewDB wraps repo with the policy. Reads are retried and hedged; creates, updates and deletes
+// write the whole given data or remove the document, so they are safe to repeat and are retried
+// but never hedged. PrepareCreate does no I/O and Changes streams run as long as their context,
+// so both are passed through.
+//
+// Parameters:
+//   - repo: Repository to wrap
+//   - policy: Timeouts, retries and hedging applied to the calls
+//
+// Returns:
+//   - db.DB[T]: The wrapped repository
+func NewDB[T any](repo db.DB[T], policy Policy) db.DB[T] {
+	return &resilientDB[T]{repo: repo, policy: policy}
+}
+
+// list is the result of a paginated read.
+type list[T any] struct {
+	items     []*T
+	nextToken string
+}
+
+func (r *resilientDB[T]) GetAll(ctx context.Context, pageToken string, pageSize int) ([]*T, string, error) {
+	result, err := do(ctx, r.policy, true, func(ctx context.Context) (list[T], error) {
+		items, nextToken, err := r.repo.GetAll(ctx, pageToken, pageSize)
+		return list[T]{items: items, nextToken: nextToken}, err
+	})
+
+	return result.items, result.nextToken, err
+}
+
+func (r *resilientDB[T]) GetByID(ctx context.Context, id string) (*T, error) {
+	return do(ctx, r.policy, true, func(ctx context.Context) (*T, error) {
+		return r.repo.GetByID(ctx, id)
+	})
+}
+
+func (r *resilientDB[T]) GetByQuery(ctx context.Context, queries []db.QueryConstraint, pageToken string, pageSize int) ([]*T, string, error) {
+	result, err := do(ctx, r.policy, true, func(ctx context.Context) (list[T], error) {
+		items, nextToken, err := r.repo.GetByQuery(ctx, queries, pageToken, pageSize)
+		return list[T]{items: items, nextToken: nextToken}, err
+	})
+
+	return result.items, result.nextToken, err
+}
+
+func (r *resilientDB[T]) Find(ctx context.Context, query *db.QueryBuilder[T]) ([]*T, string, error) {
+	result, err := do(ctx, r.policy, true, func(ctx context.Context) (list[T], error) {
+		items, nextToken, err := r.repo.Find(ctx, query)
+		return list[T]{items: items, nextToken: nextToken}, err
+	})
+
+	return result.items, result.nextToken, err
+}
+
+func (r *resilientDB[T]) FindPage(ctx context.Context, query *db.QueryBuilder[T]) (*db.Page[T], error) {
+	return do(ctx, r.policy, true, func(ctx context.Context) (*db.Page[T], error) {
+		return r.repo.FindPage(ctx, query)
+	})
+}
+
+// Create is retried with a copy of data for every attempt, as repositories prepare the data they
+// are given in place.
+func (r *resilientDB[T]) Create(ctx context.Context, id string, data map[string]interface{}) (*T, error) {
+	return do(ctx, r.policy, false, func(ctx context.Context) (*T, error) {
+		return r.repo.Create(ctx, id, cloneData(data))
+	})
+}
+
+func (r *resilientDB[T]) PrepareCreate(ctx context.Context, id string, data map[string]interface{}) (db.Write, error) {
+	return r.repo.PrepareCreate(ctx, id, data)
+}
+
+// Update is retried with a copy of data for every attempt, like Create.
+func (r *resilientDB[T]) Update(ctx context.Context, id string, data map[string]interface{}) (*T, error) {
+	return do(ctx, r.policy, false, func(ctx context.Context) (*T, error) {
+		return r.repo.Update(ctx, id, cloneData(data))
+	})
+}
+
+// UpdateIfUnchanged is retried like Update. A retry after a write that succeeded but timed out
+// fails with db.ErrChanged, as the document is no longer the one read.
+func (r *resilientDB[T]) UpdateIfUnchanged(ctx context.Context, id string, updatedAt time.Time, data map[string]interface{}) (*T, error) {
+	return do(ctx, r.policy, false, func(ctx context.Context) (*T, error) {
+		return r.repo.UpdateIfUnchanged(ctx, id, updatedAt, cloneData(data))
+	})
+}
+
+func (r *resilientDB[T]) Delete(ctx context.Context, id string) error {
+	_, err := do(ctx, r.policy, false, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, r.repo.Delete(ctx, id)
+	})
+
+	return err
+}
+
+func (r *resilientDB[T]) Count(ctx context.Context, queries []db.QueryConstraint) (int64, error) {
+	return do(ctx, r.policy, true, func(ctx context.Context) (int64, error) {
+		return r.repo.Count(ctx, queries)
+	})
+}
+
+func (r *resilientDB[T]) Changes(ctx context.Context, since time.Time) (db.ChangeStream[T], error) {
+	return r.repo.Changes(ctx, since)
+}
+
+// cloneData copies data and the maps nested in it.
+func cloneData(data map[string]interface{}) map[string]interface{} {
+	clone := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		if nested, ok := value.(map[string]interface{}); ok {
+			value = cloneData(nested)
+		}
+		clone[key] = value
+	}
+
+	return clone
+}
@@ -0,0 +1,63 @@
+package resilience
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/thoughtgears/shared-services/internal/db"
+)
+
+type item struct {
+	Name string
+}
+
+// flakyDB fails the first create with a transient error after preparing data in place, as
+// repositories do.
+type flakyDB struct {
+	db.DB[item]
+	// creates are the data of each create as received, before it is prepared
+	creates []string
+}
+
+func (f *flakyDB) Create(_ context.Context, _ string, data map[string]interface{}) (*item, error) {
+	f.creates = append(f.creates, fmt.Sprint(data))
+
+	data["updated_at"] = "now"
+	data["address"].(map[string]interface{})["street"] = "encrypted"
+	if len(f.creates) == 1 {
+		return nil, errUnavailable
+	}
+
+	return &item{Name: data["name"].(string)}, nil
+}
+
+func TestDBRetriesWithOriginalData(t *testing.T) {
+	repo := &flakyDB{}
+	users := NewDB[item](repo, Policy{MaxRetries: 1})
+
+	data := map[string]interface{}{
+		"name":    "Ada",
+		"address": map[string]interface{}{"street": "Main Street"},
+	}
+	if _, err := users.Create(context.Background(), "user-1", data); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	if len(repo.creates) != 2 {
+		t.Fatalf("creates = %d, want 2", len(repo.creates))
+	}
+	want := map[string]interface{}{
+		"name":    "Ada",
+		"address": map[string]interface{}{"street": "Main Street"},
+	}
+	for i, got := range repo.creates {
+		if got != fmt.Sprint(want) {
+			t.Errorf("create %d got %v, want %v", i+1, got, want)
+		}
+	}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("caller's data = %v, want it unchanged", data)
+	}
+}
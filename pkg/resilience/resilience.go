@@ -0,0 +1,187 @@
+// Package resilience provides decorators that wrap a DB[T] or Storage implementation with
+// per-operation timeouts, bounded retries of transient failures and optional hedging of reads,
+// so every backend gets the same robustness without implementing it.
+//
+//	policy := resilience.Policy{Timeout: 10 * time.Second, MaxRetries: 2, HedgeDelay: 200 * time.Millisecond}
+//	users := resilience.NewDB(db.NewRepository[models.User](backend, "users"), policy)
+//	files := resilience.NewStorage(storage, policy)
+package resilience
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	baseBackoff = 100 * time.Millisecond
+	maxBackoff  = 2 * time.Second
+)
+
+// Policy configures the decorators. The zero Policy passes every call through unchanged.
+type Policy struct {
+	// Timeout bounds every attempt of an operation, 0 disables it.
+	Timeout time.Duration
+	// MaxRetries is the number of times a failed operation is retried, 0 disables retries.
+	MaxRetries int
+	// HedgeDelay starts a second attempt of a read that has not returned after the delay, the
+	// first result wins. 0 disables hedging.
+	HedgeDelay time.Duration
+	// Retryable reports whether a failed attempt may be retried, Transient when nil.
+	Retryable func(err error) bool
+}
+
+// Transient reports whether err is a failure that may succeed when retried: unavailable or
+// overloaded Firestore, Cloud Storage and MongoDB servers, aborted transactions, network errors
+// and attempts that ran out of time.
+func Transient(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	if s, ok := status.FromError(err); ok {
+		switch s.Code() {
+		case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.DeadlineExceeded:
+			return true
+		}
+	}
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.Code {
+		case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+	}
+
+	if mongo.IsNetworkError(err) || mongo.IsTimeout(err) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// retryable reports whether err may be retried under the policy.
+func (p Policy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+
+	return Transient(err)
+}
+
+// withTimeout returns the context of a single attempt.
+func (p Policy) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, p.Timeout)
+}
+
+// do runs op under the policy, retrying transient failures with backoff. Reads are hedged when
+// the policy has a hedge delay. Retries stop once ctx is done.
+func do[R any](ctx context.Context, p Policy, read bool, op func(ctx context.Context) (R, error)) (R, error) {
+	for attempt := 0; ; attempt++ {
+		var (
+			result R
+			err    error
+		)
+		if read && p.HedgeDelay > 0 {
+			result, err = hedged(ctx, p, op)
+		} else {
+			result, err = once(ctx, p, op)
+		}
+		if err == nil {
+			return result, nil
+		}
+
+		if attempt >= p.MaxRetries || ctx.Err() != nil || !p.retryable(err) {
+			return result, err
+		}
+
+		if sleepErr := sleep(ctx, backoff(attempt)); sleepErr != nil {
+			return result, errors.Join(err, sleepErr)
+		}
+	}
+}
+
+// once runs a single attempt of op.
+func once[R any](ctx context.Context, p Policy, op func(ctx context.Context) (R, error)) (R, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	return op(ctx)
+}
+
+// hedged runs an attempt of op and starts a second one if the first has not returned after the
+// hedge delay. The first success is returned and the other attempt is cancelled; the attempt is
+// only failed once every started attempt failed.
+func hedged[R any](ctx context.Context, p Policy, op func(ctx context.Context) (R, error)) (R, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type outcome struct {
+		result R
+		err    error
+	}
+	// Buffered for both attempts, so the losing one never blocks
+	outcomes := make(chan outcome, 2)
+	run := func() {
+		result, err := once(ctx, p, op)
+		outcomes <- outcome{result: result, err: err}
+	}
+
+	go run()
+	pending := 1
+
+	timer := time.NewTimer(p.HedgeDelay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			pending++
+			go run()
+		case o := <-outcomes:
+			pending--
+			if o.err == nil || pending == 0 {
+				return o.result, o.err
+			}
+		}
+	}
+}
+
+// backoff returns the delay before the next attempt.
+func backoff(attempt int) time.Duration {
+	delay := maxBackoff
+	if attempt < 8 {
+		delay = min(baseBackoff<<attempt, maxBackoff)
+	}
+
+	// Full jitter keeps callers that failed together from retrying together
+	return rand.N(delay) + time.Millisecond
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
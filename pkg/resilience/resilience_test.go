@@ -0,0 +1,175 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errUnavailable is a transient failure of a backend.
+var errUnavailable = status.Error(codes.Unavailable, "unavailable")
+
+func TestTransient(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want bool
+	}{
+		"unavailable":       {errUnavailable, true},
+		"aborted":           {status.Error(codes.Aborted, "aborted"), true},
+		"not found":         {status.Error(codes.NotFound, "not found"), false},
+		"permission denied": {status.Error(codes.PermissionDenied, "denied"), false},
+		"api 503":           {&googleapi.Error{Code: http.StatusServiceUnavailable}, true},
+		"api 429":           {&googleapi.Error{Code: http.StatusTooManyRequests}, true},
+		"api 404":           {&googleapi.Error{Code: http.StatusNotFound}, false},
+		"deadline exceeded": {fmt.Errorf("read: %w", context.DeadlineExceeded), true},
+		"canceled":          {context.Canceled, false},
+		"network":           {&net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		"other":             {errors.New("invalid argument"), false},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := Transient(tt.err); got != tt.want {
+				t.Errorf("Transient(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDoRetries(t *testing.T) {
+	errInvalid := errors.New("invalid argument")
+
+	tests := map[string]struct {
+		errs      []error
+		wantErr   error
+		wantCalls int
+	}{
+		"success":                 {errs: nil, wantCalls: 1},
+		"transient then success":  {errs: []error{errUnavailable}, wantCalls: 2},
+		"not retryable":           {errs: []error{errInvalid}, wantErr: errInvalid, wantCalls: 1},
+		"retries exhausted":       {errs: []error{errUnavailable, errUnavailable, errUnavailable}, wantErr: errUnavailable, wantCalls: 3},
+		"transient then not":      {errs: []error{errUnavailable, errInvalid}, wantErr: errInvalid, wantCalls: 2},
+		"success after two fails": {errs: []error{errUnavailable, errUnavailable}, wantCalls: 3},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			calls := 0
+			result, err := do(context.Background(), Policy{MaxRetries: 2}, false, func(context.Context) (string, error) {
+				calls++
+				if calls <= len(tt.errs) {
+					return "", tt.errs[calls-1]
+				}
+
+				return "ok", nil
+			})
+
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("do() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && result != "ok" {
+				t.Errorf("do() = %q, want %q", result, "ok")
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDoTimesOutAttempts(t *testing.T) {
+	calls := 0
+	_, err := do(context.Background(), Policy{Timeout: 10 * time.Millisecond, MaxRetries: 1}, false, func(ctx context.Context) (string, error) {
+		calls++
+		<-ctx.Done()
+
+		return "", ctx.Err()
+	})
+
+	// An attempt that ran out of time is transient, so it is retried once
+	if !errors.Is(err, context.DeadlineExceeded) || calls != 2 {
+		t.Errorf("do() error = %v after %d calls, want a deadline exceeded after 2", err, calls)
+	}
+}
+
+func TestDoStopsWhenCancelledDuringBackoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	calls := 0
+	start := time.Now()
+	_, err := do(ctx, Policy{MaxRetries: 5}, false, func(context.Context) (string, error) {
+		calls++
+		// Cancelled after the attempt failed, while do waits to retry
+		time.AfterFunc(time.Millisecond, cancel)
+
+		return "", errUnavailable
+	})
+
+	if !errors.Is(err, errUnavailable) || !errors.Is(err, context.Canceled) {
+		t.Errorf("do() error = %v, want the attempt's error and context.Canceled", err)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want no retry after the cancellation", calls)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("do() returned after %s, want it to stop waiting when cancelled", elapsed)
+	}
+}
+
+func TestHedgedReadWins(t *testing.T) {
+	var (
+		calls          atomic.Int32
+		firstCancelled = make(chan struct{})
+	)
+	result, err := do(context.Background(), Policy{HedgeDelay: 10 * time.Millisecond}, true, func(ctx context.Context) (string, error) {
+		if calls.Add(1) == 1 {
+			// The first attempt hangs until the hedge wins and cancels it
+			<-ctx.Done()
+			close(firstCancelled)
+
+			return "", ctx.Err()
+		}
+
+		return "hedge", nil
+	})
+
+	if err != nil || result != "hedge" {
+		t.Fatalf("do() = %q, %v, want the hedged attempt's result", result, err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("calls = %d, want 2", got)
+	}
+	select {
+	case <-firstCancelled:
+	case <-time.After(time.Second):
+		t.Error("the losing attempt was not cancelled")
+	}
+}
+
+func TestHedgedReadFailsOnceAllAttemptsFail(t *testing.T) {
+	var calls atomic.Int32
+	errFirst, errSecond := errors.New("first"), errors.New("second")
+
+	_, err := do(context.Background(), Policy{HedgeDelay: 5 * time.Millisecond}, true, func(context.Context) (string, error) {
+		if calls.Add(1) == 1 {
+			time.Sleep(20 * time.Millisecond)
+
+			return "", errFirst
+		}
+
+		return "", errSecond
+	})
+
+	// The hedge failed first, the result waits for the first attempt
+	if !errors.Is(err, errFirst) || calls.Load() != 2 {
+		t.Errorf("do() error = %v after %d calls, want the last attempt's error after 2", err, calls.Load())
+	}
+}
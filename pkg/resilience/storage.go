@@ -0,0 +1,128 @@
+package resilience
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/thoughtgears/shared-services/internal/gcs"
+)
+
+// resilientStorage is a Storage decorator applying a Policy to every call of the wrapped storage.
+type resilientStorage struct {
+	storage gcs.Storage
+	policy  Policy
+}
+
+// NewStorage wraps storage with the policy. Transfers take as long as the object is large, so the
+// timeout of a download only covers opening the object and uploads have none. Uploads are only
+// retried when the content can be rewound, i.e. is an io.Seeker. Lists are hedged.
+//
+// Parameters:
+//   - storage: Storage to wrap
+//   - policy: Timeouts, retries and hedging applied to the calls
+//
+// Returns:
+//   - gcs.Storage: The wrapped storage
+func NewStorage(storage gcs.Storage, policy Policy) gcs.Storage {
+	return &resilientStorage{storage: storage, policy: policy}
+}
+
+func (s *resilientStorage) Upload(ctx context.Context, path string, content io.Reader, contentType string) (*gcs.FileInfo, error) {
+	seeker, ok := content.(io.Seeker)
+	if !ok {
+		return s.storage.Upload(ctx, path, content, contentType)
+	}
+
+	policy := s.policy
+	policy.Timeout = 0
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+
+	return do(ctx, policy, false, func(ctx context.Context) (*gcs.FileInfo, error) {
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			return nil, err
+		}
+
+		return s.storage.Upload(ctx, path, content, contentType)
+	})
+}
+
+func (s *resilientStorage) Download(ctx context.Context, path string) (io.ReadCloser, error) {
+	// The reader reads with the context it was opened with, so it is cancelled on close instead
+	policy := s.policy
+	policy.Timeout = 0
+
+	return do(ctx, policy, false, func(ctx context.Context) (io.ReadCloser, error) {
+		ctx, cancel := context.WithCancel(ctx)
+		if s.policy.Timeout > 0 {
+			timer := time.AfterFunc(s.policy.Timeout, cancel)
+			defer timer.Stop()
+		}
+
+		reader, err := s.storage.Download(ctx, path)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+
+		return &cancelReader{ReadCloser: reader, cancel: cancel}, nil
+	})
+}
+
+func (s *resilientStorage) Delete(ctx context.Context, path string) error {
+	_, err := do(ctx, s.policy, false, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, s.storage.Delete(ctx, path)
+	})
+
+	return err
+}
+
+func (s *resilientStorage) List(ctx context.Context, prefix string) ([]gcs.FileInfo, error) {
+	return do(ctx, s.policy, true, func(ctx context.Context) ([]gcs.FileInfo, error) {
+		return s.storage.List(ctx, prefix)
+	})
+}
+
+func (s *resilientStorage) SignedURL(ctx context.Context, path string, expires time.Duration, downloadName string) (string, error) {
+	return do(ctx, s.policy, false, func(ctx context.Context) (string, error) {
+		return s.storage.SignedURL(ctx, path, expires, downloadName)
+	})
+}
+
+func (s *resilientStorage) SetStorageClass(ctx context.Context, path string, class string) error {
+	// Rewriting a large object takes several calls, it is not cut short like the other operations
+	policy := s.policy
+	policy.Timeout = 0
+
+	_, err := do(ctx, policy, false, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, s.storage.SetStorageClass(ctx, path, class)
+	})
+
+	return err
+}
+
+func (s *resilientStorage) SetHold(ctx context.Context, path string, hold string, held bool) error {
+	_, err := do(ctx, s.policy, false, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, s.storage.SetHold(ctx, path, hold, held)
+	})
+
+	return err
+}
+
+// cancelReader cancels the context of a download when it is closed.
+type cancelReader struct {
+	io.ReadCloser
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+}
+
+func (r *cancelReader) Close() error {
+	err := r.ReadCloser.Close()
+	r.closeOnce.Do(r.cancel)
+
+	return err
+}
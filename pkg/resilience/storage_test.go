@@ -0,0 +1,69 @@
+package resilience
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/thoughtgears/shared-services/internal/gcs"
+)
+
+// flakyStorage fails the first upload with a transient error after reading part of the content.
+type flakyStorage struct {
+	gcs.Storage
+	uploads []string
+}
+
+func (s *flakyStorage) Upload(_ context.Context, path string, content io.Reader, _ string) (*gcs.FileInfo, error) {
+	if len(s.uploads) == 0 {
+		partial := make([]byte, 2)
+		_, _ = io.ReadFull(content, partial)
+		s.uploads = append(s.uploads, string(partial))
+
+		return nil, errUnavailable
+	}
+
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, err
+	}
+	s.uploads = append(s.uploads, string(data))
+
+	return &gcs.FileInfo{Path: path, Size: int64(len(data))}, nil
+}
+
+func TestUploadRetriesFromOriginalOffset(t *testing.T) {
+	storage := &flakyStorage{}
+	files := NewStorage(storage, Policy{MaxRetries: 1})
+
+	content := bytes.NewReader([]byte("header:content"))
+	if _, err := content.Seek(int64(len("header:")), io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := files.Upload(context.Background(), "documents/a.txt", content, "text/plain")
+	if err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+	if got := storage.uploads; len(got) != 2 || got[1] != "content" {
+		t.Errorf("uploads = %q, want the retry to send %q", got, "content")
+	}
+	if info.Size != int64(len("content")) {
+		t.Errorf("Size = %d, want %d", info.Size, len("content"))
+	}
+}
+
+func TestUploadWithoutSeekerIsNotRetried(t *testing.T) {
+	storage := &flakyStorage{}
+	files := NewStorage(storage, Policy{MaxRetries: 1})
+
+	// The part read by the failed attempt is gone, so a retry would upload a truncated object
+	if _, err := files.Upload(context.Background(), "documents/a.txt", io.MultiReader(strings.NewReader("content")), "text/plain"); err == nil {
+		t.Error("Upload() succeeded, want the first attempt's error")
+	}
+	if len(storage.uploads) != 1 {
+		t.Errorf("uploads = %d, want 1", len(storage.uploads))
+	}
+}